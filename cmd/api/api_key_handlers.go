@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// getAPIKeysHandler is the handler for the "GET /admin/api-keys" endpoint
+func (app *Application) getAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving API keys")
+	defer span.End()
+
+	// Read query string
+	queryString := r.URL.Query()
+
+	// Instantiate validator
+	v := validation.New()
+
+	// Extract values from query string if they exist
+	findOpts := filters.Filters{
+		Page:         app.readIntFromQueryString(queryString, "page", 1, v),
+		PageSize:     app.readIntFromQueryString(queryString, "page_size", 20, v),
+		Sort:         app.ReadStringFromQueryString(queryString, "sort", "name"),
+		SortSafelist: []string{"name", "created_at", "-name", "-created_at"},
+	}
+
+	data.ValidateFilters(v, findOpts)
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Retrieve the API keys of the tenant
+	apiKeys, metadata, err := app.APIKeysRepository.GetAll(ctx, bson.M{data.TenantField: app.contextTenant(r.Context())}, findOpts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"api_keys": apiKeys,
+		"metadata": metadata,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// createAPIKeyHandler is the handler for the "POST /admin/api-keys" endpoint.
+// The generated key is only returned in the response of this endpoint.
+func (app *Application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Creating API key")
+	defer span.End()
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		Name        string     `json:"name"`
+		Permissions []string   `json:"permissions"`
+		ExpiresAt   *time.Time `json:"expires_at"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Generate the key
+	key, hash, err := data.GenerateAPIKey()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	// Copy the values from the input struct to a new APIKey struct
+	apiKey := data.APIKey{
+		Name:        input.Name,
+		Prefix:      key[:len(data.APIKeyPrefix)+6],
+		Hash:        hash,
+		Permissions: input.Permissions,
+		TenantID:    app.contextTenant(r.Context()),
+		CreatedBy:   app.ContextGetUser(r).ID,
+		ExpiresAt:   input.ExpiresAt,
+		Version:     1,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	// Perform validation checks
+	data.ValidateAPIKey(v, apiKey)
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Record API key attributes in trace
+	span.SetAttributes(
+		attribute.String("name", apiKey.Name),
+		attribute.String("prefix", apiKey.Prefix),
+	)
+
+	// Create a record in the database
+	id, err := app.APIKeysRepository.Create(ctx, apiKey)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	apiKey.ID = *id
+
+	// Include a Location header to let the client know where to find the newly-created resource
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/admin/api-keys/%s", apiKey.ID.Hex()))
+
+	env := types.Envelope{
+		"api_key": apiKey,
+		"key":     key,
+	}
+
+	err = app.WriteJSON(w, http.StatusCreated, env, headers)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// deleteAPIKeyHandler is the handler for the "DELETE /admin/api-keys/:id" endpoint.
+// Deleted keys are rejected from the next request on.
+func (app *Application) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Deleting API key")
+	defer span.End()
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	// Record API key id in trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	// Retrieve the API key of the tenant with given id
+	apiKey, err := app.APIKeysRepository.GetByFilter(ctx, bson.M{"_id": id, data.TenantField: app.contextTenant(r.Context())})
+	if err == nil {
+		// Delete API key in the database
+		err = app.APIKeysRepository.Delete(ctx, apiKey.ID)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	env := types.Envelope{
+		"message": "API key deleted successfully",
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
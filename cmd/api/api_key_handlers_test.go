@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// requestWithAPIKey is a helper method that makes a request authenticated with the given API key
+func (ts *testServer) requestWithAPIKey(t *testing.T, method string, urlPath string, apiKey string) (int, []byte) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "ApiKey "+apiKey)
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res.StatusCode, resBody
+}
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", map[string]any{"name": "Batch job", "permissions": []string{"catalog:read"}}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Valid submission", map[string]any{"name": "Batch job", "permissions": []string{"catalog:read"}}, accessTokenUser1, http.StatusCreated, []byte(`"key": "pcat_`)},
+		{"Empty name", map[string]any{"name": "", "permissions": []string{"catalog:read"}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"No permissions", map[string]any{"name": "Batch job", "permissions": []string{}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must contain at least one permission")},
+		{"Unsupported permission", map[string]any{"name": "Batch job", "permissions": []string{"inventory:read"}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("contains an unsupported permission inventory:read")},
+		{"Duplicated permissions", map[string]any{"name": "Batch job", "permissions": []string{"catalog:read", "catalog:read"}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must not contain duplicate values")},
+		{"Expired", map[string]any{"name": "Batch job", "permissions": []string{"catalog:read"}, "expires_at": time.Now().Add(-time.Hour)}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be in the future")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, "/admin/api-keys", tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// The hash of the keys is never returned
+	statusCode, _, resBody := ts.get(t, "/admin/api-keys", true, accessTokenUser1)
+	if statusCode != http.StatusOK {
+		t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+	}
+
+	if !bytes.Contains(resBody, []byte(`"name": "Batch job"`)) || bytes.Contains(resBody, []byte("hash")) {
+		t.Errorf("want body %q to list the API key without its hash", resBody)
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Create a read-only API key
+	statusCode, _, resBody := ts.post(t, "/admin/api-keys", map[string]any{"name": "Batch job", "permissions": []string{"catalog:read"}}, true, accessTokenUser1)
+	if statusCode != http.StatusCreated {
+		t.Fatalf("want %d; got %d", http.StatusCreated, statusCode)
+	}
+
+	var created struct {
+		APIKey struct {
+			ID string `json:"id"`
+		} `json:"api_key"`
+		Key string `json:"key"`
+	}
+
+	err := json.Unmarshal(resBody, &created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName           string
+		method             string
+		urlPath            string
+		apiKey             string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Unknown API key", http.MethodGet, "/v1/items", "pcat_unknown", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"Permission of the key", http.MethodGet, "/v1/items", created.Key, http.StatusOK, []byte(`"items"`)},
+		{"Permission not granted to the key", http.MethodDelete, "/v1/items/" + created.APIKey.ID, created.Key, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Admin endpoint", http.MethodGet, "/admin/api-keys", created.Key, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, resBody := ts.requestWithAPIKey(t, tt.method, tt.urlPath, tt.apiKey)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// Deleted keys are rejected
+	statusCode, _, _ = ts.delete(t, "/admin/api-keys/"+created.APIKey.ID, true, accessTokenUser1)
+	if statusCode != http.StatusOK {
+		t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+	}
+
+	statusCode, _ = ts.requestWithAPIKey(t, http.MethodGet, "/v1/items", created.Key)
+	if statusCode != http.StatusUnauthorized {
+		t.Errorf("want %d; got %d", http.StatusUnauthorized, statusCode)
+	}
+}
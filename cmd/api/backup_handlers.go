@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Formats of the items backups
+const (
+	backupFormatNDJSON = "ndjson" // One relaxed MongoDB Extended JSON document per line
+	backupFormatBSON   = "bson"   // Concatenated BSON documents, like the files written by mongodump
+)
+
+// Bounds of the size of a BSON document, in bytes
+const (
+	minBSONDocumentSize = 5
+	maxBSONDocumentSize = 16 * 1024 * 1024
+)
+
+// backupContentTypes maps the formats of the items backups to the content type of the responses
+var backupContentTypes = map[string]string{
+	backupFormatNDJSON: "application/x-ndjson",
+	backupFormatBSON:   "application/bson",
+}
+
+// backupHandler is the handler for the "POST /admin/backup" endpoint.
+// It streams the stored documents of every item of the catalog in the requested format, including the fields
+// which are not part of the items (i.e. the popularity counters). On replica sets and sharded clusters
+// the items are read from a snapshot so that the backup reflects a single point in time.
+func (app *Application) backupHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Backing up items")
+	defer span.End()
+
+	v := validation.New()
+
+	format := app.ReadStringFromQueryString(r.URL.Query(), "format", backupFormatNDJSON)
+	v.Check(validator.In(format, backupFormatNDJSON, backupFormatBSON), "format", "unsupported_value", "invalid format")
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Snapshot reads are not supported by standalone servers
+	snapshot := app.RuntimeInfo != nil && app.RuntimeInfo.MongoDB.Topology != "standalone"
+	consistency := "snapshot"
+	if !snapshot {
+		consistency = "cursor"
+	}
+
+	span.SetAttributes(attribute.String("format", format), attribute.Bool("snapshot", snapshot))
+
+	// Headers are only sent along with the first item so that a failure to start
+	// the export can still be reported with an error response
+	started := false
+	count := 0
+
+	start := func() {
+		filename := fmt.Sprintf("items-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+
+		w.Header().Set("Content-Type", backupContentTypes[format])
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("X-Backup-Consistency", consistency)
+		w.Header().Set("Trailer", "X-Backup-Status, X-Backup-Count")
+		w.WriteHeader(http.StatusOK)
+
+		started = true
+	}
+
+	err := app.ItemsRepository.Export(ctx, snapshot, func(document data.Document[data.Item]) error {
+		encoded, err := marshalBackupDocument(document.Raw, format)
+		if err != nil {
+			return err
+		}
+
+		if !started {
+			start()
+		}
+
+		_, err = w.Write(encoded)
+		count++
+
+		return err
+	})
+
+	span.SetAttributes(attribute.Int("count", count))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		if !started {
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		// The status code has already been sent, the failure is reported in the trailers
+		app.Logger.Error(err, map[string]string{"operation": "backup", "exported": fmt.Sprint(count)})
+		w.Header().Set("X-Backup-Status", "failed")
+		w.Header().Set("X-Backup-Count", fmt.Sprint(count))
+		return
+	}
+
+	// Empty catalog
+	if !started {
+		start()
+	}
+
+	w.Header().Set("X-Backup-Status", "complete")
+	w.Header().Set("X-Backup-Count", fmt.Sprint(count))
+}
+
+// restoreHandler is the handler for the "POST /admin/restore" endpoint.
+// It reads a backup produced by the "POST /admin/backup" endpoint, validates every item and then
+// inserts the missing items and replaces the stored ones along with the fields of their documents
+// which are not part of the items. Items missing from the backup are kept.
+func (app *Application) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Restoring items")
+	defer span.End()
+
+	v := validation.New()
+
+	format := app.ReadStringFromQueryString(r.URL.Query(), "format", backupFormatNDJSON)
+	v.Check(validator.In(format, backupFormatNDJSON, backupFormatBSON), "format", "unsupported_value", "invalid format")
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.String("format", format))
+
+	var documents []data.Document[data.Item]
+
+	// Use http.MaxBytesReader() to limit the size of the backup
+	body, err := app.limitBody(w, r, app.contextGetBodyLimit(r))
+	if err == nil {
+		documents, err = readBackup(body, format)
+	}
+
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			err = bodyTooLargeError(maxBytesError.Limit)
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Validate the whole backup before writing anything
+	for i, document := range documents {
+		validateBackupItem(v, document.Entity, app.Settings)
+
+		if v.HasErrors() {
+			v.AddError("document", "invalid_document", fmt.Sprintf("number %d of the backup failed validation", i+1))
+			span.SetStatus(codes.Error, "Validation failed")
+			app.failedValidationResponse(w, r, v)
+			return
+		}
+	}
+
+	span.SetAttributes(attribute.Int("count", len(documents)))
+
+	err = app.ItemsRepository.Restore(ctx, documents)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrDuplicateKey):
+			app.duplicateKeyResponse(w, r, err, "item")
+		case errors.Is(err, data.ErrCrossTenant):
+			v.AddError("id", "already_exists", "item with this id already exists in another tenant")
+			app.validationErrorResponse(w, r, http.StatusConflict, v)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"restored": len(documents)}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// validateBackupItem runs validation checks on an item read from a backup.
+// On top of the checks of the API, the fields set by the store must be provided.
+func validateBackupItem(v *validation.Validator, item data.Item, catalogSettings *settings.Settings) {
+	// The items are restored even if they contain words banned after the backup
+	data.ValidateItem(v, item, catalogSettings.Pricing, settings.Moderation{})
+	v.Check(!item.ID.IsZero(), "id", "required", "must be provided")
+	v.Check(item.Version >= 1, "version", "out_of_range", "must be greater than zero")
+	v.Check(!item.CreatedAt.IsZero(), "created_at", "required", "must be provided")
+	v.Check(!item.UpdatedAt.IsZero(), "updated_at", "required", "must be provided")
+}
+
+// marshalBackupDocument encodes a stored document as a document of a backup in the given format
+func marshalBackupDocument(raw bson.Raw, format string) ([]byte, error) {
+	if format == backupFormatBSON {
+		return raw, nil
+	}
+
+	document, err := bson.MarshalExtJSON(raw, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(document, '\n'), nil
+}
+
+// readBackup decodes the documents of a backup in the given format, along with their items
+func readBackup(body io.Reader, format string) ([]data.Document[data.Item], error) {
+	reader := bufio.NewReader(body)
+	documents := []data.Document[data.Item]{}
+
+	for number := 1; ; number++ {
+		document, err := readBackupDocument(reader, format)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var raw bson.Raw
+
+		if format == backupFormatBSON {
+			raw = document
+		} else {
+			err = bson.UnmarshalExtJSON(document, false, &raw)
+		}
+
+		var item data.Item
+
+		if err == nil {
+			err = bson.Unmarshal(raw, &item)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("body contains a malformed document (document %d): %w", number, err)
+		}
+
+		documents = append(documents, data.Document[data.Item]{Entity: item, Raw: raw})
+	}
+
+	if len(documents) == 0 {
+		return nil, errors.New("body must not be empty")
+	}
+
+	return documents, nil
+}
+
+// readBackupDocument reads the next document of a backup in the given format.
+// It returns io.EOF once every document has been read.
+func readBackupDocument(reader *bufio.Reader, format string) ([]byte, error) {
+	if format == backupFormatBSON {
+		// Every BSON document starts with its length, as a little endian 32 bits integer
+		header, err := reader.Peek(4)
+		switch {
+		case errors.Is(err, io.EOF) && len(header) == 0:
+			return nil, io.EOF
+		case errors.Is(err, io.EOF):
+			return nil, errors.New("body contains a truncated BSON document")
+		case err != nil:
+			return nil, err
+		}
+
+		length := binary.LittleEndian.Uint32(header)
+		if length < minBSONDocumentSize || length > maxBSONDocumentSize {
+			return nil, errors.New("body contains a malformed BSON document")
+		}
+
+		document := make([]byte, length)
+		_, err = io.ReadFull(reader, document)
+		switch {
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return nil, errors.New("body contains a truncated BSON document")
+		case err != nil:
+			return nil, err
+		}
+
+		return document, nil
+	}
+
+	// Skip blank lines between the NDJSON documents
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+
+		if len(line) != 0 {
+			return line, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
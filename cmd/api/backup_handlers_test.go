@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReadBackup(t *testing.T) {
+	item := data.Item{
+		ID:          primitive.NewObjectID(),
+		Name:        "Potion",
+		Description: "Restores a small amount of HP",
+		Price:       5,
+		Tags:        []string{"consumable"},
+		Version:     2,
+		CreatedAt:   time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2022, 10, 2, 12, 0, 0, 0, time.UTC),
+	}
+
+	// The stored documents also hold fields which are not part of the items
+	raw, err := bson.Marshal(bson.M{
+		"_id":                 item.ID,
+		"name":                item.Name,
+		"description":         item.Description,
+		"price":               item.Price,
+		"tags":                item.Tags,
+		"version":             item.Version,
+		"created_at":          item.CreatedAt,
+		"updated_at":          item.UpdatedAt,
+		"popularity":          bson.M{"purchases": 3},
+		"expiration_notified": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{backupFormatNDJSON, backupFormatBSON} {
+		t.Run("Round trip "+format, func(t *testing.T) {
+			document, err := marshalBackupDocument(raw, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			documents, err := readBackup(bytes.NewReader(append(document, document...)), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(documents) != 2 {
+				t.Fatalf("want %d; got %d", 2, len(documents))
+			}
+
+			restored := documents[0].Entity
+			if restored.ID != item.ID || restored.Version != item.Version || !restored.CreatedAt.Equal(item.CreatedAt) {
+				t.Errorf("want %v; got %v", item, restored)
+			}
+
+			purchases, ok := documents[0].Raw.Lookup("popularity", "purchases").AsInt64OK()
+			if !ok || purchases != 3 {
+				t.Errorf("want %d purchases; got %v", 3, documents[0].Raw.Lookup("popularity", "purchases"))
+			}
+
+			if notified, ok := documents[0].Raw.Lookup("expiration_notified").BooleanOK(); !ok || !notified {
+				t.Errorf("want expiration_notified to be kept; got %v", documents[0].Raw.Lookup("expiration_notified"))
+			}
+		})
+	}
+
+	tests := []struct {
+		testName     string
+		body         []byte
+		format       string
+		wantedErrMsg string
+	}{
+		{"Empty backup", []byte("\n\n"), backupFormatNDJSON, "body must not be empty"},
+		{"Malformed NDJSON document", []byte("{\"name\": \"Potion\"}\n{\"name\": \n"), backupFormatNDJSON, "malformed document (document 2)"},
+		{"Truncated BSON document", []byte{0x20, 0, 0, 0, 0x0a}, backupFormatBSON, "truncated BSON document"},
+		{"Malformed BSON document", []byte{0x01, 0, 0, 0}, backupFormatBSON, "malformed BSON document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			_, err := readBackup(bytes.NewReader(tt.body), tt.format)
+			if err == nil {
+				t.Fatalf("want error containing %q; got nil", tt.wantedErrMsg)
+			}
+
+			if !bytes.Contains([]byte(err.Error()), []byte(tt.wantedErrMsg)) {
+				t.Errorf("want error %q to contain %q", err, tt.wantedErrMsg)
+			}
+		})
+	}
+}
+
+func TestBackupAndRestoreHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Backup", func(t *testing.T) {
+		tests := []struct {
+			testName           string
+			urlPath            string
+			accessToken        string
+			wantedStatusCode   int
+			wantedResponseBody []byte
+		}{
+			{"User does not have permission - has catalog:read", "/admin/backup", accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+			{"Invalid format", "/admin/backup?format=csv", accessTokenUser1, http.StatusUnprocessableEntity, []byte("invalid format")},
+			{"NDJSON backup", "/admin/backup", accessTokenUser1, http.StatusOK, []byte(`"name":"Antidote"`)},
+			{"BSON backup", "/admin/backup?format=bson", accessTokenUser1, http.StatusOK, []byte("Antidote")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.testName, func(t *testing.T) {
+				statusCode, _, resBody := ts.post(t, tt.urlPath, nil, true, tt.accessToken)
+
+				if statusCode != tt.wantedStatusCode {
+					t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+				}
+
+				if !bytes.Contains(resBody, tt.wantedResponseBody) {
+					t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+				}
+			})
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		for _, format := range []string{backupFormatNDJSON, backupFormatBSON} {
+			_, _, backup := ts.post(t, "/admin/backup?format="+format, nil, true, accessTokenUser1)
+
+			statusCode, _, resBody := ts.postRaw(t, "/admin/restore?format="+format, backup, accessTokenUser1)
+
+			if statusCode != http.StatusOK {
+				t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+			}
+
+			if !bytes.Contains(resBody, []byte(`"restored"`)) {
+				t.Errorf("want body %q to contain %q", resBody, `"restored"`)
+			}
+		}
+
+		tests := []struct {
+			testName           string
+			body               []byte
+			accessToken        string
+			wantedStatusCode   int
+			wantedResponseBody []byte
+		}{
+			{"User does not have permission - has catalog:read", []byte("{}"), accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+			{"Empty body", nil, accessTokenUser1, http.StatusBadRequest, []byte("body must not be empty")},
+			{"Malformed document", []byte("{\"name\": \n"), accessTokenUser1, http.StatusBadRequest, []byte("malformed document")},
+			{"Invalid document", []byte(`{"name": "Potion", "description": "Restores HP", "price": 5}`), accessTokenUser1, http.StatusUnprocessableEntity, []byte("number 1 of the backup failed validation")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.testName, func(t *testing.T) {
+				statusCode, _, resBody := ts.postRaw(t, "/admin/restore", tt.body, tt.accessToken)
+
+				if statusCode != tt.wantedStatusCode {
+					t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+				}
+
+				if !bytes.Contains(resBody, tt.wantedResponseBody) {
+					t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+				}
+			})
+		}
+	})
+}
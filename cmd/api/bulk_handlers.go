@@ -0,0 +1,518 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"github.com/PlayEconomy37/Play.Catalog/internal/tagging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// readBulkIDs converts the ids of a bulk operation into ObjectIDs and checks that there are not too many of them
+func (app *Application) readBulkIDs(v *validation.Validator, ids []string) []primitive.ObjectID {
+	v.Check(len(ids) != 0, "ids", "required", "must contain at least one id")
+	v.Check(len(ids) <= app.Settings.Administration.MaxBulkItems, "ids", "too_many", fmt.Sprintf("must not contain more than %d ids", app.Settings.Administration.MaxBulkItems))
+	v.Check(validator.NoDuplicates(ids), "ids", "duplicate", "must not contain duplicate values")
+
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			v.AddError("ids", "invalid_format", "must only contain valid ids")
+			continue
+		}
+
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	return objectIDs
+}
+
+// bulkDeleteItemsHandler is the handler for the "POST /v1/items/bulk-delete" endpoint.
+// The ids of the items which do not exist are returned instead of failing the whole operation.
+func (app *Application) bulkDeleteItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Deleting items")
+	defer span.End()
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		IDs []string `json:"ids"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	ids := app.readBulkIDs(v, input.IDs)
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("items", len(ids)))
+
+	// Delete the items one by one so that every deletion is indexed and published. The deletions
+	// run within a transaction, when enabled, so that none of the items is deleted if one of them fails.
+	var deleted int
+	var notFound []string
+
+	err = app.Transactions.Run(ctx, func(ctx context.Context) error {
+		deleted = 0
+		notFound = []string{}
+
+		for _, id := range ids {
+			err := app.ItemsRepository.Delete(ctx, id)
+			switch {
+			case err == nil:
+				deleted++
+			case errors.Is(err, database.ErrRecordNotFound):
+				notFound = append(notFound, id.Hex())
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	app.Logger.Info("Items deleted in bulk", map[string]string{"deleted": fmt.Sprint(deleted)})
+
+	env := types.Envelope{
+		"deleted":   deleted,
+		"not_found": notFound,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// adjustItemPricesHandler is the handler for the "POST /v1/items/price-adjustments" endpoint.
+// The prices of the given items are changed by the given percentage (i.e. -10 for a 10% discount) and
+// rounded to the allowed number of decimal places. Nothing is changed if one of the adjusted prices is not valid.
+// The adjustment is run as a background job when the request has the "Prefer: respond-async" header.
+func (app *Application) adjustItemPricesHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Adjusting item prices")
+	defer span.End()
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		IDs     []string `json:"ids"`
+		Percent *float64 `json:"percent"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	ids := app.readBulkIDs(v, input.IDs)
+
+	if input.Percent == nil {
+		v.AddError("percent", "required", "must be provided")
+	} else {
+		v.Check(*input.Percent != 0 && validator.Between(*input.Percent, -99.0, 1000.0), "percent", "out_of_range", "must be greater or equal to -99 and lower or equal to 1000, zero excluded")
+	}
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("items", len(ids)), attribute.Float64("percent", *input.Percent))
+
+	if app.Jobs != nil && prefersAsync(r) {
+		app.enqueueJob(w, r, span, priceAdjustmentJob, priceAdjustmentParams{IDs: ids, Percent: *input.Percent})
+		return
+	}
+
+	adjustment, priceValidator, err := app.adjustItemPrices(ctx, ids, *input.Percent, &jobs.Reporter{})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrEditConflict):
+			app.EditConflictResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	if priceValidator != nil {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, priceValidator)
+		return
+	}
+
+	env := types.Envelope{
+		"updated":   adjustment.Updated,
+		"not_found": adjustment.NotFound,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// priceAdjustment is a struct that holds the outcome of a price adjustment
+type priceAdjustment struct {
+	Updated  int
+	NotFound []string
+}
+
+// adjustItemPrices changes the prices of the items with the given ids by the given percentage. It returns the
+// validation errors of the adjusted prices, in which case none of them is changed. The checked items are reported
+// to the given reporter, the items which were not found as failed.
+func (app *Application) adjustItemPrices(ctx context.Context, ids []primitive.ObjectID, percent float64, reporter *jobs.Reporter) (priceAdjustment, *validation.Validator, error) {
+	reporter.SetTotal(int64(len(ids)))
+
+	// Adjust the prices of the existing items and check every adjusted item before changing any of them
+	v := validation.New()
+	items := make([]data.Item, 0, len(ids))
+	notFound := []string{}
+
+	for _, id := range ids {
+		item, err := app.ItemsRepository.GetByID(ctx, id)
+		switch {
+		case err == nil:
+		case errors.Is(err, database.ErrRecordNotFound):
+			notFound = append(notFound, id.Hex())
+			reporter.Fail(1)
+			continue
+		default:
+			return priceAdjustment{}, nil, err
+		}
+
+		item.Price = data.AdjustPrice(item.Price, percent, app.Settings.Pricing.MaxDecimals)
+		item.UpdatedAt = time.Now().UTC()
+
+		itemValidator := validation.New()
+		data.ValidateItem(itemValidator, item, app.Settings.Pricing, app.Settings.Moderation)
+
+		if message, ok := itemValidator.Errors["price"]; ok {
+			v.AddError(fmt.Sprintf("items.%s.price", item.ID.Hex()), itemValidator.Reasons["price"], message)
+		}
+
+		items = append(items, app.TaggingEngine.Apply(item))
+		reporter.Succeed(1)
+	}
+
+	if v.HasErrors() {
+		return priceAdjustment{}, v, nil
+	}
+
+	// Update the items within a transaction, when enabled, so that none of the prices
+	// is changed if one of the items was edited in the meantime
+	err := app.Transactions.Run(ctx, func(ctx context.Context) error {
+		for _, item := range items {
+			err := app.ItemsRepository.Update(ctx, item)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return priceAdjustment{}, nil, err
+	}
+
+	app.Logger.Info("Item prices adjusted", map[string]string{"updated": fmt.Sprint(len(items)), "percent": fmt.Sprint(percent)})
+
+	return priceAdjustment{Updated: len(items), NotFound: notFound}, nil, nil
+}
+
+// Statuses of the rows of a bulk upsert
+const (
+	upsertCreated = "created"
+	upsertUpdated = "updated"
+	upsertFailed  = "failed"
+)
+
+// bulkUpsertRow is a struct that holds an item of a bulk upsert, identified by its natural key
+type bulkUpsertRow struct {
+	ExternalID  string     `json:"external_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Price       float64    `json:"price"`
+	Tags        []string   `json:"tags"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// naturalKey returns the value of the given natural key of the row
+func (row bulkUpsertRow) naturalKey(key string) string {
+	if key == "external_id" {
+		return data.NormalizeExternalID(row.ExternalID)
+	}
+
+	return row.Name
+}
+
+// bulkUpsertResult is a struct that holds the outcome of a row of a bulk upsert
+type bulkUpsertResult struct {
+	Index  int               `json:"index" bson:"index"`
+	Key    string            `json:"key" bson:"key"`
+	Status string            `json:"status" bson:"status"`
+	ID     string            `json:"id,omitempty" bson:"id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty" bson:"errors,omitempty"`
+}
+
+// bulkUpsertItemsHandler is the handler for the "PUT /v1/items/bulk" endpoint.
+// Every item is created if no item has its natural key (its name or its external id) and replaces the existing
+// one otherwise. The rows are written independently, like an unordered bulk write, so that a failed row does not
+// stop the others, and go through the repository so that every change is indexed, recorded and published.
+// The upsert is run as a background import job when the request has the "Prefer: respond-async" header.
+func (app *Application) bulkUpsertItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Upserting items")
+	defer span.End()
+
+	if app.Jobs != nil && app.Uploads != nil && prefersAsync(r) {
+		app.enqueueItemImport(w, r, span)
+		return
+	}
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		Key   string          `json:"key"`
+		Items []bulkUpsertRow `json:"items"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Key == "" {
+		input.Key = "name"
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	v.Check(validator.In(input.Key, "name", "external_id"), "key", "unsupported_value", "must be one of name or external_id")
+	v.Check(len(input.Items) != 0, "items", "required", "must contain at least one item")
+	v.Check(len(input.Items) <= app.Settings.Administration.MaxBulkItems, "items", "too_many", fmt.Sprintf("must not contain more than %d items", app.Settings.Administration.MaxBulkItems))
+
+	keys := make([]string, 0, len(input.Items))
+	for _, row := range input.Items {
+		if key := row.naturalKey(input.Key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	v.Check(validator.NoDuplicates(keys), "items", "duplicate", fmt.Sprintf("must not contain the same %s more than once", input.Key))
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.String("key", input.Key), attribute.Int("items", len(input.Items)))
+
+	existing, err := app.existingItems(ctx, input.Key, keys)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	results := make([]bulkUpsertResult, 0, len(input.Items))
+	counts := map[string]int{upsertCreated: 0, upsertUpdated: 0, upsertFailed: 0}
+
+	for i, row := range input.Items {
+		result := app.upsertItem(ctx, app.ContextGetUser(r).ID, input.Key, row, existing)
+		result.Index = i
+		counts[result.Status]++
+		results = append(results, result)
+	}
+
+	app.Logger.Info("Items upserted in bulk", map[string]string{
+		"created": fmt.Sprint(counts[upsertCreated]),
+		"updated": fmt.Sprint(counts[upsertUpdated]),
+		"failed":  fmt.Sprint(counts[upsertFailed]),
+	})
+
+	env := types.Envelope{
+		"created": counts[upsertCreated],
+		"updated": counts[upsertUpdated],
+		"failed":  counts[upsertFailed],
+		"results": results,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// existingItems retrieves the items with the given natural keys with a single query, by key
+func (app *Application) existingItems(ctx context.Context, key string, keys []string) (map[string]data.Item, error) {
+	existing := make(map[string]data.Item, len(keys))
+
+	if len(keys) == 0 {
+		return existing, nil
+	}
+
+	filter := bson.M{key: bson.M{"$in": keys}}
+	findOpts := filters.Filters{Page: 1, PageSize: len(keys), Sort: "_id", SortSafelist: []string{"_id"}}
+
+	items, _, err := app.ItemsRepository.GetAllWithOptions(ctx, filter, findOpts, data.ListOptions{SkipCount: true})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		if key == "external_id" {
+			existing[item.ExternalID] = item
+		} else {
+			existing[item.Name] = item
+		}
+	}
+
+	return existing, nil
+}
+
+// upsertItem creates or replaces the item of a row of a bulk upsert, on behalf of the given user.
+// Invalid rows and failed writes, including the unexpected errors, are reported in the result.
+func (app *Application) upsertItem(ctx context.Context, userID int64, key string, row bulkUpsertRow, existing map[string]data.Item) bulkUpsertResult {
+	result := bulkUpsertResult{Key: row.naturalKey(key)}
+	externalID := data.NormalizeExternalID(row.ExternalID)
+
+	item, found := existing[result.Key]
+	if !found {
+		item = data.Item{
+			ExternalID: externalID,
+			CreatedBy:  userID,
+			Version:    1,
+			CreatedAt:  time.Now().UTC(),
+		}
+	}
+
+	// The external id of the existing items is only changed when provided
+	if externalID != "" {
+		item.ExternalID = externalID
+	}
+
+	item.Name = row.Name
+	item.Description = app.Sanitizer.Sanitize(row.Description)
+	item.Price = row.Price
+	item = tagging.SetManualTags(item, row.Tags)
+	item.ExpiresAt = utcTime(row.ExpiresAt)
+	item.UpdatedAt = time.Now().UTC()
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	v.Check(result.Key != "", key, "required", "must be provided")
+	data.ValidateItem(v, item, app.Settings.Pricing, app.Settings.Moderation)
+	validateExpiresAt(v, item.ExpiresAt)
+
+	if v.HasErrors() {
+		result.Status = upsertFailed
+		result.Errors = v.Errors
+		return result
+	}
+
+	// Apply auto-tagging rules and moderation
+	item = app.TaggingEngine.Apply(item)
+	item = data.FlagForReview(item, app.Settings.Moderation)
+
+	var err error
+
+	if found {
+		result.Status = upsertUpdated
+		result.ID = item.ID.Hex()
+		err = app.ItemsRepository.Update(ctx, item)
+	} else {
+		var id *primitive.ObjectID
+
+		result.Status = upsertCreated
+		id, err = app.ItemsRepository.Create(ctx, item)
+		if err == nil {
+			result.ID = id.Hex()
+		}
+	}
+
+	switch {
+	case err == nil:
+		return result
+	case errors.Is(err, database.ErrEditConflict):
+		result.Errors = map[string]string{"version": "the item was modified in the meantime, please try again"}
+	case errors.Is(err, database.ErrDuplicateKey):
+		var duplicateKeyErr data.DuplicateKeyError
+
+		field := "id"
+		if errors.As(err, &duplicateKeyErr) && duplicateKeyErr.Field != "" {
+			field = duplicateKeyErr.Field
+		}
+
+		result.Errors = map[string]string{field: fmt.Sprintf("an item with this %s already exists", field)}
+	default:
+		// The row is reported as failed so that the rows already written are still reported
+		app.Logger.Error(err, map[string]string{"key": result.Key, "status": result.Status})
+		result.Errors = map[string]string{"item": "the server encountered a problem and could not write the item"}
+	}
+
+	result.Status = upsertFailed
+	result.ID = ""
+
+	return result
+}
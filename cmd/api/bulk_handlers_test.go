@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// seededItemIDs returns the ids of the seeded items by name
+func seededItemIDs(t *testing.T, app *Application) map[string]string {
+	items, _, err := app.ItemsRepository.GetAll(context.Background(), bson.M{}, filters.Filters{Page: 1, PageSize: 20, Sort: "_id", SortSafelist: []string{"_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make(map[string]string, len(items))
+	for _, item := range items {
+		ids[item.Name] = item.ID.Hex()
+	}
+
+	return ids
+}
+
+func TestAdjustItemPricesHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	missingID := "63407e2c8bcd4a43ec1c4ff4"
+
+	tests := []struct {
+		testName           string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", map[string]any{"ids": []string{ids["Potion"]}, "percent": 10}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"No ids", map[string]any{"ids": []string{}, "percent": 10}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must contain at least one id")},
+		{"Invalid id", map[string]any{"ids": []string{"invalid"}, "percent": 10}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must only contain valid ids")},
+		{"No percent", map[string]any{"ids": []string{ids["Potion"]}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"Out of range percent", map[string]any{"ids": []string{ids["Potion"]}, "percent": -100}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be greater or equal to -99")},
+		{"Adjusted price out of range", map[string]any{"ids": []string{ids["Potion"], ids["Mega Potion"]}, "percent": -99}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("items." + ids["Potion"] + ".price")},
+		{"Valid submission", map[string]any{"ids": []string{ids["Potion"], missingID}, "percent": -10}, accessTokenUser1, http.StatusOK, []byte(missingID)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, "/v1/items/price-adjustments", tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// Only the valid adjustment was applied
+	if potion := fetchItem(t, app.ItemsRepository, ids["Potion"]); potion.Price != 4.5 {
+		t.Errorf("want price %v; got %v", 4.5, potion.Price)
+	}
+
+	if megaPotion := fetchItem(t, app.ItemsRepository, ids["Mega Potion"]); megaPotion.Price != 10 {
+		t.Errorf("want price %v; got %v", 10, megaPotion.Price)
+	}
+}
+
+func TestBulkDeleteItemsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", map[string]any{"ids": []string{ids["Potion"]}}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Duplicated ids", map[string]any{"ids": []string{ids["Potion"], ids["Potion"]}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must not contain duplicate values")},
+		{"Valid submission", map[string]any{"ids": []string{ids["Potion"], ids["Ether"]}}, accessTokenUser1, http.StatusOK, []byte(`"deleted": 2`)},
+		{"Deleted items", map[string]any{"ids": []string{ids["Potion"]}}, accessTokenUser1, http.StatusOK, []byte(`"deleted": 0`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, "/v1/items/bulk-delete", tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	if remaining := seededItemIDs(t, app); len(remaining) != 3 {
+		t.Errorf("want %d items; got %d", 3, len(remaining))
+	}
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		method             string
+		urlPath            string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", http.MethodPut, "/admin/maintenance", map[string]any{"enabled": true}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Unknown key", http.MethodPut, "/admin/maintenance", map[string]any{"other": true}, accessTokenUser1, http.StatusBadRequest, []byte("body contains unknown key")},
+		{"Enable", http.MethodPut, "/admin/maintenance", map[string]any{"enabled": true}, accessTokenUser1, http.StatusOK, []byte(`"enabled": true`)},
+		{"Reads are served", http.MethodGet, "/v1/items/" + ids["Potion"], nil, accessTokenUser1, http.StatusOK, []byte(`"name": "Potion"`)},
+		{"Changes are rejected", http.MethodDelete, "/v1/items/" + ids["Potion"], nil, accessTokenUser1, http.StatusServiceUnavailable, []byte("the catalog is under maintenance")},
+		{"Bulk operations are served", http.MethodPost, "/v1/items/bulk-delete", map[string]any{"ids": []string{ids["Ether"]}}, accessTokenUser1, http.StatusOK, []byte(`"deleted": 1`)},
+		{"Disable", http.MethodPut, "/admin/maintenance", map[string]any{"enabled": false}, accessTokenUser1, http.StatusOK, []byte(`"enabled": false`)},
+		{"Changes are served again", http.MethodDelete, "/v1/items/" + ids["Potion"], nil, accessTokenUser1, http.StatusOK, []byte("Item deleted successfully")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, tt.urlPath, tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	t.Run("Shared by the instances", func(t *testing.T) {
+		other := newMaintenanceMode(app.Maintenance.store, app.Logger)
+
+		if _, err := app.Maintenance.Set(context.Background(), true); err != nil {
+			t.Fatal(err)
+		}
+
+		status, err := other.Refresh(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !status.Enabled || status.Since == nil || !other.Status().Enabled {
+			t.Errorf("want maintenance mode enabled on the other instance; got %+v", status)
+		}
+
+		if _, err := other.Set(context.Background(), false); err != nil {
+			t.Fatal(err)
+		}
+
+		if status, _ := app.Maintenance.Refresh(context.Background()); status.Enabled {
+			t.Errorf("want maintenance mode disabled; got %+v", status)
+		}
+	})
+}
+
+func TestBulkUpsertItemsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	potion := map[string]any{"name": "Potion", "description": "Restores a bit more health", "price": 6}
+	elixir := map[string]any{"name": "Elixir", "description": "Fully restores health and mana", "price": 50}
+	invalid := map[string]any{"name": "Phoenix Down", "description": "Revives a fallen ally", "price": 0}
+
+	tests := []struct {
+		testName           string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", map[string]any{"items": []any{potion}}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"No items", map[string]any{"items": []any{}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must contain at least one item")},
+		{"Invalid key", map[string]any{"key": "price", "items": []any{potion}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be one of name or external_id")},
+		{"Duplicated keys", map[string]any{"items": []any{potion, potion}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must not contain the same name more than once")},
+		{"Valid submission", map[string]any{"items": []any{potion, elixir, invalid}}, accessTokenUser1, http.StatusOK, []byte(`"created": 1`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.put(t, "/v1/items/bulk", tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// The existing item was updated, the new one created and the invalid one skipped
+	if updated := fetchItem(t, app.ItemsRepository, ids["Potion"]); updated.Price != 6 || updated.Version != 2 {
+		t.Errorf("want price %v and version %d; got %v and %d", 6, 2, updated.Price, updated.Version)
+	}
+
+	upserted := seededItemIDs(t, app)
+
+	if _, ok := upserted["Elixir"]; !ok {
+		t.Errorf("want item %q to be created", "Elixir")
+	}
+
+	if _, ok := upserted["Phoenix Down"]; ok {
+		t.Errorf("want item %q not to be created", "Phoenix Down")
+	}
+}
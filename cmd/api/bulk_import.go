@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// importBatchSize is the number of rows of an import whose existing items are retrieved at once
+const importBatchSize = 100
+
+// maxImportFailures is the maximum number of failed rows whose result is kept on an import job
+const maxImportFailures = 100
+
+// itemImportParams is a struct that holds the parameters of an import job
+type itemImportParams struct {
+	Upload primitive.ObjectID `bson:"upload"`
+}
+
+// enqueueItemImport stores the body of a bulk upsert, up to BodyLimits.BulkImport bytes, and enqueues the job
+// importing it. The body is streamed to the uploads store and only validated by the job.
+func (app *Application) enqueueItemImport(w http.ResponseWriter, r *http.Request, span trace.Span) {
+	body, err := app.limitBody(w, r, app.Settings.BodyLimits.BulkImport)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	upload, err := app.Uploads.Save("items-import", itemImportJob, body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.BadRequestResponse(w, r, bodyTooLargeError(maxBytesError.Limit))
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	span.SetAttributes(attribute.String("upload", upload.Hex()))
+
+	if !app.enqueueJob(w, r, span, itemImportJob, itemImportParams{Upload: upload}) {
+		err = app.Uploads.Delete(upload)
+		if err != nil {
+			app.Logger.Error(err, map[string]string{"operation": "delete_upload", "upload": upload.Hex()})
+		}
+	}
+}
+
+// runItemImportJob upserts the rows of the bulk upsert uploaded for an import job, like the "PUT /v1/items/bulk"
+// endpoint but without limiting the number of rows to Administration.MaxBulkItems. The upload is read twice:
+// once to check it before anything is written and once to write the rows, a batch at a time. The upload is
+// deleted once the job finished.
+func (app *Application) runItemImportJob(ctx context.Context, job jobs.Job, reporter *jobs.Reporter) (bson.M, error) {
+	var params itemImportParams
+
+	err := job.DecodeParams(&params)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err := app.Uploads.Delete(params.Upload)
+		if err != nil {
+			app.Logger.Error(err, map[string]string{"operation": "delete_upload", "upload": params.Upload.Hex()})
+		}
+	}()
+
+	// Check the upload and count its rows
+	total := 0
+
+	key, err := app.readUpload(params.Upload, func(row bulkUpsertRow) error {
+		total++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v := validation.New()
+
+	v.Check(validator.In(key, "name", "external_id"), "key", "unsupported_value", "must be one of name or external_id")
+	v.Check(total != 0, "items", "required", "must contain at least one item")
+	v.Check(total <= app.Settings.Administration.MaxImportRows, "items", "too_many", fmt.Sprintf("must not contain more than %d items", app.Settings.Administration.MaxImportRows))
+
+	if v.HasErrors() {
+		return nil, &jobs.ValidationError{Errors: v.Errors}
+	}
+
+	reporter.SetTotal(int64(total))
+
+	userID, _ := data.ActorFromContext(ctx)
+
+	counts := map[string]int{upsertCreated: 0, upsertUpdated: 0, upsertFailed: 0}
+	failures := []bulkUpsertResult{}
+	batch := make([]bulkUpsertRow, 0, importBatchSize)
+	index := 0
+
+	// Write the rows of the batch, retrieving their existing items with a single query
+	flush := func() error {
+		keys := make([]string, 0, len(batch))
+		for _, row := range batch {
+			if value := row.naturalKey(key); value != "" {
+				keys = append(keys, value)
+			}
+		}
+
+		existing, err := app.existingItems(ctx, key, keys)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range batch {
+			result := app.upsertItem(ctx, userID, key, row, existing)
+			result.Index = index
+			index++
+			counts[result.Status]++
+
+			if result.Status == upsertFailed {
+				reporter.Fail(1)
+
+				if len(failures) < maxImportFailures {
+					failures = append(failures, result)
+				}
+			} else {
+				reporter.Succeed(1)
+			}
+		}
+
+		batch = batch[:0]
+
+		return nil
+	}
+
+	_, err = app.readUpload(params.Upload, func(row bulkUpsertRow) error {
+		batch = append(batch, row)
+
+		if len(batch) == importBatchSize {
+			return flush()
+		}
+
+		return nil
+	})
+	if err == nil && len(batch) != 0 {
+		err = flush()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	app.Logger.Info("Items imported", map[string]string{
+		"job_id":  job.ID.Hex(),
+		"created": fmt.Sprint(counts[upsertCreated]),
+		"updated": fmt.Sprint(counts[upsertUpdated]),
+		"failed":  fmt.Sprint(counts[upsertFailed]),
+	})
+
+	return bson.M{
+		"key":      key,
+		"created":  counts[upsertCreated],
+		"updated":  counts[upsertUpdated],
+		"failed":   counts[upsertFailed],
+		"failures": failures,
+	}, nil
+}
+
+// readUpload reads the bulk upsert of the upload with the given id, calling the given function with every row
+func (app *Application) readUpload(id primitive.ObjectID, onRow func(row bulkUpsertRow) error) (string, error) {
+	upload, err := app.Uploads.Open(id)
+	if err != nil {
+		return "", err
+	}
+
+	defer upload.Close()
+
+	return readBulkUpsert(upload, onRow)
+}
+
+// readBulkUpsert reads a bulk upsert body token by token and calls the given function with every row, so that the
+// rows are never all held in memory. It returns the natural key of the rows, "name" by default, which may follow
+// them in the body.
+func readBulkUpsert(body io.Reader, onRow func(row bulkUpsertRow) error) (string, error) {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	err := readDelim(decoder, '{')
+	if err != nil {
+		return "", err
+	}
+
+	key := "name"
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", malformedBodyError(err)
+		}
+
+		// The tokens read at the position of a key are always strings
+		switch field := token.(string); field {
+		case "key":
+			err = decoder.Decode(&key)
+			if err != nil {
+				return "", fmt.Errorf("body contains incorrect JSON type for field %q", "key")
+			}
+		case "items":
+			err = readBulkUpsertRows(decoder, onRow)
+			if err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("body contains unknown key %q", field)
+		}
+	}
+
+	err = readDelim(decoder, '}')
+	if err != nil {
+		return "", err
+	}
+
+	if key == "" {
+		key = "name"
+	}
+
+	// Make sure that the body only contains a single JSON value
+	_, err = decoder.Token()
+	if err != io.EOF {
+		return "", errors.New("body must only contain a single JSON value")
+	}
+
+	return key, nil
+}
+
+// readBulkUpsertRows reads the array of the rows of a bulk upsert body and calls the given function with every row
+func readBulkUpsertRows(decoder *json.Decoder, onRow func(row bulkUpsertRow) error) error {
+	err := readDelim(decoder, '[')
+	if err != nil {
+		return err
+	}
+
+	for i := 0; decoder.More(); i++ {
+		var row bulkUpsertRow
+
+		err = decoder.Decode(&row)
+		if err != nil {
+			return fmt.Errorf("body contains an invalid item at index %d: %w", i, malformedBodyError(err))
+		}
+
+		err = onRow(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	return readDelim(decoder, ']')
+}
+
+// readDelim reads the given delimiter from the decoder
+func readDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return malformedBodyError(err)
+	}
+
+	if token != delim {
+		return fmt.Errorf("body contains malformed JSON (at character %d)", decoder.InputOffset())
+	}
+
+	return nil
+}
+
+// malformedBodyError converts the given error of a JSON decoder into the error of the bulk upsert body
+func malformedBodyError(err error) error {
+	var syntaxError *json.SyntaxError
+	var unmarshalTypeError *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Errorf("body contains malformed JSON (at character %d)", syntaxError.Offset)
+	case errors.As(err, &unmarshalTypeError) && unmarshalTypeError.Field != "":
+		return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+	case errors.As(err, &unmarshalTypeError):
+		return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("body contains malformed JSON")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("body contains unknown key %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	default:
+		return err
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeJobUploads keeps the uploaded files in memory
+type fakeJobUploads struct {
+	files map[primitive.ObjectID][]byte
+}
+
+// Save stores the content of the given reader
+func (uploads *fakeJobUploads) Save(name string, jobType string, content io.Reader) (primitive.ObjectID, error) {
+	body, err := io.ReadAll(content)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	id := primitive.NewObjectID()
+	uploads.files[id] = body
+
+	return id, nil
+}
+
+// Open returns a reader of the upload with the given id
+func (uploads *fakeJobUploads) Open(id primitive.ObjectID) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(uploads.files[id])), nil
+}
+
+// Delete removes the upload with the given id
+func (uploads *fakeJobUploads) Delete(id primitive.ObjectID) error {
+	delete(uploads.files, id)
+
+	return nil
+}
+
+func TestReadBulkUpsert(t *testing.T) {
+	tests := []struct {
+		testName    string
+		body        string
+		wantedKey   string
+		wantedRows  int
+		wantedError string
+	}{
+		{"Default key", `{"items": [{"name": "Potion", "price": 5}, {"name": "Ether", "price": 8}]}`, "name", 2, ""},
+		{"Key following the items", `{"items": [{"external_id": "7f9c", "name": "Potion", "price": 5}], "key": "external_id"}`, "external_id", 1, ""},
+		{"No items", `{"key": "name"}`, "name", 0, ""},
+		{"Unknown key", `{"rows": []}`, "", 0, `body contains unknown key "rows"`},
+		{"Unknown item field", `{"items": [{"name": "Potion", "color": "red"}]}`, "", 0, `body contains an invalid item at index 0: body contains unknown key "color"`},
+		{"Incorrect item type", `{"items": [{"name": "Potion"}, {"name": 5}]}`, "", 1, `body contains an invalid item at index 1: body contains incorrect JSON type for field "name"`},
+		{"Malformed JSON", `{"items": [{"name": "Potion"`, "", 0, "body contains an invalid item at index 0: body contains malformed JSON"},
+		{"Truncated body", `{"items": []`, "", 0, "body contains malformed JSON"},
+		{"Not an object", `[]`, "", 0, "body contains malformed JSON (at character 1)"},
+		{"Several values", `{"items": []} {}`, "", 0, "body must only contain a single JSON value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			rows := 0
+
+			key, err := readBulkUpsert(strings.NewReader(tt.body), func(row bulkUpsertRow) error {
+				rows++
+				return nil
+			})
+
+			if tt.wantedError != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantedError)) {
+				t.Errorf("want error %q; got %v", tt.wantedError, err)
+			}
+
+			if tt.wantedError == "" && (err != nil || key != tt.wantedKey) {
+				t.Errorf("want key %q; got %q, %v", tt.wantedKey, key, err)
+			}
+
+			if rows != tt.wantedRows {
+				t.Errorf("want %d rows; got %d", tt.wantedRows, rows)
+			}
+		})
+	}
+}
+
+func TestItemImportJob(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	queue := &fakeJobQueue{}
+	uploads := &fakeJobUploads{files: make(map[primitive.ObjectID][]byte)}
+	app.Jobs = queue
+	app.Uploads = uploads
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+
+	body := `{"items": [
+		{"name": "Potion", "description": "Restores a small amount of health", "price": 6},
+		{"name": "Phoenix Down", "description": "Revives a fallen ally", "price": 300},
+		{"name": "", "description": "Nameless", "price": 1}
+	]}`
+
+	statusCode, _, resBody := ts.makeAsyncRequest(t, http.MethodPut, "/v1/items/bulk", body)
+	if statusCode != http.StatusAccepted {
+		t.Fatalf("want %d; got %d with %s", http.StatusAccepted, statusCode, resBody)
+	}
+
+	if len(queue.jobs) != 1 || len(uploads.files) != 1 {
+		t.Fatalf("want 1 job and 1 upload; got %d and %d", len(queue.jobs), len(uploads.files))
+	}
+
+	reporter := &jobs.Reporter{}
+	ctx := data.ContextWithActor(data.ContextWithTenant(context.Background(), queue.jobs[0].TenantID), 1)
+
+	result, err := app.runItemImportJob(ctx, queue.jobs[0], reporter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result["created"] != 1 || result["updated"] != 1 || result["failed"] != 1 {
+		t.Errorf("want 1 created, 1 updated and 1 failed rows; got %v", result)
+	}
+
+	if failures := result["failures"].([]bulkUpsertResult); len(failures) != 1 || failures[0].Index != 2 {
+		t.Errorf("want the third row to fail; got %v", failures)
+	}
+
+	wantedProgress := jobs.Progress{Total: 3, Processed: 3, Succeeded: 2, Failed: 1}
+	if progress := reporter.Progress(); progress != wantedProgress {
+		t.Errorf("want progress %+v; got %+v", wantedProgress, progress)
+	}
+
+	if len(uploads.files) != 0 {
+		t.Errorf("want the upload to be deleted; got %d uploads", len(uploads.files))
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// compareItemsHandler is the handler for the "GET /v1/items/compare" endpoint.
+// It returns the items with the given ids, in the order of the ids, along with their compared fields.
+func (app *Application) compareItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Comparing items")
+	defer span.End()
+
+	// Instantiate validator
+	v := validation.New()
+
+	ids := app.ReadCsvFromQueryString(r.URL.Query(), "ids", []string{})
+
+	v.Check(len(ids) >= 2, "ids", "too_few", "must contain at least 2 ids")
+	v.Check(len(ids) <= data.MaxComparedItems, "ids", "too_many", fmt.Sprintf("must not contain more than %d ids", data.MaxComparedItems))
+	v.Check(validator.NoDuplicates(ids), "ids", "duplicate", "must not contain duplicate values")
+
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			v.AddError("ids", "invalid_format", "must only contain valid ids")
+			continue
+		}
+
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.StringSlice("ids", ids))
+
+	filter := bson.M{"_id": bson.M{"$in": objectIDs}}
+	findOpts := filters.Filters{Page: 1, PageSize: len(objectIDs), Sort: "_id", SortSafelist: []string{"_id"}}
+
+	found, _, err := app.ItemsRepository.GetAllWithOptions(ctx, filter, findOpts, data.ListOptions{SkipCount: true})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	foundByID := make(map[primitive.ObjectID]data.Item, len(found))
+	for _, item := range found {
+		foundByID[item.ID] = item
+	}
+
+	items := make([]data.Item, 0, len(objectIDs))
+
+	for _, id := range objectIDs {
+		item, ok := foundByID[id]
+		if !ok {
+			// Every compared item must exist
+			span.SetStatus(codes.Error, "Item not found")
+			app.NotFoundResponse(w, r)
+			return
+		}
+
+		items = append(items, item)
+	}
+
+	env := types.Envelope{
+		"items":      items,
+		"comparison": data.CompareItems(items),
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCompareItemsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		query              string
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has inventory:read", fmt.Sprintf("%s,%s", ids["Potion"], ids["Ether"]), accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Single id", ids["Potion"], accessTokenUser2, http.StatusUnprocessableEntity, []byte("must contain at least 2 ids")},
+		{"Too many ids", fmt.Sprintf("%s,%s,%s,%s,%s", ids["Potion"], ids["Ether"], ids["Antidote"], ids["Hi-Potion"], ids["Mega Potion"]), accessTokenUser2, http.StatusUnprocessableEntity, []byte("must not contain more than 4 ids")},
+		{"Invalid id", fmt.Sprintf("%s,potion", ids["Potion"]), accessTokenUser2, http.StatusUnprocessableEntity, []byte("must only contain valid ids")},
+		{"Unknown item", fmt.Sprintf("%s,63407e2c8bcd4a43ec1c4ff4", ids["Potion"]), accessTokenUser2, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Same price", fmt.Sprintf("%s,%s", ids["Potion"], ids["Antidote"]), accessTokenUser2, http.StatusOK, []byte(`"price": 5`)},
+		{"Different prices in the order of the ids", fmt.Sprintf("%s,%s", ids["Mega Potion"], ids["Ether"]), accessTokenUser2, http.StatusOK, []byte("\"price\": [\n\t\t\t\t10,\n\t\t\t\t3\n\t\t\t]")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, "/v1/items/compare?ids="+tt.query, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipWriterPools holds a pool of gzip writers per compression level
+var gzipWriterPools sync.Map
+
+// getGzipWriter retrieves a gzip writer with the given compression level from the pool
+func getGzipWriter(w io.Writer, level int) *gzip.Writer {
+	pool, _ := gzipWriterPools.LoadOrStore(level, &sync.Pool{})
+
+	if gzipWriter, ok := pool.(*sync.Pool).Get().(*gzip.Writer); ok {
+		gzipWriter.Reset(w)
+		return gzipWriter
+	}
+
+	// The level is validated at startup so this never fails
+	gzipWriter, _ := gzip.NewWriterLevel(w, level)
+
+	return gzipWriter
+}
+
+// putGzipWriter puts a gzip writer back in the pool of its compression level
+func putGzipWriter(gzipWriter *gzip.Writer, level int) {
+	pool, _ := gzipWriterPools.LoadOrStore(level, &sync.Pool{})
+	pool.(*sync.Pool).Put(gzipWriter)
+}
+
+// acceptsGzip returns true if the Accept-Encoding header of the request allows gzip encoded responses
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, encoding := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			if name != "gzip" && name != "*" {
+				continue
+			}
+
+			// An encoding with a quality value of 0 is explicitly refused by the client
+			if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err == nil && value == 0 {
+					return false
+				}
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressResponseWriter is a http.ResponseWriter which buffers the response until it reaches
+// a minimum size and then gzip compresses it. Smaller responses are sent as is.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	level       int
+	status      int
+	buffer      []byte
+	gzipWriter  *gzip.Writer
+	passthrough bool
+}
+
+// WriteHeader records the status code. Headers are sent once we know whether the response is compressed.
+func (w *compressResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write buffers the response body until the compression decision can be made
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	switch {
+	case w.gzipWriter != nil:
+		return w.gzipWriter.Write(b)
+	case w.passthrough:
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buffer = append(w.buffer, b...)
+
+	if len(w.buffer) >= w.minSize {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// start sends the headers and the buffered body, compressing it unless
+// the response is not eligible for compression
+func (w *compressResponseWriter) start() error {
+	header := w.Header()
+
+	// Responses already encoded (i.e. /metrics) or without body are sent as is
+	if header.Get("Content-Encoding") != "" || w.status == http.StatusNoContent || w.status == http.StatusNotModified || len(w.buffer) < w.minSize {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+		_, err := w.ResponseWriter.Write(w.buffer)
+		w.buffer = nil
+
+		return err
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.gzipWriter = getGzipWriter(w.ResponseWriter, w.level)
+	_, err := w.gzipWriter.Write(w.buffer)
+	w.buffer = nil
+
+	return err
+}
+
+// Flush sends any buffered data to the client
+func (w *compressResponseWriter) Flush() {
+	if w.gzipWriter == nil && !w.passthrough {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+
+		w.start()
+	}
+
+	if w.gzipWriter != nil {
+		w.gzipWriter.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
+
+// close sends the remaining part of the response and releases the gzip writer
+func (w *compressResponseWriter) close() error {
+	if w.gzipWriter != nil {
+		err := w.gzipWriter.Close()
+		putGzipWriter(w.gzipWriter, w.level)
+		w.gzipWriter = nil
+
+		return err
+	}
+
+	// Nothing was written by the handler
+	if w.status == 0 {
+		return nil
+	}
+
+	if !w.passthrough {
+		return w.start()
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// Define a custom contextKey type with the underlying type string
+type contextKey string
+
+// bodyLimitContextKey is the key used for getting and setting the maximum request
+// body size of the current route in the request context
+const bodyLimitContextKey = contextKey("bodyLimit")
+
+// publicContextKey is the key used for getting and setting whether the current request
+// is an anonymous request of the public catalog in the request context
+const publicContextKey = contextKey("public")
+
+// defaultBodyLimit is the maximum request body size used when a route does not define one
+const defaultBodyLimit int64 = 1_048_576
+
+// contextSetBodyLimit returns a new copy of the request with the provided
+// maximum request body size added to the context
+func (app *Application) contextSetBodyLimit(r *http.Request, limit int64) *http.Request {
+	ctx := context.WithValue(r.Context(), bodyLimitContextKey, limit)
+
+	return r.WithContext(ctx)
+}
+
+// contextGetBodyLimit retrieves the maximum request body size from the request context.
+// It returns the default limit if none was set for the current route.
+func (app *Application) contextGetBodyLimit(r *http.Request) int64 {
+	limit, ok := r.Context().Value(bodyLimitContextKey).(int64)
+	if !ok {
+		return defaultBodyLimit
+	}
+
+	return limit
+}
+
+// contextSetPublic returns a new copy of the request marked as an anonymous request of the public catalog
+func (app *Application) contextSetPublic(r *http.Request) *http.Request {
+	ctx := context.WithValue(r.Context(), publicContextKey, true)
+
+	return r.WithContext(ctx)
+}
+
+// contextGetPublic checks if the request is an anonymous request of the public catalog
+func (app *Application) contextGetPublic(r *http.Request) bool {
+	public, _ := r.Context().Value(publicContextKey).(bool)
+
+	return public
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/rabbitmq"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// parkingLot is implemented by the parking lots of the messages dead lettered by the consumers
+type parkingLot interface {
+	Queues() ([]rabbitmq.DeadLetterQueue, error)
+	Peek(name string, limit int) ([]rabbitmq.DeadLetter, error)
+	Requeue(ctx context.Context, name string, ids []string) (int, error)
+	Purge(name string, ids []string) (int, error)
+}
+
+// getDeadLetterQueuesHandler is the handler for the "GET /admin/dead-letters" endpoint.
+// It returns the dead letter queues of the consumers along with their number of messages.
+func (app *Application) getDeadLetterQueuesHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Retrieving dead letter queues")
+	defer span.End()
+
+	queues, err := app.ParkingLot.Queues()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"queues": queues}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getDeadLettersHandler is the handler for the "GET /admin/dead-letters/{queue}" endpoint.
+// It returns the oldest messages of a dead letter queue along with the reason of their failure,
+// without removing them from the queue.
+func (app *Application) getDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Retrieving dead letters")
+	defer span.End()
+
+	queue := chi.URLParam(r, "queue")
+	span.SetAttributes(attribute.String("queue", queue))
+
+	// Instantiate validator
+	v := validation.New()
+
+	limit := app.readIntFromQueryString(r.URL.Query(), "limit", 20, v)
+	v.Check(validator.Between(limit, 1, 100), "limit", "out_of_range", "must be greater or equal to 1 and lower or equal to 100")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	messages, err := app.ParkingLot.Peek(queue, limit)
+	if err != nil {
+		app.deadLetterErrorResponse(w, r, span, err)
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"queue": queue, "messages": messages}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// requeueDeadLettersHandler is the handler for the "POST /admin/dead-letters/{queue}/requeue" endpoint.
+// It publishes the selected messages of a dead letter queue back to the queue of their consumer.
+func (app *Application) requeueDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Requeuing dead letters")
+	defer span.End()
+
+	queue := chi.URLParam(r, "queue")
+	span.SetAttributes(attribute.String("queue", queue))
+
+	ids, ok := app.readDeadLetterSelection(w, r, span)
+	if !ok {
+		return
+	}
+
+	requeued, err := app.ParkingLot.Requeue(ctx, queue, ids)
+
+	span.SetAttributes(attribute.Int("requeued", requeued))
+
+	// The messages requeued before the failure stay requeued
+	if requeued != 0 {
+		app.Logger.Info("Dead letters requeued", map[string]string{"queue": queue, "requeued": fmt.Sprint(requeued)})
+	}
+
+	if err != nil {
+		app.deadLetterErrorResponse(w, r, span, err)
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"queue": queue, "requeued": requeued}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// purgeDeadLettersHandler is the handler for the "POST /admin/dead-letters/{queue}/purge" endpoint.
+// It removes the selected messages of a dead letter queue for good.
+func (app *Application) purgeDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Purging dead letters")
+	defer span.End()
+
+	queue := chi.URLParam(r, "queue")
+	span.SetAttributes(attribute.String("queue", queue))
+
+	ids, ok := app.readDeadLetterSelection(w, r, span)
+	if !ok {
+		return
+	}
+
+	purged, err := app.ParkingLot.Purge(queue, ids)
+
+	span.SetAttributes(attribute.Int("purged", purged))
+
+	if purged != 0 {
+		app.Logger.Info("Dead letters purged", map[string]string{"queue": queue, "purged": fmt.Sprint(purged)})
+	}
+
+	if err != nil {
+		app.deadLetterErrorResponse(w, r, span, err)
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"queue": queue, "purged": purged}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// readDeadLetterSelection reads the messages selected by a requeue or a purge, either by their ids or all of them,
+// and sends the error response when the selection is invalid. A nil slice selects all the messages.
+func (app *Application) readDeadLetterSelection(w http.ResponseWriter, r *http.Request, span trace.Span) ([]string, bool) {
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		IDs []string `json:"ids"`
+		All bool     `json:"all"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	// Instantiate validator
+	v := validation.New()
+
+	// Every message is only selected explicitly so that an empty list of ids does not select them all
+	v.Check(input.All != (len(input.IDs) != 0), "ids", "required", "must contain at least one id, or be omitted when all is true")
+	v.Check(len(input.IDs) <= app.Settings.Administration.MaxBulkItems, "ids", "too_many", fmt.Sprintf("must not contain more than %d ids", app.Settings.Administration.MaxBulkItems))
+	v.Check(validator.NoDuplicates(input.IDs), "ids", "duplicate", "must not contain duplicate values")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return nil, false
+	}
+
+	if input.All {
+		return nil, true
+	}
+
+	return input.IDs, true
+}
+
+// deadLetterErrorResponse sends the error response of a failed operation on a dead letter queue
+func (app *Application) deadLetterErrorResponse(w http.ResponseWriter, r *http.Request, span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	if errors.Is(err, rabbitmq.ErrUnknownQueue) {
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	app.ServerErrorResponse(w, r, err)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/rabbitmq"
+)
+
+// fakeParkingLot holds the ids of the messages of a single dead letter queue
+type fakeParkingLot struct {
+	name     string
+	messages []string
+}
+
+// Queues returns the dead letter queue
+func (lot *fakeParkingLot) Queues() ([]rabbitmq.DeadLetterQueue, error) {
+	return []rabbitmq.DeadLetterQueue{{Name: lot.name, Queue: "Play.Catalog-user-updated", Messages: len(lot.messages)}}, nil
+}
+
+// Peek returns up to limit messages of the dead letter queue
+func (lot *fakeParkingLot) Peek(name string, limit int) ([]rabbitmq.DeadLetter, error) {
+	if name != lot.name {
+		return nil, rabbitmq.ErrUnknownQueue
+	}
+
+	letters := []rabbitmq.DeadLetter{}
+	for i := 0; i < len(lot.messages) && i < limit; i++ {
+		letters = append(letters, rabbitmq.DeadLetter{ID: lot.messages[i], Reason: "transient error: timeout"})
+	}
+
+	return letters, nil
+}
+
+// Requeue removes the selected messages
+func (lot *fakeParkingLot) Requeue(ctx context.Context, name string, ids []string) (int, error) {
+	return lot.remove(name, ids)
+}
+
+// Purge removes the selected messages
+func (lot *fakeParkingLot) Purge(name string, ids []string) (int, error) {
+	return lot.remove(name, ids)
+}
+
+// remove removes the messages with the given ids, or all of them when no id is given
+func (lot *fakeParkingLot) remove(name string, ids []string) (int, error) {
+	if name != lot.name {
+		return 0, rabbitmq.ErrUnknownQueue
+	}
+
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+
+	kept := []string{}
+	for _, id := range lot.messages {
+		if len(ids) != 0 && !selected[id] {
+			kept = append(kept, id)
+		}
+	}
+
+	removed := len(lot.messages) - len(kept)
+	lot.messages = kept
+
+	return removed, nil
+}
+
+func TestDeadLetterHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	app.ParkingLot = &fakeParkingLot{name: "Play.Catalog-user-updated.dead-letter", messages: []string{"user-1", "user-2", "user-3"}}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		method             string
+		urlPath            string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", http.MethodGet, "/admin/dead-letters", nil, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Queues", http.MethodGet, "/admin/dead-letters", nil, accessTokenUser1, http.StatusOK, []byte(`"messages": 3`)},
+		{"Unknown queue", http.MethodGet, "/admin/dead-letters/Play.Catalog-unknown.dead-letter", nil, accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Invalid limit", http.MethodGet, "/admin/dead-letters/Play.Catalog-user-updated.dead-letter?limit=0", nil, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be greater or equal to 1")},
+		{"Messages", http.MethodGet, "/admin/dead-letters/Play.Catalog-user-updated.dead-letter?limit=2", nil, accessTokenUser1, http.StatusOK, []byte(`"reason": "transient error: timeout"`)},
+		{"No selection", http.MethodPost, "/admin/dead-letters/Play.Catalog-user-updated.dead-letter/requeue", map[string]any{"ids": []string{}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must contain at least one id")},
+		{"Requeue selected messages", http.MethodPost, "/admin/dead-letters/Play.Catalog-user-updated.dead-letter/requeue", map[string]any{"ids": []string{"user-1"}}, accessTokenUser1, http.StatusOK, []byte(`"requeued": 1`)},
+		{"Purge all messages", http.MethodPost, "/admin/dead-letters/Play.Catalog-user-updated.dead-letter/purge", map[string]any{"all": true}, accessTokenUser1, http.StatusOK, []byte(`"purged": 2`)},
+		{"Empty queue", http.MethodGet, "/admin/dead-letters", nil, accessTokenUser1, http.StatusOK, []byte(`"messages": 0`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, tt.urlPath, tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
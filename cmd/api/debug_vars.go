@@ -0,0 +1,63 @@
+package main
+
+import (
+	"expvar"
+	"runtime"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/rabbitmq"
+)
+
+// consumerBacklog is implemented by the consumers reporting the messages of their queue which are not processed yet
+type consumerBacklog interface {
+	Backlog() (rabbitmq.Backlog, error)
+}
+
+// publishDebugVars publishes the application counters served by "GET /debug/vars" next to the command line and
+// the memory statistics published by expvar. The counters are computed on every request of the endpoint.
+// It must only be called once since the variables of expvar cannot be published twice.
+func (app *Application) publishDebugVars(poolStats *data.PoolStats, consumers []messagingTopology) {
+	expvar.Publish("version", expvar.Func(func() any {
+		return app.RuntimeInfo.Version
+	}))
+
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+
+	expvar.Publish("mongodb", expvar.Func(func() any {
+		return map[string]int64{
+			"open_connections":   poolStats.OpenConnections(),
+			"in_use_connections": poolStats.InUseConnections(),
+		}
+	}))
+
+	expvar.Publish("consumers", expvar.Func(func() any {
+		backlogs := []rabbitmq.Backlog{}
+
+		for _, consumer := range consumers {
+			reporter, ok := consumer.(consumerBacklog)
+			if !ok {
+				continue
+			}
+
+			// The messages being processed are still reported when the broker cannot be reached
+			backlog, err := reporter.Backlog()
+			if err != nil {
+				app.Logger.Error(err, map[string]string{"operation": "debug vars", "queue": backlog.Queue})
+			}
+
+			backlogs = append(backlogs, backlog)
+		}
+
+		return backlogs
+	}))
+
+	expvar.Publish("user_cache", expvar.Func(func() any {
+		if app.UserCache == nil {
+			return nil
+		}
+
+		return app.UserCache.Stats()
+	}))
+}
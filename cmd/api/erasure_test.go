@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEraseUser(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	mongoClient, err := database.NewMongoClient(app.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer mongoClient.Disconnect(context.Background())
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	// The player reviews and favorites items, the admin updates one of them
+	statusCode, _, _ := ts.post(t, fmt.Sprintf("/v1/items/%s/reviews", ids["Ether"]), map[string]any{"rating": 4, "comment": "Handy"}, true, accessTokenUser2)
+	if statusCode != http.StatusCreated {
+		t.Fatalf("want %d; got %d", http.StatusCreated, statusCode)
+	}
+
+	statusCode, _, _ = ts.put(t, fmt.Sprintf("/v1/items/%s/favorite", ids["Potion"]), nil, true, accessTokenUser2)
+	if statusCode != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+	}
+
+	statusCode, _, _ = ts.put(t, "/v1/items/"+ids["Potion"], map[string]any{"name": "Great Potion", "price": 8}, true, accessTokenUser1)
+	if statusCode != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+	}
+
+	store := data.NewErasureStore(mongoClient, TestDatabase, nil)
+
+	// The admin is referenced at least by the item it updated
+	tests := []struct {
+		testName      string
+		userID        int64
+		wantedErasure data.Erasure
+		minActors     int64
+	}{
+		{"Player", 2, data.Erasure{Users: 1, Reviews: 1, Favorites: 1}, 0},
+		{"Player erased again", 2, data.Erasure{}, 0},
+		{"Admin", 1, data.Erasure{Users: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			erasure, err := store.Erase(context.Background(), tt.userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actors := erasure.Actors
+			erasure.Actors = 0
+
+			if erasure != tt.wantedErasure {
+				t.Errorf("want %+v; got %+v", tt.wantedErasure, erasure)
+			}
+
+			if actors < tt.minActors {
+				t.Errorf("want at least %d actors; got %d", tt.minActors, actors)
+			}
+		})
+	}
+
+	t.Run("No reference left", func(t *testing.T) {
+		db := mongoClient.Database(TestDatabase)
+
+		for _, filter := range []struct {
+			collection string
+			query      bson.M
+		}{
+			{constants.ItemsCollection, bson.M{"updated_by": int64(1)}},
+			{constants.ItemRevisionsCollection, bson.M{"item.updated_by": int64(1)}},
+			{constants.ReviewsCollection, bson.M{"user_id": int64(2)}},
+			{constants.FavoritesCollection, bson.M{"user_id": int64(2)}},
+		} {
+			count, err := db.Collection(filter.collection).CountDocuments(context.Background(), filter.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if count != 0 {
+				t.Errorf("want no document of %s matching %v; got %d", filter.collection, filter.query, count)
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/types"
+)
+
+// validationErrorResponse is a helper for sending validation errors to the client with a given status code.
+// The messages are sent in the "error" field and their codes (i.e. "price.out_of_range") in the "error_codes" field
+// so that clients can react to them without parsing the messages.
+func (app *Application) validationErrorResponse(w http.ResponseWriter, r *http.Request, status int, v *validation.Validator) {
+	env := types.Envelope{
+		"error":       v.Errors,
+		"error_codes": v.Codes(),
+	}
+
+	err := app.WriteJSON(w, status, env, nil)
+	if err != nil {
+		app.Logger.Error(err, map[string]string{
+			"request_method": r.Method,
+			"request_url":    r.URL.String(),
+		})
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// failedValidationResponse will be used to send a 422 Unprocessable Entity status code along with
+// the contents of the errors map from our Validator type and their codes as a JSON response body
+func (app *Application) failedValidationResponse(w http.ResponseWriter, r *http.Request, v *validation.Validator) {
+	app.validationErrorResponse(w, r, http.StatusUnprocessableEntity, v)
+}
+
+// duplicateKeyResponse will be used to send a 409 Conflict status code along with a validation-style
+// JSON response naming the field whose value is already used by another record
+func (app *Application) duplicateKeyResponse(w http.ResponseWriter, r *http.Request, err error, recordName string) {
+	var duplicateKeyErr data.DuplicateKeyError
+
+	field := "id"
+	if errors.As(err, &duplicateKeyErr) && duplicateKeyErr.Field != "" {
+		field = duplicateKeyErr.Field
+	}
+
+	v := validation.New()
+	v.AddError(field, "already_exists", fmt.Sprintf("%s with this %s already exists", recordName, field))
+
+	app.validationErrorResponse(w, r, http.StatusConflict, v)
+}
+
+// notOwnerResponse will be used to send a 403 Forbidden status code when a user changes an item created by someone else
+func (app *Application) notOwnerResponse(w http.ResponseWriter, r *http.Request) {
+	err := app.WriteJSON(w, http.StatusForbidden, types.Envelope{"error": "you can only modify the items you created"}, nil)
+	if err != nil {
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// staleVersionResponse will be used to send a 409 Conflict status code along with the current version of an item
+// when a client sent changes based on another version of it, so that it can merge them without fetching the item again
+func (app *Application) staleVersionResponse(w http.ResponseWriter, r *http.Request, item data.Item) {
+	headers := make(http.Header)
+	headers.Set("ETag", item.ETag())
+
+	env := types.Envelope{
+		"error": "the item does not match the expected version, its current version is returned",
+		"item":  item,
+	}
+
+	err := app.WriteJSON(w, http.StatusConflict, env, headers)
+	if err != nil {
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// preconditionFailedResponse will be used to send a 412 Precondition Failed status code when an item was modified
+// after the If-Unmodified-Since date of the request
+func (app *Application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	err := app.WriteJSON(w, http.StatusPreconditionFailed, types.Envelope{"error": "the item was modified since the given date"}, nil)
+	if err != nil {
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// ServerErrorResponse will be used to send a 503 Service Unavailable status code when the database circuit breaker
+// rejected the operation, so that the clients retry later, and a 500 Internal Server Error status code otherwise
+func (app *Application) ServerErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, data.ErrCircuitOpen) {
+		app.App.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Retry-After", strconv.Itoa(app.Settings.CircuitBreaker.OpenDuration))
+
+	err = app.WriteJSON(w, http.StatusServiceUnavailable, types.Envelope{"error": "the catalog is temporarily unavailable, please try again later"}, headers)
+	if err != nil {
+		app.App.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/common"
+	"github.com/PlayEconomy37/Play.Common/logger"
+)
+
+func TestServerErrorResponse(t *testing.T) {
+	app := &Application{
+		App:      common.App{Logger: logger.New(io.Discard, logger.LevelInfo)},
+		Settings: &settings.Settings{CircuitBreaker: settings.CircuitBreaker{OpenDuration: 10}},
+	}
+
+	tests := []struct {
+		testName           string
+		err                error
+		wantedStatusCode   int
+		wantedRetryAfter   string
+		wantedResponseBody []byte
+	}{
+		{"Unexpected error", errors.New("unexpected"), http.StatusInternalServerError, "", []byte("The server encountered a problem")},
+		{"Circuit open", fmt.Errorf("retrieving items: %w", data.ErrCircuitOpen), http.StatusServiceUnavailable, "10", []byte("the catalog is temporarily unavailable")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/v1/items", nil)
+
+			app.ServerErrorResponse(rr, r, tt.err)
+
+			if rr.Code != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, rr.Code)
+			}
+
+			if retryAfter := rr.Header().Get("Retry-After"); retryAfter != tt.wantedRetryAfter {
+				t.Errorf("want Retry-After %q; got %q", tt.wantedRetryAfter, retryAfter)
+			}
+
+			if !bytes.Contains(rr.Body.Bytes(), tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", rr.Body.Bytes(), tt.wantedResponseBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/scheduler"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/logger"
+)
+
+// expirationBatchSize is the maximum number of expired items published by a single check
+const expirationBatchSize = 100
+
+// itemExpiredPublisher is implemented by the publishers of the ItemExpired events
+type itemExpiredPublisher interface {
+	Publish(ctx context.Context, item data.Item) error
+}
+
+// itemExpirationTask is the name of the scheduled task publishing the events of the expired items
+const itemExpirationTask = "items.expiration"
+
+// newItemExpirationTask returns the scheduled task which checks for expired items at the given interval
+// and publishes their events
+func newItemExpirationTask(
+	store *data.ExpirationStore,
+	publisher itemExpiredPublisher,
+	transactions *data.Transactions,
+	interval time.Duration,
+	logger *logger.Logger,
+) scheduler.Task {
+	return scheduler.Task{
+		Name:     itemExpirationTask,
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			published, err := publishExpiredItems(ctx, store, publisher, transactions, time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("%w (%d events published)", err, published)
+			}
+
+			if published != 0 {
+				logger.Info("Expired items published", map[string]string{"published": fmt.Sprint(published)})
+			}
+
+			return nil
+		},
+	}
+}
+
+// publishExpiredItems publishes the event of the items expired at the given time whose event was
+// not published yet and returns the number of published events. Each item is claimed and its event
+// enqueued within a transaction, when enabled, so that a failed publication leaves the item unclaimed.
+// Otherwise, an item whose event could not be published is released so that it is published by the next check.
+func publishExpiredItems(
+	ctx context.Context,
+	store *data.ExpirationStore,
+	publisher itemExpiredPublisher,
+	transactions *data.Transactions,
+	now time.Time,
+) (int, error) {
+	published := 0
+
+	for published < expirationBatchSize {
+		err := transactions.Run(ctx, func(ctx context.Context) error {
+			item, err := store.ClaimExpired(ctx, now)
+			if err != nil {
+				return err
+			}
+
+			err = publisher.Publish(ctx, item)
+			if err != nil && !transactions.Enabled() {
+				if releaseErr := store.Release(ctx, item); releaseErr != nil {
+					return fmt.Errorf("%w (failed to release the item: %v)", err, releaseErr)
+				}
+			}
+
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, database.ErrRecordNotFound) {
+				break
+			}
+
+			return published, err
+		}
+
+		published++
+	}
+
+	return published, nil
+}
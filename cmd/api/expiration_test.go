@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/database"
+)
+
+// fakeItemExpiredPublisher records the published items and fails while err is set
+type fakeItemExpiredPublisher struct {
+	published []data.Item
+	err       error
+}
+
+// Publish records the given item
+func (publisher *fakeItemExpiredPublisher) Publish(ctx context.Context, item data.Item) error {
+	if publisher.err != nil {
+		return publisher.err
+	}
+
+	publisher.published = append(publisher.published, item)
+
+	return nil
+}
+
+func TestExpiredItems(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	mongoClient, err := database.NewMongoClient(app.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer mongoClient.Disconnect(context.Background())
+
+	store := data.NewExpirationStore(mongoClient, TestDatabase, constants.ItemsCollection)
+	transactions := data.NewTransactions(mongoClient, false)
+
+	// Expired items can't be created through the API
+	expiresAt := time.Now().UTC().Add(-time.Minute).Truncate(time.Millisecond)
+
+	_, err = app.ItemsRepository.Create(context.Background(), data.Item{
+		Name:        "Expired promotional potion",
+		Description: "Only sold during the event",
+		Price:       5,
+		ExpiresAt:   &expiresAt,
+		Version:     1,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Hidden from listings", func(t *testing.T) {
+		statusCode, _, resBody := ts.get(t, "/v1/items?name=promotional", true, accessTokenUser1)
+
+		if statusCode != http.StatusOK {
+			t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		if bytes.Contains(resBody, []byte("Expired promotional potion")) {
+			t.Errorf("want body %q not to contain %q", resBody, "Expired promotional potion")
+		}
+	})
+
+	t.Run("Failed publication is retried", func(t *testing.T) {
+		publisher := &fakeItemExpiredPublisher{err: errors.New("broker unavailable")}
+
+		_, err := publishExpiredItems(context.Background(), store, publisher, transactions, time.Now().UTC())
+		if err == nil {
+			t.Fatal("want error; got nil")
+		}
+
+		publisher.err = nil
+
+		published, err := publishExpiredItems(context.Background(), store, publisher, transactions, time.Now().UTC())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if published != 1 {
+			t.Errorf("want %d; got %d", 1, published)
+		}
+	})
+
+	t.Run("Published once", func(t *testing.T) {
+		publisher := &fakeItemExpiredPublisher{}
+
+		published, err := publishExpiredItems(context.Background(), store, publisher, transactions, time.Now().UTC())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if published != 0 {
+			t.Errorf("want %d; got %d", 0, published)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// setFavorited sets the favorited flag of the authenticated user on the given items, which are
+// updated in place. Anonymous requests of the public catalog have no favorites.
+func (app *Application) setFavorited(ctx context.Context, r *http.Request, items any) error {
+	if app.contextGetPublic(r) {
+		return nil
+	}
+
+	var list []*data.Item
+
+	switch items := items.(type) {
+	case []data.Item:
+		for i := range items {
+			list = append(list, &items[i])
+		}
+	case []data.SearchedItem:
+		for i := range items {
+			list = append(list, &items[i].Item)
+		}
+	}
+
+	// Items retrieved without their id (i.e. with the fields query string parameter) are not flagged
+	if len(list) != 0 && list[0].ID.IsZero() {
+		return nil
+	}
+
+	if len(list) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(list))
+	for _, item := range list {
+		ids = append(ids, item.ID)
+	}
+
+	filter := data.FavoritesFilter(app.contextTenant(ctx), app.ContextGetUser(r).ID, ids)
+	findOpts := filters.Filters{Page: 1, PageSize: len(ids), Sort: "_id", SortSafelist: []string{"_id"}}
+
+	favorites, _, err := app.FavoritesRepository.GetAll(ctx, filter, findOpts)
+	if err != nil {
+		return err
+	}
+
+	favorited := make(map[primitive.ObjectID]bool, len(favorites))
+	for _, favorite := range favorites {
+		favorited[favorite.ItemID] = true
+	}
+
+	for _, item := range list {
+		flag := favorited[item.ID]
+		item.Favorited = &flag
+	}
+
+	return nil
+}
+
+// setItemFavorited returns the given item along with the favorited flag of the authenticated user
+func (app *Application) setItemFavorited(ctx context.Context, r *http.Request, item data.Item) (data.Item, error) {
+	items := []data.Item{item}
+
+	err := app.setFavorited(ctx, r, items)
+
+	return items[0], err
+}
+
+// favoriteItemHandler is the handler for the "PUT /v1/items/:id/favorite" endpoint.
+// Adding an item which is already a favorite succeeds.
+func (app *Application) favoriteItemHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Adding item to favorites")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// Only the items of the tenant can be added to the favorites
+	_, err := app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	favorite := data.Favorite{
+		TenantID:  app.contextTenant(ctx),
+		UserID:    app.ContextGetUser(r).ID,
+		ItemID:    id,
+		Version:   1,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	_, err = app.FavoritesRepository.Create(ctx, favorite)
+	if err != nil && !errors.Is(err, database.ErrDuplicateKey) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"message": "Item added to favorites successfully",
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// unfavoriteItemHandler is the handler for the "DELETE /v1/items/:id/favorite" endpoint
+func (app *Application) unfavoriteItemHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Removing item from favorites")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{data.TenantField: app.contextTenant(ctx), "user_id": app.ContextGetUser(r).ID, "item_id": id}
+
+	favorite, err := app.FavoritesRepository.GetByFilter(ctx, filter)
+	if err == nil {
+		err = app.FavoritesRepository.Delete(ctx, favorite.ID)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	env := types.Envelope{
+		"message": "Item removed from favorites successfully",
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getFavoritesHandler is the handler for the "GET /v1/me/favorites" endpoint.
+// It returns the favorite items of the authenticated user, the latest added first.
+// The items which were deleted or which expired since they were added are skipped.
+func (app *Application) getFavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving favorite items")
+	defer span.End()
+
+	// Read query string
+	queryString := r.URL.Query()
+
+	// Instantiate validator
+	v := validation.New()
+
+	findOpts := filters.Filters{
+		Page:         app.readIntFromQueryString(queryString, "page", 1, v),
+		PageSize:     app.readIntFromQueryString(queryString, "page_size", 20, v),
+		Sort:         "-created_at",
+		SortSafelist: []string{"-created_at"},
+	}
+
+	data.ValidateFilters(v, findOpts)
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	filter := bson.M{data.TenantField: app.contextTenant(ctx), "user_id": app.ContextGetUser(r).ID}
+
+	favorites, metadata, err := app.FavoritesRepository.GetAll(ctx, filter, findOpts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	items := []data.Item{}
+
+	if len(favorites) != 0 {
+		ids := make([]primitive.ObjectID, 0, len(favorites))
+		for _, favorite := range favorites {
+			ids = append(ids, favorite.ItemID)
+		}
+
+		itemsFilter := bson.M{"_id": bson.M{"$in": ids}, "$or": data.NotExpiredFilter(time.Now().UTC())}
+		itemsOpts := filters.Filters{Page: 1, PageSize: len(ids), Sort: "_id", SortSafelist: []string{"_id"}}
+
+		found, _, err := app.ItemsRepository.GetAllWithOptions(ctx, itemsFilter, itemsOpts, data.ListOptions{SkipCount: true})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		foundByID := make(map[primitive.ObjectID]data.Item, len(found))
+		for _, item := range found {
+			foundByID[item.ID] = item
+		}
+
+		favorited := true
+
+		for _, id := range ids {
+			if item, ok := foundByID[id]; ok {
+				item.Favorited = &favorited
+				items = append(items, item)
+			}
+		}
+	}
+
+	env := types.Envelope{
+		"items":    items,
+		"metadata": metadata,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFavoriteItemHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		method             string
+		id                 string
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has inventory:read", http.MethodPut, ids["Potion"], accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Unknown item", http.MethodPut, "63407e2c8bcd4a43ec1c4ff4", accessTokenUser2, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Favorite item", http.MethodPut, ids["Potion"], accessTokenUser2, http.StatusOK, []byte("Item added to favorites successfully")},
+		{"Item already favorite", http.MethodPut, ids["Potion"], accessTokenUser2, http.StatusOK, []byte("Item added to favorites successfully")},
+		{"Another favorite item", http.MethodPut, ids["Ether"], accessTokenUser2, http.StatusOK, []byte("Item added to favorites successfully")},
+		{"Item removed from favorites", http.MethodDelete, ids["Ether"], accessTokenUser2, http.StatusOK, []byte("Item removed from favorites successfully")},
+		{"Item which is not a favorite", http.MethodDelete, ids["Ether"], accessTokenUser2, http.StatusNotFound, []byte("The requested resource could not be found")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, fmt.Sprintf("/v1/items/%s/favorite", tt.id), nil, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	t.Run("Favorites of the user", func(t *testing.T) {
+		statusCode, _, resBody := ts.get(t, "/v1/me/favorites", true, accessTokenUser2)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		var response struct {
+			Items []struct {
+				Name      string `json:"name"`
+				Favorited bool   `json:"favorited"`
+			} `json:"items"`
+		}
+
+		err := json.Unmarshal(resBody, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Items) != 1 || response.Items[0].Name != "Potion" || !response.Items[0].Favorited {
+			t.Errorf("want the favorite Potion; got %+v", response.Items)
+		}
+	})
+
+	t.Run("Favorited flag", func(t *testing.T) {
+		tests := []struct {
+			testName    string
+			id          string
+			accessToken string
+			wantedFlag  []byte
+		}{
+			{"Favorite of the user", ids["Potion"], accessTokenUser2, []byte(`"favorited": true`)},
+			{"Not a favorite of the user", ids["Ether"], accessTokenUser2, []byte(`"favorited": false`)},
+			{"Favorite of another user", ids["Potion"], accessTokenUser1, []byte(`"favorited": false`)},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.testName, func(t *testing.T) {
+				_, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s", tt.id), true, tt.accessToken)
+
+				if !bytes.Contains(resBody, tt.wantedFlag) {
+					t.Errorf("want body %q to contain %q", resBody, tt.wantedFlag)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// getFeaturedItemsHandler is the handler for the "GET /v1/items/featured" endpoint.
+// It returns the featured items, the highest priority first, for the storefront carousel.
+func (app *Application) getFeaturedItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving featured items")
+	defer span.End()
+
+	// Instantiate validator
+	v := validation.New()
+
+	limit := app.readIntFromQueryString(r.URL.Query(), "limit", 10, v)
+	v.Check(validator.Between(limit, 1, 50), "limit", "out_of_range", "must be greater or equal to 1 and lower or equal to 50")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Expired items are never featured. The filter matches the partial index of the featured items.
+	filter := bson.M{"featured": true, "$or": data.NotExpiredFilter(time.Now().UTC())}
+	findOpts := filters.Filters{Page: 1, PageSize: limit, Sort: "-featured_priority", SortSafelist: []string{"-featured_priority"}}
+
+	items, _, err := app.ItemsRepository.GetAllWithOptions(ctx, filter, findOpts, data.ListOptions{SkipCount: true})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"items": items,
+	}
+
+	// Anonymous requests only retrieve the public fields of the items
+	if app.contextGetPublic(r) {
+		env["items"] = selectItemFields(items, app.Settings.PublicCatalog.Fields)
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// setItemFeaturedHandler is the handler for the "PUT /v1/items/:id/featured" endpoint
+func (app *Application) setItemFeaturedHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Featuring item")
+	defer span.End()
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("id", chi.URLParamFromCtx(r.Context(), "id")))
+
+		// Throw Not found error if extracted id is not a valid ObjectID
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	// Record item id in the trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	var input struct {
+		Featured *bool  `json:"featured"`
+		Priority *int32 `json:"priority"`
+	}
+
+	// Read request body and decode it into the input struct
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	v.Check(input.Featured != nil, "featured", "required", "must be provided")
+
+	if input.Priority != nil {
+		v.Check(validator.Between(*input.Priority, 0, 1000), "priority", "out_of_range", "must be greater or equal to 0 and lower or equal to 1000")
+		v.Check(input.Featured == nil || *input.Featured, "priority", "not_allowed", "can only be used along with a featured item")
+	}
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Retrieve item with given id
+	item, err := app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// The priority is reset when the item stops being featured
+	item.Featured = *input.Featured
+	item.FeaturedPriority = 0
+
+	if input.Priority != nil {
+		item.FeaturedPriority = *input.Priority
+	}
+
+	item.UpdatedAt = time.Now().UTC()
+
+	span.SetAttributes(attribute.Bool("featured", item.Featured), attribute.Int("priority", int(item.FeaturedPriority)))
+
+	// Update item in the database
+	err = app.ItemsRepository.Update(ctx, item)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrEditConflict):
+			app.EditConflictResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	env := types.Envelope{
+		"item": item,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFeaturedItemsHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		id                 string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", ids["Potion"], map[string]any{"featured": true}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"No featured", ids["Potion"], map[string]any{"priority": 5}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"Out of range priority", ids["Potion"], map[string]any{"featured": true, "priority": 1001}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be greater or equal to 0 and lower or equal to 1000")},
+		{"Priority of an item which is not featured", ids["Potion"], map[string]any{"featured": false, "priority": 5}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("can only be used along with a featured item")},
+		{"Unknown item", "63407e2c8bcd4a43ec1c4ff4", map[string]any{"featured": true}, accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Featured item", ids["Potion"], map[string]any{"featured": true, "priority": 5}, accessTokenUser1, http.StatusOK, []byte(`"featured_priority": 5`)},
+		{"Featured item with a higher priority", ids["Antidote"], map[string]any{"featured": true, "priority": 10}, accessTokenUser1, http.StatusOK, []byte(`"featured": true`)},
+		{"Featured item without priority", ids["Ether"], map[string]any{"featured": true}, accessTokenUser1, http.StatusOK, []byte(`"featured": true`)},
+		{"Item not featured anymore", ids["Ether"], map[string]any{"featured": false}, accessTokenUser1, http.StatusOK, []byte(`"featured": false`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.put(t, fmt.Sprintf("/v1/items/%s/featured", tt.id), tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	t.Run("Featured items by priority", func(t *testing.T) {
+		statusCode, _, resBody := ts.get(t, "/v1/items/featured", true, accessTokenUser2)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		var response struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+		}
+
+		err := json.Unmarshal(resBody, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := []string{}
+		for _, item := range response.Items {
+			names = append(names, item.Name)
+		}
+
+		if fmt.Sprint(names) != fmt.Sprint([]string{"Antidote", "Potion"}) {
+			t.Errorf("want %v; got %v", []string{"Antidote", "Potion"}, names)
+		}
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		statusCode, _, _ := ts.get(t, "/v1/items/featured?limit=0", true, accessTokenUser2)
+
+		if statusCode != http.StatusUnprocessableEntity {
+			t.Errorf("want %d; got %d", http.StatusUnprocessableEntity, statusCode)
+		}
+	})
+}
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/tagging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
 	"github.com/PlayEconomy37/Play.Common/database"
-	"github.com/PlayEconomy37/Play.Common/filters"
 	"github.com/PlayEconomy37/Play.Common/types"
 	"github.com/PlayEconomy37/Play.Common/validator"
 	"github.com/go-chi/chi/v5"
@@ -17,10 +20,18 @@ import (
 	"go.opentelemetry.io/otel/codes"
 )
 
-// healthCheckHandler is the handler for the "GET /healthcheck" endpoint
+// healthCheckHandler is the handler for the "GET /healthcheck" endpoint.
+// It reports the build and the environment of the instance so that operators can verify what is deployed.
 func (app *Application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	env := types.Envelope{
 		"status": "available",
+		"system_info": map[string]string{
+			"environment": app.RuntimeInfo.Profile,
+			"version":     app.RuntimeInfo.Version,
+			"commit":      app.RuntimeInfo.Commit,
+			"build_time":  app.RuntimeInfo.BuildTime,
+			"uptime":      time.Since(app.RuntimeInfo.StartedAt).Round(time.Second).String(),
+		},
 	}
 
 	err := app.WriteJSON(w, http.StatusOK, env, nil)
@@ -29,72 +40,140 @@ func (app *Application) healthCheckHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
-// getItemsHandler is the handler for the "GET /items" endpoint
+// getItemsHandler is the handler for the "GET /v1/items" endpoint
 func (app *Application) getItemsHandler(w http.ResponseWriter, r *http.Request) {
 	// Create trace for the handler
 	ctx, span := app.Tracer.Start(r.Context(), "Retrieving items")
 	defer span.End()
 
-	// Anonymous struct used to hold the expected values from the request's query string
-	var input struct {
-		Name     string
-		MinPrice float64
-		MaxPrice float64
-		filters.Filters
-	}
-
 	// Read query string
 	queryString := r.URL.Query()
 
 	// Instantiate validator
-	v := validator.New()
+	v := validation.New()
+
+	// Merge the saved filter into the query string if one was requested
+	if slug := queryString.Get("saved_filter"); slug != "" {
+		span.SetAttributes(attribute.String("saved_filter", slug))
+
+		savedFilter, err := app.SavedFiltersRepository.GetByFilter(ctx, bson.M{"slug": slug})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			switch {
+			case errors.Is(err, database.ErrRecordNotFound):
+				v.AddError("saved_filter", "not_found", "does not exist")
+				app.failedValidationResponse(w, r, v)
+			default:
+				app.ServerErrorResponse(w, r, err)
+			}
+
+			return
+		}
 
-	// Extract values from query string if they exist
-	input.Name = app.ReadStringFromQueryString(queryString, "name", "")
-	input.MinPrice = app.ReadFloatFromQueryString(queryString, "min_price", database.DefaultPrice, v)
-	input.MaxPrice = app.ReadFloatFromQueryString(queryString, "max_price", database.DefaultPrice, v)
-	input.Filters.Page = app.ReadIntFromQueryString(queryString, "page", 1, v)
-	input.Filters.PageSize = app.ReadIntFromQueryString(queryString, "page_size", 20, v)
-	input.Filters.Sort = app.ReadStringFromQueryString(queryString, "sort", "_id")
+		queryString = savedFilter.Apply(queryString)
+	}
 
-	// Add the supported sort values for this endpoint to the sort safelist
-	input.Filters.SortSafelist = []string{"_id", "name", "price", "-_id", "-name", "-price"}
+	// Extract and validate values from query string
+	input := app.readItemsQuery(queryString, v)
+	facets := app.ReadCsvFromQueryString(queryString, "facets", []string{})
 
-	// Validate query string
-	v.Check(validator.Between(input.MinPrice, 0.1, 1000), "min_price", "must be greater or equal to 0.1 or lower and equal to 1000")
-	v.Check(validator.Between(input.MaxPrice, 0.1, 1000), "max_price", "must be greater or equal to 0.1 or lower and equal to 1000")
+	v.Check(validator.AllIn(facets, "tag", "price"), "facets", "unsupported_value", "invalid facets value")
+	v.Check(validator.NoDuplicates(facets), "facets", "duplicate", "must not contain duplicate values")
 
-	// Only run this check if both min_price and max_price have been set
-	if input.MinPrice != database.DefaultPrice && input.MaxPrice != database.DefaultPrice {
-		v.Check(input.MaxPrice >= input.MinPrice, "max_price", "must be greater or equal to specified min_price")
-	}
+	fields := app.ReadCsvFromQueryString(queryString, "fields", []string{})
+
+	v.Check(validator.AllIn(fields, data.ItemFields...), "fields", "unsupported_value", "invalid fields value")
+	v.Check(validator.NoDuplicates(fields), "fields", "duplicate", "must not contain duplicate values")
 
-	filters.ValidateFilters(v, input.Filters)
+	// The stock is retrieved by item id
+	expandStock := app.readExpandStock(queryString, v)
+	renderHTML := app.readRenderHTML(queryString, v)
+	v.Check(!expandStock || len(fields) == 0 || validator.In("id", fields...), "expand", "not_allowed", "stock can only be used along with the id field")
+
+	// Anonymous requests only retrieve the public fields of the items
+	if app.contextGetPublic(r) {
+		v.Check(validator.AllIn(fields, app.Settings.PublicCatalog.Fields...), "fields", "invalid_format", "must only contain public fields")
+		v.Check(!expandStock, "expand", "not_allowed", "stock requires authentication")
+
+		if len(fields) == 0 {
+			fields = app.Settings.PublicCatalog.Fields
+		}
+	}
 
 	// Check the Validator instance for any errors
 	if v.HasErrors() {
 		span.SetStatus(codes.Error, "Validation failed")
-		app.FailedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
 	// Set query filters
-	filter := bson.M{}
+	filter := input.mongoFilter()
+
+	// Retrieve all items
+	var items any
+	var metadata data.Metadata
+	var err error
+
+	// Search the names with the configured search backend
+	searched := false
+	backend := app.Settings.Search.Backend
 
-	if input.Name != "" {
-		filter["$text"] = bson.M{"$search": input.Name}
+	// The popularity of the items is not indexed by Elasticsearch
+	if backend == "elasticsearch" && input.sortsBy(data.PopularitySort) {
+		backend = "text"
 	}
 
-	if input.MinPrice != database.DefaultPrice && input.MaxPrice == database.DefaultPrice {
-		filter["price"] = bson.M{"$gte": input.MinPrice}
-	} else if input.MaxPrice != database.DefaultPrice && input.MinPrice == database.DefaultPrice {
-		filter["price"] = bson.M{"$lte": input.MaxPrice}
-	} else if input.MaxPrice != database.DefaultPrice && input.MinPrice != database.DefaultPrice {
-		filter["price"] = bson.M{"$gte": input.MinPrice, "$lte": input.MaxPrice}
+	// A backend which just failed is skipped until its backoff elapsed
+	if input.Name != "" && backend != "text" && app.SearchBackoff.available(time.Now()) {
+		switch backend {
+		case "atlas":
+			items, metadata, err = app.atlasSearchItems(ctx, input)
+		case "elasticsearch":
+			items, metadata, err = app.elasticsearchItems(ctx, input)
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			app.SearchBackoff.fail(time.Now())
+			app.Logger.Warning("Search backend is not available, falling back to the text index", map[string]string{
+				"backend": app.Settings.Search.Backend,
+				"error":   err.Error(),
+				"backoff": fmt.Sprint(app.Settings.Search.FailureBackoff),
+			})
+		} else {
+			app.SearchBackoff.succeed()
+		}
+
+		searched = err == nil
 	}
 
-	// Retrieve all items
-	items, metadata, err := app.ItemsRepository.GetAll(ctx, filter, input.Filters)
+	if !searched {
+		// Only retrieve the selected fields if some were requested and skip the count
+		// of the matching items if the total is not needed
+		listOpts := data.ListOptions{SkipCount: !input.IncludeTotal}
+		if len(fields) != 0 {
+			listOpts.Projection = data.ItemProjection(fields)
+		}
+
+		items, metadata, err = app.listingsRepository().GetAllWithOptions(ctx, filter, input.Filters, listOpts)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// Render the Markdown descriptions if requested
+	if renderHTML {
+		items = app.renderDescriptions(items)
+	}
+
+	// Flag the favorite items of the user
+	err = app.setFavorited(ctx, r, items)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -102,11 +181,67 @@ func (app *Application) getItemsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Only send back the selected fields if some were requested
+	if len(fields) != 0 {
+		items = selectItemFields(items, fields)
+	}
+
+	// Embed the stock of the items if requested
+	if expandStock {
+		items, err = app.withStock(ctx, r, items)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	env := types.Envelope{
 		"items":    items,
 		"metadata": metadata,
 	}
 
+	// Count the matching items per facet if requested
+	if len(facets) != 0 {
+		var results []data.ItemFacets
+
+		pipeline := data.ItemFacetsPipeline(filter, facets)
+		if app.ListingsRepository != nil {
+			pipeline = data.ListingFacetsPipeline(filter, facets)
+		}
+
+		err = app.listingsRepository().Aggregate(ctx, pipeline, &results)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		// The $facet stage always returns a single document
+		itemFacets := data.ItemFacets{}
+		if len(results) != 0 {
+			itemFacets = results[0]
+		}
+
+		itemFacets.Finalize()
+
+		// Only send back the requested facets
+		facetsEnv := types.Envelope{}
+
+		for _, facet := range facets {
+			switch facet {
+			case "tag":
+				facetsEnv["tags"] = itemFacets.Tags
+			case "price":
+				facetsEnv["price"] = itemFacets.Price
+			}
+		}
+
+		env["facets"] = facetsEnv
+	}
+
 	// Send back response
 	err = app.WriteJSON(w, http.StatusOK, env, nil)
 	if err != nil {
@@ -116,7 +251,346 @@ func (app *Application) getItemsHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// getItemHandler is the handler for the "GET /items/:id" endpoint
+// getItemsStatsHandler is the handler for the "GET /v1/items/stats" endpoint
+func (app *Application) getItemsStatsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving items statistics")
+	defer span.End()
+
+	// Read query string
+	queryString := r.URL.Query()
+
+	// Instantiate validator
+	v := validation.New()
+
+	// Extract and validate values from query string. Statistics accept the same filters as "GET /v1/items".
+	input := app.readItemsQuery(queryString, v)
+	groupBy := app.ReadStringFromQueryString(queryString, "group_by", "")
+
+	v.Check(validator.In(groupBy, "", "tag"), "group_by", "unsupported_value", "invalid group_by value")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.String("group_by", groupBy))
+
+	// Compute statistics
+	var results []data.ItemStats
+
+	err := app.ItemsRepository.Aggregate(ctx, data.ItemStatsPipeline(input.mongoFilter(), groupBy == "tag"), &results)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	// The $facet stage always returns a single document
+	stats := data.PriceStats{}
+	groups := []data.PriceStats{}
+
+	if len(results) != 0 {
+		if len(results[0].Overall) != 0 {
+			stats = results[0].Overall[0]
+		}
+
+		if results[0].Groups != nil {
+			groups = results[0].Groups
+		}
+	}
+
+	env := types.Envelope{
+		"stats": stats,
+	}
+
+	if groupBy != "" {
+		env["groups"] = groups
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getItemSuggestionsHandler is the handler for the "GET /v1/items/suggest" endpoint
+func (app *Application) getItemSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item suggestions")
+	defer span.End()
+
+	// Read query string
+	queryString := r.URL.Query()
+
+	// Instantiate validator
+	v := validation.New()
+
+	// Extract values from query string if they exist
+	prefix := app.ReadStringFromQueryString(queryString, "q", "")
+	limit := app.readIntFromQueryString(queryString, "limit", 10, v)
+
+	// Validate query string
+	v.Check(validator.NotBlank(prefix), "q", "required", "must be provided")
+	v.Check(validator.MaxCharacters(prefix, 50), "q", "too_long", "must not be more than 50 characters long")
+	v.Check(validator.Between(limit, 1, 20), "limit", "out_of_range", "must be greater or equal to 1 and lower or equal to 20")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.String("q", prefix))
+
+	// Retrieve the names matching the prefix
+	var results []data.ItemSuggestion
+
+	err := app.ItemsRepository.Aggregate(ctx, data.ItemSuggestionsPipeline(prefix, limit), &results)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	suggestions := []string{}
+	for _, result := range results {
+		suggestions = append(suggestions, result.Name)
+	}
+
+	env := types.Envelope{
+		"suggestions": suggestions,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getSimilarItemsHandler is the handler for the "GET /v1/items/:id/similar" endpoint
+func (app *Application) getSimilarItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving similar items")
+	defer span.End()
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("id", chi.URLParamFromCtx(r.Context(), "id")))
+
+		// Throw Not found error if extracted id is not a valid ObjectID
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	// Record item id in the trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	// Instantiate validator
+	v := validation.New()
+
+	limit := app.readIntFromQueryString(r.URL.Query(), "limit", 5, v)
+	v.Check(validator.Between(limit, 1, 20), "limit", "out_of_range", "must be greater or equal to 1 and lower or equal to 20")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Retrieve item with given id
+	item, err := app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// Retrieve the most similar items
+	items := []data.SimilarItem{}
+
+	err = app.ItemsRepository.Aggregate(ctx, data.SimilarItemsPipeline(item, time.Now().UTC(), limit), &items)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"items": items,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getTrendingItemsHandler is the handler for the "GET /v1/items/trending" endpoint
+func (app *Application) getTrendingItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving trending items")
+	defer span.End()
+
+	// Instantiate validator
+	v := validation.New()
+
+	queryString := r.URL.Query()
+
+	days := app.readIntFromQueryString(queryString, "days", app.Settings.Popularity.TrendingDays, v)
+	limit := app.readIntFromQueryString(queryString, "limit", 10, v)
+
+	v.Check(
+		validator.Between(days, 1, settings.PopularityRetentionDays),
+		"days",
+		"out_of_range",
+		fmt.Sprintf("must be greater or equal to 1 and lower or equal to %d", settings.PopularityRetentionDays),
+	)
+	v.Check(validator.Between(limit, 1, 50), "limit", "out_of_range", "must be greater or equal to 1 and lower or equal to 50")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("days", days))
+
+	// Today is included in the trending period
+	now := time.Now().UTC()
+	since := now.AddDate(0, 0, 1-days)
+
+	items, err := app.PopularityStore.Trending(ctx, app.contextTenant(ctx), since, now, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"items": items,
+		"days":  days,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getRandomItemsHandler is the handler for the "GET /v1/items/random" endpoint.
+// It picks random items among the items having all the given tags (i.e. a rarity or a category) and price range.
+func (app *Application) getRandomItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving random items")
+	defer span.End()
+
+	// Instantiate validator
+	v := validation.New()
+
+	queryString := r.URL.Query()
+
+	// The price range is read and validated like the one of "GET /v1/items"
+	input := itemsQuery{
+		MinPrice: app.readFloatFromQueryString(queryString, "min_price", database.DefaultPrice, v),
+		MaxPrice: app.readFloatFromQueryString(queryString, "max_price", database.DefaultPrice, v),
+	}
+	tags := app.ReadCsvFromQueryString(queryString, "tags", []string{})
+	limit := app.readIntFromQueryString(queryString, "limit", 1, v)
+
+	pricing := app.Settings.Pricing
+	priceRangeMessage := fmt.Sprintf(
+		"must be greater or equal to %s or lower and equal to %s",
+		data.FormatPrice(pricing.MinPrice),
+		data.FormatPrice(pricing.MaxPrice),
+	)
+
+	v.Check(validator.Between(input.MinPrice, pricing.MinPrice, pricing.MaxPrice), "min_price", "out_of_range", priceRangeMessage)
+	v.Check(validator.Between(input.MaxPrice, pricing.MinPrice, pricing.MaxPrice), "max_price", "out_of_range", priceRangeMessage)
+
+	// Only run this check if both min_price and max_price have been set
+	if input.MinPrice != database.DefaultPrice && input.MaxPrice != database.DefaultPrice {
+		v.Check(input.MaxPrice >= input.MinPrice, "max_price", "invalid_range", "must be greater or equal to specified min_price")
+	}
+
+	v.Check(validator.NoDuplicates(tags), "tags", "duplicate", "must not contain duplicate values")
+
+	for _, tag := range tags {
+		v.Check(validator.NotBlank(tag), "tags", "blank", "must not contain empty values")
+	}
+
+	v.Check(validator.Between(limit, 1, 50), "limit", "out_of_range", "must be greater or equal to 1 and lower or equal to 50")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.StringSlice("tags", tags), attribute.Int("limit", limit))
+
+	// Expired items are never picked
+	filter := input.mongoFilter()
+	if len(tags) != 0 {
+		filter["tags"] = bson.M{"$all": tags}
+	}
+
+	items := []data.Item{}
+
+	err := app.ItemsRepository.Aggregate(ctx, data.RandomItemsPipeline(filter, limit), &items)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	// Every request picks other items so the responses must not be cached
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "no-store")
+
+	env := types.Envelope{
+		"items": items,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, headers)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getItemHandler is the handler for the "GET /v1/items/:id" endpoint
 func (app *Application) getItemHandler(w http.ResponseWriter, r *http.Request) {
 	// Create trace for the handler
 	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item")
@@ -138,6 +612,22 @@ func (app *Application) getItemHandler(w http.ResponseWriter, r *http.Request) {
 	// Record item id in the trace
 	span.SetAttributes(attribute.String("id", id.Hex()))
 
+	// Instantiate validator
+	v := validation.New()
+
+	expandStock := app.readExpandStock(r.URL.Query(), v)
+	renderHTML := app.readRenderHTML(r.URL.Query(), v)
+
+	public := app.contextGetPublic(r)
+	v.Check(!public || !expandStock, "expand", "not_allowed", "stock requires authentication")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
 	// Retrieve item with given id
 	item, err := app.ItemsRepository.GetByID(ctx, id)
 	if err != nil {
@@ -154,11 +644,51 @@ func (app *Application) getItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Count the view without waiting for the database
+	app.PopularityCounter.RecordView(item.ID)
+
+	// Include the entity tag and the last modification date of the item so that clients can detect changes
+	headers := make(http.Header)
+	headers.Set("ETag", item.ETag())
+	headers.Set("Last-Modified", item.UpdatedAt.UTC().Format(http.TimeFormat))
+
+	// Render the Markdown description if requested
+	if renderHTML {
+		item = app.renderDescription(item)
+	}
+
+	// Flag the item if the user added it to their favorites
+	item, err = app.setItemFavorited(ctx, r, item)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
 	env := types.Envelope{
 		"item": item,
 	}
 
-	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	// Anonymous requests only retrieve the public fields of the item
+	if public {
+		env["item"] = item.SelectFields(app.Settings.PublicCatalog.Fields)
+	}
+
+	// Embed the stock of the item if requested
+	if expandStock {
+		expanded, err := app.withStock(ctx, r, []data.Item{item})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		env["item"] = expanded[0]
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, headers)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -166,7 +696,143 @@ func (app *Application) getItemHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// createItemHandler is the handler for the "POST /items" endpoint
+// getItemByExternalIDHandler is the handler for the "GET /v1/items/external/:externalId" endpoint
+func (app *Application) getItemByExternalIDHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item by external id")
+	defer span.End()
+
+	// Extract external id parameter from request URL parameters
+	externalID := chi.URLParamFromCtx(r.Context(), "externalId")
+	span.SetAttributes(attribute.String("external_id", externalID))
+
+	// Throw Not found error if extracted id is not a valid UUID
+	if !validator.Matches(externalID, data.UUIDRegex) {
+		span.SetStatus(codes.Error, "Invalid external id")
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	// Instantiate validator
+	v := validation.New()
+
+	expandStock := app.readExpandStock(r.URL.Query(), v)
+	renderHTML := app.readRenderHTML(r.URL.Query(), v)
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Retrieve item with given external id
+	item, err := app.ItemsRepository.GetByFilter(ctx, bson.M{"external_id": data.NormalizeExternalID(externalID)})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// Count the view without waiting for the database
+	app.PopularityCounter.RecordView(item.ID)
+
+	// Include the entity tag and the last modification date of the item so that clients can detect changes
+	headers := make(http.Header)
+	headers.Set("ETag", item.ETag())
+	headers.Set("Last-Modified", item.UpdatedAt.UTC().Format(http.TimeFormat))
+
+	// Render the Markdown description if requested
+	if renderHTML {
+		item = app.renderDescription(item)
+	}
+
+	// Flag the item if the user added it to their favorites
+	item, err = app.setItemFavorited(ctx, r, item)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"item": item,
+	}
+
+	// Embed the stock of the item if requested
+	if expandStock {
+		expanded, err := app.withStock(ctx, r, []data.Item{item})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		env["item"] = expanded[0]
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, headers)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// headItemHandler is the handler for the "HEAD /v1/items/:id" endpoint.
+// It lets other services check that an item exists without retrieving it.
+func (app *Application) headItemHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Checking item existence")
+	defer span.End()
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		// Record error in the trace
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("id", chi.URLParamFromCtx(r.Context(), "id")))
+
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Record item id in the trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	// Retrieve item with given id
+	item, err := app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	w.Header().Set("ETag", item.ETag())
+	w.Header().Set("Last-Modified", item.UpdatedAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+// createItemHandler is the handler for the "POST /v1/items" endpoint
 func (app *Application) createItemHandler(w http.ResponseWriter, r *http.Request) {
 	// Create trace for the handler
 	ctx, span := app.Tracer.Start(r.Context(), "Creating item")
@@ -175,13 +841,16 @@ func (app *Application) createItemHandler(w http.ResponseWriter, r *http.Request
 	// Declare an anonymous struct to hold the information that we expect to be in the
 	// request body. This struct will be our *target decode destination*
 	var input struct {
-		Name        string  `json:"name"`
-		Description string  `json:"description"`
-		Price       float64 `json:"price"`
+		ExternalID  string     `json:"external_id"`
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		Price       float64    `json:"price"`
+		Tags        []string   `json:"tags"`
+		ExpiresAt   *time.Time `json:"expires_at"`
 	}
 
 	// Read request body and decode it into the input struct
-	err := app.ReadJSON(w, r, &input)
+	err := app.readJSON(w, r, &input)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -191,26 +860,35 @@ func (app *Application) createItemHandler(w http.ResponseWriter, r *http.Request
 
 	// Copy the values from the input struct to a new Item struct
 	item := data.Item{
+		ExternalID:  data.NormalizeExternalID(input.ExternalID),
 		Name:        input.Name,
-		Description: input.Description,
+		Description: app.Sanitizer.Sanitize(input.Description),
 		Price:       input.Price,
+		Tags:        input.Tags,
+		ExpiresAt:   utcTime(input.ExpiresAt),
+		CreatedBy:   app.ContextGetUser(r).ID,
 		Version:     1,
 		CreatedAt:   time.Now().UTC(),
 		UpdatedAt:   time.Now().UTC(),
 	}
 
 	// Initialize a new Validator instance
-	v := validator.New()
+	v := validation.New()
 
 	// Perform validation checks
-	data.ValidateItem(v, item)
+	data.ValidateItem(v, item, app.Settings.Pricing, app.Settings.Moderation)
+	validateExpiresAt(v, item.ExpiresAt)
 
 	if v.HasErrors() {
 		span.SetStatus(codes.Error, "Validation failed")
-		app.FailedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
+	// Apply auto-tagging rules
+	item = app.TaggingEngine.Apply(item)
+	item = data.FlagForReview(item, app.Settings.Moderation)
+
 	// Record item attributes in trace
 	span.SetAttributes(
 		attribute.String("name", item.Name),
@@ -223,14 +901,21 @@ func (app *Application) createItemHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		app.ServerErrorResponse(w, r, err)
+
+		switch {
+		case errors.Is(err, database.ErrDuplicateKey):
+			app.duplicateKeyResponse(w, r, err, "an item")
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
 		return
 	}
 
 	// When sending a HTTP response, we want to include a Location header to let the
 	// client know which URL they can find the newly-created resource at
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/items/%s", id.Hex()))
+	headers.Set("Location", fmt.Sprintf("/v1/items/%s", id.Hex()))
 
 	env := types.Envelope{
 		"message": "Item created successfully",
@@ -244,7 +929,7 @@ func (app *Application) createItemHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
-// updateItemHandler is the handler for the "PUT /items/:id" endpoint
+// updateItemHandler is the handler for the "PUT /v1/items/:id" endpoint
 func (app *Application) updateItemHandler(w http.ResponseWriter, r *http.Request) {
 	// Create trace for the handler
 	ctx, span := app.Tracer.Start(r.Context(), "Updating item")
@@ -281,16 +966,35 @@ func (app *Application) updateItemHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Check that the user is allowed to change the item
+	if !app.canModifyItem(r, item) {
+		span.SetStatus(codes.Error, "Not the owner of the item")
+		app.notOwnerResponse(w, r)
+		return
+	}
+
+	// Reject the changes if the item was modified after the date given by the client
+	if !unmodifiedSince(r, item) {
+		span.SetStatus(codes.Error, "Item modified since the given date")
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
 	// We use pointers so that we get a nil value when decoding these values from JSON.
 	// This way we can check if a user has provided the key/value pair in the JSON or not.
 	var input struct {
-		Name        *string  `json:"name"`
-		Description *string  `json:"description"`
-		Price       *float64 `json:"price"`
+		Name        *string   `json:"name"`
+		Description *string   `json:"description"`
+		Price       *float64  `json:"price"`
+		Tags        *[]string `json:"tags"`
+		// A raw value is used to tell a missing expiration date apart from a null one, which clears it
+		ExpiresAt json.RawMessage `json:"expires_at"`
+		// Version of the item the client based its changes on, for the clients without ETag support
+		ExpectedVersion *int32 `json:"expected_version"`
 	}
 
 	// Read request body and decode it into the input struct
-	err = app.ReadJSON(w, r, &input)
+	err = app.readJSON(w, r, &input)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -298,34 +1002,61 @@ func (app *Application) updateItemHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Reject the changes based on another version of the item to avoid lost updates
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != item.Version {
+		span.SetStatus(codes.Error, "Stale item version")
+		app.staleVersionResponse(w, r, item)
+		return
+	}
+
 	// Copy the values from the input struct to the fetched item if they exist
 	if input.Name != nil {
 		item.Name = *input.Name
 	}
 
 	if input.Description != nil {
-		item.Description = *input.Description
+		item.Description = app.Sanitizer.Sanitize(*input.Description)
 	}
 
 	if input.Price != nil {
 		item.Price = *input.Price
 	}
 
-	// Update item's updated at date
-	item.UpdatedAt = time.Now().UTC()
+	if input.Tags != nil {
+		item = tagging.SetManualTags(item, *input.Tags)
+	}
 
 	// Initialize a new Validator instance
-	v := validator.New()
+	v := validation.New()
+
+	if input.ExpiresAt != nil {
+		var expiresAt *time.Time
+
+		err = json.Unmarshal(input.ExpiresAt, &expiresAt)
+		if err != nil {
+			v.AddError("expires_at", "invalid_format", "must be a valid RFC3339 timestamp or null")
+		} else {
+			item.ExpiresAt = utcTime(expiresAt)
+			validateExpiresAt(v, item.ExpiresAt)
+		}
+	}
+
+	// Update item's updated at date
+	item.UpdatedAt = time.Now().UTC()
 
 	// Perform validation checks
-	data.ValidateItem(v, item)
+	data.ValidateItem(v, item, app.Settings.Pricing, app.Settings.Moderation)
 
 	if v.HasErrors() {
 		span.SetStatus(codes.Error, "Validation failed")
-		app.FailedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
+	// Re-evaluate auto-tagging rules and moderation against the updated item
+	item = app.TaggingEngine.Apply(item)
+	item = data.FlagForReview(item, app.Settings.Moderation)
+
 	// Update item in the database
 	err = app.ItemsRepository.Update(ctx, item)
 	if err != nil {
@@ -335,6 +1066,8 @@ func (app *Application) updateItemHandler(w http.ResponseWriter, r *http.Request
 		switch {
 		case errors.Is(err, database.ErrEditConflict):
 			app.EditConflictResponse(w, r)
+		case errors.Is(err, database.ErrDuplicateKey):
+			app.duplicateKeyResponse(w, r, err, "an item")
 		default:
 			app.ServerErrorResponse(w, r, err)
 		}
@@ -354,7 +1087,7 @@ func (app *Application) updateItemHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
-// deleteItemHandler is the handler for the "DELETE /items/:id" endpoint
+// deleteItemHandler is the handler for the "DELETE /v1/items/:id" endpoint
 func (app *Application) deleteItemHandler(w http.ResponseWriter, r *http.Request) {
 	// Create trace for the handler
 	ctx, span := app.Tracer.Start(r.Context(), "Deleting item")
@@ -375,6 +1108,37 @@ func (app *Application) deleteItemHandler(w http.ResponseWriter, r *http.Request
 	// Record item id in the trace
 	span.SetAttributes(attribute.String("id", id.Hex()))
 
+	// Check that the user is allowed to delete the item and that it was not modified after the date given by the client.
+	// The item is only needed in "owner" authorization mode or with an If-Unmodified-Since header.
+	if app.Settings.Authorization.Mode == "owner" || r.Header.Get("If-Unmodified-Since") != "" {
+		item, err := app.ItemsRepository.GetByID(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			switch {
+			case errors.Is(err, database.ErrRecordNotFound):
+				app.NotFoundResponse(w, r)
+			default:
+				app.ServerErrorResponse(w, r, err)
+			}
+
+			return
+		}
+
+		if !app.canModifyItem(r, item) {
+			span.SetStatus(codes.Error, "Not the owner of the item")
+			app.notOwnerResponse(w, r)
+			return
+		}
+
+		if !unmodifiedSince(r, item) {
+			span.SetStatus(codes.Error, "Item modified since the given date")
+			app.preconditionFailedResponse(w, r)
+			return
+		}
+	}
+
 	// Delete item in the database
 	err = app.ItemsRepository.Delete(ctx, id)
 	if err != nil {
@@ -2,12 +2,20 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/inventory"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -27,6 +35,12 @@ func TestHealthCheckHandler(t *testing.T) {
 	if !bytes.Contains(resBody, []byte("available")) {
 		t.Errorf("want body %q to contain %q", []byte("available"), resBody)
 	}
+
+	for _, field := range []string{`"environment": "dev"`, `"version": "dev"`, `"uptime": "`} {
+		if !bytes.Contains(resBody, []byte(field)) {
+			t.Errorf("want body %q to contain %q", resBody, field)
+		}
+	}
 }
 
 func TestCreateItemHandler(t *testing.T) {
@@ -57,7 +71,7 @@ func TestCreateItemHandler(t *testing.T) {
 			body["description"] = "Restores a small amount of health"
 			body["price"] = 5
 
-			statusCode, _, resBody := ts.post(t, "/items", body, tt.useAuthHeader, tt.accessToken)
+			statusCode, _, resBody := ts.post(t, "/v1/items", body, tt.useAuthHeader, tt.accessToken)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -80,10 +94,15 @@ func TestCreateItemHandler(t *testing.T) {
 		wantedResponseBody []byte
 	}{
 		{"Valid submission", "Potion", "Restores a small amount of health", 5, http.StatusCreated, []byte("Item created successfully")},
+		{"Duplicate name", "Potion", "Restores a large amount of health", 5, http.StatusConflict, []byte("an item with this name already exists")},
+		{"Shared description", "Elixir", "Restores a small amount of health", 5, http.StatusCreated, []byte("Item created successfully")},
 		{"Empty name", "", "Restores a small amount of health", 5, http.StatusUnprocessableEntity, []byte("must be provided")},
 		{"Empty description", "Potion", "", 5, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"Sanitized description", "Ether", "<b>Restores</b> mana<script>alert(1)</script>", 5, http.StatusCreated, []byte("Item created successfully")},
+		{"Description emptied by sanitization", "Potion", "<script>alert(1)</script>", 5, http.StatusUnprocessableEntity, []byte("must be provided")},
 		{"Invalid price value (below 0.1)", "Potion", "Restores a small amount of health", 0, http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 and lower or equal to 1000")},
 		{"Invalid price value (above 1000.0)", "Potion", "Restores a small amount of health", 1001, http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 and lower or equal to 1000")},
+		{"Invalid price precision", "Potion", "Restores a small amount of health", 5.125, http.StatusUnprocessableEntity, []byte("must not have more than 2 decimal places")},
 	}
 
 	for _, tt := range validationTests {
@@ -93,7 +112,40 @@ func TestCreateItemHandler(t *testing.T) {
 			body["description"] = tt.description
 			body["price"] = tt.price
 
-			statusCode, _, resBody := ts.post(t, "/items", body, true, accessTokenUser1)
+			statusCode, _, resBody := ts.post(t, "/v1/items", body, true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// -----------------------------
+
+	expirationTests := []struct {
+		testName           string
+		name               string
+		expiresAt          string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Expiration date in the future", "Promotional potion", time.Now().Add(time.Hour).Format(time.RFC3339), http.StatusCreated, []byte("Item created successfully")},
+		{"Expiration date in the past", "Expired potion", time.Now().Add(-time.Hour).Format(time.RFC3339), http.StatusUnprocessableEntity, []byte("must be in the future")},
+	}
+
+	for _, tt := range expirationTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			body := map[string]any{}
+			body["name"] = tt.name
+			body["description"] = "Only sold during the event"
+			body["price"] = 5
+			body["expires_at"] = tt.expiresAt
+
+			statusCode, _, resBody := ts.post(t, "/v1/items", body, true, accessTokenUser1)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -128,7 +180,7 @@ func TestCreateItemHandler(t *testing.T) {
 	body["description"] = malformedJSONTest.description
 	body["price"] = malformedJSONTest.price
 
-	statusCode, _, resBody := ts.post(t, "/items", body, true, accessTokenUser1)
+	statusCode, _, resBody := ts.post(t, "/v1/items", body, true, accessTokenUser1)
 
 	if statusCode != malformedJSONTest.wantedStatusCode {
 		t.Errorf("want %d; got %d", malformedJSONTest.wantedStatusCode, statusCode)
@@ -161,7 +213,7 @@ func TestCreateItemHandler(t *testing.T) {
 	body["description"] = unknownKeyTest.description
 	body["invalid"] = unknownKeyTest.invalid
 
-	statusCode, _, resBody = ts.post(t, "/items", body, true, accessTokenUser1)
+	statusCode, _, resBody = ts.post(t, "/v1/items", body, true, accessTokenUser1)
 
 	if statusCode != unknownKeyTest.wantedStatusCode {
 		t.Errorf("want %d; got %d", unknownKeyTest.wantedStatusCode, statusCode)
@@ -170,6 +222,39 @@ func TestCreateItemHandler(t *testing.T) {
 	if !bytes.Contains(resBody, unknownKeyTest.wantedResponseBody) {
 		t.Errorf("want body %q to contain %q", resBody, unknownKeyTest.wantedResponseBody)
 	}
+
+	// -----------------------------
+
+	bodyTooLargeTest := struct {
+		testName           string
+		name               string
+		description        string
+		price              float64
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		"Body too large",
+		"Potion",
+		strings.Repeat("a", int(app.Settings.BodyLimits.Items)),
+		5,
+		http.StatusBadRequest,
+		[]byte(fmt.Sprintf("body must not be larger than %d bytes", app.Settings.BodyLimits.Items)),
+	}
+
+	body = map[string]any{}
+	body["name"] = bodyTooLargeTest.name
+	body["description"] = bodyTooLargeTest.description
+	body["price"] = bodyTooLargeTest.price
+
+	statusCode, _, resBody = ts.post(t, "/v1/items", body, true, accessTokenUser1)
+
+	if statusCode != bodyTooLargeTest.wantedStatusCode {
+		t.Errorf("want %d; got %d", bodyTooLargeTest.wantedStatusCode, statusCode)
+	}
+
+	if !bytes.Contains(resBody, bodyTooLargeTest.wantedResponseBody) {
+		t.Errorf("want body %q to contain %q", resBody, bodyTooLargeTest.wantedResponseBody)
+	}
 }
 
 func TestGetItemsHandler(t *testing.T) {
@@ -197,7 +282,7 @@ func TestGetItemsHandler(t *testing.T) {
 
 	for _, tt := range authenticationTests {
 		t.Run(tt.testName, func(t *testing.T) {
-			statusCode, _, resBody := ts.get(t, "/items", tt.useAuthHeader, tt.accessToken)
+			statusCode, _, resBody := ts.get(t, "/v1/items", tt.useAuthHeader, tt.accessToken)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -222,10 +307,23 @@ func TestGetItemsHandler(t *testing.T) {
 		{"Invalid page", "?page=invalid", http.StatusUnprocessableEntity, []byte("must be an integer value")},
 		{"Invalid page_size", "?page_size=invalid", http.StatusUnprocessableEntity, []byte("must be an integer value")},
 		{"Invalid sort value", "?sort=invalid", http.StatusUnprocessableEntity, []byte("invalid sort value")},
+		{"Invalid sort value error code", "?sort=invalid", http.StatusUnprocessableEntity, []byte(`"sort": "sort.unsupported_value"`)},
+		{"Invalid sort value in sort list", "?sort=name,invalid", http.StatusUnprocessableEntity, []byte("invalid sort value")},
+		{"Relevance sort without name", "?sort=relevance", http.StatusUnprocessableEntity, []byte("relevance can only be used along with a name search")},
+		{"Same sort field twice", "?sort=price,-price", http.StatusUnprocessableEntity, []byte("must not contain the same field more than once")},
 		{"min_price lower than 0.1", "?min_price=0", http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 or lower and equal to 1000")},
 		{"min_price greater than 1000", "?min_price=1001", http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 or lower and equal to 1000")},
 		{"max_price lower than 0.1", "?max_price=0", http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 or lower and equal to 1000")},
 		{"max_price greater than 1000", "?max_price=1001", http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 or lower and equal to 1000")},
+		{"Invalid created_after", "?created_after=yesterday", http.StatusUnprocessableEntity, []byte("must be a valid RFC3339 timestamp")},
+		{"Invalid updated_before", "?updated_before=2022-10-01", http.StatusUnprocessableEntity, []byte("must be a valid RFC3339 timestamp")},
+		{"created_before earlier than created_after", "?created_after=2022-10-02T00:00:00Z&created_before=2022-10-01T00:00:00Z", http.StatusUnprocessableEntity, []byte("must be later than specified created_after")},
+		{"updated_before earlier than updated_after", "?updated_after=2022-10-02T00:00:00Z&updated_before=2022-10-01T00:00:00Z", http.StatusUnprocessableEntity, []byte("must be later than specified updated_after")},
+		{"Invalid facets value", "?facets=tag,invalid", http.StatusUnprocessableEntity, []byte("invalid facets value")},
+		{"Duplicate facets value", "?facets=price,price", http.StatusUnprocessableEntity, []byte("must not contain duplicate values")},
+		{"Invalid include_total", "?include_total=maybe", http.StatusUnprocessableEntity, []byte("must be a boolean value")},
+		{"Invalid fields value", "?fields=name,invalid", http.StatusUnprocessableEntity, []byte("invalid fields value")},
+		{"Duplicate fields value", "?fields=name,name", http.StatusUnprocessableEntity, []byte("must not contain duplicate values")},
 		{"page lower than 0", "?page=-1", http.StatusUnprocessableEntity, []byte("must be greater or equal to 0 and lower or equal to 10 million")},
 		{"page greater than 10000000", "?page=10000001", http.StatusUnprocessableEntity, []byte("must be greater or equal to 0 and lower or equal to 10 million")},
 		{"page_size lower than 0", "?page_size=-1", http.StatusUnprocessableEntity, []byte("must be greater or equal to 0 and lower or equal to 100")},
@@ -234,7 +332,7 @@ func TestGetItemsHandler(t *testing.T) {
 
 	for _, tt := range validationTests {
 		t.Run(tt.testName, func(t *testing.T) {
-			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/items%s", tt.queryString), true, accessTokenUser1)
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items%s", tt.queryString), true, accessTokenUser1)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -264,11 +362,15 @@ func TestGetItemsHandler(t *testing.T) {
 		{"name, page and page_size filters (page 1)", "?name=potion&page=1&page_size=2", http.StatusOK, 2, 1, 2},
 		{"name, page and page_size filters (page 2)", "?name=potion&page=2&page_size=2", http.StatusOK, 1, 2, 2},
 		{"name and sort filters", "?name=potion&sort=-name", http.StatusOK, 3, 1, 1},
+		{"created_after filter", "?created_after=2022-10-01T00:00:00Z", http.StatusOK, 5, 1, 1},
+		{"name and updated_after filters", "?name=potion&updated_after=2022-10-01T00:00:00Z", http.StatusOK, 3, 1, 1},
+		{"name and relevance sort filters", "?name=potion&sort=relevance", http.StatusOK, 3, 1, 1},
+		{"name and multiple sort filters", "?name=potion&sort=-price,name", http.StatusOK, 3, 1, 1},
 	}
 
 	for _, tt := range successTests {
 		t.Run(tt.testName, func(t *testing.T) {
-			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/items%s", tt.queryString), true, accessTokenUser1)
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items%s", tt.queryString), true, accessTokenUser1)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -300,6 +402,15 @@ func TestGetItemsHandler(t *testing.T) {
 				}
 			}
 
+			if tt.testName == "name and multiple sort filters" {
+				for i, name := range []string{"Mega Potion", "Hi-Potion", "Potion"} {
+					item := (items[i]).(map[string]any)
+					if item["name"] != name {
+						t.Errorf("want to receive %s but got %s", name, item["name"])
+					}
+				}
+			}
+
 			if len(items) != tt.expectedTotalRecords {
 				t.Errorf("want to receive %d items but got %d", tt.expectedTotalRecords, len(items))
 			}
@@ -319,41 +430,134 @@ func TestGetItemsHandler(t *testing.T) {
 			}
 		})
 	}
+
+	// -----------------------------
+
+	facetsTests := []struct {
+		testName           string
+		queryString        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Price facet", "?facets=price", http.StatusOK, []byte(`"max_price": 10`)},
+		{"Tag facet", "?facets=tag", http.StatusOK, []byte(`"tags": [`)},
+		{"Facets of filtered items", "?name=potion&facets=tag,price", http.StatusOK, []byte(`"price": [`)},
+	}
+
+	for _, tt := range facetsTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items%s", tt.queryString), true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// -----------------------------
+
+	t.Run("Selected fields", func(t *testing.T) {
+		statusCode, _, resBody := ts.get(t, "/v1/items?fields=id,name,price", true, accessTokenUser1)
+
+		if statusCode != http.StatusOK {
+			t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		var jsonRes struct {
+			Items []map[string]any `json:"items"`
+		}
+
+		err := json.Unmarshal(resBody, &jsonRes)
+		if err != nil {
+			t.Error("Failed to parse json response")
+		}
+
+		for _, item := range jsonRes.Items {
+			if len(item) != 3 || item["id"] == nil || item["name"] == nil || item["price"] == nil {
+				t.Errorf("want item to only contain id, name and price but got %v", item)
+			}
+		}
+	})
+
+	// -----------------------------
+
+	countTests := []struct {
+		testName        string
+		queryString     string
+		expectedItems   int
+		expectedHasMore bool
+	}{
+		{"More pages", "?include_total=false&page_size=2", 2, true},
+		{"Last page", "?include_total=false&page=3&page_size=2", 1, false},
+	}
+
+	for _, tt := range countTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items%s", tt.queryString), true, accessTokenUser1)
+
+			if statusCode != http.StatusOK {
+				t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+			}
+
+			var jsonRes struct {
+				Items    []map[string]any `json:"items"`
+				Metadata map[string]any   `json:"metadata"`
+			}
+
+			err := json.Unmarshal(resBody, &jsonRes)
+			if err != nil {
+				t.Error("Failed to parse json response")
+			}
+
+			if len(jsonRes.Items) != tt.expectedItems {
+				t.Errorf("want to receive %d items but got %d", tt.expectedItems, len(jsonRes.Items))
+			}
+
+			if jsonRes.Metadata["has_more"] != tt.expectedHasMore {
+				t.Errorf("want has_more to be %t but got %v", tt.expectedHasMore, jsonRes.Metadata["has_more"])
+			}
+
+			if _, ok := jsonRes.Metadata["total_records"]; ok {
+				t.Errorf("want total_records to be omitted but got %v", jsonRes.Metadata["total_records"])
+			}
+		})
+	}
 }
 
-func TestGetItemHandler(t *testing.T) {
+func TestGetItemsStatsHandler(t *testing.T) {
 	app, cleanup := newTestApplication(t)
 	t.Cleanup(cleanup)
 
 	ts := newTestServer(t, app.routes())
 	defer ts.Close()
 
-	// Create an item and retrieve its id
-	itemName := "Potion"
-	body := map[string]any{}
-	body["name"] = itemName
-	body["description"] = "Restores a small amount of health"
-	body["price"] = 5
-
-	_, headers, _ := ts.post(t, "/items", body, true, accessTokenUser1)
-	itemID := strings.Split(headers.Get("Location"), "/")[2]
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
 
-	authenticationTests := []struct {
+	tests := []struct {
 		testName           string
+		queryString        string
 		useAuthHeader      bool
 		accessToken        string
 		wantedStatusCode   int
 		wantedResponseBody []byte
 	}{
-		{"No Authorization header", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
-		{"Invalid access token", true, "invalid", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
-		{"Access token not generated by identity microservice", true, invalidAccessToken, http.StatusUnauthorized, []byte("invalid or missing authentication token")},
-		{"User does not have permission - has inventory:read", true, accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"No Authorization header", "", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has inventory:read", "", true, accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Invalid group_by value", "?group_by=invalid", true, accessTokenUser2, http.StatusUnprocessableEntity, []byte("invalid group_by value")},
+		{"Invalid min_price", "?min_price=invalid", true, accessTokenUser2, http.StatusUnprocessableEntity, []byte("must be a float64 value")},
+		{"All items", "", true, accessTokenUser2, http.StatusOK, []byte(`"median_price": 5`)},
+		{"Filtered items", "?name=potion", true, accessTokenUser2, http.StatusOK, []byte(`"median_price": 7`)},
+		{"Grouped by tag", "?group_by=tag", true, accessTokenUser2, http.StatusOK, []byte(`"groups": [`)},
 	}
 
-	for _, tt := range authenticationTests {
+	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/items/%s", itemID), tt.useAuthHeader, tt.accessToken)
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/stats%s", tt.queryString), tt.useAuthHeader, tt.accessToken)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -364,23 +568,39 @@ func TestGetItemHandler(t *testing.T) {
 			}
 		})
 	}
+}
 
-	// -----------------------------
+func TestGetSimilarItemsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
 
-	validationTests := []struct {
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+
+	potion, err := app.ItemsRepository.GetByFilter(context.Background(), bson.M{"name": "Potion"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
 		testName           string
-		id                 string
+		urlPath            string
+		accessToken        string
 		wantedStatusCode   int
 		wantedResponseBody []byte
 	}{
-		{"Invalid id (int)", "5", http.StatusNotFound, []byte("The requested resource could not be found")},
-		{"Invalid id (uuid)", "73f4fa2d-f134-4351-a14e-a0f0a8541712", http.StatusNotFound, []byte("The requested resource could not be found")},
-		{"Unknown id", primitive.NewObjectID().Hex(), http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"User does not have permission - has inventory:read", fmt.Sprintf("/v1/items/%s/similar", potion.ID.Hex()), accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Non-existent ID", fmt.Sprintf("/v1/items/%s/similar", primitive.NewObjectID().Hex()), accessTokenUser2, http.StatusNotFound, []byte("the requested resource could not be found")},
+		{"Invalid limit", fmt.Sprintf("/v1/items/%s/similar?limit=50", potion.ID.Hex()), accessTokenUser2, http.StatusUnprocessableEntity, []byte("must be greater or equal to 1 and lower or equal to 20")},
+		{"Valid request", fmt.Sprintf("/v1/items/%s/similar", potion.ID.Hex()), accessTokenUser2, http.StatusOK, []byte(`"score":`)},
 	}
 
-	for _, tt := range validationTests {
+	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/items/%s", tt.id), true, accessTokenUser1)
+			statusCode, _, resBody := ts.get(t, tt.urlPath, true, tt.accessToken)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -392,82 +612,79 @@ func TestGetItemHandler(t *testing.T) {
 		})
 	}
 
-	// -----------------------------
-
-	successTest := struct {
-		testName         string
-		wantedStatusCode int
-	}{
-		"Valid submission",
-		http.StatusOK,
-	}
-
-	statusCode, _, resBody := ts.get(t, fmt.Sprintf("/items/%s", itemID), true, accessTokenUser1)
-
-	if statusCode != successTest.wantedStatusCode {
-		t.Errorf("want %d; got %d", successTest.wantedStatusCode, statusCode)
-	}
+	t.Run("Most similar item first", func(t *testing.T) {
+		_, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s/similar", potion.ID.Hex()), true, accessTokenUser2)
 
-	var jsonRes map[string]any
+		var response struct {
+			Items []data.SimilarItem `json:"items"`
+		}
 
-	err := json.Unmarshal(resBody, &jsonRes)
-	if err != nil {
-		t.Error("Failed to parse json response")
-	}
+		err := json.Unmarshal(resBody, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	item := (jsonRes["item"]).(map[string]any)
+		// Shares the "potion" word and has the closest price among the potions
+		if len(response.Items) == 0 || response.Items[0].Name != "Hi-Potion" {
+			t.Errorf("want first similar item to be %q; got %v", "Hi-Potion", response.Items)
+		}
 
-	if item["name"] != itemName {
-		t.Errorf("want to receive %s but got %s", itemName, item["name"])
-	}
+		for _, item := range response.Items {
+			if item.ID == potion.ID {
+				t.Errorf("want item itself to be excluded")
+			}
+		}
+	})
 }
 
-func TestUpdateItemHandler(t *testing.T) {
+func TestGetTrendingItemsHandler(t *testing.T) {
 	app, cleanup := newTestApplication(t)
 	t.Cleanup(cleanup)
 
 	ts := newTestServer(t, app.routes())
 	defer ts.Close()
 
-	// Create an item and retrieve its id
-	name := "Potion"
-	body := map[string]any{}
-	body["name"] = name
-	body["description"] = "Restores a small amount of health"
-	body["price"] = 5
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
 
-	_, headers, _ := ts.post(t, "/items", body, true, accessTokenUser1)
-	itemID := strings.Split(headers.Get("Location"), "/")[2]
+	ether, err := app.ItemsRepository.GetByFilter(context.Background(), bson.M{"name": "Ether"})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	// Fetch created item and make sure it exists in database
-	createdItem := fetchItem(t, app.ItemsRepository, itemID)
+	// Views are counted in memory until they are flushed
+	statusCode, _, _ := ts.get(t, fmt.Sprintf("/v1/items/%s", ether.ID.Hex()), true, accessTokenUser2)
+	if statusCode != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+	}
 
-	if createdItem.Name != name {
-		t.Errorf("want %s; got %s", name, createdItem.Name)
+	err = app.PopularityCounter.RecordPurchase(context.Background(), "purchase:1", ether.ID, 1)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	authenticationTests := []struct {
+	err = app.PopularityCounter.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
 		testName           string
-		useAuthHeader      bool
+		urlPath            string
 		accessToken        string
 		wantedStatusCode   int
 		wantedResponseBody []byte
 	}{
-		{"No Authorization header", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
-		{"Invalid access token", true, "invalid", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
-		{"Access token not generated by identity microservice", true, invalidAccessToken, http.StatusUnauthorized, []byte("invalid or missing authentication token")},
-		{"User does not have permission - has catalog:read", true, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
-		{"User does not have permission - has inventory:read", true, accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"User does not have permission - has inventory:read", "/v1/items/trending", accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Invalid days", "/v1/items/trending?days=90", accessTokenUser2, http.StatusUnprocessableEntity, []byte("must be greater or equal to 1 and lower or equal to 30")},
+		{"Invalid limit", "/v1/items/trending?limit=0", accessTokenUser2, http.StatusUnprocessableEntity, []byte("must be greater or equal to 1 and lower or equal to 50")},
+		{"Valid request", "/v1/items/trending?days=1", accessTokenUser2, http.StatusOK, []byte(`"name": "Ether"`)},
+		{"Sorted by popularity", "/v1/items?sort=-popularity&page_size=1", accessTokenUser2, http.StatusOK, []byte(`"name": "Ether"`)},
 	}
 
-	for _, tt := range authenticationTests {
+	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			body := map[string]any{}
-			body["name"] = "Potion"
-			body["description"] = "Restores a small amount of health"
-			body["price"] = 5
-
-			statusCode, _, resBody := ts.put(t, fmt.Sprintf("/items/%s", itemID), body, tt.useAuthHeader, tt.accessToken)
+			statusCode, _, resBody := ts.get(t, tt.urlPath, true, tt.accessToken)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -479,35 +696,99 @@ func TestUpdateItemHandler(t *testing.T) {
 		})
 	}
 
-	// -----------------------------
+	t.Run("Counts of the trending period", func(t *testing.T) {
+		_, _, resBody := ts.get(t, "/v1/items/trending", true, accessTokenUser2)
+
+		var response struct {
+			Items []data.TrendingItem `json:"items"`
+		}
+
+		err := json.Unmarshal(resBody, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Items) != 1 {
+			t.Fatalf("want %d trending items; got %d", 1, len(response.Items))
+		}
+
+		if response.Items[0].Views != 1 || response.Items[0].Purchases != 1 {
+			t.Errorf("want %d view and %d purchase; got %+v", 1, 1, response.Items[0])
+		}
+	})
+
+	t.Run("Items of the other tenants", func(t *testing.T) {
+		ctx := data.ContextWithTenant(context.Background(), "eu-1")
+
+		id, err := app.ItemsRepository.Create(ctx, data.Item{Name: "Elixir", Description: "Restores all HP and MP", Price: 20, Version: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app.PopularityCounter.RecordView(*id)
+		app.PopularityCounter.RecordView(*id)
+
+		err = app.PopularityCounter.Flush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Now().UTC()
+
+		tests := []struct {
+			tenant      string
+			wantedNames []string
+		}{
+			{app.Settings.Tenancy.DefaultTenant, []string{"Ether"}},
+			{"eu-1", []string{"Elixir"}},
+		}
+
+		for _, tt := range tests {
+			items, err := app.PopularityStore.Trending(context.Background(), tt.tenant, now, now, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := []string{}
+			for _, item := range items {
+				names = append(names, item.Name)
+			}
+
+			if !reflect.DeepEqual(names, tt.wantedNames) {
+				t.Errorf("want trending items %v of tenant %q; got %v", tt.wantedNames, tt.tenant, names)
+			}
+		}
+	})
+}
+
+func TestGetRandomItemsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
 
 	tests := []struct {
 		testName           string
-		idURLParam         string
-		name               string
-		description        string
-		price              float64
+		urlPath            string
+		accessToken        string
 		wantedStatusCode   int
 		wantedResponseBody []byte
 	}{
-		{"Valid submission", itemID, "Potion", "Restores a small amount of health", 7, http.StatusOK, []byte("Item updated successfully")},
-		{"Empty name", itemID, "", "Restores a small amount of health", 5, http.StatusUnprocessableEntity, []byte("must be provided")},
-		{"Empty description", itemID, "Potion", "", 5, http.StatusUnprocessableEntity, []byte("must be provided")},
-		{"Invalid price value (below 0.1)", itemID, "Potion", "Restores a small amount of health", 0, http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 and lower or equal to 1000")},
-		{"Invalid price value (above 1000.0)", itemID, "Potion", "Restores a small amount of health", 1001, http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 and lower or equal to 1000")},
-		{"Invalid id URL param (int)", "5", "Potion", "Restores a small amount of health", 5, http.StatusNotFound, []byte("The requested resource could not be found")},
-		{"Invalid id URL param (uuid)", "73f4fa2d-f134-4351-a14e-a0f0a8541712", "Potion", "Restores a small amount of health", 5, http.StatusNotFound, []byte("The requested resource could not be found")},
-		{"Unknown id URL param", primitive.NewObjectID().Hex(), "Potion", "Restores a small amount of health", 5, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"User does not have permission - has inventory:read", "/v1/items/random", accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Invalid limit", "/v1/items/random?limit=51", accessTokenUser2, http.StatusUnprocessableEntity, []byte("must be greater or equal to 1 and lower or equal to 50")},
+		{"Invalid price range", "/v1/items/random?min_price=7&max_price=5", accessTokenUser2, http.StatusUnprocessableEntity, []byte("must be greater or equal to specified min_price")},
+		{"Duplicate tags", "/v1/items/random?tags=rare,rare", accessTokenUser2, http.StatusUnprocessableEntity, []byte("must not contain duplicate values")},
+		{"Price range", "/v1/items/random?min_price=2&max_price=4", accessTokenUser2, http.StatusOK, []byte(`"name": "Ether"`)},
+		{"No matching item", "/v1/items/random?tags=legendary", accessTokenUser2, http.StatusOK, []byte(`"items": []`)},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			body := map[string]any{}
-			body["name"] = tt.name
-			body["description"] = tt.description
-			body["price"] = tt.price
-
-			statusCode, _, resBody := ts.put(t, fmt.Sprintf("/items/%s", tt.idURLParam), body, true, accessTokenUser1)
+			statusCode, _, resBody := ts.get(t, tt.urlPath, true, tt.accessToken)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -519,12 +800,500 @@ func TestUpdateItemHandler(t *testing.T) {
 		})
 	}
 
-	// Fetch item again and make sure it was updated in the database
-	updatedItem := fetchItem(t, app.ItemsRepository, itemID)
+	t.Run("Distinct items", func(t *testing.T) {
+		_, headers, resBody := ts.get(t, "/v1/items/random?limit=10", true, accessTokenUser2)
 
-	if updatedItem.Price != 7 {
-		t.Errorf("want %d; got %f", 7, updatedItem.Price)
-	}
+		if headers.Get("Cache-Control") != "no-store" {
+			t.Errorf("want Cache-Control %q; got %q", "no-store", headers.Get("Cache-Control"))
+		}
+
+		var response struct {
+			Items []data.Item `json:"items"`
+		}
+
+		err := json.Unmarshal(resBody, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := map[string]bool{}
+		for _, item := range response.Items {
+			names[item.Name] = true
+		}
+
+		if len(response.Items) != 5 || len(names) != 5 {
+			t.Errorf("want %d distinct items; got %d items", 5, len(response.Items))
+		}
+	})
+}
+
+func TestExpandStock(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	// Every item has 7 units in stock
+	inventoryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stock := map[string]int64{}
+		for _, id := range strings.Split(r.URL.Query().Get("item_ids"), ",") {
+			stock[id] = 7
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"stock": stock})
+	}))
+	defer inventoryServer.Close()
+
+	app.InventoryClient = inventory.NewClient(settings.Inventory{URL: inventoryServer.URL, Timeout: 500, FailureThreshold: 1, OpenDuration: 30})
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+
+	potion, err := app.ItemsRepository.GetByFilter(context.Background(), bson.M{"name": "Potion"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName           string
+		urlPath            string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Invalid expand", "/v1/items?expand=inventory", http.StatusUnprocessableEntity, []byte("invalid expand value")},
+		{"Stock without the id field", "/v1/items?expand=stock&fields=name", http.StatusUnprocessableEntity, []byte("stock can only be used along with the id field")},
+		{"Items with stock", "/v1/items?expand=stock&fields=id,name", http.StatusOK, []byte(`"stock": 7`)},
+		{"Item with stock", fmt.Sprintf("/v1/items/%s?expand=stock", potion.ID.Hex()), http.StatusOK, []byte(`"stock": 7`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, tt.urlPath, true, accessTokenUser2)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	t.Run("Inventory unavailable", func(t *testing.T) {
+		inventoryServer.Close()
+
+		// Skip the cached stock
+		app.InventoryClient = inventory.NewClient(settings.Inventory{URL: inventoryServer.URL, Timeout: 500, FailureThreshold: 1, OpenDuration: 30})
+
+		statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s?expand=stock", potion.ID.Hex()), true, accessTokenUser2)
+
+		if statusCode != http.StatusOK {
+			t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		if !bytes.Contains(resBody, []byte(`"stock": null`)) {
+			t.Errorf("want body %q to contain %q", resBody, `"stock": null`)
+		}
+	})
+}
+
+func TestGetItemSuggestionsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+
+	tests := []struct {
+		testName           string
+		queryString        string
+		useAuthHeader      bool
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No Authorization header", "?q=po", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has inventory:read", "?q=po", true, accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Missing q", "", true, accessTokenUser2, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"Invalid limit", "?q=po&limit=21", true, accessTokenUser2, http.StatusUnprocessableEntity, []byte("must be greater or equal to 1 and lower or equal to 20")},
+		{"Prefix ignoring case", "?q=po", true, accessTokenUser2, http.StatusOK, []byte(`"Potion"`)},
+		{"Prefix with special characters", "?q=hi-", true, accessTokenUser2, http.StatusOK, []byte(`"Hi-Potion"`)},
+		{"No match", "?q=sword", true, accessTokenUser2, http.StatusOK, []byte(`"suggestions": []`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/suggest%s", tt.queryString), tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestGetItemHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Create an item and retrieve its id
+	itemName := "Potion"
+	body := map[string]any{}
+	body["name"] = itemName
+	body["description"] = "Restores a small amount of health"
+	body["price"] = 5
+
+	_, headers, _ := ts.post(t, "/v1/items", body, true, accessTokenUser1)
+	itemID := strings.Split(headers.Get("Location"), "/")[3]
+
+	authenticationTests := []struct {
+		testName           string
+		useAuthHeader      bool
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No Authorization header", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"Invalid access token", true, "invalid", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"Access token not generated by identity microservice", true, invalidAccessToken, http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has inventory:read", true, accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+	}
+
+	for _, tt := range authenticationTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s", itemID), tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// -----------------------------
+
+	validationTests := []struct {
+		testName           string
+		id                 string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Invalid id (int)", "5", http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Invalid id (uuid)", "73f4fa2d-f134-4351-a14e-a0f0a8541712", http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Unknown id", primitive.NewObjectID().Hex(), http.StatusNotFound, []byte("The requested resource could not be found")},
+	}
+
+	for _, tt := range validationTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s", tt.id), true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// -----------------------------
+
+	successTest := struct {
+		testName         string
+		wantedStatusCode int
+	}{
+		"Valid submission",
+		http.StatusOK,
+	}
+
+	statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s", itemID), true, accessTokenUser1)
+
+	if statusCode != successTest.wantedStatusCode {
+		t.Errorf("want %d; got %d", successTest.wantedStatusCode, statusCode)
+	}
+
+	var jsonRes map[string]any
+
+	err := json.Unmarshal(resBody, &jsonRes)
+	if err != nil {
+		t.Error("Failed to parse json response")
+	}
+
+	item := (jsonRes["item"]).(map[string]any)
+
+	if item["name"] != itemName {
+		t.Errorf("want to receive %s but got %s", itemName, item["name"])
+	}
+
+	if _, ok := item["description_html"]; ok {
+		t.Error("want no rendered description; got description_html")
+	}
+
+	// -----------------------------
+
+	renderTests := []struct {
+		testName           string
+		render             string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Rendered description", "html", http.StatusOK, []byte(`"description_html": "\u003cp\u003eRestores a small amount of health\u003c/p\u003e"`)},
+		{"Invalid render value", "markdown", http.StatusUnprocessableEntity, []byte("invalid render value")},
+	}
+
+	for _, tt := range renderTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s?render=%s", itemID, tt.render), true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestGetItemByExternalIDHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	externalID := "73f4fa2d-f134-4351-a14e-a0f0a8541712"
+
+	creationTests := []struct {
+		testName           string
+		name               string
+		externalID         string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Valid external id", "Potion", externalID, http.StatusCreated, []byte("Item created successfully")},
+		{"Duplicate external id", "Hi-Potion", externalID, http.StatusConflict, []byte("an item with this external_id already exists")},
+		{"Invalid external id", "Mega Potion", "potion", http.StatusUnprocessableEntity, []byte("must be a valid UUID")},
+	}
+
+	for _, tt := range creationTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			body := map[string]any{}
+			body["external_id"] = tt.externalID
+			body["name"] = tt.name
+			body["description"] = fmt.Sprintf("Description of %s", tt.name)
+			body["price"] = 5
+
+			statusCode, _, resBody := ts.post(t, "/v1/items", body, true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// -----------------------------
+
+	lookupTests := []struct {
+		testName           string
+		externalID         string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Invalid external id", "5", http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Unknown external id", "0d5b8c5e-6d2a-4e0b-9a57-2f0f5c1e9b3a", http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Existing external id", externalID, http.StatusOK, []byte(`"name": "Potion"`)},
+	}
+
+	for _, tt := range lookupTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/external/%s", tt.externalID), true, accessTokenUser2)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestHeadItemHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Create an item and retrieve its id
+	body := map[string]any{}
+	body["name"] = "Potion"
+	body["description"] = "Restores a small amount of health"
+	body["price"] = 5
+
+	_, headers, _ := ts.post(t, "/v1/items", body, true, accessTokenUser1)
+	itemID := strings.Split(headers.Get("Location"), "/")[3]
+
+	tests := []struct {
+		testName         string
+		id               string
+		useAuthHeader    bool
+		accessToken      string
+		wantedStatusCode int
+		wantedETag       string
+	}{
+		{"No Authorization header", itemID, false, "", http.StatusUnauthorized, ""},
+		{"User does not have permission - has inventory:read", itemID, true, accessTokenUser3, http.StatusForbidden, ""},
+		{"Invalid id", "5", true, accessTokenUser2, http.StatusNotFound, ""},
+		{"Unknown id", primitive.NewObjectID().Hex(), true, accessTokenUser2, http.StatusNotFound, ""},
+		{"Existing item", itemID, true, accessTokenUser2, http.StatusOK, fmt.Sprintf(`"%s-1"`, itemID)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, headers, resBody := ts.head(t, fmt.Sprintf("/v1/items/%s", tt.id), tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if headers.Get("ETag") != tt.wantedETag {
+				t.Errorf("want ETag %q; got %q", tt.wantedETag, headers.Get("ETag"))
+			}
+
+			if len(resBody) != 0 {
+				t.Errorf("want empty body; got %q", resBody)
+			}
+		})
+	}
+}
+
+func TestUpdateItemHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Create an item and retrieve its id
+	name := "Potion"
+	body := map[string]any{}
+	body["name"] = name
+	body["description"] = "Restores a small amount of health"
+	body["price"] = 5
+
+	_, headers, _ := ts.post(t, "/v1/items", body, true, accessTokenUser1)
+	itemID := strings.Split(headers.Get("Location"), "/")[3]
+
+	// Fetch created item and make sure it exists in database
+	createdItem := fetchItem(t, app.ItemsRepository, itemID)
+
+	if createdItem.Name != name {
+		t.Errorf("want %s; got %s", name, createdItem.Name)
+	}
+
+	authenticationTests := []struct {
+		testName           string
+		useAuthHeader      bool
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No Authorization header", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"Invalid access token", true, "invalid", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"Access token not generated by identity microservice", true, invalidAccessToken, http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has catalog:read", true, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"User does not have permission - has inventory:read", true, accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+	}
+
+	for _, tt := range authenticationTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			body := map[string]any{}
+			body["name"] = "Potion"
+			body["description"] = "Restores a small amount of health"
+			body["price"] = 5
+
+			statusCode, _, resBody := ts.put(t, fmt.Sprintf("/v1/items/%s", itemID), body, tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// -----------------------------
+
+	tests := []struct {
+		testName           string
+		idURLParam         string
+		name               string
+		description        string
+		price              float64
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Valid submission", itemID, "Potion", "Restores a small amount of health", 7, http.StatusOK, []byte("Item updated successfully")},
+		{"Empty name", itemID, "", "Restores a small amount of health", 5, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"Empty description", itemID, "Potion", "", 5, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"Invalid price value (below 0.1)", itemID, "Potion", "Restores a small amount of health", 0, http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 and lower or equal to 1000")},
+		{"Invalid price value (above 1000.0)", itemID, "Potion", "Restores a small amount of health", 1001, http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 and lower or equal to 1000")},
+		{"Invalid id URL param (int)", "5", "Potion", "Restores a small amount of health", 5, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Invalid id URL param (uuid)", "73f4fa2d-f134-4351-a14e-a0f0a8541712", "Potion", "Restores a small amount of health", 5, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Unknown id URL param", primitive.NewObjectID().Hex(), "Potion", "Restores a small amount of health", 5, http.StatusNotFound, []byte("The requested resource could not be found")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			body := map[string]any{}
+			body["name"] = tt.name
+			body["description"] = tt.description
+			body["price"] = tt.price
+
+			statusCode, _, resBody := ts.put(t, fmt.Sprintf("/v1/items/%s", tt.idURLParam), body, true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// Fetch item again and make sure it was updated in the database
+	updatedItem := fetchItem(t, app.ItemsRepository, itemID)
+
+	if updatedItem.Price != 7 {
+		t.Errorf("want %d; got %f", 7, updatedItem.Price)
+	}
 
 	if updatedItem.Version != 2 {
 		t.Errorf("want %d; got %d", 2, updatedItem.Version)
@@ -555,7 +1324,7 @@ func TestUpdateItemHandler(t *testing.T) {
 	body["description"] = malformedJSONTest.description
 	body["price"] = malformedJSONTest.price
 
-	statusCode, _, resBody := ts.put(t, fmt.Sprintf("/items/%s", malformedJSONTest.idURLParam), body, true, accessTokenUser1)
+	statusCode, _, resBody := ts.put(t, fmt.Sprintf("/v1/items/%s", malformedJSONTest.idURLParam), body, true, accessTokenUser1)
 
 	if statusCode != malformedJSONTest.wantedStatusCode {
 		t.Errorf("want %d; got %d", malformedJSONTest.wantedStatusCode, statusCode)
@@ -590,7 +1359,7 @@ func TestUpdateItemHandler(t *testing.T) {
 	body["description"] = unknownKeyTest.description
 	body["invalid"] = unknownKeyTest.invalid
 
-	statusCode, _, resBody = ts.put(t, fmt.Sprintf("/items/%s", unknownKeyTest.idURLParam), body, true, accessTokenUser1)
+	statusCode, _, resBody = ts.put(t, fmt.Sprintf("/v1/items/%s", unknownKeyTest.idURLParam), body, true, accessTokenUser1)
 
 	if statusCode != unknownKeyTest.wantedStatusCode {
 		t.Errorf("want %d; got %d", unknownKeyTest.wantedStatusCode, statusCode)
@@ -601,6 +1370,111 @@ func TestUpdateItemHandler(t *testing.T) {
 	}
 }
 
+func TestUpdateItemExpectedVersion(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		body               map[string]any
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Matching version", map[string]any{"price": 6, "expected_version": 1}, http.StatusOK, []byte("Item updated successfully")},
+		{"Stale version", map[string]any{"price": 8, "expected_version": 1}, http.StatusConflict, []byte(`"version": 2`)},
+		{"No expected version", map[string]any{"price": 9}, http.StatusOK, []byte("Item updated successfully")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.put(t, fmt.Sprintf("/v1/items/%s", ids["Potion"]), tt.body, true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// Make sure the stale changes were not applied
+	updatedItem := fetchItem(t, app.ItemsRepository, ids["Potion"])
+
+	if updatedItem.Price != 9 {
+		t.Errorf("want %d; got %f", 9, updatedItem.Price)
+	}
+}
+
+func TestItemUnmodifiedSince(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	past := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		testName         string
+		method           string
+		id               string
+		unmodifiedSince  string
+		wantedStatusCode int
+	}{
+		{"Update of an item modified since the date", http.MethodPut, ids["Potion"], past, http.StatusPreconditionFailed},
+		{"Update of an item not modified since the date", http.MethodPut, ids["Potion"], future, http.StatusOK},
+		{"Update with an invalid date", http.MethodPut, ids["Potion"], "yesterday", http.StatusOK},
+		{"Deletion of an item modified since the date", http.MethodDelete, ids["Ether"], past, http.StatusPreconditionFailed},
+		{"Deletion of an item not modified since the date", http.MethodDelete, ids["Ether"], future, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, fmt.Sprintf("%s/v1/items/%s", ts.URL, tt.id), strings.NewReader(`{"price": 6}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+accessTokenUser1)
+			req.Header.Set("If-Unmodified-Since", tt.unmodifiedSince)
+
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, res.StatusCode)
+			}
+		})
+	}
+
+	t.Run("Last-Modified header", func(t *testing.T) {
+		_, headers, _ := ts.get(t, fmt.Sprintf("/v1/items/%s", ids["Potion"]), true, accessTokenUser1)
+
+		if _, err := http.ParseTime(headers.Get("Last-Modified")); err != nil {
+			t.Errorf("want a valid Last-Modified header; got %q", headers.Get("Last-Modified"))
+		}
+	})
+}
+
 func TestDeleteItemHandler(t *testing.T) {
 	app, cleanup := newTestApplication(t)
 	t.Cleanup(cleanup)
@@ -615,8 +1489,8 @@ func TestDeleteItemHandler(t *testing.T) {
 	body["description"] = "Restores a small amount of health"
 	body["price"] = 5
 
-	_, headers, _ := ts.post(t, "/items", body, true, accessTokenUser1)
-	itemID := strings.Split(headers.Get("Location"), "/")[2]
+	_, headers, _ := ts.post(t, "/v1/items", body, true, accessTokenUser1)
+	itemID := strings.Split(headers.Get("Location"), "/")[3]
 
 	// Fetch created item and make sure it exists in database
 	createdItem := fetchItem(t, app.ItemsRepository, itemID)
@@ -641,7 +1515,7 @@ func TestDeleteItemHandler(t *testing.T) {
 
 	for _, tt := range authenticationTests {
 		t.Run(tt.testName, func(t *testing.T) {
-			statusCode, _, resBody := ts.delete(t, fmt.Sprintf("/items/%s", itemID), tt.useAuthHeader, tt.accessToken)
+			statusCode, _, resBody := ts.delete(t, fmt.Sprintf("/v1/items/%s", itemID), tt.useAuthHeader, tt.accessToken)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -669,7 +1543,7 @@ func TestDeleteItemHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			statusCode, _, resBody := ts.delete(t, fmt.Sprintf("/items/%s", tt.id), true, accessTokenUser1)
+			statusCode, _, resBody := ts.delete(t, fmt.Sprintf("/v1/items/%s", tt.id), true, accessTokenUser1)
 
 			if statusCode != tt.wantedStatusCode {
 				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
@@ -689,3 +1563,73 @@ func TestDeleteItemHandler(t *testing.T) {
 		t.Errorf("want %s; got %s", "name", item.Name)
 	}
 }
+
+func TestItemOwnership(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	app.Settings.Authorization.Mode = "owner"
+
+	// Sign the tokens of the users with a key of our own
+	key, publicKey := newTestKey(t)
+	app.Config.RSA.PublicKey = publicKey
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection and add a user who can create items without being an admin
+	seedItemsCollection(t, app.ItemsRepository)
+
+	_, err := app.UsersRepository.Create(context.Background(), data.User{ID: 4, Name: "Merchant", Permissions: []string{"catalog:read", "catalog:write"}, Activated: true, Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adminToken := signTestToken(t, key, app.Config.Authority, "1", "")
+	writerToken := signTestToken(t, key, app.Config.Authority, "4", "")
+
+	potion, err := app.ItemsRepository.GetByFilter(context.Background(), bson.M{"name": "Potion"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statusCode, headers, resBody := ts.post(t, "/v1/items", map[string]any{"name": "Elixir", "description": "Fully restores health", "price": 50}, true, writerToken)
+	if statusCode != http.StatusCreated {
+		t.Fatalf("want %d; got %d (%s)", http.StatusCreated, statusCode, resBody)
+	}
+
+	elixirPath := headers.Get("Location")
+
+	tests := []struct {
+		testName           string
+		method             string
+		urlPath            string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Owner of the item", http.MethodGet, elixirPath, nil, writerToken, http.StatusOK, []byte(`"created_by": 4`)},
+		{"Update item of another user", http.MethodPut, "/v1/items/" + potion.ID.Hex(), map[string]any{"price": 6}, writerToken, http.StatusForbidden, []byte("you can only modify the items you created")},
+		{"Delete item of another user", http.MethodDelete, "/v1/items/" + potion.ID.Hex(), nil, writerToken, http.StatusForbidden, []byte("you can only modify the items you created")},
+		{"Update own item", http.MethodPut, elixirPath, map[string]any{"price": 45}, writerToken, http.StatusOK, []byte("Item updated successfully")},
+		{"Admin updates any item", http.MethodPut, "/v1/items/" + potion.ID.Hex(), map[string]any{"price": 6}, adminToken, http.StatusOK, []byte("Item updated successfully")},
+		{"Last updated by the admin", http.MethodGet, "/v1/items/" + potion.ID.Hex(), nil, adminToken, http.StatusOK, []byte(`"updated_by": 1`)},
+		{"Last updated by the owner", http.MethodGet, elixirPath, nil, writerToken, http.StatusOK, []byte(`"updated_by": 4`)},
+		{"Delete own item", http.MethodDelete, elixirPath, nil, writerToken, http.StatusOK, []byte("Item deleted successfully")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, tt.urlPath, tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
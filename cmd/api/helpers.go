@@ -0,0 +1,575 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/markdown"
+	"github.com/PlayEconomy37/Play.Catalog/internal/search"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// itemsQuery is a struct that holds the expected values from the query string of the "GET /items" endpoint
+type itemsQuery struct {
+	Name          string
+	MinPrice      float64
+	MaxPrice      float64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	IncludeTotal  bool
+	filters.Filters
+}
+
+// readItemsQuery extracts the values of the "GET /items" query string and validates them.
+// Any validation error is recorded in the provided Validator instance.
+func (app *Application) readItemsQuery(queryString url.Values, v *validation.Validator) itemsQuery {
+	var input itemsQuery
+
+	// Extract values from query string if they exist
+	input.Name = app.ReadStringFromQueryString(queryString, "name", "")
+	input.MinPrice = app.readFloatFromQueryString(queryString, "min_price", database.DefaultPrice, v)
+	input.MaxPrice = app.readFloatFromQueryString(queryString, "max_price", database.DefaultPrice, v)
+	input.CreatedAfter = app.readTimeFromQueryString(queryString, "created_after", v)
+	input.CreatedBefore = app.readTimeFromQueryString(queryString, "created_before", v)
+	input.UpdatedAfter = app.readTimeFromQueryString(queryString, "updated_after", v)
+	input.UpdatedBefore = app.readTimeFromQueryString(queryString, "updated_before", v)
+	input.IncludeTotal = app.readBoolFromQueryString(queryString, "include_total", true, v)
+	input.Filters.Page = app.readIntFromQueryString(queryString, "page", 1, v)
+	input.Filters.PageSize = app.readIntFromQueryString(queryString, "page_size", 20, v)
+	input.Filters.Sort = app.ReadStringFromQueryString(queryString, "sort", "_id")
+
+	// Add the supported sort values for this endpoint to the sort safelist.
+	// Several values can be combined in a comma separated list (i.e. "name,-price").
+	input.Filters.SortSafelist = []string{
+		"_id", "name", "price", data.PopularitySort,
+		"-_id", "-name", "-price", "-" + data.PopularitySort,
+		data.RelevanceSort,
+	}
+
+	// Validate query string
+	pricing := app.Settings.Pricing
+	priceRangeMessage := fmt.Sprintf(
+		"must be greater or equal to %s or lower and equal to %s",
+		data.FormatPrice(pricing.MinPrice),
+		data.FormatPrice(pricing.MaxPrice),
+	)
+
+	v.Check(validator.Between(input.MinPrice, pricing.MinPrice, pricing.MaxPrice), "min_price", "out_of_range", priceRangeMessage)
+	v.Check(validator.Between(input.MaxPrice, pricing.MinPrice, pricing.MaxPrice), "max_price", "out_of_range", priceRangeMessage)
+
+	// Only run this check if both min_price and max_price have been set
+	if input.MinPrice != database.DefaultPrice && input.MaxPrice != database.DefaultPrice {
+		v.Check(input.MaxPrice >= input.MinPrice, "max_price", "invalid_range", "must be greater or equal to specified min_price")
+	}
+
+	// Only run these checks if both bounds of the date ranges have been set
+	if !input.CreatedAfter.IsZero() && !input.CreatedBefore.IsZero() {
+		v.Check(input.CreatedBefore.After(input.CreatedAfter), "created_before", "invalid_range", "must be later than specified created_after")
+	}
+
+	if !input.UpdatedAfter.IsZero() && !input.UpdatedBefore.IsZero() {
+		v.Check(input.UpdatedBefore.After(input.UpdatedAfter), "updated_before", "invalid_range", "must be later than specified updated_after")
+	}
+
+	data.ValidateFilters(v, input.Filters)
+
+	// Relevance is only known when searching by name
+	if input.Name == "" {
+		for _, field := range strings.Split(input.Filters.Sort, ",") {
+			v.Check(field != data.RelevanceSort, "sort", "not_allowed", "relevance can only be used along with a name search")
+		}
+	}
+
+	return input
+}
+
+// sortsBy returns true if the items are sorted by the given field, in any direction
+func (input itemsQuery) sortsBy(field string) bool {
+	for _, sortField := range strings.Split(input.Filters.Sort, ",") {
+		if strings.TrimPrefix(sortField, "-") == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mongoFilter converts the values of the "GET /items" query string into a MongoDB filter.
+// Expired items are never listed.
+func (input itemsQuery) mongoFilter() bson.M {
+	filter := bson.M{"$or": data.NotExpiredFilter(time.Now().UTC())}
+
+	if input.Name != "" {
+		filter["$text"] = bson.M{"$search": input.Name}
+	}
+
+	if input.MinPrice != database.DefaultPrice && input.MaxPrice == database.DefaultPrice {
+		filter["price"] = bson.M{"$gte": input.MinPrice}
+	} else if input.MaxPrice != database.DefaultPrice && input.MinPrice == database.DefaultPrice {
+		filter["price"] = bson.M{"$lte": input.MaxPrice}
+	} else if input.MaxPrice != database.DefaultPrice && input.MinPrice != database.DefaultPrice {
+		filter["price"] = bson.M{"$gte": input.MinPrice, "$lte": input.MaxPrice}
+	}
+
+	if dateFilter := dateRangeFilter(input.CreatedAfter, input.CreatedBefore); dateFilter != nil {
+		filter["created_at"] = dateFilter
+	}
+
+	if dateFilter := dateRangeFilter(input.UpdatedAfter, input.UpdatedBefore); dateFilter != nil {
+		filter["updated_at"] = dateFilter
+	}
+
+	return filter
+}
+
+// dateRangeFilter returns the MongoDB filter matching the dates strictly between the given bounds.
+// Zero bounds are ignored and nil is returned if both of them are zero.
+func dateRangeFilter(after time.Time, before time.Time) bson.M {
+	if after.IsZero() && before.IsZero() {
+		return nil
+	}
+
+	dateFilter := bson.M{}
+
+	if !after.IsZero() {
+		dateFilter["$gt"] = after
+	}
+
+	if !before.IsZero() {
+		dateFilter["$lt"] = before
+	}
+
+	return dateFilter
+}
+
+// utcTime returns the given optional time in UTC
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	utc := t.UTC()
+
+	return &utc
+}
+
+// validateExpiresAt checks that an expiration date set by a client is in the future
+func validateExpiresAt(v *validation.Validator, expiresAt *time.Time) {
+	if expiresAt != nil {
+		v.Check(expiresAt.After(time.Now()), "expires_at", "not_in_future", "must be in the future")
+	}
+}
+
+// readTimeFromQueryString reads a RFC3339 timestamp from the query string.
+// If no matching key could be found, it returns the zero time. If the value couldn't be
+// parsed, then we record an error message in the provided Validator instance.
+func (app *Application) readTimeFromQueryString(queryString url.Values, key string, v *validation.Validator) time.Time {
+	// Extract the value from the query string
+	str := queryString.Get(key)
+
+	// If no key exists (or the value is empty) then return the zero time
+	if str == "" {
+		return time.Time{}
+	}
+
+	value, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		v.AddError(key, "invalid_format", "must be a valid RFC3339 timestamp")
+		return time.Time{}
+	}
+
+	return value.UTC()
+}
+
+// readBoolFromQueryString reads a boolean value from the query string.
+// If no matching key could be found, it returns the provided default value. If the value
+// couldn't be parsed, then we record an error message in the provided Validator instance.
+func (app *Application) readBoolFromQueryString(queryString url.Values, key string, defaultValue bool, v *validation.Validator) bool {
+	// Extract the value from the query string
+	str := queryString.Get(key)
+
+	// If no key exists (or the value is empty) then return the default value
+	if str == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(str)
+	if err != nil {
+		v.AddError(key, "invalid_format", "must be a boolean value")
+		return defaultValue
+	}
+
+	return value
+}
+
+// readIntFromQueryString reads an integer value from the query string with the common helper,
+// recording its errors with the "invalid_format" reason
+func (app *Application) readIntFromQueryString(queryString url.Values, key string, defaultValue int, v *validation.Validator) int {
+	var value int
+
+	v.Track("invalid_format", func(common *validator.Validator) {
+		value = app.ReadIntFromQueryString(queryString, key, defaultValue, common)
+	})
+
+	return value
+}
+
+// readFloatFromQueryString reads a float value from the query string with the common helper,
+// recording its errors with the "invalid_format" reason
+func (app *Application) readFloatFromQueryString(queryString url.Values, key string, defaultValue float64, v *validation.Validator) float64 {
+	var value float64
+
+	v.Track("invalid_format", func(common *validator.Validator) {
+		value = app.ReadFloatFromQueryString(queryString, key, defaultValue, common)
+	})
+
+	return value
+}
+
+// atlasSearchItems retrieves the page of items whose name matches the name search of the given
+// query with Atlas Search. The other filters of the query are applied on the search results.
+func (app *Application) atlasSearchItems(ctx context.Context, input itemsQuery) ([]data.SearchedItem, data.Metadata, error) {
+	// The name search is handled by Atlas Search instead of the text index
+	filter := input.mongoFilter()
+	delete(filter, "$text")
+
+	pipeline := data.AtlasSearchPipeline(
+		app.Settings.Search.Index,
+		input.Name,
+		app.Settings.Search.MaxEdits,
+		filter,
+		input.Filters,
+	)
+
+	// The $facet stage always returns a single document
+	var results []data.ItemSearchResults
+
+	err := app.ItemsRepository.Aggregate(ctx, pipeline, &results)
+	if err != nil || len(results) == 0 {
+		return nil, data.Metadata{}, err
+	}
+
+	metadata := filters.CalculateMetadata(results[0].Total(), input.Filters.Page, input.Filters.PageSize)
+
+	return results[0].Items, data.Metadata{Metadata: metadata}, nil
+}
+
+// canModifyItem checks if the authenticated user is allowed to update or delete the given item.
+// In "owner" authorization mode, only the user who created the item and the catalog:admin users are.
+func (app *Application) canModifyItem(r *http.Request, item data.Item) bool {
+	if app.Settings.Authorization.Mode != "owner" {
+		return true
+	}
+
+	user := app.ContextGetUser(r)
+	if user.GetPermissions().Include("catalog:admin") {
+		return true
+	}
+
+	// Items whose creator is unknown (i.e. created before their creator was recorded) are left to the admins
+	return item.CreatedBy != 0 && item.CreatedBy == user.ID
+}
+
+// listingsRepository returns the repository serving the listings of the items, which is their read model when it is
+// enabled and the items repository otherwise
+func (app *Application) listingsRepository() data.Repository[primitive.ObjectID, data.Item] {
+	if app.ListingsRepository != nil {
+		return app.ListingsRepository
+	}
+
+	return app.ItemsRepository
+}
+
+// contextTenant returns the tenant of the given context, or the default tenant if it does not hold any
+func (app *Application) contextTenant(ctx context.Context) string {
+	tenant, ok := data.TenantFromContext(ctx)
+	if !ok {
+		return app.Settings.Tenancy.DefaultTenant
+	}
+
+	return tenant
+}
+
+// elasticsearchItems retrieves the page of items matching the name search and the filters
+// of the given query from the Elasticsearch index
+func (app *Application) elasticsearchItems(ctx context.Context, input itemsQuery) ([]data.Item, data.Metadata, error) {
+	query := search.Query{
+		Tenant:        app.contextTenant(ctx),
+		Text:          input.Name,
+		CreatedAfter:  input.CreatedAfter,
+		CreatedBefore: input.CreatedBefore,
+		UpdatedAfter:  input.UpdatedAfter,
+		UpdatedBefore: input.UpdatedBefore,
+		Filters:       input.Filters,
+	}
+
+	if input.MinPrice != database.DefaultPrice {
+		query.MinPrice = input.MinPrice
+	}
+
+	if input.MaxPrice != database.DefaultPrice {
+		query.MaxPrice = input.MaxPrice
+	}
+
+	items, total, err := app.SearchIndex.SearchItems(ctx, query)
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	metadata := filters.CalculateMetadata(total, input.Filters.Page, input.Filters.PageSize)
+
+	return items, data.Metadata{Metadata: metadata}, nil
+}
+
+// selectItemFields restricts the JSON representation of the given items to the given fields.
+// The highlights of the items returned by Atlas Search are always sent back.
+func selectItemFields(items any, fields []string) []map[string]any {
+	selected := []map[string]any{}
+
+	switch items := items.(type) {
+	case []data.Item:
+		for _, item := range items {
+			selected = append(selected, item.SelectFields(fields))
+		}
+	case []data.SearchedItem:
+		for _, item := range items {
+			selectedItem := item.SelectFields(fields)
+			selectedItem["highlights"] = item.Highlights
+			selected = append(selected, selectedItem)
+		}
+	}
+
+	return selected
+}
+
+// readRenderHTML reads the "render" query string parameter and returns true if the Markdown descriptions
+// of the items must be rendered into HTML
+func (app *Application) readRenderHTML(queryString url.Values, v *validation.Validator) bool {
+	render := app.ReadStringFromQueryString(queryString, "render", "")
+	v.Check(validator.In(render, "", "html"), "render", "unsupported_value", "invalid render value")
+
+	return render == "html"
+}
+
+// renderDescription returns the given item along with the sanitized HTML rendering of its Markdown description.
+// The description was sanitized before being stored, so its entities are unescaped to render the Markdown
+// syntax they hold (i.e. the "&gt;" of a blockquote), the rendered HTML being sanitized again.
+func (app *Application) renderDescription(item data.Item) data.Item {
+	if item.Description != "" {
+		item.DescriptionHTML = app.Sanitizer.Sanitize(markdown.Render(html.UnescapeString(item.Description)))
+	}
+
+	return item
+}
+
+// renderDescriptions renders the Markdown descriptions of the given items into HTML
+func (app *Application) renderDescriptions(items any) any {
+	switch items := items.(type) {
+	case []data.Item:
+		for i := range items {
+			items[i] = app.renderDescription(items[i])
+		}
+	case []data.SearchedItem:
+		for i := range items {
+			items[i].Item = app.renderDescription(items[i].Item)
+		}
+	}
+
+	return items
+}
+
+// readExpandStock reads the "expand" query string parameter and returns true if the stock of the items
+// must be embedded in the response
+func (app *Application) readExpandStock(queryString url.Values, v *validation.Validator) bool {
+	expand := app.ReadCsvFromQueryString(queryString, "expand", []string{})
+
+	v.Check(validator.AllIn(expand, "stock"), "expand", "unsupported_value", "invalid expand value")
+	v.Check(validator.NoDuplicates(expand), "expand", "duplicate", "must not contain duplicate values")
+
+	expandStock := validator.In("stock", expand...)
+	v.Check(!expandStock || app.InventoryClient != nil, "expand", "not_allowed", "stock can only be used when the inventory service is configured")
+
+	return expandStock
+}
+
+// withStock returns the JSON representation of the given items along with their current stock.
+// The stock is null when the Inventory microservice is unavailable so that the items are still served.
+func (app *Application) withStock(ctx context.Context, r *http.Request, items any) ([]map[string]any, error) {
+	js, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := []map[string]any{}
+
+	// Keep the numbers as they are
+	decoder := json.NewDecoder(bytes.NewReader(js))
+	decoder.UseNumber()
+
+	err = decoder.Decode(&expanded)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(expanded))
+	for _, item := range expanded {
+		ids = append(ids, fmt.Sprint(item["id"]))
+	}
+
+	stock, err := app.InventoryClient.GetStock(ctx, ids, r.Header.Get("Authorization"))
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		app.Logger.Warning("Stock of the items is not available", map[string]string{"error": err.Error()})
+	}
+
+	for i, id := range ids {
+		if err != nil {
+			expanded[i]["stock"] = nil
+			continue
+		}
+
+		expanded[i]["stock"] = stock[id]
+	}
+
+	return expanded, nil
+}
+
+// bodyTooLargeError returns the error sent to the client when the request body exceeds the given limit
+func bodyTooLargeError(maxBytes int64) error {
+	return fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+}
+
+// limitBody returns the body of the request limited to the given size. Requests announcing a bigger body
+// are rejected before it is read.
+func (app *Application) limitBody(w http.ResponseWriter, r *http.Request, maxBytes int64) (io.ReadCloser, error) {
+	if r.ContentLength > maxBytes {
+		return nil, bodyTooLargeError(maxBytes)
+	}
+
+	return http.MaxBytesReader(w, r.Body, maxBytes), nil
+}
+
+// readJSON is a helper function for reading JSON data from HTTP request to the specified target.
+// It behaves like the common ReadJSON helper but limits the size of the request body to the
+// limit defined for the current route.
+func (app *Application) readJSON(w http.ResponseWriter, r *http.Request, target any) error {
+	// Use http.MaxBytesReader() to limit the size of the request body
+	body, err := app.limitBody(w, r, app.contextGetBodyLimit(r))
+	if err != nil {
+		return err
+	}
+
+	r.Body = body
+
+	// Initialize the json.Decoder and call the DisallowUnknownFields() method on it
+	// before decoding so that unknown fields are rejected
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	// Decode the request body into the target destination
+	err = decoder.Decode(target)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+		var invalidUnmarshalError *json.InvalidUnmarshalError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains malformed JSON (at character %d)", syntaxError.Offset)
+
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains malformed JSON")
+
+		case errors.As(err, &unmarshalTypeError):
+			if unmarshalTypeError.Field != "" {
+				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+			}
+
+			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+
+			return fmt.Errorf("body contains unknown key %s", fieldName)
+
+		case errors.As(err, &maxBytesError):
+			return bodyTooLargeError(maxBytesError.Limit)
+
+		// We pass a non-nil pointer to Decode() so this is a programming error
+		case errors.As(err, &invalidUnmarshalError):
+			panic(err)
+
+		default:
+			return err
+		}
+	}
+
+	// Make sure that the request body only contains a single JSON value
+	err = decoder.Decode(&struct{}{})
+	if err != io.EOF {
+		return errors.New("body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// readIDParam extracts the id of the request URL parameters and records it in the trace.
+// It sends a Not found response itself and returns false if the id is not a valid ObjectID.
+func (app *Application) readIDParam(ctx context.Context, w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	span := trace.SpanFromContext(ctx)
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("id", chi.URLParamFromCtx(r.Context(), "id")))
+
+		// Throw Not found error if extracted id is not a valid ObjectID
+		app.NotFoundResponse(w, r)
+		return primitive.NilObjectID, false
+	}
+
+	// Record id in the trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	return id, true
+}
+
+// unmodifiedSince reports whether the given item was not modified after the If-Unmodified-Since date of the request.
+// Requests without this header or with an invalid date are always allowed, as required by the HTTP specification.
+func unmodifiedSince(r *http.Request, item data.Item) bool {
+	since, err := http.ParseTime(r.Header.Get("If-Unmodified-Since"))
+	if err != nil {
+		return true
+	}
+
+	// HTTP dates are only precise to the second
+	return !item.UpdatedAt.Truncate(time.Second).After(since)
+}
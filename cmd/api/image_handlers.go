@@ -0,0 +1,339 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/storage"
+	"github.com/PlayEconomy37/Play.Catalog/internal/thumbnail"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// imageKeyPrefix returns the prefix of the keys of the images of the given item.
+// The keys are scoped to the tenant of the item so that the tenants never share an object.
+func imageKeyPrefix(tenant string, id primitive.ObjectID) string {
+	return fmt.Sprintf("items/%s/%s/", tenant, id.Hex())
+}
+
+// createImageUploadHandler is the handler for the "POST /v1/items/:id/images/uploads" endpoint
+func (app *Application) createImageUploadHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Creating image upload")
+	defer span.End()
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("id", chi.URLParamFromCtx(r.Context(), "id")))
+
+		// Throw Not found error if extracted id is not a valid ObjectID
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	// Record item id in the trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	// Retrieve item with given id
+	item, err := app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// Check that the user is allowed to change the item
+	if !app.canModifyItem(r, item) {
+		span.SetStatus(codes.Error, "Not the owner of the item")
+		app.notOwnerResponse(w, r)
+		return
+	}
+
+	var input struct {
+		ContentType string `json:"content_type"`
+	}
+
+	// Read request body and decode it into the input struct
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	images := app.Settings.Images
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	v.Check(input.ContentType != "", "content_type", "required", "must be provided")
+	v.Check(input.ContentType == "" || validator.In(input.ContentType, images.ContentTypes...), "content_type", "unsupported_value", "must be one of "+strings.Join(images.ContentTypes, ", "))
+	v.Check(len(item.Images) < images.MaxImages, "images", "too_many", fmt.Sprintf("must not contain more than %d images", images.MaxImages))
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Every upload has its own key so that an image is never replaced
+	key := imageKeyPrefix(app.contextTenant(ctx), item.ID) + primitive.NewObjectID().Hex()
+	span.SetAttributes(attribute.String("key", key))
+
+	env := types.Envelope{
+		"upload": app.ImageStorage.PresignUpload(key, input.ContentType),
+	}
+
+	err = app.WriteJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// confirmImageUploadHandler is the handler for the "POST /v1/items/:id/images" endpoint
+func (app *Application) confirmImageUploadHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Confirming image upload")
+	defer span.End()
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("id", chi.URLParamFromCtx(r.Context(), "id")))
+
+		// Throw Not found error if extracted id is not a valid ObjectID
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	// Record item id in the trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	// Retrieve item with given id
+	item, err := app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// Check that the user is allowed to change the item
+	if !app.canModifyItem(r, item) {
+		span.SetStatus(codes.Error, "Not the owner of the item")
+		app.notOwnerResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Key string `json:"key"`
+	}
+
+	// Read request body and decode it into the input struct
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	span.SetAttributes(attribute.String("key", input.Key))
+
+	images := app.Settings.Images
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	// Only the keys issued for the item are accepted
+	prefix := imageKeyPrefix(app.contextTenant(ctx), item.ID)
+	_, objectIDErr := primitive.ObjectIDFromHex(strings.TrimPrefix(input.Key, prefix))
+
+	v.Check(strings.HasPrefix(input.Key, prefix) && objectIDErr == nil, "key", "invalid_format", "must be a key issued for an upload of the item")
+
+	for _, image := range item.Images {
+		v.Check(image.Key != input.Key, "key", "already_confirmed", "was already confirmed")
+	}
+
+	v.Check(len(item.Images) < images.MaxImages, "images", "too_many", fmt.Sprintf("must not contain more than %d images", images.MaxImages))
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Check the uploaded object, whose size and content type are chosen by the client
+	object, err := app.ImageStorage.Stat(ctx, input.Key)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, storage.ErrObjectNotFound):
+			v.AddError("key", "not_uploaded", "was not uploaded")
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	v.Check(object.Size <= images.MaxSize, "key", "too_large", fmt.Sprintf("must not be larger than %d bytes", images.MaxSize))
+	v.Check(validator.In(object.ContentType, images.ContentTypes...), "key", "unsupported_value", "must have one of the content types "+strings.Join(images.ContentTypes, ", "))
+
+	if v.HasErrors() {
+		// The rejected object is not left behind in the storage. The client can still upload another image with
+		// the same upload request until it expires.
+		err = app.ImageStorage.Delete(ctx, input.Key)
+		if err != nil {
+			span.RecordError(err)
+			app.Logger.Error(err, map[string]string{"operation": "delete_rejected_image", "key": input.Key})
+		}
+
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	now := time.Now().UTC()
+
+	image := data.ItemImage{
+		Key:         input.Key,
+		URL:         app.ImageStorage.URL(input.Key),
+		ContentType: object.ContentType,
+		Size:        object.Size,
+		UploadedAt:  now,
+	}
+
+	item.Images = append(item.Images, image)
+	item.UpdatedAt = now
+
+	// Update item in the database
+	err = app.ItemsRepository.Update(ctx, item)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrEditConflict):
+			app.EditConflictResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	app.ThumbnailGenerator.Enqueue(item.ID, image)
+
+	env := types.Envelope{
+		"image": image,
+	}
+
+	err = app.WriteJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getItemImageHandler is the handler for the "GET /v1/items/:id/image" endpoint.
+// It redirects to the first image of the item, or to its thumbnail of the requested size.
+func (app *Application) getItemImageHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item image")
+	defer span.End()
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("id", chi.URLParamFromCtx(r.Context(), "id")))
+
+		// Throw Not found error if extracted id is not a valid ObjectID
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	// Record item id in the trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	// Instantiate validator
+	v := validation.New()
+
+	size := app.ReadStringFromQueryString(r.URL.Query(), "size", "original")
+	v.Check(validator.In(size, thumbnail.Small, thumbnail.Medium, "original"), "size", "unsupported_value", "must be small, medium or original")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Retrieve item with given id
+	item, err := app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	if len(item.Images) == 0 {
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	// The original image is served until its thumbnails are generated, as well as for the unsupported formats
+	url := item.Images[0].URL
+	if resized, ok := item.Images[0].Thumbnail(size); ok {
+		url = resized.URL
+	}
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
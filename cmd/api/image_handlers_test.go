@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/storage"
+	"github.com/PlayEconomy37/Play.Catalog/internal/thumbnail"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testThumbnailMetrics are shared by the generators of the tests since prometheus metrics can only be registered once
+var testThumbnailMetrics = thumbnail.NewMetrics("image_handlers_test")
+
+// fakeObject is an object of the fake object storage
+type fakeObject struct {
+	size        int
+	contentType string
+}
+
+// newTestImageStorage returns a storage backed by a fake S3 bucket holding the given objects
+func newTestImageStorage(t *testing.T, objects map[string]fakeObject) (*storage.Storage, settings.Images) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		object, ok := objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(object.size))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := settings.Images{
+		Backend:          "s3",
+		PublicURL:        "https://cdn.example.com",
+		UploadExpiration: 900,
+		MaxSize:          1024,
+		ContentTypes:     []string{"image/png"},
+		MaxImages:        1,
+		S3:               settings.S3{Endpoint: server.URL, Region: "us-east-1", Bucket: "images", AccessKeyID: "id", SecretAccessKey: "secret"},
+	}
+
+	imageStorage, err := storage.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return imageStorage, cfg
+}
+
+func TestImageUploadHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+
+	potion, err := app.ItemsRepository.GetByFilter(context.Background(), bson.M{"name": "Potion"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := imageKeyPrefix(app.Settings.Tenancy.DefaultTenant, potion.ID)
+	uploaded := prefix + primitive.NewObjectID().Hex()
+	tooLarge := prefix + primitive.NewObjectID().Hex()
+	notImage := prefix + primitive.NewObjectID().Hex()
+
+	app.ImageStorage, app.Settings.Images = newTestImageStorage(t, map[string]fakeObject{
+		"/images/" + uploaded: {size: 512, contentType: "image/png"},
+		"/images/" + tooLarge: {size: 2048, contentType: "image/png"},
+		"/images/" + notImage: {size: 512, contentType: "text/html"},
+	})
+
+	// The generator is not run so the thumbnails are never generated
+	app.ThumbnailGenerator = thumbnail.NewGenerator(nil, nil, app.Settings.Images, app.Logger, testThumbnailMetrics)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	uploadsPath := fmt.Sprintf("/v1/items/%s/images/uploads", potion.ID.Hex())
+	imagesPath := fmt.Sprintf("/v1/items/%s/images", potion.ID.Hex())
+
+	tests := []struct {
+		testName           string
+		urlPath            string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", uploadsPath, map[string]any{"content_type": "image/png"}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Non-existent ID", fmt.Sprintf("/v1/items/%s/images/uploads", primitive.NewObjectID().Hex()), map[string]any{"content_type": "image/png"}, accessTokenUser1, http.StatusNotFound, []byte("the requested resource could not be found")},
+		{"Invalid content type", uploadsPath, map[string]any{"content_type": "text/html"}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be one of image/png")},
+		{"Valid upload", uploadsPath, map[string]any{"content_type": "image/png"}, accessTokenUser1, http.StatusCreated, []byte(`"method": "PUT"`)},
+		{"Key of another item", imagesPath, map[string]any{"key": imageKeyPrefix(app.Settings.Tenancy.DefaultTenant, primitive.NewObjectID()) + primitive.NewObjectID().Hex()}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be a key issued for an upload of the item")},
+		{"Not uploaded", imagesPath, map[string]any{"key": prefix + primitive.NewObjectID().Hex()}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("was not uploaded")},
+		{"Too large", imagesPath, map[string]any{"key": tooLarge}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must not be larger than 1024 bytes")},
+		{"Not an image", imagesPath, map[string]any{"key": notImage}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must have one of the content types image/png")},
+		{"Valid confirmation", imagesPath, map[string]any{"key": uploaded}, accessTokenUser1, http.StatusCreated, []byte(`"url": "https://cdn.example.com/` + uploaded + `"`)},
+		{"Too many images", uploadsPath, map[string]any{"content_type": "image/png"}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must not contain more than 1 images")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, tt.urlPath, tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	item, err := app.ItemsRepository.GetByID(context.Background(), potion.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(item.Images) != 1 || item.Images[0].Key != uploaded {
+		t.Errorf("want image %q recorded; got %v", uploaded, item.Images)
+	}
+}
+
+func TestGetItemImageHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	app.ImageStorage, app.Settings.Images = newTestImageStorage(t, nil)
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+
+	potion, err := app.ItemsRepository.GetByFilter(context.Background(), bson.M{"name": "Potion"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ether, err := app.ItemsRepository.GetByFilter(context.Background(), bson.M{"name": "Ether"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	potion.Images = []data.ItemImage{{
+		Key:        "items/default/potion",
+		URL:        "https://cdn.example.com/items/default/potion",
+		Thumbnails: []data.Thumbnail{{Size: thumbnail.Small, URL: "https://cdn.example.com/items/default/potion-small"}},
+	}}
+
+	err = app.ItemsRepository.Update(context.Background(), potion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Check the redirections instead of following them
+	ts.Client().CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	tests := []struct {
+		testName         string
+		urlPath          string
+		wantedStatusCode int
+		wantedLocation   string
+	}{
+		{"Original", fmt.Sprintf("/v1/items/%s/image", potion.ID.Hex()), http.StatusFound, "https://cdn.example.com/items/default/potion"},
+		{"Thumbnail", fmt.Sprintf("/v1/items/%s/image?size=small", potion.ID.Hex()), http.StatusFound, "https://cdn.example.com/items/default/potion-small"},
+		{"Thumbnail not generated", fmt.Sprintf("/v1/items/%s/image?size=medium", potion.ID.Hex()), http.StatusFound, "https://cdn.example.com/items/default/potion"},
+		{"Invalid size", fmt.Sprintf("/v1/items/%s/image?size=large", potion.ID.Hex()), http.StatusUnprocessableEntity, ""},
+		{"Item without image", fmt.Sprintf("/v1/items/%s/image", ether.ID.Hex()), http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, headers, _ := ts.get(t, tt.urlPath, true, accessTokenUser2)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if headers.Get("Location") != tt.wantedLocation {
+				t.Errorf("want %q; got %q", tt.wantedLocation, headers.Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// batchGetItemsHandler is the handler for the "GET /internal/items" endpoint.
+// It returns the items with the ids of the "ids" query string parameter, in the order of the ids,
+// along with the ids which were not found. Expired items are returned since the Play services
+// holding them (i.e. in an inventory) still need their details.
+func (app *Application) batchGetItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving items in batch")
+	defer span.End()
+
+	// Instantiate validator
+	v := validation.New()
+
+	ids := app.readBulkIDs(v, app.ReadCsvFromQueryString(r.URL.Query(), "ids", []string{}))
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("ids", len(ids)))
+
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	findOpts := filters.Filters{Page: 1, PageSize: len(ids), Sort: "_id", SortSafelist: []string{"_id"}}
+
+	found, _, err := app.ItemsRepository.GetAllWithOptions(ctx, filter, findOpts, data.ListOptions{SkipCount: true})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	foundByID := make(map[primitive.ObjectID]data.Item, len(found))
+	for _, item := range found {
+		foundByID[item.ID] = item
+	}
+
+	items := make([]data.Item, 0, len(found))
+	missing := []string{}
+
+	for _, id := range ids {
+		if item, ok := foundByID[id]; ok {
+			items = append(items, item)
+		} else {
+			missing = append(missing, id.Hex())
+		}
+	}
+
+	env := types.Envelope{
+		"items":   items,
+		"missing": missing,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBatchGetItemsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	// The client certificates are verified by the internal listener
+	ts := newTestServer(t, app.internalTenant(http.HandlerFunc(app.batchGetItemsHandler)))
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		query              string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No ids", "", http.StatusUnprocessableEntity, []byte("must contain at least one id")},
+		{"Invalid id", fmt.Sprintf("%s,potion", ids["Potion"]), http.StatusUnprocessableEntity, []byte("must only contain valid ids")},
+		{"Duplicate ids", fmt.Sprintf("%s,%s", ids["Potion"], ids["Potion"]), http.StatusUnprocessableEntity, []byte("must not contain duplicate values")},
+		{"Items in the order of the ids", fmt.Sprintf("%s,%s", ids["Mega Potion"], ids["Ether"]), http.StatusOK, []byte(`"name": "Mega Potion"`)},
+		{"Unknown item", fmt.Sprintf("%s,63407e2c8bcd4a43ec1c4ff4", ids["Potion"]), http.StatusOK, []byte("\"missing\": [\n\t\t\"63407e2c8bcd4a43ec1c4ff4\"\n\t]")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, "/internal/items?ids="+tt.query, false, "")
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// readItemCollectionIDs converts the ids of the items of a collection into ObjectIDs
+func readItemCollectionIDs(v *validation.Validator, ids []string) []primitive.ObjectID {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			v.AddError("item_ids", "invalid_format", "must only contain valid ids")
+			continue
+		}
+
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	return objectIDs
+}
+
+// getTenantItemCollection retrieves the item collection of the request tenant with the id of the request URL parameters.
+// It sends the error response itself and returns false if the item collection could not be retrieved.
+func (app *Application) getTenantItemCollection(ctx context.Context, w http.ResponseWriter, r *http.Request) (data.ItemCollection, bool) {
+	span := trace.SpanFromContext(ctx)
+
+	// Extract id parameter from request URL parameters
+	id, err := app.ReadObjectIDParam(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("id", chi.URLParamFromCtx(r.Context(), "id")))
+
+		// Throw Not found error if extracted id is not a valid ObjectID
+		app.NotFoundResponse(w, r)
+		return data.ItemCollection{}, false
+	}
+
+	// Record item collection id in the trace
+	span.SetAttributes(attribute.String("id", id.Hex()))
+
+	// Item collections of the other tenants are never found
+	collection, err := app.ItemCollectionsRepository.GetByFilter(ctx, bson.M{"_id": id, data.TenantField: app.contextTenant(ctx)})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return data.ItemCollection{}, false
+	}
+
+	return collection, true
+}
+
+// getItemCollectionsHandler is the handler for the "GET /v1/collections" endpoint
+func (app *Application) getItemCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item collections")
+	defer span.End()
+
+	// Read query string
+	queryString := r.URL.Query()
+
+	// Instantiate validator
+	v := validation.New()
+
+	// Extract values from query string if they exist
+	findOpts := filters.Filters{
+		Page:         app.readIntFromQueryString(queryString, "page", 1, v),
+		PageSize:     app.readIntFromQueryString(queryString, "page_size", 20, v),
+		Sort:         app.ReadStringFromQueryString(queryString, "sort", "name"),
+		SortSafelist: []string{"name", "starts_at", "ends_at", "-name", "-starts_at", "-ends_at"},
+	}
+	active := app.readBoolFromQueryString(queryString, "active", false, v)
+
+	data.ValidateFilters(v, findOpts)
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	filter := bson.M{data.TenantField: app.contextTenant(ctx)}
+	if active {
+		filter["$and"] = data.ActiveItemCollectionsFilter(time.Now().UTC())
+	}
+
+	// Retrieve the item collections of the tenant
+	collections, metadata, err := app.ItemCollectionsRepository.GetAll(ctx, filter, findOpts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"collections": collections,
+		"metadata":    metadata,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getItemCollectionHandler is the handler for the "GET /v1/collections/:id" endpoint
+func (app *Application) getItemCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item collection")
+	defer span.End()
+
+	collection, ok := app.getTenantItemCollection(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	env := types.Envelope{
+		"collection": collection,
+	}
+
+	err := app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getItemCollectionItemsHandler is the handler for the "GET /v1/collections/:id/items" endpoint.
+// It returns the items of an active collection in the order of the collection. The items which were
+// deleted or which expired since they were added are skipped.
+func (app *Application) getItemCollectionItemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item collection items")
+	defer span.End()
+
+	collection, ok := app.getTenantItemCollection(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	// Collections are not shown outside of their active window
+	if !collection.Active(now) {
+		span.SetStatus(codes.Error, "Inactive item collection")
+		app.NotFoundResponse(w, r)
+		return
+	}
+
+	items := []data.Item{}
+
+	if len(collection.ItemIDs) != 0 {
+		filter := bson.M{"_id": bson.M{"$in": collection.ItemIDs}, "$or": data.NotExpiredFilter(now)}
+		findOpts := filters.Filters{Page: 1, PageSize: len(collection.ItemIDs), Sort: "_id", SortSafelist: []string{"_id"}}
+
+		found, _, err := app.ItemsRepository.GetAllWithOptions(ctx, filter, findOpts, data.ListOptions{SkipCount: true})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		foundByID := make(map[primitive.ObjectID]data.Item, len(found))
+		for _, item := range found {
+			foundByID[item.ID] = item
+		}
+
+		for _, id := range collection.ItemIDs {
+			if item, ok := foundByID[id]; ok {
+				items = append(items, item)
+			}
+		}
+	}
+
+	env := types.Envelope{
+		"collection": collection,
+		"items":      items,
+	}
+
+	err := app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// createItemCollectionHandler is the handler for the "POST /v1/collections" endpoint
+func (app *Application) createItemCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Creating item collection")
+	defer span.End()
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		ItemIDs     []string   `json:"item_ids"`
+		StartsAt    *time.Time `json:"starts_at"`
+		EndsAt      *time.Time `json:"ends_at"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	// Copy the values from the input struct to a new ItemCollection struct
+	collection := data.ItemCollection{
+		TenantID:    app.contextTenant(ctx),
+		Name:        input.Name,
+		Description: input.Description,
+		ItemIDs:     readItemCollectionIDs(v, input.ItemIDs),
+		StartsAt:    utcTime(input.StartsAt),
+		EndsAt:      utcTime(input.EndsAt),
+		CreatedBy:   app.ContextGetUser(r).ID,
+		Version:     1,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+
+	// Perform validation checks
+	data.ValidateItemCollection(v, collection)
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Record item collection attributes in trace
+	span.SetAttributes(attribute.String("name", collection.Name), attribute.Int("items", len(collection.ItemIDs)))
+
+	// Create a record in the database
+	id, err := app.ItemCollectionsRepository.Create(ctx, collection)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	collection.ID = *id
+
+	// Include a Location header to let the client know where to find the newly-created resource
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/collections/%s", collection.ID.Hex()))
+
+	env := types.Envelope{
+		"collection": collection,
+	}
+
+	err = app.WriteJSON(w, http.StatusCreated, env, headers)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// updateItemCollectionHandler is the handler for the "PUT /v1/collections/:id" endpoint
+func (app *Application) updateItemCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Updating item collection")
+	defer span.End()
+
+	collection, ok := app.getTenantItemCollection(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// We use pointers so that we get a nil value when decoding these values from JSON.
+	// This way we can check if a user has provided the key/value pair in the JSON or not.
+	var input struct {
+		Name        *string   `json:"name"`
+		Description *string   `json:"description"`
+		ItemIDs     *[]string `json:"item_ids"`
+		// Raw values are used to tell a missing bound of the active window apart from a null one, which clears it
+		StartsAt json.RawMessage `json:"starts_at"`
+		EndsAt   json.RawMessage `json:"ends_at"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	// Copy the values from the input struct to the fetched item collection if they exist
+	if input.Name != nil {
+		collection.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		collection.Description = *input.Description
+	}
+
+	if input.ItemIDs != nil {
+		collection.ItemIDs = readItemCollectionIDs(v, *input.ItemIDs)
+	}
+
+	for _, bound := range []struct {
+		key   string
+		raw   json.RawMessage
+		value **time.Time
+	}{
+		{"starts_at", input.StartsAt, &collection.StartsAt},
+		{"ends_at", input.EndsAt, &collection.EndsAt},
+	} {
+		if bound.raw == nil {
+			continue
+		}
+
+		var t *time.Time
+
+		err = json.Unmarshal(bound.raw, &t)
+		if err != nil {
+			v.AddError(bound.key, "invalid_format", "must be a valid RFC3339 timestamp or null")
+			continue
+		}
+
+		*bound.value = utcTime(t)
+	}
+
+	collection.UpdatedAt = time.Now().UTC()
+
+	// Perform validation checks
+	data.ValidateItemCollection(v, collection)
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Update item collection in the database
+	err = app.ItemCollectionsRepository.Update(ctx, collection)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrEditConflict):
+			app.EditConflictResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	env := types.Envelope{
+		"collection": collection,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// deleteItemCollectionHandler is the handler for the "DELETE /v1/collections/:id" endpoint.
+// The items of the collection are left untouched.
+func (app *Application) deleteItemCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Deleting item collection")
+	defer span.End()
+
+	collection, ok := app.getTenantItemCollection(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// Delete item collection in the database
+	err := app.ItemCollectionsRepository.Delete(ctx, collection.ID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	env := types.Envelope{
+		"message": "Item collection deleted successfully",
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreateItemCollectionHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", map[string]any{"name": "Summer sale", "item_ids": []string{ids["Potion"]}}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Valid submission", map[string]any{"name": "Summer sale", "item_ids": []string{ids["Ether"], ids["Potion"]}}, accessTokenUser1, http.StatusCreated, []byte(`"name": "Summer sale"`)},
+		{"Empty name", map[string]any{"name": "", "item_ids": []string{ids["Potion"]}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"Invalid item id", map[string]any{"name": "Summer sale", "item_ids": []string{"potion"}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must only contain valid ids")},
+		{"Duplicated item ids", map[string]any{"name": "Summer sale", "item_ids": []string{ids["Potion"], ids["Potion"]}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must not contain duplicate values")},
+		{"Window ending before it starts", map[string]any{"name": "Summer sale", "starts_at": time.Now().Add(time.Hour), "ends_at": time.Now()}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be later than specified starts_at")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, "/v1/collections", tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestItemCollectionItemsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	createCollection := func(t *testing.T, body map[string]any) string {
+		statusCode, _, resBody := ts.post(t, "/v1/collections", body, true, accessTokenUser1)
+		if statusCode != http.StatusCreated {
+			t.Fatalf("want %d; got %d", http.StatusCreated, statusCode)
+		}
+
+		var response struct {
+			Collection struct {
+				ID string `json:"id"`
+			} `json:"collection"`
+		}
+
+		err := json.Unmarshal(resBody, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return response.Collection.ID
+	}
+
+	active := createCollection(t, map[string]any{"name": "Summer sale", "item_ids": []string{ids["Mega Potion"], ids["Ether"], ids["Potion"]}})
+	upcoming := createCollection(t, map[string]any{"name": "Winter sale", "item_ids": []string{ids["Potion"]}, "starts_at": time.Now().Add(24 * time.Hour)})
+
+	t.Run("Items in the order of the collection", func(t *testing.T) {
+		statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/collections/%s/items", active), true, accessTokenUser2)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		var response struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+		}
+
+		err := json.Unmarshal(resBody, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := []string{}
+		for _, item := range response.Items {
+			names = append(names, item.Name)
+		}
+
+		if fmt.Sprint(names) != fmt.Sprint([]string{"Mega Potion", "Ether", "Potion"}) {
+			t.Errorf("want %v; got %v", []string{"Mega Potion", "Ether", "Potion"}, names)
+		}
+	})
+
+	t.Run("Inactive collection", func(t *testing.T) {
+		statusCode, _, _ := ts.get(t, fmt.Sprintf("/v1/collections/%s/items", upcoming), true, accessTokenUser2)
+
+		if statusCode != http.StatusNotFound {
+			t.Errorf("want %d; got %d", http.StatusNotFound, statusCode)
+		}
+	})
+
+	t.Run("Active collections", func(t *testing.T) {
+		statusCode, _, resBody := ts.get(t, "/v1/collections?active=true", true, accessTokenUser2)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		if !bytes.Contains(resBody, []byte(`"name": "Summer sale"`)) || bytes.Contains(resBody, []byte(`"name": "Winter sale"`)) {
+			t.Errorf("want body %q to only contain the active collection", resBody)
+		}
+	})
+
+	t.Run("Window cleared", func(t *testing.T) {
+		statusCode, _, _ := ts.put(t, fmt.Sprintf("/v1/collections/%s", upcoming), map[string]any{"starts_at": nil}, true, accessTokenUser1)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/collections/%s/items", upcoming), true, accessTokenUser2)
+
+		if statusCode != http.StatusOK {
+			t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		if !bytes.Contains(resBody, []byte(`"name": "Potion"`)) {
+			t.Errorf("want body %q to contain %q", resBody, `"name": "Potion"`)
+		}
+	})
+
+	t.Run("Deleted collection", func(t *testing.T) {
+		statusCode, _, _ := ts.delete(t, fmt.Sprintf("/v1/collections/%s", active), true, accessTokenUser1)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		statusCode, _, _ = ts.get(t, fmt.Sprintf("/v1/collections/%s", active), true, accessTokenUser2)
+
+		if statusCode != http.StatusNotFound {
+			t.Errorf("want %d; got %d", http.StatusNotFound, statusCode)
+		}
+	})
+}
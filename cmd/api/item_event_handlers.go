@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// itemEvents is implemented by the event-sourced items repositories, from which the events of the items
+// and their state at any time are retrieved
+type itemEvents interface {
+	Events(ctx context.Context, id primitive.ObjectID, tenant string, after int64, limit int) ([]data.ItemEvent, error)
+	LoadAt(ctx context.Context, id primitive.ObjectID, tenant string, at time.Time) (data.Item, error)
+	Rebuild(ctx context.Context, id primitive.ObjectID, tenant string) (data.Item, error)
+}
+
+// getItemEventsHandler is the handler for the "GET /admin/items/{id}/events" endpoint.
+// It returns the events of an item in the order in which they happened, following the "after" sequence.
+func (app *Application) getItemEventsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item events")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// Instantiate validator
+	v := validation.New()
+
+	after := app.readIntFromQueryString(r.URL.Query(), "after", 0, v)
+	limit := app.readIntFromQueryString(r.URL.Query(), "limit", 20, v)
+	v.Check(after >= 0, "after", "out_of_range", "must be greater or equal to 0")
+	v.Check(validator.Between(limit, 1, 100), "limit", "out_of_range", "must be greater or equal to 1 and lower or equal to 100")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	events, err := app.ItemEvents.Events(ctx, id, app.contextTenant(ctx), int64(after), limit)
+	if err != nil {
+		app.itemEventsErrorResponse(w, r, span, err)
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"events": events}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getItemStateHandler is the handler for the "GET /admin/items/{id}/state" endpoint.
+// It returns the item as it was at the "at" time, or as it currently is, derived from its events.
+func (app *Application) getItemStateHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item state")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// Instantiate validator
+	v := validation.New()
+
+	var at time.Time
+
+	if value := r.URL.Query().Get("at"); value != "" {
+		var err error
+
+		at, err = time.Parse(time.RFC3339, value)
+		v.Check(err == nil, "at", "invalid_format", "must be a RFC 3339 date")
+	}
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if !at.IsZero() {
+		span.SetAttributes(attribute.String("at", at.UTC().Format(time.RFC3339)))
+	}
+
+	item, err := app.ItemEvents.LoadAt(ctx, id, app.contextTenant(ctx), at.UTC())
+	if err != nil {
+		app.itemEventsErrorResponse(w, r, span, err)
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"item": item}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// rebuildItemHandler is the handler for the "POST /admin/items/{id}/rebuild" endpoint.
+// It replaces the stored item with the state derived from its events, i.e. after it was changed by hand.
+func (app *Application) rebuildItemHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Rebuilding item")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	item, err := app.ItemEvents.Rebuild(ctx, id, app.contextTenant(ctx))
+	if err != nil {
+		app.itemEventsErrorResponse(w, r, span, err)
+		return
+	}
+
+	app.Logger.Info("Item rebuilt from its events", map[string]string{"id": id.Hex()})
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"item": item}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// itemEventsErrorResponse sends the error response of a failed operation on the events of an item
+func (app *Application) itemEventsErrorResponse(w http.ResponseWriter, r *http.Request, span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	switch {
+	case errors.Is(err, database.ErrRecordNotFound):
+		app.NotFoundResponse(w, r)
+	case errors.Is(err, database.ErrEditConflict):
+		app.EditConflictResponse(w, r)
+	default:
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeItemEvents holds the events of a single item, which was renamed after its creation
+type fakeItemEvents struct {
+	id      primitive.ObjectID
+	renamed time.Time
+}
+
+// Events returns the events of the item following the given sequence
+func (store *fakeItemEvents) Events(ctx context.Context, id primitive.ObjectID, tenant string, after int64, limit int) ([]data.ItemEvent, error) {
+	if id != store.id {
+		return nil, database.ErrRecordNotFound
+	}
+
+	events := []data.ItemEvent{}
+	for _, event := range []data.ItemEvent{
+		{Sequence: 1, Type: data.ItemCreatedEvent, Version: 1, Set: bson.M{"name": "Potion"}},
+		{Sequence: 2, Type: data.ItemUpdatedEvent, Version: 2, Set: bson.M{"name": "Elixir"}, OccurredAt: store.renamed},
+	} {
+		if event.Sequence > after && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// LoadAt returns the item with the name it had at the given time
+func (store *fakeItemEvents) LoadAt(ctx context.Context, id primitive.ObjectID, tenant string, at time.Time) (data.Item, error) {
+	if id != store.id {
+		return data.Item{}, database.ErrRecordNotFound
+	}
+
+	if !at.IsZero() && at.Before(store.renamed) {
+		return data.Item{ID: id, Name: "Potion", Version: 1}, nil
+	}
+
+	return data.Item{ID: id, Name: "Elixir", Version: 2}, nil
+}
+
+// Rebuild returns the current state of the item
+func (store *fakeItemEvents) Rebuild(ctx context.Context, id primitive.ObjectID, tenant string) (data.Item, error) {
+	return store.LoadAt(ctx, id, tenant, time.Time{})
+}
+
+func TestItemEventHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	id := primitive.NewObjectID()
+	app.ItemEvents = &fakeItemEvents{id: id, renamed: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	path := "/admin/items/" + id.Hex()
+
+	tests := []struct {
+		testName           string
+		method             string
+		urlPath            string
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", http.MethodGet, path + "/events", accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Events", http.MethodGet, path + "/events", accessTokenUser1, http.StatusOK, []byte(`"type": "updated"`)},
+		{"Events after a sequence", http.MethodGet, path + "/events?after=1&limit=1", accessTokenUser1, http.StatusOK, []byte(`"sequence": 2`)},
+		{"Invalid limit", http.MethodGet, path + "/events?limit=101", accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be greater or equal to 1")},
+		{"Unknown item", http.MethodGet, "/admin/items/" + primitive.NewObjectID().Hex() + "/events", accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Invalid id", http.MethodGet, "/admin/items/123/events", accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Current state", http.MethodGet, path + "/state", accessTokenUser1, http.StatusOK, []byte(`"name": "Elixir"`)},
+		{"Past state", http.MethodGet, path + "/state?at=2024-02-01T00:00:00Z", accessTokenUser1, http.StatusOK, []byte(`"name": "Potion"`)},
+		{"Invalid time", http.MethodGet, path + "/state?at=yesterday", accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be a RFC 3339 date")},
+		{"Rebuild", http.MethodPost, path + "/rebuild", accessTokenUser1, http.StatusOK, []byte(`"version": 2`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, tt.urlPath, nil, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
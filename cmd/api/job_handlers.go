@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Types of the background jobs
+const (
+	priceAdjustmentJob   = "items.price_adjustment"
+	itemImportJob        = "items.import"
+	migrationBackfillJob = "migration.backfill"
+	searchReindexJob     = "search.reindex"
+	userResyncJob        = "users.resync"
+)
+
+// jobQueue is implemented by the pools running the background jobs
+type jobQueue interface {
+	Enqueue(ctx context.Context, jobType string, params any) (jobs.Job, error)
+	Get(ctx context.Context, id primitive.ObjectID, tenant string) (jobs.Job, error)
+}
+
+// jobUploads is implemented by the stores of the files uploaded for the background jobs
+type jobUploads interface {
+	Save(name string, jobType string, content io.Reader) (primitive.ObjectID, error)
+	Open(id primitive.ObjectID) (io.ReadCloser, error)
+	Delete(id primitive.ObjectID) error
+}
+
+// priceAdjustmentParams is a struct that holds the parameters of a price adjustment job
+type priceAdjustmentParams struct {
+	IDs     []primitive.ObjectID `bson:"ids"`
+	Percent float64              `bson:"percent"`
+}
+
+// prefersAsync returns true if the client asked for the request to be processed asynchronously (RFC 7240)
+func prefersAsync(r *http.Request) bool {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			if strings.EqualFold(strings.TrimSpace(preference), "respond-async") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// enqueueJob enqueues a job of the given type with the given parameters and sends the 202 response pointing to
+// its status. It returns false if the job could not be enqueued.
+func (app *Application) enqueueJob(w http.ResponseWriter, r *http.Request, span trace.Span, jobType string, params any) bool {
+	job, err := app.Jobs.Enqueue(r.Context(), jobType, params)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return false
+	}
+
+	span.SetAttributes(attribute.String("job_id", job.ID.Hex()))
+
+	app.Logger.Info("Job enqueued", map[string]string{"job_id": job.ID.Hex(), "type": jobType})
+
+	// Include a Location header to let the client know where to follow the progress of the job
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/jobs/%s", job.ID.Hex()))
+
+	if prefersAsync(r) {
+		headers.Set("Preference-Applied", "respond-async")
+	}
+
+	err = app.WriteJSON(w, http.StatusAccepted, types.Envelope{"job": job}, headers)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+
+	return true
+}
+
+// getJobHandler is the handler for the "GET /v1/jobs/{id}" endpoint.
+// It returns the status, progress, result and errors of a job of the tenant.
+func (app *Application) getJobHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving job")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	job, err := app.Jobs.Get(ctx, id, app.contextTenant(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"job": job}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// reindexSearchHandler is the handler for the "POST /admin/search/reindex" endpoint.
+// It enqueues a job indexing every item into Elasticsearch again, i.e. after the index was recreated.
+func (app *Application) reindexSearchHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Reindexing search")
+	defer span.End()
+
+	app.enqueueJob(w, r, span, searchReindexJob, nil)
+}
+
+// backfillMigrationHandler is the handler for the "POST /admin/migration/backfill" endpoint.
+// It enqueues a job copying the items and saved filters of the source store of a storage migration into its target store.
+func (app *Application) backfillMigrationHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Backfilling migration target")
+	defer span.End()
+
+	app.enqueueJob(w, r, span, migrationBackfillJob, nil)
+}
+
+// runPriceAdjustmentJob adjusts the prices of the items of a price adjustment job.
+// The validation errors of the adjusted prices fail the job without changing any price.
+func (app *Application) runPriceAdjustmentJob(ctx context.Context, job jobs.Job, reporter *jobs.Reporter) (bson.M, error) {
+	var params priceAdjustmentParams
+
+	err := job.DecodeParams(&params)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment, priceValidator, err := app.adjustItemPrices(ctx, params.IDs, params.Percent, reporter)
+	if err != nil {
+		return nil, err
+	}
+
+	if priceValidator != nil {
+		return nil, &jobs.ValidationError{Errors: priceValidator.Errors}
+	}
+
+	return bson.M{"updated": adjustment.Updated, "not_found": adjustment.NotFound}, nil
+}
+
+// newSearchReindexJob returns the handler of the reindex jobs, indexing every item with the given backfill.
+// The items are not scoped to the tenant of the job since the index holds the items of every tenant.
+func newSearchReindexJob(backfill func(ctx context.Context, progress func(indexed int, total int)) (int, error)) jobs.Handler {
+	return func(ctx context.Context, job jobs.Job, reporter *jobs.Reporter) (bson.M, error) {
+		indexed, err := backfill(ctx, func(indexed int, total int) {
+			reporter.SetTotal(int64(total))
+			reporter.Succeed(int64(indexed) - reporter.Progress().Succeeded)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.M{"indexed": indexed}, nil
+	}
+}
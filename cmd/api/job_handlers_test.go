@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeJobQueue keeps the enqueued jobs in memory without running them
+type fakeJobQueue struct {
+	jobs []jobs.Job
+}
+
+// Enqueue stores a new queued job of the tenant of the context
+func (queue *fakeJobQueue) Enqueue(ctx context.Context, jobType string, params any) (jobs.Job, error) {
+	tenant, _ := data.TenantFromContext(ctx)
+
+	job := jobs.Job{
+		ID:        primitive.NewObjectID(),
+		TenantID:  tenant,
+		Type:      jobType,
+		Status:    jobs.StatusQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if params != nil {
+		raw, err := bson.Marshal(params)
+		if err != nil {
+			return jobs.Job{}, err
+		}
+
+		job.Params = raw
+	}
+
+	queue.jobs = append(queue.jobs, job)
+
+	return job, nil
+}
+
+// Get retrieves the job of the given tenant with the given id
+func (queue *fakeJobQueue) Get(ctx context.Context, id primitive.ObjectID, tenant string) (jobs.Job, error) {
+	for _, job := range queue.jobs {
+		if job.ID == id && job.TenantID == tenant {
+			return job, nil
+		}
+	}
+
+	return jobs.Job{}, database.ErrRecordNotFound
+}
+
+// makeAsyncRequest sends a request with the given JSON body asking for an asynchronous response, as user 1
+func (ts *testServer) makeAsyncRequest(t *testing.T, method string, urlPath string, body string) (int, http.Header, []byte) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessTokenUser1)
+	req.Header.Set("Prefer", "respond-async")
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res.StatusCode, res.Header, resBody
+}
+
+func TestPrefersAsync(t *testing.T) {
+	tests := []struct {
+		testName string
+		headers  []string
+		wanted   bool
+	}{
+		{"No preference", nil, false},
+		{"Asynchronous response", []string{"respond-async"}, true},
+		{"Several preferences", []string{"return=minimal, Respond-Async"}, true},
+		{"Several headers", []string{"return=minimal", "respond-async"}, true},
+		{"Other preference", []string{"wait=10"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodPost, "/v1/items/price-adjustments", nil)
+			for _, header := range tt.headers {
+				r.Header.Add("Prefer", header)
+			}
+
+			if got := prefersAsync(r); got != tt.wanted {
+				t.Errorf("want %t; got %t", tt.wanted, got)
+			}
+		})
+	}
+}
+
+func TestJobHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	queue := &fakeJobQueue{}
+	app.Jobs = queue
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	var location string
+
+	t.Run("Asynchronous price adjustment", func(t *testing.T) {
+		statusCode, headers, body := ts.makeAsyncRequest(t, http.MethodPost, "/v1/items/price-adjustments", `{"ids": ["`+ids["Potion"]+`"], "percent": 10}`)
+
+		if statusCode != http.StatusAccepted {
+			t.Errorf("want %d; got %d", http.StatusAccepted, statusCode)
+		}
+
+		if !bytes.Contains(body, []byte(`"status": "queued"`)) {
+			t.Errorf("want body %q to contain %q", body, `"status": "queued"`)
+		}
+
+		location = headers.Get("Location")
+		if len(queue.jobs) != 1 || location != "/v1/jobs/"+queue.jobs[0].ID.Hex() {
+			t.Errorf("want a Location header pointing to the enqueued job; got %q", location)
+		}
+	})
+
+	tests := []struct {
+		testName           string
+		method             string
+		urlPath            string
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", http.MethodGet, location, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Job", http.MethodGet, location, accessTokenUser1, http.StatusOK, []byte(`"type": "` + priceAdjustmentJob + `"`)},
+		{"Unknown job", http.MethodGet, "/v1/jobs/" + primitive.NewObjectID().Hex(), accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Invalid id", http.MethodGet, "/v1/jobs/123", accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Reindex without search index", http.MethodPost, "/admin/search/reindex", accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Users resync without Identity microservice", http.MethodPost, "/admin/users/resync", accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, tt.urlPath, nil, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/logging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// getLogLevelHandler is the handler for the "GET /admin/loglevel" endpoint
+func (app *Application) getLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Retrieving log level")
+	defer span.End()
+
+	err := app.WriteJSON(w, http.StatusOK, types.Envelope{"log_level": app.LogFilter.Level().String()}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// updateLogLevelHandler is the handler for the "PUT /admin/loglevel" endpoint.
+// The level applies to the instance receiving the request until it is changed again or the instance restarts.
+func (app *Application) updateLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Updating log level")
+	defer span.End()
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		Level string `json:"level"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	level, err := logging.ParseLevel(input.Level)
+	if err != nil {
+		span.SetStatus(codes.Error, "Validation failed")
+		v := validation.New()
+		v.AddError("level", "unsupported_value", fmt.Sprintf("invalid level, must be one of %s", strings.Join(logging.Levels, ", ")))
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.String("level", level.String()))
+
+	previous := app.LogFilter.Level()
+	properties := map[string]string{"from": previous.String(), "to": level.String()}
+
+	// The change is logged while the most verbose of both levels is in effect so that it is never filtered out
+	if level > previous {
+		app.Logger.Info("Log level changed", properties)
+		app.LogFilter.SetLevel(level)
+	} else {
+		app.LogFilter.SetLevel(level)
+		app.Logger.Info("Log level changed", properties)
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"log_level": level.String()}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/logging"
+)
+
+func TestLogLevelHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		method             string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", http.MethodPut, map[string]any{"level": "debug"}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Current level", http.MethodGet, nil, accessTokenUser1, http.StatusOK, []byte(`"log_level": "info"`)},
+		{"Invalid level", http.MethodPut, map[string]any{"level": "verbose"}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("invalid level, must be one of debug, info, error")},
+		{"Switch to debug", http.MethodPut, map[string]any{"level": "debug"}, accessTokenUser1, http.StatusOK, []byte(`"log_level": "debug"`)},
+		{"Debug level", http.MethodGet, nil, accessTokenUser1, http.StatusOK, []byte(`"log_level": "debug"`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, "/admin/loglevel", tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	if level := app.LogFilter.Level(); level != logging.LevelDebug {
+		t.Errorf("want level %s; got %s", logging.LevelDebug, level)
+	}
+}
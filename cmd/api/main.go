@@ -2,43 +2,156 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/PlayEconomy37/Play.Catalog/internal/changestream"
 	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
 	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/identity"
+	"github.com/PlayEconomy37/Play.Catalog/internal/inventory"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jetstream"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"github.com/PlayEconomy37/Play.Catalog/internal/kafka"
+	"github.com/PlayEconomy37/Play.Catalog/internal/logging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/outbox"
+	"github.com/PlayEconomy37/Play.Catalog/internal/popularity"
 	"github.com/PlayEconomy37/Play.Catalog/internal/rabbitmq"
+	"github.com/PlayEconomy37/Play.Catalog/internal/sanitize"
+	"github.com/PlayEconomy37/Play.Catalog/internal/scheduler"
+	"github.com/PlayEconomy37/Play.Catalog/internal/search"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/storage"
+	"github.com/PlayEconomy37/Play.Catalog/internal/tagging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/thumbnail"
+	"github.com/PlayEconomy37/Play.Catalog/internal/tracing"
 	"github.com/PlayEconomy37/Play.Common/common"
 	"github.com/PlayEconomy37/Play.Common/configuration"
 	"github.com/PlayEconomy37/Play.Common/database"
 	"github.com/PlayEconomy37/Play.Common/events"
 	"github.com/PlayEconomy37/Play.Common/logger"
-	"github.com/PlayEconomy37/Play.Common/opentelemetry"
 	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel"
+
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // Application is a struct that defines the Catalog's microservice application.
 // It embeds the common packages common application struct.
 type Application struct {
 	common.App
-	ItemsRepository types.MongoRepository[primitive.ObjectID, data.Item]
-	UsersRepository types.MongoRepository[int64, database.User]
+	Settings           *settings.Settings
+	ItemsRepository    data.Repository[primitive.ObjectID, data.Item]
+	ListingsRepository data.Repository[primitive.ObjectID, data.Item] // Nil unless the listings are served from their read model
+	UsersRepository    types.MongoRepository[int64, data.User]
+	UserCache          *data.UserCache // Nil when the users are not cached
+	PublicLimiter      *clientLimiter  // Nil when the public catalog is disabled
+
+	SavedFiltersRepository    data.Repository[primitive.ObjectID, data.SavedFilter]
+	APIKeysRepository         data.Repository[primitive.ObjectID, data.APIKey]
+	ItemCollectionsRepository data.Repository[primitive.ObjectID, data.ItemCollection]
+	ReviewsRepository         data.Repository[primitive.ObjectID, data.Review]
+	RatingStore               *data.RatingStore
+	FavoritesRepository       data.Repository[primitive.ObjectID, data.Favorite]
+	RevisionStore             *data.RevisionStore
+	TaggingEngine             *tagging.Engine
+	Sanitizer                 *sanitize.Policy
+
+	SelftestItemsRepository data.Repository[primitive.ObjectID, data.Item]
+	RuntimeInfo             *runtimeInfo
+	SearchIndex             *search.Elasticsearch
+	SearchBackoff           *searchBackoff // Nil when the failed search backends are tried on every search
+
+	PopularityStore    *data.PopularityStore
+	PopularityCounter  *popularity.Counter
+	InventoryClient    *inventory.Client    // Nil when the stock expansion is disabled
+	IdentityClient     *identity.Client     // Nil when the users are not resynchronized
+	ImageStorage       *storage.Storage     // Nil when the images are disabled
+	ThumbnailGenerator *thumbnail.Generator // Nil when the images are disabled
+	EventSerializer    *messaging.Serializer
+	SnapshotPublisher  itemSnapshotPublisher
+	ItemUpdates        itemUpdatePublisher // Nil when the changes of the items are published from the change stream
+	OutboxRelay        outboxRelay
+	EventHistory       eventHistory
+	ParkingLot         parkingLot // Nil unless the events are consumed from RabbitMQ
+	ItemEvents         itemEvents // Nil unless the items are event sourced
+	Jobs               jobQueue
+	Uploads            jobUploads
+	Transactions       *data.Transactions
+	Maintenance        *maintenanceMode
+	LogFilter          *logging.LevelFilter
 }
 
 func main() {
-	// Setup logger
-	logger := logger.New(os.Stdout, logger.LevelInfo)
+	// Setup logger. Its entries go through a filter whose level can be changed at runtime.
+	logFilter := logging.NewLevelFilter(os.Stdout, logging.LevelInfo)
+	logger := logger.New(logFilter, logger.LevelInfo)
 
 	// Read configuration
-	config, err := configuration.LoadConfig("config/dev.json")
+	configFile := "config/dev.json"
+
+	config, err := configuration.LoadConfig(configFile)
 	if err != nil {
 		logger.Fatal(err, nil)
 	}
 
-	// Start MongoDB
-	mongoClient, err := database.NewMongoClient(config)
+	// Read Catalog specific settings
+	catalogSettings, err := settings.LoadSettings(configFile)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// The item fields are unknown to the settings package
+	if !validator.AllIn(catalogSettings.PublicCatalog.Fields, data.ItemFields...) {
+		logger.Fatal(fmt.Errorf("invalid public catalog fields %v", catalogSettings.PublicCatalog.Fields), nil)
+	}
+
+	logLevel, err := logging.ParseLevel(catalogSettings.Logging.Level)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	logFilter.SetLevel(logLevel)
+
+	// Export the resource usage of the Go runtime and of the process on /metrics
+	err = registerRuntimeCollectors(prometheus.DefaultRegisterer)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Initialize tracer
+	tracerProvider, err := tracing.SetupTracer(catalogSettings.Tracing, config.ServiceName)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error(err, nil)
+		}
+	}()
+
+	// Trace every MongoDB command as a child span of the current span
+	mongoMonitor := tracing.NewMongoMonitor(tracerProvider, catalogSettings.Tracing.MongoStatements)
+
+	// Start MongoDB. The connections of its pools are reported by /debug/vars.
+	poolStats := &data.PoolStats{}
+
+	mongoClient, err := data.NewMongoClient(config, catalogSettings.Database, mongoMonitor, poolStats)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
 
 	defer func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -50,50 +163,625 @@ func main() {
 	}()
 
 	// Create "items" collection
-	err = data.CreateItemsCollection(mongoClient, constants.Database)
+	err = data.CreateItemsCollection(mongoClient, constants.Database, catalogSettings.Pricing, catalogSettings.Constraints, catalogSettings.Expiration)
 	if err != nil {
 		logger.Fatal(err, nil)
 	}
 
 	// Create "users" collection
-	err = database.CreateUsersCollection(mongoClient, constants.Database)
+	err = data.CreateUsersCollection(mongoClient, constants.Database)
 	if err != nil {
 		logger.Fatal(err, nil)
 	}
 
-	// Initialize tracer
-	tracerProvider := opentelemetry.SetupTracer(false)
+	// Create "saved_filters" collection
+	err = data.CreateSavedFiltersCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
 
-	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	// Create "api_keys" collection
+	err = data.CreateAPIKeysCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
 
-		if err := tracerProvider.Shutdown(ctx); err != nil {
-			logger.Error(err, nil)
+	// Create "item_collections" collection
+	err = data.CreateItemCollectionsCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Create "reviews" collection
+	err = data.CreateReviewsCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Create "favorites" collection
+	err = data.CreateFavoritesCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Create "item_revisions" collection
+	err = data.CreateItemRevisionsCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Create "jobs" collection
+	err = jobs.CreateJobsCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Create "item_events" and "item_snapshots" collections
+	if catalogSettings.EventSourcing.Enabled {
+		err = data.CreateItemEventsCollections(mongoClient, constants.Database)
+		if err != nil {
+			logger.Fatal(err, nil)
 		}
-	}()
+	}
+
+	// Create "selftest_items" sandbox collection
+	err = data.CreateSelftestItemsCollection(mongoClient, constants.Database, catalogSettings.Pricing, catalogSettings.Constraints, catalogSettings.Expiration)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
 
-	// Connect to RabbitMQ
-	rabbitMQConnection, err := events.NewRabbitMQConnection(config)
+	// Create "item_popularity" collection
+	err = data.CreatePopularityCollection(mongoClient, constants.Database)
 	if err != nil {
 		logger.Fatal(err, nil)
 	}
 
-	defer rabbitMQConnection.Close()
+	// Create "outbox" collection
+	err = outbox.CreateOutboxCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Create "outbox_history" collection
+	err = outbox.CreateOutboxHistoryCollection(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Bring the validator and the indexes of the existing collections up to date
+	for _, collection := range []string{constants.ItemsCollection, constants.SelftestItemsCollection} {
+		changes, err := data.ReconcileItemsCollection(
+			context.Background(),
+			mongoClient,
+			constants.Database,
+			collection,
+			catalogSettings.Pricing,
+			catalogSettings.Constraints,
+			catalogSettings.Expiration,
+		)
+		if err != nil {
+			logger.Fatal(err, map[string]string{"collection": collection})
+		}
+
+		if len(changes) != 0 {
+			logger.Info("Collection reconciled", map[string]string{
+				"collection": collection,
+				"changes":    strings.Join(changes, ","),
+			})
+		}
+	}
+
+	// Assign the items created before the catalog was shared between tenants to the default tenant.
+	// The validator must accept the tenant of the items beforehand.
+	backfilled, err := data.BackfillTenant(
+		context.Background(),
+		mongoClient,
+		constants.Database,
+		constants.ItemsCollection,
+		catalogSettings.Tenancy.DefaultTenant,
+	)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	if backfilled != 0 {
+		logger.Info("Items assigned to the default tenant", map[string]string{
+			"tenant": catalogSettings.Tenancy.DefaultTenant,
+			"items":  fmt.Sprint(backfilled),
+		})
+	}
+
+	// Connect to the message broker. Kafka only receives the published events.
+	var rabbitMQConnection *rabbitmq.Connection
+	var jetStream nats.JetStreamContext
+
+	if catalogSettings.MessageBroker.Type == "nats" {
+		natsConnection, js, err := jetstream.Connect(catalogSettings.MessageBroker.NATS, config.ServiceName)
+		if err != nil {
+			logger.Fatal(err, nil)
+		}
+
+		defer natsConnection.Close()
+
+		err = jetstream.CreateStream(js, catalogSettings.MessageBroker.NATS)
+		if err != nil {
+			logger.Fatal(err, nil)
+		}
+
+		jetStream = js
+	} else {
+		// The connection is re-established when the broker restarts
+		rabbitMQConnection, err = rabbitmq.NewConnection(
+			func() (*amqp.Connection, error) { return events.NewRabbitMQConnection(config) },
+			catalogSettings.MessageBroker.RabbitMQ,
+			logger,
+		)
+		if err != nil {
+			logger.Fatal(err, nil)
+		}
+
+		defer rabbitMQConnection.Close()
+	}
 
 	// Create users repository
-	usersRepository := database.NewMongoRepository[int64, database.User](mongoClient, constants.Database, database.UsersCollection)
+	usersRepository := database.NewMongoRepository[int64, data.User](mongoClient, constants.Database, database.UsersCollection)
+
+	// The RabbitMQ consumers and publisher report the exchanges and queues they declare
+	consumerMetrics := messaging.NewConsumerMetrics(config.ServiceName)
+	consumers := []messagingTopology{}
+	rabbitMQConsumers := []*rabbitmq.Consumer{}
+
+	// startConsumer consumes the events of the given subscription from the message broker
+	startConsumer := func(subscription messaging.Subscription, handle messaging.Handler) {
+		var consumer messaging.Consumer
+
+		if jetStream != nil {
+			consumer = jetstream.NewConsumer(
+				jetStream,
+				catalogSettings.MessageBroker.NATS,
+				subscription,
+				handle,
+				config.ServiceName,
+				logger,
+				consumerMetrics,
+			)
+		} else {
+			rabbitMQConsumer := rabbitmq.NewConsumer(
+				rabbitMQConnection,
+				subscription,
+				handle,
+				config.ServiceName,
+				logger,
+				consumerMetrics,
+				catalogSettings.Consumers,
+			)
+
+			consumer = rabbitMQConsumer
+			consumers = append(consumers, rabbitMQConsumer)
+
+			// The messages of the broadcast subscriptions are not dead lettered
+			if !subscription.Broadcast {
+				rabbitMQConsumers = append(rabbitMQConsumers, rabbitMQConsumer)
+			}
+		}
+
+		// Watch the queue and consume events
+		go func() {
+			err := consumer.StartConsumer()
+			if err != nil {
+				logger.Fatal(err, map[string]string{"subscription": subscription.Name})
+			}
+		}()
+	}
+
+	// Cache the users authenticating the requests. The cached users are invalidated by their events.
+	userCache := data.NewUserCache(catalogSettings.UserCache, data.NewUserCacheMetrics(config.ServiceName))
+	usersStore := data.NewUsersStore(mongoClient, constants.Database)
+
+	startConsumer(messaging.UserUpdatedSubscription, messaging.NewUserUpdatedHandler(usersStore, userCache).Handle)
+	startConsumer(messaging.UserDeletedSubscription, messaging.NewUserDeletedHandler(usersStore, userCache).Handle)
+
+	// The events of the users are stored by a single instance, so every instance holds the changed users in its cache
+	if catalogSettings.UserCache.TTL != 0 {
+		userCacheHandler := messaging.NewUserCacheHandler(userCache)
+		startConsumer(messaging.UserCacheUpdatedSubscription, userCacheHandler.HandleUpdated)
+		startConsumer(messaging.UserCacheDeletedSubscription, userCacheHandler.HandleDeleted)
+		startConsumer(messaging.UserCacheErasedSubscription, userCacheHandler.HandleErasureRequested)
+	}
+
+	// Count the views and purchases of the items. The counts are kept in the main store.
+	popularityStore := data.NewPopularityStore(mongoClient, constants.Database)
+	popularityCounter := popularity.NewCounter(popularityStore, logger)
+
+	popularityCtx, stopPopularityCounter := context.WithCancel(context.Background())
+	go popularityCounter.Run(popularityCtx, time.Duration(catalogSettings.Popularity.FlushInterval)*time.Second)
+
+	if catalogSettings.Popularity.TrackPurchases {
+		startConsumer(messaging.PurchaseCompletedSubscription, messaging.NewPurchaseCompletedHandler(popularityCounter).Handle)
+	}
 
-	// Create consumer
-	updatedUserConsumer := rabbitmq.NewUserUpdatedConsumer(rabbitMQConnection, usersRepository, config.ServiceName, logger)
+	// Select the versions and the envelope of the published events
+	eventSerializer, err := messaging.NewSerializer(catalogSettings.Events, config.ServiceName)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Select the message broker the events are published to
+	var eventPublisher messaging.Publisher
+
+	switch catalogSettings.MessageBroker.Type {
+	case "kafka":
+		eventPublisher = kafka.NewPublisher(catalogSettings.MessageBroker.Kafka)
+	case "nats":
+		eventPublisher = jetstream.NewPublisher(jetStream)
+	default:
+		rabbitMQPublisher := rabbitmq.NewPublisher(rabbitMQConnection)
+		eventPublisher = rabbitMQPublisher
+		consumers = append(consumers, rabbitMQPublisher)
+	}
+
+	// Store the events in the outbox so that they are not lost while the message broker is unavailable.
+	// The relay publishes them in batches which are confirmed by the broker.
+	outboxStore := outbox.NewStore(mongoClient, constants.Database, time.Duration(catalogSettings.Outbox.HistoryRetention)*time.Second)
+	outboxRelay := outbox.NewRelay(
+		outboxStore,
+		eventPublisher,
+		catalogSettings.Outbox,
+		config.ServiceName,
+		logger,
+		outbox.NewMetrics(config.ServiceName),
+	)
+
+	go outboxRelay.Run(time.Duration(catalogSettings.Outbox.Interval) * time.Second)
+
+	// Publish the events of the expired items. The expirations are tracked in the main store.
+	itemExpiredPublisher := messaging.NewItemExpiredPublisher(
+		outbox.NewOutbox(outboxStore, eventPublisher),
+		eventSerializer,
+		config.ServiceName,
+	)
+
+	// Run the composite writes within transactions when the replica set supports them
+	transactions := data.NewTransactions(mongoClient, catalogSettings.Transactions.Enabled)
+
+	// Run the recurring maintenance tasks, each run on a single instance
+	taskScheduler := scheduler.New(
+		scheduler.NewStore(mongoClient, constants.Database),
+		catalogSettings.Scheduler,
+		config.ServiceName,
+		logger,
+		scheduler.NewMetrics(config.ServiceName),
+	)
+
+	taskScheduler.Register(newItemExpirationTask(
+		data.NewExpirationStore(mongoClient, constants.Database, constants.ItemsCollection),
+		itemExpiredPublisher,
+		transactions,
+		time.Duration(catalogSettings.Expiration.CheckInterval)*time.Second,
+		logger,
+	))
+	taskScheduler.Register(newOutboxCompactionTask(outboxStore, time.Duration(catalogSettings.Scheduler.OutboxCompactionInterval)*time.Second))
+
+	// The change streams of the items share their metrics, labelled by stream
+	changeStreamMetrics := changestream.NewMetrics(config.ServiceName)
+
+	// Publish the events of the changes made to the items, whichever their origin, from the change stream.
+	// Without change stream, the rollbacks publish their own events.
+	itemChangePublisher := messaging.NewItemChangePublisher(outbox.NewOutbox(outboxStore, eventPublisher), eventSerializer, config.ServiceName)
+
+	var itemUpdates itemUpdatePublisher = itemChangePublisher
+
+	if catalogSettings.ChangeStream.Enabled {
+		itemUpdates = nil
+
+		itemChangeWatcher := changestream.NewWatcher(
+			changestream.ItemsStream,
+			mongoClient.Database(constants.Database).Collection(constants.ItemsCollection),
+			changestream.NewStore(mongoClient, constants.Database),
+			itemChangePublisher,
+			transactions,
+			catalogSettings.ChangeStream,
+			logger,
+			changeStreamMetrics,
+		)
 
-	// Watch the queue and consume events
-	go func() {
-		err = updatedUserConsumer.StartConsumer()
+		go itemChangeWatcher.Run()
+	}
+
+	// Serve the listings from their read model, which is updated from another change stream of the items
+	var listingsRepository data.Repository[primitive.ObjectID, data.Item]
+
+	if catalogSettings.ReadModel.Enabled {
+		// Create "item_listings" collection before the listings are written
+		err := data.CreateItemListingsCollection(mongoClient, constants.Database)
 		if err != nil {
 			logger.Fatal(err, nil)
 		}
-	}()
+
+		listingStore := data.NewListingStore(mongoClient, constants.Database)
+
+		listingWatcher := changestream.NewWatcher(
+			changestream.ListingsStream,
+			mongoClient.Database(constants.Database).Collection(constants.ItemsCollection),
+			changestream.NewStore(mongoClient, constants.Database),
+			listingStore,
+			transactions,
+			catalogSettings.ChangeStream,
+			logger,
+			changeStreamMetrics,
+		)
+
+		go listingWatcher.Run()
+
+		listingsRepository = data.NewTenantRepository(
+			data.NewMongoRepository[primitive.ObjectID, data.Item](mongoClient, constants.Database, constants.ItemListingsCollection),
+			catalogSettings.Tenancy.DefaultTenant,
+		)
+	}
+
+	// Publish the catalog snapshots requested by the admins
+	itemSnapshotPublisher := messaging.NewItemSnapshotPublisher(eventPublisher, eventSerializer, config.ServiceName)
+
+	// Record the revisions of the items so that they can be rolled back
+	revisionStore := data.NewRevisionStore(mongoClient, constants.Database)
+
+	// Compile auto-tagging rules
+	taggingEngine, err := tagging.NewEngine(catalogSettings.Tagging.Rules)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Create catalog repositories
+	itemsRepository := data.NewMongoRepository[primitive.ObjectID, data.Item](mongoClient, constants.Database, constants.ItemsCollection)
+	savedFiltersRepository := data.NewMongoRepository[primitive.ObjectID, data.SavedFilter](mongoClient, constants.Database, constants.SavedFiltersCollection)
+
+	// Append the changes made to the items to their events, the items collection being their projection
+	var eventSourcedItems *data.EventSourcedRepository
+
+	if catalogSettings.EventSourcing.Enabled {
+		itemEventStore := data.NewItemEventStore(mongoClient, constants.Database, catalogSettings.EventSourcing.SnapshotInterval)
+		eventSourcedItems = data.NewEventSourcedRepository(itemsRepository, itemEventStore, transactions)
+		itemsRepository = eventSourcedItems
+	}
+
+	// Log the operations slower than the threshold along with their filter
+	if catalogSettings.SlowQueries.Threshold > 0 {
+		threshold := time.Duration(catalogSettings.SlowQueries.Threshold) * time.Millisecond
+		slowQueryMetrics := data.NewSlowQueryMetrics(config.ServiceName)
+
+		itemsRepository = data.NewSlowQueryRepository(itemsRepository, constants.ItemsCollection, threshold, logger, slowQueryMetrics)
+		savedFiltersRepository = data.NewSlowQueryRepository(savedFiltersRepository, constants.SavedFiltersCollection, threshold, logger, slowQueryMetrics)
+	}
+
+	// Retry the idempotent operations failing with a transient error (i.e. during a primary election)
+	if catalogSettings.Retries.Enabled {
+		retrier := data.NewRetrier(catalogSettings.Retries, data.NewRetryMetrics(config.ServiceName))
+
+		itemsRepository = data.NewRetryRepository(itemsRepository, constants.ItemsCollection, retrier)
+		savedFiltersRepository = data.NewRetryRepository(savedFiltersRepository, constants.SavedFiltersCollection, retrier)
+	}
+
+	// Fail fast while MongoDB is unhealthy instead of piling up the requests
+	if catalogSettings.CircuitBreaker.Enabled {
+		breaker := data.NewCircuitBreaker(catalogSettings.CircuitBreaker, data.NewCircuitBreakerMetrics(config.ServiceName))
+
+		itemsRepository = data.NewCircuitBreakerRepository(itemsRepository, constants.ItemsCollection, breaker)
+		savedFiltersRepository = data.NewCircuitBreakerRepository(savedFiltersRepository, constants.SavedFiltersCollection, breaker)
+	}
+
+	// Write to both stores while migrating to another storage backend
+	var migrationBackfill jobs.Handler
+
+	if catalogSettings.Migration.Mode == "dual-write" {
+		targetClient, cleanupTarget, err := setupMigrationTarget(catalogSettings, func(dsn string) (*mongo.Client, error) {
+			targetConfig := *config
+			targetConfig.DB.Dsn = dsn
+
+			return data.NewMongoClient(&targetConfig, catalogSettings.Database, mongoMonitor, nil)
+		})
+		if err != nil {
+			logger.Fatal(err, nil)
+		}
+
+		defer cleanupTarget()
+
+		targetDatabase := catalogSettings.Migration.TargetDatabase
+		dualWriteMetrics := data.NewDualWriteMetrics(config.ServiceName)
+
+		targetItems := data.NewMongoRepository[primitive.ObjectID, data.Item](targetClient, targetDatabase, constants.ItemsCollection)
+		targetSavedFilters := data.NewMongoRepository[primitive.ObjectID, data.SavedFilter](targetClient, targetDatabase, constants.SavedFiltersCollection)
+
+		itemsRepository = withDualWrite(
+			catalogSettings.Migration,
+			itemsRepository,
+			targetItems,
+			constants.ItemsCollection,
+			logger,
+			dualWriteMetrics,
+		)
+
+		savedFiltersRepository = withDualWrite(
+			catalogSettings.Migration,
+			savedFiltersRepository,
+			targetSavedFilters,
+			constants.SavedFiltersCollection,
+			logger,
+			dualWriteMetrics,
+		)
+
+		// The backfill copies the stored documents, including the fields written without the repositories
+		migrationBackfill = newMigrationBackfillJob(
+			data.NewMongoRepository[primitive.ObjectID, data.Item](mongoClient, constants.Database, constants.ItemsCollection),
+			targetItems,
+			data.NewMongoRepository[primitive.ObjectID, data.SavedFilter](mongoClient, constants.Database, constants.SavedFiltersCollection),
+			targetSavedFilters,
+		)
+
+		logger.Info("Dual write mode enabled", map[string]string{
+			"primary":         catalogSettings.Migration.Primary,
+			"target_database": targetDatabase,
+		})
+	}
+
+	// Mirror the items into Elasticsearch when it is the configured search backend. With change stream, the index
+	// is updated from a change stream of the items. Otherwise the stores writing the items without the repository
+	// re-index them through the indexer.
+	var searchIndex *search.Elasticsearch
+	var searchBackfill func(ctx context.Context, progress func(indexed int, total int)) (int, error)
+	var itemsIndexer data.ItemsIndexer
+
+	if catalogSettings.Search.Backend == "elasticsearch" && catalogSettings.ChangeStream.Enabled {
+		searchIndex = search.NewElasticsearch(catalogSettings.Search.Elasticsearch)
+
+		// The index is created and backfilled by the watcher, which resynchronizes it whenever it was deleted
+		streamIndexer := search.NewStreamIndexer(searchIndex, itemsRepository)
+		searchBackfill = streamIndexer.Backfill
+
+		searchWatcher := changestream.NewWatcher(
+			changestream.SearchStream,
+			mongoClient.Database(constants.Database).Collection(constants.ItemsCollection),
+			changestream.NewStore(mongoClient, constants.Database),
+			streamIndexer,
+			transactions,
+			catalogSettings.ChangeStream,
+			logger,
+			changeStreamMetrics,
+		)
+
+		go searchWatcher.Run()
+	} else if catalogSettings.Search.Backend == "elasticsearch" {
+		searchIndex = search.NewElasticsearch(catalogSettings.Search.Elasticsearch)
+		indexingRepository := search.NewIndexingRepository(itemsRepository, searchIndex, logger)
+		itemsRepository = indexingRepository
+		itemsIndexer = indexingRepository
+
+		searchBackfill = func(ctx context.Context, progress func(indexed int, total int)) (int, error) {
+			return search.Backfill(ctx, indexingRepository, searchIndex, progress)
+		}
+
+		// Index the existing items when the index is created. Searches fall back to
+		// the text index while Elasticsearch is unreachable.
+		go func() {
+			ctx := context.Background()
+
+			created, err := searchIndex.EnsureIndex(ctx)
+			if err != nil {
+				logger.Error(err, map[string]string{"store": "elasticsearch"})
+				return
+			}
+
+			if created {
+				indexed, err := search.Backfill(ctx, itemsRepository, searchIndex, nil)
+				if err != nil {
+					logger.Error(err, map[string]string{"store": "elasticsearch"})
+				}
+
+				logger.Info("Elasticsearch index backfilled", map[string]string{
+					"index": catalogSettings.Search.Elasticsearch.Index,
+					"items": fmt.Sprint(indexed),
+				})
+			}
+		}()
+	}
+
+	// Skip the search backend for a while once it failed, the searches using the text index in the meantime
+	var backoff *searchBackoff
+	if catalogSettings.Search.FailureBackoff != 0 {
+		backoff = newSearchBackoff(time.Duration(catalogSettings.Search.FailureBackoff) * time.Second)
+	}
+
+	// Erase the personal data of the users on request of the Identity microservice. The erasures are confirmed
+	// through the outbox and the erased items are re-indexed.
+	startConsumer(messaging.UserErasureRequestedSubscription, messaging.NewUserErasureHandler(
+		data.NewErasureStore(mongoClient, constants.Database, itemsIndexer),
+		userCache,
+		outbox.NewOutbox(outboxStore, eventPublisher),
+		eventSerializer,
+		config.ServiceName,
+	).Handle)
+
+	// Retrieve the stock of the items from the Inventory microservice when it is configured
+	var inventoryClient *inventory.Client
+
+	if catalogSettings.Inventory.URL != "" {
+		inventoryClient = inventory.NewClient(catalogSettings.Inventory)
+	}
+
+	// Resynchronize the users from the Identity microservice when it is configured
+	var identityClient *identity.Client
+
+	if catalogSettings.Identity.URL != "" {
+		identityClient = identity.NewClient(catalogSettings.Identity)
+	}
+
+	// Store the images of the items in the object storage when it is configured
+	var imageStorage *storage.Storage
+	var thumbnailGenerator *thumbnail.Generator
+
+	if catalogSettings.Images.Backend != "" {
+		imageStorage, err = storage.New(catalogSettings.Images)
+		if err != nil {
+			logger.Fatal(err, nil)
+		}
+
+		// Generate the thumbnails of the confirmed images in the background
+		thumbnailsStore := data.NewThumbnailsStore(mongoClient, constants.Database, itemsIndexer)
+		thumbnailGenerator = thumbnail.NewGenerator(imageStorage, thumbnailsStore, catalogSettings.Images, logger, thumbnail.NewMetrics(config.ServiceName))
+
+		go thumbnailGenerator.Run()
+	}
+
+	// Store the background jobs so that their status can be retrieved from every instance
+	jobPool := jobs.NewPool(
+		jobs.NewStore(mongoClient, constants.Database, time.Duration(catalogSettings.Jobs.Retention)*time.Second),
+		catalogSettings.Jobs,
+		config.ServiceName,
+		logger,
+		jobs.NewMetrics(config.ServiceName),
+	)
+
+	// The files uploaded for the jobs (i.e. the imports) are stored in GridFS to be read by any instance
+	jobUploadStore, err := jobs.NewUploadStore(mongoClient, constants.Database)
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	// Log a summary of the environment for operators
+	runtimeInfo, err := collectRuntimeInfo(
+		context.Background(),
+		configFile,
+		catalogSettings,
+		mongoClient,
+		constants.Database,
+		rabbitMQConnection,
+		consumers...,
+	)
+	if err != nil {
+		logger.Error(err, nil)
+	}
+
+	logger.Info("Runtime information", runtimeInfo.logProperties())
+
+	// Limit the rate of the anonymous requests of the public catalog per client
+	var publicLimiter *clientLimiter
+	if catalogSettings.PublicCatalog.Enabled {
+		publicLimiter = newClientLimiter(catalogSettings.PublicCatalog.RateLimit, catalogSettings.PublicCatalog.Burst)
+	}
+
+	// Share the maintenance mode between the instances, which read it again periodically
+	maintenance := newMaintenanceMode(data.NewMaintenanceStore(mongoClient, constants.Database), logger)
+
+	if catalogSettings.Administration.Maintenance {
+		_, err = maintenance.Set(context.Background(), true)
+	} else {
+		_, err = maintenance.Refresh(context.Background())
+	}
+
+	if err != nil {
+		logger.Fatal(err, nil)
+	}
+
+	go maintenance.Run(time.Duration(catalogSettings.Administration.MaintenancePollInterval) * time.Second)
 
 	app := &Application{
 		App: common.App{
@@ -101,11 +789,93 @@ func main() {
 			Logger: logger,
 			Tracer: otel.Tracer(config.ServiceName),
 		},
-		ItemsRepository: database.NewMongoRepository[primitive.ObjectID, data.Item](mongoClient, constants.Database, constants.ItemsCollection),
-		UsersRepository: usersRepository,
+		Settings:           catalogSettings,
+		ItemsRepository:    data.NewTenantRepository(data.NewActorRepository(data.NewRevisionRepository(itemsRepository, revisionStore, transactions)), catalogSettings.Tenancy.DefaultTenant),
+		ListingsRepository: listingsRepository,
+		UsersRepository:    usersRepository,
+		UserCache:          userCache,
+		PublicLimiter:      publicLimiter,
+
+		SavedFiltersRepository:    savedFiltersRepository,
+		APIKeysRepository:         data.NewMongoRepository[primitive.ObjectID, data.APIKey](mongoClient, constants.Database, constants.APIKeysCollection),
+		ItemCollectionsRepository: data.NewMongoRepository[primitive.ObjectID, data.ItemCollection](mongoClient, constants.Database, constants.ItemCollectionsCollection),
+		ReviewsRepository:         data.NewMongoRepository[primitive.ObjectID, data.Review](mongoClient, constants.Database, constants.ReviewsCollection),
+		RatingStore:               data.NewRatingStore(mongoClient, constants.Database, itemsIndexer),
+		FavoritesRepository:       data.NewMongoRepository[primitive.ObjectID, data.Favorite](mongoClient, constants.Database, constants.FavoritesCollection),
+		RevisionStore:             revisionStore,
+		TaggingEngine:             taggingEngine,
+		Sanitizer:                 sanitize.NewPolicy(catalogSettings.Sanitization),
+
+		SelftestItemsRepository: data.NewMongoRepository[primitive.ObjectID, data.Item](mongoClient, constants.Database, constants.SelftestItemsCollection),
+		RuntimeInfo:             runtimeInfo,
+		SearchIndex:             searchIndex,
+		SearchBackoff:           backoff,
+
+		PopularityStore:    popularityStore,
+		PopularityCounter:  popularityCounter,
+		InventoryClient:    inventoryClient,
+		IdentityClient:     identityClient,
+		ImageStorage:       imageStorage,
+		ThumbnailGenerator: thumbnailGenerator,
+		EventSerializer:    eventSerializer,
+		SnapshotPublisher:  itemSnapshotPublisher,
+		ItemUpdates:        itemUpdates,
+		OutboxRelay:        outboxRelay,
+		EventHistory:       outboxStore,
+		Jobs:               jobPool,
+		Uploads:            jobUploadStore,
+		Transactions:       transactions,
+		Maintenance:        maintenance,
+		LogFilter:          logFilter,
+	}
+
+	// The messages dead lettered by the RabbitMQ consumers are inspected and requeued through the parking lot
+	if len(rabbitMQConsumers) != 0 {
+		app.ParkingLot = rabbitmq.NewParkingLot(rabbitMQConnection, rabbitMQConsumers)
+	}
+
+	if eventSourcedItems != nil {
+		app.ItemEvents = eventSourcedItems
+	}
+
+	// Run the long operations in the background. The reindex covers the items of every tenant.
+	jobPool.Register(priceAdjustmentJob, app.runPriceAdjustmentJob)
+	jobPool.Register(itemImportJob, app.runItemImportJob)
+
+	if searchBackfill != nil {
+		jobPool.Register(searchReindexJob, newSearchReindexJob(searchBackfill))
+	}
+
+	if migrationBackfill != nil {
+		jobPool.Register(migrationBackfillJob, migrationBackfill)
+	}
+
+	if identityClient != nil {
+		jobPool.Register(userResyncJob, newUserResyncJob(identityClient, usersStore, userCache))
+	}
+
+	go jobPool.Run()
+
+	taskScheduler.Register(newRetentionPurgeTask(revisionStore, catalogSettings.Retention, data.NewRetentionMetrics(config.ServiceName), logger))
+	taskScheduler.Register(newCacheEvictionTask(userCache, inventoryClient, time.Duration(catalogSettings.Scheduler.CacheEvictionInterval)*time.Second, logger))
+
+	go taskScheduler.Run()
+
+	app.publishDebugVars(poolStats, consumers)
+
+	err = app.serve(app.routes())
+
+	// Write the views counted since the last flush
+	stopPopularityCounter()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	flushErr := popularityCounter.Flush(flushCtx)
+	if flushErr != nil {
+		logger.Error(flushErr, map[string]string{"operation": "flush_popularity"})
 	}
 
-	err = app.Serve(app.routes())
 	if err != nil {
 		logger.Fatal(err, nil)
 	}
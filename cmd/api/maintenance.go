@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// maintenanceStore is implemented by the stores of the maintenance mode shared by the instances
+type maintenanceStore interface {
+	Get(ctx context.Context) (data.MaintenanceStatus, error)
+	Set(ctx context.Context, enabled bool) (data.MaintenanceStatus, error)
+}
+
+// maintenanceMode holds whether the changes to the items are rejected, i.e. while the catalog is migrated.
+// The mode is stored so that it applies to every instance: it is cached by each instance, which reads it
+// again periodically.
+type maintenanceMode struct {
+	store  maintenanceStore
+	logger *logger.Logger
+
+	mu     sync.RWMutex
+	status data.MaintenanceStatus
+}
+
+// newMaintenanceMode creates a new maintenance mode kept in the given store
+func newMaintenanceMode(store maintenanceStore, logger *logger.Logger) *maintenanceMode {
+	return &maintenanceMode{store: store, logger: logger}
+}
+
+// Set enables or disables the maintenance mode of every instance. The time at which it was enabled is kept
+// when it is enabled again.
+func (mode *maintenanceMode) Set(ctx context.Context, enabled bool) (data.MaintenanceStatus, error) {
+	status, err := mode.store.Set(ctx, enabled)
+	if err != nil {
+		return data.MaintenanceStatus{}, err
+	}
+
+	mode.cache(status)
+
+	return status, nil
+}
+
+// Refresh reads the maintenance mode from the store, i.e. after it was set by another instance
+func (mode *maintenanceMode) Refresh(ctx context.Context) (data.MaintenanceStatus, error) {
+	status, err := mode.store.Get(ctx)
+	if err != nil {
+		return data.MaintenanceStatus{}, err
+	}
+
+	mode.cache(status)
+
+	return status, nil
+}
+
+// Run refreshes the maintenance mode at the given interval. The cached mode is kept when it can't be read.
+func (mode *maintenanceMode) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_, err := mode.Refresh(context.Background())
+		if err != nil {
+			mode.logger.Error(err, map[string]string{"operation": "refresh_maintenance"})
+		}
+	}
+}
+
+// Status returns the cached state of the maintenance mode
+func (mode *maintenanceMode) Status() data.MaintenanceStatus {
+	mode.mu.RLock()
+	defer mode.mu.RUnlock()
+
+	return mode.status
+}
+
+// cache replaces the cached state of the maintenance mode
+func (mode *maintenanceMode) cache(status data.MaintenanceStatus) {
+	mode.mu.Lock()
+	defer mode.mu.Unlock()
+
+	mode.status = status
+}
+
+// rejectDuringMaintenance is a middleware used to reject the changes to the items while the maintenance mode is enabled
+func (app *Application) rejectDuringMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.Maintenance.Status().Enabled {
+			app.maintenanceResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// maintenanceResponse will be used to send a 503 Service Unavailable status code while the maintenance mode is enabled
+func (app *Application) maintenanceResponse(w http.ResponseWriter, r *http.Request) {
+	headers := make(http.Header)
+	headers.Set("Retry-After", "60")
+
+	err := app.WriteJSON(w, http.StatusServiceUnavailable, types.Envelope{"error": "the catalog is under maintenance, changes are temporarily disabled"}, headers)
+	if err != nil {
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getMaintenanceHandler is the handler for the "GET /admin/maintenance" endpoint
+func (app *Application) getMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving maintenance mode")
+	defer span.End()
+
+	status, err := app.Maintenance.Refresh(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"maintenance": status}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// updateMaintenanceHandler is the handler for the "PUT /admin/maintenance" endpoint.
+// While the maintenance mode is enabled, the items can only be changed by the catalog:admin operations.
+// The other instances apply the mode once they read it again.
+func (app *Application) updateMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Updating maintenance mode")
+	defer span.End()
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		Enabled *bool `json:"enabled"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Enabled == nil {
+		span.SetStatus(codes.Error, "Validation failed")
+		v := validation.New()
+		v.AddError("enabled", "required", "must be provided")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.Bool("enabled", *input.Enabled))
+
+	status, err := app.Maintenance.Set(ctx, *input.Enabled)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	if *input.Enabled {
+		app.Logger.Info("Maintenance mode enabled", nil)
+	} else {
+		app.Logger.Info("Maintenance mode disabled", nil)
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, types.Envelope{"maintenance": status}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/logging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/msgpack"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/common"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"github.com/felixge/httpsnoop"
+	"github.com/pascaldekloe/jwt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// logRequestDetails is a middleware used to log the outcome of every request while the log level is debug
+func (app *Application) logRequestDetails(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.LogFilter.Level() != logging.LevelDebug {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		metrics := httpsnoop.CaptureMetrics(next, w, r)
+
+		app.LogFilter.Debug("Request completed", map[string]string{
+			"method":   r.Method,
+			"uri":      r.URL.RequestURI(),
+			"status":   strconv.Itoa(metrics.Code),
+			"bytes":    strconv.FormatInt(metrics.Written, 10),
+			"duration": metrics.Duration.String(),
+		})
+	})
+}
+
+// limitRequestBody is a middleware used to set the maximum size of the request body accepted by a route.
+// The limit of a route replaces the limit of the router, so the announced size of the body is only checked
+// once it is read.
+func (app *Application) limitRequestBody(maxBytes int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = app.contextSetBodyLimit(r, maxBytes)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// compressResponse is a middleware used to gzip compress the responses bigger than the configured
+// minimum size when the client supports it
+func (app *Application) compressResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Add the "Vary: Accept-Encoding" header to the response so that caches
+		// store compressed and uncompressed responses separately
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !app.Settings.Compression.Enabled || r.Method == http.MethodHead || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		compressWriter := &compressResponseWriter{
+			ResponseWriter: w,
+			minSize:        app.Settings.Compression.MinSize,
+			level:          app.Settings.Compression.Level,
+		}
+
+		defer func() {
+			if err := compressWriter.close(); err != nil {
+				app.Logger.Error(err, nil)
+			}
+		}()
+
+		next.ServeHTTP(compressWriter, r)
+	})
+}
+
+// negotiateContentType is a middleware letting clients send their request bodies in MessagePack and receive
+// MessagePack or XML responses with "Accept: application/msgpack" or "Accept: application/xml". The bodies
+// are converted from and into JSON so that the handlers are not aware of it. JSON stays the default.
+func (app *Application) negotiateContentType(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Add the "Vary: Accept" header to the response so that caches
+		// store the responses of every media type separately
+		w.Header().Add("Vary", "Accept")
+
+		if isMessagePack(r) {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, app.contextGetBodyLimit(r)))
+			if err != nil {
+				var maxBytesError *http.MaxBytesError
+				if errors.As(err, &maxBytesError) {
+					err = bodyTooLargeError(maxBytesError.Limit)
+				}
+
+				app.BadRequestResponse(w, r, err)
+				return
+			}
+
+			converted, err := msgpack.ToJSON(body)
+			if err != nil {
+				app.BadRequestResponse(w, r, fmt.Errorf("body contains %w", err))
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(converted))
+			r.ContentLength = int64(len(converted))
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		contentType := negotiateResponseType(r)
+		if contentType == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		convertWriter := &convertResponseWriter{ResponseWriter: w, contentType: contentType}
+
+		defer func() {
+			if err := convertWriter.close(); err != nil {
+				app.Logger.Error(err, nil)
+			}
+		}()
+
+		next.ServeHTTP(convertWriter, r)
+	})
+}
+
+// deprecated is a middleware used to flag the responses of deprecated routes with a "Deprecation"
+// header along with a link to the route replacing them
+func (app *Application) deprecated(successor string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// readPreference is a middleware making the reads of the handler use the given read preference
+// (i.e. "secondaryPreferred"). The primary read preference leaves the handler untouched.
+func (app *Application) readPreference(mode string) func(next http.Handler) http.Handler {
+	pref, err := data.ParseReadPreference(mode, app.Settings.ReadPreferences.MaxStaleness)
+
+	return func(next http.Handler) http.Handler {
+		if err != nil || pref.Mode() == readpref.PrimaryMode {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(data.ContextWithReadPreference(r.Context(), pref)))
+		})
+	}
+}
+
+// enableCORS is a middleware used to allow the configured origins to call the API from a browser.
+// Preflight requests are answered directly without reaching the router.
+func (app *Application) enableCORS(next http.Handler) http.Handler {
+	cors := app.Settings.CORS
+
+	allowedMethods := strings.Join(cors.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(cors.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(cors.ExposedHeaders, ", ")
+	allowAllOrigins := validator.In("*", cors.AllowedOrigins...)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response varies depending on the origin so caches must not share it between origins
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin == "" || (!allowAllOrigins && !validator.In(origin, cors.AllowedOrigins...)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Echo the origin when credentials are allowed since the "*" wildcard is rejected by
+		// browsers for credentialed requests
+		if allowAllOrigins && !cors.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if cors.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// Answer preflight requests
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+
+			if cors.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if exposedHeaders != "" {
+			w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate is a middleware used to authenticate the caller before accessing a certain route.
+// Requests with an "Authorization: ApiKey <key>" header are authenticated with an API key, the other
+// ones with an access token of the Identity microservice.
+func (app *Application) authenticate(repository common.AuthRepository) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		next = app.recordActor(next)
+		authenticateToken := app.Authenticate(repository, app.Config.RSA.PublicKey)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorizationHeader := r.Header.Get("Authorization")
+			if !strings.HasPrefix(authorizationHeader, "ApiKey ") {
+				authenticateToken.ServeHTTP(w, r)
+				return
+			}
+
+			key := strings.TrimPrefix(authorizationHeader, "ApiKey ")
+
+			w.Header().Add("Vary", "Authorization")
+
+			apiKey, err := app.APIKeysRepository.GetByFilter(r.Context(), bson.M{"hash": data.HashAPIKey(key)})
+			if err != nil {
+				switch {
+				case errors.Is(err, database.ErrRecordNotFound):
+					app.InvalidAuthenticationTokenResponse(w, r)
+				default:
+					app.ServerErrorResponse(w, r, err)
+				}
+
+				return
+			}
+
+			// Expired keys are rejected until MongoDB removes them
+			if apiKey.Expired(time.Now()) {
+				app.InvalidAuthenticationTokenResponse(w, r)
+				return
+			}
+
+			r = r.WithContext(data.ContextWithAPIKey(r.Context(), apiKey))
+			r = app.ContextSetUser(r, apiKey.AuthUser())
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// recordActor is a middleware used to attribute the changes of the request to the authenticated user.
+// API keys are attributed to the user who created them.
+func (app *Application) recordActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := app.ContextGetUser(r).ID
+		if apiKey, ok := data.APIKeyFromContext(r.Context()); ok {
+			actor = apiKey.CreatedBy
+		}
+
+		r = r.WithContext(data.ContextWithActor(r.Context(), actor))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireTenant is a middleware used to scope the request to the tenant of the access token.
+// The tenant is read from the configured claim of the token, which must have been authenticated beforehand,
+// and tokens without tenant belong to the default tenant. Requests targeting another tenant with the
+// tenant header are rejected.
+func (app *Application) requireTenant(next http.Handler) http.Handler {
+	publicKey, err := common.LoadRsaPublicKey(app.Config.RSA.PublicKey)
+	if err != nil {
+		app.Logger.Fatal(err, nil)
+	}
+
+	tenancy := app.Settings.Tenancy
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := app.requestTenant(r, publicKey)
+		if !ok {
+			app.InvalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		if tenant == "" {
+			tenant = tenancy.DefaultTenant
+		}
+
+		if !validator.Matches(tenant, settings.TenantRegex) {
+			app.InvalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		if requested := r.Header.Get(tenancy.Header); requested != "" && requested != tenant {
+			app.crossTenantResponse(w, r)
+			return
+		}
+
+		r = r.WithContext(data.ContextWithTenant(r.Context(), tenant))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// publicRead is a middleware used on the read routes of the items which the public catalog serves anonymously.
+// Requests with an "Authorization" header, and all the requests while the public catalog is disabled, are
+// authenticated and scoped to their tenant as usual. The anonymous requests are rate limited per client and
+// scoped to the tenant of the public catalog.
+func (app *Application) publicRead(authRepository data.UsersAuthRepository) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := app.authenticate(authRepository)(app.requireTenant(app.RequirePermission(authRepository, "catalog:read")(next)))
+
+		public := app.Settings.PublicCatalog
+		if !public.Enabled {
+			return authenticated
+		}
+
+		tenant := public.Tenant
+		if tenant == "" {
+			tenant = app.Settings.Tenancy.DefaultTenant
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The response depends on whether the request is authenticated
+			w.Header().Add("Vary", "Authorization")
+
+			if r.Header.Get("Authorization") != "" {
+				authenticated.ServeHTTP(w, r)
+				return
+			}
+
+			if !app.PublicLimiter.Allow(clientIP(r, public.ClientIPHeader)) {
+				app.RateLimitExceededResponse(w, r)
+				return
+			}
+
+			if requested := r.Header.Get(app.Settings.Tenancy.Header); requested != "" && requested != tenant {
+				app.crossTenantResponse(w, r)
+				return
+			}
+
+			r = r.WithContext(data.ContextWithTenant(r.Context(), tenant))
+			r = app.contextSetPublic(r)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestTenant returns the tenant of the API key or of the access token which authenticated the request.
+// The tenant is empty when the access token does not have the tenant claim.
+func (app *Application) requestTenant(r *http.Request, publicKey *rsa.PublicKey) (string, bool) {
+	if apiKey, ok := data.APIKeyFromContext(r.Context()); ok {
+		return apiKey.TenantID, true
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	claims, err := jwt.RSACheck([]byte(token), publicKey)
+	if err != nil {
+		return "", false
+	}
+
+	tenant, _ := claims.String(app.Settings.Tenancy.Claim)
+
+	return tenant, true
+}
+
+// crossTenantResponse will be used to send a 403 Forbidden status code when a request targets another tenant
+func (app *Application) crossTenantResponse(w http.ResponseWriter, r *http.Request) {
+	err := app.WriteJSON(w, http.StatusForbidden, types.Envelope{"error": "your access token doesn't grant access to this tenant"}, nil)
+	if err != nil {
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// requireClientCertificate is a middleware used on the internal listener to only serve the trusted Play services.
+// The client certificate was verified against the client CA during the handshake, its common name or one of its
+// DNS names must also be one of the allowed clients of the settings when there are some.
+func (app *Application) requireClientCertificate(next http.Handler) http.Handler {
+	allowedClients := app.Settings.Server.Internal.AllowedClients
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			app.untrustedClientResponse(w, r)
+			return
+		}
+
+		certificate := r.TLS.VerifiedChains[0][0]
+
+		if len(allowedClients) != 0 && !validator.In(certificate.Subject.CommonName, allowedClients...) {
+			allowed := false
+
+			for _, name := range certificate.DNSNames {
+				if validator.In(name, allowedClients...) {
+					allowed = true
+					break
+				}
+			}
+
+			if !allowed {
+				app.untrustedClientResponse(w, r)
+				return
+			}
+		}
+
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("client", certificate.Subject.CommonName))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// internalTenant is a middleware used to scope the requests of the internal listener to the tenant of the
+// tenant header. The trusted Play services are not bound to a tenant and target the default one without header.
+func (app *Application) internalTenant(next http.Handler) http.Handler {
+	tenancy := app.Settings.Tenancy
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.Header.Get(tenancy.Header)
+		if tenant == "" {
+			tenant = tenancy.DefaultTenant
+		}
+
+		if !validator.Matches(tenant, settings.TenantRegex) {
+			app.BadRequestResponse(w, r, fmt.Errorf("invalid %s header", tenancy.Header))
+			return
+		}
+
+		r = r.WithContext(data.ContextWithTenant(r.Context(), tenant))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// untrustedClientResponse will be used to send a 403 Forbidden status code when the client certificate
+// of a request of the internal listener is not one of the allowed clients
+func (app *Application) untrustedClientResponse(w http.ResponseWriter, r *http.Request) {
+	err := app.WriteJSON(w, http.StatusForbidden, types.Envelope{"error": "your client certificate doesn't grant access to the internal endpoints"}, nil)
+	if err != nil {
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/msgpack"
+	"github.com/PlayEconomy37/Play.Catalog/internal/protobuf"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/common"
+	"github.com/PlayEconomy37/Play.Common/configuration"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/pascaldekloe/jwt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCompressResponse(t *testing.T) {
+	app := &Application{
+		App: common.App{
+			Logger: logger.New(io.Discard, logger.LevelInfo),
+		},
+		Settings: &settings.Settings{
+			Compression: settings.Compression{Enabled: true, MinSize: 1024, Level: gzip.DefaultCompression},
+		},
+	}
+
+	smallBody := []byte(`{"status": "available"}`)
+	largeBody := bytes.Repeat([]byte("a"), 2048)
+
+	tests := []struct {
+		testName              string
+		acceptEncoding        string
+		body                  []byte
+		wantedContentEncoding string
+	}{
+		{"Large response with gzip support", "gzip, deflate, br", largeBody, "gzip"},
+		{"Large response without gzip support", "deflate", largeBody, ""},
+		{"Large response with gzip refused", "gzip;q=0", largeBody, ""},
+		{"Small response with gzip support", "gzip", smallBody, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write(tt.body)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+			rr := httptest.NewRecorder()
+			app.compressResponse(next).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("want %d; got %d", http.StatusOK, rr.Code)
+			}
+
+			contentEncoding := rr.Header().Get("Content-Encoding")
+			if contentEncoding != tt.wantedContentEncoding {
+				t.Errorf("want Content-Encoding %q; got %q", tt.wantedContentEncoding, contentEncoding)
+			}
+
+			body := rr.Body.Bytes()
+
+			if contentEncoding == "gzip" {
+				gzipReader, err := gzip.NewReader(rr.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				body, err = io.ReadAll(gzipReader)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !bytes.Equal(body, tt.body) {
+				t.Errorf("want body of %d bytes; got %d bytes", len(tt.body), len(body))
+			}
+		})
+	}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	app := &Application{
+		App: common.App{
+			Logger: logger.New(io.Discard, logger.LevelInfo),
+		},
+	}
+
+	jsonBody := `{"name":"Potion","price":5}`
+
+	msgpackBody, err := msgpack.FromJSON([]byte(jsonBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName          string
+		accept            string
+		contentType       string
+		body              []byte
+		wantedStatusCode  int
+		wantedContentType string
+		wantedBody        []byte
+	}{
+		{"JSON by default", "", "application/json", []byte(jsonBody), http.StatusOK, "application/json", []byte(jsonBody)},
+		{"JSON preferred", "application/json, application/msgpack;q=0.5", "application/json", []byte(jsonBody), http.StatusOK, "application/json", []byte(jsonBody)},
+		{"MessagePack response", "application/msgpack", "application/json", []byte(jsonBody), http.StatusOK, msgpack.ContentType, msgpackBody},
+		{"MessagePack request", "", "application/msgpack", msgpackBody, http.StatusOK, "application/json", []byte(jsonBody)},
+		{"MessagePack request and response", "application/msgpack", "application/msgpack", msgpackBody, http.StatusOK, msgpack.ContentType, msgpackBody},
+		{"XML response", "application/xml", "application/json", []byte(jsonBody), http.StatusOK, "application/xml", []byte("<response><name>Potion</name><price>5</price></response>")},
+		{"XML response from a MessagePack request", "application/xml;q=0.9, application/json;q=0.5", "application/msgpack", msgpackBody, http.StatusOK, "application/xml", []byte("<response><name>Potion</name><price>5</price></response>")},
+		{"Malformed MessagePack request", "", "application/msgpack", []byte{0x82, 0xa1}, http.StatusBadRequest, "application/json", []byte("body contains malformed MessagePack")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// Echo the JSON request body
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+				w.WriteHeader(http.StatusOK)
+				w.Write(body)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/items", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Accept", tt.accept)
+
+			rr := httptest.NewRecorder()
+			app.negotiateContentType(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, rr.Code)
+			}
+
+			if contentType := rr.Header().Get("Content-Type"); contentType != tt.wantedContentType {
+				t.Errorf("want Content-Type %q; got %q", tt.wantedContentType, contentType)
+			}
+
+			if !bytes.Contains(rr.Body.Bytes(), tt.wantedBody) {
+				t.Errorf("want body %q to contain %q", rr.Body.Bytes(), tt.wantedBody)
+			}
+		})
+	}
+}
+
+func TestNegotiateProtobuf(t *testing.T) {
+	app := &Application{
+		App: common.App{
+			Logger: logger.New(io.Discard, logger.LevelInfo),
+		},
+	}
+
+	itemBody := `{"item": {"name": "Potion"}}`
+
+	tests := []struct {
+		testName          string
+		method            string
+		body              string
+		wantedContentType string
+	}{
+		{"Item", http.MethodGet, itemBody, protobuf.ContentType},
+		{"Items", http.MethodGet, `{"items": [], "metadata": {}}`, protobuf.ContentType},
+		{"Error", http.MethodGet, `{"error": "the requested resource could not be found"}`, "application/json"},
+		{"Not a GET request", http.MethodPost, itemBody, "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(tt.body))
+			})
+
+			req := httptest.NewRequest(tt.method, "/v1/items", nil)
+			req.Header.Set("Accept", protobuf.ContentType)
+
+			rr := httptest.NewRecorder()
+			app.negotiateContentType(next).ServeHTTP(rr, req)
+
+			if contentType := rr.Header().Get("Content-Type"); contentType != tt.wantedContentType {
+				t.Errorf("want Content-Type %q; got %q", tt.wantedContentType, contentType)
+			}
+		})
+	}
+}
+
+func TestEnableCORS(t *testing.T) {
+	tests := []struct {
+		testName               string
+		cors                   settings.CORS
+		method                 string
+		origin                 string
+		requestMethod          string
+		wantedStatusCode       int
+		wantedAllowOrigin      string
+		wantedAllowMethods     string
+		wantedAllowCredentials string
+	}{
+		{
+			"No Origin header",
+			settings.CORS{AllowedOrigins: []string{"http://localhost:3000"}},
+			http.MethodGet, "", "",
+			http.StatusOK, "", "", "",
+		},
+		{
+			"Origin not allowed",
+			settings.CORS{AllowedOrigins: []string{"http://localhost:3000"}},
+			http.MethodGet, "http://evil.com", "",
+			http.StatusOK, "", "", "",
+		},
+		{
+			"Origin allowed",
+			settings.CORS{AllowedOrigins: []string{"http://localhost:3000"}},
+			http.MethodGet, "http://localhost:3000", "",
+			http.StatusOK, "http://localhost:3000", "", "",
+		},
+		{
+			"Every origin allowed",
+			settings.CORS{AllowedOrigins: []string{"*"}},
+			http.MethodGet, "http://localhost:3000", "",
+			http.StatusOK, "*", "", "",
+		},
+		{
+			"Origin allowed with credentials",
+			settings.CORS{AllowedOrigins: []string{"http://localhost:3000"}, AllowCredentials: true},
+			http.MethodGet, "http://localhost:3000", "",
+			http.StatusOK, "http://localhost:3000", "", "true",
+		},
+		{
+			"Preflight request",
+			settings.CORS{AllowedOrigins: []string{"http://localhost:3000"}, AllowedMethods: []string{"GET", "POST"}},
+			http.MethodOptions, "http://localhost:3000", http.MethodPost,
+			http.StatusNoContent, "http://localhost:3000", "GET, POST", "",
+		},
+		{
+			"Preflight request from origin not allowed",
+			settings.CORS{AllowedOrigins: []string{"http://localhost:3000"}, AllowedMethods: []string{"GET", "POST"}},
+			http.MethodOptions, "http://evil.com", http.MethodPost,
+			http.StatusOK, "", "", "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			app := &Application{Settings: &settings.Settings{CORS: tt.cors}}
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/items", nil)
+
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+
+			rr := httptest.NewRecorder()
+			app.enableCORS(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, rr.Code)
+			}
+
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.wantedAllowOrigin {
+				t.Errorf("want Access-Control-Allow-Origin %q; got %q", tt.wantedAllowOrigin, got)
+			}
+
+			if got := rr.Header().Get("Access-Control-Allow-Methods"); got != tt.wantedAllowMethods {
+				t.Errorf("want Access-Control-Allow-Methods %q; got %q", tt.wantedAllowMethods, got)
+			}
+
+			if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantedAllowCredentials {
+				t.Errorf("want Access-Control-Allow-Credentials %q; got %q", tt.wantedAllowCredentials, got)
+			}
+		})
+	}
+}
+
+// signTestToken returns an access token of the given user signed with the given key.
+// The tenant claim is only set when the tenant is not empty.
+func signTestToken(t *testing.T, key *rsa.PrivateKey, authority string, userID string, tenant string) string {
+	claims := jwt.Claims{
+		Registered: jwt.Registered{
+			Issuer:    authority,
+			Subject:   userID,
+			Audiences: []string{"http://localhost:3000"},
+			Expires:   jwt.NewNumericTime(time.Now().Add(time.Hour)),
+		},
+	}
+
+	if tenant != "" {
+		claims.Set = map[string]any{"tenant": tenant}
+	}
+
+	token, err := claims.RSASign(jwt.RS256, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(token)
+}
+
+// newTestKey returns a RSA key along with its public key encoded as expected by the configuration
+func newTestKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+
+	return key, base64.StdEncoding.EncodeToString(encoded)
+}
+
+func TestRequireTenant(t *testing.T) {
+	key, publicKey := newTestKey(t)
+
+	config := &configuration.Config{}
+	config.RSA.PublicKey = publicKey
+
+	app := &Application{
+		App: common.App{
+			Config: config,
+			Logger: logger.New(io.Discard, logger.LevelInfo),
+		},
+		Settings: &settings.Settings{
+			Tenancy: settings.Tenancy{Claim: "tenant", Header: "X-Tenant-ID", DefaultTenant: "default"},
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, _ := data.TenantFromContext(r.Context())
+		w.Write([]byte(tenant))
+	})
+
+	handler := app.requireTenant(next)
+
+	tests := []struct {
+		testName           string
+		tenant             string
+		header             string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Tenant of the token", "eu-1", "", http.StatusOK, []byte("eu-1")},
+		{"Token without tenant", "", "", http.StatusOK, []byte("default")},
+		{"Header of the tenant of the token", "eu-1", "eu-1", http.StatusOK, []byte("eu-1")},
+		{"Header of another tenant", "eu-1", "us-1", http.StatusForbidden, []byte("your access token doesn't grant access to this tenant")},
+		{"Default tenant targeting another tenant", "", "eu-1", http.StatusForbidden, []byte("your access token doesn't grant access to this tenant")},
+		{"Invalid tenant", "eu 1", "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/items", nil)
+			req.Header.Set("Authorization", "Bearer "+signTestToken(t, key, config.Authority, "1", tt.tenant))
+
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, rr.Code)
+			}
+
+			if !bytes.Contains(rr.Body.Bytes(), tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", rr.Body.Bytes(), tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestPublicRead(t *testing.T) {
+	_, publicKey := newTestKey(t)
+
+	config := &configuration.Config{}
+	config.RSA.PublicKey = publicKey
+
+	app := &Application{
+		App: common.App{
+			Config: config,
+			Logger: logger.New(io.Discard, logger.LevelInfo),
+		},
+		Settings: &settings.Settings{
+			Tenancy:       settings.Tenancy{Claim: "tenant", Header: "X-Tenant-ID", DefaultTenant: "default"},
+			PublicCatalog: settings.PublicCatalog{Enabled: true, Tenant: "eu-1", Fields: []string{"name"}, RateLimit: 1, Burst: 3},
+		},
+		PublicLimiter: newClientLimiter(1, 3),
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, _ := data.TenantFromContext(r.Context())
+		w.Write([]byte(fmt.Sprintf("%s %t", tenant, app.contextGetPublic(r))))
+	})
+
+	handler := app.publicRead(data.UsersAuthRepository{})(next)
+
+	// The requests come from the same client, whose burst is spent by the first three anonymous requests
+	tests := []struct {
+		testName           string
+		authorization      string
+		header             string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Anonymous request", "", "", http.StatusOK, []byte("eu-1 true")},
+		{"Header of the public tenant", "", "eu-1", http.StatusOK, []byte("eu-1 true")},
+		{"Header of another tenant", "", "us-1", http.StatusForbidden, []byte("your access token doesn't grant access to this tenant")},
+		{"Rate limit exceeded", "", "", http.StatusTooManyRequests, []byte("rate limit exceeded")},
+		{"Invalid access token", "Bearer invalid", "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/items", nil)
+
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, rr.Code)
+			}
+
+			if !bytes.Contains(rr.Body.Bytes(), tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", rr.Body.Bytes(), tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	// Sign the tokens of the tenants with a key of our own
+	key, publicKey := newTestKey(t)
+	app.Config.RSA.PublicKey = publicKey
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection of the default tenant
+	seedItemsCollection(t, app.ItemsRepository)
+
+	potion, err := app.ItemsRepository.GetByFilter(context.Background(), bson.M{"name": "Potion"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaultToken := signTestToken(t, key, app.Config.Authority, "1", "")
+	tenantToken := signTestToken(t, key, app.Config.Authority, "1", "eu-1")
+
+	tests := []struct {
+		testName           string
+		method             string
+		urlPath            string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Item of the default tenant", http.MethodGet, "/v1/items/" + potion.ID.Hex(), nil, defaultToken, http.StatusOK, []byte(`"name": "Potion"`)},
+		{"Item of another tenant", http.MethodGet, "/v1/items/" + potion.ID.Hex(), nil, tenantToken, http.StatusNotFound, []byte("the requested resource could not be found")},
+		{"Delete item of another tenant", http.MethodDelete, "/v1/items/" + potion.ID.Hex(), nil, tenantToken, http.StatusNotFound, []byte("the requested resource could not be found")},
+		{
+			"Name only unique within a tenant",
+			http.MethodPost,
+			"/v1/items",
+			map[string]any{"name": "Potion", "description": "Restores a small amount of health", "price": 5},
+			tenantToken,
+			http.StatusCreated,
+			[]byte(`"name": "Potion"`),
+		},
+		{"Listing of a tenant", http.MethodGet, "/v1/items", nil, tenantToken, http.StatusOK, []byte(`"total_records": 1`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, tt.urlPath, tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// The item of the default tenant was not deleted
+	fetchedItem := fetchItem(t, app.ItemsRepository, potion.ID.Hex())
+	if fetchedItem.ID != potion.ID {
+		t.Errorf("want item %s to be kept", potion.ID.Hex())
+	}
+}
+
+func TestReadPreference(t *testing.T) {
+	app := &Application{
+		Settings: &settings.Settings{
+			ReadPreferences: settings.ReadPreferences{MaxStaleness: 120},
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pref := data.ReadPreferenceFromContext(r.Context())
+		if pref == nil {
+			w.Write([]byte("none"))
+			return
+		}
+
+		maxStaleness, _ := pref.MaxStaleness()
+		fmt.Fprintf(w, "%s %s", pref.Mode(), maxStaleness)
+	})
+
+	tests := []struct {
+		testName           string
+		mode               string
+		wantedResponseBody string
+	}{
+		{"Primary", "primary", "none"},
+		{"Secondary preferred", "secondaryPreferred", "secondaryPreferred 2m0s"},
+		{"Nearest", "nearest", "nearest 2m0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			app.readPreference(tt.mode)(next).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/items", nil))
+
+			if body := rr.Body.String(); body != tt.wantedResponseBody {
+				t.Errorf("want body %q; got %q", tt.wantedResponseBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migrationBatchSize is the number of documents copied at once by the migration backfills
+const migrationBatchSize = 500
+
+// setupMigrationTarget connects to the target store of a storage migration and creates its collections.
+// It returns the target client along with a cleanup function which disconnects it.
+func setupMigrationTarget(
+	catalogSettings *settings.Settings,
+	connect func(dsn string) (*mongo.Client, error),
+) (*mongo.Client, func(), error) {
+	cfg := catalogSettings.Migration
+
+	targetClient, err := connect(cfg.TargetDsn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		targetClient.Disconnect(ctx)
+	}
+
+	// Create "items" collection in target store
+	err = data.CreateItemsCollection(targetClient, cfg.TargetDatabase, catalogSettings.Pricing, catalogSettings.Constraints, catalogSettings.Expiration)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	// Bring the target "items" collection up to date if it already existed
+	_, err = data.ReconcileItemsCollection(
+		context.Background(),
+		targetClient,
+		cfg.TargetDatabase,
+		constants.ItemsCollection,
+		catalogSettings.Pricing,
+		catalogSettings.Constraints,
+		catalogSettings.Expiration,
+	)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	// Create "saved_filters" collection in target store
+	err = data.CreateSavedFiltersCollection(targetClient, cfg.TargetDatabase)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return targetClient, cleanup, nil
+}
+
+// withDualWrite wraps the source and target repositories of a collection into a dual write
+// repository. The source store serves the reads.
+func withDualWrite[K any, T types.MongoEntity[K, T]](
+	cfg settings.Migration,
+	source data.Repository[K, T],
+	target data.Repository[K, T],
+	collection string,
+	logger *logger.Logger,
+	metrics *data.DualWriteMetrics,
+) data.Repository[K, T] {
+	return data.NewDualWriteRepository(source, target, collection, cfg.ShadowReads, logger, metrics)
+}
+
+// newMigrationBackfillJob returns the handler of the migration backfill jobs, copying every item and saved filter
+// of the source store into the target store. The stored documents of the target store are replaced so that the
+// fields written to the source store only are brought up to date. The repositories are not scoped to the tenant
+// of the job since the stores hold the documents of every tenant.
+func newMigrationBackfillJob(
+	sourceItems data.Repository[primitive.ObjectID, data.Item],
+	targetItems data.Repository[primitive.ObjectID, data.Item],
+	sourceSavedFilters data.Repository[primitive.ObjectID, data.SavedFilter],
+	targetSavedFilters data.Repository[primitive.ObjectID, data.SavedFilter],
+) jobs.Handler {
+	return func(ctx context.Context, job jobs.Job, reporter *jobs.Reporter) (bson.M, error) {
+		progress := func(copied int) {
+			reporter.Succeed(int64(copied))
+		}
+
+		items, err := data.CopyDocuments(ctx, sourceItems, targetItems, migrationBatchSize, progress)
+		if err != nil {
+			return nil, err
+		}
+
+		savedFilters, err := data.CopyDocuments(ctx, sourceSavedFilters, targetSavedFilters, migrationBatchSize, progress)
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.M{"items": items, "saved_filters": savedFilters}, nil
+	}
+}
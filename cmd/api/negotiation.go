@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/msgpack"
+	"github.com/PlayEconomy37/Play.Catalog/internal/protobuf"
+	"github.com/PlayEconomy37/Play.Catalog/internal/xmlconv"
+)
+
+// mediaTypeQuality returns the quality value given to a media type by the Accept header of the request,
+// or 0 if the media type is not accepted. Wildcards are ignored so that JSON stays the default.
+func mediaTypeQuality(r *http.Request, mediaType string) float64 {
+	quality := 0.0
+
+	for _, header := range r.Header.Values("Accept") {
+		for _, accepted := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(accepted), ";")
+
+			if !strings.EqualFold(strings.TrimSpace(name), mediaType) {
+				continue
+			}
+
+			value := 1.0
+			if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+				if parsed, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err == nil {
+					value = parsed
+				}
+			}
+
+			if value > quality {
+				quality = value
+			}
+		}
+	}
+
+	return quality
+}
+
+// responseConverters maps the media types of the responses which can be negotiated with the Accept header
+// to the conversion of the JSON responses into them. JSON stays the default.
+var responseConverters = map[string]func(body []byte) ([]byte, error){
+	msgpack.ContentType: msgpack.FromJSON,
+	xmlconv.ContentType: func(body []byte) ([]byte, error) {
+		return xmlconv.FromJSON(body, "response")
+	},
+	protobuf.ContentType: protobuf.FromJSON,
+}
+
+// negotiateResponseType returns the media type preferred by the client among the converted ones,
+// or an empty string if it does not prefer any of them over JSON.
+// Protobuf messages are only defined for the items, which are returned by the GET requests.
+func negotiateResponseType(r *http.Request) string {
+	preferred := ""
+	quality := mediaTypeQuality(r, "application/json")
+
+	mediaTypes := []string{msgpack.ContentType, xmlconv.ContentType}
+	if r.Method == http.MethodGet {
+		mediaTypes = append(mediaTypes, protobuf.ContentType)
+	}
+
+	// The media types are checked in a stable order so that ties are always settled the same way
+	for _, mediaType := range mediaTypes {
+		if value := mediaTypeQuality(r, mediaType); value > quality {
+			preferred = mediaType
+			quality = value
+		}
+	}
+
+	return preferred
+}
+
+// isMessagePack returns true if the request body is a MessagePack document
+func isMessagePack(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	return err == nil && mediaType == msgpack.ContentType
+}
+
+// convertResponseWriter is a http.ResponseWriter which buffers the whole response so that
+// its JSON body can be converted into the negotiated media type once the handler is done
+type convertResponseWriter struct {
+	http.ResponseWriter
+	contentType string
+	status      int
+	buffer      []byte
+}
+
+// WriteHeader records the status code. Headers are sent once the body is converted.
+func (w *convertResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write buffers the response body
+func (w *convertResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.buffer = append(w.buffer, b...)
+
+	return len(b), nil
+}
+
+// close converts the JSON responses into the negotiated media type and sends the response.
+// The other responses (i.e. the empty ones) and the ones which could not be converted are sent as is.
+func (w *convertResponseWriter) close() error {
+	// Nothing was written by the handler
+	if w.status == 0 {
+		return nil
+	}
+
+	header := w.Header()
+	body := w.buffer
+
+	var conversionErr error
+
+	if mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil && mediaType == "application/json" && len(body) != 0 {
+		converted, err := responseConverters[w.contentType](body)
+
+		switch {
+		case err == nil:
+			body = converted
+			header.Set("Content-Type", w.contentType)
+			header.Del("Content-Length")
+		case !errors.Is(err, protobuf.ErrUnsupported):
+			// The response is still sent in JSON rather than lost. The responses
+			// without protobuf message (i.e. errors) are always sent in JSON.
+			conversionErr = err
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	_, err := w.ResponseWriter.Write(body)
+	if err != nil {
+		return err
+	}
+
+	return conversionErr
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/outbox"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// outboxRelay is implemented by the relays of the outbox
+type outboxRelay interface {
+	Pause()
+	Resume()
+	Drain(ctx context.Context) (int, error)
+	Requeue(ctx context.Context) (int, error)
+	Status(ctx context.Context) (outbox.Status, error)
+}
+
+// getOutboxHandler is the handler for the "GET /admin/outbox" endpoint.
+// It returns the state of the relay along with the number of events waiting to be published.
+func (app *Application) getOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving outbox status")
+	defer span.End()
+
+	app.writeOutboxStatus(ctx, w, r, nil)
+}
+
+// pauseOutboxHandler is the handler for the "POST /admin/outbox/pause" endpoint.
+// Events keep being stored in the outbox but are not published until the relay is resumed.
+func (app *Application) pauseOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Pausing outbox relay")
+	defer span.End()
+
+	app.OutboxRelay.Pause()
+
+	app.Logger.Info("Outbox relay paused", nil)
+
+	app.writeOutboxStatus(ctx, w, r, nil)
+}
+
+// resumeOutboxHandler is the handler for the "POST /admin/outbox/resume" endpoint
+func (app *Application) resumeOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Resuming outbox relay")
+	defer span.End()
+
+	app.OutboxRelay.Resume()
+
+	app.Logger.Info("Outbox relay resumed", nil)
+
+	app.writeOutboxStatus(ctx, w, r, nil)
+}
+
+// drainOutboxHandler is the handler for the "POST /admin/outbox/drain" endpoint.
+// It publishes every pending event, even when the relay is paused, and returns the number of published events.
+func (app *Application) drainOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Draining outbox")
+	defer span.End()
+
+	published, err := app.OutboxRelay.Drain(ctx)
+
+	span.SetAttributes(attribute.Int("published", published))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		if !errors.Is(err, outbox.ErrDrainInProgress) {
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.WriteJSON(w, http.StatusConflict, types.Envelope{"error": err.Error()}, nil)
+		if err != nil {
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	app.writeOutboxStatus(ctx, w, r, types.Envelope{"published": published})
+}
+
+// requeueOutboxHandler is the handler for the "POST /admin/outbox/requeue" endpoint.
+// It stores the dead lettered events in the outbox again and returns the number of requeued events.
+func (app *Application) requeueOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Requeuing dead lettered outbox events")
+	defer span.End()
+
+	requeued, err := app.OutboxRelay.Requeue(ctx)
+
+	span.SetAttributes(attribute.Int("requeued", requeued))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	app.Logger.Info("Dead lettered outbox events requeued", map[string]string{"requeued": fmt.Sprint(requeued)})
+
+	app.writeOutboxStatus(ctx, w, r, types.Envelope{"requeued": requeued})
+}
+
+// writeOutboxStatus sends the state of the outbox relay along with the given properties
+func (app *Application) writeOutboxStatus(ctx context.Context, w http.ResponseWriter, r *http.Request, env types.Envelope) {
+	status, err := app.OutboxRelay.Status(ctx)
+	if err != nil {
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	if env == nil {
+		env = types.Envelope{}
+	}
+
+	env["outbox"] = status
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/outbox"
+)
+
+// fakeOutboxRelay keeps the state of a relay whose pending events are all published when drained
+type fakeOutboxRelay struct {
+	paused       bool
+	pending      int64
+	deadLettered int64
+}
+
+// Pause pauses the relay
+func (relay *fakeOutboxRelay) Pause() {
+	relay.paused = true
+}
+
+// Resume resumes the relay
+func (relay *fakeOutboxRelay) Resume() {
+	relay.paused = false
+}
+
+// Drain publishes every pending event
+func (relay *fakeOutboxRelay) Drain(ctx context.Context) (int, error) {
+	published := relay.pending
+	relay.pending = 0
+
+	return int(published), nil
+}
+
+// Requeue moves the dead lettered events back to the pending ones
+func (relay *fakeOutboxRelay) Requeue(ctx context.Context) (int, error) {
+	requeued := relay.deadLettered
+	relay.pending += requeued
+	relay.deadLettered = 0
+
+	return int(requeued), nil
+}
+
+// Status returns the state of the relay
+func (relay *fakeOutboxRelay) Status(ctx context.Context) (outbox.Status, error) {
+	return outbox.Status{Paused: relay.paused, Pending: relay.pending, DeadLettered: relay.deadLettered}, nil
+}
+
+func TestOutboxHandlers(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	app.OutboxRelay = &fakeOutboxRelay{pending: 3, deadLettered: 2}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		method             string
+		urlPath            string
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", http.MethodGet, "/admin/outbox", accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Status", http.MethodGet, "/admin/outbox", accessTokenUser1, http.StatusOK, []byte(`"pending": 3`)},
+		{"Pause", http.MethodPost, "/admin/outbox/pause", accessTokenUser1, http.StatusOK, []byte(`"paused": true`)},
+		{"Drain paused relay", http.MethodPost, "/admin/outbox/drain", accessTokenUser1, http.StatusOK, []byte(`"published": 3`)},
+		{"Resume", http.MethodPost, "/admin/outbox/resume", accessTokenUser1, http.StatusOK, []byte(`"paused": false`)},
+		{"Drained outbox", http.MethodGet, "/admin/outbox", accessTokenUser1, http.StatusOK, []byte(`"pending": 0`)},
+		{"Dead lettered events", http.MethodGet, "/admin/outbox", accessTokenUser1, http.StatusOK, []byte(`"dead_lettered": 2`)},
+		{"Requeue", http.MethodPost, "/admin/outbox/requeue", accessTokenUser1, http.StatusOK, []byte(`"requeued": 2`)},
+		{"Requeued events", http.MethodGet, "/admin/outbox", accessTokenUser1, http.StatusOK, []byte(`"pending": 2`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.makeRequest(t, tt.method, tt.urlPath, nil, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clientsCleanupInterval is the interval at which the idle clients are forgotten by the rate limiter
+const clientsCleanupInterval = time.Minute
+
+// bucket is the token bucket of a client of the rate limiter
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// clientLimiter is a struct that limits the rate of the requests of each client with a token bucket.
+// The bucket of a client holds up to burst tokens and is refilled at the given rate, each request taking a token.
+type clientLimiter struct {
+	rate  float64 // Tokens added per second
+	burst float64
+	now   func() time.Time
+
+	mu          sync.Mutex
+	clients     map[string]*bucket
+	lastCleanup time.Time
+}
+
+// newClientLimiter returns a new clientLimiter allowing the given requests per second and burst to each client
+func newClientLimiter(rate float64, burst int) *clientLimiter {
+	return &clientLimiter{
+		rate:        rate,
+		burst:       float64(burst),
+		now:         time.Now,
+		clients:     make(map[string]*bucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Allow checks if the given client can send a request and takes a token from its bucket if so
+func (limiter *clientLimiter) Allow(client string) bool {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+
+	if now.Sub(limiter.lastCleanup) >= clientsCleanupInterval {
+		limiter.cleanup(now)
+	}
+
+	b, ok := limiter.clients[client]
+	if !ok {
+		b = &bucket{tokens: limiter.burst, last: now}
+		limiter.clients[client] = b
+	}
+
+	b.tokens = limiter.refill(b, now)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// refill returns the tokens of the given bucket at the given time
+func (limiter *clientLimiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(limiter.burst, b.tokens+now.Sub(b.last).Seconds()*limiter.rate)
+}
+
+// cleanup forgets the clients whose bucket is full again, which behave as new clients
+func (limiter *clientLimiter) cleanup(now time.Time) {
+	for client, b := range limiter.clients {
+		if limiter.refill(b, now) >= limiter.burst {
+			delete(limiter.clients, client)
+		}
+	}
+
+	limiter.lastCleanup = now
+}
+
+// clientIP returns the address of the client of the given request. The address is read from the given header,
+// whose first value is the client when it was set by a proxy, and from the remote address otherwise.
+func clientIP(r *http.Request, header string) string {
+	if header != "" {
+		if value := r.Header.Get(header); value != "" {
+			client, _, _ := strings.Cut(value, ",")
+			return strings.TrimSpace(client)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
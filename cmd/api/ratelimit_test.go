@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientLimiter(t *testing.T) {
+	now := time.Now()
+
+	limiter := newClientLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	// The burst is allowed at once
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("1.2.3.4") {
+			t.Fatalf("want request %d allowed; got refused", i+1)
+		}
+	}
+
+	if limiter.Allow("1.2.3.4") {
+		t.Error("want request over the burst refused; got allowed")
+	}
+
+	// The other clients have their own bucket
+	if !limiter.Allow("5.6.7.8") {
+		t.Error("want request of another client allowed; got refused")
+	}
+
+	// A token is added every half second
+	now = now.Add(500 * time.Millisecond)
+
+	if !limiter.Allow("1.2.3.4") {
+		t.Error("want request allowed after refill; got refused")
+	}
+
+	if limiter.Allow("1.2.3.4") {
+		t.Error("want request refused once the refilled token is used; got allowed")
+	}
+
+	// The clients whose bucket is full again are forgotten
+	now = now.Add(clientsCleanupInterval)
+	limiter.Allow("9.9.9.9")
+
+	if len(limiter.clients) != 1 {
+		t.Errorf("want %d; got %d", 1, len(limiter.clients))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		testName     string
+		header       string
+		forwardedFor string
+		wanted       string
+	}{
+		{"Remote address", "", "", "192.0.2.1"},
+		{"Header not configured", "", "203.0.113.7", "192.0.2.1"},
+		{"Header of the proxy", "X-Forwarded-For", "203.0.113.7, 198.51.100.2", "203.0.113.7"},
+		{"Missing header", "X-Forwarded-For", "", "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/items", nil)
+
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			if got := clientIP(req, tt.header); got != tt.wanted {
+				t.Errorf("want %q; got %q", tt.wanted, got)
+			}
+		})
+	}
+}
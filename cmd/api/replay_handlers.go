@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/outbox"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// eventHistory is implemented by the stores keeping the published events so that they can be replayed
+type eventHistory interface {
+	CountHistory(ctx context.Context, filter outbox.ReplayFilter) (int64, error)
+	Replay(ctx context.Context, filter outbox.ReplayFilter) (int, error)
+}
+
+// replayEventsHandler is the handler for the "POST /admin/events/replay" endpoint.
+// It stores the published events selected by the filters of the request in the outbox again, so that they are
+// published to the services which lost them. With "dry_run", it only returns the number of selected events.
+func (app *Application) replayEventsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Replaying events")
+	defer span.End()
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		ItemIDs []string   `json:"item_ids"`
+		Types   []string   `json:"types"`
+		From    *time.Time `json:"from"`
+		To      *time.Time `json:"to"`
+		DryRun  bool       `json:"dry_run"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Instantiate validator
+	v := validation.New()
+
+	v.Check(len(input.ItemIDs) <= app.Settings.Administration.MaxBulkItems, "item_ids", "too_many", fmt.Sprintf("must not contain more than %d ids", app.Settings.Administration.MaxBulkItems))
+	for _, id := range input.ItemIDs {
+		v.Check(primitive.IsValidObjectID(id), "item_ids", "unsupported_value", fmt.Sprintf("invalid item id %q", id))
+	}
+
+	// The names of the events are validated as schemas of their first version
+	for _, eventType := range input.Types {
+		_, schemaErr := messaging.ParseSchema(eventType)
+		_, nameErr := messaging.ParseSchema(eventType + ".v1")
+		v.Check(schemaErr == nil || nameErr == nil, "types", "unsupported_value", fmt.Sprintf("invalid event name or schema %q", eventType))
+	}
+
+	filter := outbox.ReplayFilter{Keys: input.ItemIDs, Types: input.Types}
+
+	if input.From != nil {
+		filter.From = input.From.UTC()
+	}
+
+	if input.To != nil {
+		filter.To = input.To.UTC()
+	}
+
+	v.Check(input.From == nil || input.To == nil || filter.From.Before(filter.To), "to", "invalid_format", "must be after from")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	matched, err := app.EventHistory.CountHistory(ctx, filter)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("matched", matched), attribute.Bool("dry_run", input.DryRun))
+
+	// Large replays are split by the operators so that they do not flood the outbox
+	if matched > int64(app.Settings.Outbox.MaxReplayEvents) {
+		span.SetStatus(codes.Error, "Validation failed")
+		v := validation.New()
+		v.AddError("filters", "too_many", fmt.Sprintf("select %d events, more than the maximum of %d", matched, app.Settings.Outbox.MaxReplayEvents))
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	env := types.Envelope{"matched": matched, "dry_run": input.DryRun}
+
+	if !input.DryRun {
+		replayed, err := app.EventHistory.Replay(ctx, filter)
+
+		span.SetAttributes(attribute.Int("replayed", replayed))
+
+		// The events stored in the outbox before the failure are replayed anyway
+		if replayed != 0 {
+			app.Logger.Info("Events replayed", map[string]string{"replayed": fmt.Sprint(replayed)})
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			app.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		env["replayed"] = replayed
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/outbox"
+)
+
+// fakeEventHistory holds a fixed number of events, all selected by any filter
+type fakeEventHistory struct {
+	events   int
+	replayed int
+}
+
+// CountHistory returns the number of events
+func (history *fakeEventHistory) CountHistory(ctx context.Context, filter outbox.ReplayFilter) (int64, error) {
+	return int64(history.events), nil
+}
+
+// Replay replays every event
+func (history *fakeEventHistory) Replay(ctx context.Context, filter outbox.ReplayFilter) (int, error) {
+	history.replayed += history.events
+
+	return history.events, nil
+}
+
+func TestReplayEventsHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	history := &fakeEventHistory{events: 3}
+	app.EventHistory = history
+	app.Settings.Outbox.MaxReplayEvents = 5
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+		wantedReplayed     int
+	}{
+		{"User does not have permission - has catalog:read", map[string]any{"dry_run": true}, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource"), 0},
+		{"Invalid item id", map[string]any{"item_ids": []string{"potion"}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte(`invalid item id \"potion\"`), 0},
+		{"Invalid event type", map[string]any{"types": []string{"Item Updated"}}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("invalid event name or schema"), 0},
+		{"Invalid time range", map[string]any{"from": "2022-10-02T00:00:00Z", "to": "2022-10-01T00:00:00Z"}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be after from"), 0},
+		{"Dry run", map[string]any{"types": []string{"item.updated", "item.created.v2"}, "dry_run": true}, accessTokenUser1, http.StatusOK, []byte(`"matched": 3`), 0},
+		{"Replay", map[string]any{"item_ids": []string{"63407e2c8bcd4a43ec1c4ff4"}, "from": "2022-10-01T00:00:00Z"}, accessTokenUser1, http.StatusOK, []byte(`"replayed": 3`), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			history.replayed = 0
+
+			statusCode, _, resBody := ts.post(t, "/admin/events/replay", tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+
+			if history.replayed != tt.wantedReplayed {
+				t.Errorf("want %d replayed events; got %d", tt.wantedReplayed, history.replayed)
+			}
+		})
+	}
+
+	t.Run("Too many events", func(t *testing.T) {
+		history.events = 6
+
+		statusCode, _, resBody := ts.post(t, "/admin/events/replay", map[string]any{"dry_run": true}, true, accessTokenUser1)
+
+		if statusCode != http.StatusUnprocessableEntity {
+			t.Errorf("want %d; got %d", http.StatusUnprocessableEntity, statusCode)
+		}
+
+		if !bytes.Contains(resBody, []byte("more than the maximum of 5")) {
+			t.Errorf("want body %q to contain %q", resBody, "more than the maximum of 5")
+		}
+	})
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// getTenantReview retrieves the review of the request tenant with the id of the request URL parameters.
+// It sends the error response itself and returns false if the review could not be retrieved.
+func (app *Application) getTenantReview(ctx context.Context, w http.ResponseWriter, r *http.Request) (data.Review, bool) {
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return data.Review{}, false
+	}
+
+	// Reviews of the other tenants are never found
+	review, err := app.ReviewsRepository.GetByFilter(ctx, bson.M{"_id": id, data.TenantField: app.contextTenant(ctx)})
+	if err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return data.Review{}, false
+	}
+
+	return review, true
+}
+
+// refreshItemRating recomputes the rating of the given item after one of its reviews changed.
+// It sends a Server error response itself and returns false if the rating could not be recomputed,
+// in which case the next change of the reviews of the item fixes it.
+func (app *Application) refreshItemRating(ctx context.Context, w http.ResponseWriter, r *http.Request, itemID primitive.ObjectID) bool {
+	err := app.RatingStore.Refresh(ctx, itemID)
+	if err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return false
+	}
+
+	return true
+}
+
+// readReviewFilters reads the pagination and sort of the listed reviews from the query string
+func (app *Application) readReviewFilters(r *http.Request, v *validation.Validator) filters.Filters {
+	queryString := r.URL.Query()
+
+	findOpts := filters.Filters{
+		Page:         app.readIntFromQueryString(queryString, "page", 1, v),
+		PageSize:     app.readIntFromQueryString(queryString, "page_size", 20, v),
+		Sort:         app.ReadStringFromQueryString(queryString, "sort", "-created_at"),
+		SortSafelist: []string{"created_at", "rating", "-created_at", "-rating"},
+	}
+
+	data.ValidateFilters(v, findOpts)
+
+	return findOpts
+}
+
+// getItemReviewsHandler is the handler for the "GET /v1/items/:id/reviews" endpoint.
+// It only returns the published reviews.
+func (app *Application) getItemReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving item reviews")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// Instantiate validator
+	v := validation.New()
+
+	findOpts := app.readReviewFilters(r, v)
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	filter := bson.M{data.TenantField: app.contextTenant(ctx), "item_id": id, "status": data.ReviewPublished}
+
+	reviews, metadata, err := app.ReviewsRepository.GetAll(ctx, filter, findOpts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"reviews":  reviews,
+		"metadata": metadata,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// createItemReviewHandler is the handler for the "POST /v1/items/:id/reviews" endpoint
+func (app *Application) createItemReviewHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Reviewing item")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		Rating  int32  `json:"rating"`
+		Comment string `json:"comment"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Only the items of the tenant can be reviewed
+	_, err = app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	review := data.Review{
+		TenantID:  app.contextTenant(ctx),
+		ItemID:    id,
+		UserID:    app.ContextGetUser(r).ID,
+		Rating:    input.Rating,
+		Comment:   input.Comment,
+		Version:   1,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	review.Status = data.InitialReviewStatus(review, app.Settings.Moderation)
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	// Perform validation checks
+	data.ValidateReview(v, review, app.Settings.Moderation)
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Record review attributes in trace
+	span.SetAttributes(attribute.Int("rating", int(review.Rating)), attribute.String("status", review.Status))
+
+	// Create a record in the database
+	reviewID, err := app.ReviewsRepository.Create(ctx, review)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrDuplicateKey):
+			v.AddError("item_id", "already_exists", "has already been reviewed by this user")
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	review.ID = *reviewID
+
+	if review.Status == data.ReviewPublished && !app.refreshItemRating(ctx, w, r, id) {
+		return
+	}
+
+	// Include a Location header to let the client know where to find the reviews of the item
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/items/%s/reviews", id.Hex()))
+
+	env := types.Envelope{
+		"review": review,
+	}
+
+	err = app.WriteJSON(w, http.StatusCreated, env, headers)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getReviewsHandler is the handler for the "GET /admin/reviews" endpoint.
+// It lists the reviews with the given status (pending by default) for the moderators.
+func (app *Application) getReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving reviews")
+	defer span.End()
+
+	// Instantiate validator
+	v := validation.New()
+
+	findOpts := app.readReviewFilters(r, v)
+	status := app.ReadStringFromQueryString(r.URL.Query(), "status", data.ReviewPending)
+
+	v.Check(validator.In(status, data.ReviewStatuses...), "status", "unsupported_value", "invalid status value")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	filter := bson.M{data.TenantField: app.contextTenant(ctx), "status": status}
+
+	reviews, metadata, err := app.ReviewsRepository.GetAll(ctx, filter, findOpts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"reviews":  reviews,
+		"metadata": metadata,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// moderateReviewHandler is the handler for the "PUT /admin/reviews/:id" endpoint
+func (app *Application) moderateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Moderating review")
+	defer span.End()
+
+	review, ok := app.getTenantReview(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Status string `json:"status"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	v.Check(validator.In(input.Status, data.ReviewStatuses...), "status", "unsupported_value", "must be one of published, pending or hidden")
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.String("status", input.Status))
+
+	review.Status = input.Status
+	review.UpdatedAt = time.Now().UTC()
+
+	// Update review in the database
+	err = app.ReviewsRepository.Update(ctx, review)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrEditConflict):
+			app.EditConflictResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	if !app.refreshItemRating(ctx, w, r, review.ItemID) {
+		return
+	}
+
+	env := types.Envelope{
+		"review": review,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// deleteReviewHandler is the handler for the "DELETE /admin/reviews/:id" endpoint
+func (app *Application) deleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Deleting review")
+	defer span.End()
+
+	review, ok := app.getTenantReview(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// Delete review in the database
+	err := app.ReviewsRepository.Delete(ctx, review.ID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	if !app.refreshItemRating(ctx, w, r, review.ItemID) {
+		return
+	}
+
+	env := types.Envelope{
+		"message": "Review deleted successfully",
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
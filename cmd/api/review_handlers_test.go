@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+func TestCreateItemReviewHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	tests := []struct {
+		testName           string
+		id                 string
+		body               map[string]any
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has inventory:read", ids["Potion"], map[string]any{"rating": 5}, accessTokenUser3, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Out of range rating", ids["Potion"], map[string]any{"rating": 6}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be greater or equal to 1 and lower or equal to 5")},
+		{"Unknown item", "63407e2c8bcd4a43ec1c4ff4", map[string]any{"rating": 5}, accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"Valid submission", ids["Potion"], map[string]any{"rating": 5, "comment": "Saved my run"}, accessTokenUser1, http.StatusCreated, []byte(`"status": "published"`)},
+		{"Second review of the same user", ids["Potion"], map[string]any{"rating": 1}, accessTokenUser1, http.StatusUnprocessableEntity, []byte("has already been reviewed by this user")},
+		{"Review of another user", ids["Potion"], map[string]any{"rating": 2}, accessTokenUser2, http.StatusCreated, []byte(`"rating": 2`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, fmt.Sprintf("/v1/items/%s/reviews", tt.id), tt.body, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	t.Run("Rating of the item", func(t *testing.T) {
+		statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s", ids["Potion"]), true, accessTokenUser2)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		if !bytes.Contains(resBody, []byte(`"average": 3.5`)) || !bytes.Contains(resBody, []byte(`"count": 2`)) {
+			t.Errorf("want body %q to contain the rating of the reviews", resBody)
+		}
+	})
+}
+
+func TestModerateReviewHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	app.Settings.Moderation = settings.Moderation{BannedWords: []string{"scam"}, Action: "review"}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	statusCode, _, resBody := ts.post(t, fmt.Sprintf("/v1/items/%s/reviews", ids["Ether"]), map[string]any{"rating": 1, "comment": "What a scam"}, true, accessTokenUser2)
+	if statusCode != http.StatusCreated {
+		t.Fatalf("want %d; got %d", http.StatusCreated, statusCode)
+	}
+
+	var created struct {
+		Review struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"review"`
+	}
+
+	err := json.Unmarshal(resBody, &created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Review.Status != "pending" {
+		t.Errorf("want %q; got %q", "pending", created.Review.Status)
+	}
+
+	t.Run("Pending review not listed", func(t *testing.T) {
+		_, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s/reviews", ids["Ether"]), true, accessTokenUser2)
+
+		if bytes.Contains(resBody, []byte(created.Review.ID)) {
+			t.Errorf("want body %q not to contain %q", resBody, created.Review.ID)
+		}
+	})
+
+	t.Run("Pending reviews", func(t *testing.T) {
+		statusCode, _, resBody := ts.get(t, "/admin/reviews", true, accessTokenUser1)
+
+		if statusCode != http.StatusOK {
+			t.Errorf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		if !bytes.Contains(resBody, []byte(created.Review.ID)) {
+			t.Errorf("want body %q to contain %q", resBody, created.Review.ID)
+		}
+	})
+
+	t.Run("Invalid status", func(t *testing.T) {
+		statusCode, _, _ := ts.put(t, "/admin/reviews/"+created.Review.ID, map[string]any{"status": "deleted"}, true, accessTokenUser1)
+
+		if statusCode != http.StatusUnprocessableEntity {
+			t.Errorf("want %d; got %d", http.StatusUnprocessableEntity, statusCode)
+		}
+	})
+
+	t.Run("Published review", func(t *testing.T) {
+		statusCode, _, _ := ts.put(t, "/admin/reviews/"+created.Review.ID, map[string]any{"status": "published"}, true, accessTokenUser1)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		_, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s", ids["Ether"]), true, accessTokenUser2)
+
+		if !bytes.Contains(resBody, []byte(`"count": 1`)) {
+			t.Errorf("want body %q to contain %q", resBody, `"count": 1`)
+		}
+	})
+
+	t.Run("Deleted review", func(t *testing.T) {
+		statusCode, _, _ := ts.delete(t, "/admin/reviews/"+created.Review.ID, true, accessTokenUser1)
+
+		if statusCode != http.StatusOK {
+			t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+		}
+
+		_, _, resBody := ts.get(t, fmt.Sprintf("/v1/items/%s", ids["Ether"]), true, accessTokenUser2)
+
+		if bytes.Contains(resBody, []byte(`"rating"`)) {
+			t.Errorf("want body %q not to contain %q", resBody, `"rating"`)
+		}
+	})
+}
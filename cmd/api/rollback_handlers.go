@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// itemUpdatePublisher is implemented by the publishers of the item updated events
+type itemUpdatePublisher interface {
+	PublishUpdated(ctx context.Context, item data.Item, at time.Time) error
+}
+
+// rollbackItemHandler is the handler for the "POST /v1/items/:id/rollback" endpoint.
+// The content of the given version is restored as a new version of the item.
+func (app *Application) rollbackItemHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Rolling back item")
+	defer span.End()
+
+	id, ok := app.readIDParam(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	// Instantiate validator
+	v := validation.New()
+
+	version := app.readIntFromQueryString(r.URL.Query(), "version", 0, v)
+	v.Check(version >= 1, "version", "required", "must be provided and greater or equal to 1")
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("version", version))
+
+	// Retrieve item with given id
+	item, err := app.ItemsRepository.GetByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// Check that the user is allowed to change the item
+	if !app.canModifyItem(r, item) {
+		span.SetStatus(codes.Error, "Not the owner of the item")
+		app.notOwnerResponse(w, r)
+		return
+	}
+
+	v.Check(int32(version) < item.Version, "version", "out_of_range", "must be lower than the current version of the item")
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Retrieve the revision to restore. The versions written before the revisions were recorded are not found.
+	revision, err := app.RevisionStore.Get(ctx, id, int32(version))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	item = data.Rollback(item, revision)
+	item.UpdatedAt = time.Now().UTC()
+
+	// The restored content must follow the current rules
+	data.ValidateItem(v, item, app.Settings.Pricing, app.Settings.Moderation)
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Re-evaluate auto-tagging rules and moderation against the restored item
+	item = app.TaggingEngine.Apply(item)
+	item = data.FlagForReview(item, app.Settings.Moderation)
+
+	// Update item in the database and publish the change as an ItemUpdated event through the outbox, unless the
+	// change stream publishes it. The event is stored along with the update when transactions are enabled.
+	err = app.Transactions.Run(ctx, func(ctx context.Context) error {
+		err := app.ItemsRepository.Update(ctx, item)
+		if err != nil || app.ItemUpdates == nil {
+			return err
+		}
+
+		// The version was incremented by the update
+		return app.ItemUpdates.PublishUpdated(ctx, item.SetVersion(item.Version+1), item.UpdatedAt)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrEditConflict):
+			app.EditConflictResponse(w, r)
+		case errors.Is(err, database.ErrDuplicateKey):
+			app.duplicateKeyResponse(w, r, err, "an item")
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// The version was incremented by the update
+	item.Version++
+
+	env := types.Envelope{
+		"item": item,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
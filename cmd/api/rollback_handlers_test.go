@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRollbackItemHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+	ids := seededItemIDs(t, app)
+
+	// Write the second version of the potion
+	statusCode, _, _ := ts.put(t, "/v1/items/"+ids["Potion"], map[string]any{"name": "Great Potion", "price": 8}, true, accessTokenUser1)
+	if statusCode != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, statusCode)
+	}
+
+	tests := []struct {
+		testName           string
+		id                 string
+		query              string
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", ids["Potion"], "version=1", accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Missing version", ids["Potion"], "", accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be provided and greater or equal to 1")},
+		{"Current version", ids["Potion"], "version=2", accessTokenUser1, http.StatusUnprocessableEntity, []byte("must be lower than the current version of the item")},
+		{"Unknown item", "63407e2c8bcd4a43ec1c4ff4", "version=1", accessTokenUser1, http.StatusNotFound, []byte("The requested resource could not be found")},
+		{"First version restored as the third one", ids["Potion"], "version=1", accessTokenUser1, http.StatusOK, []byte(`"version": 3`)},
+		{"Second version restored as the fourth one", ids["Potion"], "version=2", accessTokenUser1, http.StatusOK, []byte(`"name": "Great Potion"`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, fmt.Sprintf("/v1/items/%s/rollback?%s", tt.id, tt.query), nil, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	t.Run("Restored content", func(t *testing.T) {
+		_, _, resBody := ts.post(t, fmt.Sprintf("/v1/items/%s/rollback?version=1", ids["Potion"]), nil, true, accessTokenUser1)
+
+		if !bytes.Contains(resBody, []byte(`"name": "Potion"`)) || !bytes.Contains(resBody, []byte(`"price": 5`)) {
+			t.Errorf("want body %q to hold the first version", resBody)
+		}
+	})
+
+	t.Run("Purged revisions", func(t *testing.T) {
+		purged, err := app.RevisionStore.PurgeBefore(context.Background(), time.Now().Add(-time.Hour))
+		if err != nil || purged != 0 {
+			t.Fatalf("want %d revisions purged; got %d, %v", 0, purged, err)
+		}
+
+		// The revisions recorded within the last second are purged too, the ids having a precision of a second
+		purged, err = app.RevisionStore.PurgeBefore(context.Background(), time.Now().Add(time.Second))
+		if err != nil || purged == 0 {
+			t.Fatalf("want revisions purged; got %d, %v", purged, err)
+		}
+
+		statusCode, _, _ := ts.post(t, fmt.Sprintf("/v1/items/%s/rollback?version=2", ids["Potion"]), nil, true, accessTokenUser1)
+		if statusCode != http.StatusNotFound {
+			t.Errorf("want %d; got %d", http.StatusNotFound, statusCode)
+		}
+	})
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"expvar"
 	"net/http"
+	"net/http/pprof"
 
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/riandyrn/otelchi"
@@ -12,28 +15,266 @@ import (
 func (app *Application) routes() http.Handler {
 	router := chi.NewRouter()
 
+	// Repository used by the authentication and authorization middlewares
+	authRepository := data.NewUsersAuthRepository(app.UsersRepository, app.UserCache)
+
 	router.NotFound(http.HandlerFunc(app.NotFoundResponse))
 	router.MethodNotAllowed(http.HandlerFunc(app.MethodNotAllowedResponse))
 
 	router.Use(app.RecoverPanic)
+	router.Use(app.enableCORS)
+	router.Use(app.compressResponse)
 	// router.Use(app.HTTPMetrics(app.Config.ServiceName))
 	router.Use(otelchi.Middleware(app.Config.ServiceName, otelchi.WithChiRoutes(router)))
 	router.Use(app.LogRequest)
+	router.Use(app.logRequestDetails)
+	router.Use(app.SecureHeaders)
+	router.Use(app.limitRequestBody(app.Settings.BodyLimits.Default))
+
+	router.Get("/healthcheck", app.healthCheckHandler)
+
+	// Versioned API. A future version with breaking response changes gets its own
+	// set of routes (and handlers where needed) mounted next to this one.
+	router.Route("/v1", func(r chi.Router) {
+		r.Route("/items", app.itemsRoutesV1(authRepository))
+		r.Route("/collections", app.itemCollectionsRoutesV1(authRepository))
+
+		// Resources of the authenticated user
+		r.Route("/me", func(r chi.Router) {
+			r.Use(app.authenticate(authRepository))
+			r.Use(app.requireTenant)
+			r.Use(app.RequirePermission(authRepository, "catalog:read"))
+
+			r.Get("/favorites", app.getFavoritesHandler)
+		})
+
+		// Status of the background jobs enqueued by the catalog:admin operations
+		if app.Jobs != nil {
+			r.Route("/jobs", func(r chi.Router) {
+				r.Use(app.authenticate(authRepository))
+				r.Use(app.requireTenant)
+				r.Use(app.RequirePermission(authRepository, "catalog:admin"))
+
+				r.Get("/{id}", app.getJobHandler)
+			})
+		}
+	})
+
+	// Unversioned paths are kept as deprecated aliases of the v1 routes
+	router.With(app.deprecated("/v1/items")).Route("/items", app.itemsRoutesV1(authRepository))
+
+	// The operational endpoints are only served by the admin listener when it is enabled
+	if !app.Settings.Server.Admin.Enabled {
+		app.operationalRoutes(router, authRepository)
+	}
+
+	return router
+}
+
+// adminRoutes defines the routes and handlers of the admin listener, which serves the operational endpoints
+// on a port which is not exposed publicly
+func (app *Application) adminRoutes() http.Handler {
+	router := chi.NewRouter()
+
+	// Repository used by the authentication and authorization middlewares
+	authRepository := data.NewUsersAuthRepository(app.UsersRepository, app.UserCache)
+
+	router.NotFound(http.HandlerFunc(app.NotFoundResponse))
+	router.MethodNotAllowed(http.HandlerFunc(app.MethodNotAllowedResponse))
+
+	router.Use(app.RecoverPanic)
+	router.Use(app.compressResponse)
+	router.Use(otelchi.Middleware(app.Config.ServiceName, otelchi.WithChiRoutes(router)))
+	router.Use(app.LogRequest)
+	router.Use(app.logRequestDetails)
 	router.Use(app.SecureHeaders)
+	router.Use(app.limitRequestBody(app.Settings.BodyLimits.Default))
 
 	router.Get("/healthcheck", app.healthCheckHandler)
 
-	router.Route("/items", func(r chi.Router) {
-		r.Use(app.Authenticate(app.UsersRepository, app.Config.RSA.PublicKey))
+	app.operationalRoutes(router, authRepository)
+
+	return router
+}
+
+// operationalRoutes defines the routes and handlers of the administration, profiling and metrics endpoints
+func (app *Application) operationalRoutes(router chi.Router, authRepository data.UsersAuthRepository) {
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(app.authenticate(authRepository))
+		r.Use(app.requireTenant)
+		r.Use(app.RequirePermission(authRepository, "catalog:admin"))
+
+		r.Get("/saved-filters", app.getSavedFiltersHandler)
+		r.Get("/saved-filters/{slug}", app.getSavedFilterHandler)
+		r.With(app.limitRequestBody(app.Settings.BodyLimits.SavedFilters)).Post("/saved-filters", app.createSavedFilterHandler)
+		r.Delete("/saved-filters/{slug}", app.deleteSavedFilterHandler)
+
+		r.Get("/reviews", app.getReviewsHandler)
+		r.With(app.limitRequestBody(app.Settings.BodyLimits.Items)).Put("/reviews/{id}", app.moderateReviewHandler)
+		r.Delete("/reviews/{id}", app.deleteReviewHandler)
+
+		r.Get("/api-keys", app.getAPIKeysHandler)
+		r.Post("/api-keys", app.createAPIKeyHandler)
+		r.Delete("/api-keys/{id}", app.deleteAPIKeyHandler)
+
+		r.Post("/backup", app.backupHandler)
+		r.With(app.limitRequestBody(app.Settings.BodyLimits.BulkImport)).Post("/restore", app.restoreHandler)
+		r.Post("/snapshot", app.snapshotHandler)
+
+		r.Get("/outbox", app.getOutboxHandler)
+		r.Post("/outbox/pause", app.pauseOutboxHandler)
+		r.Post("/outbox/resume", app.resumeOutboxHandler)
+		r.Post("/outbox/drain", app.drainOutboxHandler)
+		r.Post("/outbox/requeue", app.requeueOutboxHandler)
+		r.Post("/events/replay", app.replayEventsHandler)
 
-		r.With(app.RequirePermission(app.UsersRepository, "catalog:read")).Get("/", app.getItemsHandler)
-		r.With(app.RequirePermission(app.UsersRepository, "catalog:read")).Get("/{id}", app.getItemHandler)
-		r.With(app.RequirePermission(app.UsersRepository, "catalog:write")).Post("/", app.createItemHandler)
-		r.With(app.RequirePermission(app.UsersRepository, "catalog:write")).Put("/{id}", app.updateItemHandler)
-		r.With(app.RequirePermission(app.UsersRepository, "catalog:write")).Delete("/{id}", app.deleteItemHandler)
+		// The parking lot only holds the messages of the RabbitMQ consumers
+		if app.ParkingLot != nil {
+			r.Get("/dead-letters", app.getDeadLetterQueuesHandler)
+			r.Get("/dead-letters/{queue}", app.getDeadLettersHandler)
+			r.Post("/dead-letters/{queue}/requeue", app.requeueDeadLettersHandler)
+			r.Post("/dead-letters/{queue}/purge", app.purgeDeadLettersHandler)
+		}
+
+		// The events of the items are only kept when they are event sourced
+		if app.ItemEvents != nil {
+			r.Get("/items/{id}/events", app.getItemEventsHandler)
+			r.Get("/items/{id}/state", app.getItemStateHandler)
+			r.Post("/items/{id}/rebuild", app.rebuildItemHandler)
+		}
+
+		// The search index is only reindexed when the items are mirrored into Elasticsearch
+		if app.SearchIndex != nil && app.Jobs != nil {
+			r.Post("/search/reindex", app.reindexSearchHandler)
+		}
+
+		// The target store of a storage migration is only backfilled in dual write mode
+		if app.Settings.Migration.Mode == "dual-write" && app.Jobs != nil {
+			r.Post("/migration/backfill", app.backfillMigrationHandler)
+		}
+
+		// The users are only resynchronized when the Identity microservice is configured
+		if app.IdentityClient != nil && app.Jobs != nil {
+			r.Post("/users/resync", app.resyncUsersHandler)
+		}
+
+		r.Get("/maintenance", app.getMaintenanceHandler)
+		r.Put("/maintenance", app.updateMaintenanceHandler)
+
+		r.Get("/loglevel", app.getLogLevelHandler)
+		r.Put("/loglevel", app.updateLogLevelHandler)
+
+		r.Post("/selftest", app.selftestHandler)
+		r.Get("/runtime-info", app.getRuntimeInfoHandler)
 	})
 
+	// Runtime profiling endpoints (i.e. go tool pprof -http=: "http://localhost:4444/debug/pprof/heap")
+	router.Route("/debug/pprof", func(r chi.Router) {
+		r.Use(app.authenticate(authRepository))
+		r.Use(app.RequirePermission(authRepository, "catalog:admin"))
+
+		r.Get("/", pprof.Index)
+		r.Get("/cmdline", pprof.Cmdline)
+		r.Get("/profile", pprof.Profile)
+		r.Get("/symbol", pprof.Symbol)
+		r.Post("/symbol", pprof.Symbol)
+		r.Get("/trace", pprof.Trace)
+		r.Get("/{profile}", pprof.Index) // Named profiles (heap, goroutine, allocs, block, mutex, threadcreate)
+	})
+
+	// Application counters and memory statistics, for a quick inspection without Prometheus
+	router.With(app.authenticate(authRepository), app.RequirePermission(authRepository, "catalog:admin")).Get("/debug/vars", expvar.Handler().ServeHTTP)
+
 	router.Get("/metrics", promhttp.Handler().ServeHTTP)
+}
+
+// internalRoutes defines the routes and handlers of the internal listener, which only serves the trusted
+// Play services authenticated with their client certificate
+func (app *Application) internalRoutes() http.Handler {
+	router := chi.NewRouter()
+
+	router.NotFound(http.HandlerFunc(app.NotFoundResponse))
+	router.MethodNotAllowed(http.HandlerFunc(app.MethodNotAllowedResponse))
+
+	router.Use(app.RecoverPanic)
+	router.Use(app.compressResponse)
+	router.Use(otelchi.Middleware(app.Config.ServiceName, otelchi.WithChiRoutes(router)))
+	router.Use(app.LogRequest)
+	router.Use(app.logRequestDetails)
+	router.Use(app.limitRequestBody(app.Settings.BodyLimits.Default))
+	router.Use(app.requireClientCertificate)
+	router.Use(app.internalTenant)
+
+	router.Route("/internal", func(r chi.Router) {
+		r.Get("/items", app.batchGetItemsHandler)
+		r.Post("/snapshot", app.snapshotHandler)
+	})
 
 	return router
 }
+
+// itemsRoutesV1 defines the routes and handlers of the v1 items API
+func (app *Application) itemsRoutesV1(authRepository data.UsersAuthRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Use(app.negotiateContentType)
+
+		// Routes served anonymously in public catalog mode
+		r.Group(func(r chi.Router) {
+			r.Use(app.publicRead(authRepository))
+
+			r.With(app.readPreference(app.Settings.ReadPreferences.ListItems)).Get("/", app.getItemsHandler)
+			r.With(app.readPreference(app.Settings.ReadPreferences.ListItems)).Get("/featured", app.getFeaturedItemsHandler)
+			r.With(app.readPreference(app.Settings.ReadPreferences.GetItem)).Get("/{id}", app.getItemHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.authenticate(authRepository))
+			r.Use(app.requireTenant)
+
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/stats", app.getItemsStatsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/suggest", app.getItemSuggestionsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/trending", app.getTrendingItemsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/random", app.getRandomItemsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/compare", app.compareItemsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Head("/{id}", app.headItemHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/{id}/similar", app.getSimilarItemsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/{id}/reviews", app.getItemReviewsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read"), app.rejectDuringMaintenance, app.limitRequestBody(app.Settings.BodyLimits.Items)).Post("/{id}/reviews", app.createItemReviewHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read"), app.rejectDuringMaintenance).Put("/{id}/favorite", app.favoriteItemHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read"), app.rejectDuringMaintenance).Delete("/{id}/favorite", app.unfavoriteItemHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/external/{externalId}", app.getItemByExternalIDHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:write"), app.rejectDuringMaintenance, app.limitRequestBody(app.Settings.BodyLimits.Items)).Post("/", app.createItemHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:write"), app.rejectDuringMaintenance, app.limitRequestBody(app.Settings.BodyLimits.Items)).Put("/{id}", app.updateItemHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:write"), app.rejectDuringMaintenance).Delete("/{id}", app.deleteItemHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:write"), app.rejectDuringMaintenance).Post("/{id}/rollback", app.rollbackItemHandler)
+
+			// Images are uploaded directly to the object storage with the presigned URLs
+			if app.ImageStorage != nil {
+				r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/{id}/image", app.getItemImageHandler)
+				r.With(app.RequirePermission(authRepository, "catalog:write"), app.rejectDuringMaintenance, app.limitRequestBody(app.Settings.BodyLimits.Items)).Post("/{id}/images/uploads", app.createImageUploadHandler)
+				r.With(app.RequirePermission(authRepository, "catalog:write"), app.rejectDuringMaintenance, app.limitRequestBody(app.Settings.BodyLimits.Items)).Post("/{id}/images", app.confirmImageUploadHandler)
+			}
+
+			// Destructive and bulk operations are reserved to the catalog:admin permission, even in maintenance mode
+			r.With(app.RequirePermission(authRepository, "catalog:admin")).Post("/bulk-delete", app.bulkDeleteItemsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:admin")).Post("/price-adjustments", app.adjustItemPricesHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:admin")).Put("/bulk", app.bulkUpsertItemsHandler)
+			r.With(app.RequirePermission(authRepository, "catalog:admin"), app.limitRequestBody(app.Settings.BodyLimits.Items)).Put("/{id}/featured", app.setItemFeaturedHandler)
+		})
+	}
+}
+
+// itemCollectionsRoutesV1 defines the routes and handlers of the v1 item collections API
+func (app *Application) itemCollectionsRoutesV1(authRepository data.UsersAuthRepository) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Use(app.authenticate(authRepository))
+		r.Use(app.requireTenant)
+
+		r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/", app.getItemCollectionsHandler)
+		r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/{id}", app.getItemCollectionHandler)
+		r.With(app.RequirePermission(authRepository, "catalog:read")).Get("/{id}/items", app.getItemCollectionItemsHandler)
+		r.With(app.RequirePermission(authRepository, "catalog:admin"), app.limitRequestBody(app.Settings.BodyLimits.Items)).Post("/", app.createItemCollectionHandler)
+		r.With(app.RequirePermission(authRepository, "catalog:admin"), app.limitRequestBody(app.Settings.BodyLimits.Items)).Put("/{id}", app.updateItemCollectionHandler)
+		r.With(app.RequirePermission(authRepository, "catalog:admin")).Delete("/{id}", app.deleteItemCollectionHandler)
+	}
+}
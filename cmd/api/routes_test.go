@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestPprofRoutes(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		urlPath            string
+		useAuthHeader      bool
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No Authorization header", "/debug/pprof/", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has catalog:read", "/debug/pprof/", true, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Index", "/debug/pprof/", true, accessTokenUser1, http.StatusOK, []byte("goroutine")},
+		{"Named profile", "/debug/pprof/goroutine?debug=1", true, accessTokenUser1, http.StatusOK, []byte("goroutine profile")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, tt.urlPath, tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestDebugVarsRoute(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		useAuthHeader      bool
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No Authorization header", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has catalog:read", true, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Valid request", true, accessTokenUser1, http.StatusOK, []byte(`"memstats"`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, "/debug/vars", tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestDeprecatedItemsRoutes(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName          string
+		urlPath           string
+		wantedStatusCode  int
+		wantedDeprecation string
+	}{
+		{"Versioned route", "/v1/items", http.StatusOK, ""},
+		{"Deprecated alias", "/items", http.StatusOK, "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, headers, _ := ts.get(t, tt.urlPath, true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if deprecation := headers.Get("Deprecation"); deprecation != tt.wantedDeprecation {
+				t.Errorf("want Deprecation header %q; got %q", tt.wantedDeprecation, deprecation)
+			}
+		})
+	}
+}
+
+func TestGetRuntimeInfoHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		useAuthHeader      bool
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No Authorization header", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has catalog:read", true, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Valid request", true, accessTokenUser1, http.StatusOK, []byte(`"profile": "dev"`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, "/admin/runtime-info", tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestAdminListenerRoutes(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	app.Settings.Server.Admin.Enabled = true
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	adminTs := newTestServer(t, app.adminRoutes())
+	defer adminTs.Close()
+
+	tests := []struct {
+		testName         string
+		urlPath          string
+		wantedStatusCode int
+		wantedAdminCode  int
+	}{
+		{"Metrics", "/metrics", http.StatusNotFound, http.StatusOK},
+		{"Profiling", "/debug/pprof/", http.StatusNotFound, http.StatusOK},
+		{"Maintenance mode", "/admin/maintenance", http.StatusNotFound, http.StatusOK},
+		{"Healthcheck", "/healthcheck", http.StatusOK, http.StatusOK},
+		{"Items", "/v1/items", http.StatusOK, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, _ := ts.get(t, tt.urlPath, true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d on the main listener; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			statusCode, _, _ = adminTs.get(t, tt.urlPath, true, accessTokenUser1)
+
+			if statusCode != tt.wantedAdminCode {
+				t.Errorf("want %d on the admin listener; got %d", tt.wantedAdminCode, statusCode)
+			}
+		})
+	}
+}
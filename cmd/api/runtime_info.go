@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/rabbitmq"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Build information of the binary, set with the linker flags of the build
+// (i.e. go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse HEAD) -X main.buildTime=...")
+var (
+	version   = "dev"
+	commit    = ""
+	buildTime = ""
+)
+
+// mongoInfo is a struct that holds information about the MongoDB deployment used by the service
+type mongoInfo struct {
+	Version  string              `json:"version"`
+	Topology string              `json:"topology"`
+	Indexes  map[string][]string `json:"indexes"` // Index names by collection
+}
+
+// rabbitMQInfo is a struct that holds information about the RabbitMQ broker used by the service
+type rabbitMQInfo struct {
+	Version   string   `json:"version"`
+	Exchanges []string `json:"exchanges"`
+	Queues    []string `json:"queues"`
+}
+
+// runtimeInfo is a struct that holds a summary of the environment the service is running in
+type runtimeInfo struct {
+	StartedAt time.Time         `json:"started_at"`
+	Profile   string            `json:"profile"`
+	Version   string            `json:"version"`
+	Commit    string            `json:"commit"`
+	BuildTime string            `json:"build_time"`
+	GoVersion string            `json:"go_version"`
+	MongoDB   mongoInfo         `json:"mongodb"`
+	RabbitMQ  rabbitMQInfo      `json:"rabbitmq"`
+	Features  map[string]string `json:"features"`
+}
+
+// messagingTopology is implemented by the consumers and publishers to report the exchanges and queues they declare
+type messagingTopology interface {
+	Topology() (exchanges []string, queues []string)
+}
+
+// collectRuntimeInfo gathers the runtime information of the service.
+// The information which could be collected is returned along with the first error encountered.
+func collectRuntimeInfo(
+	ctx context.Context,
+	configFile string,
+	catalogSettings *settings.Settings,
+	mongoClient *mongo.Client,
+	databaseName string,
+	rabbitMQConnection *rabbitmq.Connection,
+	consumers ...messagingTopology,
+) (*runtimeInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	info := &runtimeInfo{
+		StartedAt: time.Now().UTC(),
+		Profile:   strings.TrimSuffix(filepath.Base(configFile), filepath.Ext(configFile)),
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Features:  featureFlags(catalogSettings),
+		MongoDB:   mongoInfo{Indexes: make(map[string][]string)},
+		RabbitMQ:  rabbitMQInfo{Exchanges: []string{}, Queues: []string{}},
+	}
+
+	// Binaries built without the linker flags still know the commit they were built from
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	// RabbitMQ
+	if rabbitMQConnection != nil {
+		info.RabbitMQ.Version = fmt.Sprint(rabbitMQConnection.Properties()["version"])
+	}
+
+	for _, consumer := range consumers {
+		exchanges, queues := consumer.Topology()
+		info.RabbitMQ.Exchanges = append(info.RabbitMQ.Exchanges, exchanges...)
+		info.RabbitMQ.Queues = append(info.RabbitMQ.Queues, queues...)
+	}
+
+	// MongoDB
+	db := mongoClient.Database(databaseName)
+
+	var buildInfo struct {
+		Version string `bson:"version"`
+	}
+
+	err := db.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo)
+	if err != nil {
+		return info, err
+	}
+
+	info.MongoDB.Version = buildInfo.Version
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	err = db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return info, err
+	}
+
+	switch {
+	case hello.Msg == "isdbgrid":
+		info.MongoDB.Topology = "sharded"
+	case hello.SetName != "":
+		info.MongoDB.Topology = fmt.Sprintf("replica set %s", hello.SetName)
+	default:
+		info.MongoDB.Topology = "standalone"
+	}
+
+	collections := []string{
+		constants.ItemsCollection,
+		database.UsersCollection,
+		constants.SavedFiltersCollection,
+		constants.SelftestItemsCollection,
+	}
+
+	for _, collection := range collections {
+		cursor, err := db.Collection(collection).Indexes().List(ctx)
+		if err != nil {
+			return info, err
+		}
+
+		var indexes []struct {
+			Name string `bson:"name"`
+		}
+
+		err = cursor.All(ctx, &indexes)
+		if err != nil {
+			return info, err
+		}
+
+		names := []string{}
+		for _, index := range indexes {
+			names = append(names, index.Name)
+		}
+
+		info.MongoDB.Indexes[collection] = names
+	}
+
+	return info, nil
+}
+
+// featureFlags returns the state of the optional features of the service
+func featureFlags(catalogSettings *settings.Settings) map[string]string {
+	return map[string]string{
+		"tracing_exporter":   catalogSettings.Tracing.Exporter,
+		"migration_mode":     catalogSettings.Migration.Mode,
+		"migration_primary":  catalogSettings.Migration.Primary,
+		"shadow_reads":       fmt.Sprint(catalogSettings.Migration.ShadowReads),
+		"tagging_rules":      fmt.Sprint(len(catalogSettings.Tagging.Rules)),
+		"compression":        fmt.Sprint(catalogSettings.Compression.Enabled),
+		"cors":               fmt.Sprint(len(catalogSettings.CORS.AllowedOrigins) != 0),
+		"consumer_retries":   fmt.Sprint(catalogSettings.Consumers.MaxRetries),
+		"body_limit_default": fmt.Sprint(catalogSettings.BodyLimits.Default),
+		"search_backend":     catalogSettings.Search.Backend,
+		"unique_fields":      strings.Join(catalogSettings.Constraints.UniqueFields, ","),
+		"expiration_purge":   fmt.Sprint(catalogSettings.Expiration.Purge),
+		"track_purchases":    fmt.Sprint(catalogSettings.Popularity.TrackPurchases),
+		"inventory_stock":    fmt.Sprint(catalogSettings.Inventory.URL != ""),
+		"event_versions":     strings.Join(catalogSettings.Events.Versions, ","),
+		"cloud_events":       fmt.Sprint(catalogSettings.Events.CloudEvents),
+		"message_broker":     catalogSettings.MessageBroker.Type,
+		"outbox_batch_size":  fmt.Sprint(catalogSettings.Outbox.BatchSize),
+	}
+}
+
+// logProperties flattens the runtime information into log properties
+func (info *runtimeInfo) logProperties() map[string]string {
+	properties := map[string]string{
+		"profile":            info.Profile,
+		"version":            info.Version,
+		"commit":             info.Commit,
+		"build_time":         info.BuildTime,
+		"go_version":         info.GoVersion,
+		"mongodb.version":    info.MongoDB.Version,
+		"mongodb.topology":   info.MongoDB.Topology,
+		"rabbitmq.version":   info.RabbitMQ.Version,
+		"rabbitmq.exchanges": strings.Join(info.RabbitMQ.Exchanges, ","),
+		"rabbitmq.queues":    strings.Join(info.RabbitMQ.Queues, ","),
+	}
+
+	for collection, indexes := range info.MongoDB.Indexes {
+		properties[fmt.Sprintf("mongodb.indexes.%s", collection)] = strings.Join(indexes, ",")
+	}
+
+	for feature, value := range info.Features {
+		properties[fmt.Sprintf("features.%s", feature)] = value
+	}
+
+	return properties
+}
+
+// getRuntimeInfoHandler is the handler for the "GET /admin/runtime-info" endpoint
+func (app *Application) getRuntimeInfoHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Retrieving runtime information")
+	defer span.End()
+
+	env := types.Envelope{
+		"runtime_info": app.RuntimeInfo,
+	}
+
+	err := app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
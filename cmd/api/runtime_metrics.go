@@ -0,0 +1,41 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// runtimeMetricsRules are the runtime/metrics exported on top of the MemStats like metrics of the Go collector
+// (i.e. go_goroutines, go_gc_duration_seconds and go_memstats_heap_alloc_bytes): the distribution of the GC
+// pauses, the heap memory by class and the latency of the scheduler
+var runtimeMetricsRules = []collectors.GoRuntimeMetricsRule{
+	{Matcher: regexp.MustCompile(`^/gc/pauses:seconds$`)},
+	{Matcher: regexp.MustCompile(`^/gc/heap/(allocs|frees|goal):bytes$`)},
+	{Matcher: regexp.MustCompile(`^/memory/classes/heap/.*`)},
+	{Matcher: regexp.MustCompile(`^/sched/latencies:seconds$`)},
+}
+
+// registerRuntimeCollectors registers the collectors of the Go runtime and of the process (i.e. process_open_fds
+// and process_resident_memory_bytes) to the given registerer, which is served by "GET /metrics" for the default one.
+// The Go collector registered by default by client_golang is replaced by one exporting the runtime metrics rules.
+func registerRuntimeCollectors(registerer prometheus.Registerer) error {
+	registerer.Unregister(collectors.NewGoCollector())
+	registerer.Unregister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	runtimeCollectors := []prometheus.Collector{
+		collectors.NewGoCollector(collectors.WithGoCollectorRuntimeMetrics(runtimeMetricsRules...)),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewBuildInfoCollector(),
+	}
+
+	for _, collector := range runtimeCollectors {
+		err := registerer.Register(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
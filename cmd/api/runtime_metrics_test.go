@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegisterRuntimeCollectors(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	err := registerRuntimeCollectors(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gathered := make(map[string]bool, len(families))
+	for _, family := range families {
+		gathered[family.GetName()] = true
+	}
+
+	for _, name := range []string{"go_goroutines", "go_gc_duration_seconds", "go_gc_pauses_seconds", "go_memstats_heap_alloc_bytes", "process_open_fds", "go_build_info"} {
+		if !gathered[name] {
+			t.Errorf("want metric %s to be gathered; got %v", name, gathered)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// getSavedFiltersHandler is the handler for the "GET /admin/saved-filters" endpoint
+func (app *Application) getSavedFiltersHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving saved filters")
+	defer span.End()
+
+	// Read query string
+	queryString := r.URL.Query()
+
+	// Instantiate validator
+	v := validation.New()
+
+	// Extract values from query string if they exist
+	findOpts := filters.Filters{
+		Page:         app.readIntFromQueryString(queryString, "page", 1, v),
+		PageSize:     app.readIntFromQueryString(queryString, "page_size", 20, v),
+		Sort:         app.ReadStringFromQueryString(queryString, "sort", "slug"),
+		SortSafelist: []string{"slug", "name", "-slug", "-name"},
+	}
+
+	data.ValidateFilters(v, findOpts)
+
+	// Check the Validator instance for any errors
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Retrieve all saved filters
+	savedFilters, metadata, err := app.SavedFiltersRepository.GetAll(ctx, bson.M{}, findOpts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	env := types.Envelope{
+		"saved_filters": savedFilters,
+		"metadata":      metadata,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// getSavedFilterHandler is the handler for the "GET /admin/saved-filters/:slug" endpoint
+func (app *Application) getSavedFilterHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Retrieving saved filter")
+	defer span.End()
+
+	// Extract slug parameter from request URL parameters and record it in the trace
+	slug := chi.URLParamFromCtx(r.Context(), "slug")
+	span.SetAttributes(attribute.String("slug", slug))
+
+	// Retrieve saved filter with given slug
+	savedFilter, err := app.SavedFiltersRepository.GetByFilter(ctx, bson.M{"slug": slug})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	env := types.Envelope{
+		"saved_filter": savedFilter,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// createSavedFilterHandler is the handler for the "POST /admin/saved-filters" endpoint
+func (app *Application) createSavedFilterHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Creating saved filter")
+	defer span.End()
+
+	// Declare an anonymous struct to hold the information that we expect to be in the request body
+	var input struct {
+		Slug  string            `json:"slug"`
+		Name  string            `json:"name"`
+		Query map[string]string `json:"query"`
+	}
+
+	// Read request body and decode it into the input struct
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Copy the values from the input struct to a new SavedFilter struct
+	savedFilter := data.SavedFilter{
+		Slug:      input.Slug,
+		Name:      input.Name,
+		Query:     input.Query,
+		CreatedBy: app.ContextGetUser(r).ID,
+		Version:   1,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	// Initialize a new Validator instance
+	v := validation.New()
+
+	// Perform validation checks
+	data.ValidateSavedFilter(v, savedFilter)
+
+	// Validate the saved query against the "GET /items" grammar
+	queryValidator := validation.New()
+	app.readItemsQuery(savedFilter.Values(), queryValidator)
+
+	for key, message := range queryValidator.Errors {
+		v.AddError(fmt.Sprintf("query.%s", key), queryValidator.Reasons[key], message)
+	}
+
+	if v.HasErrors() {
+		span.SetStatus(codes.Error, "Validation failed")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Record saved filter attributes in trace
+	span.SetAttributes(
+		attribute.String("slug", savedFilter.Slug),
+		attribute.String("name", savedFilter.Name),
+	)
+
+	// Create a record in the database
+	_, err = app.SavedFiltersRepository.Create(ctx, savedFilter)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrDuplicateKey):
+			v.AddError("slug", "already_exists", "a saved filter with this slug already exists")
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// Include a Location header to let the client know where to find the newly-created resource
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/admin/saved-filters/%s", savedFilter.Slug))
+
+	env := types.Envelope{
+		"message": "Saved filter created successfully",
+	}
+
+	err = app.WriteJSON(w, http.StatusCreated, env, headers)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
+
+// deleteSavedFilterHandler is the handler for the "DELETE /admin/saved-filters/:slug" endpoint
+func (app *Application) deleteSavedFilterHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Deleting saved filter")
+	defer span.End()
+
+	// Extract slug parameter from request URL parameters and record it in the trace
+	slug := chi.URLParamFromCtx(r.Context(), "slug")
+	span.SetAttributes(attribute.String("slug", slug))
+
+	// Retrieve saved filter with given slug
+	savedFilter, err := app.SavedFiltersRepository.GetByFilter(ctx, bson.M{"slug": slug})
+	if err == nil {
+		// Delete saved filter in the database
+		err = app.SavedFiltersRepository.Delete(ctx, savedFilter.ID)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.NotFoundResponse(w, r)
+		default:
+			app.ServerErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	env := types.Envelope{
+		"message": "Saved filter deleted successfully",
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
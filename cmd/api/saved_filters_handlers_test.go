@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCreateSavedFilterHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	authenticationTests := []struct {
+		testName           string
+		useAuthHeader      bool
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No Authorization header", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"Invalid access token", true, "invalid", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has catalog:read", true, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+	}
+
+	for _, tt := range authenticationTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			body := map[string]any{}
+			body["slug"] = "cheap-potions"
+			body["name"] = "Cheap potions"
+			body["query"] = map[string]string{"name": "potion", "max_price": "6"}
+
+			statusCode, _, resBody := ts.post(t, "/admin/saved-filters", body, tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	// -----------------------------
+
+	validationTests := []struct {
+		testName           string
+		slug               string
+		name               string
+		query              map[string]string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"Valid submission", "cheap-potions", "Cheap potions", map[string]string{"name": "potion", "max_price": "6"}, http.StatusCreated, []byte("Saved filter created successfully")},
+		{"Duplicate slug", "cheap-potions", "Cheap potions", map[string]string{"name": "potion"}, http.StatusUnprocessableEntity, []byte("a saved filter with this slug already exists")},
+		{"Invalid slug", "Cheap Potions", "Cheap potions", map[string]string{"name": "potion"}, http.StatusUnprocessableEntity, []byte("must only contain lowercase letters, digits and hyphens")},
+		{"Empty name", "cheap-potions-2", "", map[string]string{"name": "potion"}, http.StatusUnprocessableEntity, []byte("must be provided")},
+		{"Empty query", "cheap-potions-2", "Cheap potions", map[string]string{}, http.StatusUnprocessableEntity, []byte("must contain at least one parameter")},
+		{"Unsupported parameter", "cheap-potions-2", "Cheap potions", map[string]string{"page": "2"}, http.StatusUnprocessableEntity, []byte("contains an unsupported parameter page")},
+		{"Invalid sort value", "cheap-potions-2", "Cheap potions", map[string]string{"sort": "invalid"}, http.StatusUnprocessableEntity, []byte("invalid sort value")},
+		{"Invalid max_price", "cheap-potions-2", "Cheap potions", map[string]string{"max_price": "1001"}, http.StatusUnprocessableEntity, []byte("must be greater or equal to 0.1 or lower and equal to 1000")},
+	}
+
+	for _, tt := range validationTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			body := map[string]any{}
+			body["slug"] = tt.slug
+			body["name"] = tt.name
+			body["query"] = tt.query
+
+			statusCode, _, resBody := ts.post(t, "/admin/saved-filters", body, true, accessTokenUser1)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+}
+
+func TestGetItemsHandlerWithSavedFilter(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+
+	// Save a filter
+	body := map[string]any{}
+	body["slug"] = "cheap-potions"
+	body["name"] = "Cheap potions"
+	body["query"] = map[string]string{"name": "potion", "max_price": "6"}
+
+	ts.post(t, "/admin/saved-filters", body, true, accessTokenUser1)
+
+	tests := []struct {
+		testName            string
+		queryString         string
+		wantedStatusCode    int
+		expectedItemsLength int
+	}{
+		{"Saved filter", "?saved_filter=cheap-potions", http.StatusOK, 1},
+		{"Saved filter overridden by query string", "?saved_filter=cheap-potions&max_price=8", http.StatusOK, 2},
+		{"Unknown saved filter", "?saved_filter=unknown", http.StatusUnprocessableEntity, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.get(t, fmt.Sprintf("/v1/items%s", tt.queryString), true, accessTokenUser2)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if statusCode != http.StatusOK {
+				return
+			}
+
+			var jsonRes map[string]any
+
+			err := json.Unmarshal(resBody, &jsonRes)
+			if err != nil {
+				t.Error("Failed to parse json response")
+			}
+
+			items := (jsonRes["items"]).([]any)
+
+			if len(items) != tt.expectedItemsLength {
+				t.Errorf("want to receive %d items but got %d", tt.expectedItemsLength, len(items))
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/inventory"
+	"github.com/PlayEconomy37/Play.Catalog/internal/outbox"
+	"github.com/PlayEconomy37/Play.Catalog/internal/scheduler"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+)
+
+// Names of the scheduled maintenance tasks, besides itemExpirationTask
+const (
+	cacheEvictionTask    = "caches.eviction"
+	outboxCompactionTask = "outbox.compaction"
+	retentionPurgeTask   = "retention.purge"
+)
+
+// newCacheEvictionTask returns the scheduled task which removes the expired entries of the in-memory caches of the
+// instance at the given interval. The caches otherwise keep the entries which are not requested anymore until they
+// are full. The inventory client is nil when the stock is not retrieved.
+func newCacheEvictionTask(userCache *data.UserCache, inventoryClient *inventory.Client, interval time.Duration, logger *logger.Logger) scheduler.Task {
+	return scheduler.Task{
+		Name:     cacheEvictionTask,
+		Interval: interval,
+		Local:    true,
+		Run: func(ctx context.Context) error {
+			users := userCache.EvictExpired()
+			stock := 0
+
+			if inventoryClient != nil {
+				stock = inventoryClient.EvictExpired()
+			}
+
+			if users != 0 || stock != 0 {
+				logger.Info("Expired cache entries evicted", map[string]string{"users": fmt.Sprint(users), "stock": fmt.Sprint(stock)})
+			}
+
+			return nil
+		},
+	}
+}
+
+// newOutboxCompactionTask returns the scheduled task which compacts the outbox collections at the given interval
+func newOutboxCompactionTask(store *outbox.Store, interval time.Duration) scheduler.Task {
+	return scheduler.Task{
+		Name:     outboxCompactionTask,
+		Interval: interval,
+		Run:      store.Compact,
+	}
+}
+
+// newRetentionPurgeTask returns the scheduled task which purges, at the purge interval, the revisions of the items
+// recorded before their retention window. The revisions are kept when their retention is 0.
+func newRetentionPurgeTask(revisions *data.RevisionStore, cfg settings.Retention, metrics *data.RetentionMetrics, logger *logger.Logger) scheduler.Task {
+	return scheduler.Task{
+		Name:     retentionPurgeTask,
+		Interval: time.Duration(cfg.PurgeInterval) * time.Second,
+		Run: func(ctx context.Context) error {
+			if cfg.Revisions == 0 {
+				return nil
+			}
+
+			purged, err := revisions.PurgeBefore(ctx, time.Now().UTC().Add(-time.Duration(cfg.Revisions)*time.Second))
+			if err != nil {
+				return err
+			}
+
+			metrics.PurgedCounter.WithLabelValues(constants.ItemRevisionsCollection).Add(float64(purged))
+
+			if purged != 0 {
+				logger.Info("Item revisions purged", map[string]string{"purged": fmt.Sprint(purged)})
+			}
+
+			return nil
+		},
+	}
+}
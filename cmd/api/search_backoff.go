@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// searchBackoff remembers the last failure of the search backend so that the name searches go straight to the
+// text index for a while, instead of waiting for the backend to fail on every search while it is unavailable
+type searchBackoff struct {
+	duration time.Duration
+	failedAt atomic.Int64 // Unix time in nanoseconds of the last failure, 0 once the backend is tried again
+}
+
+// newSearchBackoff creates a new searchBackoff skipping the backend for the given duration after a failure
+func newSearchBackoff(duration time.Duration) *searchBackoff {
+	return &searchBackoff{duration: duration}
+}
+
+// available checks if the backend can be tried at the given time. Nil backoffs always try the backend.
+func (backoff *searchBackoff) available(now time.Time) bool {
+	if backoff == nil {
+		return true
+	}
+
+	failedAt := backoff.failedAt.Load()
+
+	return failedAt == 0 || now.Sub(time.Unix(0, failedAt)) >= backoff.duration
+}
+
+// fail records that the backend failed at the given time
+func (backoff *searchBackoff) fail(now time.Time) {
+	if backoff != nil {
+		backoff.failedAt.Store(now.UnixNano())
+	}
+}
+
+// succeed records that the backend answered, so that it is tried again right away after its next failure
+func (backoff *searchBackoff) succeed() {
+	if backoff != nil {
+		backoff.failedAt.Store(0)
+	}
+}
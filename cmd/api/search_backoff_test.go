@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchBackoff(t *testing.T) {
+	backoff := newSearchBackoff(30 * time.Second)
+	now := time.Now()
+
+	if !backoff.available(now) {
+		t.Fatal("want the backend to be available before its first failure")
+	}
+
+	backoff.fail(now)
+
+	tests := []struct {
+		testName string
+		elapsed  time.Duration
+		wanted   bool
+	}{
+		{"Right after the failure", 0, false},
+		{"Within the backoff", 29 * time.Second, false},
+		{"Once the backoff elapsed", 30 * time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if got := backoff.available(now.Add(tt.elapsed)); got != tt.wanted {
+				t.Errorf("want %t; got %t", tt.wanted, got)
+			}
+		})
+	}
+
+	backoff.succeed()
+
+	if !backoff.available(now) {
+		t.Error("want the backend to be available once it answered")
+	}
+
+	// The backend is always tried without backoff
+	var disabled *searchBackoff
+
+	disabled.fail(now)
+
+	if !disabled.available(now) {
+		t.Error("want the backend to be available without backoff")
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Status of a self-test step
+const (
+	selftestPassed  = "passed"
+	selftestFailed  = "failed"
+	selftestSkipped = "skipped"
+)
+
+// selftestStep is a struct that holds the outcome of a single self-test step
+type selftestStep struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// selftestReport is a struct that holds the outcome of a self-test run
+type selftestReport struct {
+	Status     string         `json:"status"`
+	DurationMs float64        `json:"duration_ms"`
+	Steps      []selftestStep `json:"steps"`
+}
+
+// run executes the given step function, records its outcome and timing in the report
+// and returns true if the step passed. Once a step failed, the following ones are skipped.
+func (report *selftestReport) run(name string, step func() error) bool {
+	if report.Status == selftestFailed {
+		report.skip(name, "previous step failed")
+		return false
+	}
+
+	start := time.Now()
+	err := step()
+
+	result := selftestStep{
+		Name:       name,
+		Status:     selftestPassed,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		result.Status = selftestFailed
+		result.Error = err.Error()
+		report.Status = selftestFailed
+	}
+
+	report.Steps = append(report.Steps, result)
+
+	return err == nil
+}
+
+// skip records a step which was not executed in the report
+func (report *selftestReport) skip(name string, reason string) {
+	report.Steps = append(report.Steps, selftestStep{
+		Name:   name,
+		Status: selftestSkipped,
+		Error:  reason,
+	})
+}
+
+// selftestPublisher records the events published by the self-test instead of sending them
+type selftestPublisher struct {
+	sent []messaging.Envelope
+}
+
+func (publisher *selftestPublisher) System() string { return "selftest" }
+
+func (publisher *selftestPublisher) Destination(route messaging.Route) string { return route.Exchange }
+
+func (publisher *selftestPublisher) Send(ctx context.Context, route messaging.Route, msg messaging.Message) error {
+	publisher.sent = append(publisher.sent, messaging.Envelope{Route: route, Message: msg})
+
+	return nil
+}
+
+// selftestHandler is the handler for the "POST /admin/selftest" endpoint.
+// It runs the items write path against a sandbox collection and reports the timing of each step.
+func (app *Application) selftestHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Running self-test")
+	defer span.End()
+
+	start := time.Now()
+	report := selftestReport{Status: selftestPassed}
+
+	// Build a synthetic item which cannot collide with another self-test run
+	runID := primitive.NewObjectID().Hex()
+	span.SetAttributes(attribute.String("run_id", runID))
+
+	item := data.Item{
+		Name:        fmt.Sprintf("selftest-%s", runID),
+		Description: fmt.Sprintf("Synthetic item created by self-test run %s", runID),
+		Price:       1,
+		Version:     1,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+
+	created := report.run("create", func() error {
+		item = app.TaggingEngine.Apply(item)
+
+		id, err := app.SelftestItemsRepository.Create(ctx, item)
+		if err != nil {
+			return err
+		}
+
+		item.ID = *id
+
+		return nil
+	})
+
+	report.run("read", func() error {
+		fetched, err := app.SelftestItemsRepository.GetByID(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+
+		if fetched.Name != item.Name {
+			return fmt.Errorf("read item name %q does not match created item name %q", fetched.Name, item.Name)
+		}
+
+		item = fetched
+
+		return nil
+	})
+
+	report.run("update", func() error {
+		item.Price = 2
+		item.UpdatedAt = time.Now().UTC()
+		item = app.TaggingEngine.Apply(item)
+
+		return app.SelftestItemsRepository.Update(ctx, item)
+	})
+
+	// The event is serialized like the ones stored in the outbox but never enqueued, so that the
+	// consumers do not receive the synthetic item
+	if app.EventSerializer != nil {
+		report.run("publish_event", func() error {
+			publisher := &selftestPublisher{}
+
+			err := messaging.NewItemChangePublisher(publisher, app.EventSerializer, app.Config.ServiceName).PublishUpdated(ctx, item, item.UpdatedAt)
+			if err != nil {
+				return err
+			}
+
+			if len(publisher.sent) == 0 {
+				return errors.New("no event was published")
+			}
+
+			for _, envelope := range publisher.sent {
+				if envelope.Message.Key != item.ID.Hex() || len(envelope.Message.Body) == 0 {
+					return fmt.Errorf("event %q does not hold the updated item", envelope.Message.ID)
+				}
+			}
+
+			return nil
+		})
+	} else {
+		report.skip("publish_event", "no event serializer is configured")
+	}
+
+	// A synthetic user, whose negative id is never assigned by the Identity microservice, is cached and invalidated
+	if app.UserCache != nil && app.Settings.UserCache.TTL != 0 {
+		report.run("invalidate_cache", func() error {
+			user := data.User{ID: -time.Now().UnixNano(), Name: fmt.Sprintf("selftest-%s", runID)}
+
+			app.UserCache.Set(user)
+
+			if _, ok := app.UserCache.Get(user.ID); !ok {
+				return errors.New("user was not cached")
+			}
+
+			app.UserCache.Invalidate(user.ID)
+
+			if _, ok := app.UserCache.Get(user.ID); ok {
+				return errors.New("user is still cached after its invalidation")
+			}
+
+			return nil
+		})
+	} else {
+		report.skip("invalidate_cache", "the user cache is disabled")
+	}
+
+	deleted := report.run("delete", func() error {
+		return app.SelftestItemsRepository.Delete(ctx, item.ID)
+	})
+
+	report.run("verify_delete", func() error {
+		_, err := app.SelftestItemsRepository.GetByID(ctx, item.ID)
+		if errors.Is(err, database.ErrRecordNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return errors.New("item still exists after deletion")
+	})
+
+	// Do not leave the synthetic item behind if the run failed midway
+	if created && !deleted {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := app.SelftestItemsRepository.Delete(cleanupCtx, item.ID)
+		if err != nil {
+			app.Logger.Error(err, map[string]string{"run_id": runID, "step": "cleanup"})
+		}
+	}
+
+	report.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+	statusCode := http.StatusOK
+	if report.Status == selftestFailed {
+		span.SetStatus(codes.Error, "Self-test failed")
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	env := types.Envelope{
+		"selftest": report,
+	}
+
+	err := app.WriteJSON(w, statusCode, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
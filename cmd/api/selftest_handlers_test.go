@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+func TestSelftestHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	serializer, err := messaging.NewSerializer(app.Settings.Events, app.Config.ServiceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.EventSerializer = serializer
+	app.UserCache = data.NewUserCache(settings.UserCache{TTL: 60, MaxEntries: 10}, data.NewUserCacheMetrics("selftest_test"))
+	app.Settings.UserCache.TTL = 60
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		testName           string
+		useAuthHeader      bool
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"No Authorization header", false, "", http.StatusUnauthorized, []byte("invalid or missing authentication token")},
+		{"User does not have permission - has catalog:read", true, accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Valid request", true, accessTokenUser1, http.StatusOK, []byte(`"status": "passed"`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, "/admin/selftest", nil, tt.useAuthHeader, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+
+			if statusCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Selftest selftestReport `json:"selftest"`
+			}
+
+			err := json.Unmarshal(resBody, &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, step := range body.Selftest.Steps {
+				if step.Status != selftestPassed {
+					t.Errorf("want step %q to be %s; got %s (%s)", step.Name, selftestPassed, step.Status, step.Error)
+				}
+			}
+		})
+	}
+}
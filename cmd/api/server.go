@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// tlsCipherSuites are the TLS 1.2 cipher suites accepted by the HTTPS listeners. They all provide forward
+// secrecy and authenticated encryption. The TLS 1.3 cipher suites are not configurable and are all secure.
+var tlsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// serve starts the HTTP servers of the listeners of the settings and stops them gracefully on SIGINT and SIGTERM.
+// The main listener serves the given router, in cleartext unless TLS is enabled.
+func (app *Application) serve(router http.Handler) error {
+	var tlsConfig *tls.Config
+
+	if app.Settings.Server.TLS.Enabled {
+		var err error
+
+		tlsConfig, err = newTLSConfig(app.Settings.Server.TLS)
+		if err != nil {
+			return err
+		}
+	}
+
+	server, err := app.newServer(app.Config.Address, router, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	servers := []*http.Server{server}
+
+	if internal := app.Settings.Server.Internal; internal.Enabled {
+		internalTLSConfig, err := newInternalTLSConfig(internal, app.Settings.Server.TLS.MinVersion)
+		if err != nil {
+			return err
+		}
+
+		internalServer, err := app.newServer(internal.Address, app.internalRoutes(), internalTLSConfig)
+		if err != nil {
+			return err
+		}
+
+		servers = append(servers, internalServer)
+	}
+
+	// The admin listener is not exposed publicly and is served in cleartext
+	if admin := app.Settings.Server.Admin; admin.Enabled {
+		adminServer, err := app.newServer(admin.Address, app.adminRoutes(), nil)
+		if err != nil {
+			return err
+		}
+
+		servers = append(servers, adminServer)
+	}
+
+	return app.runServers(servers)
+}
+
+// runServers starts the given servers and blocks until they are shut down. All the servers are stopped
+// when one of them fails (i.e. its address is already in use). The background tasks are completed on shutdown.
+func (app *Application) runServers(servers []*http.Server) error {
+	serveError := make(chan error, len(servers))
+
+	for _, server := range servers {
+		server := server
+
+		app.Logger.Info("Starting server", map[string]string{
+			"addr": server.Addr,
+			"tls":  fmt.Sprint(server.TLSConfig != nil),
+		})
+
+		go func() {
+			// The certificates are part of the TLS configuration
+			if server.TLSConfig != nil {
+				serveError <- server.ListenAndServeTLS("", "")
+			} else {
+				serveError <- server.ListenAndServe()
+			}
+		}()
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	var err error
+
+	select {
+	case sig := <-quit:
+		app.Logger.Info("Shutting down server", map[string]string{
+			"signal": sig.String(),
+		})
+	case err = <-serveError:
+		app.Logger.Error(err, map[string]string{"operation": "serve"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, server := range servers {
+		shutdownErr := server.Shutdown(ctx)
+		if shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+
+	app.Logger.Info("Completing background tasks", nil)
+
+	app.WaitGroup.Wait()
+
+	if err != nil {
+		return err
+	}
+
+	app.Logger.Info("Stopped server", nil)
+
+	return nil
+}
+
+// newServer creates an HTTP server with the timeouts of Play.Common for the given address,
+// which serves HTTPS with the given TLS configuration or cleartext HTTP when it is nil.
+// HTTP/2 is negotiated with ALPN over TLS and accepted without TLS (h2c) from the ingresses.
+func (app *Application) newServer(address string, router http.Handler, tlsConfig *tls.Config) (*http.Server, error) {
+	if tlsConfig == nil && app.Settings.Server.HTTP2 {
+		router = h2c.NewHandler(router, &http2.Server{})
+	}
+
+	server := &http.Server{
+		Addr:         address,
+		Handler:      router,
+		ErrorLog:     log.New(app.Logger, "", 0),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		TLSConfig:    tlsConfig,
+	}
+
+	if tlsConfig == nil {
+		return server, nil
+	}
+
+	if app.Settings.Server.HTTP2 {
+		err := http2.ConfigureServer(server, &http2.Server{})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// A non-nil empty map disables the HTTP/2 support of net/http
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	return server, nil
+}
+
+// newTLSConfig creates the TLS configuration of the HTTPS listener from the settings.
+// The certificate files are loaded up front so that a misconfiguration stops the service on startup.
+func newTLSConfig(tlsSettings settings.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CipherSuites:     tlsCipherSuites,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+	}
+
+	if tlsSettings.MinVersion == "1.3" {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	if len(tlsSettings.AutocertHosts) == 0 {
+		certificate, err := tls.LoadX509KeyPair(tlsSettings.CertFile, tlsSettings.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+
+		return tlsConfig, nil
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(tlsSettings.AutocertHosts...),
+		Email:      tlsSettings.AutocertEmail,
+	}
+
+	if tlsSettings.AutocertCacheDir != "" {
+		manager.Cache = autocert.DirCache(tlsSettings.AutocertCacheDir)
+	}
+
+	// The certificates are obtained with the TLS-ALPN-01 challenge on the HTTPS listener itself
+	tlsConfig.GetCertificate = manager.GetCertificate
+	tlsConfig.NextProtos = []string{acme.ALPNProto}
+
+	return tlsConfig, nil
+}
+
+// newInternalTLSConfig creates the TLS configuration of the internal listener, which only completes the
+// handshakes of the clients presenting a certificate signed by the client CA of the settings
+func newInternalTLSConfig(internal settings.InternalListener, minVersion string) (*tls.Config, error) {
+	tlsConfig, err := newTLSConfig(settings.TLS{CertFile: internal.CertFile, KeyFile: internal.KeyFile, MinVersion: minVersion})
+	if err != nil {
+		return nil, err
+	}
+
+	clientCAs, err := os.ReadFile(internal.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(clientCAs) {
+		return nil, fmt.Errorf("no certificate found in client CA file %s", internal.ClientCAFile)
+	}
+
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return tlsConfig, nil
+}
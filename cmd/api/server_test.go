@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// writeSelfSignedCertificate writes a self-signed certificate for 127.0.0.1 with the given common name and its key
+// into the given directory. The certificate is both a server and a client certificate.
+func writeSelfSignedCertificate(t *testing.T, dir string, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true, // Self-signed certificates are their own CA
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, commonName+".crt")
+	keyFile := filepath.Join(dir, commonName+".key")
+
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKey}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCertificate(t, t.TempDir(), "Play.Catalog")
+
+	tests := []struct {
+		name          string
+		minVersion    string
+		clientVersion uint16
+		wantedVersion uint16
+		wantedErr     bool
+	}{
+		{"TLS 1.2 minimum with TLS 1.3 client", "1.2", tls.VersionTLS13, tls.VersionTLS13, false},
+		{"TLS 1.2 minimum with TLS 1.2 client", "1.2", tls.VersionTLS12, tls.VersionTLS12, false},
+		{"TLS 1.3 minimum with TLS 1.2 client", "1.3", tls.VersionTLS12, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(settings.TLS{Enabled: true, CertFile: certFile, KeyFile: keyFile, MinVersion: tt.minVersion})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Proto))
+			}))
+			ts.EnableHTTP2 = true
+			ts.TLS = tlsConfig
+			ts.StartTLS()
+			defer ts.Close()
+
+			// Trust the self-signed certificate
+			client := ts.Client()
+			transport := client.Transport.(*http.Transport)
+			transport.TLSClientConfig.MaxVersion = tt.clientVersion
+
+			res, err := client.Get(ts.URL)
+
+			if tt.wantedErr {
+				if err == nil {
+					res.Body.Close()
+					t.Error("want handshake error; got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if res.TLS.Version != tt.wantedVersion {
+				t.Errorf("want TLS version %x; got %x", tt.wantedVersion, res.TLS.Version)
+			}
+
+			if res.ProtoMajor != 2 {
+				t.Errorf("want HTTP/2; got %s", res.Proto)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfigMissingCertificate(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := newTLSConfig(settings.TLS{Enabled: true, CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem"), MinVersion: "1.2"})
+	if err == nil {
+		t.Error("want error; got nil")
+	}
+}
+
+func TestNewTLSConfigAutocert(t *testing.T) {
+	tlsConfig, err := newTLSConfig(settings.TLS{Enabled: true, AutocertHosts: []string{"catalog.example.com"}, MinVersion: "1.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tlsConfig.GetCertificate == nil {
+		t.Error("want certificates obtained with autocert; got nil GetCertificate")
+	}
+
+	// Hosts out of the whitelist are refused without contacting the certificate authority
+	_, err = tlsConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"})
+	if err == nil {
+		t.Error("want error for host out of the whitelist; got nil")
+	}
+}
+
+func TestInternalListener(t *testing.T) {
+	dir := t.TempDir()
+
+	certFile, keyFile := writeSelfSignedCertificate(t, dir, "Play.Catalog")
+	inventoryCertFile, inventoryKeyFile := writeSelfSignedCertificate(t, dir, "Play.Inventory")
+	identityCertFile, identityKeyFile := writeSelfSignedCertificate(t, dir, "Play.Identity")
+	untrustedCertFile, untrustedKeyFile := writeSelfSignedCertificate(t, dir, "Play.Untrusted")
+
+	// The client CA file holds the certificates of Play.Inventory and Play.Identity
+	clientCAFile := filepath.Join(dir, "clients.pem")
+
+	var clientCAs []byte
+	for _, file := range []string{inventoryCertFile, identityCertFile} {
+		certificate, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		clientCAs = append(clientCAs, certificate...)
+	}
+
+	err := os.WriteFile(clientCAFile, clientCAs, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	internal := settings.InternalListener{
+		Enabled:        true,
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ClientCAFile:   clientCAFile,
+		AllowedClients: []string{"Play.Inventory"},
+	}
+
+	tlsConfig, err := newInternalTLSConfig(internal, "1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &Application{Settings: &settings.Settings{Server: settings.Server{Internal: internal}}}
+
+	ts := httptest.NewUnstartedServer(app.requireClientCertificate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})))
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	tests := []struct {
+		name            string
+		certFile        string
+		keyFile         string
+		wantedCode      int
+		wantedHandshake bool
+	}{
+		{"Allowed client", inventoryCertFile, inventoryKeyFile, http.StatusOK, true},
+		{"Trusted client which is not allowed", identityCertFile, identityKeyFile, http.StatusForbidden, true},
+		{"Untrusted client", untrustedCertFile, untrustedKeyFile, 0, false},
+		{"No client certificate", "", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Trust the certificate of the server
+			transport := ts.Client().Transport.(*http.Transport).Clone()
+
+			if tt.certFile != "" {
+				certificate, err := tls.LoadX509KeyPair(tt.certFile, tt.keyFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				transport.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+			}
+
+			client := &http.Client{Transport: transport}
+
+			res, err := client.Get(ts.URL)
+
+			if !tt.wantedHandshake {
+				if err == nil {
+					res.Body.Close()
+					t.Error("want handshake error; got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.wantedCode {
+				t.Errorf("want %d; got %d", tt.wantedCode, res.StatusCode)
+			}
+		})
+	}
+}
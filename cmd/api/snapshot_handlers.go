@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// itemSnapshotPublisher is implemented by the publishers of the catalog snapshots
+type itemSnapshotPublisher interface {
+	PublishItem(ctx context.Context, snapshotID string, item data.Item) error
+	PublishCompleted(ctx context.Context, snapshotID string, items int) error
+}
+
+// snapshotHandler is the handler for the "POST /admin/snapshot" endpoint.
+// It republishes every item of the catalog as an ItemCreated event so that new consumers can
+// bootstrap their copy of the catalog. Expired items are not published.
+func (app *Application) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	ctx, span := app.Tracer.Start(r.Context(), "Publishing catalog snapshot")
+	defer span.End()
+
+	snapshotID := primitive.NewObjectID().Hex()
+
+	// Snapshot reads are not supported by standalone servers
+	snapshot := app.RuntimeInfo != nil && app.RuntimeInfo.MongoDB.Topology != "standalone"
+
+	span.SetAttributes(attribute.String("snapshot_id", snapshotID), attribute.Bool("snapshot", snapshot))
+
+	now := time.Now().UTC()
+	published := 0
+
+	err := app.ItemsRepository.Export(ctx, snapshot, func(document data.Document[data.Item]) error {
+		if document.Entity.IsExpired(now) {
+			return nil
+		}
+
+		err := app.SnapshotPublisher.PublishItem(ctx, snapshotID, document.Entity)
+		if err != nil {
+			return err
+		}
+
+		published++
+
+		return nil
+	})
+	if err == nil {
+		err = app.SnapshotPublisher.PublishCompleted(ctx, snapshotID, published)
+	}
+
+	span.SetAttributes(attribute.Int("published", published))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	app.Logger.Info("Catalog snapshot published", map[string]string{"snapshot_id": snapshotID, "published": fmt.Sprint(published)})
+
+	env := types.Envelope{
+		"snapshot_id": snapshotID,
+		"items":       published,
+	}
+
+	err = app.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		app.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+)
+
+// fakeItemSnapshotPublisher records the published snapshots
+type fakeItemSnapshotPublisher struct {
+	items     []data.Item
+	completed map[string]int
+}
+
+// PublishItem records the given item
+func (publisher *fakeItemSnapshotPublisher) PublishItem(ctx context.Context, snapshotID string, item data.Item) error {
+	publisher.items = append(publisher.items, item)
+
+	return nil
+}
+
+// PublishCompleted records the number of items of the given snapshot
+func (publisher *fakeItemSnapshotPublisher) PublishCompleted(ctx context.Context, snapshotID string, items int) error {
+	publisher.completed[snapshotID] = items
+
+	return nil
+}
+
+func TestSnapshotHandler(t *testing.T) {
+	app, cleanup := newTestApplication(t)
+	t.Cleanup(cleanup)
+
+	publisher := &fakeItemSnapshotPublisher{completed: make(map[string]int)}
+	app.SnapshotPublisher = publisher
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Seed items collection
+	seedItemsCollection(t, app.ItemsRepository)
+
+	tests := []struct {
+		testName           string
+		accessToken        string
+		wantedStatusCode   int
+		wantedResponseBody []byte
+	}{
+		{"User does not have permission - has catalog:read", accessTokenUser2, http.StatusForbidden, []byte("your user account doesn't have the necessary permissions to access this resource")},
+		{"Valid request", accessTokenUser1, http.StatusOK, []byte(`"items": 5`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			statusCode, _, resBody := ts.post(t, "/admin/snapshot", nil, true, tt.accessToken)
+
+			if statusCode != tt.wantedStatusCode {
+				t.Errorf("want %d; got %d", tt.wantedStatusCode, statusCode)
+			}
+
+			if !bytes.Contains(resBody, tt.wantedResponseBody) {
+				t.Errorf("want body %q to contain %q", resBody, tt.wantedResponseBody)
+			}
+		})
+	}
+
+	if len(publisher.items) != 5 {
+		t.Errorf("want %d published items; got %d", 5, len(publisher.items))
+	}
+
+	if len(publisher.completed) != 1 {
+		t.Errorf("want %d completed snapshot; got %d", 1, len(publisher.completed))
+	}
+}
@@ -15,6 +15,11 @@ import (
 
 	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
 	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/logging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/popularity"
+	"github.com/PlayEconomy37/Play.Catalog/internal/sanitize"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/tagging"
 	"github.com/PlayEconomy37/Play.Common/common"
 	"github.com/PlayEconomy37/Play.Common/configuration"
 	"github.com/PlayEconomy37/Play.Common/database"
@@ -59,7 +64,8 @@ func newTestApplication(t *testing.T) (*Application, func()) {
 		output = io.Discard
 	}
 
-	logger := logger.New(output, logger.LevelInfo)
+	logFilter := logging.NewLevelFilter(output, logging.LevelInfo)
+	logger := logger.New(logFilter, logger.LevelInfo)
 
 	// Read configuration
 	config, err := configuration.LoadConfig("../../config/dev.json")
@@ -67,27 +73,99 @@ func newTestApplication(t *testing.T) (*Application, func()) {
 		t.Fatal(err, nil)
 	}
 
+	// Read Catalog specific settings
+	catalogSettings, err := settings.LoadSettings("../../config/dev.json")
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
+	// Compile auto-tagging rules
+	taggingEngine, err := tagging.NewEngine(catalogSettings.Tagging.Rules)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
 	// Start MongoDB
 	mongoClient, err := database.NewMongoClient(config)
 
 	// Create "items" collection in test database
-	err = data.CreateItemsCollection(mongoClient, TestDatabase)
+	err = data.CreateItemsCollection(mongoClient, TestDatabase, catalogSettings.Pricing, catalogSettings.Constraints, catalogSettings.Expiration)
 	if err != nil {
 		t.Fatal(err, nil)
 	}
 
 	// Create "users" collection
-	err = database.CreateUsersCollection(mongoClient, TestDatabase)
+	err = data.CreateUsersCollection(mongoClient, TestDatabase)
 	if err != nil {
 		logger.Fatal(err, nil)
 	}
 
+	// Create "api_keys" collection in test database
+	err = data.CreateAPIKeysCollection(mongoClient, TestDatabase)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
+	// Create "saved_filters" collection in test database
+	err = data.CreateSavedFiltersCollection(mongoClient, TestDatabase)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
+	// Create "item_collections" collection in test database
+	err = data.CreateItemCollectionsCollection(mongoClient, TestDatabase)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
+	// Create "reviews" collection in test database
+	err = data.CreateReviewsCollection(mongoClient, TestDatabase)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
+	// Create "favorites" collection in test database
+	err = data.CreateFavoritesCollection(mongoClient, TestDatabase)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
+	// Create "item_revisions" collection in test database
+	err = data.CreateItemRevisionsCollection(mongoClient, TestDatabase)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
+	// Create "selftest_items" sandbox collection in test database
+	err = data.CreateSelftestItemsCollection(mongoClient, TestDatabase, catalogSettings.Pricing, catalogSettings.Constraints, catalogSettings.Expiration)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
+	// Create "item_popularity" collection in test database
+	err = data.CreatePopularityCollection(mongoClient, TestDatabase)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
 	// Create users repository
-	usersRepository := database.NewMongoRepository[int64, database.User](mongoClient, TestDatabase, database.UsersCollection)
+	usersRepository := database.NewMongoRepository[int64, data.User](mongoClient, TestDatabase, database.UsersCollection)
 
 	// Seed users
 	seedUsersCollection(t, usersRepository)
 
+	// Create popularity store
+	popularityStore := data.NewPopularityStore(mongoClient, TestDatabase)
+
+	// Create revision store
+	revisionStore := data.NewRevisionStore(mongoClient, TestDatabase)
+
+	// Collect runtime information
+	runtimeInfo, err := collectRuntimeInfo(context.Background(), "../../config/dev.json", catalogSettings, mongoClient, TestDatabase, nil)
+	if err != nil {
+		t.Fatal(err, nil)
+	}
+
 	// Database cleanup function
 	cleanup := func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -112,8 +190,31 @@ func newTestApplication(t *testing.T) (*Application, func()) {
 			Logger: logger,
 			Tracer: tracerProvider.Tracer(config.ServiceName),
 		},
-		ItemsRepository: database.NewMongoRepository[primitive.ObjectID, data.Item](mongoClient, TestDatabase, constants.ItemsCollection),
+		Settings: catalogSettings,
+		ItemsRepository: data.NewTenantRepository(
+			data.NewActorRepository(data.NewRevisionRepository(data.NewMongoRepository[primitive.ObjectID, data.Item](mongoClient, TestDatabase, constants.ItemsCollection), revisionStore, data.NewTransactions(mongoClient, catalogSettings.Transactions.Enabled))),
+			catalogSettings.Tenancy.DefaultTenant,
+		),
 		UsersRepository: usersRepository,
+
+		SavedFiltersRepository:    data.NewMongoRepository[primitive.ObjectID, data.SavedFilter](mongoClient, TestDatabase, constants.SavedFiltersCollection),
+		APIKeysRepository:         data.NewMongoRepository[primitive.ObjectID, data.APIKey](mongoClient, TestDatabase, constants.APIKeysCollection),
+		ItemCollectionsRepository: data.NewMongoRepository[primitive.ObjectID, data.ItemCollection](mongoClient, TestDatabase, constants.ItemCollectionsCollection),
+		ReviewsRepository:         data.NewMongoRepository[primitive.ObjectID, data.Review](mongoClient, TestDatabase, constants.ReviewsCollection),
+		RatingStore:               data.NewRatingStore(mongoClient, TestDatabase, nil),
+		FavoritesRepository:       data.NewMongoRepository[primitive.ObjectID, data.Favorite](mongoClient, TestDatabase, constants.FavoritesCollection),
+		RevisionStore:             revisionStore,
+		TaggingEngine:             taggingEngine,
+		Sanitizer:                 sanitize.NewPolicy(catalogSettings.Sanitization),
+
+		SelftestItemsRepository: data.NewMongoRepository[primitive.ObjectID, data.Item](mongoClient, TestDatabase, constants.SelftestItemsCollection),
+		RuntimeInfo:             runtimeInfo,
+
+		PopularityStore:   popularityStore,
+		PopularityCounter: popularity.NewCounter(popularityStore, logger),
+		Transactions:      data.NewTransactions(mongoClient, catalogSettings.Transactions.Enabled),
+		Maintenance:       newMaintenanceMode(data.NewMaintenanceStore(mongoClient, TestDatabase), logger),
+		LogFilter:         logFilter,
 	}, cleanup
 }
 
@@ -182,11 +283,40 @@ func (ts *testServer) get(t *testing.T, urlPath string, useAuthHeader bool, acce
 	return ts.makeRequest(t, "GET", urlPath, map[string]any{}, useAuthHeader, accessToken)
 }
 
+// head is a helper method for sending HEAD requests to the test server
+func (ts *testServer) head(t *testing.T, urlPath string, useAuthHeader bool, accessToken string) (int, http.Header, []byte) {
+	return ts.makeRequest(t, "HEAD", urlPath, map[string]any{}, useAuthHeader, accessToken)
+}
+
 // post is a helper method for sending POST requests to the test server
 func (ts *testServer) post(t *testing.T, urlPath string, body map[string]any, useAuthHeader bool, accessToken string) (int, http.Header, []byte) {
 	return ts.makeRequest(t, "POST", urlPath, body, useAuthHeader, accessToken)
 }
 
+// postRaw is a helper method for sending POST requests with a non JSON body to the test server
+func (ts *testServer) postRaw(t *testing.T, urlPath string, body []byte, accessToken string) (int, http.Header, []byte) {
+	req, err := http.NewRequest("POST", ts.URL+urlPath, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res.StatusCode, res.Header, resBody
+}
+
 // put is a helper method for sending PUT requests to the test server
 func (ts *testServer) put(t *testing.T, urlPath string, body map[string]any, useAuthHeader bool, accessToken string) (int, http.Header, []byte) {
 	return ts.makeRequest(t, "PUT", urlPath, body, useAuthHeader, accessToken)
@@ -244,7 +374,7 @@ func seedItemsCollection(t *testing.T, repository types.MongoRepository[primitiv
 }
 
 // seedUsersCollection inserts some users into the database
-func seedUsersCollection(t *testing.T, repository types.MongoRepository[int64, database.User]) {
+func seedUsersCollection(t *testing.T, repository types.MongoRepository[int64, data.User]) {
 	// Check if users are already in the database
 	fetchedUsers, _, err := repository.GetAll(context.Background(), bson.M{}, filters.Filters{Page: 1, PageSize: 20, Sort: "_id", SortSafelist: []string{"_id"}})
 	if err != nil {
@@ -256,10 +386,10 @@ func seedUsersCollection(t *testing.T, repository types.MongoRepository[int64, d
 		return
 	}
 
-	users := []database.User{
-		{ID: 1, Permissions: permissions.Permissions{"catalog:read", "catalog:write"}, Activated: true, Version: 2},
-		{ID: 2, Permissions: permissions.Permissions{"catalog:read"}, Activated: true, Version: 2},
-		{ID: 3, Permissions: permissions.Permissions{"inventory:read"}, Activated: true, Version: 2},
+	users := []data.User{
+		{ID: 1, Name: "Admin", Email: "admin@playeconomy.com", Permissions: permissions.Permissions{"catalog:read", "catalog:write", "catalog:admin"}, Activated: true, Version: 2},
+		{ID: 2, Name: "Player", Email: "player@playeconomy.com", Permissions: permissions.Permissions{"catalog:read"}, Activated: true, Version: 2},
+		{ID: 3, Name: "Inventory", Email: "inventory@playeconomy.com", Permissions: permissions.Permissions{"inventory:read"}, Activated: true, Version: 2},
 	}
 
 	for i := range users {
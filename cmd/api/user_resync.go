@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/identity"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// errNoIdentityUsers is returned by the resynchronizations for which the Identity microservice lists no user,
+// which would otherwise delete every user of the catalog
+var errNoIdentityUsers = errors.New("the Identity microservice listed no users")
+
+// userLister is implemented by the clients listing the users of the Identity microservice
+type userLister interface {
+	ListUsers(ctx context.Context, fn func(users []identity.User, total int) error) error
+}
+
+// userResyncStore is implemented by the stores of the users which are resynchronized
+type userResyncStore interface {
+	IDs(ctx context.Context) ([]int64, error)
+	Apply(ctx context.Context, update data.UserUpdate) (bool, error)
+	DeleteMany(ctx context.Context, ids []int64) (int64, error)
+}
+
+// resyncUsersHandler is the handler for the "POST /admin/users/resync" endpoint.
+// It enqueues a job reconciling the users of the catalog with the ones of the Identity microservice,
+// i.e. after user events were missed.
+func (app *Application) resyncUsersHandler(w http.ResponseWriter, r *http.Request) {
+	// Create trace for the handler
+	_, span := app.Tracer.Start(r.Context(), "Resynchronizing users")
+	defer span.End()
+
+	app.enqueueJob(w, r, span, userResyncJob, nil)
+}
+
+// newUserResyncJob returns the handler of the user resynchronization jobs. Every user listed by the Identity
+// microservice is applied as a whole, unless the stored user is already at the same or a newer version, and the
+// stored users which are not listed anymore are deleted. Only the users stored before the listing are deleted
+// so that the users created by an event in the meantime are kept, and no user is deleted when the listing is
+// incomplete. The changed users are invalidated.
+func newUserResyncJob(lister userLister, store userResyncStore, invalidator messaging.UserInvalidator) jobs.Handler {
+	return func(ctx context.Context, job jobs.Job, reporter *jobs.Reporter) (bson.M, error) {
+		stored, err := store.IDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		listed := make(map[int64]bool)
+		updated := 0
+
+		err = lister.ListUsers(ctx, func(users []identity.User, total int) error {
+			reporter.SetTotal(int64(total))
+
+			for _, user := range users {
+				listed[user.ID] = true
+
+				applied, err := store.Apply(ctx, data.UserUpdate{
+					ID:          user.ID,
+					Name:        user.Name,
+					Email:       user.Email,
+					Permissions: user.Permissions,
+					Activated:   user.Activated,
+					Version:     user.Version,
+					Complete:    true,
+				})
+				if err != nil {
+					return err
+				}
+
+				if applied {
+					invalidator.Invalidate(user.ID)
+					updated++
+				}
+
+				reporter.Succeed(1)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(listed) == 0 && len(stored) != 0 {
+			return nil, errNoIdentityUsers
+		}
+
+		stale := []int64{}
+
+		for _, id := range stored {
+			if !listed[id] {
+				stale = append(stale, id)
+			}
+		}
+
+		deleted, err := store.DeleteMany(ctx, stale)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range stale {
+			invalidator.Invalidate(id)
+		}
+
+		return bson.M{"listed": len(listed), "updated": updated, "deleted": deleted}, nil
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/identity"
+	"github.com/PlayEconomy37/Play.Catalog/internal/jobs"
+	"github.com/PlayEconomy37/Play.Common/permissions"
+)
+
+// fakeUserLister lists the given users in pages of two users
+type fakeUserLister struct {
+	users []identity.User
+}
+
+// ListUsers calls fn for each page of the users
+func (lister *fakeUserLister) ListUsers(ctx context.Context, fn func(users []identity.User, total int) error) error {
+	for start := 0; start < len(lister.users); start += 2 {
+		end := start + 2
+		if end > len(lister.users) {
+			end = len(lister.users)
+		}
+
+		err := fn(lister.users[start:end], len(lister.users))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fakeUserResyncStore keeps the users in memory along with their version in the Identity microservice
+type fakeUserResyncStore struct {
+	users map[int64]data.UserUpdate
+}
+
+// IDs returns the ids of the stored users
+func (store *fakeUserResyncStore) IDs(ctx context.Context) ([]int64, error) {
+	ids := []int64{}
+	for id := range store.users {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Apply stores the given update unless the stored user is at the same or a newer version
+func (store *fakeUserResyncStore) Apply(ctx context.Context, update data.UserUpdate) (bool, error) {
+	if stored, ok := store.users[update.ID]; ok && stored.Version >= update.Version {
+		return false, nil
+	}
+
+	store.users[update.ID] = update
+
+	return true, nil
+}
+
+// DeleteMany deletes the users with the given ids
+func (store *fakeUserResyncStore) DeleteMany(ctx context.Context, ids []int64) (int64, error) {
+	deleted := int64(0)
+
+	for _, id := range ids {
+		if _, ok := store.users[id]; ok {
+			delete(store.users, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// fakeUserInvalidator records the invalidated users
+type fakeUserInvalidator struct {
+	invalidated []int64
+}
+
+// Invalidate records the invalidated user
+func (invalidator *fakeUserInvalidator) Invalidate(id int64) {
+	invalidator.invalidated = append(invalidator.invalidated, id)
+}
+
+func TestUserResyncJob(t *testing.T) {
+	tests := []struct {
+		testName        string
+		listed          []identity.User
+		wantedResult    string
+		wantedUsers     string
+		wantedCompleted bool
+		wantedError     error
+		wantedInvalid   string
+	}{
+		{
+			"Missed updates",
+			[]identity.User{
+				{ID: 1, Permissions: permissions.Permissions{"catalog:read", "catalog:write"}, Activated: true, Version: 3},
+				{ID: 2, Permissions: permissions.Permissions{"catalog:read"}, Activated: false, Version: 5},
+				{ID: 4, Permissions: permissions.Permissions{"catalog:read"}, Activated: true, Version: 1},
+			},
+			"map[deleted:1 listed:3 updated:2]",
+			"[1 2 4]",
+			true,
+			nil,
+			"[2 3 4]",
+		},
+		{"No users listed", nil, "map[]", "[1 2 3]", false, errNoIdentityUsers, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// User 1 is up to date, user 2 missed an update and user 3 was deleted in the Identity microservice
+			store := &fakeUserResyncStore{users: map[int64]data.UserUpdate{
+				1: {ID: 1, Activated: true, Version: 3},
+				2: {ID: 2, Activated: true, Version: 4},
+				3: {ID: 3, Activated: true, Version: 1},
+			}}
+			invalidator := &fakeUserInvalidator{}
+			reporter := &jobs.Reporter{}
+
+			result, err := newUserResyncJob(&fakeUserLister{users: tt.listed}, store, invalidator)(context.Background(), jobs.Job{}, reporter)
+			if !errors.Is(err, tt.wantedError) {
+				t.Fatalf("want error %v; got %v", tt.wantedError, err)
+			}
+
+			if fmt.Sprint(result) != tt.wantedResult {
+				t.Errorf("want result %s; got %v", tt.wantedResult, result)
+			}
+
+			sort.Slice(invalidator.invalidated, func(i, j int) bool { return invalidator.invalidated[i] < invalidator.invalidated[j] })
+
+			if fmt.Sprint(invalidator.invalidated) != tt.wantedInvalid {
+				t.Errorf("want users %s invalidated; got %v", tt.wantedInvalid, invalidator.invalidated)
+			}
+
+			ids, _ := store.IDs(context.Background())
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+			if fmt.Sprint(ids) != tt.wantedUsers {
+				t.Errorf("want users %s; got %v", tt.wantedUsers, ids)
+			}
+
+			if tt.wantedCompleted && (!store.users[2].Complete || store.users[2].Activated) {
+				t.Errorf("want user 2 to be deactivated; got %+v", store.users[2])
+			}
+
+			if progress := reporter.Progress(); progress.Succeeded != int64(len(tt.listed)) {
+				t.Errorf("want %d users processed; got %d", len(tt.listed), progress.Succeeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package changestream
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics is a struct that holds some prometheus metrics regarding the change streams of the items,
+// labelled by stream
+type Metrics struct {
+	EventsCounter *prometheus.CounterVec
+	LagGauge      *prometheus.GaugeVec
+}
+
+// NewMetrics creates the counters and gauges used to keep track of the change streams
+func NewMetrics(appName string) *Metrics {
+	eventsCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_change_stream_events_total", appName),
+		Help: "The total number of changes of the items processed from the change stream",
+	}, []string{"stream", "operation"})
+
+	lagGauge := promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: fmt.Sprintf("%s_change_stream_lag_seconds", appName),
+		Help: "Time elapsed between the last processed change of the items and its processing",
+	}, []string{"stream"})
+
+	return &Metrics{
+		EventsCounter: eventsCounter,
+		LagGauge:      lagGauge,
+	}
+}
@@ -0,0 +1,117 @@
+// Package changestream watches the changes made to the items collection and converts them into events,
+// so that the events are published whichever the origin of the writes (i.e. the API, a migration or a script).
+package changestream
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrLeaseLost is returned when the lease of a change stream was taken over by another instance
+var ErrLeaseLost = errors.New("change stream lease was lost")
+
+// state is a struct that defines the resume token of a change stream along with its lease
+type state struct {
+	Name        string    `bson:"_id"`
+	ResumeToken bson.Raw  `bson:"resume_token,omitempty"`
+	Owner       string    `bson:"owner"`
+	LeaseUntil  time.Time `bson:"lease_until"`
+	UpdatedAt   time.Time `bson:"updated_at"`
+}
+
+// Store is a struct that manages the states of the change streams. The resume token of a stream is the
+// position of the last change processed, from which the stream resumes after a restart. The lease of
+// a stream makes sure that a single instance of the service watches it at a time.
+type Store struct {
+	collection *mongo.Collection
+}
+
+// NewStore creates a new Store
+func NewStore(client *mongo.Client, databaseName string) *Store {
+	return &Store{collection: client.Database(databaseName).Collection(constants.ChangeStreamsCollection)}
+}
+
+// Acquire acquires or renews the lease of the given stream for the given owner until the given time.
+// It returns false when the lease is held by another owner and did not expire yet.
+func (store *Store) Acquire(ctx context.Context, name string, owner string, now time.Time, leaseUntil time.Time) (bool, error) {
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{bson.M{"owner": owner}, bson.M{"lease_until": bson.M{"$lte": now}}},
+	}
+
+	// The upsert fails with a duplicate key error when the lease of an existing state is held by another owner
+	_, err := store.collection.UpdateOne(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"owner": owner, "lease_until": leaseUntil}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Token returns the resume token of the given stream, which is nil when the stream was never watched
+func (store *Store) Token(ctx context.Context, name string) (bson.Raw, error) {
+	var s state
+
+	err := store.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&s)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return s.ResumeToken, nil
+}
+
+// Save records the resume token of the given stream. It returns ErrLeaseLost when the lease of the stream
+// is no longer held by the given owner.
+func (store *Store) Save(ctx context.Context, name string, owner string, token bson.Raw) error {
+	result, err := store.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": name, "owner": owner},
+		bson.M{"$set": bson.M{"resume_token": token, "updated_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Reset removes the resume token of the given stream so that it is watched from the current changes
+func (store *Store) Reset(ctx context.Context, name string, owner string) error {
+	result, err := store.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": name, "owner": owner},
+		bson.M{"$unset": bson.M{"resume_token": ""}, "$set": bson.M{"updated_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
@@ -0,0 +1,370 @@
+package changestream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Names of the change streams of the items collection, which identify their lease and their resume token
+const (
+	ItemsStream    = "items"         // Publishes the events of the changes
+	ListingsStream = "item_listings" // Updates the read model of the listings
+	SearchStream   = "item_search"   // Updates the Elasticsearch index
+)
+
+// ignoredFields are the fields of the items whose changes, including the changes of their
+// subfields, are not published since they are bookkeeping of the service (i.e. the expiration
+// whose event was published or the popularity counts and their applied batches)
+var ignoredFields = map[string]bool{
+	"expiration_notified": true,
+	"popularity":          true,
+}
+
+// itemChangePublisher is implemented by the publishers of the events of the changes made to the items
+type itemChangePublisher interface {
+	PublishCreated(ctx context.Context, item data.Item, at time.Time) error
+	PublishUpdated(ctx context.Context, item data.Item, at time.Time) error
+	PublishDeleted(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+// resyncer is implemented by the publishers which rebuild their state from the items collection, such as a read
+// model, since the changes made before the stream starts without resume token are never processed
+type resyncer interface {
+	Resync(ctx context.Context) error
+}
+
+// staleChecker is implemented by the resyncers whose state can be lost while the stream keeps its resume token,
+// such as a search index which was deleted
+type staleChecker interface {
+	Stale(ctx context.Context) (bool, error)
+}
+
+// stateStore is implemented by the stores of the states of the change streams
+type stateStore interface {
+	Acquire(ctx context.Context, name string, owner string, now time.Time, leaseUntil time.Time) (bool, error)
+	Token(ctx context.Context, name string) (bson.Raw, error)
+	Save(ctx context.Context, name string, owner string, token bson.Raw) error
+	Reset(ctx context.Context, name string, owner string) error
+}
+
+// changeEvent is a struct that defines the change events of the items collection
+type changeEvent struct {
+	ID                bson.Raw            `bson:"_id"` // Resume token of the event
+	OperationType     string              `bson:"operationType"`
+	ClusterTime       primitive.Timestamp `bson:"clusterTime"`
+	DocumentKey       documentKey         `bson:"documentKey"`
+	FullDocument      *data.Item          `bson:"fullDocument"`
+	UpdateDescription *updateDescription  `bson:"updateDescription"`
+}
+
+// documentKey is a struct that defines the key of the document of a change event
+type documentKey struct {
+	ID primitive.ObjectID `bson:"_id"`
+}
+
+// updateDescription is a struct that defines the fields changed by an update
+type updateDescription struct {
+	UpdatedFields bson.M   `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// Watcher is a struct that watches the changes made to the items collection and publishes their events.
+// The resume token of the last published change is saved along with its event, within the same transaction
+// when enabled, so that the watch resumes where it stopped after a failure or a restart. While another
+// instance holds the lease of the stream, the watcher waits for the lease to expire.
+type Watcher struct {
+	stream        string
+	collection    *mongo.Collection
+	store         stateStore
+	publisher     itemChangePublisher
+	transactions  *data.Transactions
+	owner         string
+	leaseDuration time.Duration
+	retryInterval time.Duration
+	logger        *logger.Logger
+	metrics       *Metrics
+	now           func() time.Time
+}
+
+// NewWatcher returns a new Watcher publishing the changes of the given items collection with the given publisher,
+// under the given stream name
+func NewWatcher(
+	stream string,
+	collection *mongo.Collection,
+	store stateStore,
+	publisher itemChangePublisher,
+	transactions *data.Transactions,
+	cfg settings.ChangeStream,
+	logger *logger.Logger,
+	metrics *Metrics,
+) *Watcher {
+	hostname, _ := os.Hostname()
+
+	return &Watcher{
+		stream:        stream,
+		collection:    collection,
+		store:         store,
+		publisher:     publisher,
+		transactions:  transactions,
+		owner:         fmt.Sprintf("%s-%s", hostname, primitive.NewObjectID().Hex()),
+		leaseDuration: time.Duration(cfg.LeaseDuration) * time.Second,
+		retryInterval: time.Duration(cfg.RetryInterval) * time.Second,
+		logger:        logger,
+		metrics:       metrics,
+		now:           time.Now,
+	}
+}
+
+// Run watches the changes of the items collection and resumes the watch at the retry interval after a failure
+func (watcher *Watcher) Run() {
+	for {
+		err := watcher.watch(context.Background())
+		if err != nil {
+			watcher.logger.Error(err, map[string]string{"operation": "watch_changes", "stream": watcher.stream})
+		}
+
+		time.Sleep(watcher.retryInterval)
+	}
+}
+
+// watch acquires the lease of the stream and publishes the changes until the lease is lost or the stream fails.
+// It returns nil without watching when the lease is held by another instance.
+func (watcher *Watcher) watch(ctx context.Context) error {
+	renewAt, err := watcher.acquire(ctx)
+	if err != nil || renewAt.IsZero() {
+		return err
+	}
+
+	token, err := watcher.store.Token(ctx, watcher.stream)
+	if err != nil {
+		return err
+	}
+
+	// The items are looked up so that the events of the updates hold the whole item.
+	// The change stream is polled every second so that the lease is renewed in time.
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup).SetMaxAwaitTime(time.Second)
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+
+	stream, err := watcher.collection.Watch(ctx, mongo.Pipeline{}, opts)
+	if err != nil {
+		return watcher.fail(ctx, err)
+	}
+
+	defer stream.Close(ctx)
+
+	// The stream is opened beforehand so that the changes made during the resynchronization follow
+	err = watcher.resync(ctx, token != nil)
+	if err != nil {
+		return err
+	}
+
+	watcher.logger.Info("Watching changes", map[string]string{"stream": watcher.stream, "resumed": fmt.Sprint(token != nil)})
+
+	for {
+		if !watcher.now().Before(renewAt) {
+			renewAt, err = watcher.acquire(ctx)
+			if err != nil {
+				return err
+			}
+
+			if renewAt.IsZero() {
+				return ErrLeaseLost
+			}
+		}
+
+		if !stream.TryNext(ctx) {
+			if stream.Err() != nil {
+				return watcher.fail(ctx, stream.Err())
+			}
+
+			continue
+		}
+
+		var event changeEvent
+
+		err = stream.Decode(&event)
+		if err != nil {
+			return err
+		}
+
+		// The collection was dropped or renamed so the stream can't be resumed after this event
+		if event.OperationType == "invalidate" {
+			watcher.logger.Warning("Change stream invalidated", map[string]string{"stream": watcher.stream})
+			return watcher.store.Reset(ctx, watcher.stream, watcher.owner)
+		}
+
+		resumeToken := stream.ResumeToken()
+
+		err = watcher.transactions.Run(ctx, func(ctx context.Context) error {
+			err := watcher.handle(ctx, event)
+			if err != nil {
+				return err
+			}
+
+			return watcher.store.Save(ctx, watcher.stream, watcher.owner, resumeToken)
+		})
+		if err != nil {
+			return err
+		}
+
+		watcher.metrics.EventsCounter.WithLabelValues(watcher.stream, event.OperationType).Inc()
+		watcher.metrics.LagGauge.WithLabelValues(watcher.stream).Set(watcher.now().Sub(clusterTime(event)).Seconds())
+	}
+}
+
+// acquire acquires or renews the lease of the stream and returns the time at which it must be renewed,
+// which is zero when the lease is held by another instance
+func (watcher *Watcher) acquire(ctx context.Context) (time.Time, error) {
+	now := watcher.now()
+
+	acquired, err := watcher.store.Acquire(ctx, watcher.stream, watcher.owner, now.UTC(), now.Add(watcher.leaseDuration).UTC())
+	if err != nil || !acquired {
+		return time.Time{}, err
+	}
+
+	return now.Add(watcher.leaseDuration / 2), nil
+}
+
+// resync rebuilds the state of a publisher which is a resyncer from the items. The stream starts without resume
+// token the first time and once it was reset, so the publisher catches up with the items, as well as when the
+// publisher lost its state.
+func (watcher *Watcher) resync(ctx context.Context, resumed bool) error {
+	resyncer, ok := watcher.publisher.(resyncer)
+	if !ok {
+		return nil
+	}
+
+	if checker, ok := resyncer.(staleChecker); ok && resumed {
+		stale, err := checker.Stale(ctx)
+		if err != nil {
+			return err
+		}
+
+		resumed = !stale
+	}
+
+	if resumed {
+		return nil
+	}
+
+	err := resyncer.Resync(ctx)
+	if err != nil {
+		return err
+	}
+
+	watcher.logger.Info("Changes resynchronized", map[string]string{"stream": watcher.stream})
+
+	return nil
+}
+
+// fail handles an error of the stream. The resume token is reset when the change it points to is no longer
+// in the oplog, so that the next watch starts from the current changes. The changes in between are lost,
+// unless the publisher resynchronizes its state from the items.
+func (watcher *Watcher) fail(ctx context.Context, err error) error {
+	if !historyLost(err) {
+		return err
+	}
+
+	watcher.logger.Error(err, map[string]string{"operation": "resume_changes", "stream": watcher.stream})
+
+	return watcher.store.Reset(ctx, watcher.stream, watcher.owner)
+}
+
+// handle publishes the event of the given change. The changes which do not alter the published
+// fields of an item are skipped, as well as the updates of the items which were deleted since,
+// whose deletion follows.
+func (watcher *Watcher) handle(ctx context.Context, event changeEvent) error {
+	at := clusterTime(event)
+
+	// The events are identified by their change since the writes made without the repository keep the version
+	ctx = messaging.ContextWithChangeID(ctx, changeID(event))
+
+	switch event.OperationType {
+	case "insert":
+		if event.FullDocument == nil {
+			return nil
+		}
+
+		return watcher.publisher.PublishCreated(ctx, *event.FullDocument, at)
+	case "update", "replace":
+		if event.FullDocument == nil || ignored(event.UpdateDescription) {
+			return nil
+		}
+
+		return watcher.publisher.PublishUpdated(ctx, *event.FullDocument, at)
+	case "delete":
+		return watcher.publisher.PublishDeleted(ctx, event.DocumentKey.ID, at)
+	default:
+		return nil
+	}
+}
+
+// changeID returns the id of the change of the given event, the data of its resume token, which is the same
+// whenever the change is processed again
+func changeID(event changeEvent) string {
+	id, _ := event.ID.Lookup("_data").StringValueOK()
+
+	return id
+}
+
+// ignored checks if the given update only changed ignored fields
+func ignored(description *updateDescription) bool {
+	if description == nil {
+		return false
+	}
+
+	for field := range description.UpdatedFields {
+		if !ignoredField(field) {
+			return false
+		}
+	}
+
+	for _, field := range description.RemovedFields {
+		if !ignoredField(field) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ignoredField checks if the given field, in dot notation, is one of the ignored fields or one of their subfields
+func ignoredField(field string) bool {
+	if i := strings.IndexByte(field, '.'); i >= 0 {
+		field = field[:i]
+	}
+
+	return ignoredFields[field]
+}
+
+// clusterTime returns the time at which the given change was made
+func clusterTime(event changeEvent) time.Time {
+	return time.Unix(int64(event.ClusterTime.T), 0).UTC()
+}
+
+// historyLost checks if the given error shows that the stream can't be resumed
+// because its resume token is no longer in the oplog
+func historyLost(err error) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+
+	return serverErr.HasErrorCode(286) || // ChangeStreamHistoryLost
+		serverErr.HasErrorCode(280) // ChangeStreamFatalError
+}
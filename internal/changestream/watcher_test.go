@@ -0,0 +1,332 @@
+package changestream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// testMetrics are shared by the watchers of the tests since prometheus metrics can only be registered once
+var testMetrics = NewMetrics("changestream_test")
+
+// fakePublisher records the published events
+type fakePublisher struct {
+	published []string
+}
+
+// PublishCreated records the item created event of the given item
+func (publisher *fakePublisher) PublishCreated(ctx context.Context, item data.Item, at time.Time) error {
+	publisher.published = append(publisher.published, fmt.Sprintf("created %s %d", item.Name, item.Version))
+	return nil
+}
+
+// PublishUpdated records the item updated event of the given item
+func (publisher *fakePublisher) PublishUpdated(ctx context.Context, item data.Item, at time.Time) error {
+	publisher.published = append(publisher.published, fmt.Sprintf("updated %s %d", item.Name, item.Version))
+	return nil
+}
+
+// PublishDeleted records the item deleted event of the item with the given id
+func (publisher *fakePublisher) PublishDeleted(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	publisher.published = append(publisher.published, "deleted "+id.Hex())
+	return nil
+}
+
+// fakeResyncer records its resynchronizations, its state being lost when stale
+type fakeResyncer struct {
+	fakePublisher
+	stale   bool
+	resyncs int
+}
+
+// Resync records a resynchronization
+func (resyncer *fakeResyncer) Resync(ctx context.Context) error {
+	resyncer.resyncs++
+	return nil
+}
+
+// Stale reports whether the state was lost
+func (resyncer *fakeResyncer) Stale(ctx context.Context) (bool, error) {
+	return resyncer.stale, nil
+}
+
+// fakeStore keeps the state of a single change stream in memory
+type fakeStore struct {
+	owner      string
+	leaseUntil time.Time
+	token      bson.Raw
+}
+
+// Acquire acquires the lease if it is held by the given owner or expired
+func (store *fakeStore) Acquire(ctx context.Context, name string, owner string, now time.Time, leaseUntil time.Time) (bool, error) {
+	if store.owner != owner && store.leaseUntil.After(now) {
+		return false, nil
+	}
+
+	store.owner = owner
+	store.leaseUntil = leaseUntil
+
+	return true, nil
+}
+
+// Token returns the saved resume token
+func (store *fakeStore) Token(ctx context.Context, name string) (bson.Raw, error) {
+	return store.token, nil
+}
+
+// Save saves the given resume token
+func (store *fakeStore) Save(ctx context.Context, name string, owner string, token bson.Raw) error {
+	store.token = token
+	return nil
+}
+
+// Reset removes the resume token
+func (store *fakeStore) Reset(ctx context.Context, name string, owner string) error {
+	store.token = nil
+	return nil
+}
+
+// newTestWatcher returns a watcher publishing to the given publisher
+func newTestWatcher(store stateStore, publisher itemChangePublisher) *Watcher {
+	return NewWatcher(
+		ItemsStream,
+		nil,
+		store,
+		publisher,
+		data.NewTransactions(nil, false),
+		settings.ChangeStream{LeaseDuration: 30, RetryInterval: 5},
+		logger.New(io.Discard, logger.LevelInfo),
+		testMetrics,
+	)
+}
+
+func TestHandle(t *testing.T) {
+	id := primitive.NewObjectID()
+	item := bson.M{"_id": id, "name": "Potion", "price": 5, "version": 2}
+
+	tests := []struct {
+		name  string
+		event bson.M
+		want  []string
+	}{
+		{
+			name:  "Insert",
+			event: bson.M{"operationType": "insert", "documentKey": bson.M{"_id": id}, "fullDocument": item},
+			want:  []string{"created Potion 2"},
+		},
+		{
+			name: "Update",
+			event: bson.M{
+				"operationType":     "update",
+				"documentKey":       bson.M{"_id": id},
+				"fullDocument":      item,
+				"updateDescription": bson.M{"updatedFields": bson.M{"price": 5, "version": 2}, "removedFields": bson.A{}},
+			},
+			want: []string{"updated Potion 2"},
+		},
+		{
+			name: "Expiration notified",
+			event: bson.M{
+				"operationType":     "update",
+				"documentKey":       bson.M{"_id": id},
+				"fullDocument":      item,
+				"updateDescription": bson.M{"updatedFields": bson.M{"expiration_notified": time.Now()}, "removedFields": bson.A{}},
+			},
+			want: nil,
+		},
+		{
+			name: "Expiration released",
+			event: bson.M{
+				"operationType":     "update",
+				"documentKey":       bson.M{"_id": id},
+				"fullDocument":      item,
+				"updateDescription": bson.M{"updatedFields": bson.M{}, "removedFields": bson.A{"expiration_notified"}},
+			},
+			want: nil,
+		},
+		{
+			name: "Popularity counted",
+			event: bson.M{
+				"operationType":     "update",
+				"documentKey":       bson.M{"_id": id},
+				"fullDocument":      item,
+				"updateDescription": bson.M{"updatedFields": bson.M{"popularity.views": 3, "popularity.score": 3, "popularity.batches": bson.A{"a"}}, "removedFields": bson.A{}},
+			},
+			want: nil,
+		},
+		{
+			name: "Popularity counted along with a price change",
+			event: bson.M{
+				"operationType":     "update",
+				"documentKey":       bson.M{"_id": id},
+				"fullDocument":      item,
+				"updateDescription": bson.M{"updatedFields": bson.M{"popularity.views": 3, "price": 5}, "removedFields": bson.A{}},
+			},
+			want: []string{"updated Potion 2"},
+		},
+		{
+			name: "Update of a deleted item",
+			event: bson.M{
+				"operationType":     "update",
+				"documentKey":       bson.M{"_id": id},
+				"fullDocument":      nil,
+				"updateDescription": bson.M{"updatedFields": bson.M{"price": 5}, "removedFields": bson.A{}},
+			},
+			want: nil,
+		},
+		{
+			name:  "Replace",
+			event: bson.M{"operationType": "replace", "documentKey": bson.M{"_id": id}, "fullDocument": item},
+			want:  []string{"updated Potion 2"},
+		},
+		{
+			name:  "Delete",
+			event: bson.M{"operationType": "delete", "documentKey": bson.M{"_id": id}},
+			want:  []string{"deleted " + id.Hex()},
+		},
+		{
+			name:  "Drop",
+			event: bson.M{"operationType": "drop"},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := bson.Marshal(tt.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var event changeEvent
+
+			err = bson.Unmarshal(raw, &event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			publisher := &fakePublisher{}
+
+			err = newTestWatcher(&fakeStore{}, publisher).handle(context.Background(), event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(publisher.published) != fmt.Sprint(tt.want) {
+				t.Errorf("want %v; got %v", tt.want, publisher.published)
+			}
+		})
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	store := &fakeStore{owner: "other", leaseUntil: time.Now().Add(time.Minute)}
+	watcher := newTestWatcher(store, &fakePublisher{})
+
+	renewAt, err := watcher.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !renewAt.IsZero() {
+		t.Errorf("want lease held by another owner; got renewal at %v", renewAt)
+	}
+
+	// The watcher takes over the lease once it expired
+	store.leaseUntil = time.Now().Add(-time.Second)
+
+	renewAt, err = watcher.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renewAt.IsZero() || store.owner != watcher.owner {
+		t.Errorf("want lease acquired by %q; got owner %q", watcher.owner, store.owner)
+	}
+
+	// The lease is renewed halfway through its duration
+	if wait := time.Until(renewAt); wait < 14*time.Second || wait > 15*time.Second {
+		t.Errorf("want renewal in 15s; got %v", wait)
+	}
+}
+
+func TestHistoryLost(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "History lost", err: mongo.CommandError{Code: 286, Name: "ChangeStreamHistoryLost"}, want: true},
+		{name: "Fatal error", err: mongo.CommandError{Code: 280, Name: "ChangeStreamFatalError"}, want: true},
+		{name: "Other server error", err: mongo.CommandError{Code: 11600, Name: "InterruptedAtShutdown"}, want: false},
+		{name: "Other error", err: context.DeadlineExceeded, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := historyLost(tt.err); got != tt.want {
+				t.Errorf("want %t; got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResync(t *testing.T) {
+	tests := []struct {
+		name    string
+		resumed bool
+		stale   bool
+		want    int
+	}{
+		{name: "Without resume token", resumed: false, stale: false, want: 1},
+		{name: "Resumed", resumed: true, stale: false, want: 0},
+		{name: "Resumed with a lost state", resumed: true, stale: true, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resyncer := &fakeResyncer{stale: tt.stale}
+			watcher := newTestWatcher(&fakeStore{}, resyncer)
+
+			err := watcher.resync(context.Background(), tt.resumed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resyncer.resyncs != tt.want {
+				t.Errorf("want %d resynchronizations; got %d", tt.want, resyncer.resyncs)
+			}
+		})
+	}
+}
+
+func TestChangeID(t *testing.T) {
+	raw, err := bson.Marshal(bson.M{"_id": bson.M{"_data": "8263F1"}, "operationType": "update"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event changeEvent
+
+	err = bson.Unmarshal(raw, &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := changeID(event); got != "8263F1" {
+		t.Errorf("want %q; got %q", "8263F1", got)
+	}
+
+	if got := changeID(changeEvent{}); got != "" {
+		t.Errorf("want %q; got %q", "", got)
+	}
+}
@@ -9,4 +9,61 @@ const (
 
 	// UsersCollection is a constant tht defines the users collection name
 	UsersCollection = "users"
+
+	// APIKeysCollection is a constant tht defines the API keys collection name
+	APIKeysCollection = "api_keys"
+
+	// SavedFiltersCollection is a constant tht defines the saved filters collection name
+	SavedFiltersCollection = "saved_filters"
+
+	// ItemCollectionsCollection is a constant tht defines the collection holding the curated item collections
+	ItemCollectionsCollection = "item_collections"
+
+	// ReviewsCollection is a constant tht defines the collection holding the ratings and reviews of the items
+	ReviewsCollection = "reviews"
+
+	// FavoritesCollection is a constant tht defines the collection holding the favorite items of the users
+	FavoritesCollection = "favorites"
+
+	// ItemRevisionsCollection is a constant tht defines the collection holding the previous versions of the items
+	ItemRevisionsCollection = "item_revisions"
+
+	// ItemEventsCollection is a constant tht defines the collection holding the events of the event-sourced items
+	ItemEventsCollection = "item_events"
+
+	// ItemListingsCollection is a constant tht defines the collection holding the read model of the item listings
+	ItemListingsCollection = "item_listings"
+
+	// ItemSnapshotsCollection is a constant tht defines the collection holding the snapshots of the event-sourced items
+	ItemSnapshotsCollection = "item_snapshots"
+
+	// SelftestItemsCollection is a constant tht defines the sandbox collection used by the self-test
+	SelftestItemsCollection = "selftest_items"
+
+	// PopularityCollection is a constant tht defines the collection holding the daily popularity counts of the items
+	PopularityCollection = "item_popularity"
+
+	// OutboxCollection is a constant tht defines the collection holding the events waiting to be relayed to the message broker
+	OutboxCollection = "outbox"
+
+	// OutboxHistoryCollection is a constant tht defines the collection holding the published events which can be replayed
+	OutboxHistoryCollection = "outbox_history"
+
+	// OutboxDeadLettersCollection is a constant tht defines the collection holding the events which exhausted their publish attempts
+	OutboxDeadLettersCollection = "outbox_dead_letters"
+
+	// ChangeStreamsCollection is a constant tht defines the collection holding the resume tokens and leases of the change streams
+	ChangeStreamsCollection = "change_streams"
+
+	// JobsCollection is a constant tht defines the collection holding the background jobs and their progress
+	JobsCollection = "jobs"
+
+	// JobUploadsBucket is a constant tht defines the GridFS bucket holding the files uploaded for the background jobs
+	JobUploadsBucket = "job_uploads"
+
+	// SchedulerLocksCollection is a constant tht defines the collection holding the locks and last runs of the scheduled tasks
+	SchedulerLocksCollection = "scheduler_locks"
+
+	// MaintenanceCollection is a constant tht defines the collection holding the maintenance mode shared by the instances
+	MaintenanceCollection = "maintenance"
 )
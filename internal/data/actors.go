@@ -0,0 +1,59 @@
+package data
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// actorContextKey is the key used for getting and setting the actor in a context
+type actorContextKey struct{}
+
+// ContextWithActor returns a copy of the given context holding the id of the user making the changes
+func ContextWithActor(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, userID)
+}
+
+// ActorFromContext retrieves the id of the user making the changes from the given context.
+// It returns false if the context does not hold any actor.
+func ActorFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(actorContextKey{}).(int64)
+
+	return userID, ok && userID != 0
+}
+
+// ActorRepository is an items repository which records the user of the context on the items they create
+// and update. Changes whose context does not hold any actor (i.e. the background jobs) are not attributed
+// and keep the previous actor.
+type ActorRepository struct {
+	Repository[primitive.ObjectID, Item]
+}
+
+// NewActorRepository creates a new items repository attributing the changes of the given repository
+func NewActorRepository(repository Repository[primitive.ObjectID, Item]) Repository[primitive.ObjectID, Item] {
+	return &ActorRepository{
+		Repository: repository,
+	}
+}
+
+// Create inserts a new item created by the actor of the context
+func (repo ActorRepository) Create(ctx context.Context, item Item) (*primitive.ObjectID, error) {
+	if userID, ok := ActorFromContext(ctx); ok {
+		if item.CreatedBy == 0 {
+			item.CreatedBy = userID
+		}
+
+		item.UpdatedBy = userID
+	}
+
+	return repo.Repository.Create(ctx, item)
+}
+
+// Update updates an item on behalf of the actor of the context
+func (repo ActorRepository) Update(ctx context.Context, item Item) error {
+	if userID, ok := ActorFromContext(ctx); ok {
+		item.UpdatedBy = userID
+	}
+
+	return repo.Repository.Update(ctx, item)
+}
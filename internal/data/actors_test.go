@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// writeRepository records the items it writes
+type writeRepository struct {
+	Repository[primitive.ObjectID, Item]
+	item Item
+}
+
+// Create records the given item
+func (repo *writeRepository) Create(ctx context.Context, item Item) (*primitive.ObjectID, error) {
+	repo.item = item
+
+	return &primitive.NilObjectID, nil
+}
+
+// Update records the given item
+func (repo *writeRepository) Update(ctx context.Context, item Item) error {
+	repo.item = item
+
+	return nil
+}
+
+func TestActorRepository(t *testing.T) {
+	tests := []struct {
+		testName        string
+		ctx             context.Context
+		item            Item
+		create          bool
+		wantedCreatedBy int64
+		wantedUpdatedBy int64
+	}{
+		{"Created by the actor", ContextWithActor(context.Background(), 4), Item{}, true, 4, 4},
+		{"Creator kept", ContextWithActor(context.Background(), 4), Item{CreatedBy: 2}, true, 2, 4},
+		{"Updated by the actor", ContextWithActor(context.Background(), 4), Item{CreatedBy: 2, UpdatedBy: 2}, false, 2, 4},
+		{"Update without actor", context.Background(), Item{CreatedBy: 2, UpdatedBy: 2}, false, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			inner := &writeRepository{}
+			repo := NewActorRepository(inner)
+
+			var err error
+			if tt.create {
+				_, err = repo.Create(tt.ctx, tt.item)
+			} else {
+				err = repo.Update(tt.ctx, tt.item)
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if inner.item.CreatedBy != tt.wantedCreatedBy {
+				t.Errorf("want created_by %d; got %d", tt.wantedCreatedBy, inner.item.CreatedBy)
+			}
+
+			if inner.item.UpdatedBy != tt.wantedUpdatedBy {
+				t.Errorf("want updated_by %d; got %d", tt.wantedUpdatedBy, inner.item.UpdatedBy)
+			}
+		})
+	}
+}
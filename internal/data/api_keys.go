@@ -0,0 +1,217 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/permissions"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyPrefix is the prefix of the generated API keys, which makes them easy to spot (i.e. by secret scanners)
+const APIKeyPrefix = "pcat_"
+
+// APIKeyPermissions is the list of permissions which can be granted to an API key
+var APIKeyPermissions = []string{"catalog:read", "catalog:write", "catalog:admin"}
+
+// APIKey is a struct that defines a key used by the batch jobs and the internal services to call the catalog.
+// Only the SHA-256 hash of the key is stored; the key itself is returned once, when it is created.
+type APIKey struct {
+	ID          primitive.ObjectID      `json:"id" bson:"_id,omitempty"`
+	Name        string                  `json:"name" bson:"name"`
+	Prefix      string                  `json:"prefix" bson:"prefix"` // First characters of the key, used to recognize it
+	Hash        string                  `json:"-" bson:"hash"`
+	Permissions permissions.Permissions `json:"permissions" bson:"permissions"`
+	TenantID    string                  `json:"-" bson:"tenant_id"`
+	CreatedBy   int64                   `json:"created_by" bson:"created_by"`
+	ExpiresAt   *time.Time              `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	Version     int32                   `json:"version" bson:"version"`
+	CreatedAt   time.Time               `json:"created_at" bson:"created_at"`
+}
+
+// GetID returns the id of an API key.
+// This method is necessary for our generic constraint of our mongo repository.
+func (k APIKey) GetID() primitive.ObjectID {
+	return k.ID
+}
+
+// GetVersion returns the version of an API key.
+// This method is necessary for our generic constraint of our mongo repository.
+func (k APIKey) GetVersion() int32 {
+	return k.Version
+}
+
+// SetVersion sets the version of an API key to the given value and returns the API key.
+// This method is necessary for our generic constraint of our mongo repository.
+func (k APIKey) SetVersion(version int32) APIKey {
+	k.Version = version
+
+	return k
+}
+
+// Expired checks if the API key is expired at the given time
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// AuthUser converts the API key into the user expected by the common authorization middlewares.
+// API keys do not impersonate any user, so the user has the id 0.
+func (k APIKey) AuthUser() database.User {
+	return database.User{
+		Permissions: k.Permissions,
+		Activated:   true,
+		Version:     k.Version,
+	}
+}
+
+// GenerateAPIKey generates a new random API key and returns it along with its hash
+func GenerateAPIKey() (string, string, error) {
+	secret := make([]byte, 32)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hash under which the given API key is stored.
+// The keys are random enough for a plain SHA-256 hash to be safe and it lets the keys be looked up by hash.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// ValidateAPIKey runs validation checks on the `APIKey` struct
+func ValidateAPIKey(v *validation.Validator, apiKey APIKey) {
+	v.Check(apiKey.Name != "", "name", "required", "must be provided")
+	v.Check(validator.MaxCharacters(apiKey.Name, 100), "name", "too_long", "must not be more than 100 characters long")
+	v.Check(len(apiKey.Permissions) != 0, "permissions", "required", "must contain at least one permission")
+	v.Check(validator.NoDuplicates(apiKey.Permissions), "permissions", "duplicate", "must not contain duplicate values")
+
+	for _, permission := range apiKey.Permissions {
+		v.Check(validator.In(permission, APIKeyPermissions...), "permissions", "unsupported_value", "contains an unsupported permission "+permission)
+	}
+
+	if apiKey.ExpiresAt != nil {
+		v.Check(apiKey.ExpiresAt.After(time.Now()), "expires_at", "not_in_future", "must be in the future")
+	}
+}
+
+// apiKeyContextKey is the key used for getting and setting the authenticated API key in a context
+type apiKeyContextKey struct{}
+
+// ContextWithAPIKey returns a copy of the given context holding the given authenticated API key
+func ContextWithAPIKey(ctx context.Context, apiKey APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, apiKey)
+}
+
+// APIKeyFromContext retrieves the authenticated API key from the given context.
+// It returns false if the request was not authenticated with an API key.
+func APIKeyFromContext(ctx context.Context) (APIKey, bool) {
+	apiKey, ok := ctx.Value(apiKeyContextKey{}).(APIKey)
+
+	return apiKey, ok
+}
+
+// CreateAPIKeysCollection creates API keys collection in MongoDB database
+func CreateAPIKeysCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// JSON validation schema
+	jsonSchema := bson.M{
+		"bsonType":             "object",
+		"required":             []string{"name", "prefix", "hash", "permissions", "tenant_id", "created_by", "version", "created_at"},
+		"additionalProperties": false,
+		"properties": bson.M{
+			"_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Document ID",
+			},
+			"name": bson.M{
+				"bsonType":    "string",
+				"description": "Name of the API key",
+			},
+			"prefix": bson.M{
+				"bsonType":    "string",
+				"description": "First characters of the API key",
+			},
+			"hash": bson.M{
+				"bsonType":    "string",
+				"description": "SHA-256 hash of the API key",
+			},
+			"permissions": bson.M{
+				"bsonType":    "array",
+				"description": "Permissions granted to the API key",
+			},
+			"tenant_id": bson.M{
+				"bsonType":    "string",
+				"description": "Tenant which the API key belongs to",
+			},
+			"created_by": bson.M{
+				"bsonType":    "long",
+				"description": "ID of the user who created the API key",
+			},
+			"expires_at": bson.M{
+				"bsonType":    "date",
+				"description": "Expiration date",
+			},
+			"version": bson.M{
+				"bsonType":    "int",
+				"minimum":     1,
+				"description": "Document version",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "Creation date",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	// Create collection
+	opts := options.CreateCollection().SetValidator(validator)
+	err := db.CreateCollection(context.Background(), constants.APIKeysCollection, opts)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	indexModels := []mongo.IndexModel{
+		// Keys are looked up by hash
+		{
+			Keys:    bson.M{"hash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		// Expired keys are removed by MongoDB. They are also rejected until they are removed.
+		{
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = db.Collection(constants.APIKeysCollection).Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		t.Errorf("want key %q to start with %q", key, APIKeyPrefix)
+	}
+
+	if hash != HashAPIKey(key) {
+		t.Errorf("want hash %q; got %q", HashAPIKey(key), hash)
+	}
+
+	otherKey, _, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if otherKey == key {
+		t.Errorf("want a new key; got %q twice", key)
+	}
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	tests := []struct {
+		testName  string
+		expiresAt *time.Time
+		wanted    bool
+	}{
+		{"No expiration", nil, false},
+		{"Expired", &past, true},
+		{"Expires at this moment", &now, true},
+		{"Not expired", &future, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			apiKey := APIKey{ExpiresAt: tt.expiresAt}
+
+			if got := apiKey.Expired(now); got != tt.wanted {
+				t.Errorf("want %t; got %t", tt.wanted, got)
+			}
+		})
+	}
+}
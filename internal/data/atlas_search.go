@@ -0,0 +1,91 @@
+package data
+
+import (
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SearchHighlightText is a struct that holds a fragment of a highlighted field.
+// Fragments of type "hit" matched the search query while fragments of type "text" did not.
+type SearchHighlightText struct {
+	Value string `json:"value" bson:"value"`
+	Type  string `json:"type" bson:"type"`
+}
+
+// SearchHighlight is a struct that holds the highlighted fragments of a field matching a search query
+type SearchHighlight struct {
+	Path  string                `json:"path" bson:"path"`
+	Texts []SearchHighlightText `json:"texts" bson:"texts"`
+	Score float64               `json:"score" bson:"score"`
+}
+
+// SearchedItem is a struct that defines an item returned by Atlas Search along with its highlights
+type SearchedItem struct {
+	Item       `bson:",inline"`
+	Highlights []SearchHighlight `json:"highlights" bson:"highlights"`
+}
+
+// ItemSearchResults is a struct that holds a page of items returned by Atlas Search
+// along with the total number of matching items
+type ItemSearchResults struct {
+	Items []SearchedItem `bson:"items"`
+	Count []struct {
+		Total int `bson:"total"`
+	} `bson:"count"`
+}
+
+// Total returns the total number of items matching the search query
+func (results ItemSearchResults) Total() int {
+	if len(results.Count) == 0 {
+		return 0
+	}
+
+	return results.Count[0].Total
+}
+
+// AtlasSearchPipeline returns the aggregation pipeline searching the item names with Atlas Search.
+// The search tolerates up to maxEdits typos per word and highlights the matching fragments of the names.
+// The other filters are applied on the search results before paginating them.
+func AtlasSearchPipeline(index string, query string, maxEdits int, filter bson.M, findOpts filters.Filters) mongo.Pipeline {
+	text := bson.M{
+		"query": query,
+		"path":  "name",
+	}
+
+	if maxEdits > 0 {
+		text["fuzzy"] = bson.M{"maxEdits": maxEdits}
+	}
+
+	pageStages := bson.A{
+		bson.M{"$sort": compoundSort(findOpts, bson.E{Key: "search_score", Value: -1})},
+	}
+
+	if findOpts.Offset() > 0 {
+		pageStages = append(pageStages, bson.M{"$skip": findOpts.Offset()})
+	}
+
+	// A page size of 0 means that every result is returned
+	if findOpts.Limit() > 0 {
+		pageStages = append(pageStages, bson.M{"$limit": findOpts.Limit()})
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$search", Value: bson.M{
+			"index":     index,
+			"text":      text,
+			"highlight": bson.M{"path": "name"},
+		}}},
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.M{
+			"highlights":   bson.M{"$meta": "searchHighlights"},
+			"search_score": bson.M{"$meta": "searchScore"},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"items": pageStages,
+			"count": bson.A{
+				bson.M{"$count": "total"},
+			},
+		}}},
+	}
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAtlasSearchPipeline(t *testing.T) {
+	tests := []struct {
+		testName      string
+		maxEdits      int
+		findOpts      filters.Filters
+		expectedText  bson.M
+		expectedStage bson.A
+	}{
+		{
+			"Fuzzy search sorted by relevance",
+			1,
+			filters.Filters{Page: 2, PageSize: 10, Sort: RelevanceSort, SortSafelist: sortSafelist},
+			bson.M{"query": "potoin", "path": "name", "fuzzy": bson.M{"maxEdits": 1}},
+			bson.A{
+				bson.M{"$sort": bson.D{{Key: "search_score", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$skip": 10},
+				bson.M{"$limit": 10},
+			},
+		},
+		{
+			"Exact search sorted by price",
+			0,
+			filters.Filters{Page: 1, PageSize: 20, Sort: "-price", SortSafelist: sortSafelist},
+			bson.M{"query": "potoin", "path": "name"},
+			bson.A{
+				bson.M{"$sort": bson.D{{Key: "price", Value: int8(-1)}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": 20},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			pipeline := AtlasSearchPipeline("default", "potoin", tt.maxEdits, bson.M{}, tt.findOpts)
+
+			// Atlas Search requires $search to be the first stage of the pipeline
+			search := pipeline[0].Map()["$search"].(bson.M)
+
+			if !reflect.DeepEqual(search["text"], tt.expectedText) {
+				t.Errorf("want %v; got %v", tt.expectedText, search["text"])
+			}
+
+			facet := pipeline[len(pipeline)-1].Map()["$facet"].(bson.M)
+
+			if !reflect.DeepEqual(facet["items"], tt.expectedStage) {
+				t.Errorf("want %v; got %v", tt.expectedStage, facet["items"])
+			}
+		})
+	}
+}
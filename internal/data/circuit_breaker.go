@@ -0,0 +1,220 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// ErrCircuitOpen is returned when an operation is rejected because MongoDB kept failing or being slow
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+// CircuitBreakerMetrics is a struct that holds some prometheus metrics regarding the database circuit breaker
+type CircuitBreakerMetrics struct {
+	OpenGauge       prometheus.Gauge
+	RejectedCounter *prometheus.CounterVec
+	OpenedCounter   prometheus.Counter
+}
+
+// NewCircuitBreakerMetrics creates the metrics used to keep track of the database circuit breaker
+func NewCircuitBreakerMetrics(appName string) *CircuitBreakerMetrics {
+	openGauge := promauto.NewGauge(prometheus.GaugeOpts{
+		Name: fmt.Sprintf("%s_database_circuit_open", appName),
+		Help: "Whether the database circuit breaker is open (1) or not (0)",
+	})
+
+	rejectedCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_database_rejected_operations_total", appName),
+		Help: "The total number of MongoDB operations rejected by the open circuit breaker",
+	}, []string{"collection"})
+
+	openedCounter := promauto.NewCounter(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_database_circuit_opened_total", appName),
+		Help: "The total number of times the database circuit breaker opened",
+	})
+
+	return &CircuitBreakerMetrics{
+		OpenGauge:       openGauge,
+		RejectedCounter: rejectedCounter,
+		OpenedCounter:   openedCounter,
+	}
+}
+
+// outcome is the outcome of an operation recorded by the circuit breaker
+type outcome struct {
+	failed bool
+	slow   bool
+}
+
+// CircuitBreaker is a circuit breaker which stops sending operations to MongoDB while it is unhealthy.
+// It records the outcome of the last operations and opens once enough of them were recorded and the rate
+// of the failed or of the slow ones reaches its threshold. Once it has been open for the open duration,
+// a single trial operation is allowed: the circuit closes if it succeeds in time and opens again otherwise.
+type CircuitBreaker struct {
+	cfg     settings.CircuitBreaker
+	metrics *CircuitBreakerMetrics
+	now     func() time.Time
+
+	mu        sync.Mutex
+	outcomes  []outcome // Ring buffer of the last outcomes
+	next      int
+	failures  int
+	slowCalls int
+	open      bool
+	openedAt  time.Time
+	trial     bool // A trial operation is in flight
+}
+
+// NewCircuitBreaker returns a new closed circuit breaker
+func NewCircuitBreaker(cfg settings.CircuitBreaker, metrics *CircuitBreakerMetrics) *CircuitBreaker {
+	return &CircuitBreaker{
+		cfg:      cfg,
+		metrics:  metrics,
+		now:      time.Now,
+		outcomes: make([]outcome, 0, cfg.Window),
+	}
+}
+
+// Open checks if the circuit is open
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.open
+}
+
+// Run runs the given operation unless the circuit is open, in which case ErrCircuitOpen is returned.
+// The operation fails when it returns an error showing that MongoDB is unreachable or overloaded.
+func (b *CircuitBreaker) Run(fn func() error) error {
+	trial, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	start := b.now()
+	err = fn()
+
+	b.record(trial, outcome{
+		failed: unavailable(err),
+		slow:   b.now().Sub(start) >= time.Duration(b.cfg.SlowDuration)*time.Millisecond,
+	})
+
+	return err
+}
+
+// allow returns ErrCircuitOpen if an operation must not be run. It also reports whether
+// the operation is the trial one deciding if the circuit closes.
+func (b *CircuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return false, nil
+	}
+
+	if b.trial || b.now().Sub(b.openedAt) < time.Duration(b.cfg.OpenDuration)*time.Second {
+		return false, ErrCircuitOpen
+	}
+
+	b.trial = true
+
+	return true, nil
+}
+
+// record records the outcome of an operation and opens or closes the circuit accordingly
+func (b *CircuitBreaker) record(trial bool, o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The outcome of the trial operation decides alone whether the circuit closes. The operations
+	// which were already running when the circuit opened are ignored.
+	if trial {
+		b.trial = false
+
+		if o.failed || o.slow {
+			b.openedAt = b.now()
+		} else {
+			b.reset()
+		}
+
+		return
+	}
+
+	if b.open {
+		return
+	}
+
+	if len(b.outcomes) < b.cfg.Window {
+		b.outcomes = append(b.outcomes, outcome{})
+	}
+
+	b.forget(b.outcomes[b.next])
+	b.outcomes[b.next] = o
+	b.next = (b.next + 1) % b.cfg.Window
+
+	if o.failed {
+		b.failures++
+	}
+
+	if o.slow {
+		b.slowCalls++
+	}
+
+	if len(b.outcomes) < b.cfg.MinOperations {
+		return
+	}
+
+	total := float64(len(b.outcomes))
+
+	if float64(b.failures)/total >= b.cfg.FailureRate || float64(b.slowCalls)/total >= b.cfg.SlowRate {
+		b.open = true
+		b.openedAt = b.now()
+		b.metrics.OpenGauge.Set(1)
+		b.metrics.OpenedCounter.Inc()
+	}
+}
+
+// forget removes the given outcome, which is replaced in the window, from the counts
+func (b *CircuitBreaker) forget(o outcome) {
+	if o.failed {
+		b.failures--
+	}
+
+	if o.slow {
+		b.slowCalls--
+	}
+}
+
+// reset closes the circuit and forgets the recorded outcomes
+func (b *CircuitBreaker) reset() {
+	b.open = false
+	b.outcomes = b.outcomes[:0]
+	b.next = 0
+	b.failures = 0
+	b.slowCalls = 0
+	b.metrics.OpenGauge.Set(0)
+}
+
+// unavailable checks if the given error shows that MongoDB is unreachable or overloaded.
+// The other errors (i.e. record not found or duplicate key) are successful operations for the circuit breaker.
+func unavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var serverSelectionErr topology.ServerSelectionError
+	var waitQueueErr topology.WaitQueueTimeoutError
+
+	return mongo.IsNetworkError(err) ||
+		mongo.IsTimeout(err) ||
+		errors.As(err, &serverSelectionErr) ||
+		errors.As(err, &waitQueueErr)
+}
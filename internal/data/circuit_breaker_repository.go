@@ -0,0 +1,139 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CircuitBreakerRepository is a MongoDB repository which runs the operations of the given repository through
+// a circuit breaker, so that they fail fast with ErrCircuitOpen instead of piling up while MongoDB is unhealthy.
+// Exports are not guarded since their duration depends on the size of the collection.
+type CircuitBreakerRepository[K any, T types.MongoEntity[K, T]] struct {
+	Repository[K, T]
+	collection string
+	breaker    *CircuitBreaker
+}
+
+// NewCircuitBreakerRepository creates a new repository guarding the operations of the given repository
+// with the given circuit breaker, which can be shared by the repositories of the same database
+func NewCircuitBreakerRepository[K any, T types.MongoEntity[K, T]](
+	repository Repository[K, T],
+	collection string,
+	breaker *CircuitBreaker,
+) Repository[K, T] {
+	return &CircuitBreakerRepository[K, T]{
+		Repository: repository,
+		collection: collection,
+		breaker:    breaker,
+	}
+}
+
+// GetByID retrieves a specific document by its id
+func (repo CircuitBreakerRepository[K, T]) GetByID(ctx context.Context, id K) (T, error) {
+	var entity T
+
+	err := repo.run(func() (err error) {
+		entity, err = repo.Repository.GetByID(ctx, id)
+		return err
+	})
+
+	return entity, err
+}
+
+// GetByFilter retrieves a specific document by the given filter
+func (repo CircuitBreakerRepository[K, T]) GetByFilter(ctx context.Context, filter primitive.M) (T, error) {
+	var entity T
+
+	err := repo.run(func() (err error) {
+		entity, err = repo.Repository.GetByFilter(ctx, filter)
+		return err
+	})
+
+	return entity, err
+}
+
+// GetAll retrieves all documents matching the given filter
+func (repo CircuitBreakerRepository[K, T]) GetAll(ctx context.Context, filter primitive.M, findOpts filters.Filters) ([]T, filters.Metadata, error) {
+	var entities []T
+	var metadata filters.Metadata
+
+	err := repo.run(func() (err error) {
+		entities, metadata, err = repo.Repository.GetAll(ctx, filter, findOpts)
+		return err
+	})
+
+	return entities, metadata, err
+}
+
+// GetAllWithOptions retrieves all documents matching the given filter with the given listing options
+func (repo CircuitBreakerRepository[K, T]) GetAllWithOptions(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+	listOpts ListOptions,
+) ([]T, Metadata, error) {
+	var entities []T
+	var metadata Metadata
+
+	err := repo.run(func() (err error) {
+		entities, metadata, err = repo.Repository.GetAllWithOptions(ctx, filter, findOpts, listOpts)
+		return err
+	})
+
+	return entities, metadata, err
+}
+
+// Aggregate runs the given aggregation pipeline
+func (repo CircuitBreakerRepository[K, T]) Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error {
+	return repo.run(func() error {
+		return repo.Repository.Aggregate(ctx, pipeline, results)
+	})
+}
+
+// Create inserts a new document
+func (repo CircuitBreakerRepository[K, T]) Create(ctx context.Context, entity T) (*K, error) {
+	var id *K
+
+	err := repo.run(func() (err error) {
+		id, err = repo.Repository.Create(ctx, entity)
+		return err
+	})
+
+	return id, err
+}
+
+// Update updates a document
+func (repo CircuitBreakerRepository[K, T]) Update(ctx context.Context, entity T) error {
+	return repo.run(func() error {
+		return repo.Repository.Update(ctx, entity)
+	})
+}
+
+// Delete deletes the document with the given id
+func (repo CircuitBreakerRepository[K, T]) Delete(ctx context.Context, id K) error {
+	return repo.run(func() error {
+		return repo.Repository.Delete(ctx, id)
+	})
+}
+
+// Restore writes the given documents
+func (repo CircuitBreakerRepository[K, T]) Restore(ctx context.Context, documents []Document[T]) error {
+	return repo.run(func() error {
+		return repo.Repository.Restore(ctx, documents)
+	})
+}
+
+// run runs the given operation through the circuit breaker and counts the rejected operations
+func (repo CircuitBreakerRepository[K, T]) run(fn func() error) error {
+	err := repo.breaker.Run(fn)
+	if errors.Is(err, ErrCircuitOpen) {
+		repo.breaker.metrics.RejectedCounter.WithLabelValues(repo.collection).Inc()
+	}
+
+	return err
+}
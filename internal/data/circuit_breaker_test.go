@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/database"
+)
+
+// breakerMetrics are the metrics shared by the circuit breakers of the tests
+var breakerMetrics = NewCircuitBreakerMetrics("test")
+
+// newTestBreaker returns a circuit breaker whose clock is advanced by the operations of the tests
+func newTestBreaker(now *time.Time) *CircuitBreaker {
+	breaker := NewCircuitBreaker(settings.CircuitBreaker{
+		Enabled:       true,
+		Window:        4,
+		MinOperations: 2,
+		FailureRate:   0.5,
+		SlowDuration:  100,
+		SlowRate:      0.75,
+		OpenDuration:  10,
+	}, breakerMetrics)
+	breaker.now = func() time.Time { return *now }
+
+	return breaker
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	// operation is an operation taking the given duration and returning the given error
+	type operation struct {
+		duration time.Duration
+		err      error
+	}
+
+	fast := operation{duration: time.Millisecond}
+	slow := operation{duration: time.Second}
+	notFound := operation{duration: time.Millisecond, err: database.ErrRecordNotFound}
+	timeout := operation{duration: time.Millisecond, err: context.DeadlineExceeded}
+
+	tests := []struct {
+		testName   string
+		operations []operation
+		wantedOpen bool
+	}{
+		{"Successful operations", []operation{fast, fast, fast, fast}, false},
+		{"Not found errors are successes", []operation{notFound, notFound, notFound}, false},
+		{"Not enough operations", []operation{timeout}, false},
+		{"Failure rate reached", []operation{fast, fast, timeout, timeout}, true},
+		{"Failure rate not reached", []operation{fast, fast, timeout, fast, fast, fast, timeout}, false},
+		{"Slow rate reached", []operation{fast, slow, slow, slow}, true},
+		{"Slow rate not reached", []operation{slow, fast, slow, fast}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			now := time.Now()
+			breaker := newTestBreaker(&now)
+
+			for i, op := range tt.operations {
+				err := breaker.Run(func() error {
+					now = now.Add(op.duration)
+					return op.err
+				})
+
+				// The circuit can only open after the last operation
+				if errors.Is(err, ErrCircuitOpen) {
+					t.Fatalf("want operation %d to run; got %v", i, err)
+				}
+			}
+
+			if open := breaker.Open(); open != tt.wantedOpen {
+				t.Errorf("want open %t; got %t", tt.wantedOpen, open)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerTrial(t *testing.T) {
+	tests := []struct {
+		testName   string
+		trialErr   error
+		wantedOpen bool
+	}{
+		{"Successful trial", nil, false},
+		{"Failed trial", context.DeadlineExceeded, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			now := time.Now()
+			breaker := newTestBreaker(&now)
+
+			for i := 0; i < 2; i++ {
+				breaker.Run(func() error { return context.DeadlineExceeded })
+			}
+
+			// Operations fail fast while the circuit is open
+			err := breaker.Run(func() error {
+				t.Fatal("want operation to be rejected")
+				return nil
+			})
+			if !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("want %v; got %v", ErrCircuitOpen, err)
+			}
+
+			now = now.Add(10 * time.Second)
+
+			err = breaker.Run(func() error {
+				// Only a single trial operation runs at a time
+				if err := breaker.Run(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+					t.Errorf("want concurrent operation to be rejected; got %v", err)
+				}
+
+				return tt.trialErr
+			})
+			if !errors.Is(err, tt.trialErr) {
+				t.Fatalf("want %v; got %v", tt.trialErr, err)
+			}
+
+			if open := breaker.Open(); open != tt.wantedOpen {
+				t.Errorf("want open %t; got %t", tt.wantedOpen, open)
+			}
+		})
+	}
+}
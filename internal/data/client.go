@@ -0,0 +1,98 @@
+package data
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/configuration"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PoolStats is a struct that keeps track of the connections of the pools of a MongoDB client
+type PoolStats struct {
+	open       int64
+	checkedOut int64
+}
+
+// Monitor returns the pool monitor keeping the stats up to date
+func (stats *PoolStats) Monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(poolEvent *event.PoolEvent) {
+			switch poolEvent.Type {
+			case event.ConnectionCreated:
+				atomic.AddInt64(&stats.open, 1)
+			case event.ConnectionClosed:
+				atomic.AddInt64(&stats.open, -1)
+			case event.GetSucceeded:
+				atomic.AddInt64(&stats.checkedOut, 1)
+			case event.ConnectionReturned:
+				atomic.AddInt64(&stats.checkedOut, -1)
+			}
+		},
+	}
+}
+
+// OpenConnections returns the number of open connections to the MongoDB servers
+func (stats *PoolStats) OpenConnections() int64 {
+	return atomic.LoadInt64(&stats.open)
+}
+
+// InUseConnections returns the number of connections checked out of the pools by running operations
+func (stats *PoolStats) InUseConnections() int64 {
+	return atomic.LoadInt64(&stats.checkedOut)
+}
+
+// NewMongoClient creates a new MongoDB client with the given configuration and pool settings. The given command
+// monitor, if any, is notified of every command sent to MongoDB (i.e. to trace them) and the pool stats, if any,
+// keep track of the connections of the client.
+func NewMongoClient(cfg *configuration.Config, dbSettings settings.Database, monitor *event.CommandMonitor, poolStats *PoolStats) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), startupTimeout(dbSettings))
+	defer cancel()
+
+	maxPoolSize := dbSettings.MaxPoolSize
+	if maxPoolSize == 0 {
+		maxPoolSize = cfg.DB.MaxOpenConns
+	}
+
+	// MongoDB connection options
+	opts := options.Client().
+		ApplyURI(cfg.DB.Dsn).
+		SetMaxPoolSize(uint64(maxPoolSize)).
+		SetMinPoolSize(uint64(dbSettings.MinPoolSize)).
+		SetMaxConnIdleTime(time.Duration(cfg.DB.MaxIdleTimeMS) * time.Millisecond).
+		SetConnectTimeout(time.Duration(dbSettings.ConnectTimeout) * time.Millisecond).
+		SetServerSelectionTimeout(time.Duration(dbSettings.ServerSelectionTimeout) * time.Millisecond).
+		SetMonitor(monitor)
+
+	if poolStats != nil {
+		opts.SetPoolMonitor(poolStats.Monitor())
+	}
+
+	if dbSettings.SocketTimeout > 0 {
+		opts.SetSocketTimeout(time.Duration(dbSettings.SocketTimeout) * time.Millisecond)
+	}
+
+	// Connect to MongoDB
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ping MongoDB to make sure it is up and running
+	if err = client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// startupTimeout returns the time given to the connection to MongoDB and to its first ping, which selects
+// a server and establishes a connection to it within their configured timeouts
+func startupTimeout(dbSettings settings.Database) time.Duration {
+	return time.Duration(dbSettings.ServerSelectionTimeout+dbSettings.ConnectTimeout) * time.Millisecond
+}
@@ -0,0 +1,321 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// shadowTimeout is the maximum amount of time given to an asynchronous shadow operation
+const shadowTimeout = 5 * time.Second
+
+// DualWriteMetrics is a struct that holds some prometheus metrics
+// regarding the dual write mode used when migrating storage backends
+type DualWriteMetrics struct {
+	SecondaryErrorsCounter *prometheus.CounterVec
+	DivergencesCounter     *prometheus.CounterVec
+	ComparisonsCounter     *prometheus.CounterVec
+}
+
+// NewDualWriteMetrics creates counters used to keep track of dual write metrics in our application
+func NewDualWriteMetrics(appName string) *DualWriteMetrics {
+	secondaryErrorsCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_dual_write_secondary_errors_total", appName),
+		Help: "The total number of operations which failed on the secondary store",
+	}, []string{"collection", "operation"})
+
+	divergencesCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_dual_write_divergences_total", appName),
+		Help: "The total number of shadow reads whose result differed from the primary store",
+	}, []string{"collection", "operation"})
+
+	comparisonsCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_dual_write_comparisons_total", appName),
+		Help: "The total number of shadow reads compared against the primary store",
+	}, []string{"collection", "operation"})
+
+	return &DualWriteMetrics{
+		SecondaryErrorsCounter: secondaryErrorsCounter,
+		DivergencesCounter:     divergencesCounter,
+		ComparisonsCounter:     comparisonsCounter,
+	}
+}
+
+// DualWriteRepository is a MongoDB repository which writes to a primary and a secondary store
+// and compares the reads of both stores asynchronously. The primary store is always the source
+// of truth of the responses, failures on the secondary store are only logged and reported.
+// Only the writes made through the repository are mirrored.
+type DualWriteRepository[K any, T types.MongoEntity[K, T]] struct {
+	primary     Repository[K, T]
+	secondary   Repository[K, T]
+	collection  string
+	shadowReads bool
+	logger      *logger.Logger
+	metrics     *DualWriteMetrics
+}
+
+// NewDualWriteRepository creates a new dual write repository
+func NewDualWriteRepository[K any, T types.MongoEntity[K, T]](
+	primary Repository[K, T],
+	secondary Repository[K, T],
+	collection string,
+	shadowReads bool,
+	logger *logger.Logger,
+	metrics *DualWriteMetrics,
+) Repository[K, T] {
+	return &DualWriteRepository[K, T]{
+		primary:     primary,
+		secondary:   secondary,
+		collection:  collection,
+		shadowReads: shadowReads,
+		logger:      logger,
+		metrics:     metrics,
+	}
+}
+
+// GetByID retrieves a specific document from the primary store by its id
+func (repo DualWriteRepository[K, T]) GetByID(ctx context.Context, id K) (T, error) {
+	entity, err := repo.primary.GetByID(ctx, id)
+
+	repo.shadow("get_by_id", func(ctx context.Context) (any, any, error) {
+		shadowEntity, shadowErr := repo.secondary.GetByID(ctx, id)
+		return entityOrError(entity, err), entityOrError(shadowEntity, shadowErr), nil
+	})
+
+	return entity, err
+}
+
+// GetByFilter retrieves a specific document from the primary store by the given filter
+func (repo DualWriteRepository[K, T]) GetByFilter(ctx context.Context, filter primitive.M) (T, error) {
+	entity, err := repo.primary.GetByFilter(ctx, filter)
+
+	repo.shadow("get_by_filter", func(ctx context.Context) (any, any, error) {
+		shadowEntity, shadowErr := repo.secondary.GetByFilter(ctx, filter)
+		return entityOrError(entity, err), entityOrError(shadowEntity, shadowErr), nil
+	})
+
+	return entity, err
+}
+
+// GetAll retrieves all documents from the primary store
+func (repo DualWriteRepository[K, T]) GetAll(ctx context.Context, filter primitive.M, findOpts filters.Filters) ([]T, filters.Metadata, error) {
+	entities, metadata, err := repo.GetAllWithOptions(ctx, filter, findOpts, ListOptions{})
+
+	return entities, metadata.Metadata, err
+}
+
+// GetAllWithOptions retrieves all documents from the primary store with the given listing options
+func (repo DualWriteRepository[K, T]) GetAllWithOptions(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+	listOpts ListOptions,
+) ([]T, Metadata, error) {
+	entities, metadata, err := repo.primary.GetAllWithOptions(ctx, filter, findOpts, listOpts)
+
+	repo.shadow("get_all", func(ctx context.Context) (any, any, error) {
+		// Nothing to compare when the primary store failed
+		if err != nil {
+			return nil, nil, nil
+		}
+
+		shadowEntities, shadowMetadata, shadowErr := repo.secondary.GetAllWithOptions(ctx, filter, findOpts, listOpts)
+		if shadowErr != nil {
+			return nil, nil, shadowErr
+		}
+
+		return []any{entities, metadata}, []any{shadowEntities, shadowMetadata}, nil
+	})
+
+	return entities, metadata, err
+}
+
+// Aggregate runs the given aggregation pipeline against the primary store.
+// Aggregations are not shadowed since their results depend on the pipeline.
+func (repo DualWriteRepository[K, T]) Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error {
+	return repo.primary.Aggregate(ctx, pipeline, results)
+}
+
+// Export calls fn with every document of the primary store
+func (repo DualWriteRepository[K, T]) Export(ctx context.Context, snapshot bool, fn func(document Document[T]) error) error {
+	return repo.primary.Export(ctx, snapshot, fn)
+}
+
+// Restore writes the given documents in the primary store and in the secondary store
+func (repo DualWriteRepository[K, T]) Restore(ctx context.Context, documents []Document[T]) error {
+	err := repo.primary.Restore(ctx, documents)
+	if err != nil {
+		return err
+	}
+
+	err = repo.secondary.Restore(ctx, documents)
+	if err != nil {
+		repo.reportSecondaryError("restore", err)
+	}
+
+	return nil
+}
+
+// Create inserts a new document in the primary store and mirrors it in the secondary store
+func (repo DualWriteRepository[K, T]) Create(ctx context.Context, entity T) (*K, error) {
+	id, err := repo.primary.Create(ctx, entity)
+	if err != nil {
+		return id, err
+	}
+
+	// Mirror the stored document so that both stores share the same id
+	created, err := repo.primary.GetByID(ctx, *id)
+	if err != nil {
+		repo.reportSecondaryError("create", err)
+		return id, nil
+	}
+
+	_, err = repo.secondary.Create(ctx, created)
+	if err != nil {
+		repo.reportSecondaryError("create", err)
+	}
+
+	return id, nil
+}
+
+// Update updates a specific document in the primary store and in the secondary store
+func (repo DualWriteRepository[K, T]) Update(ctx context.Context, entity T) error {
+	err := repo.primary.Update(ctx, entity)
+	if err != nil {
+		return err
+	}
+
+	err = repo.secondary.Update(ctx, entity)
+	if err != nil {
+		repo.reportSecondaryError("update", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a specific document from the primary store and from the secondary store
+func (repo DualWriteRepository[K, T]) Delete(ctx context.Context, id K) error {
+	err := repo.primary.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = repo.secondary.Delete(ctx, id)
+	if err != nil {
+		repo.reportSecondaryError("delete", err)
+	}
+
+	return nil
+}
+
+// CopyDocuments copies every document of the source repository into the target repository, the given number of
+// documents at a time, along with the fields of the stored documents which are not mapped by the entities. The
+// documents already stored in the target repository are replaced. The given function, if any, is called with the
+// number of documents of each copied batch. It returns the number of copied documents.
+func CopyDocuments[K any, T types.MongoEntity[K, T]](
+	ctx context.Context,
+	source Repository[K, T],
+	target Repository[K, T],
+	batchSize int,
+	progress func(copied int),
+) (int, error) {
+	copied := 0
+	batch := make([]Document[T], 0, batchSize)
+
+	flush := func() error {
+		err := target.Restore(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		copied += len(batch)
+
+		if progress != nil {
+			progress(len(batch))
+		}
+
+		batch = batch[:0]
+
+		return nil
+	}
+
+	err := source.Export(ctx, false, func(document Document[T]) error {
+		batch = append(batch, document)
+
+		if len(batch) == batchSize {
+			return flush()
+		}
+
+		return nil
+	})
+	if err == nil && len(batch) != 0 {
+		err = flush()
+	}
+
+	return copied, err
+}
+
+// shadow runs the given read against the secondary store in the background and
+// compares its result with the result of the primary store
+func (repo DualWriteRepository[K, T]) shadow(operation string, read func(ctx context.Context) (any, any, error)) {
+	if !repo.shadowReads {
+		return
+	}
+
+	go func() {
+		// Recover any panic so that a shadow read can never crash the service
+		defer func() {
+			if err := recover(); err != nil {
+				repo.logger.Error(fmt.Errorf("%s", err), nil)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+		defer cancel()
+
+		primaryResult, secondaryResult, err := read(ctx)
+		if err != nil {
+			repo.reportSecondaryError(operation, err)
+			return
+		}
+
+		repo.metrics.ComparisonsCounter.WithLabelValues(repo.collection, operation).Inc()
+
+		if !reflect.DeepEqual(primaryResult, secondaryResult) {
+			repo.metrics.DivergencesCounter.WithLabelValues(repo.collection, operation).Inc()
+			repo.logger.Warning("Shadow read diverged from primary store", map[string]string{
+				"collection": repo.collection,
+				"operation":  operation,
+			})
+		}
+	}()
+}
+
+// reportSecondaryError logs an error which happened on the secondary store and increments the matching metric
+func (repo DualWriteRepository[K, T]) reportSecondaryError(operation string, err error) {
+	repo.metrics.SecondaryErrorsCounter.WithLabelValues(repo.collection, operation).Inc()
+	repo.logger.Error(err, map[string]string{
+		"collection": repo.collection,
+		"operation":  operation,
+		"store":      "secondary",
+	})
+}
+
+// entityOrError returns the error message if there is one so that a missing document
+// in both stores is considered equal, or the entity otherwise
+func entityOrError[T any](entity T, err error) any {
+	if err != nil {
+		return err.Error()
+	}
+
+	return entity
+}
@@ -0,0 +1,320 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errStoreDown is returned by the memory repositories which are down
+var errStoreDown = errors.New("store down")
+
+// memoryRepository keeps the items in memory and fails every operation while it is down
+type memoryRepository struct {
+	Repository[primitive.ObjectID, Item]
+	mu    sync.Mutex
+	items map[primitive.ObjectID]Item
+	down  bool
+}
+
+// newMemoryRepository returns a memory repository holding the given items
+func newMemoryRepository(items ...Item) *memoryRepository {
+	repo := &memoryRepository{items: map[primitive.ObjectID]Item{}}
+	for _, item := range items {
+		repo.items[item.ID] = item
+	}
+
+	return repo
+}
+
+// GetByID returns the stored item with the given id
+func (repo *memoryRepository) GetByID(ctx context.Context, id primitive.ObjectID) (Item, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.down {
+		return Item{}, errStoreDown
+	}
+
+	item, ok := repo.items[id]
+	if !ok {
+		return Item{}, database.ErrRecordNotFound
+	}
+
+	return item, nil
+}
+
+// Create stores the given item, with a new id unless it has one
+func (repo *memoryRepository) Create(ctx context.Context, item Item) (*primitive.ObjectID, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.down {
+		return nil, errStoreDown
+	}
+
+	if item.ID.IsZero() {
+		item.ID = primitive.NewObjectID()
+	}
+
+	repo.items[item.ID] = item
+
+	return &item.ID, nil
+}
+
+// Update replaces the stored item
+func (repo *memoryRepository) Update(ctx context.Context, item Item) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.down {
+		return errStoreDown
+	}
+
+	repo.items[item.ID] = item
+
+	return nil
+}
+
+// Delete removes the stored item with the given id
+func (repo *memoryRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.down {
+		return errStoreDown
+	}
+
+	delete(repo.items, id)
+
+	return nil
+}
+
+// Export calls fn with every stored item
+func (repo *memoryRepository) Export(ctx context.Context, snapshot bool, fn func(document Document[Item]) error) error {
+	repo.mu.Lock()
+	items := make([]Item, 0, len(repo.items))
+	for _, item := range repo.items {
+		items = append(items, item)
+	}
+	repo.mu.Unlock()
+
+	for _, item := range items {
+		err := fn(Document[Item]{Entity: item})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Restore stores the items of the given documents
+func (repo *memoryRepository) Restore(ctx context.Context, documents []Document[Item]) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.down {
+		return errStoreDown
+	}
+
+	for _, document := range documents {
+		repo.items[document.Entity.ID] = document.Entity
+	}
+
+	return nil
+}
+
+// stored returns the stored item with the given id, if any
+func (repo *memoryRepository) stored(id primitive.ObjectID) (Item, bool) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	item, ok := repo.items[id]
+
+	return item, ok
+}
+
+// dualWriteMetrics is shared by the tests since the metrics are registered once
+var dualWriteMetrics = NewDualWriteMetrics("test")
+
+func TestDualWriteRepository(t *testing.T) {
+	discard := logger.New(io.Discard, logger.LevelInfo)
+
+	t.Run("Mirrored writes", func(t *testing.T) {
+		primary, secondary := newMemoryRepository(), newMemoryRepository()
+		repo := NewDualWriteRepository[primitive.ObjectID, Item](primary, secondary, "items", false, discard, dualWriteMetrics)
+
+		id, err := repo.Create(context.Background(), Item{Name: "Potion", Price: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if item, ok := secondary.stored(*id); !ok || item.Name != "Potion" {
+			t.Fatalf("want the created item to be mirrored with id %s; got %v", id.Hex(), item)
+		}
+
+		err = repo.Update(context.Background(), Item{ID: *id, Name: "Great Potion", Price: 8})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if item, _ := secondary.stored(*id); item.Name != "Great Potion" {
+			t.Errorf("want %q; got %q", "Great Potion", item.Name)
+		}
+
+		err = repo.Delete(context.Background(), *id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := secondary.stored(*id); ok {
+			t.Error("want the deleted item to be deleted from the secondary store")
+		}
+	})
+
+	t.Run("Secondary store down", func(t *testing.T) {
+		primary, secondary := newMemoryRepository(), newMemoryRepository()
+		secondary.down = true
+
+		repo := NewDualWriteRepository[primitive.ObjectID, Item](primary, secondary, "items", false, discard, dualWriteMetrics)
+
+		counter := dualWriteMetrics.SecondaryErrorsCounter.WithLabelValues("items", "create")
+		before := testutil.ToFloat64(counter)
+
+		id, err := repo.Create(context.Background(), Item{Name: "Potion", Price: 5})
+		if err != nil {
+			t.Fatalf("want the write to succeed on the primary store; got %v", err)
+		}
+
+		if _, ok := primary.stored(*id); !ok {
+			t.Error("want the item to be stored in the primary store")
+		}
+
+		if count := testutil.ToFloat64(counter) - before; count != 1 {
+			t.Errorf("want %v secondary errors; got %v", 1, count)
+		}
+	})
+
+	t.Run("Primary store down", func(t *testing.T) {
+		primary, secondary := newMemoryRepository(), newMemoryRepository()
+		primary.down = true
+
+		repo := NewDualWriteRepository[primitive.ObjectID, Item](primary, secondary, "items", false, discard, dualWriteMetrics)
+
+		_, err := repo.Create(context.Background(), Item{Name: "Potion", Price: 5})
+		if !errors.Is(err, errStoreDown) {
+			t.Fatalf("want %v; got %v", errStoreDown, err)
+		}
+
+		if len(secondary.items) != 0 {
+			t.Errorf("want %d mirrored items; got %d", 0, len(secondary.items))
+		}
+	})
+
+	t.Run("Shadow reads", func(t *testing.T) {
+		item := Item{ID: primitive.NewObjectID(), Name: "Potion", Price: 5}
+
+		tests := []struct {
+			testName          string
+			secondaryItem     Item
+			wantedDivergences float64
+		}{
+			{"Same item", item, 0},
+			{"Diverging item", Item{ID: item.ID, Name: "Potion", Price: 6}, 1},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.testName, func(t *testing.T) {
+				repo := NewDualWriteRepository[primitive.ObjectID, Item](
+					newMemoryRepository(item),
+					newMemoryRepository(tt.secondaryItem),
+					"items",
+					true,
+					discard,
+					dualWriteMetrics,
+				)
+
+				comparisons := dualWriteMetrics.ComparisonsCounter.WithLabelValues("items", "get_by_id")
+				divergences := dualWriteMetrics.DivergencesCounter.WithLabelValues("items", "get_by_id")
+				comparisonsBefore, divergencesBefore := testutil.ToFloat64(comparisons), testutil.ToFloat64(divergences)
+
+				got, err := repo.GetByID(context.Background(), item.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got.Price != item.Price {
+					t.Errorf("want the primary item to be returned with price %v; got %v", item.Price, got.Price)
+				}
+
+				// The shadow read is compared in the background, the divergence being counted after the comparison
+				compared := func() bool {
+					return testutil.ToFloat64(comparisons) != comparisonsBefore &&
+						testutil.ToFloat64(divergences)-divergencesBefore == tt.wantedDivergences
+				}
+
+				deadline := time.Now().Add(time.Second)
+				for !compared() && time.Now().Before(deadline) {
+					time.Sleep(5 * time.Millisecond)
+				}
+
+				if count := testutil.ToFloat64(comparisons) - comparisonsBefore; count != 1 {
+					t.Fatalf("want %v comparisons; got %v", 1, count)
+				}
+
+				if count := testutil.ToFloat64(divergences) - divergencesBefore; count != tt.wantedDivergences {
+					t.Errorf("want %v divergences; got %v", tt.wantedDivergences, count)
+				}
+			})
+		}
+	})
+}
+
+func TestCopyDocuments(t *testing.T) {
+	items := []Item{
+		{ID: primitive.NewObjectID(), Name: "Potion", Price: 5},
+		{ID: primitive.NewObjectID(), Name: "Ether", Price: 10},
+		{ID: primitive.NewObjectID(), Name: "Elixir", Price: 50},
+	}
+
+	// The target store holds an outdated copy of the potion
+	source := newMemoryRepository(items...)
+	target := newMemoryRepository(Item{ID: items[0].ID, Name: "Potion", Price: 4})
+
+	batches := 0
+
+	copied, err := CopyDocuments[primitive.ObjectID, Item](context.Background(), source, target, 2, func(int) { batches++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if copied != len(items) {
+		t.Errorf("want %d copied documents; got %d", len(items), copied)
+	}
+
+	if batches != 2 {
+		t.Errorf("want %d batches; got %d", 2, batches)
+	}
+
+	for _, item := range items {
+		if stored, ok := target.stored(item.ID); !ok || stored.Price != item.Price {
+			t.Errorf("want %v; got %v", item, stored)
+		}
+	}
+
+	target.down = true
+
+	_, err = CopyDocuments[primitive.ObjectID, Item](context.Background(), source, target, 2, nil)
+	if !errors.Is(err, errStoreDown) {
+		t.Errorf("want %v; got %v", errStoreDown, err)
+	}
+}
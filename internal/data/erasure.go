@@ -0,0 +1,149 @@
+package data
+
+import (
+	"context"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// actorField is a field holding the id of the user who wrote a document, which is unset on erasure
+// or, when the schema of the collection requires it, reset to 0 (unknown user)
+type actorField struct {
+	collection string
+	field      string
+	required   bool
+}
+
+// actorFields are the fields of the catalog referencing the users who wrote the documents
+var actorFields = []actorField{
+	{collection: constants.ItemsCollection, field: "created_by"},
+	{collection: constants.ItemsCollection, field: "updated_by"},
+	{collection: constants.ItemRevisionsCollection, field: "item.created_by"},
+	{collection: constants.ItemRevisionsCollection, field: "item.updated_by"},
+	{collection: constants.ItemEventsCollection, field: "set.created_by"},
+	{collection: constants.ItemEventsCollection, field: "set.updated_by"},
+	{collection: constants.ItemSnapshotsCollection, field: "state.created_by"},
+	{collection: constants.ItemSnapshotsCollection, field: "state.updated_by"},
+	{collection: constants.JobsCollection, field: "created_by"},
+	{collection: constants.SavedFiltersCollection, field: "created_by", required: true},
+	{collection: constants.ItemCollectionsCollection, field: "created_by", required: true},
+	{collection: constants.APIKeysCollection, field: "created_by", required: true},
+}
+
+// Erasure is a struct that holds the number of documents changed by the erasure of a user
+type Erasure struct {
+	Users     int64 `json:"users"`     // 1 when the user was still stored
+	Reviews   int64 `json:"reviews"`   // Deleted reviews
+	Favorites int64 `json:"favorites"` // Deleted favorites
+	Actors    int64 `json:"actors"`    // Documents whose reference to the user was removed
+}
+
+// ErasureStore is a struct that erases the personal data the catalog holds about a user (i.e. for the right
+// to erasure of the GDPR), in every tenant. The user, its reviews and its favorites are deleted while the
+// documents written by the user, including the events and snapshots of the event-sourced items, are kept without
+// their reference to the user. The changed items are re-indexed with the given indexer, if any. Erasing a user
+// twice changes nothing the second time.
+type ErasureStore struct {
+	db        *mongo.Database
+	users     *mongo.Collection
+	reviews   *mongo.Collection
+	favorites *mongo.Collection
+	items     *mongo.Collection
+	ratings   *RatingStore
+	indexer   ItemsIndexer
+}
+
+// NewErasureStore creates a new ErasureStore for the given database
+func NewErasureStore(client *mongo.Client, databaseName string, indexer ItemsIndexer) *ErasureStore {
+	db := client.Database(databaseName)
+
+	return &ErasureStore{
+		db:        db,
+		users:     db.Collection(database.UsersCollection),
+		reviews:   db.Collection(constants.ReviewsCollection),
+		favorites: db.Collection(constants.FavoritesCollection),
+		items:     db.Collection(constants.ItemsCollection),
+		ratings:   NewRatingStore(client, databaseName, indexer),
+		indexer:   indexer,
+	}
+}
+
+// Erase erases the personal data of the user with the given id and returns the number of changed documents.
+// The ratings of the items reviewed by the user are recomputed without its reviews.
+func (store *ErasureStore) Erase(ctx context.Context, userID int64) (Erasure, error) {
+	var erasure Erasure
+
+	reviewedItems, err := store.reviews.Distinct(ctx, "item_id", bson.M{"user_id": userID})
+	if err != nil {
+		return erasure, err
+	}
+
+	result, err := store.reviews.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return erasure, err
+	}
+
+	erasure.Reviews = result.DeletedCount
+
+	for _, itemID := range reviewedItems {
+		if id, ok := itemID.(primitive.ObjectID); ok {
+			err = store.ratings.Refresh(ctx, id)
+			if err != nil {
+				return erasure, err
+			}
+		}
+	}
+
+	result, err = store.favorites.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return erasure, err
+	}
+
+	erasure.Favorites = result.DeletedCount
+
+	// The items written by the user are looked up beforehand so that they are re-indexed without the user
+	writtenItems, err := store.items.Distinct(ctx, "_id", bson.M{"$or": bson.A{
+		bson.M{"created_by": userID},
+		bson.M{"updated_by": userID},
+	}})
+	if err != nil {
+		return erasure, err
+	}
+
+	for _, actor := range actorFields {
+		update := bson.M{"$unset": bson.M{actor.field: ""}}
+		if actor.required {
+			update = bson.M{"$set": bson.M{actor.field: int64(0)}}
+		}
+
+		updateResult, err := store.db.Collection(actor.collection).UpdateMany(ctx, bson.M{actor.field: userID}, update)
+		if err != nil {
+			return erasure, err
+		}
+
+		erasure.Actors += updateResult.ModifiedCount
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(writtenItems))
+	for _, itemID := range writtenItems {
+		if id, ok := itemID.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	reindex(ctx, store.indexer, ids...)
+
+	// The user is deleted last so that it stays known until its data was erased
+	result, err = store.users.DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return erasure, err
+	}
+
+	erasure.Users = result.DeletedCount
+
+	return erasure, nil
+}
@@ -0,0 +1,88 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// duplicateKeyCode is the code of the write errors raised by a unique index violation
+const duplicateKeyCode = 11000
+
+// duplicateKeyIndexRX extracts the name of the violated index from the message of a duplicate key
+// error (i.e. "E11000 duplicate key error collection: catalog.items index: tenant_id_1_name_1 dup key: ...").
+// The tenant prefixing the unique indexes which are scoped to the tenants is skipped.
+var duplicateKeyIndexRX = regexp.MustCompile(`index: (?:tenant_id_1_)?(\w+?)_-?1 dup key`)
+
+// DuplicateKeyError is returned when a write violates a unique index.
+// It names the field holding the duplicated value and matches database.ErrDuplicateKey.
+type DuplicateKeyError struct {
+	Field string
+}
+
+// Error returns the message of the error
+func (e DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key on field %q", e.Field)
+}
+
+// Is reports whether the error matches the given target
+func (e DuplicateKeyError) Is(target error) bool {
+	return target == database.ErrDuplicateKey
+}
+
+// duplicateKeyError converts a duplicate key error returned by MongoDB (by a single or a bulk write)
+// into a DuplicateKeyError. Any other error is returned unchanged.
+func duplicateKeyError(err error) error {
+	var writeErrors []mongo.WriteError
+
+	var writeException mongo.WriteException
+	var bulkWriteException mongo.BulkWriteException
+
+	switch {
+	case errors.As(err, &writeException):
+		writeErrors = writeException.WriteErrors
+	case errors.As(err, &bulkWriteException):
+		for _, bulkWriteError := range bulkWriteException.WriteErrors {
+			writeErrors = append(writeErrors, bulkWriteError.WriteError)
+		}
+	default:
+		return err
+	}
+
+	for _, writeError := range writeErrors {
+		if writeError.Code != duplicateKeyCode {
+			continue
+		}
+
+		// Recent servers report the key pattern of the violated index
+		if keyPattern, ok := writeError.Raw.Lookup("keyPattern").DocumentOK(); ok {
+			if elements, err := keyPattern.Elements(); err == nil && len(elements) != 0 {
+				return DuplicateKeyError{Field: duplicatedField(elements)}
+			}
+		}
+
+		if matches := duplicateKeyIndexRX.FindStringSubmatch(writeError.Message); matches != nil {
+			return DuplicateKeyError{Field: matches[1]}
+		}
+
+		return DuplicateKeyError{}
+	}
+
+	return err
+}
+
+// duplicatedField returns the field of the given key pattern of a unique index holding the duplicated value.
+// The tenant of the unique indexes which are scoped to the tenants is skipped.
+func duplicatedField(keyPattern []bson.RawElement) string {
+	for _, element := range keyPattern {
+		if element.Key() != TenantField {
+			return element.Key()
+		}
+	}
+
+	return keyPattern[0].Key()
+}
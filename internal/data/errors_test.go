@@ -0,0 +1,107 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestDuplicateKeyError(t *testing.T) {
+	keyPattern, _ := bson.Marshal(bson.M{"keyPattern": bson.M{"description": 1}})
+	tenantKeyPattern, _ := bson.Marshal(bson.M{"keyPattern": bson.D{{Key: "tenant_id", Value: 1}, {Key: "name", Value: 1}}})
+
+	tests := []struct {
+		testName      string
+		err           error
+		expectedField string
+	}{
+		{
+			"Key pattern reported by the server",
+			mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Raw: keyPattern}}},
+			"description",
+		},
+		{
+			"Index name in the message",
+			mongo.WriteException{WriteErrors: []mongo.WriteError{{
+				Code:    11000,
+				Message: `E11000 duplicate key error collection: catalog.items index: name_1 dup key: { name: "Potion" }`,
+			}}},
+			"name",
+		},
+		{
+			"Key pattern of an index scoped to the tenants",
+			mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Raw: tenantKeyPattern}}},
+			"name",
+		},
+		{
+			"Index scoped to the tenants in the message",
+			mongo.WriteException{WriteErrors: []mongo.WriteError{{
+				Code:    11000,
+				Message: `E11000 duplicate key error collection: catalog.items index: tenant_id_1_external_id_1 dup key: { tenant_id: "default", external_id: "..." }`,
+			}}},
+			"external_id",
+		},
+		{
+			"Bulk write",
+			mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Code: 11000, Raw: keyPattern}}}},
+			"description",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			err := duplicateKeyError(tt.err)
+
+			var duplicateKeyErr DuplicateKeyError
+			if !errors.As(err, &duplicateKeyErr) {
+				t.Fatalf("want DuplicateKeyError; got %v", err)
+			}
+
+			if duplicateKeyErr.Field != tt.expectedField {
+				t.Errorf("want %q; got %q", tt.expectedField, duplicateKeyErr.Field)
+			}
+
+			if !errors.Is(err, database.ErrDuplicateKey) {
+				t.Errorf("want %v to match %v", err, database.ErrDuplicateKey)
+			}
+		})
+	}
+
+	t.Run("Other write error", func(t *testing.T) {
+		err := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 121, Message: "Document failed validation"}}}
+
+		if errors.Is(duplicateKeyError(err), database.ErrDuplicateKey) {
+			t.Errorf("want %v not to match %v", err, database.ErrDuplicateKey)
+		}
+	})
+}
+
+func TestIgnoreDuplicateKeys(t *testing.T) {
+	duplicate := mongo.BulkWriteError{WriteError: mongo.WriteError{Code: 11000}}
+	invalid := mongo.BulkWriteError{WriteError: mongo.WriteError{Code: 121}}
+
+	tests := []struct {
+		testName  string
+		err       error
+		wantedNil bool
+	}{
+		{"No error", nil, true},
+		{"Duplicate keys only", mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{duplicate, duplicate}}, true},
+		{"Other write error", mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{duplicate, invalid}}, false},
+		{"Write concern error", mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{duplicate}, WriteConcernError: &mongo.WriteConcernError{Code: 64}}, false},
+		{"Other error", errors.New("connection reset"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			err := ignoreDuplicateKeys(tt.err)
+
+			if (err == nil) != tt.wantedNil {
+				t.Errorf("want nil %t; got %v", tt.wantedNil, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventSourcedRepository is an items repository which appends an event for every change it makes to an item.
+// The items collection of the wrapped repository is the projection of the current state of the items, from which
+// they are read, and it is written within the same transaction as the events so that both never diverge.
+type EventSourcedRepository struct {
+	Repository[primitive.ObjectID, Item]
+	store        *ItemEventStore
+	transactions *Transactions
+}
+
+// NewEventSourcedRepository creates a new items repository appending the events of the changes made through the
+// given repository to the given store
+func NewEventSourcedRepository(repository Repository[primitive.ObjectID, Item], store *ItemEventStore, transactions *Transactions) *EventSourcedRepository {
+	return &EventSourcedRepository{
+		Repository:   repository,
+		store:        store,
+		transactions: transactions,
+	}
+}
+
+// Create inserts a new item and appends its creation event.
+// The id of the item is assigned beforehand so that the event is appended along with the item.
+func (repo EventSourcedRepository) Create(ctx context.Context, item Item) (*primitive.ObjectID, error) {
+	if item.ID.IsZero() {
+		item.ID = primitive.NewObjectID()
+	}
+
+	event, state, err := newItemEvent(ItemCreatedEvent, item)
+	if err != nil {
+		return nil, err
+	}
+
+	var id *primitive.ObjectID
+
+	err = repo.transactions.Run(ctx, func(ctx context.Context) error {
+		var err error
+
+		id, err = repo.Repository.Create(ctx, item)
+		if err != nil {
+			return err
+		}
+
+		return repo.store.Append(ctx, event, state)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return id, nil
+}
+
+// Update updates an item and appends the event of the fields which changed
+func (repo EventSourcedRepository) Update(ctx context.Context, item Item) error {
+	return repo.transactions.Run(ctx, func(ctx context.Context) error {
+		previous, err := repo.Repository.GetByID(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+
+		err = repo.Repository.Update(ctx, item)
+		if err != nil {
+			return err
+		}
+
+		// The version of the item is incremented by the update
+		event, state, err := newItemUpdatedEvent(previous, item.SetVersion(item.Version+1))
+		if err != nil {
+			return err
+		}
+
+		return repo.store.Append(ctx, event, state)
+	})
+}
+
+// Delete deletes an item and appends its deletion event
+func (repo EventSourcedRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return repo.transactions.Run(ctx, func(ctx context.Context) error {
+		previous, err := repo.Repository.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = repo.Repository.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return repo.store.Append(ctx, ItemEvent{
+			ItemID:     id,
+			TenantID:   previous.TenantID,
+			Type:       ItemDeletedEvent,
+			Version:    previous.Version,
+			OccurredAt: time.Now().UTC(),
+		}, nil)
+	})
+}
+
+// Restore writes the given items as they are and appends their restoration events
+func (repo EventSourcedRepository) Restore(ctx context.Context, documents []Document[Item]) error {
+	return repo.transactions.Run(ctx, func(ctx context.Context) error {
+		err := repo.Repository.Restore(ctx, documents)
+		if err != nil {
+			return err
+		}
+
+		for _, document := range documents {
+			event, state, err := newItemEvent(ItemRestoredEvent, document.Entity)
+			if err != nil {
+				return err
+			}
+
+			err = repo.store.Append(ctx, event, state)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Events retrieves up to limit events of the item of the given tenant with the given id which follow the given
+// sequence, in order
+func (repo EventSourcedRepository) Events(ctx context.Context, id primitive.ObjectID, tenant string, after int64, limit int) ([]ItemEvent, error) {
+	return repo.store.Events(ctx, id, tenant, after, limit)
+}
+
+// LoadAt derives the state of the item of the given tenant with the given id as it was at the given time
+// from its events
+func (repo EventSourcedRepository) LoadAt(ctx context.Context, id primitive.ObjectID, tenant string, at time.Time) (Item, error) {
+	return repo.store.LoadAt(ctx, id, tenant, at)
+}
+
+// Rebuild replaces the projection of the item of the given tenant with the given id with the state derived from its
+// events and returns it. The projection of a deleted item is removed and database.ErrRecordNotFound is returned.
+// The fields which are not changed through the repository (i.e. its rating and the thumbnails of its images) are
+// kept from the projection, and its version is incremented so that the clients holding the previous one get an
+// edit conflict.
+func (repo EventSourcedRepository) Rebuild(ctx context.Context, id primitive.ObjectID, tenant string) (Item, error) {
+	var rebuilt Item
+
+	err := repo.transactions.Run(ctx, func(ctx context.Context) error {
+		err := repo.store.checkTenant(ctx, id, tenant)
+		if err != nil {
+			return err
+		}
+
+		state, err := repo.store.state(ctx, id, time.Time{})
+		if err != nil {
+			return err
+		}
+
+		projection, err := repo.Repository.GetByID(ctx, id)
+		if err != nil && !errors.Is(err, database.ErrRecordNotFound) {
+			return err
+		}
+
+		exists := err == nil
+
+		if state == nil {
+			if exists {
+				err = repo.Repository.Delete(ctx, id)
+				if err != nil {
+					return err
+				}
+			}
+
+			return database.ErrRecordNotFound
+		}
+
+		rebuilt, err = decodeItemState(id, state)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			rebuilt = keepProjectedFields(rebuilt, projection)
+		}
+
+		return repo.Repository.Restore(ctx, []Document[Item]{{Entity: rebuilt}})
+	})
+	if err != nil {
+		return Item{}, err
+	}
+
+	return rebuilt, nil
+}
+
+// keepProjectedFields returns the given derived item with the fields written to the projection outside the
+// repository, and with the version following the version of the projection
+func keepProjectedFields(derived Item, projection Item) Item {
+	derived.Rating = projection.Rating
+
+	thumbnails := make(map[string][]Thumbnail, len(projection.Images))
+	for _, image := range projection.Images {
+		thumbnails[image.Key] = image.Thumbnails
+	}
+
+	for i, image := range derived.Images {
+		if image.Thumbnails == nil {
+			derived.Images[i].Thumbnails = thumbnails[image.Key]
+		}
+	}
+
+	if derived.Version <= projection.Version {
+		derived.Version = projection.Version + 1
+	}
+
+	return derived
+}
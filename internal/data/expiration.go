@@ -0,0 +1,72 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExpirationStore is a struct that keeps track of the expired items whose ItemExpired event was published.
+// The expiration date for which the event was published is recorded in the "expiration_notified" field
+// so that every expiration is only published once, even when several instances of the service are running,
+// and so that an item whose expiration date is changed afterwards is published again.
+type ExpirationStore struct {
+	collection *mongo.Collection
+}
+
+// NewExpirationStore creates a new expiration store for the given collection holding items
+func NewExpirationStore(client *mongo.Client, databaseName, collectionName string) *ExpirationStore {
+	return &ExpirationStore{
+		collection: client.Database(databaseName).Collection(collectionName),
+	}
+}
+
+// ClaimExpired atomically records that the event of an item expired at the given time is being published
+// and returns the item. It returns database.ErrRecordNotFound when there is no such item left.
+func (store *ExpirationStore) ClaimExpired(ctx context.Context, now time.Time) (Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		"expires_at": bson.M{"$lte": now},
+		"$expr":      bson.M{"$ne": bson.A{"$expiration_notified", "$expires_at"}},
+	}
+
+	// Pipeline update so that the expiration date can be copied
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"expiration_notified": "$expires_at"}}}}
+
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "expires_at", Value: 1}})
+
+	var item Item
+
+	err := store.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&item)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Item{}, database.ErrRecordNotFound
+		}
+
+		return Item{}, err
+	}
+
+	return item, nil
+}
+
+// Release removes the claim of an item whose event could not be published so that it is published
+// by a later check
+func (store *ExpirationStore) Release(ctx context.Context, item Item) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := store.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": item.ID, "expiration_notified": item.ExpiresAt},
+		bson.M{"$unset": bson.M{"expiration_notified": ""}},
+	)
+
+	return err
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Favorite is a struct that defines an item a user added to their favorites (i.e. their wishlist)
+type Favorite struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID  string             `json:"-" bson:"tenant_id"`
+	UserID    int64              `json:"user_id" bson:"user_id"`
+	ItemID    primitive.ObjectID `json:"item_id" bson:"item_id"`
+	Version   int32              `json:"version" bson:"version"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// GetID returns the id of a favorite.
+// This method is necessary for our generic constraint of our mongo repository.
+func (f Favorite) GetID() primitive.ObjectID {
+	return f.ID
+}
+
+// GetVersion returns the version of a favorite.
+// This method is necessary for our generic constraint of our mongo repository.
+func (f Favorite) GetVersion() int32 {
+	return f.Version
+}
+
+// SetVersion sets the version of a favorite to the given value and returns the favorite.
+// This method is necessary for our generic constraint of our mongo repository.
+func (f Favorite) SetVersion(version int32) Favorite {
+	f.Version = version
+
+	return f
+}
+
+// FavoritesFilter returns the MongoDB filter matching the favorites of the given user among the given items
+func FavoritesFilter(tenant string, userID int64, itemIDs []primitive.ObjectID) bson.M {
+	return bson.M{TenantField: tenant, "user_id": userID, "item_id": bson.M{"$in": itemIDs}}
+}
+
+// CreateFavoritesCollection creates favorites collection in MongoDB database
+func CreateFavoritesCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// JSON validation schema
+	jsonSchema := bson.M{
+		"bsonType":             "object",
+		"required":             []string{"tenant_id", "user_id", "item_id", "version", "created_at"},
+		"additionalProperties": false,
+		"properties": bson.M{
+			"_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Document ID",
+			},
+			"tenant_id": bson.M{
+				"bsonType":    "string",
+				"description": "Tenant which the favorite belongs to",
+			},
+			"user_id": bson.M{
+				"bsonType":    "long",
+				"description": "ID of the user who added the item to their favorites",
+			},
+			"item_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "ID of the favorite item",
+			},
+			"version": bson.M{
+				"bsonType":    "int",
+				"minimum":     1,
+				"description": "Document version",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "Creation date",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	// Create collection
+	opts := options.CreateCollection().SetValidator(validator)
+	err := db.CreateCollection(context.Background(), constants.FavoritesCollection, opts)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	// A user can only add an item to their favorites once. The favorites are listed per user.
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "item_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err = db.Collection(constants.FavoritesCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package data
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ItemsIndexer is implemented by the search indexes mirroring the items. The stores writing the items without the
+// items repository (i.e. to set some of their fields) re-index the items they wrote through it.
+type ItemsIndexer interface {
+	Reindex(ctx context.Context, ids ...primitive.ObjectID)
+}
+
+// reindex re-indexes the items with the given ids with the given indexer, which is nil when the items are not
+// mirrored in a search index
+func reindex(ctx context.Context, indexer ItemsIndexer, ids ...primitive.ObjectID) {
+	if indexer == nil || len(ids) == 0 {
+		return
+	}
+
+	indexer.Reindex(ctx, ids...)
+}
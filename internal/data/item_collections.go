@@ -0,0 +1,164 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxItemCollectionItems is the maximum number of items of an item collection
+const MaxItemCollectionItems = 100
+
+// ItemCollection is a struct that defines a curated and ordered group of items (i.e. a promotion).
+// It is only shown during its active window, if it has one.
+type ItemCollection struct {
+	ID          primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	TenantID    string               `json:"-" bson:"tenant_id"`
+	Name        string               `json:"name" bson:"name"`
+	Description string               `json:"description" bson:"description"`
+	ItemIDs     []primitive.ObjectID `json:"item_ids" bson:"item_ids"` // Ordered members of the collection
+	StartsAt    *time.Time           `json:"starts_at,omitempty" bson:"starts_at,omitempty"`
+	EndsAt      *time.Time           `json:"ends_at,omitempty" bson:"ends_at,omitempty"`
+	CreatedBy   int64                `json:"created_by" bson:"created_by"`
+	Version     int32                `json:"version" bson:"version"`
+	CreatedAt   time.Time            `json:"-" bson:"created_at"`
+	UpdatedAt   time.Time            `json:"-" bson:"updated_at"`
+}
+
+// GetID returns the id of an item collection.
+// This method is necessary for our generic constraint of our mongo repository.
+func (c ItemCollection) GetID() primitive.ObjectID {
+	return c.ID
+}
+
+// GetVersion returns the version of an item collection.
+// This method is necessary for our generic constraint of our mongo repository.
+func (c ItemCollection) GetVersion() int32 {
+	return c.Version
+}
+
+// SetVersion sets the version of an item collection to the given value and returns the item collection.
+// This method is necessary for our generic constraint of our mongo repository.
+func (c ItemCollection) SetVersion(version int32) ItemCollection {
+	c.Version = version
+
+	return c
+}
+
+// Active returns true if the given time is within the active window of the item collection
+func (c ItemCollection) Active(now time.Time) bool {
+	return (c.StartsAt == nil || !now.Before(*c.StartsAt)) && (c.EndsAt == nil || now.Before(*c.EndsAt))
+}
+
+// ActiveItemCollectionsFilter returns the MongoDB filter matching the item collections active at the given time
+func ActiveItemCollectionsFilter(now time.Time) bson.A {
+	return bson.A{
+		bson.M{"$or": bson.A{bson.M{"starts_at": nil}, bson.M{"starts_at": bson.M{"$lte": now}}}},
+		bson.M{"$or": bson.A{bson.M{"ends_at": nil}, bson.M{"ends_at": bson.M{"$gt": now}}}},
+	}
+}
+
+// ValidateItemCollection runs validation checks on the `ItemCollection` struct
+func ValidateItemCollection(v *validation.Validator, collection ItemCollection) {
+	v.Check(collection.Name != "", "name", "required", "must be provided")
+	v.Check(validator.MaxCharacters(collection.Name, 100), "name", "too_long", "must not be more than 100 characters long")
+	v.Check(validator.MaxCharacters(collection.Description, 1000), "description", "too_long", "must not be more than 1000 characters long")
+	v.Check(len(collection.ItemIDs) <= MaxItemCollectionItems, "item_ids", "too_many", "must not contain more than 100 ids")
+	v.Check(validator.NoDuplicates(collection.ItemIDs), "item_ids", "duplicate", "must not contain duplicate values")
+
+	if collection.StartsAt != nil && collection.EndsAt != nil {
+		v.Check(collection.EndsAt.After(*collection.StartsAt), "ends_at", "invalid_range", "must be later than specified starts_at")
+	}
+}
+
+// CreateItemCollectionsCollection creates item collections collection in MongoDB database
+func CreateItemCollectionsCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// JSON validation schema
+	jsonSchema := bson.M{
+		"bsonType":             "object",
+		"required":             []string{"tenant_id", "name", "description", "item_ids", "created_by", "version", "created_at", "updated_at"},
+		"additionalProperties": false,
+		"properties": bson.M{
+			"_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Document ID",
+			},
+			"tenant_id": bson.M{
+				"bsonType":    "string",
+				"description": "Tenant which the item collection belongs to",
+			},
+			"name": bson.M{
+				"bsonType":    "string",
+				"description": "Name of the item collection",
+			},
+			"description": bson.M{
+				"bsonType":    "string",
+				"description": "Description of the item collection",
+			},
+			"item_ids": bson.M{
+				"bsonType":    "array",
+				"items":       bson.M{"bsonType": "objectId"},
+				"maxItems":    MaxItemCollectionItems,
+				"description": "Ordered ids of the items of the collection",
+			},
+			"starts_at": bson.M{
+				"bsonType":    "date",
+				"description": "Start of the active window",
+			},
+			"ends_at": bson.M{
+				"bsonType":    "date",
+				"description": "End of the active window",
+			},
+			"created_by": bson.M{
+				"bsonType":    "long",
+				"description": "ID of the user who created the item collection",
+			},
+			"version": bson.M{
+				"bsonType":    "int",
+				"minimum":     1,
+				"description": "Document version",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "Creation date",
+			},
+			"updated_at": bson.M{
+				"bsonType":    "date",
+				"description": "Last update date",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	// Create collection
+	opts := options.CreateCollection().SetValidator(validator)
+	err := db.CreateCollection(context.Background(), constants.ItemCollectionsCollection, opts)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	// Item collections are listed per tenant
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: TenantField, Value: 1}, {Key: "name", Value: 1}},
+	}
+
+	_, err = db.Collection(constants.ItemCollectionsCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"reflect"
+	"sort"
+)
+
+// MaxComparedItems is the maximum number of items which can be compared at once
+const MaxComparedItems = 4
+
+// ComparedFields is the list of item fields compared by the item comparison
+var ComparedFields = []string{"description", "price", "tags", "rating", "featured", "expires_at"}
+
+// ItemComparison is a struct that holds the compared fields of items split between the fields
+// sharing the same value on every item and the fields whose values differ
+type ItemComparison struct {
+	Shared    map[string]any   `json:"shared"`    // Value of the fields shared by every item
+	Different map[string][]any `json:"different"` // Values of the differing fields, in the order of the items
+}
+
+// CompareItems compares the ComparedFields of the given items. The order of the tags does not matter.
+func CompareItems(items []Item) ItemComparison {
+	comparison := ItemComparison{
+		Shared:    map[string]any{},
+		Different: map[string][]any{},
+	}
+
+	selected := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		item.Tags = sortedTags(item.Tags)
+		selected = append(selected, item.SelectFields(ComparedFields))
+	}
+
+	for _, field := range ComparedFields {
+		values := make([]any, 0, len(selected))
+		shared := true
+
+		for _, fields := range selected {
+			values = append(values, fields[field])
+			shared = shared && reflect.DeepEqual(fields[field], values[0])
+		}
+
+		if shared && len(values) != 0 {
+			comparison.Shared[field] = values[0]
+			continue
+		}
+
+		comparison.Different[field] = values
+	}
+
+	return comparison
+}
+
+// sortedTags returns a sorted copy of the given tags
+func sortedTags(tags []string) []string {
+	sorted := append([]string{}, tags...)
+	sort.Strings(sorted)
+
+	return sorted
+}
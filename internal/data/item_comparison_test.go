@@ -0,0 +1,35 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareItems(t *testing.T) {
+	items := []Item{
+		{Name: "Potion", Description: "Restores health", Price: 5, Tags: []string{"rare", "healing"}},
+		{Name: "Ether", Description: "Restores health", Price: 3, Tags: []string{"healing", "rare"}, Featured: true},
+	}
+
+	comparison := CompareItems(items)
+
+	wantedShared := map[string]any{
+		"description": "Restores health",
+		"tags":        []string{"healing", "rare"},
+		"rating":      (*ItemRating)(nil),
+		"expires_at":  items[0].ExpiresAt,
+	}
+
+	if !reflect.DeepEqual(comparison.Shared, wantedShared) {
+		t.Errorf("want %v; got %v", wantedShared, comparison.Shared)
+	}
+
+	wantedDifferent := map[string][]any{
+		"price":    {5.0, 3.0},
+		"featured": {false, true},
+	}
+
+	if !reflect.DeepEqual(comparison.Different, wantedDifferent) {
+		t.Errorf("want %v; got %v", wantedDifferent, comparison.Different)
+	}
+}
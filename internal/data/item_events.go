@@ -0,0 +1,428 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Types of the events of the event-sourced items
+const (
+	ItemCreatedEvent  = "created"
+	ItemUpdatedEvent  = "updated"
+	ItemDeletedEvent  = "deleted"
+	ItemRestoredEvent = "restored"
+)
+
+// ItemEvent is a struct that holds a change made to an event-sourced item. The events of an item are
+// numbered in the order in which they happened, its state is derived by applying them one after the other.
+type ItemEvent struct {
+	ItemID     primitive.ObjectID `json:"-" bson:"item_id"`
+	TenantID   string             `json:"-" bson:"tenant_id,omitempty"`
+	Sequence   int64              `json:"sequence" bson:"sequence"`
+	Type       string             `json:"type" bson:"type"`
+	Version    int32              `json:"version" bson:"version"`             // Version of the item after the event
+	Set        bson.M             `json:"set,omitempty" bson:"set,omitempty"` // Fields set by the event, the whole item for the creations and the restorations
+	Unset      []string           `json:"unset,omitempty" bson:"unset,omitempty"`
+	OccurredAt time.Time          `json:"occurred_at" bson:"occurred_at"`
+}
+
+// ItemSnapshot is a struct that holds the state of an event-sourced item after one of its events,
+// so that its state is derived without applying all of its events. The state of a deleted item is empty.
+type ItemSnapshot struct {
+	ItemID   primitive.ObjectID `bson:"item_id"`
+	Sequence int64              `bson:"sequence"`
+	State    bson.Raw           `bson:"state,omitempty"`
+	TakenAt  time.Time          `bson:"taken_at"` // Time of the event of the snapshot
+}
+
+// ItemEventStore is a struct that appends the events of the event-sourced items and derives their state
+type ItemEventStore struct {
+	events           *mongo.Collection
+	snapshots        *mongo.Collection
+	snapshotInterval int64
+}
+
+// NewItemEventStore creates a new item event store for the given database, which takes a snapshot of an item
+// every snapshotInterval events
+func NewItemEventStore(client *mongo.Client, databaseName string, snapshotInterval int) *ItemEventStore {
+	db := client.Database(databaseName)
+
+	return &ItemEventStore{
+		events:           db.Collection(constants.ItemEventsCollection),
+		snapshots:        db.Collection(constants.ItemSnapshotsCollection),
+		snapshotInterval: int64(snapshotInterval),
+	}
+}
+
+// Append numbers the given event after the last event of its item, stores it and takes a snapshot of the given
+// state of the item when it is due. It returns database.ErrEditConflict when another event of the item was
+// appended concurrently. It must run within the transaction writing the projection of the item.
+func (store *ItemEventStore) Append(ctx context.Context, event ItemEvent, state bson.Raw) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var last ItemEvent
+
+	err := store.events.FindOne(
+		ctx,
+		bson.M{"item_id": event.ItemID},
+		options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}}).SetProjection(bson.M{"sequence": 1}),
+	).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	event.Sequence = last.Sequence + 1
+
+	// The items written before they were event sourced are recorded as a whole by their first update
+	if event.Sequence == 1 && event.Type == ItemUpdatedEvent {
+		event.Set, _, err = diffItemStates(nil, state)
+		if err != nil {
+			return err
+		}
+
+		event.Unset = nil
+	}
+
+	_, err = store.events.InsertOne(ctx, event)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return database.ErrEditConflict
+		}
+
+		return err
+	}
+
+	if event.Sequence%store.snapshotInterval != 0 {
+		return nil
+	}
+
+	_, err = store.snapshots.InsertOne(ctx, ItemSnapshot{
+		ItemID:   event.ItemID,
+		Sequence: event.Sequence,
+		State:    state,
+		TakenAt:  event.OccurredAt,
+	})
+
+	return err
+}
+
+// checkTenant checks that the item with the given id belongs to the given tenant.
+// It returns database.ErrRecordNotFound if the item has no event of the tenant.
+func (store *ItemEventStore) checkTenant(ctx context.Context, itemID primitive.ObjectID, tenant string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	count, err := store.events.CountDocuments(ctx, bson.M{"item_id": itemID, TenantField: tenant}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return database.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Events retrieves up to limit events of the item of the given tenant with the given id which follow the given
+// sequence, in order. It returns database.ErrRecordNotFound if the item has no event.
+func (store *ItemEventStore) Events(ctx context.Context, itemID primitive.ObjectID, tenant string, after int64, limit int) ([]ItemEvent, error) {
+	err := store.checkTenant(ctx, itemID, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	cursor, err := store.events.Find(
+		ctx,
+		bson.M{"item_id": itemID, "sequence": bson.M{"$gt": after}},
+		options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []ItemEvent{}
+
+	err = cursor.All(ctx, &events)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// LoadAt derives the state of the item of the given tenant with the given id as it was at the given time, or its
+// current state when the time is zero, from its last snapshot and the events which followed it.
+// It returns database.ErrRecordNotFound if the item did not exist at that time.
+func (store *ItemEventStore) LoadAt(ctx context.Context, itemID primitive.ObjectID, tenant string, at time.Time) (Item, error) {
+	err := store.checkTenant(ctx, itemID, tenant)
+	if err != nil {
+		return Item{}, err
+	}
+
+	state, err := store.state(ctx, itemID, at)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if state == nil {
+		return Item{}, database.ErrRecordNotFound
+	}
+
+	return decodeItemState(itemID, state)
+}
+
+// state derives the fields of the item with the given id at the given time. The state of a deleted item is nil.
+// It returns database.ErrRecordNotFound if the item has no event before that time.
+func (store *ItemEventStore) state(ctx context.Context, itemID primitive.ObjectID, at time.Time) (bson.M, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	snapshotFilter := bson.M{"item_id": itemID}
+	if !at.IsZero() {
+		snapshotFilter["taken_at"] = bson.M{"$lte": at}
+	}
+
+	var snapshot ItemSnapshot
+
+	err := store.snapshots.FindOne(ctx, snapshotFilter, options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}})).Decode(&snapshot)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	found := err == nil
+
+	var state bson.M
+
+	if snapshot.State != nil {
+		err = bson.Unmarshal(snapshot.State, &state)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	eventsFilter := bson.M{"item_id": itemID, "sequence": bson.M{"$gt": snapshot.Sequence}}
+	if !at.IsZero() {
+		eventsFilter["occurred_at"] = bson.M{"$lte": at}
+	}
+
+	cursor, err := store.events.Find(ctx, eventsFilter, options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var event ItemEvent
+
+		err = cursor.Decode(&event)
+		if err != nil {
+			return nil, err
+		}
+
+		state = applyItemEvent(state, event)
+		found = true
+	}
+
+	if cursor.Err() != nil {
+		return nil, cursor.Err()
+	}
+
+	if !found {
+		return nil, database.ErrRecordNotFound
+	}
+
+	return state, nil
+}
+
+// newItemEvent returns the event of the given type which records the given item as a whole,
+// along with the state of the item
+func newItemEvent(eventType string, item Item) (ItemEvent, bson.Raw, error) {
+	state, err := bson.Marshal(item)
+	if err != nil {
+		return ItemEvent{}, nil, err
+	}
+
+	set, _, err := diffItemStates(nil, state)
+	if err != nil {
+		return ItemEvent{}, nil, err
+	}
+
+	return ItemEvent{
+		ItemID:     item.ID,
+		TenantID:   item.TenantID,
+		Type:       eventType,
+		Version:    item.Version,
+		Set:        set,
+		OccurredAt: time.Now().UTC(),
+	}, state, nil
+}
+
+// newItemUpdatedEvent returns the event recording the fields which changed from the previous item to the next one,
+// along with the state of the next item
+func newItemUpdatedEvent(previous Item, next Item) (ItemEvent, bson.Raw, error) {
+	previousState, err := bson.Marshal(previous)
+	if err != nil {
+		return ItemEvent{}, nil, err
+	}
+
+	state, err := bson.Marshal(next)
+	if err != nil {
+		return ItemEvent{}, nil, err
+	}
+
+	set, unset, err := diffItemStates(previousState, state)
+	if err != nil {
+		return ItemEvent{}, nil, err
+	}
+
+	return ItemEvent{
+		ItemID:     next.ID,
+		TenantID:   next.TenantID,
+		Type:       ItemUpdatedEvent,
+		Version:    next.Version,
+		Set:        set,
+		Unset:      unset,
+		OccurredAt: time.Now().UTC(),
+	}, state, nil
+}
+
+// diffItemStates returns the top-level fields of the next state which differ from the previous one and the fields
+// of the previous state which were removed. The id of the item is never part of the difference.
+func diffItemStates(previous bson.Raw, next bson.Raw) (bson.M, []string, error) {
+	set := bson.M{}
+	unset := []string{}
+
+	elements, err := next.Elements()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, element := range elements {
+		key := element.Key()
+		if key == "_id" {
+			continue
+		}
+
+		value := element.Value()
+
+		if previous != nil {
+			previousValue, err := previous.LookupErr(key)
+			if err == nil && previousValue.Type == value.Type && bytes.Equal(previousValue.Value, value.Value) {
+				continue
+			}
+		}
+
+		set[key] = value
+	}
+
+	if previous != nil {
+		previousElements, err := previous.Elements()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, element := range previousElements {
+			if _, err := next.LookupErr(element.Key()); err != nil && element.Key() != "_id" {
+				unset = append(unset, element.Key())
+			}
+		}
+	}
+
+	return set, unset, nil
+}
+
+// applyItemEvent returns the given state of an item once the given event is applied to it
+func applyItemEvent(state bson.M, event ItemEvent) bson.M {
+	switch event.Type {
+	case ItemCreatedEvent, ItemRestoredEvent:
+		state = bson.M{}
+	case ItemDeletedEvent:
+		return nil
+	}
+
+	if state == nil {
+		state = bson.M{}
+	}
+
+	for key, value := range event.Set {
+		state[key] = value
+	}
+
+	for _, key := range event.Unset {
+		delete(state, key)
+	}
+
+	return state
+}
+
+// decodeItemState converts the derived state of the item with the given id into an item
+func decodeItemState(itemID primitive.ObjectID, state bson.M) (Item, error) {
+	document, err := bson.Marshal(state)
+	if err != nil {
+		return Item{}, err
+	}
+
+	var item Item
+
+	err = bson.Unmarshal(document, &item)
+	if err != nil {
+		return Item{}, err
+	}
+
+	item.ID = itemID
+
+	return item, nil
+}
+
+// CreateItemEventsCollections creates the collections of the events and of the snapshots of the event-sourced items
+// in MongoDB database. The sequence of an event is unique among the events of its item.
+func CreateItemEventsCollections(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	collections := []struct {
+		name  string
+		index mongo.IndexModel
+	}{
+		{
+			name: constants.ItemEventsCollection,
+			index: mongo.IndexModel{
+				Keys:    bson.D{{Key: "item_id", Value: 1}, {Key: "sequence", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		{
+			name:  constants.ItemSnapshotsCollection,
+			index: mongo.IndexModel{Keys: bson.D{{Key: "item_id", Value: 1}, {Key: "sequence", Value: -1}}},
+		},
+	}
+
+	for _, collection := range collections {
+		err := db.CreateCollection(context.Background(), collection.name)
+		if err != nil {
+			// Returns error if collection already exists so we ignore it
+			continue
+		}
+
+		_, err = db.Collection(collection.name).Indexes().CreateOne(context.Background(), collection.index)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
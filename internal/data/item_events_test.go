@@ -0,0 +1,130 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewItemUpdatedEvent(t *testing.T) {
+	previous := Item{ID: primitive.NewObjectID(), Name: "Potion", Description: "Restores health", Price: 5, Featured: true, Version: 1}
+
+	next := previous
+	next.Price = 7
+	next.Featured = false
+	next.Version = 2
+
+	event, _, err := newItemUpdatedEvent(previous, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := make([]string, 0, len(event.Set))
+	for _, key := range []string{"name", "description", "price", "version", "featured"} {
+		if _, ok := event.Set[key]; ok {
+			set = append(set, key)
+		}
+	}
+
+	if want := []string{"price", "version"}; !reflect.DeepEqual(set, want) {
+		t.Errorf("want set %v; got %v", want, set)
+	}
+
+	// Featured is omitted once false
+	if want := []string{"featured"}; !reflect.DeepEqual(event.Unset, want) {
+		t.Errorf("want unset %v; got %v", want, event.Unset)
+	}
+
+	if event.Type != ItemUpdatedEvent || event.Version != 2 {
+		t.Errorf("want updated event of version 2; got %s event of version %d", event.Type, event.Version)
+	}
+}
+
+func TestApplyItemEvent(t *testing.T) {
+	id := primitive.NewObjectID()
+	created := Item{ID: id, Name: "Potion", Description: "Restores health", Price: 5, Tags: []string{"potion"}, Featured: true, Version: 1}
+
+	updated := created
+	updated.Name = "Elixir"
+	updated.Featured = false
+	updated.Version = 2
+
+	createdEvent, _, err := newItemEvent(ItemCreatedEvent, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updatedEvent, _, err := newItemUpdatedEvent(created, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		events []ItemEvent
+		want   *Item
+	}{
+		{"Created", []ItemEvent{createdEvent}, &created},
+		{"Updated", []ItemEvent{createdEvent, updatedEvent}, &updated},
+		{"Deleted", []ItemEvent{createdEvent, updatedEvent, {Type: ItemDeletedEvent}}, nil},
+		{"Restored", []ItemEvent{createdEvent, {Type: ItemDeletedEvent}, createdEvent}, &created},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state bson.M
+
+			for _, event := range tt.events {
+				state = applyItemEvent(state, event)
+			}
+
+			if tt.want == nil {
+				if state != nil {
+					t.Errorf("want deleted item; got %v", state)
+				}
+
+				return
+			}
+
+			item, err := decodeItemState(id, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(item, *tt.want) {
+				t.Errorf("want %+v; got %+v", *tt.want, item)
+			}
+		})
+	}
+}
+
+func TestKeepProjectedFields(t *testing.T) {
+	thumbnails := []Thumbnail{{Width: 128}}
+	derived := Item{Name: "Potion", Images: []ItemImage{{Key: "a"}, {Key: "b"}}, Version: 3}
+	projection := Item{
+		Name:    "Broken",
+		Images:  []ItemImage{{Key: "a", Thumbnails: thumbnails}},
+		Rating:  &ItemRating{Count: 2},
+		Version: 5,
+	}
+
+	rebuilt := keepProjectedFields(derived, projection)
+
+	if rebuilt.Name != "Potion" {
+		t.Errorf("want name %q; got %q", "Potion", rebuilt.Name)
+	}
+
+	if !reflect.DeepEqual(rebuilt.Images[0].Thumbnails, thumbnails) || rebuilt.Images[1].Thumbnails != nil {
+		t.Errorf("want thumbnails of image a only; got %+v", rebuilt.Images)
+	}
+
+	if rebuilt.Rating != projection.Rating {
+		t.Errorf("want rating of the projection; got %+v", rebuilt.Rating)
+	}
+
+	if rebuilt.Version != 6 {
+		t.Errorf("want version %d; got %d", 6, rebuilt.Version)
+	}
+}
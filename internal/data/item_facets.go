@@ -0,0 +1,96 @@
+package data
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PriceBucketBoundaries are the lower bounds of the price buckets of the price facet.
+// The last bucket holds every item priced above its lower bound.
+var PriceBucketBoundaries = []float64{0, 10, 50, 100, 500}
+
+// TagFacet is a struct that holds the number of items having a tag
+type TagFacet struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
+
+// PriceFacet is a struct that holds the number of items priced within a bucket
+type PriceFacet struct {
+	MinPrice float64  `json:"min_price" bson:"_id"`
+	MaxPrice *float64 `json:"max_price" bson:"-"` // nil for the last bucket
+	Count    int      `json:"count" bson:"count"`
+}
+
+// ItemFacets is a struct that holds the facet counts of the items matching a filter
+type ItemFacets struct {
+	Tags  []TagFacet   `json:"tags" bson:"tags"`
+	Price []PriceFacet `json:"price" bson:"price"`
+}
+
+// ItemFacetsPipeline returns the aggregation pipeline counting the items matching the given filter
+// for each of the requested facets ("tag" and/or "price")
+func ItemFacetsPipeline(filter bson.M, facets []string) mongo.Pipeline {
+	return facetsPipeline(filter, facets, bson.A{
+		bson.M{"$bucket": bson.M{
+			"groupBy":    "$price",
+			"boundaries": PriceBucketBoundaries,
+			"default":    PriceBucketBoundaries[len(PriceBucketBoundaries)-1],
+			"output":     bson.M{"count": bson.M{"$sum": 1}},
+		}},
+	})
+}
+
+// ListingFacetsPipeline returns the aggregation pipeline counting the listings matching the given filter
+// for each of the requested facets, whose price buckets are computed beforehand
+func ListingFacetsPipeline(filter bson.M, facets []string) mongo.Pipeline {
+	return facetsPipeline(filter, facets, bson.A{
+		bson.M{"$group": bson.M{"_id": "$" + PriceBucketField, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	})
+}
+
+// facetsPipeline returns the aggregation pipeline counting the documents matching the given filter
+// for each of the requested facets, the prices being counted by the given stages
+func facetsPipeline(filter bson.M, facets []string, priceStages bson.A) mongo.Pipeline {
+	stages := bson.M{}
+
+	for _, facet := range facets {
+		switch facet {
+		case "tag":
+			stages["tags"] = bson.A{
+				bson.M{"$unwind": "$tags"},
+				bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			}
+		case "price":
+			stages["price"] = priceStages
+		}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$facet", Value: stages}},
+	}
+}
+
+// Finalize fills the upper bound of each price bucket and replaces the facets without
+// any matching item by empty lists
+func (facets *ItemFacets) Finalize() {
+	if facets.Tags == nil {
+		facets.Tags = []TagFacet{}
+	}
+
+	if facets.Price == nil {
+		facets.Price = []PriceFacet{}
+	}
+
+	for i := range facets.Price {
+		for j, boundary := range PriceBucketBoundaries {
+			if boundary == facets.Price[i].MinPrice && j+1 < len(PriceBucketBoundaries) {
+				maxPrice := PriceBucketBoundaries[j+1]
+				facets.Price[i].MaxPrice = &maxPrice
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package data
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// ItemFields is the list of item fields which can be selected with the "fields" query string parameter
+var ItemFields = []string{"id", "external_id", "name", "description", "price", "tags", "auto_tags", "images", "flagged_for_review", "featured", "featured_priority", "rating", "version", "expires_at", "created_by", "updated_by"}
+
+// ItemProjection returns the MongoDB projection only retrieving the given item fields.
+// The fields must have been validated against ItemFields beforehand.
+func ItemProjection(fields []string) bson.M {
+	// The id is returned by default so it must be excluded explicitly
+	projection := bson.M{"_id": 0}
+
+	for _, field := range fields {
+		if field == "id" {
+			projection["_id"] = 1
+			continue
+		}
+
+		projection[field] = 1
+	}
+
+	return projection
+}
+
+// SelectFields returns the JSON representation of the item restricted to the given fields
+func (i Item) SelectFields(fields []string) map[string]any {
+	selected := make(map[string]any, len(fields))
+
+	for _, field := range fields {
+		switch field {
+		case "id":
+			selected[field] = i.ID
+		case "external_id":
+			selected[field] = i.ExternalID
+		case "name":
+			selected[field] = i.Name
+		case "description":
+			selected[field] = i.Description
+
+			// The rendered description goes along with its source
+			if i.DescriptionHTML != "" {
+				selected["description_html"] = i.DescriptionHTML
+			}
+		case "price":
+			selected[field] = i.Price
+		case "tags":
+			selected[field] = i.Tags
+		case "auto_tags":
+			selected[field] = i.AutoTags
+		case "images":
+			selected[field] = i.Images
+		case "flagged_for_review":
+			selected[field] = i.FlaggedForReview
+		case "featured":
+			selected[field] = i.Featured
+		case "featured_priority":
+			selected[field] = i.FeaturedPriority
+		case "rating":
+			selected[field] = i.Rating
+		case "version":
+			selected[field] = i.Version
+		case "expires_at":
+			selected[field] = i.ExpiresAt
+		case "created_by":
+			selected[field] = i.CreatedBy
+		case "updated_by":
+			selected[field] = i.UpdatedBy
+		}
+	}
+
+	// The favorited flag of the user goes along with the selected fields
+	if i.Favorited != nil {
+		selected["favorited"] = *i.Favorited
+	}
+
+	return selected
+}
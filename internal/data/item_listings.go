@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PriceBucketField is the field of the listings holding the lower bound of the price bucket of the item
+const PriceBucketField = "price_bucket"
+
+// ListingStore is a struct that maintains the read model of the listings of the items. A listing is the item
+// as stored, along with the values computed for the listings (i.e. the bucket of its price facet) so that they
+// are not computed by every listing query. It is updated from the change stream of the items, like the event
+// publishers, so it lags slightly behind the items.
+type ListingStore struct {
+	items    *mongo.Collection
+	listings *mongo.Collection
+}
+
+// NewListingStore creates a new listing store for the given database
+func NewListingStore(client *mongo.Client, databaseName string) *ListingStore {
+	db := client.Database(databaseName)
+
+	return &ListingStore{
+		items:    db.Collection(constants.ItemsCollection),
+		listings: db.Collection(constants.ItemListingsCollection),
+	}
+}
+
+// PublishCreated writes the listing of the created item
+func (store *ListingStore) PublishCreated(ctx context.Context, item Item, at time.Time) error {
+	return store.project(ctx, item.ID, at)
+}
+
+// PublishUpdated writes the listing of the updated item
+func (store *ListingStore) PublishUpdated(ctx context.Context, item Item, at time.Time) error {
+	return store.project(ctx, item.ID, at)
+}
+
+// PublishDeleted deletes the listing of the deleted item
+func (store *ListingStore) PublishDeleted(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := store.listings.DeleteOne(ctx, bson.M{"_id": id})
+
+	return err
+}
+
+// project replaces the listing of the item with the given id with the listing of the item as it currently is,
+// so that the changes processed late or twice never overwrite a newer listing. The listing of an item which was
+// deleted since is left to the processing of its deletion.
+func (store *ListingStore) project(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	cursor, err := store.items.Aggregate(ctx, listingPipeline(bson.M{"_id": id}, at))
+	if err != nil {
+		return err
+	}
+
+	var listings []bson.Raw
+
+	err = cursor.All(ctx, &listings)
+	if err != nil || len(listings) == 0 {
+		return err
+	}
+
+	_, err = store.listings.ReplaceOne(ctx, bson.M{"_id": id}, listings[0], options.Replace().SetUpsert(true))
+
+	return err
+}
+
+// Resync writes the listings of every item when the listings stream starts without resume token, i.e. when the
+// read model is enabled on an existing catalog or once its stream was reset, since the changes made until then
+// were not processed. The listings are merged with the aggregation framework so that the items are not loaded by
+// the service, replacing the listings synchronized before the merge, and the listings which were not merged are
+// deleted since their item was deleted meanwhile.
+func (store *ListingStore) Resync(ctx context.Context) error {
+	at := time.Now().UTC()
+
+	pipeline := append(listingPipeline(bson.M{}, at), bson.D{{Key: "$merge", Value: bson.M{
+		"into": constants.ItemListingsCollection,
+		"on":   "_id",
+		"whenMatched": mongo.Pipeline{{{Key: "$replaceWith", Value: bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$synced_at", "$$new.synced_at"}},
+			"$$ROOT",
+			"$$new",
+		}}}}},
+		"whenNotMatched": "insert",
+	}}})
+
+	cursor, err := store.items.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	err = cursor.Close(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.listings.DeleteMany(ctx, bson.M{"synced_at": bson.M{"$lt": at}})
+
+	return err
+}
+
+// listingPipeline returns the aggregation pipeline converting the items matching the given filter into their
+// listings, synchronized at the given time. The bookkeeping fields of the items are not listed.
+func listingPipeline(filter bson.M, at time.Time) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$unset", Value: "expiration_notified"}},
+		{{Key: "$set", Value: bson.M{
+			PriceBucketField: priceBucketExpression(),
+			"synced_at":      at,
+		}}},
+	}
+}
+
+// priceBucketExpression returns the aggregation expression of the lower bound of the price facet bucket of an item
+func priceBucketExpression() bson.M {
+	branches := bson.A{}
+
+	for i := len(PriceBucketBoundaries) - 1; i > 0; i-- {
+		branches = append(branches, bson.M{
+			"case": bson.M{"$gte": bson.A{"$price", PriceBucketBoundaries[i]}},
+			"then": PriceBucketBoundaries[i],
+		})
+	}
+
+	return bson.M{"$switch": bson.M{"branches": branches, "default": PriceBucketBoundaries[0]}}
+}
+
+// CreateItemListingsCollection creates the item listings collection in MongoDB database along with the indexes of
+// the listing queries. The listings are not validated nor unique since they are copies of the items.
+func CreateItemListingsCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	err := db.CreateCollection(context.Background(), constants.ItemListingsCollection)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: TenantField, Value: 1}}},
+		{Keys: bson.D{{Key: "name", Value: "text"}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "popularity.score", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: TenantField, Value: 1}, {Key: "featured_priority", Value: -1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"featured": true}),
+		},
+	}
+
+	_, err = db.Collection(constants.ItemListingsCollection).Indexes().CreateMany(context.Background(), indexModels)
+
+	return err
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPriceBucketExpression(t *testing.T) {
+	branches := priceBucketExpression()["$switch"].(bson.M)["branches"].(bson.A)
+
+	// The highest bucket is checked first and the lowest one is the default
+	if len(branches) != len(PriceBucketBoundaries)-1 {
+		t.Fatalf("want %d branches; got %d", len(PriceBucketBoundaries)-1, len(branches))
+	}
+
+	for i, branch := range branches {
+		want := PriceBucketBoundaries[len(PriceBucketBoundaries)-1-i]
+
+		if got := branch.(bson.M)["then"]; got != want {
+			t.Errorf("want branch %d to be %v; got %v", i, want, got)
+		}
+	}
+}
+
+func TestListingFacetsPipeline(t *testing.T) {
+	pipeline := ListingFacetsPipeline(bson.M{"tags": "potion"}, []string{"price"})
+
+	facets := pipeline[1][0].Value.(bson.M)
+	if _, ok := facets["tags"]; ok {
+		t.Errorf("want only the price facet; got %v", facets)
+	}
+
+	group := facets["price"].(bson.A)[0].(bson.M)["$group"].(bson.M)
+	if group["_id"] != "$"+PriceBucketField {
+		t.Errorf("want prices grouped by %q; got %v", "$"+PriceBucketField, group["_id"])
+	}
+}
@@ -0,0 +1,16 @@
+package data
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RandomItemsPipeline returns the aggregation pipeline picking at most the given number of random items
+// among the items matching the given filter. Since the sample does not start the pipeline, MongoDB sorts the
+// matching items randomly rather than using a random cursor so that the same item is never picked twice.
+func RandomItemsPipeline(filter bson.M, limit int) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sample", Value: bson.M{"size": limit}}},
+	}
+}
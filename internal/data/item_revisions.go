@@ -0,0 +1,179 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ItemRevision is a struct that holds an item as it was at one of its versions
+type ItemRevision struct {
+	ItemID    primitive.ObjectID `bson:"item_id"`
+	Version   int32              `bson:"version"`
+	Item      Item               `bson:"item"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// RevisionStore is a struct that keeps the revisions of the items
+type RevisionStore struct {
+	revisions *mongo.Collection
+}
+
+// NewRevisionStore creates a new revision store for the given database
+func NewRevisionStore(client *mongo.Client, databaseName string) *RevisionStore {
+	return &RevisionStore{
+		revisions: client.Database(databaseName).Collection(constants.ItemRevisionsCollection),
+	}
+}
+
+// Record stores the given item as the revision of its version. Recording a version twice keeps the first revision.
+func (store *RevisionStore) Record(ctx context.Context, item Item) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	revision := ItemRevision{
+		ItemID:    item.ID,
+		Version:   item.Version,
+		Item:      item,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	_, err := store.revisions.UpdateOne(
+		ctx,
+		bson.M{"item_id": item.ID, "version": item.Version},
+		bson.M{"$setOnInsert": revision},
+		options.Update().SetUpsert(true),
+	)
+
+	return err
+}
+
+// Get retrieves the given version of the item with the given id.
+// It returns database.ErrRecordNotFound if the version was not recorded.
+func (store *RevisionStore) Get(ctx context.Context, itemID primitive.ObjectID, version int32) (Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var revision ItemRevision
+
+	err := store.revisions.FindOne(ctx, bson.M{"item_id": itemID, "version": version}).Decode(&revision)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Item{}, database.ErrRecordNotFound
+		}
+
+		return Item{}, err
+	}
+
+	return revision.Item, nil
+}
+
+// PurgeBefore deletes the revisions recorded before the given time, including those of the deleted items,
+// and returns the number of deleted revisions. The revisions are inserted with a generated id, whose
+// timestamp is the time they were recorded, so that the purge uses the index of the ids.
+func (store *RevisionStore) PurgeBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := store.revisions.DeleteMany(ctx, bson.M{"_id": bson.M{"$lt": primitive.NewObjectIDFromTimestamp(before)}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// Rollback returns the given item with the content of the given revision. The identity of the item,
+// its version and the fields which are not edited along with the item (i.e. its rating) are kept.
+func Rollback(item Item, revision Item) Item {
+	item.Name = revision.Name
+	item.Description = revision.Description
+	item.Price = revision.Price
+	item.Tags = revision.Tags
+	item.Images = revision.Images
+	item.ExpiresAt = revision.ExpiresAt
+
+	return item
+}
+
+// RevisionRepository is an items repository which records every version of the items it writes.
+// An item and its revision are written within the same transaction, when transactions are enabled.
+type RevisionRepository struct {
+	Repository[primitive.ObjectID, Item]
+	store        *RevisionStore
+	transactions *Transactions
+}
+
+// NewRevisionRepository creates a new items repository recording the revisions of the given repository
+func NewRevisionRepository(repository Repository[primitive.ObjectID, Item], store *RevisionStore, transactions *Transactions) Repository[primitive.ObjectID, Item] {
+	return &RevisionRepository{
+		Repository:   repository,
+		store:        store,
+		transactions: transactions,
+	}
+}
+
+// Create inserts a new item and records its first revision
+func (repo RevisionRepository) Create(ctx context.Context, item Item) (*primitive.ObjectID, error) {
+	var id *primitive.ObjectID
+
+	err := repo.transactions.Run(ctx, func(ctx context.Context) error {
+		var err error
+
+		id, err = repo.Repository.Create(ctx, item)
+		if err != nil {
+			return err
+		}
+
+		item.ID = *id
+
+		return repo.store.Record(ctx, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return id, nil
+}
+
+// Update updates an item and records its new revision
+func (repo RevisionRepository) Update(ctx context.Context, item Item) error {
+	return repo.transactions.Run(ctx, func(ctx context.Context) error {
+		err := repo.Repository.Update(ctx, item)
+		if err != nil {
+			return err
+		}
+
+		// The version of the item is incremented by the update
+		return repo.store.Record(ctx, item.SetVersion(item.Version+1))
+	})
+}
+
+// CreateItemRevisionsCollection creates item revisions collection in MongoDB database.
+// The revisions are not validated since they hold the items as they were.
+func CreateItemRevisionsCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	err := db.CreateCollection(context.Background(), constants.ItemRevisionsCollection)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	// A version of an item is only recorded once
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "item_id", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err = db.Collection(constants.ItemRevisionsCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Weights of the criteria of the similarity score of two items
+const (
+	sharedTagWeight  = 2
+	sharedWordWeight = 1
+)
+
+// similarPriceRatio is the maximum relative price difference of two items considered similar
+// on price alone (i.e. 0.5 matches the items costing between 5 and 15 for an item costing 10)
+const similarPriceRatio = 0.5
+
+// nameWordRX matches the words of an item name
+var nameWordRX = regexp.MustCompile("[a-z0-9]+")
+
+// SimilarItem is a struct that defines an item similar to another item along with its similarity score
+type SimilarItem struct {
+	Item  `bson:",inline"`
+	Score float64 `json:"score" bson:"score"`
+}
+
+// nameWords returns the distinct lower case words of an item name which are at least 3 characters long
+func nameWords(name string) []string {
+	words := []string{}
+	seen := make(map[string]bool)
+
+	for _, word := range nameWordRX.FindAllString(strings.ToLower(name), -1) {
+		if len(word) < 3 || seen[word] {
+			continue
+		}
+
+		seen[word] = true
+		words = append(words, word)
+	}
+
+	return words
+}
+
+// SimilarItemsPipeline returns the aggregation pipeline retrieving the items which are the most similar
+// to the given item and not expired at the given time. Items are scored on their shared tags, the words
+// shared by their names and the closeness of their prices. Items sharing none of them are not returned.
+func SimilarItemsPipeline(item Item, now time.Time, limit int) mongo.Pipeline {
+	tags := item.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	words := nameWords(item.Name)
+
+	// Candidates share a tag, a name word or have a close price
+	candidates := bson.A{
+		bson.M{"tags": bson.M{"$in": tags}},
+		bson.M{"price": bson.M{
+			"$gte": item.Price * (1 - similarPriceRatio),
+			"$lte": item.Price * (1 + similarPriceRatio),
+		}},
+	}
+
+	if len(words) != 0 {
+		quoted := make([]string, 0, len(words))
+		for _, word := range words {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+
+		candidates = append(candidates, bson.M{"name": primitive.Regex{
+			Pattern: fmt.Sprintf(`\b(%s)\b`, strings.Join(quoted, "|")),
+			Options: "i",
+		}})
+	}
+
+	// Lower case words of the candidate name
+	candidateWords := bson.M{"$map": bson.M{
+		"input": bson.M{"$regexFindAll": bson.M{"input": bson.M{"$toLower": "$name"}, "regex": nameWordRX.String()}},
+		"as":    "word",
+		"in":    "$$word.match",
+	}}
+
+	// 1 for the same price, down to 0 for a price twice as high (or lower)
+	priceCloseness := bson.M{"$max": bson.A{
+		0,
+		bson.M{"$subtract": bson.A{
+			1,
+			bson.M{"$divide": bson.A{bson.M{"$abs": bson.M{"$subtract": bson.A{"$price", item.Price}}}, item.Price}},
+		}},
+	}}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"_id": bson.M{"$ne": item.ID},
+			"$and": bson.A{
+				bson.M{"$or": NotExpiredFilter(now)},
+				bson.M{"$or": candidates},
+			},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"score": bson.M{"$add": bson.A{
+				bson.M{"$multiply": bson.A{
+					sharedTagWeight,
+					bson.M{"$size": bson.M{"$setIntersection": bson.A{bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}, tags}}},
+				}},
+				bson.M{"$multiply": bson.A{
+					sharedWordWeight,
+					bson.M{"$size": bson.M{"$setIntersection": bson.A{candidateWords, words}}},
+				}},
+				priceCloseness,
+			}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+}
@@ -0,0 +1,62 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNameWords(t *testing.T) {
+	tests := []struct {
+		testName      string
+		name          string
+		expectedWords []string
+	}{
+		{"Single word", "Potion", []string{"potion"}},
+		{"Punctuation and short words", "Hi-Potion of the Sea", []string{"potion", "the", "sea"}},
+		{"Duplicated words", "Potion potion", []string{"potion"}},
+		{"No word", "X", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			words := nameWords(tt.name)
+
+			if !reflect.DeepEqual(words, tt.expectedWords) {
+				t.Errorf("want %v; got %v", tt.expectedWords, words)
+			}
+		})
+	}
+}
+
+func TestSimilarItemsPipeline(t *testing.T) {
+	item := Item{ID: primitive.NewObjectID(), Name: "Mega Potion", Price: 10}
+
+	pipeline := SimilarItemsPipeline(item, time.Now(), 5)
+
+	match := pipeline[0][0].Value.(bson.M)
+	if !reflect.DeepEqual(match["_id"], bson.M{"$ne": item.ID}) {
+		t.Errorf("want item itself to be excluded; got %v", match["_id"])
+	}
+
+	candidates := match["$and"].(bson.A)[1].(bson.M)["$or"].(bson.A)
+	if len(candidates) != 3 {
+		t.Fatalf("want %d candidate criteria; got %d", 3, len(candidates))
+	}
+
+	if !reflect.DeepEqual(candidates[0], bson.M{"tags": bson.M{"$in": []string{}}}) {
+		t.Errorf("want items without tags to match no tag; got %v", candidates[0])
+	}
+
+	expectedName := bson.M{"name": primitive.Regex{Pattern: `\b(mega|potion)\b`, Options: "i"}}
+	if !reflect.DeepEqual(candidates[2], expectedName) {
+		t.Errorf("want %v; got %v", expectedName, candidates[2])
+	}
+
+	if !reflect.DeepEqual(pipeline[len(pipeline)-1], bson.D{{Key: "$limit", Value: 5}}) {
+		t.Errorf("want last stage to limit the results; got %v", pipeline[len(pipeline)-1])
+	}
+}
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PriceStats is a struct that holds the price statistics of a group of items
+type PriceStats struct {
+	Tag         string  `json:"tag,omitempty" bson:"_id"`
+	Count       int     `json:"count" bson:"count"`
+	MinPrice    float64 `json:"min_price" bson:"min_price"`
+	MaxPrice    float64 `json:"max_price" bson:"max_price"`
+	AvgPrice    float64 `json:"avg_price" bson:"avg_price"`
+	MedianPrice float64 `json:"median_price" bson:"median_price"`
+}
+
+// ItemStats is a struct that holds the price statistics of all the matching items
+// along with the statistics of each group of items when they are grouped
+type ItemStats struct {
+	Overall []PriceStats `bson:"overall"`
+	Groups  []PriceStats `bson:"groups"`
+}
+
+// ItemStatsPipeline returns the aggregation pipeline computing the price statistics of the items
+// matching the given filter. When groupByTag is true, statistics are also computed for each tag.
+// The items have no category nor rarity field, both being tags, so the tags are their only grouping.
+func ItemStatsPipeline(filter bson.M, groupByTag bool) mongo.Pipeline {
+	facets := bson.M{
+		"overall": priceStatsStages(nil),
+	}
+
+	if groupByTag {
+		groupStages := bson.A{bson.M{"$unwind": "$tags"}}
+		groupStages = append(groupStages, priceStatsStages("$tags")...)
+		groupStages = append(groupStages, bson.M{"$sort": bson.M{"_id": 1}})
+
+		facets["groups"] = groupStages
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		// Sort prices so that they are pushed in order when computing the median
+		{{Key: "$sort", Value: bson.M{"price": 1}}},
+		{{Key: "$facet", Value: facets}},
+	}
+}
+
+// priceStatsStages returns the aggregation stages computing the price statistics
+// of the items grouped by the given expression (nil to compute them on all the items)
+func priceStatsStages(groupBy any) bson.A {
+	size := bson.M{"$size": "$prices"}
+	middle := bson.M{"$toInt": bson.M{"$floor": bson.M{"$divide": bson.A{size, 2}}}}
+
+	// Middle value for an odd number of prices and average of the two middle values otherwise
+	median := bson.M{
+		"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$mod": bson.A{size, 2}}, 1}},
+			bson.M{"$arrayElemAt": bson.A{"$prices", middle}},
+			bson.M{"$avg": bson.A{
+				bson.M{"$arrayElemAt": bson.A{"$prices", bson.M{"$subtract": bson.A{middle, 1}}}},
+				bson.M{"$arrayElemAt": bson.A{"$prices", middle}},
+			}},
+		},
+	}
+
+	return bson.A{
+		bson.M{"$group": bson.M{
+			"_id":       groupBy,
+			"count":     bson.M{"$sum": 1},
+			"min_price": bson.M{"$min": "$price"},
+			"max_price": bson.M{"$max": "$price"},
+			"avg_price": bson.M{"$avg": "$price"},
+			"prices":    bson.M{"$push": "$price"},
+		}},
+		bson.M{"$project": bson.M{
+			"count":        1,
+			"min_price":    1,
+			"max_price":    1,
+			"avg_price":    1,
+			"median_price": median,
+		}},
+	}
+}
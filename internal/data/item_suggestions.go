@@ -0,0 +1,30 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ItemSuggestion is a struct that holds an item name suggested for a search prefix
+type ItemSuggestion struct {
+	Name string `bson:"name"`
+}
+
+// ItemSuggestionsPipeline returns the aggregation pipeline retrieving the names of the items
+// starting with the given prefix (ignoring case). The lowercased prefix is matched case-sensitively
+// against the lowercased names so that the query is a range scan of their index.
+func ItemSuggestionsPipeline(prefix string, limit int) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"name_lower": primitive.Regex{Pattern: fmt.Sprintf("^%s", regexp.QuoteMeta(strings.ToLower(prefix)))},
+		}}},
+		{{Key: "$sort", Value: bson.M{"name_lower": 1}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "name": 1}}},
+	}
+}
@@ -2,9 +2,16 @@ package data
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
 	"github.com/PlayEconomy37/Play.Common/validator"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,15 +19,57 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// AutoTag is a struct that records which auto-tagging rule added a tag to an item
+type AutoTag struct {
+	Tag  string `json:"tag" bson:"tag"`
+	Rule string `json:"rule" bson:"rule"`
+}
+
+// ItemImage is a struct that defines an image of an item uploaded to the object storage
+type ItemImage struct {
+	Key         string      `json:"key" bson:"key"` // Key of the object in the object storage
+	URL         string      `json:"url" bson:"url"` // URL serving the image, resolved when the upload was confirmed
+	ContentType string      `json:"content_type" bson:"content_type"`
+	Size        int64       `json:"size" bson:"size"`
+	UploadedAt  time.Time   `json:"uploaded_at" bson:"uploaded_at"`
+	Thumbnails  []Thumbnail `json:"thumbnails,omitempty" bson:"thumbnails,omitempty"` // Generated asynchronously after the upload
+}
+
+// UUIDRegex is a regular expression used for checking the format of the identifiers supplied by clients
+var UUIDRegex = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+// NormalizeExternalID returns the canonical form of the given external id. UUIDs are accepted in any case but
+// stored and looked up in lowercase, since the unique index of the external ids is case-sensitive.
+func NormalizeExternalID(externalID string) string {
+	return strings.ToLower(externalID)
+}
+
+// MaxTags is the maximum number of tags of an item, auto tags included
+const MaxTags = 20
+
 // Item is a struct that defines an item in our application
 type Item struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name        string             `json:"name" bson:"name"`
-	Description string             `json:"description" bson:"description"`
-	Price       float64            `json:"price" bson:"price"`
-	Version     int32              `json:"version" bson:"version"`
-	CreatedAt   time.Time          `json:"-" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"-" bson:"updated_at"`
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID         string             `json:"-" bson:"tenant_id,omitempty"`                       // Tenant the item belongs to
+	ExternalID       string             `json:"external_id,omitempty" bson:"external_id,omitempty"` // Optional identifier supplied by the client
+	Name             string             `json:"name" bson:"name"`
+	Description      string             `json:"description" bson:"description"`
+	DescriptionHTML  string             `json:"description_html,omitempty" bson:"-"` // Rendered from the Markdown description when requested
+	Price            float64            `json:"price" bson:"price"`
+	Tags             []string           `json:"tags" bson:"tags"`
+	AutoTags         []AutoTag          `json:"auto_tags" bson:"auto_tags"`
+	Images           []ItemImage        `json:"images,omitempty" bson:"images,omitempty"`
+	FlaggedForReview bool               `json:"flagged_for_review,omitempty" bson:"flagged_for_review,omitempty"` // Set when the item contains banned words
+	Featured         bool               `json:"featured" bson:"featured,omitempty"`
+	FeaturedPriority int32              `json:"featured_priority,omitempty" bson:"featured_priority,omitempty"` // Featured items with a higher priority come first
+	Rating           *ItemRating        `json:"rating,omitempty" bson:"rating,omitempty"`                       // Aggregated from the published reviews
+	Favorited        *bool              `json:"favorited,omitempty" bson:"-"`                                   // Whether the authenticated user added the item to their favorites
+	Version          int32              `json:"version" bson:"version"`
+	ExpiresAt        *time.Time         `json:"expires_at,omitempty" bson:"expires_at"`           // Items without expiration date never expire
+	CreatedBy        int64              `json:"created_by,omitempty" bson:"created_by,omitempty"` // ID of the user who created the item, if known
+	UpdatedBy        int64              `json:"updated_by,omitempty" bson:"updated_by,omitempty"` // ID of the user who last updated the item, if known
+	CreatedAt        time.Time          `json:"-" bson:"created_at"`
+	UpdatedAt        time.Time          `json:"-" bson:"updated_at"`
 }
 
 // GetID returns the id of an item.
@@ -43,17 +92,143 @@ func (i Item) SetVersion(version int32) Item {
 	return i
 }
 
+// ETag returns the entity tag of the current version of an item
+func (i Item) ETag() string {
+	return fmt.Sprintf(`"%s-%d"`, i.ID.Hex(), i.Version)
+}
+
+// MarshalBSON encodes an item along with its lowercased name, which is stored on every write
+// so that the name suggestions can match a prefix without ignoring case
+func (i Item) MarshalBSON() ([]byte, error) {
+	type document Item
+
+	return bson.Marshal(struct {
+		Item      document `bson:",inline"`
+		NameLower string   `bson:"name_lower"`
+	}{Item: document(i), NameLower: strings.ToLower(i.Name)})
+}
+
+// FormatPrice formats a price bound for validation messages (i.e. 0.1 or 1000)
+func FormatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
+
+// HasMaxDecimals returns true if the given price has at most the given number of decimal places
+func HasMaxDecimals(price float64, maxDecimals int) bool {
+	scaled := price * math.Pow10(maxDecimals)
+
+	// Tolerate the representation error of binary floating point numbers (i.e. 0.07 * 100)
+	return math.Abs(scaled-math.Round(scaled)) < 1e-6
+}
+
+// AdjustPrice returns the given price changed by the given percentage (i.e. -10 for a 10% discount),
+// rounded to the given number of decimal places
+func AdjustPrice(price float64, percent float64, maxDecimals int) float64 {
+	scale := math.Pow10(maxDecimals)
+
+	return math.Round(price*(1+percent/100)*scale) / scale
+}
+
 // ValidateItem runs validation checks on the `Item` struct
-func ValidateItem(v *validator.Validator, item Item) {
-	v.Check(item.Name != "", "name", "must be provided")
-	v.Check(item.Description != "", "name", "must be provided")
-	v.Check(validator.Between(item.Price, 0.1, 1000.0), "price", "must be greater or equal to 0.1 and lower or equal to 1000")
+func ValidateItem(v *validation.Validator, item Item, pricing settings.Pricing, moderation settings.Moderation) {
+	v.Check(item.Name != "", "name", "required", "must be provided")
+	v.Check(item.Description != "", "name", "required", "must be provided")
+	v.Check(
+		validator.Between(item.Price, pricing.MinPrice, pricing.MaxPrice),
+		"price",
+		"out_of_range",
+		fmt.Sprintf("must be greater or equal to %s and lower or equal to %s", FormatPrice(pricing.MinPrice), FormatPrice(pricing.MaxPrice)),
+	)
+	v.Check(HasMaxDecimals(item.Price, pricing.MaxDecimals), "price", "too_precise", fmt.Sprintf("must not have more than %d decimal places", pricing.MaxDecimals))
+	v.Check(len(item.Tags) <= MaxTags, "tags", "too_many", fmt.Sprintf("must not contain more than %d tags", MaxTags))
+	v.Check(validator.NoDuplicates(item.Tags), "tags", "duplicate", "must not contain duplicate values")
+
+	if item.ExternalID != "" {
+		v.Check(validator.Matches(item.ExternalID, UUIDRegex), "external_id", "invalid_format", "must be a valid UUID")
+	}
+
+	for _, tag := range item.Tags {
+		v.Check(validator.NotBlank(tag), "tags", "blank", "must not contain empty values")
+		v.Check(validator.MaxCharacters(tag, 30), "tags", "too_long", "must not contain values longer than 30 characters")
+	}
+
+	validateBannedWords(v, item, moderation)
+}
+
+// IsExpired returns true if the item has an expiration date which is not later than the given time
+func (i Item) IsExpired(now time.Time) bool {
+	return i.ExpiresAt != nil && !i.ExpiresAt.After(now)
+}
+
+// NotExpiredFilter returns the MongoDB filter matching the items which are not expired at the given time
+func NotExpiredFilter(now time.Time) bson.A {
+	return bson.A{
+		bson.M{"expires_at": nil},
+		bson.M{"expires_at": bson.M{"$gt": now}},
+	}
+}
+
+// CreateItemsCollection creates items collection in MongoDB database.
+// The validation schema enforces the given pricing rules, the given fields are indexed as unique
+// and the expired items are purged according to the given expiration settings.
+func CreateItemsCollection(
+	client *mongo.Client,
+	databaseName string,
+	pricing settings.Pricing,
+	constraints settings.Constraints,
+	expiration settings.Expiration,
+) error {
+	return createItemsCollection(client, databaseName, constants.ItemsCollection, pricing, constraints, expiration)
 }
 
-// CreateItemsCollection creates items collection in MongoDB database
-func CreateItemsCollection(client *mongo.Client, databaseName string) error {
+// CreateSelftestItemsCollection creates the sandbox collection used by the self-test
+// in MongoDB database. It shares the schema and indexes of the items collection.
+func CreateSelftestItemsCollection(
+	client *mongo.Client,
+	databaseName string,
+	pricing settings.Pricing,
+	constraints settings.Constraints,
+	expiration settings.Expiration,
+) error {
+	return createItemsCollection(client, databaseName, constants.SelftestItemsCollection, pricing, constraints, expiration)
+}
+
+// createItemsCollection creates a collection holding items in MongoDB database
+func createItemsCollection(
+	client *mongo.Client,
+	databaseName string,
+	collectionName string,
+	pricing settings.Pricing,
+	constraints settings.Constraints,
+	expiration settings.Expiration,
+) error {
 	db := client.Database(databaseName)
 
+	// Create collection
+	opts := options.CreateCollection().SetValidator(itemsValidator(pricing))
+	err := db.CreateCollection(context.Background(), collectionName, opts)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	// Create unique, text and TTL indexes
+	indexModels := []mongo.IndexModel{}
+	for _, spec := range itemIndexSpecs(constraints, expiration) {
+		indexModels = append(indexModels, spec.model())
+	}
+
+	_, err = db.Collection(collectionName).Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// itemsValidator returns the validator of the collections holding items.
+// The validation schema enforces the given pricing rules.
+func itemsValidator(pricing settings.Pricing) bson.M {
 	// JSON validation schema
 	jsonSchema := bson.M{
 		"bsonType":             "object",
@@ -64,24 +239,86 @@ func CreateItemsCollection(client *mongo.Client, databaseName string) error {
 				"bsonType":    "objectId",
 				"description": "Document ID",
 			},
+			"tenant_id": bson.M{
+				"bsonType":    "string",
+				"description": "Tenant the item belongs to",
+			},
+			"created_by": bson.M{
+				"bsonType":    "long",
+				"description": "ID of the user who created the item",
+			},
+			"updated_by": bson.M{
+				"bsonType":    "long",
+				"description": "ID of the user who last updated the item",
+			},
+			"external_id": bson.M{
+				"bsonType":    "string",
+				"description": "Identifier of the item supplied by the client",
+			},
 			"name": bson.M{
 				"bsonType":    "string",
 				"description": "Name of the item",
 			},
+			"name_lower": bson.M{
+				"bsonType":    "string",
+				"description": "Lowercased name of the item, matched by the name suggestions",
+			},
 			"description": bson.M{
 				"bsonType":    "string",
 				"description": "Description of the item",
 			},
 			"price": bson.M{
 				"bsonType":    "double",
-				"minimum":     0.1,
+				"minimum":     pricing.MinPrice,
+				"maximum":     pricing.MaxPrice,
 				"description": "Price of the item",
 			},
+			"tags": bson.M{
+				"bsonType":    bson.A{"array", "null"},
+				"items":       bson.M{"bsonType": "string"},
+				"description": "Tags of the item",
+			},
+			"auto_tags": bson.M{
+				"bsonType":    bson.A{"array", "null"},
+				"description": "Tags added by auto-tagging rules along with the rule which added them",
+			},
+			"images": bson.M{
+				"bsonType":    "array",
+				"description": "Images of the item uploaded to the object storage",
+			},
+			"flagged_for_review": bson.M{
+				"bsonType":    "bool",
+				"description": "Whether the item contains banned words and must be reviewed",
+			},
+			"featured": bson.M{
+				"bsonType":    "bool",
+				"description": "Whether the item is featured on the storefront",
+			},
+			"featured_priority": bson.M{
+				"bsonType":    "int",
+				"description": "Priority of the featured item, the highest first",
+			},
+			"rating": bson.M{
+				"bsonType":    "object",
+				"description": "Average rating and number of the published reviews",
+			},
 			"version": bson.M{
 				"bsonType":    "int",
 				"minimum":     1,
 				"description": "Document version",
 			},
+			"expires_at": bson.M{
+				"bsonType":    bson.A{"date", "null"},
+				"description": "Expiration date",
+			},
+			"expiration_notified": bson.M{
+				"bsonType":    "date",
+				"description": "Expiration date for which the ItemExpired event was published",
+			},
+			"popularity": bson.M{
+				"bsonType":    "object",
+				"description": "Views and purchases counted since the creation of the item",
+			},
 			"created_at": bson.M{
 				"bsonType":    "date",
 				"description": "Creation date",
@@ -93,37 +330,7 @@ func CreateItemsCollection(client *mongo.Client, databaseName string) error {
 		},
 	}
 
-	validator := bson.M{
+	return bson.M{
 		"$jsonSchema": jsonSchema,
 	}
-
-	// Create collection
-	opts := options.CreateCollection().SetValidator(validator)
-	err := db.CreateCollection(context.Background(), constants.ItemsCollection, opts)
-	if err != nil {
-		// Returns error if collection already exists so we ignore it
-		return nil
-	}
-
-	// Create unique and text indexes
-	indexModels := []mongo.IndexModel{
-		{
-			Keys:    bson.M{"name": 1},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys:    bson.M{"description": 1},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys: bson.M{"name": "text"},
-		},
-	}
-
-	_, err = db.Collection(constants.ItemsCollection).Indexes().CreateMany(context.Background(), indexModels)
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestHasMaxDecimals(t *testing.T) {
+	tests := []struct {
+		price       float64
+		maxDecimals int
+		expected    bool
+	}{
+		{5, 0, true},
+		{5.5, 0, false},
+		{0.07, 2, true},
+		{19.99, 2, true},
+		{5.125, 2, false},
+		{0.1, 1, true},
+	}
+
+	for _, tt := range tests {
+		if HasMaxDecimals(tt.price, tt.maxDecimals) != tt.expected {
+			t.Errorf("want HasMaxDecimals(%v, %d) to be %t", tt.price, tt.maxDecimals, tt.expected)
+		}
+	}
+}
+
+func TestAdjustPrice(t *testing.T) {
+	tests := []struct {
+		price       float64
+		percent     float64
+		maxDecimals int
+		expected    float64
+	}{
+		{10, -10, 2, 9},
+		{19.99, 15, 2, 22.99},
+		{5, 33, 0, 7},
+		{0.5, -50, 1, 0.3},
+	}
+
+	for _, tt := range tests {
+		if got := AdjustPrice(tt.price, tt.percent, tt.maxDecimals); got != tt.expected {
+			t.Errorf("want AdjustPrice(%v, %v, %d) to be %v; got %v", tt.price, tt.percent, tt.maxDecimals, tt.expected, got)
+		}
+	}
+}
+
+func TestNormalizeExternalID(t *testing.T) {
+	tests := []struct {
+		externalID string
+		expected   string
+	}{
+		{"3F2504E0-4F89-11D3-9A0C-0305E82C3301", "3f2504e0-4f89-11d3-9a0c-0305e82c3301"},
+		{"3f2504e0-4f89-11d3-9a0c-0305e82c3301", "3f2504e0-4f89-11d3-9a0c-0305e82c3301"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeExternalID(tt.externalID); got != tt.expected {
+			t.Errorf("want NormalizeExternalID(%q) to be %q; got %q", tt.externalID, tt.expected, got)
+		}
+	}
+}
+
+func TestItemMarshalBSON(t *testing.T) {
+	document, err := bson.Marshal(Item{Name: "Mega Potion", Price: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields struct {
+		Name      string  `bson:"name"`
+		NameLower string  `bson:"name_lower"`
+		Price     float64 `bson:"price"`
+	}
+
+	err = bson.Unmarshal(document, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fields.Name != "Mega Potion" || fields.NameLower != "mega potion" || fields.Price != 5 {
+		t.Errorf("want the item fields along with the lowercased name; got %+v", fields)
+	}
+}
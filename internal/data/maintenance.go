@@ -0,0 +1,72 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maintenanceID is the id of the document holding the maintenance mode
+const maintenanceID = "maintenance"
+
+// MaintenanceStatus is a struct that holds the state of the maintenance mode
+type MaintenanceStatus struct {
+	Enabled bool       `json:"enabled" bson:"enabled"`
+	Since   *time.Time `json:"since,omitempty" bson:"since,omitempty"` // Time at which the maintenance mode was enabled
+}
+
+// MaintenanceStore is a struct that stores the maintenance mode so that it is shared by every instance
+type MaintenanceStore struct {
+	collection *mongo.Collection
+}
+
+// NewMaintenanceStore creates a new maintenance mode store for the given database
+func NewMaintenanceStore(client *mongo.Client, databaseName string) *MaintenanceStore {
+	return &MaintenanceStore{collection: client.Database(databaseName).Collection(constants.MaintenanceCollection)}
+}
+
+// Get returns the stored state of the maintenance mode. The maintenance mode is disabled until it is first set.
+func (store *MaintenanceStore) Get(ctx context.Context) (MaintenanceStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var status MaintenanceStatus
+
+	err := store.collection.FindOne(ctx, bson.M{"_id": maintenanceID}).Decode(&status)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return MaintenanceStatus{}, nil
+	}
+
+	return status, err
+}
+
+// Set enables or disables the maintenance mode and returns its new state.
+// The time at which it was enabled is kept when it is enabled again.
+func (store *MaintenanceStore) Set(ctx context.Context, enabled bool) (MaintenanceStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	update := mongo.Pipeline{{{Key: "$unset", Value: "since"}}, {{Key: "$set", Value: bson.M{"enabled": false}}}}
+	if enabled {
+		update = mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"enabled": true,
+			"since":   bson.M{"$ifNull": bson.A{"$since", time.Now().UTC()}},
+		}}}}
+	}
+
+	var status MaintenanceStatus
+
+	err := store.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": maintenanceID},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&status)
+
+	return status, err
+}
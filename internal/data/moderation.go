@@ -0,0 +1,51 @@
+package data
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+)
+
+// ContainsBannedWords returns true if the given text contains one of the given banned words.
+// The words are matched as whole words regardless of the case so that a banned "ass" does not match "class".
+func ContainsBannedWords(text string, bannedWords []string) bool {
+	if len(bannedWords) == 0 {
+		return false
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, word := range words {
+		for _, bannedWord := range bannedWords {
+			if word == strings.ToLower(bannedWord) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// validateBannedWords checks that the name and the description of the given item do not contain banned words
+// unless the items containing them are flagged for review instead
+func validateBannedWords(v *validation.Validator, item Item, moderation settings.Moderation) {
+	if moderation.Action != "reject" {
+		return
+	}
+
+	v.Check(!ContainsBannedWords(item.Name, moderation.BannedWords), "name", "banned", "must not contain banned words")
+	v.Check(!ContainsBannedWords(item.Description, moderation.BannedWords), "description", "banned", "must not contain banned words")
+}
+
+// FlagForReview returns the given item flagged for review if its name or its description contains banned words
+// and the moderation action is "review". The flag is cleared once the banned words are removed.
+func FlagForReview(item Item, moderation settings.Moderation) Item {
+	item.FlaggedForReview = moderation.Action == "review" &&
+		(ContainsBannedWords(item.Name, moderation.BannedWords) || ContainsBannedWords(item.Description, moderation.BannedWords))
+
+	return item
+}
@@ -0,0 +1,76 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+)
+
+func TestContainsBannedWords(t *testing.T) {
+	bannedWords := []string{"scam", "Ass"}
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Restores a small amount of health", false},
+		{"Not a scam", true},
+		{"SCAM potion", true},
+		{"Kick-ass sword", true},
+		{"Sword of the assassin class", false},
+		{"Scammer's potion", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := ContainsBannedWords(tt.text, bannedWords); got != tt.want {
+				t.Errorf("want %t; got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestModeration(t *testing.T) {
+	item := Item{Name: "Potion", Description: "Not a scam", Price: 5}
+	pricing := settings.Pricing{MinPrice: 0.1, MaxPrice: 1000, MaxDecimals: 2}
+
+	t.Run("Reject", func(t *testing.T) {
+		moderation := settings.Moderation{BannedWords: []string{"scam"}, Action: "reject"}
+
+		v := validation.New()
+		ValidateItem(v, item, pricing, moderation)
+
+		if v.Errors["description"] != "must not contain banned words" {
+			t.Errorf("want banned words error; got %v", v.Errors)
+		}
+
+		if FlagForReview(item, moderation).FlaggedForReview {
+			t.Error("want item not flagged; got flagged")
+		}
+	})
+
+	t.Run("Review", func(t *testing.T) {
+		moderation := settings.Moderation{BannedWords: []string{"scam"}, Action: "review"}
+
+		v := validation.New()
+		ValidateItem(v, item, pricing, moderation)
+
+		if v.HasErrors() {
+			t.Errorf("want no errors; got %v", v.Errors)
+		}
+
+		flagged := FlagForReview(item, moderation)
+		if !flagged.FlaggedForReview {
+			t.Error("want item flagged; got not flagged")
+		}
+
+		// The flag is cleared once the banned words are removed
+		flagged.Description = "Restores a small amount of health"
+
+		if FlagForReview(flagged, moderation).FlaggedForReview {
+			t.Error("want flag cleared; got flagged")
+		}
+	})
+}
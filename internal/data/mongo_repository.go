@@ -0,0 +1,399 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultTimeout is a constant that defines the default context timeout for database operations
+const defaultTimeout = 3 * time.Second
+
+// Repository is a generic MongoDB repository interface used by the catalog collections.
+// It extends the common repository interface with listing options and aggregations.
+type Repository[K any, T types.MongoEntity[K, T]] interface {
+	types.MongoRepository[K, T]
+	GetAllWithOptions(ctx context.Context, filter primitive.M, findOpts filters.Filters, listOpts ListOptions) ([]T, Metadata, error)
+	Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error
+	Export(ctx context.Context, snapshot bool, fn func(document Document[T]) error) error
+	Restore(ctx context.Context, documents []Document[T]) error
+}
+
+// Document is an entity along with the raw document it was decoded from, if any. The raw document
+// also holds the fields written by the store which are not mapped by the entity (i.e. the popularity
+// counters of the items) so that exporting and restoring the documents does not lose them.
+type Document[T any] struct {
+	Entity T
+	Raw    bson.Raw
+}
+
+// replacement returns the fields of the entity along with the fields of the raw document which are
+// not mapped by the entity. The entity wins over the raw document so that its changes are written.
+func (document Document[T]) replacement() (bson.D, error) {
+	var fields bson.D
+
+	entity, err := bson.Marshal(document.Entity)
+	if err == nil {
+		err = bson.Unmarshal(entity, &fields)
+	}
+
+	if err != nil || document.Raw == nil {
+		return fields, err
+	}
+
+	mapped := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		mapped[field.Key] = true
+	}
+
+	elements, err := document.Raw.Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, element := range elements {
+		if !mapped[element.Key()] {
+			fields = append(fields, bson.E{Key: element.Key(), Value: element.Value()})
+		}
+	}
+
+	return fields, nil
+}
+
+// ListOptions is a struct that holds the optional settings of a listing query
+type ListOptions struct {
+	Projection bson.M // Fields to retrieve, nil to retrieve whole documents
+	SkipCount  bool   // Skip the count of the matching documents and only report whether there are more pages
+}
+
+// Metadata is a struct that holds the pagination metadata of a listing query.
+// The total number of records and the last page are unknown when the count is skipped,
+// HasMore is set instead.
+type Metadata struct {
+	filters.Metadata
+	HasMore *bool `json:"has_more,omitempty"`
+}
+
+// MongoRepository is a generic MongoDB repository struct used by the catalog collections.
+// It relies on the common MongoDB repository for deletions and implements its own read and write paths.
+// The reads use the read preference of their context, if any, and the primary otherwise.
+type MongoRepository[K any, T types.MongoEntity[K, T]] struct {
+	types.MongoRepository[K, T]
+	collection *mongo.Collection
+}
+
+// NewMongoRepository creates a new MongoDB repository
+func NewMongoRepository[K any, T types.MongoEntity[K, T]](client *mongo.Client, databaseName, collectionName string) Repository[K, T] {
+	return &MongoRepository[K, T]{
+		MongoRepository: database.NewMongoRepository[K, T](client, databaseName, collectionName),
+		collection:      client.Database(databaseName).Collection(collectionName),
+	}
+}
+
+// GetByID retrieves a specific document from the collection by its id
+func (repo MongoRepository[K, T]) GetByID(ctx context.Context, id K) (T, error) {
+	return repo.GetByFilter(ctx, bson.M{"_id": id})
+}
+
+// GetByFilter retrieves a specific document from the collection by the given filter
+func (repo MongoRepository[K, T]) GetByFilter(ctx context.Context, filter primitive.M) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var entity T
+
+	err := repo.readCollection(ctx).FindOne(ctx, filter).Decode(&entity)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return entity, database.ErrRecordNotFound
+	}
+
+	return entity, err
+}
+
+// GetAll retrieves all documents from the collection matching the given filter.
+// The total count and the requested page are queried concurrently and share the same
+// context so that a failure of one of the queries cancels the other one.
+func (repo MongoRepository[K, T]) GetAll(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+) ([]T, filters.Metadata, error) {
+	items, metadata, err := repo.GetAllWithOptions(ctx, filter, findOpts, ListOptions{})
+
+	return items, metadata.Metadata, err
+}
+
+// GetAllWithOptions behaves like GetAll with the given listing options
+func (repo MongoRepository[K, T]) GetAllWithOptions(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+	listOpts ListOptions,
+) ([]T, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if listOpts.SkipCount {
+		return repo.getPageWithoutCount(ctx, filter, findOpts, listOpts)
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+
+	var items []T
+	var count int64
+
+	// Get total number of records that exist in database with given filters
+	group.Go(func() error {
+		var err error
+		count, err = repo.readCollection(ctx).CountDocuments(ctx, filter)
+
+		return err
+	})
+
+	// Get requested page
+	group.Go(func() error {
+		var err error
+		items, err = repo.findPage(ctx, filter, findOpts, listOpts, findOpts.Limit())
+
+		return err
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	// Generate a Metadata struct, passing in the total document count and pagination
+	// parameters from the client
+	metadata := filters.CalculateMetadata(int(count), findOpts.Page, findOpts.PageSize)
+
+	return items, Metadata{Metadata: metadata}, nil
+}
+
+// getPageWithoutCount retrieves the documents of the requested page without counting the matching
+// documents. One extra document is requested to find out whether there is a next page.
+func (repo MongoRepository[K, T]) getPageWithoutCount(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+	listOpts ListOptions,
+) ([]T, Metadata, error) {
+	limit := findOpts.Limit()
+	if limit > 0 {
+		limit++
+	}
+
+	items, err := repo.findPage(ctx, filter, findOpts, listOpts, limit)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	hasMore := findOpts.Limit() > 0 && len(items) > findOpts.Limit()
+	if hasMore {
+		items = items[:findOpts.Limit()]
+	}
+
+	metadata := Metadata{
+		Metadata: filters.Metadata{
+			CurrentPage: findOpts.Page,
+			PageSize:    findOpts.PageSize,
+			FirstPage:   1,
+		},
+		HasMore: &hasMore,
+	}
+
+	return items, metadata, nil
+}
+
+// findPage retrieves at most limit documents of the requested page
+func (repo MongoRepository[K, T]) findPage(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+	listOpts ListOptions,
+	limit int,
+) ([]T, error) {
+	// Find options
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(findOpts.Offset()))
+	findOptions.SetLimit(int64(limit))
+	findOptions.SetSort(sortDocument(findOpts))
+
+	if listOpts.Projection != nil {
+		findOptions.SetProjection(listOpts.Projection)
+	}
+
+	cursor, err := repo.readCollection(ctx).Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	var items []T
+
+	for cursor.Next(ctx) {
+		var item T
+
+		if err = cursor.Decode(&item); err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Create inserts a new document in the collection.
+// Unique index violations are reported with a DuplicateKeyError naming the duplicated field.
+func (repo MongoRepository[K, T]) Create(ctx context.Context, entity T) (*K, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := repo.collection.InsertOne(ctx, entity)
+	if err != nil {
+		return nil, duplicateKeyError(err)
+	}
+
+	id, ok := (result.InsertedID).(K)
+	if !ok {
+		return nil, fmt.Errorf("unexpected inserted id type %T", result.InsertedID)
+	}
+
+	return &id, nil
+}
+
+// Update updates a specific document of the collection if its version did not change.
+// Unique index violations are reported with a DuplicateKeyError naming the duplicated field.
+func (repo MongoRepository[K, T]) Update(ctx context.Context, entity T) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := repo.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": entity.GetID(), "version": entity.GetVersion()},
+		bson.M{"$set": entity.SetVersion(entity.GetVersion() + 1)},
+	)
+	if err != nil {
+		return duplicateKeyError(err)
+	}
+
+	// No document with given id and version was found in the database
+	if result.MatchedCount == 0 {
+		return database.ErrEditConflict
+	}
+
+	return nil
+}
+
+// Aggregate runs the given aggregation pipeline against the collection and decodes
+// the resulting documents into results, which must be a pointer to a slice
+func (repo MongoRepository[K, T]) Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	cursor, err := repo.readCollection(ctx).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(ctx, results)
+}
+
+// Export calls fn with every document of the collection, decoded and raw, in the order of their ids.
+// When snapshot is true, the documents are read at a single point in time through a snapshot
+// session, which requires a replica set or a sharded cluster (MongoDB 5.0+).
+// The export is bound to the given context only since it may last longer than the default timeout.
+func (repo MongoRepository[K, T]) Export(ctx context.Context, snapshot bool, fn func(document Document[T]) error) error {
+	if snapshot {
+		session, err := repo.collection.Database().Client().StartSession(options.Session().SetSnapshot(true))
+		if err != nil {
+			return err
+		}
+
+		defer session.EndSession(ctx)
+
+		ctx = mongo.NewSessionContext(ctx, session)
+	}
+
+	cursor, err := repo.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entity T
+
+		if err = cursor.Decode(&entity); err != nil {
+			return err
+		}
+
+		// The raw document is only valid until the cursor moves
+		raw := make(bson.Raw, len(cursor.Current))
+		copy(raw, cursor.Current)
+
+		if err = fn(Document[T]{Entity: entity, Raw: raw}); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// Restore inserts the given documents in the collection or replaces the stored documents
+// having the same ids, keeping the fields of their raw documents which are not mapped by the entities.
+// The documents are written in order and the restore stops at the first failure.
+// Unique index violations are reported with a DuplicateKeyError naming the duplicated field.
+func (repo MongoRepository[K, T]) Restore(ctx context.Context, documents []Document[T]) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(documents))
+	for _, document := range documents {
+		replacement, err := document.replacement()
+		if err != nil {
+			return err
+		}
+
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": document.Entity.GetID()}).
+			SetReplacement(replacement).
+			SetUpsert(true))
+	}
+
+	_, err := repo.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(true))
+
+	return duplicateKeyError(err)
+}
+
+// readCollection returns the collection with the read preference of the given context, if any
+func (repo MongoRepository[K, T]) readCollection(ctx context.Context) *mongo.Collection {
+	pref := ReadPreferenceFromContext(ctx)
+	if pref == nil {
+		return repo.collection
+	}
+
+	collection, err := repo.collection.Clone(options.Collection().SetReadPreference(pref))
+	if err != nil {
+		return repo.collection
+	}
+
+	return collection
+}
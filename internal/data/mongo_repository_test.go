@@ -0,0 +1,38 @@
+package data
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDocumentReplacement(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	raw, err := bson.Marshal(bson.M{"_id": id, "name": "Potion", "tenant_id": "eu-1", "popularity": bson.M{"purchases": 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document := Document[Item]{Entity: Item{ID: id, Name: "Potion", TenantID: "us-1"}, Raw: raw}
+
+	fields, err := document.replacement()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replacement := fields.Map()
+
+	if replacement["tenant_id"] != "us-1" {
+		t.Errorf("want tenant %q; got %v", "us-1", replacement["tenant_id"])
+	}
+
+	if replacement["name_lower"] != "potion" {
+		t.Errorf("want lowercased name %q; got %v", "potion", replacement["name_lower"])
+	}
+
+	if _, ok := replacement["popularity"]; !ok {
+		t.Errorf("want the popularity of the raw document to be kept; got %v", replacement)
+	}
+}
@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// purchaseWeight is the number of views a purchase is worth in the popularity score of an item
+const purchaseWeight = 10
+
+// PopularitySort is the sort value ordering the items by popularity score
+const PopularitySort = "popularity"
+
+// appliedBatches is the number of the last batches of counts whose ids are kept along with the counts
+// so that retrying a batch which was partially applied does not count it twice
+const appliedBatches = 20
+
+// PopularityCounts is a struct that holds the number of views and purchases of an item
+type PopularityCounts struct {
+	Views     int64 `json:"views" bson:"views"`
+	Purchases int64 `json:"purchases" bson:"purchases"`
+}
+
+// Add returns the sum of both counts
+func (c PopularityCounts) Add(other PopularityCounts) PopularityCounts {
+	return PopularityCounts{Views: c.Views + other.Views, Purchases: c.Purchases + other.Purchases}
+}
+
+// Score returns the popularity score of the counts. A purchase is worth several views.
+func (c PopularityCounts) Score() int64 {
+	return c.Views + purchaseWeight*c.Purchases
+}
+
+// TrendingItem is a struct that defines an item along with its popularity over the trending period
+type TrendingItem struct {
+	Item      `bson:",inline"`
+	Views     int64 `json:"views" bson:"views"`
+	Purchases int64 `json:"purchases" bson:"purchases"`
+	Score     int64 `json:"score" bson:"score"`
+}
+
+// PopularityStore is a struct that keeps track of the popularity of the items.
+// The counts since the creation of an item are stored in its "popularity" field, which is used to sort
+// the items, and the daily counts are stored in a separate collection to find the trending items.
+type PopularityStore struct {
+	items *mongo.Collection
+	days  *mongo.Collection
+}
+
+// NewPopularityStore creates a new popularity store for the given database
+func NewPopularityStore(client *mongo.Client, databaseName string) *PopularityStore {
+	db := client.Database(databaseName)
+
+	return &PopularityStore{
+		items: db.Collection(constants.ItemsCollection),
+		days:  db.Collection(constants.PopularityCollection),
+	}
+}
+
+// Day returns the day the given time belongs to, which identifies the daily counts
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// Increment adds the given counts of the items to their counts of the given day. The counts are identified
+// by the given batch id: the counts of a batch which were already added are skipped, so that a failed batch
+// can be retried as a whole.
+func (store *PopularityStore) Increment(ctx context.Context, batch string, day time.Time, counts map[primitive.ObjectID]PopularityCounts) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	itemModels := make([]mongo.WriteModel, 0, len(counts))
+	dayModels := make([]mongo.WriteModel, 0, len(counts))
+
+	for id, count := range counts {
+		inc := bson.M{"views": count.Views, "purchases": count.Purchases, "score": count.Score()}
+
+		// Counts of deleted items and counts already added by the batch are ignored
+		itemModels = append(itemModels, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "popularity.batches": bson.M{"$ne": batch}}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{
+					"popularity.views":     count.Views,
+					"popularity.purchases": count.Purchases,
+					"popularity.score":     count.Score(),
+				},
+				"$push": bson.M{"popularity.batches": bson.M{"$each": bson.A{batch}, "$slice": -appliedBatches}},
+			}))
+
+		// The daily counts already added by the batch don't match the filter, so their upsert
+		// fails with a duplicate key error
+		dayModels = append(dayModels, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"item_id": id, "day": day, "batches": bson.M{"$ne": batch}}).
+			SetUpdate(bson.M{
+				"$inc":  inc,
+				"$push": bson.M{"batches": bson.M{"$each": bson.A{batch}, "$slice": -appliedBatches}},
+			}).
+			SetUpsert(true))
+	}
+
+	// The order of the updates does not matter
+	opts := options.BulkWrite().SetOrdered(false)
+
+	_, err := store.items.BulkWrite(ctx, itemModels, opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.days.BulkWrite(ctx, dayModels, opts)
+
+	return ignoreDuplicateKeys(err)
+}
+
+// ignoreDuplicateKeys returns nil if the given error of a bulk write only reports duplicate key errors
+func ignoreDuplicateKeys(err error) error {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return err
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != duplicateKeyCode {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Trending returns the items of the given tenant with the highest popularity score since the given day.
+// Deleted items and items expired at the given time are not returned.
+func (store *PopularityStore) Trending(ctx context.Context, tenant string, since time.Time, now time.Time, limit int) ([]TrendingItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	cursor, err := store.days.Aggregate(ctx, TrendingItemsPipeline(tenant, since, now, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	items := []TrendingItem{}
+
+	err = cursor.All(ctx, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// TrendingItemsPipeline returns the aggregation pipeline summing the daily counts of the items since the
+// given day and retrieving the items of the given tenant with the highest score that are not expired at the
+// given time. The daily counts are not scoped to the tenants, the tenant of the joined items is matched instead.
+func TrendingItemsPipeline(tenant string, since time.Time, now time.Time, limit int) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"day": bson.M{"$gte": Day(since)}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$item_id",
+			"views":     bson.M{"$sum": "$views"},
+			"purchases": bson.M{"$sum": "$purchases"},
+			"score":     bson.M{"$sum": "$score"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         constants.ItemsCollection,
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "item",
+		}}},
+		// Deleted items have no match
+		{{Key: "$unwind", Value: "$item"}},
+		{{Key: "$match", Value: bson.M{
+			"item." + TenantField: tenant,
+			"$or": bson.A{
+				bson.M{"item.expires_at": nil},
+				bson.M{"item.expires_at": bson.M{"$gt": now}},
+			},
+		}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": bson.M{"$mergeObjects": bson.A{
+			"$item",
+			bson.M{"views": "$views", "purchases": "$purchases", "score": "$score"},
+		}}}}},
+	}
+}
+
+// CreatePopularityCollection creates the collection holding the daily popularity counts in MongoDB database.
+// Daily counts are deleted once they are older than the retention period.
+func CreatePopularityCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// Create collection
+	err := db.CreateCollection(context.Background(), constants.PopularityCollection)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "item_id", Value: 1}, {Key: "day", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "day", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(settings.PopularityRetentionDays * 24 * 60 * 60)),
+		},
+	}
+
+	_, err = db.Collection(constants.PopularityCollection).Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTrendingItemsPipeline(t *testing.T) {
+	now := time.Date(2022, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	for _, tenant := range []string{"default", "eu-1"} {
+		t.Run(tenant, func(t *testing.T) {
+			pipeline := TrendingItemsPipeline(tenant, now.AddDate(0, 0, -6), now, 10)
+
+			matched := ""
+
+			// The tenant is matched on the joined items, after the lookup
+			lookedUp := false
+			for _, stage := range pipeline {
+				switch stage[0].Key {
+				case "$lookup":
+					lookedUp = true
+				case "$match":
+					if value, ok := stage[0].Value.(bson.M)["item."+TenantField]; ok && lookedUp {
+						matched, _ = value.(string)
+					}
+				}
+			}
+
+			if matched != tenant {
+				t.Errorf("want items of tenant %q; got %q", tenant, matched)
+			}
+		})
+	}
+}
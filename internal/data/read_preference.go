@@ -0,0 +1,42 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// readPreferenceContextKey is the key used for getting and setting the read preference in a context
+type readPreferenceContextKey struct{}
+
+// ContextWithReadPreference returns a copy of the given context holding the read preference
+// used by the reads of the repositories (i.e. to read from the secondaries)
+func ContextWithReadPreference(ctx context.Context, pref *readpref.ReadPref) context.Context {
+	return context.WithValue(ctx, readPreferenceContextKey{}, pref)
+}
+
+// ReadPreferenceFromContext retrieves the read preference from the given context.
+// It returns nil if the context does not hold any, in which case the reads use the primary.
+func ReadPreferenceFromContext(ctx context.Context) *readpref.ReadPref {
+	pref, _ := ctx.Value(readPreferenceContextKey{}).(*readpref.ReadPref)
+
+	return pref
+}
+
+// ParseReadPreference returns the read preference of the given mode (i.e. "secondaryPreferred").
+// The secondaries lagging behind the primary by more than the given number of seconds are not read from,
+// unless it is 0.
+func ParseReadPreference(mode string, maxStaleness int) (*readpref.ReadPref, error) {
+	readMode, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []readpref.Option
+	if maxStaleness > 0 && readMode != readpref.PrimaryMode {
+		opts = append(opts, readpref.WithMaxStaleness(time.Duration(maxStaleness)*time.Second))
+	}
+
+	return readpref.New(readMode, opts...)
+}
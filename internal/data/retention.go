@@ -0,0 +1,26 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RetentionMetrics is a struct that holds some prometheus metrics regarding the purge of the history whose
+// retention window elapsed
+type RetentionMetrics struct {
+	PurgedCounter *prometheus.CounterVec
+}
+
+// NewRetentionMetrics creates the counter used to keep track of the purged documents, labelled by collection
+func NewRetentionMetrics(appName string) *RetentionMetrics {
+	purgedCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_retention_purged_total", appName),
+		Help: "The total number of documents purged once their retention window elapsed, by collection",
+	}, []string{"collection"})
+
+	return &RetentionMetrics{
+		PurgedCounter: purgedCounter,
+	}
+}
@@ -0,0 +1,285 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// transientErrorCodes are the codes of the MongoDB errors raised while a replica set member is shutting down
+// or stepping down, which succeed once the new primary is elected
+var transientErrorCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// RetryMetrics is a struct that holds some prometheus metrics regarding the retries of the MongoDB operations
+type RetryMetrics struct {
+	RetriesCounter         *prometheus.CounterVec
+	BudgetExhaustedCounter *prometheus.CounterVec
+}
+
+// NewRetryMetrics creates counters used to keep track of the retries of the MongoDB operations in our application
+func NewRetryMetrics(appName string) *RetryMetrics {
+	retriesCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_database_retries_total", appName),
+		Help: "The total number of retries of MongoDB operations which failed with a transient error",
+	}, []string{"collection", "operation"})
+
+	budgetExhaustedCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_database_retry_budget_exhausted_total", appName),
+		Help: "The total number of MongoDB operations which were not retried because the retry budget was exhausted",
+	}, []string{"collection", "operation"})
+
+	return &RetryMetrics{
+		RetriesCounter:         retriesCounter,
+		BudgetExhaustedCounter: budgetExhaustedCounter,
+	}
+}
+
+// Retrier retries the operations failing with a transient error with an exponential and jittered backoff.
+// The retries are limited by a budget shared by every operation of the retrier: each operation adds a ratio
+// of a token to the budget and each retry takes a whole token, so that the retries cannot multiply the load
+// of a struggling cluster.
+type Retrier struct {
+	cfg     settings.Retries
+	metrics *RetryMetrics
+	jitter  func(ceiling time.Duration) time.Duration
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewRetrier returns a new Retrier whose budget is full
+func NewRetrier(cfg settings.Retries, metrics *RetryMetrics) *Retrier {
+	return &Retrier{
+		cfg:     cfg,
+		metrics: metrics,
+		jitter: func(ceiling time.Duration) time.Duration {
+			return time.Duration(rand.Int63n(int64(ceiling) + 1))
+		},
+		tokens: float64(cfg.Budget),
+	}
+}
+
+// Run runs the given operation of the given collection until it succeeds, fails with an error which is not
+// transient, runs out of attempts or of budget, or the context is done. It returns the error of the last attempt.
+// The operations of a transaction are not retried since the whole transaction is retried instead.
+func (r *Retrier) Run(ctx context.Context, collection string, operation string, fn func() error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn()
+	}
+
+	r.deposit()
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.cfg.MaxAttempts || !transient(err) {
+			return err
+		}
+
+		if !r.withdraw() {
+			r.metrics.BudgetExhaustedCounter.WithLabelValues(collection, operation).Inc()
+			return err
+		}
+
+		r.metrics.RetriesCounter.WithLabelValues(collection, operation).Inc()
+
+		timer := time.NewTimer(r.backoff(attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the jittered duration to wait for after the given attempt
+func (r *Retrier) backoff(attempt int) time.Duration {
+	ceiling := time.Duration(r.cfg.InitialBackoff) * time.Millisecond
+	maxBackoff := time.Duration(r.cfg.MaxBackoff) * time.Millisecond
+
+	for i := 1; i < attempt && ceiling < maxBackoff; i++ {
+		ceiling *= 2
+	}
+
+	if ceiling > maxBackoff {
+		ceiling = maxBackoff
+	}
+
+	return r.jitter(ceiling)
+}
+
+// deposit adds the ratio of a token of an operation to the budget
+func (r *Retrier) deposit() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens += r.cfg.BudgetRatio
+	if r.tokens > float64(r.cfg.Budget) {
+		r.tokens = float64(r.cfg.Budget)
+	}
+}
+
+// withdraw takes the token of a retry from the budget. It returns false when the budget is exhausted.
+func (r *Retrier) withdraw() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tokens < 1 {
+		return false
+	}
+
+	r.tokens--
+
+	return true
+}
+
+// transient checks if the given error is a transient error, such as a network error or
+// an error raised during a primary election, so that the operation is likely to succeed if retried
+func transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+
+	if serverErr.HasErrorLabel("RetryableWriteError") {
+		return true
+	}
+
+	for _, code := range transientErrorCodes {
+		if serverErr.HasErrorCode(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTransient checks if the given error is transient or shows that MongoDB is unavailable, including when
+// the circuit breaker is open, so that an operation which failed with it is likely to succeed later
+func IsTransient(err error) bool {
+	return transient(err) || unavailable(err) || errors.Is(err, ErrCircuitOpen)
+}
+
+// RetryRepository is a MongoDB repository which retries the idempotent operations of the given repository,
+// its reads and its restores, when they fail with a transient error. The other writes are not retried since
+// they may have been applied before failing. Exports are not retried either since they stream the documents.
+type RetryRepository[K any, T types.MongoEntity[K, T]] struct {
+	Repository[K, T]
+	collection string
+	retrier    *Retrier
+}
+
+// NewRetryRepository creates a new repository retrying the idempotent operations of the given repository
+// with the given retrier, which can be shared by the repositories of the same database
+func NewRetryRepository[K any, T types.MongoEntity[K, T]](
+	repository Repository[K, T],
+	collection string,
+	retrier *Retrier,
+) Repository[K, T] {
+	return &RetryRepository[K, T]{
+		Repository: repository,
+		collection: collection,
+		retrier:    retrier,
+	}
+}
+
+// GetByID retrieves a specific document by its id
+func (repo RetryRepository[K, T]) GetByID(ctx context.Context, id K) (T, error) {
+	var entity T
+
+	err := repo.retrier.Run(ctx, repo.collection, "get_by_id", func() (err error) {
+		entity, err = repo.Repository.GetByID(ctx, id)
+		return err
+	})
+
+	return entity, err
+}
+
+// GetByFilter retrieves a specific document by the given filter
+func (repo RetryRepository[K, T]) GetByFilter(ctx context.Context, filter primitive.M) (T, error) {
+	var entity T
+
+	err := repo.retrier.Run(ctx, repo.collection, "get_by_filter", func() (err error) {
+		entity, err = repo.Repository.GetByFilter(ctx, filter)
+		return err
+	})
+
+	return entity, err
+}
+
+// GetAll retrieves all documents matching the given filter
+func (repo RetryRepository[K, T]) GetAll(ctx context.Context, filter primitive.M, findOpts filters.Filters) ([]T, filters.Metadata, error) {
+	var entities []T
+	var metadata filters.Metadata
+
+	err := repo.retrier.Run(ctx, repo.collection, "get_all", func() (err error) {
+		entities, metadata, err = repo.Repository.GetAll(ctx, filter, findOpts)
+		return err
+	})
+
+	return entities, metadata, err
+}
+
+// GetAllWithOptions retrieves all documents matching the given filter with the given listing options
+func (repo RetryRepository[K, T]) GetAllWithOptions(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+	listOpts ListOptions,
+) ([]T, Metadata, error) {
+	var entities []T
+	var metadata Metadata
+
+	err := repo.retrier.Run(ctx, repo.collection, "get_all", func() (err error) {
+		entities, metadata, err = repo.Repository.GetAllWithOptions(ctx, filter, findOpts, listOpts)
+		return err
+	})
+
+	return entities, metadata, err
+}
+
+// Aggregate runs the given aggregation pipeline
+func (repo RetryRepository[K, T]) Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error {
+	return repo.retrier.Run(ctx, repo.collection, "aggregate", func() error {
+		return repo.Repository.Aggregate(ctx, pipeline, results)
+	})
+}
+
+// Restore writes the given documents. Restores replace the documents by their id, so they can be retried.
+func (repo RetryRepository[K, T]) Restore(ctx context.Context, documents []Document[T]) error {
+	return repo.retrier.Run(ctx, repo.collection, "restore", func() error {
+		return repo.Repository.Restore(ctx, documents)
+	})
+}
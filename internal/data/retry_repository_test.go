@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// retryMetrics are the metrics shared by the retriers of the tests
+var retryMetrics = NewRetryMetrics("test")
+
+func TestRetrier(t *testing.T) {
+	stepDown := mongo.CommandError{Code: 11602, Name: "InterruptedDueToReplStateChange"}
+	networkErr := mongo.CommandError{Labels: []string{"NetworkError"}}
+
+	tests := []struct {
+		testName       string
+		budget         int
+		errs           []error // Errors of the successive attempts
+		wantedAttempts int
+		wantedErr      error
+	}{
+		{"Success", 20, []error{nil}, 1, nil},
+		{"Transient error", 20, []error{stepDown, nil}, 2, nil},
+		{"Network error", 20, []error{networkErr, networkErr, nil}, 3, nil},
+		{"Attempts exhausted", 20, []error{stepDown, stepDown, stepDown, nil}, 3, stepDown},
+		{"Not transient", 20, []error{database.ErrRecordNotFound, nil}, 1, database.ErrRecordNotFound},
+		{"Timeout", 20, []error{context.DeadlineExceeded, nil}, 1, context.DeadlineExceeded},
+		{"Budget exhausted", 1, []error{stepDown, stepDown, nil}, 2, stepDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			retrier := NewRetrier(settings.Retries{
+				MaxAttempts:    3,
+				InitialBackoff: 10,
+				MaxBackoff:     50,
+				Budget:         tt.budget,
+				BudgetRatio:    0.1,
+			}, retryMetrics)
+			retrier.jitter = func(time.Duration) time.Duration { return 0 }
+
+			attempts := 0
+
+			err := retrier.Run(context.Background(), "items", "get_by_id", func() error {
+				attempts++
+				return tt.errs[attempts-1]
+			})
+
+			if attempts != tt.wantedAttempts {
+				t.Errorf("want %d attempts; got %d", tt.wantedAttempts, attempts)
+			}
+
+			// The command errors are not comparable
+			if fmt.Sprint(err) != fmt.Sprint(tt.wantedErr) {
+				t.Errorf("want error %v; got %v", tt.wantedErr, err)
+			}
+		})
+	}
+}
+
+func TestRetrierContextDone(t *testing.T) {
+	retrier := NewRetrier(settings.Retries{
+		MaxAttempts:    3,
+		InitialBackoff: 10,
+		MaxBackoff:     50,
+		Budget:         20,
+		BudgetRatio:    0.1,
+	}, retryMetrics)
+	retrier.jitter = func(time.Duration) time.Duration { return time.Hour }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+
+	err := retrier.Run(ctx, "items", "get_by_id", func() error {
+		attempts++
+		return mongo.CommandError{Code: 189, Name: "PrimarySteppedDown"}
+	})
+
+	if attempts != 1 || err == nil {
+		t.Errorf("want %d attempt and an error; got %d attempts and %v", 1, attempts, err)
+	}
+}
+
+func TestRetrierBackoff(t *testing.T) {
+	retrier := NewRetrier(settings.Retries{InitialBackoff: 50, MaxBackoff: 150}, retryMetrics)
+	retrier.jitter = func(ceiling time.Duration) time.Duration { return ceiling }
+
+	tests := []struct {
+		attempt       int
+		wantedBackoff time.Duration
+	}{
+		{1, 50 * time.Millisecond},
+		{2, 100 * time.Millisecond},
+		{3, 150 * time.Millisecond},
+		{10, 150 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if backoff := retrier.backoff(tt.attempt); backoff != tt.wantedBackoff {
+			t.Errorf("want backoff %s after attempt %d; got %s", tt.wantedBackoff, tt.attempt, backoff)
+		}
+	}
+}
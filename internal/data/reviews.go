@@ -0,0 +1,250 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Review statuses. Only the published reviews are listed and counted in the rating of their item.
+const (
+	ReviewPublished = "published"
+	ReviewPending   = "pending" // Waiting for a moderator since the comment contains banned words
+	ReviewHidden    = "hidden"  // Hidden by a moderator
+)
+
+// ReviewStatuses is the list of statuses a moderator can set on a review
+var ReviewStatuses = []string{ReviewPublished, ReviewPending, ReviewHidden}
+
+// MaxReviewCommentCharacters is the maximum length of the comment of a review
+const MaxReviewCommentCharacters = 500
+
+// Review is a struct that defines the rating and the short review of an item by a player.
+// A player can only review an item once.
+type Review struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TenantID  string             `json:"-" bson:"tenant_id"`
+	ItemID    primitive.ObjectID `json:"item_id" bson:"item_id"`
+	UserID    int64              `json:"user_id" bson:"user_id"`
+	Rating    int32              `json:"rating" bson:"rating"` // From 1 to 5
+	Comment   string             `json:"comment" bson:"comment"`
+	Status    string             `json:"status" bson:"status"`
+	Version   int32              `json:"version" bson:"version"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"-" bson:"updated_at"`
+}
+
+// GetID returns the id of a review.
+// This method is necessary for our generic constraint of our mongo repository.
+func (r Review) GetID() primitive.ObjectID {
+	return r.ID
+}
+
+// GetVersion returns the version of a review.
+// This method is necessary for our generic constraint of our mongo repository.
+func (r Review) GetVersion() int32 {
+	return r.Version
+}
+
+// SetVersion sets the version of a review to the given value and returns the review.
+// This method is necessary for our generic constraint of our mongo repository.
+func (r Review) SetVersion(version int32) Review {
+	r.Version = version
+
+	return r
+}
+
+// ItemRating is a struct that holds the aggregated rating of the published reviews of an item
+type ItemRating struct {
+	Average float64 `json:"average" bson:"average"` // Rounded to 2 decimal places
+	Count   int32   `json:"count" bson:"count"`
+}
+
+// ValidateReview runs validation checks on the `Review` struct.
+// Comments containing banned words are rejected, or left pending when they are reviewed by a moderator instead.
+func ValidateReview(v *validation.Validator, review Review, moderation settings.Moderation) {
+	v.Check(validator.Between(review.Rating, 1, 5), "rating", "out_of_range", "must be greater or equal to 1 and lower or equal to 5")
+	v.Check(validator.MaxCharacters(review.Comment, MaxReviewCommentCharacters), "comment", "too_long", "must not be more than 500 characters long")
+
+	if moderation.Action == "reject" {
+		v.Check(!ContainsBannedWords(review.Comment, moderation.BannedWords), "comment", "banned", "must not contain banned words")
+	}
+}
+
+// InitialReviewStatus returns the status of a newly submitted review
+func InitialReviewStatus(review Review, moderation settings.Moderation) string {
+	if moderation.Action == "review" && ContainsBannedWords(review.Comment, moderation.BannedWords) {
+		return ReviewPending
+	}
+
+	return ReviewPublished
+}
+
+// RatingStore is a struct that keeps the rating of the items in line with their reviews.
+// The rating is stored in the "rating" field of the items so that it is returned along with them.
+type RatingStore struct {
+	items   *mongo.Collection
+	reviews *mongo.Collection
+	indexer ItemsIndexer // Nil when the items are not mirrored in a search index
+}
+
+// NewRatingStore creates a new rating store for the given database re-indexing the items with the given indexer
+func NewRatingStore(client *mongo.Client, databaseName string, indexer ItemsIndexer) *RatingStore {
+	db := client.Database(databaseName)
+
+	return &RatingStore{
+		items:   db.Collection(constants.ItemsCollection),
+		reviews: db.Collection(constants.ReviewsCollection),
+		indexer: indexer,
+	}
+}
+
+// Refresh recomputes the rating of the given item from its published reviews.
+// The rating is recomputed as a whole so that a missed refresh is fixed by the next one, and the item is re-indexed.
+func (store *RatingStore) Refresh(ctx context.Context, itemID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	cursor, err := store.reviews.Aggregate(ctx, ItemRatingPipeline(itemID))
+	if err != nil {
+		return err
+	}
+
+	ratings := []ItemRating{}
+
+	err = cursor.All(ctx, &ratings)
+	if err != nil {
+		return err
+	}
+
+	// Items without published reviews have no rating. Deleted items are ignored.
+	update := bson.M{"$unset": bson.M{"rating": ""}}
+	if len(ratings) != 0 {
+		update = bson.M{"$set": bson.M{"rating": ratings[0]}}
+	}
+
+	result, err := store.items.UpdateOne(ctx, bson.M{"_id": itemID}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 1 {
+		reindex(ctx, store.indexer, itemID)
+	}
+
+	return nil
+}
+
+// ItemRatingPipeline returns the aggregation pipeline computing the rating of the given item from its published reviews
+func ItemRatingPipeline(itemID primitive.ObjectID) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"item_id": itemID, "status": ReviewPublished}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"average": bson.M{"$avg": "$rating"},
+			"count":   bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":     0,
+			"average": bson.M{"$round": bson.A{"$average", 2}},
+			"count":   1,
+		}}},
+	}
+}
+
+// CreateReviewsCollection creates reviews collection in MongoDB database
+func CreateReviewsCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// JSON validation schema
+	jsonSchema := bson.M{
+		"bsonType":             "object",
+		"required":             []string{"tenant_id", "item_id", "user_id", "rating", "comment", "status", "version", "created_at", "updated_at"},
+		"additionalProperties": false,
+		"properties": bson.M{
+			"_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Document ID",
+			},
+			"tenant_id": bson.M{
+				"bsonType":    "string",
+				"description": "Tenant which the review belongs to",
+			},
+			"item_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "ID of the reviewed item",
+			},
+			"user_id": bson.M{
+				"bsonType":    "long",
+				"description": "ID of the user who reviewed the item",
+			},
+			"rating": bson.M{
+				"bsonType":    "int",
+				"minimum":     1,
+				"maximum":     5,
+				"description": "Rating of the item",
+			},
+			"comment": bson.M{
+				"bsonType":    "string",
+				"maxLength":   MaxReviewCommentCharacters,
+				"description": "Short review of the item",
+			},
+			"status": bson.M{
+				"enum":        ReviewStatuses,
+				"description": "Moderation status of the review",
+			},
+			"version": bson.M{
+				"bsonType":    "int",
+				"minimum":     1,
+				"description": "Document version",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "Creation date",
+			},
+			"updated_at": bson.M{
+				"bsonType":    "date",
+				"description": "Last update date",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	// Create collection
+	opts := options.CreateCollection().SetValidator(validator)
+	err := db.CreateCollection(context.Background(), constants.ReviewsCollection, opts)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	indexModels := []mongo.IndexModel{
+		// A user can only review an item once
+		{
+			Keys:    bson.D{{Key: "item_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Reviews are moderated per tenant and status
+		{
+			Keys: bson.D{{Key: TenantField, Value: 1}, {Key: "status", Value: 1}},
+		},
+	}
+
+	_, err = db.Collection(constants.ReviewsCollection).Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
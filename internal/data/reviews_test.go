@@ -0,0 +1,45 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+)
+
+func TestValidateReview(t *testing.T) {
+	tests := []struct {
+		name         string
+		review       Review
+		moderation   settings.Moderation
+		wantedErrors []string
+		wantedStatus string
+	}{
+		{"Valid review", Review{Rating: 4, Comment: "Tastes bad"}, settings.Moderation{BannedWords: []string{"scam"}, Action: "reject"}, nil, ReviewPublished},
+		{"Rating too low", Review{Rating: 0}, settings.Moderation{Action: "reject"}, []string{"rating"}, ReviewPublished},
+		{"Comment too long", Review{Rating: 1, Comment: string(make([]byte, MaxReviewCommentCharacters+1))}, settings.Moderation{Action: "reject"}, []string{"comment"}, ReviewPublished},
+		{"Rejected banned words", Review{Rating: 1, Comment: "What a scam"}, settings.Moderation{BannedWords: []string{"scam"}, Action: "reject"}, []string{"comment"}, ReviewPublished},
+		{"Reviewed banned words", Review{Rating: 1, Comment: "What a scam"}, settings.Moderation{BannedWords: []string{"scam"}, Action: "review"}, nil, ReviewPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validation.New()
+			ValidateReview(v, tt.review, tt.moderation)
+
+			if len(v.Errors) != len(tt.wantedErrors) {
+				t.Errorf("want %d errors; got %v", len(tt.wantedErrors), v.Errors)
+			}
+
+			for _, key := range tt.wantedErrors {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("want error on %q; got %v", key, v.Errors)
+				}
+			}
+
+			if got := InitialReviewStatus(tt.review, tt.moderation); got != tt.wantedStatus {
+				t.Errorf("want %q; got %q", tt.wantedStatus, got)
+			}
+		})
+	}
+}
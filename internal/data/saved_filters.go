@@ -0,0 +1,164 @@
+package data
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SlugRegex is a regular expression used for checking the format of saved filter slugs
+var SlugRegex = regexp.MustCompile("^[a-z0-9]+(?:-[a-z0-9]+)*$")
+
+// SavedFilterParameters is the list of "GET /items" query string parameters that can be saved in a filter
+var SavedFilterParameters = []string{"name", "min_price", "max_price", "page_size", "sort"}
+
+// SavedFilter is a struct that defines a named filter/sort combination for the "GET /items" endpoint
+type SavedFilter struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Slug      string             `json:"slug" bson:"slug"`
+	Name      string             `json:"name" bson:"name"`
+	Query     map[string]string  `json:"query" bson:"query"`
+	CreatedBy int64              `json:"created_by" bson:"created_by"`
+	Version   int32              `json:"version" bson:"version"`
+	CreatedAt time.Time          `json:"-" bson:"created_at"`
+	UpdatedAt time.Time          `json:"-" bson:"updated_at"`
+}
+
+// GetID returns the id of a saved filter.
+// This method is necessary for our generic constraint of our mongo repository.
+func (f SavedFilter) GetID() primitive.ObjectID {
+	return f.ID
+}
+
+// GetVersion returns the version of a saved filter.
+// This method is necessary for our generic constraint of our mongo repository.
+func (f SavedFilter) GetVersion() int32 {
+	return f.Version
+}
+
+// SetVersion sets the version of a saved filter to the given value and returns the saved filter.
+// This method is necessary for our generic constraint of our mongo repository.
+func (f SavedFilter) SetVersion(version int32) SavedFilter {
+	f.Version = version
+
+	return f
+}
+
+// Values converts the saved query into query string values
+func (f SavedFilter) Values() url.Values {
+	values := url.Values{}
+
+	for key, value := range f.Query {
+		values.Set(key, value)
+	}
+
+	return values
+}
+
+// Apply merges the saved query into the given query string.
+// Parameters explicitly present in the query string take precedence over the saved ones.
+func (f SavedFilter) Apply(queryString url.Values) url.Values {
+	merged := f.Values()
+
+	for key, values := range queryString {
+		merged[key] = values
+	}
+
+	return merged
+}
+
+// ValidateSavedFilter runs validation checks on the `SavedFilter` struct.
+// The saved query itself is validated against the "GET /items" grammar by the caller.
+func ValidateSavedFilter(v *validation.Validator, savedFilter SavedFilter) {
+	v.Check(savedFilter.Slug != "", "slug", "required", "must be provided")
+	v.Check(validator.MaxCharacters(savedFilter.Slug, 64), "slug", "too_long", "must not be more than 64 characters long")
+	v.Check(validator.Matches(savedFilter.Slug, SlugRegex), "slug", "invalid_format", "must only contain lowercase letters, digits and hyphens")
+	v.Check(savedFilter.Name != "", "name", "required", "must be provided")
+	v.Check(validator.MaxCharacters(savedFilter.Name, 100), "name", "too_long", "must not be more than 100 characters long")
+	v.Check(len(savedFilter.Query) != 0, "query", "required", "must contain at least one parameter")
+
+	for key := range savedFilter.Query {
+		v.Check(validator.In(key, SavedFilterParameters...), "query", "unsupported_value", "contains an unsupported parameter "+key)
+	}
+}
+
+// CreateSavedFiltersCollection creates saved filters collection in MongoDB database
+func CreateSavedFiltersCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// JSON validation schema
+	jsonSchema := bson.M{
+		"bsonType":             "object",
+		"required":             []string{"slug", "name", "query", "created_by", "version", "created_at", "updated_at"},
+		"additionalProperties": false,
+		"properties": bson.M{
+			"_id": bson.M{
+				"bsonType":    "objectId",
+				"description": "Document ID",
+			},
+			"slug": bson.M{
+				"bsonType":    "string",
+				"description": "Unique slug used to reference the saved filter",
+			},
+			"name": bson.M{
+				"bsonType":    "string",
+				"description": "Name of the saved filter",
+			},
+			"query": bson.M{
+				"bsonType":    "object",
+				"description": "Saved query string parameters",
+			},
+			"created_by": bson.M{
+				"bsonType":    "long",
+				"description": "ID of the user who saved the filter",
+			},
+			"version": bson.M{
+				"bsonType":    "int",
+				"minimum":     1,
+				"description": "Document version",
+			},
+			"created_at": bson.M{
+				"bsonType":    "date",
+				"description": "Creation date",
+			},
+			"updated_at": bson.M{
+				"bsonType":    "date",
+				"description": "Last update date",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	// Create collection
+	opts := options.CreateCollection().SetValidator(validator)
+	err := db.CreateCollection(context.Background(), constants.SavedFiltersCollection, opts)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	// Create unique index on slug
+	indexModel := mongo.IndexModel{
+		Keys:    bson.M{"slug": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err = db.Collection(constants.SavedFiltersCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
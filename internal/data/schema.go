@@ -0,0 +1,309 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UniqueItemFields is the list of item fields which can be configured to be unique
+var UniqueItemFields = []string{"name", "description"}
+
+// indexSpec is a struct that defines an index of a collection
+type indexSpec struct {
+	Name                    string `bson:"name"`
+	Keys                    bson.D `bson:"key"`
+	Unique                  bool   `bson:"unique"`
+	Sparse                  bool   `bson:"sparse"`
+	ExpireAfterSeconds      *int32 `bson:"expireAfterSeconds"`      // Only set on TTL indexes
+	PartialFilterExpression bson.M `bson:"partialFilterExpression"` // Only set on partial indexes
+}
+
+// model converts the index specification into a MongoDB index model
+func (spec indexSpec) model() mongo.IndexModel {
+	indexOptions := options.Index().SetName(spec.Name)
+
+	if spec.Unique {
+		indexOptions.SetUnique(true)
+	}
+
+	if spec.Sparse {
+		indexOptions.SetSparse(true)
+	}
+
+	if spec.ExpireAfterSeconds != nil {
+		indexOptions.SetExpireAfterSeconds(*spec.ExpireAfterSeconds)
+	}
+
+	if spec.PartialFilterExpression != nil {
+		indexOptions.SetPartialFilterExpression(spec.PartialFilterExpression)
+	}
+
+	return mongo.IndexModel{Keys: spec.Keys, Options: indexOptions}
+}
+
+// equal returns true if both index specifications define the same index.
+// Key values are compared loosely since the server may report them with another numeric type.
+func (spec indexSpec) equal(other indexSpec) bool {
+	if spec.Name != other.Name || spec.Unique != other.Unique || spec.Sparse != other.Sparse || len(spec.Keys) != len(other.Keys) {
+		return false
+	}
+
+	if (spec.ExpireAfterSeconds == nil) != (other.ExpireAfterSeconds == nil) ||
+		(spec.ExpireAfterSeconds != nil && *spec.ExpireAfterSeconds != *other.ExpireAfterSeconds) {
+		return false
+	}
+
+	if fmt.Sprint(spec.PartialFilterExpression) != fmt.Sprint(other.PartialFilterExpression) {
+		return false
+	}
+
+	for i, key := range spec.Keys {
+		if key.Key != other.Keys[i].Key || fmt.Sprint(key.Value) != fmt.Sprint(other.Keys[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// legacyItemIndexes are the names of the unique indexes which were not scoped to the tenants.
+// They are dropped by the reconciliation.
+var legacyItemIndexes = []string{"name_1", "description_1", "external_id_1"}
+
+// itemIndexSpecs returns the indexes of the collections holding items.
+// Every field which can be configured to be unique only has an index when it is unique.
+// Unique values are only unique within a tenant, so the unique indexes are prefixed by the tenant.
+// The index of the expiration dates becomes a TTL index when the expired items are purged.
+func itemIndexSpecs(constraints settings.Constraints, expiration settings.Expiration) []indexSpec {
+	specs := []indexSpec{}
+
+	for _, field := range UniqueItemFields {
+		if validator.In(field, constraints.UniqueFields...) {
+			specs = append(specs, tenantUniqueIndexSpec(field, nil))
+		}
+	}
+
+	return append(specs, []indexSpec{
+		// Only items created with an external id are indexed
+		tenantUniqueIndexSpec("external_id", bson.M{"external_id": bson.M{"$exists": true}}),
+		{Name: "tenant_id_1", Keys: bson.D{{Key: TenantField, Value: 1}}},
+		{Name: "name_text", Keys: bson.D{{Key: "name", Value: "text"}}},
+		{Name: "tenant_id_1_name_lower_1", Keys: bson.D{{Key: TenantField, Value: 1}, {Key: "name_lower", Value: 1}}},
+		{Name: "tags_1", Keys: bson.D{{Key: "tags", Value: 1}}},
+		expirationIndexSpec(expiration),
+		{Name: "popularity.score_-1", Keys: bson.D{{Key: "popularity.score", Value: -1}}},
+		// Only the featured items are indexed
+		{
+			Name:                    "tenant_id_1_featured_priority_-1",
+			Keys:                    bson.D{{Key: TenantField, Value: 1}, {Key: "featured_priority", Value: -1}},
+			PartialFilterExpression: bson.M{"featured": true},
+		},
+	}...)
+}
+
+// tenantUniqueIndexSpec returns the index of a field whose values are unique within a tenant.
+// Only the documents matching the given partial filter are indexed when it is not nil.
+func tenantUniqueIndexSpec(field string, partialFilter bson.M) indexSpec {
+	return indexSpec{
+		Name:                    fmt.Sprintf("%s_1_%s_1", TenantField, field),
+		Keys:                    bson.D{{Key: TenantField, Value: 1}, {Key: field, Value: 1}},
+		Unique:                  true,
+		PartialFilterExpression: partialFilter,
+	}
+}
+
+// expirationIndexSpec returns the index of the expiration dates of the items
+func expirationIndexSpec(expiration settings.Expiration) indexSpec {
+	spec := indexSpec{Name: "expires_at_1", Keys: bson.D{{Key: "expires_at", Value: 1}}}
+
+	if expiration.Purge {
+		purgeDelay := int32(expiration.PurgeDelay)
+		spec.ExpireAfterSeconds = &purgeDelay
+	}
+
+	return spec
+}
+
+// managedItemIndexes returns the names of the indexes managed by the reconciliation of the collections holding items.
+// Indexes created by other means (i.e. the Atlas Search indexes or indexes added by an operator) are left untouched.
+func managedItemIndexes() []string {
+	names := append([]string{}, legacyItemIndexes...)
+
+	for _, spec := range itemIndexSpecs(settings.Constraints{UniqueFields: UniqueItemFields}, settings.Expiration{}) {
+		names = append(names, spec.Name)
+	}
+
+	return names
+}
+
+// ReconcileItemsCollection updates the validator and the indexes of an existing collection holding items
+// so that they match the given pricing rules, uniqueness constraints and expiration settings. It is idempotent and returns
+// the list of applied changes. Creating a unique index fails if the collection holds duplicated values.
+func ReconcileItemsCollection(
+	ctx context.Context,
+	client *mongo.Client,
+	databaseName string,
+	collectionName string,
+	pricing settings.Pricing,
+	constraints settings.Constraints,
+	expiration settings.Expiration,
+) ([]string, error) {
+	db := client.Database(databaseName)
+	changes := []string{}
+
+	// Validator
+	changed, err := reconcileValidator(ctx, db, collectionName, itemsValidator(pricing))
+	if err != nil {
+		return changes, err
+	}
+
+	if changed {
+		changes = append(changes, "updated validator")
+	}
+
+	// Lowercase the names of the items written before the lowercased names were stored
+	result, err := db.Collection(collectionName).UpdateMany(
+		ctx,
+		bson.M{"name_lower": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"name_lower": bson.M{"$toLower": "$name"}}}}},
+	)
+	if err != nil {
+		return changes, err
+	}
+
+	if result.ModifiedCount != 0 {
+		changes = append(changes, fmt.Sprintf("lowercased %d names", result.ModifiedCount))
+	}
+
+	// Indexes
+	indexes := db.Collection(collectionName).Indexes()
+
+	cursor, err := indexes.List(ctx)
+	if err != nil {
+		return changes, err
+	}
+
+	var existingSpecs []indexSpec
+
+	err = cursor.All(ctx, &existingSpecs)
+	if err != nil {
+		return changes, err
+	}
+
+	existing := make(map[string]indexSpec)
+	for _, spec := range existingSpecs {
+		existing[spec.Name] = spec
+	}
+
+	desiredSpecs := itemIndexSpecs(constraints, expiration)
+	desired := make(map[string]indexSpec)
+	for _, spec := range desiredSpecs {
+		desired[spec.Name] = spec
+	}
+
+	// Drop the managed indexes which are not wanted anymore or whose options changed
+	// since an index can't be altered
+	for _, name := range managedItemIndexes() {
+		existingSpec, exists := existing[name]
+		desiredSpec, wanted := desired[name]
+
+		if !exists || (wanted && existingSpec.equal(desiredSpec)) {
+			continue
+		}
+
+		_, err = indexes.DropOne(ctx, name)
+		if err != nil {
+			return changes, err
+		}
+
+		delete(existing, name)
+		changes = append(changes, fmt.Sprintf("dropped index %s", name))
+	}
+
+	// Create the missing indexes
+	for _, spec := range desiredSpecs {
+		if _, exists := existing[spec.Name]; exists {
+			continue
+		}
+
+		_, err = indexes.CreateOne(ctx, spec.model())
+		if err != nil {
+			return changes, err
+		}
+
+		changes = append(changes, fmt.Sprintf("created index %s", spec.Name))
+	}
+
+	return changes, nil
+}
+
+// reconcileValidator replaces the validator of the given collection if it differs from the desired one.
+// It returns true if the validator was replaced.
+func reconcileValidator(ctx context.Context, db *mongo.Database, collectionName string, desired bson.M) (bool, error) {
+	cursor, err := db.ListCollections(ctx, bson.M{"name": collectionName})
+	if err != nil {
+		return false, err
+	}
+
+	var collections []struct {
+		Options struct {
+			Validator bson.Raw `bson:"validator"`
+		} `bson:"options"`
+	}
+
+	err = cursor.All(ctx, &collections)
+	if err != nil {
+		return false, err
+	}
+
+	if len(collections) == 0 {
+		return false, fmt.Errorf("collection %s does not exist", collectionName)
+	}
+
+	equal, err := sameDocuments(collections[0].Options.Validator, desired)
+	if err != nil || equal {
+		return false, err
+	}
+
+	err = db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collectionName},
+		{Key: "validator", Value: desired},
+	}).Err()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// sameDocuments returns true if the given BSON document holds the same values as the desired document.
+// Both documents are decoded the same way so that the order of the fields is ignored.
+func sameDocuments(actual bson.Raw, desired bson.M) (bool, error) {
+	desiredRaw, err := bson.Marshal(desired)
+	if err != nil {
+		return false, err
+	}
+
+	var actualDocument, desiredDocument bson.M
+
+	if len(actual) != 0 {
+		err = bson.Unmarshal(actual, &actualDocument)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = bson.Unmarshal(desiredRaw, &desiredDocument)
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(actualDocument, desiredDocument), nil
+}
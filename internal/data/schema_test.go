@@ -0,0 +1,101 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestItemIndexSpecs(t *testing.T) {
+	tests := []struct {
+		testName      string
+		uniqueFields  []string
+		expectedNames []string
+	}{
+		{"Unique names", []string{"name"}, []string{"tenant_id_1_name_1", "tenant_id_1_external_id_1", "tenant_id_1", "name_text", "tenant_id_1_name_lower_1", "tags_1", "expires_at_1", "popularity.score_-1", "tenant_id_1_featured_priority_-1"}},
+		{"Unique names and descriptions", []string{"name", "description"}, []string{"tenant_id_1_name_1", "tenant_id_1_description_1", "tenant_id_1_external_id_1", "tenant_id_1", "name_text", "tenant_id_1_name_lower_1", "tags_1", "expires_at_1", "popularity.score_-1", "tenant_id_1_featured_priority_-1"}},
+		{"No unique field", []string{}, []string{"tenant_id_1_external_id_1", "tenant_id_1", "name_text", "tenant_id_1_name_lower_1", "tags_1", "expires_at_1", "popularity.score_-1", "tenant_id_1_featured_priority_-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			specs := itemIndexSpecs(settings.Constraints{UniqueFields: tt.uniqueFields}, settings.Expiration{})
+
+			if len(specs) != len(tt.expectedNames) {
+				t.Fatalf("want %d indexes; got %d", len(tt.expectedNames), len(specs))
+			}
+
+			for i, spec := range specs {
+				if spec.Name != tt.expectedNames[i] {
+					t.Errorf("want %q; got %q", tt.expectedNames[i], spec.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestExpirationIndexSpec(t *testing.T) {
+	spec := expirationIndexSpec(settings.Expiration{PurgeDelay: 3600})
+	if spec.ExpireAfterSeconds != nil {
+		t.Errorf("want no TTL; got %d", *spec.ExpireAfterSeconds)
+	}
+
+	ttlSpec := expirationIndexSpec(settings.Expiration{Purge: true, PurgeDelay: 3600})
+	if ttlSpec.ExpireAfterSeconds == nil || *ttlSpec.ExpireAfterSeconds != 3600 {
+		t.Fatalf("want TTL of %d seconds; got %v", 3600, ttlSpec.ExpireAfterSeconds)
+	}
+
+	if spec.equal(ttlSpec) {
+		t.Errorf("want index to change when the purge is enabled")
+	}
+
+	otherDelay := expirationIndexSpec(settings.Expiration{Purge: true, PurgeDelay: 60})
+	if ttlSpec.equal(otherDelay) {
+		t.Errorf("want index to change along with the purge delay")
+	}
+}
+
+func TestIndexSpecEqual(t *testing.T) {
+	desired := indexSpec{Name: "name_1", Keys: bson.D{{Key: "name", Value: 1}}, Unique: true}
+
+	tests := []struct {
+		testName string
+		existing indexSpec
+		expected bool
+	}{
+		{"Same index reported with another numeric type", indexSpec{Name: "name_1", Keys: bson.D{{Key: "name", Value: int32(1)}}, Unique: true}, true},
+		{"Index which is not unique", indexSpec{Name: "name_1", Keys: bson.D{{Key: "name", Value: int32(1)}}}, false},
+		{"Descending index", indexSpec{Name: "name_1", Keys: bson.D{{Key: "name", Value: int32(-1)}}, Unique: true}, false},
+		{"Partial index", indexSpec{Name: "name_1", Keys: bson.D{{Key: "name", Value: int32(1)}}, Unique: true, PartialFilterExpression: bson.M{"name": bson.M{"$exists": true}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if tt.existing.equal(desired) != tt.expected {
+				t.Errorf("want %t; got %t", tt.expected, !tt.expected)
+			}
+		})
+	}
+}
+
+func TestSameDocuments(t *testing.T) {
+	desired := itemsValidator(settings.Pricing{MinPrice: 0.1, MaxPrice: 1000, MaxDecimals: 2})
+
+	actual, err := bson.Marshal(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equal, err := sameDocuments(actual, desired)
+	if err != nil || !equal {
+		t.Errorf("want validator to be unchanged; got %t (%v)", equal, err)
+	}
+
+	changed := itemsValidator(settings.Pricing{MinPrice: 0.1, MaxPrice: 500, MaxDecimals: 2})
+
+	equal, err = sameDocuments(actual, changed)
+	if err != nil || equal {
+		t.Errorf("want validator to be changed; got %t (%v)", equal, err)
+	}
+}
@@ -0,0 +1,173 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SlowQueryMetrics is a struct that holds some prometheus metrics regarding the slow MongoDB operations
+type SlowQueryMetrics struct {
+	SlowQueriesCounter *prometheus.CounterVec
+}
+
+// NewSlowQueryMetrics creates counters used to keep track of the slow MongoDB operations in our application
+func NewSlowQueryMetrics(appName string) *SlowQueryMetrics {
+	slowQueriesCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_slow_queries_total", appName),
+		Help: "The total number of MongoDB operations which took longer than the slow query threshold",
+	}, []string{"collection", "operation"})
+
+	return &SlowQueryMetrics{
+		SlowQueriesCounter: slowQueriesCounter,
+	}
+}
+
+// SlowQueryRepository is a MongoDB repository which times the operations of the given repository.
+// The operations slower than the threshold are logged along with their filter and counted, so that
+// the pathological filters can be found. Exports are not timed since they read the whole collection.
+type SlowQueryRepository[K any, T types.MongoEntity[K, T]] struct {
+	Repository[K, T]
+	collection string
+	threshold  time.Duration
+	logger     *logger.Logger
+	metrics    *SlowQueryMetrics
+}
+
+// NewSlowQueryRepository creates a new repository logging the slow operations of the given repository
+func NewSlowQueryRepository[K any, T types.MongoEntity[K, T]](
+	repository Repository[K, T],
+	collection string,
+	threshold time.Duration,
+	logger *logger.Logger,
+	metrics *SlowQueryMetrics,
+) Repository[K, T] {
+	return &SlowQueryRepository[K, T]{
+		Repository: repository,
+		collection: collection,
+		threshold:  threshold,
+		logger:     logger,
+		metrics:    metrics,
+	}
+}
+
+// GetByID retrieves a specific document by its id
+func (repo SlowQueryRepository[K, T]) GetByID(ctx context.Context, id K) (T, error) {
+	defer repo.observe("get_by_id", bson.M{"_id": id}, nil, time.Now())
+
+	return repo.Repository.GetByID(ctx, id)
+}
+
+// GetByFilter retrieves a specific document by the given filter
+func (repo SlowQueryRepository[K, T]) GetByFilter(ctx context.Context, filter primitive.M) (T, error) {
+	defer repo.observe("get_by_filter", filter, nil, time.Now())
+
+	return repo.Repository.GetByFilter(ctx, filter)
+}
+
+// GetAll retrieves all documents matching the given filter
+func (repo SlowQueryRepository[K, T]) GetAll(ctx context.Context, filter primitive.M, findOpts filters.Filters) ([]T, filters.Metadata, error) {
+	defer repo.observe("get_all", filter, findOptsProperties(findOpts), time.Now())
+
+	return repo.Repository.GetAll(ctx, filter, findOpts)
+}
+
+// GetAllWithOptions retrieves all documents matching the given filter with the given listing options
+func (repo SlowQueryRepository[K, T]) GetAllWithOptions(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+	listOpts ListOptions,
+) ([]T, Metadata, error) {
+	defer repo.observe("get_all", filter, findOptsProperties(findOpts), time.Now())
+
+	return repo.Repository.GetAllWithOptions(ctx, filter, findOpts, listOpts)
+}
+
+// Aggregate runs the given aggregation pipeline
+func (repo SlowQueryRepository[K, T]) Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error {
+	defer repo.observe("aggregate", pipeline, nil, time.Now())
+
+	return repo.Repository.Aggregate(ctx, pipeline, results)
+}
+
+// Create inserts a new document
+func (repo SlowQueryRepository[K, T]) Create(ctx context.Context, entity T) (*K, error) {
+	defer repo.observe("create", nil, nil, time.Now())
+
+	return repo.Repository.Create(ctx, entity)
+}
+
+// Update updates a document
+func (repo SlowQueryRepository[K, T]) Update(ctx context.Context, entity T) error {
+	defer repo.observe("update", bson.M{"_id": entity.GetID()}, nil, time.Now())
+
+	return repo.Repository.Update(ctx, entity)
+}
+
+// Delete deletes the document with the given id
+func (repo SlowQueryRepository[K, T]) Delete(ctx context.Context, id K) error {
+	defer repo.observe("delete", bson.M{"_id": id}, nil, time.Now())
+
+	return repo.Repository.Delete(ctx, id)
+}
+
+// Restore writes the given documents
+func (repo SlowQueryRepository[K, T]) Restore(ctx context.Context, documents []Document[T]) error {
+	defer repo.observe("restore", nil, map[string]string{"documents": fmt.Sprint(len(documents))}, time.Now())
+
+	return repo.Repository.Restore(ctx, documents)
+}
+
+// observe logs and counts the operation which started at the given time if it was slower than the threshold
+func (repo SlowQueryRepository[K, T]) observe(operation string, query any, properties map[string]string, start time.Time) {
+	duration := time.Since(start)
+	if duration < repo.threshold {
+		return
+	}
+
+	repo.metrics.SlowQueriesCounter.WithLabelValues(repo.collection, operation).Inc()
+
+	if properties == nil {
+		properties = make(map[string]string, 4)
+	}
+
+	properties["collection"] = repo.collection
+	properties["operation"] = operation
+	properties["duration"] = duration.String()
+
+	if query != nil {
+		properties["filter"] = formatQuery(query)
+	}
+
+	repo.logger.Warning("Slow query", properties)
+}
+
+// findOptsProperties returns the log properties of the given listing options
+func findOptsProperties(findOpts filters.Filters) map[string]string {
+	return map[string]string{
+		"sort":      findOpts.Sort,
+		"page":      fmt.Sprint(findOpts.Page),
+		"page_size": fmt.Sprint(findOpts.PageSize),
+	}
+}
+
+// formatQuery formats a filter or an aggregation pipeline as relaxed Extended JSON
+func formatQuery(query any) string {
+	// Only documents can be marshaled at the top level, so the query is wrapped in one
+	formatted, err := bson.MarshalExtJSON(bson.D{{Key: "q", Value: query}}, false, false)
+	if err != nil {
+		return fmt.Sprint(query)
+	}
+
+	return string(formatted[len(`{"q":`) : len(formatted)-1])
+}
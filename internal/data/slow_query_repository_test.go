@@ -0,0 +1,91 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// delayedRepository takes the given delay to retrieve a document
+type delayedRepository struct {
+	Repository[primitive.ObjectID, Item]
+	delay time.Duration
+}
+
+// GetByFilter waits for the delay of the repository
+func (repo delayedRepository) GetByFilter(ctx context.Context, filter primitive.M) (Item, error) {
+	time.Sleep(repo.delay)
+
+	return Item{}, nil
+}
+
+func TestSlowQueryRepository(t *testing.T) {
+	metrics := NewSlowQueryMetrics("test")
+
+	tests := []struct {
+		testName     string
+		delay        time.Duration
+		wantedLogged bool
+		wantedCount  float64
+	}{
+		{"Fast query", 0, false, 0},
+		{"Slow query", 20 * time.Millisecond, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var output bytes.Buffer
+
+			counter := metrics.SlowQueriesCounter.WithLabelValues("items", "get_by_filter")
+			before := testutil.ToFloat64(counter)
+
+			repo := NewSlowQueryRepository[primitive.ObjectID, Item](
+				delayedRepository{delay: tt.delay},
+				"items",
+				10*time.Millisecond,
+				logger.New(&output, logger.LevelInfo),
+				metrics,
+			)
+
+			_, err := repo.GetByFilter(context.Background(), bson.M{"name": bson.M{"$regex": "^Pot"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			logged := strings.Contains(output.String(), `"filter":"{\"name\":{\"$regex\":\"^Pot\"}}"`)
+			if logged != tt.wantedLogged {
+				t.Errorf("want logged %t; got %t (%s)", tt.wantedLogged, logged, output.String())
+			}
+
+			if count := testutil.ToFloat64(counter) - before; count != tt.wantedCount {
+				t.Errorf("want %v slow queries; got %v", tt.wantedCount, count)
+			}
+		})
+	}
+}
+
+func TestFormatQuery(t *testing.T) {
+	tests := []struct {
+		testName    string
+		query       any
+		wantedQuery string
+	}{
+		{"Filter", bson.M{"price": bson.M{"$gt": 5}}, `{"price":{"$gt":5}}`},
+		{"Pipeline", []bson.D{{{Key: "$match", Value: bson.M{"name": "Potion"}}}}, `[{"$match":{"name":"Potion"}}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if query := formatQuery(tt.query); query != tt.wantedQuery {
+				t.Errorf("want %s; got %s", tt.wantedQuery, query)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RelevanceSort is the sort value ordering the results of a text search by relevance (best matches first)
+const RelevanceSort = "relevance"
+
+// sortFields splits a comma separated sort parameter (i.e. "name,-price") into its fields
+func sortFields(sort string) []string {
+	return strings.Split(sort, ",")
+}
+
+// ValidateFilters is a helper function that validates filters received as query parameters.
+// It behaves like the common ValidateFilters helper but accepts a comma separated list of
+// sort fields, each of them being checked against the sort safelist.
+func ValidateFilters(v *validation.Validator, f filters.Filters) {
+	// Check that the page and page_size parameters contain sensible values
+	v.Check(validator.Between(f.Page, 0, 10_000_000), "page", "out_of_range", "must be greater or equal to 0 and lower or equal to 10 million")
+	v.Check(validator.Between(f.PageSize, 0, 100), "page_size", "out_of_range", "must be greater or equal to 0 and lower or equal to 100")
+
+	// Check that every sort field matches a value in the safelist and is only sorted once
+	columns := make(map[string]bool)
+
+	for _, field := range sortFields(f.Sort) {
+		v.Check(validator.In(field, f.SortSafelist...), "sort", "unsupported_value", "invalid sort value")
+
+		column := strings.TrimPrefix(field, "-")
+		v.Check(!columns[column], "sort", "duplicate", "must not contain the same field more than once")
+		columns[column] = true
+	}
+}
+
+// sortDocument converts the sort parameter of the given filters into a compound MongoDB sort.
+// The relevance sort uses the text score of the documents and therefore requires a $text filter.
+// A secondary sort on the id is included to ensure a consistent ordering.
+func sortDocument(f filters.Filters) bson.D {
+	return compoundSort(f, bson.E{Key: "score", Value: bson.M{"$meta": "textScore"}})
+}
+
+// compoundSort converts the sort parameter of the given filters into a compound MongoDB sort
+// in which the relevance sort is replaced by the given sort element. The popularity sort uses
+// the popularity score of the items.
+func compoundSort(f filters.Filters, relevance bson.E) bson.D {
+	sort := bson.D{}
+	sortsByID := false
+
+	for _, field := range sortFields(f.Sort) {
+		fieldFilters := f
+		fieldFilters.Sort = field
+
+		if field == RelevanceSort && validator.In(field, f.SortSafelist...) {
+			sort = append(sort, relevance)
+			continue
+		}
+
+		// SortColumn panics if the field is not in the safelist
+		column := fieldFilters.SortColumn()
+
+		// The popularity score is stored along with the view and purchase counts
+		if column == PopularitySort {
+			column = "popularity.score"
+		}
+		sort = append(sort, bson.E{Key: column, Value: fieldFilters.SortDirectionMongo()})
+
+		if column == "_id" {
+			sortsByID = true
+		}
+	}
+
+	if !sortsByID {
+		sort = append(sort, bson.E{Key: "_id", Value: 1})
+	}
+
+	return sort
+}
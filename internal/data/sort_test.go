@@ -0,0 +1,62 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/validation"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var sortSafelist = []string{"_id", "name", "price", "-_id", "-name", "-price", RelevanceSort, PopularitySort, "-" + PopularitySort}
+
+func TestValidateFilters(t *testing.T) {
+	tests := []struct {
+		testName    string
+		sort        string
+		wantsErrors bool
+	}{
+		{"Single field", "name", false},
+		{"Multiple fields", "-price,name", false},
+		{"Invalid field", "invalid", true},
+		{"Invalid field in list", "name,invalid", true},
+		{"Empty field in list", "name,", true},
+		{"Same field twice", "price,-price", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			v := validation.New()
+			ValidateFilters(v, filters.Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortSafelist: sortSafelist})
+
+			if v.HasErrors() != tt.wantsErrors {
+				t.Errorf("want errors %t; got %v", tt.wantsErrors, v.Errors)
+			}
+		})
+	}
+}
+
+func TestSortDocument(t *testing.T) {
+	tests := []struct {
+		testName     string
+		sort         string
+		expectedSort bson.D
+	}{
+		{"Single field", "name", bson.D{{Key: "name", Value: int8(1)}, {Key: "_id", Value: 1}}},
+		{"Multiple fields", "-price,name", bson.D{{Key: "price", Value: int8(-1)}, {Key: "name", Value: int8(1)}, {Key: "_id", Value: 1}}},
+		{"Sort by id", "-_id", bson.D{{Key: "_id", Value: int8(-1)}}},
+		{"Sort by popularity", "-popularity", bson.D{{Key: "popularity.score", Value: int8(-1)}, {Key: "_id", Value: 1}}},
+		{"Sort by relevance", "relevance,name", bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "name", Value: int8(1)}, {Key: "_id", Value: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			sort := sortDocument(filters.Filters{Sort: tt.sort, SortSafelist: sortSafelist})
+
+			if !reflect.DeepEqual(sort, tt.expectedSort) {
+				t.Errorf("want %v; got %v", tt.expectedSort, sort)
+			}
+		})
+	}
+}
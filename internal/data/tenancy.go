@@ -0,0 +1,214 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TenantField is the field of the items holding the tenant they belong to
+const TenantField = "tenant_id"
+
+// ErrCrossTenant is returned when a write targets an item which belongs to another tenant
+var ErrCrossTenant = errors.New("item belongs to another tenant")
+
+// tenantContextKey is the key used for getting and setting the tenant in a context
+type tenantContextKey struct{}
+
+// ContextWithTenant returns a copy of the given context holding the given tenant
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext retrieves the tenant from the given context.
+// It returns false if the context does not hold any tenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+
+	return tenant, ok && tenant != ""
+}
+
+// searchStages are the aggregation stages which must be the first stage of a pipeline
+var searchStages = []string{"$search", "$searchMeta", "$geoNear"}
+
+// TenantRepository is an items repository which scopes every read and write to the tenant of the context.
+// Items of the other tenants are never returned and can't be modified. Operations whose context does not
+// hold any tenant (i.e. the background jobs) are scoped to the default tenant.
+type TenantRepository struct {
+	Repository[primitive.ObjectID, Item]
+	defaultTenant string
+}
+
+// NewTenantRepository creates a new items repository scoping the given repository to the tenants
+func NewTenantRepository(repository Repository[primitive.ObjectID, Item], defaultTenant string) Repository[primitive.ObjectID, Item] {
+	return &TenantRepository{
+		Repository:    repository,
+		defaultTenant: defaultTenant,
+	}
+}
+
+// tenant returns the tenant of the given context
+func (repo TenantRepository) tenant(ctx context.Context) string {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		return tenant
+	}
+
+	return repo.defaultTenant
+}
+
+// scope returns a copy of the given filter restricted to the tenant of the given context
+func (repo TenantRepository) scope(ctx context.Context, filter primitive.M) primitive.M {
+	scoped := make(primitive.M, len(filter)+1)
+	for key, value := range filter {
+		scoped[key] = value
+	}
+
+	scoped[TenantField] = repo.tenant(ctx)
+
+	return scoped
+}
+
+// GetByID retrieves the item of the tenant with the given id
+func (repo TenantRepository) GetByID(ctx context.Context, id primitive.ObjectID) (Item, error) {
+	return repo.Repository.GetByFilter(ctx, repo.scope(ctx, bson.M{"_id": id}))
+}
+
+// GetByFilter retrieves the first item of the tenant matching the given filter
+func (repo TenantRepository) GetByFilter(ctx context.Context, filter primitive.M) (Item, error) {
+	return repo.Repository.GetByFilter(ctx, repo.scope(ctx, filter))
+}
+
+// GetAll retrieves the items of the tenant matching the given filter
+func (repo TenantRepository) GetAll(ctx context.Context, filter primitive.M, findOpts filters.Filters) ([]Item, filters.Metadata, error) {
+	return repo.Repository.GetAll(ctx, repo.scope(ctx, filter), findOpts)
+}
+
+// GetAllWithOptions behaves like GetAll with the given listing options
+func (repo TenantRepository) GetAllWithOptions(
+	ctx context.Context,
+	filter primitive.M,
+	findOpts filters.Filters,
+	listOpts ListOptions,
+) ([]Item, Metadata, error) {
+	return repo.Repository.GetAllWithOptions(ctx, repo.scope(ctx, filter), findOpts, listOpts)
+}
+
+// Create inserts a new item belonging to the tenant
+func (repo TenantRepository) Create(ctx context.Context, item Item) (*primitive.ObjectID, error) {
+	item.TenantID = repo.tenant(ctx)
+
+	return repo.Repository.Create(ctx, item)
+}
+
+// Update updates an item of the tenant. It returns database.ErrRecordNotFound
+// if the item belongs to another tenant.
+func (repo TenantRepository) Update(ctx context.Context, item Item) error {
+	err := repo.checkOwnership(ctx, item.ID)
+	if err != nil {
+		return err
+	}
+
+	item.TenantID = repo.tenant(ctx)
+
+	return repo.Repository.Update(ctx, item)
+}
+
+// Delete deletes an item of the tenant. It returns database.ErrRecordNotFound
+// if the item belongs to another tenant.
+func (repo TenantRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	err := repo.checkOwnership(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return repo.Repository.Delete(ctx, id)
+}
+
+// checkOwnership returns database.ErrRecordNotFound if the tenant does not have an item with the given id
+func (repo TenantRepository) checkOwnership(ctx context.Context, id primitive.ObjectID) error {
+	_, err := repo.GetByID(ctx, id)
+
+	return err
+}
+
+// Aggregate runs the given aggregation pipeline against the items of the tenant.
+// The items are matched right after the search stage of the pipeline, if any, since it must come first.
+func (repo TenantRepository) Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error {
+	match := bson.D{{Key: "$match", Value: bson.M{TenantField: repo.tenant(ctx)}}}
+
+	position := 0
+	if len(pipeline) != 0 && len(pipeline[0]) != 0 && validator.In(pipeline[0][0].Key, searchStages...) {
+		position = 1
+	}
+
+	scoped := make(mongo.Pipeline, 0, len(pipeline)+1)
+	scoped = append(scoped, pipeline[:position]...)
+	scoped = append(scoped, match)
+	scoped = append(scoped, pipeline[position:]...)
+
+	return repo.Repository.Aggregate(ctx, scoped, results)
+}
+
+// Export calls fn with every item of the tenant in the order of their ids
+func (repo TenantRepository) Export(ctx context.Context, snapshot bool, fn func(document Document[Item]) error) error {
+	tenant := repo.tenant(ctx)
+
+	return repo.Repository.Export(ctx, snapshot, func(document Document[Item]) error {
+		if document.Entity.TenantID != tenant {
+			return nil
+		}
+
+		return fn(document)
+	})
+}
+
+// Restore writes the given items for the tenant. It returns ErrCrossTenant without writing
+// anything if one of the items belongs to another tenant.
+func (repo TenantRepository) Restore(ctx context.Context, documents []Document[Item]) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	tenant := repo.tenant(ctx)
+
+	ids := make([]primitive.ObjectID, 0, len(documents))
+	scoped := make([]Document[Item], 0, len(documents))
+
+	for _, document := range documents {
+		document.Entity.TenantID = tenant
+
+		ids = append(ids, document.Entity.ID)
+		scoped = append(scoped, document)
+	}
+
+	_, err := repo.Repository.GetByFilter(ctx, bson.M{"_id": bson.M{"$in": ids}, TenantField: bson.M{"$ne": tenant}})
+	switch {
+	case err == nil:
+		return ErrCrossTenant
+	case !errors.Is(err, database.ErrRecordNotFound):
+		return err
+	}
+
+	return repo.Repository.Restore(ctx, scoped)
+}
+
+// BackfillTenant assigns the given tenant to the items of the given collection which do not belong to any tenant,
+// such as the items created before the catalog was shared between tenants. It returns the number of updated items.
+func BackfillTenant(ctx context.Context, client *mongo.Client, databaseName, collectionName, tenant string) (int64, error) {
+	result, err := client.Database(databaseName).Collection(collectionName).UpdateMany(
+		ctx,
+		bson.M{TenantField: bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{TenantField: tenant}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
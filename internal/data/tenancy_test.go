@@ -0,0 +1,77 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// pipelineRepository records the pipelines it runs
+type pipelineRepository struct {
+	Repository[primitive.ObjectID, Item]
+	pipeline mongo.Pipeline
+}
+
+// Aggregate records the given pipeline
+func (repo *pipelineRepository) Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error {
+	repo.pipeline = pipeline
+
+	return nil
+}
+
+func TestTenantRepositoryAggregate(t *testing.T) {
+	search := bson.D{{Key: "$search", Value: bson.M{"index": "items"}}}
+	match := bson.D{{Key: "$match", Value: bson.M{"price": 5}}}
+
+	tests := []struct {
+		testName       string
+		ctx            context.Context
+		pipeline       mongo.Pipeline
+		wantedPosition int
+		wantedTenant   string
+	}{
+		{"Tenant of the context", ContextWithTenant(context.Background(), "eu-1"), mongo.Pipeline{match}, 0, "eu-1"},
+		{"Default tenant", context.Background(), mongo.Pipeline{match}, 0, "default"},
+		{"Search stage kept first", ContextWithTenant(context.Background(), "eu-1"), mongo.Pipeline{search, match}, 1, "eu-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			inner := &pipelineRepository{}
+			repo := NewTenantRepository(inner, "default")
+
+			err := repo.Aggregate(tt.ctx, tt.pipeline, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(inner.pipeline) != len(tt.pipeline)+1 {
+				t.Fatalf("want %d stages; got %d", len(tt.pipeline)+1, len(inner.pipeline))
+			}
+
+			stage := inner.pipeline[tt.wantedPosition][0]
+			if stage.Key != "$match" || stage.Value.(bson.M)[TenantField] != tt.wantedTenant {
+				t.Errorf("want stage %d to match tenant %q; got %v", tt.wantedPosition, tt.wantedTenant, stage)
+			}
+		})
+	}
+}
+
+func TestTenantRepositoryScope(t *testing.T) {
+	repo := TenantRepository{defaultTenant: "default"}
+	filter := bson.M{"name": "Potion", TenantField: "other"}
+
+	scoped := repo.scope(ContextWithTenant(context.Background(), "eu-1"), filter)
+
+	if scoped[TenantField] != "eu-1" || scoped["name"] != "Potion" {
+		t.Errorf("want filter scoped to tenant %q; got %v", "eu-1", scoped)
+	}
+
+	// The filter of the caller is left untouched
+	if filter[TenantField] != "other" {
+		t.Errorf("want filter to be copied; got %v", filter)
+	}
+}
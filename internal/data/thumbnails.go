@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Thumbnail is a struct that defines a downscaled copy of an image of an item
+type Thumbnail struct {
+	Size   string `json:"size" bson:"size"` // Name of the size (i.e. "small")
+	Key    string `json:"key" bson:"key"`
+	URL    string `json:"url" bson:"url"`
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+}
+
+// Thumbnail returns the thumbnail of the image with the given size
+func (image ItemImage) Thumbnail(size string) (Thumbnail, bool) {
+	for _, thumbnail := range image.Thumbnails {
+		if thumbnail.Size == size {
+			return thumbnail, true
+		}
+	}
+
+	return Thumbnail{}, false
+}
+
+// PendingImage is a struct that defines an image of an item whose thumbnails were not generated yet
+type PendingImage struct {
+	ItemID primitive.ObjectID
+	Image  ItemImage
+}
+
+// ThumbnailsStore is a struct that records the thumbnails of the images of the items.
+// The thumbnails are set on the image they were generated from, without replacing the item.
+type ThumbnailsStore struct {
+	items   *mongo.Collection
+	indexer ItemsIndexer // Nil when the items are not mirrored in a search index
+}
+
+// NewThumbnailsStore creates a new thumbnails store for the given database re-indexing the items with the given indexer
+func NewThumbnailsStore(client *mongo.Client, databaseName string, indexer ItemsIndexer) *ThumbnailsStore {
+	return &ThumbnailsStore{
+		items:   client.Database(databaseName).Collection(constants.ItemsCollection),
+		indexer: indexer,
+	}
+}
+
+// SetThumbnails records the given thumbnails on the image with the given key of the item with the given id.
+// The version of the item is incremented so that the clients caching it see the thumbnails, and the item is re-indexed.
+// It returns false when the item or the image no longer exist.
+func (store *ThumbnailsStore) SetThumbnails(ctx context.Context, itemID primitive.ObjectID, key string, thumbnails []Thumbnail) (bool, error) {
+	filter := bson.M{"_id": itemID, "images.key": key}
+	update := bson.M{
+		"$set": bson.M{"images.$.thumbnails": thumbnails, "updated_at": time.Now().UTC()},
+		"$inc": bson.M{"version": int32(1)},
+	}
+
+	result, err := store.items.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	reindex(ctx, store.indexer, itemID)
+
+	return true, nil
+}
+
+// PendingImages returns the images of the given content types whose thumbnails were not generated,
+// from up to the given number of items
+func (store *ThumbnailsStore) PendingImages(ctx context.Context, contentTypes []string, limit int64) ([]PendingImage, error) {
+	pending := bson.M{"thumbnails": bson.M{"$exists": false}, "content_type": bson.M{"$in": contentTypes}}
+
+	opts := options.Find().SetProjection(bson.M{"images": 1}).SetLimit(limit)
+
+	cursor, err := store.items.Find(ctx, bson.M{"images": bson.M{"$elemMatch": pending}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+
+	err = cursor.All(ctx, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []PendingImage{}
+
+	for _, item := range items {
+		for _, image := range item.Images {
+			if image.Thumbnails == nil && validator.In(image.ContentType, contentTypes...) {
+				images = append(images, PendingImage{ItemID: item.ID, Image: image})
+			}
+		}
+	}
+
+	return images, nil
+}
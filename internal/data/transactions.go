@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// afterCommitKey is the key used for getting and setting the hooks of a composite write in a context
+type afterCommitKey struct{}
+
+// afterCommitHooks is a struct that holds the functions to run once the writes of a composite write are committed
+type afterCommitHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+// add adds a function to run once the writes are committed
+func (h *afterCommitHooks) add(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hooks = append(h.hooks, fn)
+}
+
+// reset drops the functions added by a previous run of a transaction, which was aborted
+func (h *afterCommitHooks) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hooks = nil
+}
+
+// run runs the added functions in order
+func (h *afterCommitHooks) run() {
+	h.mu.Lock()
+	hooks := h.hooks
+	h.hooks = nil
+	h.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// AfterCommit runs fn once the writes of the composite write of the given context are committed (i.e. to mirror
+// them in the search index), or right away when the context does not belong to a composite write. fn is dropped
+// when the transaction of the composite write is aborted.
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommitHooks)
+	if !ok {
+		fn()
+		return
+	}
+
+	hooks.add(fn)
+}
+
+// Transactions runs the composite writes, which change several documents or collections, within MongoDB
+// transactions so that a partial failure can't leave the collections out of sync. Transactions require
+// a replica set or a sharded cluster, so the writes run without transaction when they are disabled.
+type Transactions struct {
+	client  *mongo.Client
+	enabled bool
+}
+
+// NewTransactions returns a new Transactions starting the transactions on the given client when enabled
+func NewTransactions(client *mongo.Client, enabled bool) *Transactions {
+	return &Transactions{client: client, enabled: enabled}
+}
+
+// Enabled checks if the composite writes run within transactions
+func (t *Transactions) Enabled() bool {
+	return t.enabled
+}
+
+// Run runs fn within a transaction, which is committed if fn succeeds and aborted otherwise. Every operation
+// of the transaction must use the context given to fn. fn may be run again when the transaction fails with
+// a transient error (i.e. a write conflict or a primary election), so it must not keep state between its runs.
+// When the transactions are disabled, or when the given context already belongs to a session (i.e. a composite
+// write calling another one), fn runs once with the given context.
+// The functions given to AfterCommit by fn run once the transaction is committed. Without transaction, they run
+// once fn returns, even if it failed, since its writes are not rolled back.
+func (t *Transactions) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, nested := ctx.Value(afterCommitKey{}).(*afterCommitHooks); nested || mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	hooks := &afterCommitHooks{}
+	ctx = context.WithValue(ctx, afterCommitKey{}, hooks)
+
+	if !t.enabled {
+		err := fn(ctx)
+		hooks.run()
+
+		return err
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (any, error) {
+		hooks.reset()
+
+		return nil, fn(sessionCtx)
+	})
+	if err != nil {
+		return err
+	}
+
+	hooks.run()
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAfterCommit(t *testing.T) {
+	errWrite := errors.New("write failed")
+
+	tests := []struct {
+		testName  string
+		err       error
+		wantedRun []string
+	}{
+		{"Success", nil, []string{"fn", "first", "second"}},
+		// Without transaction, the writes of a failed composite write are not rolled back
+		{"Failure", errWrite, []string{"fn", "first", "second"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var run []string
+
+			transactions := NewTransactions(nil, false)
+
+			err := transactions.Run(context.Background(), func(ctx context.Context) error {
+				AfterCommit(ctx, func() { run = append(run, "first") })
+
+				// A nested composite write shares the hooks of the outer one
+				_ = transactions.Run(ctx, func(ctx context.Context) error {
+					AfterCommit(ctx, func() { run = append(run, "second") })
+					return nil
+				})
+
+				run = append(run, "fn")
+
+				return tt.err
+			})
+			if err != tt.err {
+				t.Errorf("want %v; got %v", tt.err, err)
+			}
+
+			if !reflect.DeepEqual(run, tt.wantedRun) {
+				t.Errorf("want %v; got %v", tt.wantedRun, run)
+			}
+		})
+	}
+
+	t.Run("Without composite write", func(t *testing.T) {
+		run := false
+
+		AfterCommit(context.Background(), func() { run = true })
+
+		if !run {
+			t.Error("want the function to run right away")
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package data
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// UserCacheMetrics is a struct that holds some prometheus metrics regarding the cache of the users
+type UserCacheMetrics struct {
+	HitsCounter   prometheus.Counter
+	MissesCounter prometheus.Counter
+}
+
+// NewUserCacheMetrics creates counters used to keep track of the cache of the users in our application
+func NewUserCacheMetrics(appName string) *UserCacheMetrics {
+	hitsCounter := promauto.NewCounter(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_user_cache_hits_total", appName),
+		Help: "The total number of users authenticating a request which were found in the cache",
+	})
+
+	missesCounter := promauto.NewCounter(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_user_cache_misses_total", appName),
+		Help: "The total number of users authenticating a request which were read from MongoDB",
+	})
+
+	return &UserCacheMetrics{
+		HitsCounter:   hitsCounter,
+		MissesCounter: missesCounter,
+	}
+}
+
+// cachedUser is a user kept in the cache until it expires
+type cachedUser struct {
+	user      User
+	expiresAt time.Time
+}
+
+// heldUser is a user which is not cached again until it is stored at the given version or the hold expires
+type heldUser struct {
+	version   int32 // Version of the user in the Identity microservice, zero to hold any version
+	expiresAt time.Time
+}
+
+// UserCache is a struct that keeps the users in memory for a limited time so that authenticating a request
+// does not need a MongoDB round trip. The users are invalidated as soon as their changes are received.
+type UserCache struct {
+	ttl        time.Duration
+	maxEntries int
+	metrics    *UserCacheMetrics
+	now        func() time.Time
+
+	mu     sync.Mutex
+	users  map[int64]cachedUser
+	held   map[int64]heldUser
+	hits   uint64
+	misses uint64
+}
+
+// NewUserCache returns a new empty UserCache. The cache stays empty when its TTL is zero.
+func NewUserCache(cfg settings.UserCache, metrics *UserCacheMetrics) *UserCache {
+	return &UserCache{
+		ttl:        time.Duration(cfg.TTL) * time.Second,
+		maxEntries: cfg.MaxEntries,
+		metrics:    metrics,
+		now:        time.Now,
+		users:      make(map[int64]cachedUser),
+		held:       make(map[int64]heldUser),
+	}
+}
+
+// Get returns the cached user with the given id unless it is not cached or expired
+func (cache *UserCache) Get(id int64) (User, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cached, ok := cache.users[id]
+	if !ok || !cache.now().Before(cached.expiresAt) {
+		cache.metrics.MissesCounter.Inc()
+		cache.misses++
+		return User{}, false
+	}
+
+	cache.metrics.HitsCounter.Inc()
+	cache.hits++
+
+	return cached.user, true
+}
+
+// Set caches the given user unless the cache is disabled or the user is held. When the cache is full, the
+// expired users are evicted first and then arbitrary ones.
+func (cache *UserCache) Set(user User) {
+	if cache.ttl == 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := cache.now()
+
+	if held, ok := cache.held[user.ID]; ok {
+		if now.Before(held.expiresAt) && (held.version == 0 || user.SourceVersion < held.version) {
+			return
+		}
+
+		delete(cache.held, user.ID)
+	}
+
+	if _, ok := cache.users[user.ID]; !ok && len(cache.users) >= cache.maxEntries {
+		for id, cached := range cache.users {
+			if !now.Before(cached.expiresAt) {
+				delete(cache.users, id)
+			}
+		}
+
+		for id := range cache.users {
+			if len(cache.users) < cache.maxEntries {
+				break
+			}
+
+			delete(cache.users, id)
+		}
+	}
+
+	cache.users[user.ID] = cachedUser{user: user, expiresAt: now.Add(cache.ttl)}
+}
+
+// EvictExpired removes the expired users from the cache and returns the number of removed users
+func (cache *UserCache) EvictExpired() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := cache.now()
+	evicted := 0
+
+	for id, cached := range cache.users {
+		if !now.Before(cached.expiresAt) {
+			delete(cache.users, id)
+			evicted++
+		}
+	}
+
+	for id, held := range cache.held {
+		if !now.Before(held.expiresAt) {
+			delete(cache.held, id)
+		}
+	}
+
+	return evicted
+}
+
+// UserCacheStats is a struct that holds the number of lookups of the users cache since the start of the service
+type UserCacheStats struct {
+	Entries  int     `json:"entries"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"` // Zero before the first lookup
+}
+
+// Stats returns the number of cached users and of the lookups of the cache
+func (cache *UserCache) Stats() UserCacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	stats := UserCacheStats{Entries: len(cache.users), Hits: cache.hits, Misses: cache.misses}
+
+	if lookups := cache.hits + cache.misses; lookups != 0 {
+		stats.HitRatio = float64(cache.hits) / float64(lookups)
+	}
+
+	return stats
+}
+
+// Invalidate removes the user with the given id from the cache
+func (cache *UserCache) Invalidate(id int64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.users, id)
+}
+
+// Hold invalidates the user with the given id and does not cache it again until it is stored at the given
+// version of the Identity microservice, or at any version when it is zero, for at most the TTL of the cache.
+// It is used by the instances which are notified of a change before it is applied by the instance storing it.
+func (cache *UserCache) Hold(id int64, version int32) {
+	if cache.ttl == 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.users, id)
+
+	cache.held[id] = heldUser{version: version, expiresAt: cache.now().Add(cache.ttl)}
+}
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// testUserCacheMetrics are shared by the caches of the tests since prometheus metrics can only be registered once
+var testUserCacheMetrics = NewUserCacheMetrics("user_cache_test")
+
+// newTestUserCache returns a cache whose clock is controlled by the returned function
+func newTestUserCache(cfg settings.UserCache) (*UserCache, func(time.Duration)) {
+	now := time.Now()
+
+	cache := NewUserCache(cfg, testUserCacheMetrics)
+	cache.now = func() time.Time { return now }
+
+	return cache, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestUserCache(t *testing.T) {
+	t.Run("Cached until expired", func(t *testing.T) {
+		cache, advance := newTestUserCache(settings.UserCache{TTL: 30, MaxEntries: 10})
+		cache.Set(User{ID: 1, Version: 2})
+
+		user, ok := cache.Get(1)
+		if !ok || user.Version != 2 {
+			t.Errorf("want cached user of version %d; got %v (cached %t)", 2, user, ok)
+		}
+
+		advance(30 * time.Second)
+
+		if _, ok := cache.Get(1); ok {
+			t.Error("want expired user; got cached user")
+		}
+	})
+
+	t.Run("Invalidated", func(t *testing.T) {
+		cache, _ := newTestUserCache(settings.UserCache{TTL: 30, MaxEntries: 10})
+		cache.Set(User{ID: 1})
+		cache.Invalidate(1)
+
+		if _, ok := cache.Get(1); ok {
+			t.Error("want invalidated user; got cached user")
+		}
+	})
+
+	t.Run("Held", func(t *testing.T) {
+		tests := []struct {
+			testName      string
+			heldVersion   int32
+			storedVersion int32
+			elapsed       time.Duration
+			wantedCached  bool
+		}{
+			{"Previous version", 3, 2, 0, false},
+			{"Held version", 3, 3, 0, true},
+			{"Newer version", 3, 4, 0, true},
+			{"Any version", 0, 4, 0, false},
+			{"Hold expired", 3, 2, 30 * time.Second, true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.testName, func(t *testing.T) {
+				cache, advance := newTestUserCache(settings.UserCache{TTL: 30, MaxEntries: 10})
+				cache.Set(User{ID: 1, SourceVersion: tt.storedVersion})
+				cache.Hold(1, tt.heldVersion)
+
+				if _, ok := cache.Get(1); ok {
+					t.Error("want held user to be invalidated; got cached user")
+				}
+
+				advance(tt.elapsed)
+				cache.Set(User{ID: 1, SourceVersion: tt.storedVersion})
+
+				if _, ok := cache.Get(1); ok != tt.wantedCached {
+					t.Errorf("want cached %t; got %t", tt.wantedCached, ok)
+				}
+			})
+		}
+	})
+
+	t.Run("Expired users evicted first", func(t *testing.T) {
+		cache, advance := newTestUserCache(settings.UserCache{TTL: 30, MaxEntries: 2})
+		cache.Set(User{ID: 1})
+		advance(20 * time.Second)
+		cache.Set(User{ID: 2})
+		advance(15 * time.Second)
+		cache.Set(User{ID: 3})
+
+		if len(cache.users) != 2 {
+			t.Errorf("want %d; got %d", 2, len(cache.users))
+		}
+
+		if _, ok := cache.Get(2); !ok {
+			t.Error("want user 2 to be cached; got evicted")
+		}
+
+		if _, ok := cache.Get(3); !ok {
+			t.Error("want user 3 to be cached; got evicted")
+		}
+	})
+
+	t.Run("Expired users evicted", func(t *testing.T) {
+		cache, advance := newTestUserCache(settings.UserCache{TTL: 30, MaxEntries: 10})
+		cache.Set(User{ID: 1})
+		advance(20 * time.Second)
+		cache.Set(User{ID: 2})
+		advance(15 * time.Second)
+
+		if evicted := cache.EvictExpired(); evicted != 1 {
+			t.Errorf("want %d; got %d", 1, evicted)
+		}
+
+		if _, ok := cache.users[1]; ok || len(cache.users) != 1 {
+			t.Errorf("want only user 2 to be cached; got %d users", len(cache.users))
+		}
+	})
+
+	t.Run("Full cache", func(t *testing.T) {
+		cache, _ := newTestUserCache(settings.UserCache{TTL: 30, MaxEntries: 2})
+
+		for id := int64(1); id <= 5; id++ {
+			cache.Set(User{ID: id})
+		}
+
+		if len(cache.users) != 2 {
+			t.Errorf("want %d; got %d", 2, len(cache.users))
+		}
+
+		if _, ok := cache.Get(5); !ok {
+			t.Error("want last user to be cached; got evicted")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		cache, _ := newTestUserCache(settings.UserCache{TTL: 0, MaxEntries: 10})
+		cache.Set(User{ID: 1})
+
+		if _, ok := cache.Get(1); ok {
+			t.Error("want no cached user; got cached user")
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		cache, _ := newTestUserCache(settings.UserCache{TTL: 30, MaxEntries: 10})
+
+		if stats := cache.Stats(); stats.HitRatio != 0 {
+			t.Errorf("want hit ratio %v before the first lookup; got %v", 0.0, stats.HitRatio)
+		}
+
+		cache.Set(User{ID: 1})
+		cache.Get(1)
+		cache.Get(1)
+		cache.Get(1)
+		cache.Get(2)
+
+		stats := cache.Stats()
+		want := UserCacheStats{Entries: 1, Hits: 3, Misses: 1, HitRatio: 0.75}
+
+		if stats != want {
+			t.Errorf("want %+v; got %+v", want, stats)
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package data
+
+import (
+	"context"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/permissions"
+	"github.com/PlayEconomy37/Play.Common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// User is a struct that defines the projection of an user of the Identity microservice.
+// On top of the fields needed for authorization, it holds display metadata so that
+// actors can be rendered without calling the Identity microservice.
+type User struct {
+	ID            int64                   `json:"id" bson:"_id"`
+	Name          string                  `json:"name" bson:"name,omitempty"`
+	Email         string                  `json:"email" bson:"email,omitempty"`
+	Permissions   permissions.Permissions `json:"permissions" bson:"permissions"`
+	Activated     bool                    `json:"activated" bson:"activated"`
+	Version       int32                   `json:"version" bson:"version"`
+	SourceVersion int32                   `json:"-" bson:"source_version,omitempty"` // Version of the user in the Identity microservice
+}
+
+// GetID returns the id of an user.
+// This method is necessary for our generic constraint of our mongo repository.
+func (u User) GetID() int64 {
+	return u.ID
+}
+
+// GetVersion returns the version of an user.
+// This method is necessary for our generic constraint of our mongo repository.
+func (u User) GetVersion() int32 {
+	return u.Version
+}
+
+// SetVersion sets the version of an user to the given value and returns the user.
+// This method is necessary for our generic constraint of our mongo repository.
+func (u User) SetVersion(version int32) User {
+	u.Version = version
+
+	return u
+}
+
+// AuthUser converts the user into the user expected by the common authentication middlewares
+func (u User) AuthUser() database.User {
+	return database.User{
+		ID:          u.ID,
+		Permissions: u.Permissions,
+		Activated:   u.Activated,
+		Version:     u.Version,
+	}
+}
+
+// UsersAuthRepository adapts the users repository to the repository expected by
+// the common Authenticate and RequirePermission middlewares
+type UsersAuthRepository struct {
+	users types.MongoRepository[int64, User]
+	cache *UserCache // Nil when the users are not cached
+}
+
+// NewUsersAuthRepository creates a new UsersAuthRepository reading the users through the given cache, if any
+func NewUsersAuthRepository(users types.MongoRepository[int64, User], cache *UserCache) UsersAuthRepository {
+	return UsersAuthRepository{users: users, cache: cache}
+}
+
+// GetByID retrieves a specific user by its id.
+// Requests authenticated with an API key get the permissions of the key instead.
+func (repo UsersAuthRepository) GetByID(ctx context.Context, id int64) (database.User, error) {
+	if apiKey, ok := APIKeyFromContext(ctx); ok {
+		return apiKey.AuthUser(), nil
+	}
+
+	if repo.cache != nil {
+		if user, ok := repo.cache.Get(id); ok {
+			return user.AuthUser(), nil
+		}
+	}
+
+	user, err := repo.users.GetByID(ctx, id)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	if repo.cache != nil {
+		repo.cache.Set(user)
+	}
+
+	return user.AuthUser(), nil
+}
+
+// UserUpdate is a struct that defines an update of an user received from the Identity microservice.
+// The fields which are not known by the Identity microservice are left empty and are not changed.
+type UserUpdate struct {
+	ID          int64
+	Name        string
+	Email       string
+	Permissions permissions.Permissions
+	Activated   bool
+	Version     int32 // Version of the user in the Identity microservice, zero when it is not versioned
+	Complete    bool  // The update holds the whole user, i.e. when resynchronized, so its empty fields are cleared too
+}
+
+// UsersStore is a struct that applies the updates of the users received from the Identity microservice
+type UsersStore struct {
+	collection *mongo.Collection
+}
+
+// NewUsersStore creates a new UsersStore
+func NewUsersStore(client *mongo.Client, databaseName string) *UsersStore {
+	return &UsersStore{collection: client.Database(databaseName).Collection(database.UsersCollection)}
+}
+
+// Apply creates or updates the user of the given update unless the stored user is already at the same
+// or a newer version, in which case the update is discarded and false is returned. The version is checked
+// and the user written in a single operation so that concurrent deliveries can't overwrite a newer version.
+func (store *UsersStore) Apply(ctx context.Context, update UserUpdate) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	// Users whose version was never recorded accept any version
+	filter := bson.M{"_id": update.ID}
+	if update.Version != 0 {
+		filter["source_version"] = bson.M{"$not": bson.M{"$gte": update.Version}}
+	}
+
+	// The upsert fails with a duplicate key error when the stored user is at the same or a newer version
+	_, err := store.collection.UpdateOne(ctx, filter, userUpdateDocument(update), options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Delete deletes the user with the given id. It returns database.ErrRecordNotFound when there is no such user.
+func (store *UsersStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := store.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return database.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// IDs returns the ids of the stored users
+func (store *UsersStore) IDs(ctx context.Context) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	values, err := store.collection.Distinct(ctx, "_id", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(values))
+
+	for _, value := range values {
+		if id, ok := value.(int64); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// DeleteMany deletes the users with the given ids and returns the number of deleted users
+func (store *UsersStore) DeleteMany(ctx context.Context, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := store.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// userUpdateDocument returns the update document of the given update. The fields which are not
+// known by the Identity microservice are only set when the user is created.
+func userUpdateDocument(update UserUpdate) bson.M {
+	set := bson.M{}
+	setOnInsert := bson.M{}
+
+	// Every user should have default permissions so having none means that the permissions were not changed
+	switch {
+	case len(update.Permissions) != 0:
+		set["permissions"] = update.Permissions
+	case update.Complete:
+		set["permissions"] = permissions.Permissions{}
+	default:
+		setOnInsert["permissions"] = permissions.Permissions{}
+	}
+
+	if update.Activated || update.Complete {
+		set["activated"] = update.Activated
+	} else {
+		setOnInsert["activated"] = false
+	}
+
+	// Display metadata is only sent when it is known by the Identity microservice
+	if update.Name != "" {
+		set["name"] = update.Name
+	}
+
+	if update.Email != "" {
+		set["email"] = update.Email
+	}
+
+	if update.Version != 0 {
+		set["source_version"] = update.Version
+	}
+
+	document := bson.M{"$inc": bson.M{"version": int32(1)}}
+
+	if len(set) != 0 {
+		document["$set"] = set
+	}
+
+	if len(setOnInsert) != 0 {
+		document["$setOnInsert"] = setOnInsert
+	}
+
+	return document
+}
+
+// CreateUsersCollection creates users collection in MongoDB database.
+// If the collection already exists, its validator is migrated to the current JSON schema.
+func CreateUsersCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// JSON validation schema
+	jsonSchema := bson.M{
+		"bsonType":             "object",
+		"required":             []string{"permissions", "version"},
+		"additionalProperties": false,
+		"properties": bson.M{
+			"_id": bson.M{
+				"bsonType":    "long",
+				"description": "User ID",
+			},
+			"name": bson.M{
+				"bsonType":    "string",
+				"description": "Display name of the user",
+			},
+			"email": bson.M{
+				"bsonType":    "string",
+				"description": "Email of the user",
+			},
+			"permissions": bson.M{
+				"bsonType":    "array",
+				"description": "User permissions",
+			},
+			"activated": bson.M{
+				"bsonType":    "bool",
+				"description": "Flag to check if user is activated or not",
+			},
+			"version": bson.M{
+				"bsonType":    "int",
+				"description": "Document version",
+			},
+			"source_version": bson.M{
+				"bsonType":    "int",
+				"description": "Version of the user in the Identity microservice",
+			},
+		},
+	}
+
+	validator := bson.M{
+		"$jsonSchema": jsonSchema,
+	}
+
+	// Create collection
+	opts := options.CreateCollection().SetValidator(validator)
+	err := db.CreateCollection(context.Background(), database.UsersCollection, opts)
+	if err != nil {
+		// Collection already exists so we migrate its validator to the current schema
+		return db.RunCommand(
+			context.Background(),
+			bson.D{{Key: "collMod", Value: database.UsersCollection}, {Key: "validator", Value: validator}},
+		).Err()
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Common/permissions"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUserUpdateDocument(t *testing.T) {
+	tests := []struct {
+		testName string
+		update   UserUpdate
+		wanted   bson.M
+	}{
+		{
+			"Full update",
+			UserUpdate{ID: 1, Name: "John", Email: "john@example.com", Permissions: permissions.Permissions{"catalog:read"}, Activated: true, Version: 3},
+			bson.M{
+				"$inc": bson.M{"version": int32(1)},
+				"$set": bson.M{
+					"name":           "John",
+					"email":          "john@example.com",
+					"permissions":    permissions.Permissions{"catalog:read"},
+					"activated":      true,
+					"source_version": int32(3),
+				},
+			},
+		},
+		{
+			"Unknown fields only set on creation",
+			UserUpdate{ID: 1, Version: 4},
+			bson.M{
+				"$inc":         bson.M{"version": int32(1)},
+				"$set":         bson.M{"source_version": int32(4)},
+				"$setOnInsert": bson.M{"permissions": permissions.Permissions{}, "activated": false},
+			},
+		},
+		{
+			"Unversioned update",
+			UserUpdate{ID: 1, Activated: true, Permissions: permissions.Permissions{"catalog:read"}},
+			bson.M{
+				"$inc": bson.M{"version": int32(1)},
+				"$set": bson.M{"permissions": permissions.Permissions{"catalog:read"}, "activated": true},
+			},
+		},
+		{
+			"Complete update clears empty fields",
+			UserUpdate{ID: 1, Version: 5, Complete: true},
+			bson.M{
+				"$inc": bson.M{"version": int32(1)},
+				"$set": bson.M{"permissions": permissions.Permissions{}, "activated": false, "source_version": int32(5)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			got := userUpdateDocument(tt.update)
+
+			// Maps are printed with sorted keys
+			if fmt.Sprint(got) != fmt.Sprint(tt.wanted) {
+				t.Errorf("want %v; got %v", tt.wanted, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/permissions"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// User is a struct that defines an user as listed by the Identity microservice
+type User struct {
+	ID          int64                   `json:"id"`
+	Name        string                  `json:"name"`
+	Email       string                  `json:"email"`
+	Permissions permissions.Permissions `json:"permissions"`
+	Activated   bool                    `json:"activated"`
+	Version     int32                   `json:"version"`
+}
+
+// Client is a struct that lists the users of the Identity microservice
+type Client struct {
+	client   *http.Client
+	url      string
+	token    string
+	pageSize int
+}
+
+// NewClient creates a new Identity client from the given configuration
+func NewClient(cfg settings.Identity) *Client {
+	return &Client{
+		client:   &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Millisecond},
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		token:    cfg.Token,
+		pageSize: cfg.PageSize,
+	}
+}
+
+// ErrIncompleteListing is returned when the number of listed users differs from the total number of users
+// reported by the Identity microservice, i.e. when users were created or deleted during the listing
+var ErrIncompleteListing = errors.New("the number of listed users differs from the total number of users")
+
+// ListUsers retrieves every user of the Identity microservice by ascending id, a page at a time, and calls fn
+// with the users of each page along with the total number of users. Each page starts after the last id of the
+// previous one, so that a user created or deleted during the listing does not shift the next pages. It stops at
+// the first error and fails once every page is listed if the number of listed users is not the total number of users.
+func (c *Client) ListUsers(ctx context.Context, fn func(users []User, total int) error) error {
+	var afterID int64
+	listed, total := 0, 0
+
+	for page := 1; ; page++ {
+		users, metadata, err := c.fetch(ctx, afterID)
+		if err != nil {
+			return err
+		}
+
+		// The total of the first page is the number of users when the listing started
+		if page == 1 {
+			total = metadata.TotalRecords
+		}
+
+		if len(users) == 0 {
+			break
+		}
+
+		// A page which does not start after the previous one would be listed forever
+		if users[0].ID <= afterID {
+			return fmt.Errorf("the users are not listed after id %d", afterID)
+		}
+
+		err = fn(users, total)
+		if err != nil {
+			return err
+		}
+
+		listed += len(users)
+		afterID = users[len(users)-1].ID
+	}
+
+	if listed != total {
+		return fmt.Errorf("%w: %d listed, %d in total", ErrIncompleteListing, listed, total)
+	}
+
+	return nil
+}
+
+// fetch requests the page of the users whose id follows the given id from the Identity microservice
+func (c *Client) fetch(ctx context.Context, afterID int64) ([]User, filters.Metadata, error) {
+	query := url.Values{
+		"after_id":  []string{strconv.FormatInt(afterID, 10)},
+		"page_size": []string{strconv.Itoa(c.pageSize)},
+		"sort":      []string{"id"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/users?%s", c.url, query.Encode()), nil)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	// Propagate the trace context to the Identity microservice
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, filters.Metadata{}, fmt.Errorf("failed to retrieve the users: status %d", res.StatusCode)
+	}
+
+	var response struct {
+		Users    []User           `json:"users"`
+		Metadata filters.Metadata `json:"metadata"`
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	return response.Users, response.Metadata, nil
+}
@@ -0,0 +1,77 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+func TestListUsers(t *testing.T) {
+	tests := []struct {
+		testName      string
+		token         string
+		total         int
+		ignoreAfterID bool
+		wantedUsers   []int64
+		wantedError   bool
+	}{
+		{"Every page", "token", 3, false, []int64{1, 2, 3}, false},
+		{"Unauthorized", "", 3, false, nil, true},
+		{"Users missing from the listing", "token", 4, false, []int64{1, 2, 3}, true},
+		{"Pages not listed after the last id", "token", 3, true, []int64{1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// The Identity microservice holds the users 1 to 3 and lists one user per page
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				afterID, _ := strconv.Atoi(r.URL.Query().Get("after_id"))
+				if tt.ignoreAfterID {
+					afterID = 0
+				}
+
+				users := "[]"
+				if afterID < 3 {
+					users = fmt.Sprintf(`[{"id": %d, "permissions": ["catalog:read"], "activated": true, "version": 2}]`, afterID+1)
+				}
+
+				fmt.Fprintf(w, `{"users": %s, "metadata": {"total_records": %d}}`, users, tt.total)
+			}))
+			defer ts.Close()
+
+			client := NewClient(settings.Identity{URL: ts.URL, Token: tt.token, Timeout: 500, PageSize: 1})
+
+			var ids []int64
+
+			err := client.ListUsers(context.Background(), func(users []User, total int) error {
+				if total != tt.total {
+					t.Errorf("want %d users; got %d", tt.total, total)
+				}
+
+				for _, user := range users {
+					ids = append(ids, user.ID)
+				}
+
+				return nil
+			})
+
+			if (err != nil) != tt.wantedError {
+				t.Fatalf("want error %t; got %v", tt.wantedError, err)
+			}
+
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantedUsers) {
+				t.Errorf("want users %v; got %v", tt.wantedUsers, ids)
+			}
+		})
+	}
+}
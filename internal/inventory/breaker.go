@@ -0,0 +1,73 @@
+package inventory
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request is rejected because the Inventory microservice kept failing
+var ErrCircuitOpen = errors.New("inventory circuit breaker is open")
+
+// breaker is a circuit breaker which stops sending requests to a failing service.
+// The circuit opens after a number of consecutive failures. Once it has been open for the given duration,
+// a single trial request is allowed: the circuit closes if it succeeds and opens again otherwise.
+type breaker struct {
+	threshold    int
+	openDuration time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool // A trial request is in flight
+}
+
+// newBreaker returns a new closed breaker
+func newBreaker(threshold int, openDuration time.Duration) *breaker {
+	return &breaker{
+		threshold:    threshold,
+		openDuration: openDuration,
+		now:          time.Now,
+	}
+}
+
+// allow returns ErrCircuitOpen if a request must not be sent
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+
+	if b.trial || b.now().Sub(b.openedAt) < b.openDuration {
+		return ErrCircuitOpen
+	}
+
+	b.trial = true
+
+	return nil
+}
+
+// success records a successful request, which closes the circuit
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+// failure records a failed request, which opens the circuit once the threshold is reached
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,163 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// cachedStock is the stock of an item along with the time it was retrieved
+type cachedStock struct {
+	quantity    int64
+	retrievedAt time.Time
+}
+
+// Client is a struct that retrieves the stock of the items from the Inventory microservice.
+// Stock counts are cached for a short period and a circuit breaker stops calling the service
+// while it keeps failing.
+type Client struct {
+	client   *http.Client
+	url      string
+	cacheTTL time.Duration
+	breaker  *breaker
+
+	mu    sync.Mutex
+	cache map[string]cachedStock
+}
+
+// NewClient creates a new Inventory client from the given configuration
+func NewClient(cfg settings.Inventory) *Client {
+	return &Client{
+		client:   &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Millisecond},
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		cacheTTL: time.Duration(cfg.CacheTTL) * time.Second,
+		breaker:  newBreaker(cfg.FailureThreshold, time.Duration(cfg.OpenDuration)*time.Second),
+		cache:    make(map[string]cachedStock),
+	}
+}
+
+// GetStock returns the stock of the items with the given ids. Items unknown to the Inventory microservice
+// have no stock. The given authorization header is forwarded to the Inventory microservice.
+func (c *Client) GetStock(ctx context.Context, ids []string, authorization string) (map[string]int64, error) {
+	stock := make(map[string]int64, len(ids))
+	missing := c.cached(ids, stock)
+
+	if len(missing) == 0 {
+		return stock, nil
+	}
+
+	err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	fetched, err := c.fetch(ctx, missing, authorization)
+	if err != nil {
+		c.breaker.failure()
+		return nil, err
+	}
+
+	c.breaker.success()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	for _, id := range missing {
+		stock[id] = fetched[id]
+		c.cache[id] = cachedStock{quantity: fetched[id], retrievedAt: now}
+	}
+
+	return stock, nil
+}
+
+// cached copies the cached stock of the given items into stock and returns the ids of the items
+// which are not cached. Expired entries are removed from the cache.
+func (c *Client) cached(ids []string, stock map[string]int64) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	missing := []string{}
+
+	for _, id := range ids {
+		entry, ok := c.cache[id]
+		if ok && time.Since(entry.retrievedAt) < c.cacheTTL {
+			stock[id] = entry.quantity
+			continue
+		}
+
+		delete(c.cache, id)
+		missing = append(missing, id)
+	}
+
+	return missing
+}
+
+// EvictExpired removes the expired stock counts from the cache, including those of the items which are not
+// requested anymore, and returns the number of removed entries
+func (c *Client) EvictExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	evicted := 0
+
+	for id, entry := range c.cache {
+		if time.Since(entry.retrievedAt) >= c.cacheTTL {
+			delete(c.cache, id)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+// fetch requests the stock of the given items from the Inventory microservice
+func (c *Client) fetch(ctx context.Context, ids []string, authorization string) (map[string]int64, error) {
+	query := url.Values{"item_ids": []string{strings.Join(ids, ",")}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/stock?%s", c.url, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	// Propagate the trace context to the Inventory microservice
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to retrieve the stock of the items: status %d", res.StatusCode)
+	}
+
+	var response struct {
+		Stock map[string]int64 `json:"stock"`
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Stock, nil
+}
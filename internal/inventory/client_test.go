@@ -0,0 +1,105 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+func TestGetStock(t *testing.T) {
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(`{"stock": {"potion": 12}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(settings.Inventory{URL: ts.URL, Timeout: 500, CacheTTL: 60, FailureThreshold: 5, OpenDuration: 30})
+
+	stock, err := client.GetStock(context.Background(), []string{"potion", "ether"}, "Bearer token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Items unknown to the Inventory microservice have no stock
+	if stock["potion"] != 12 || stock["ether"] != 0 {
+		t.Errorf("want %d potions and %d ethers; got %v", 12, 0, stock)
+	}
+
+	// Stock is served from the cache
+	_, err = client.GetStock(context.Background(), []string{"potion"}, "Bearer token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("want %d request; got %d", 1, requests)
+	}
+
+	_, err = client.GetStock(context.Background(), []string{"antidote"}, "")
+	if err == nil {
+		t.Error("want error; got nil")
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("want closed circuit below the threshold; got %v", err)
+	}
+
+	b.failure()
+
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("want %v; got %v", ErrCircuitOpen, err)
+	}
+
+	// A single trial request is allowed once the circuit has been open long enough
+	now = now.Add(time.Minute)
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("want trial request; got %v", err)
+	}
+
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("want %v during the trial request; got %v", ErrCircuitOpen, err)
+	}
+
+	// A failed trial opens the circuit again
+	b.failure()
+
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("want %v after a failed trial; got %v", ErrCircuitOpen, err)
+	}
+
+	now = now.Add(time.Minute)
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("want trial request; got %v", err)
+	}
+
+	b.success()
+
+	if err := b.allow(); err != nil {
+		t.Errorf("want closed circuit after a successful trial; got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Package jetstream implements the publishing and the consumption of the events with NATS JetStream.
+package jetstream
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/nats-io/nats.go"
+)
+
+// Headers holding the properties of the messages
+const (
+	contentTypeHeader = "Content-Type"
+	messageIDHeader   = "Message-Id"
+	typeHeader        = "Type"
+)
+
+// subject returns the subject of the given route (i.e. "Play.Catalog.item-expired")
+func subject(route messaging.Route) string {
+	return strings.ReplaceAll(route.Exchange, ":", ".")
+}
+
+// Connect connects to the NATS server of the given configuration and returns the connection
+// along with its JetStream context. The connection is named after the service.
+func Connect(cfg settings.NATS, serviceName string) (*nats.Conn, nats.JetStreamContext, error) {
+	conn, err := nats.Connect(cfg.URL, nats.Name(serviceName), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, js, nil
+}
+
+// CreateStream creates the stream capturing the subjects of the events published by the service
+// or brings the subjects of the existing stream up to date
+func CreateStream(js nats.JetStreamContext, cfg settings.NATS) error {
+	subjects := []string{}
+	declared := make(map[string]bool)
+
+	for _, route := range messaging.Routes() {
+		if !declared[subject(route)] {
+			subjects = append(subjects, subject(route))
+			declared[subject(route)] = true
+		}
+	}
+
+	info, err := js.StreamInfo(cfg.Stream)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     cfg.Stream,
+			Subjects: subjects,
+			Storage:  nats.FileStorage,
+		})
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	streamConfig := info.Config
+	streamConfig.Subjects = subjects
+
+	_, err = js.UpdateStream(&streamConfig)
+
+	return err
+}
@@ -0,0 +1,252 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fetchBatch is the maximum number of messages fetched at once by a consumer
+const fetchBatch = 10
+
+// fetchWait is the maximum amount of time a consumer waits for messages before fetching them again
+const fetchWait = 5 * time.Second
+
+// Consumer is the NATS JetStream implementation of messaging.Consumer. It processes the messages
+// of the subject of a subscription with a durable pull consumer named "<service>-<subscription>".
+// Processed messages are acknowledged, the ones which cannot be processed are terminated and the
+// ones whose handler panicked are redelivered after a backoff until their maximum number of deliveries.
+// The messages of a broadcast subscription are processed by an ephemeral consumer of each instance instead.
+type Consumer struct {
+	js         nats.JetStreamContext
+	subject    string
+	durable    string
+	broadcast  bool // The consumer is ephemeral and only receives the messages published once it started
+	maxDeliver int
+	backoff    []time.Duration
+	handle     messaging.Handler
+	logger     *logger.Logger
+	tracer     trace.Tracer
+	metrics    *messaging.ConsumerMetrics
+}
+
+// NewConsumer returns a new Consumer processing the messages of the given subscription
+func NewConsumer(
+	js nats.JetStreamContext,
+	cfg settings.NATS,
+	subscription messaging.Subscription,
+	handle messaging.Handler,
+	serviceName string,
+	logger *logger.Logger,
+	metrics *messaging.ConsumerMetrics,
+) *Consumer {
+	backoff := make([]time.Duration, 0, len(cfg.Backoff))
+	for _, seconds := range cfg.Backoff {
+		backoff = append(backoff, time.Duration(seconds)*time.Second)
+	}
+
+	return &Consumer{
+		js:         js,
+		subject:    subject(subscription.Route),
+		durable:    fmt.Sprintf("%s-%s", serviceName, subscription.Name),
+		broadcast:  subscription.Broadcast,
+		maxDeliver: cfg.MaxDeliver,
+		backoff:    backoff,
+		handle:     handle,
+		logger:     logger,
+		tracer:     otel.Tracer(serviceName),
+		metrics:    metrics,
+	}
+}
+
+// StartConsumer creates the durable consumer of the subscription if needed, or the ephemeral consumer of a broadcast
+// subscription, and keeps fetching its messages.
+// The stream capturing the subject of the subscription must exist.
+func (consumer *Consumer) StartConsumer() error {
+	// Messages which are not acknowledged in time are also redelivered after the backoff
+	durable, deliver := consumer.durable, nats.DeliverAll()
+	if consumer.broadcast {
+		durable, deliver = "", nats.DeliverNew()
+	}
+
+	sub, err := consumer.js.PullSubscribe(
+		consumer.subject,
+		durable,
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		deliver,
+		nats.MaxDeliver(consumer.maxDeliver),
+		nats.BackOff(consumer.backoff),
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		messages, err := sub.Fetch(fetchBatch, nats.MaxWait(fetchWait))
+		switch {
+		case errors.Is(err, nats.ErrTimeout):
+			continue
+		case errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrBadSubscription):
+			return err
+		case err != nil:
+			// The connection is being reestablished
+			consumer.logger.Error(err, map[string]string{"consumer": consumer.durable})
+			time.Sleep(fetchWait)
+			continue
+		}
+
+		var wg sync.WaitGroup
+
+		for _, msg := range messages {
+			wg.Add(1)
+
+			go func(msg *nats.Msg) {
+				defer wg.Done()
+				consumer.handleMessage(msg)
+			}(msg)
+		}
+
+		wg.Wait()
+	}
+}
+
+// handleMessage decodes a delivered message, unwrapping its CloudEvent if any, and processes it while
+// recording metrics and a trace linked to the producer's trace context. A panic raised while processing
+// the message is recovered so that the consumer keeps running and the message is redelivered.
+func (consumer *Consumer) handleMessage(msg *nats.Msg) {
+	start := time.Now()
+
+	consumer.metrics.ConsumedMessagesCounter.WithLabelValues(consumer.durable).Inc()
+
+	delivered := 1
+	if metadata, err := msg.Metadata(); err == nil {
+		delivered = int(metadata.NumDelivered)
+	}
+
+	if delivered > 1 {
+		consumer.metrics.RedeliveredMessagesCounter.WithLabelValues(consumer.durable).Inc()
+	}
+
+	defer func() {
+		consumer.metrics.ProcessingTimeHistogram.WithLabelValues(consumer.durable).Observe(time.Since(start).Seconds())
+	}()
+
+	event, unwrapErr := messaging.UnwrapCloudEvent(newMessage(msg))
+
+	// Extract trace context from message headers
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(event.Headers))
+
+	// Create trace for the message
+	ctx, span := consumer.tracer.Start(
+		ctx,
+		fmt.Sprintf("%s process", consumer.durable),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("nats"),
+			semconv.MessagingDestinationKey.String(consumer.subject),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingMessageIDKey.String(event.ID),
+			attribute.Int("messaging.nats.num_delivered", delivered),
+		),
+	)
+	defer span.End()
+
+	err := unwrapErr
+	if err == nil {
+		err = messaging.RecoverPanic(func() error {
+			return consumer.handle(ctx, span, event)
+		})
+	}
+
+	if err == nil {
+		consumer.acknowledge(msg.Ack, event.ID)
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	consumer.metrics.FailedMessagesCounter.WithLabelValues(consumer.durable).Inc()
+
+	properties := map[string]string{
+		"consumer":   consumer.durable,
+		"message_id": event.ID,
+		"delivered":  fmt.Sprint(delivered),
+	}
+
+	var handlerPanic *messaging.PanicError
+	var transientErr *messaging.TransientError
+
+	switch {
+	case errors.As(err, &handlerPanic):
+		consumer.metrics.PanicsCounter.WithLabelValues(consumer.durable).Inc()
+		properties["panic_stack"] = string(handlerPanic.Stack)
+	case errors.As(err, &transientErr):
+		// The handler is likely to succeed once MongoDB is available again
+	default:
+		// Messages which cannot be processed are not redelivered
+		consumer.logger.Error(err, properties)
+		consumer.acknowledge(msg.Term, event.ID)
+		return
+	}
+
+	consumer.logger.Error(err, properties)
+
+	if delivered >= consumer.maxDeliver {
+		consumer.acknowledge(msg.Term, event.ID)
+		consumer.metrics.DeadLetteredCounter.WithLabelValues(consumer.durable).Inc()
+		return
+	}
+
+	delay := redeliveryDelay(consumer.backoff, delivered)
+
+	consumer.acknowledge(func(opts ...nats.AckOpt) error { return msg.NakWithDelay(delay, opts...) }, event.ID)
+}
+
+// acknowledge sends the given acknowledgement of a message
+func (consumer *Consumer) acknowledge(ack func(opts ...nats.AckOpt) error, messageID string) {
+	err := ack()
+	if err != nil {
+		consumer.logger.Error(err, map[string]string{"consumer": consumer.durable, "message_id": messageID})
+	}
+}
+
+// redeliveryDelay returns the delay before the redelivery of a message which was delivered the
+// given number of times. The last backoff duration is used once every duration was used.
+func redeliveryDelay(backoff []time.Duration, delivered int) time.Duration {
+	if delivered > len(backoff) {
+		return backoff[len(backoff)-1]
+	}
+
+	return backoff[delivered-1]
+}
+
+// newMessage converts a delivered message into a messaging.Message
+func newMessage(msg *nats.Msg) messaging.Message {
+	headers := make(map[string]string, len(msg.Header))
+
+	for key := range msg.Header {
+		headers[key] = msg.Header.Get(key)
+	}
+
+	return messaging.Message{
+		ID:          headers[messageIDHeader],
+		Type:        headers[typeHeader],
+		ContentType: headers[contentTypeHeader],
+		Headers:     headers,
+		Body:        msg.Data,
+	}
+}
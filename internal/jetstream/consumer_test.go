@@ -0,0 +1,56 @@
+package jetstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/nats-io/nats.go"
+)
+
+func TestRedeliveryDelay(t *testing.T) {
+	backoff := []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+
+	tests := []struct {
+		testName    string
+		delivered   int
+		wantedDelay time.Duration
+	}{
+		{"First delivery", 1, time.Second},
+		{"Third delivery", 3, 30 * time.Second},
+		{"Backoff exhausted", 5, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			delay := redeliveryDelay(backoff, tt.delivered)
+
+			if delay != tt.wantedDelay {
+				t.Errorf("want %s; got %s", tt.wantedDelay, delay)
+			}
+		})
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	msg := nats.NewMsg(subject(messaging.UserUpdatedSubscription.Route))
+	msg.Header.Set(contentTypeHeader, "application/json")
+	msg.Header.Set(messageIDHeader, "user-1")
+	msg.Header.Set(typeHeader, "user.updated.v1")
+	msg.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	msg.Data = []byte(`{"id": 1}`)
+
+	if msg.Subject != "Play.Identity.user-updated" {
+		t.Errorf("want subject %q; got %q", "Play.Identity.user-updated", msg.Subject)
+	}
+
+	event := newMessage(msg)
+
+	if event.ID != "user-1" || event.Type != "user.updated.v1" || event.ContentType != "application/json" {
+		t.Errorf("want message %q of type %q; got %+v", "user-1", "user.updated.v1", event)
+	}
+
+	if event.Headers["traceparent"] != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("want trace context to be kept; got %v", event.Headers)
+	}
+}
@@ -0,0 +1,85 @@
+package jetstream
+
+import (
+	"context"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/nats-io/nats.go"
+)
+
+// Publisher is the NATS JetStream implementation of messaging.BatchPublisher. It publishes the messages
+// to the subjects of the routes of the events and waits for their acknowledgement by the stream.
+type Publisher struct {
+	js nats.JetStreamContext
+}
+
+// NewPublisher returns a new Publisher
+func NewPublisher(js nats.JetStreamContext) *Publisher {
+	return &Publisher{js: js}
+}
+
+// System returns the name of the message broker
+func (publisher *Publisher) System() string {
+	return "nats"
+}
+
+// Destination returns the subject of the given route
+func (publisher *Publisher) Destination(route messaging.Route) string {
+	return subject(route)
+}
+
+// Send publishes the given message to the subject of the given route
+func (publisher *Publisher) Send(ctx context.Context, route messaging.Route, msg messaging.Message) error {
+	_, err := publisher.js.PublishMsg(newMsg(route, msg), nats.Context(ctx), msgID(msg))
+
+	return err
+}
+
+// SendBatch publishes the given messages asynchronously and waits for their acknowledgement by the stream
+func (publisher *Publisher) SendBatch(ctx context.Context, batch []messaging.Envelope) []error {
+	errs := make([]error, len(batch))
+	futures := make([]nats.PubAckFuture, len(batch))
+
+	for i, envelope := range batch {
+		futures[i], errs[i] = publisher.js.PublishMsgAsync(newMsg(envelope.Route, envelope.Message), msgID(envelope.Message))
+	}
+
+	for i, future := range futures {
+		if errs[i] != nil {
+			continue
+		}
+
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			errs[i] = err
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+
+	return errs
+}
+
+// newMsg converts a message into a NATS message of the subject of the given route. The properties
+// of the message are sent as headers along with the headers of the message.
+func newMsg(route messaging.Route, msg messaging.Message) *nats.Msg {
+	natsMsg := nats.NewMsg(subject(route))
+	natsMsg.Data = msg.Body
+
+	for key, value := range msg.Headers {
+		natsMsg.Header.Set(key, value)
+	}
+
+	natsMsg.Header.Set(contentTypeHeader, msg.ContentType)
+	natsMsg.Header.Set(messageIDHeader, msg.ID)
+	natsMsg.Header.Set(typeHeader, msg.Type)
+
+	return natsMsg
+}
+
+// msgID returns the deduplication ID of a message. Every version of an event has its own message ID
+// so the stream deduplicates the messages published twice for the same version only.
+func msgID(msg messaging.Message) nats.PubOpt {
+	return nats.MsgId(msg.ID)
+}
@@ -0,0 +1,121 @@
+// Package jobs runs the long operations of the catalog (i.e. the reindex of the search index) in the background.
+// The jobs are stored in MongoDB, from which they are claimed by the workers of every instance, so that their
+// progress, result and errors can be retrieved while they run and once they finished.
+package jobs
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statuses of the jobs
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// ErrUnknownType is returned when a job is enqueued with a type which has no registered handler
+var ErrUnknownType = errors.New("unknown job type")
+
+// ErrLeaseLost is returned when the lease of a job was lost by its worker, i.e. because it was marked as failed
+var ErrLeaseLost = errors.New("lease of the job lost")
+
+// Progress is a struct that defines the advancement of a job. Every processed element either succeeded or failed.
+type Progress struct {
+	Total     int64 `json:"total" bson:"total"`
+	Processed int64 `json:"processed" bson:"processed"`
+	Succeeded int64 `json:"succeeded" bson:"succeeded"`
+	Failed    int64 `json:"failed" bson:"failed"`
+}
+
+// Job is a struct that defines a long operation run in the background
+type Job struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	TenantID   string             `json:"-" bson:"tenant_id"`
+	Type       string             `json:"type" bson:"type"`
+	Status     string             `json:"status" bson:"status"`
+	Params     bson.Raw           `json:"-" bson:"params,omitempty"`
+	Progress   Progress           `json:"progress" bson:"progress"`
+	Result     bson.M             `json:"result,omitempty" bson:"result,omitempty"`
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+	Errors     map[string]string  `json:"errors,omitempty" bson:"errors,omitempty"`
+	CreatedBy  int64              `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	Owner      string             `json:"-" bson:"owner,omitempty"`       // Worker running the job
+	LeaseUntil *time.Time         `json:"-" bson:"lease_until,omitempty"` // Time at which the job is failed if its worker did not renew the lease
+	ExpiresAt  *time.Time         `json:"-" bson:"expires_at,omitempty"`  // Time at which the finished job is removed
+}
+
+// Finished returns true if the job succeeded or failed
+func (job Job) Finished() bool {
+	return job.Status == StatusSucceeded || job.Status == StatusFailed
+}
+
+// DecodeParams decodes the parameters of the job into the given value
+func (job Job) DecodeParams(value any) error {
+	if job.Params == nil {
+		return nil
+	}
+
+	return bson.Unmarshal(job.Params, value)
+}
+
+// ValidationError is returned by the handlers of the jobs whose input turns out to be invalid once they run
+// (i.e. an adjusted price out of range). Its errors are kept on the failed job like the errors of a request.
+type ValidationError struct {
+	Errors map[string]string
+}
+
+// Error returns the message of the validation error
+func (err *ValidationError) Error() string {
+	return "validation failed"
+}
+
+// Reporter is a struct through which the handler of a job reports its progress. The progress is saved
+// periodically by the worker running the job rather than on every change.
+type Reporter struct {
+	mu       sync.Mutex
+	progress Progress
+}
+
+// SetTotal sets the number of elements processed by the job, once it is known
+func (reporter *Reporter) SetTotal(total int64) {
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+
+	reporter.progress.Total = total
+}
+
+// Succeed adds the given number of elements processed successfully to the progress of the job
+func (reporter *Reporter) Succeed(count int64) {
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+
+	reporter.progress.Processed += count
+	reporter.progress.Succeeded += count
+}
+
+// Fail adds the given number of elements which failed to be processed to the progress of the job
+func (reporter *Reporter) Fail(count int64) {
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+
+	reporter.progress.Processed += count
+	reporter.progress.Failed += count
+}
+
+// Progress returns the progress reported so far
+func (reporter *Reporter) Progress() Progress {
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+
+	return reporter.progress
+}
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics is a struct that holds some prometheus metrics regarding the background jobs
+type Metrics struct {
+	RunningGauge      prometheus.Gauge
+	FinishedCounter   *prometheus.CounterVec
+	DurationHistogram *prometheus.HistogramVec
+}
+
+// NewMetrics creates the gauges, counters and histograms used to keep track of the jobs
+func NewMetrics(appName string) *Metrics {
+	runningGauge := promauto.NewGauge(prometheus.GaugeOpts{
+		Name: fmt.Sprintf("%s_jobs_running", appName),
+		Help: "The number of jobs run by the instance",
+	})
+
+	finishedCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_jobs_finished_total", appName),
+		Help: "The total number of jobs finished by the instance, by type and status",
+	}, []string{"type", "status"})
+
+	durationHistogram := promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    fmt.Sprintf("%s_job_duration_seconds", appName),
+		Help:    "Time elapsed between the start and the end of the jobs, by type",
+		Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600},
+	}, []string{"type"})
+
+	return &Metrics{
+		RunningGauge:      runningGauge,
+		FinishedCounter:   finishedCounter,
+		DurationHistogram: durationHistogram,
+	}
+}
@@ -0,0 +1,311 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// finishTimeout is the maximum duration of the write of the outcome of a job
+const finishTimeout = 10 * time.Second
+
+// Handler is a function that runs a job and returns its result. Its context holds the tenant and the user who
+// enqueued the job, and it is canceled when the lease of the job is lost.
+type Handler func(ctx context.Context, job Job, reporter *Reporter) (bson.M, error)
+
+// jobStore is implemented by the stores of the jobs
+type jobStore interface {
+	Create(ctx context.Context, job Job) error
+	Get(ctx context.Context, id primitive.ObjectID, tenant string) (Job, error)
+	Claim(ctx context.Context, types []string, owner string, now time.Time, leaseUntil time.Time) (*Job, error)
+	Renew(ctx context.Context, id primitive.ObjectID, owner string, progress Progress, leaseUntil time.Time) error
+	Finish(ctx context.Context, job Job, now time.Time) error
+	FailInterrupted(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Pool is a struct that runs the queued jobs with a fixed number of workers. The jobs are claimed from the store,
+// which is shared by the pools of every instance, and their lease is renewed along with their progress while they run.
+type Pool struct {
+	store         jobStore
+	workers       int
+	pollInterval  time.Duration
+	leaseDuration time.Duration
+	owner         string
+	metrics       *Metrics
+	logger        *logger.Logger
+	tracer        trace.Tracer
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	// Wakes up an idle worker when a job is enqueued by the instance
+	wake chan struct{}
+}
+
+// NewPool returns a new Pool running the jobs of the given store
+func NewPool(store jobStore, cfg settings.Jobs, serviceName string, logger *logger.Logger, metrics *Metrics) *Pool {
+	return &Pool{
+		store:         store,
+		workers:       cfg.Workers,
+		pollInterval:  time.Duration(cfg.PollInterval) * time.Millisecond,
+		leaseDuration: time.Duration(cfg.LeaseDuration) * time.Second,
+		owner:         primitive.NewObjectID().Hex(),
+		metrics:       metrics,
+		logger:        logger,
+		tracer:        otel.Tracer(serviceName),
+		handlers:      make(map[string]Handler),
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler running the jobs of the given type. Only the registered types are claimed by the pool.
+func (pool *Pool) Register(jobType string, handler Handler) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.handlers[jobType] = handler
+}
+
+// Enqueue stores a new job of the given type with the given parameters, on behalf of the tenant and the user
+// of the given context, and returns it
+func (pool *Pool) Enqueue(ctx context.Context, jobType string, params any) (Job, error) {
+	if pool.handler(jobType) == nil {
+		return Job{}, fmt.Errorf("%w %q", ErrUnknownType, jobType)
+	}
+
+	job := Job{
+		ID:        primitive.NewObjectID(),
+		Type:      jobType,
+		Status:    StatusQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	job.TenantID, _ = data.TenantFromContext(ctx)
+	job.CreatedBy, _ = data.ActorFromContext(ctx)
+
+	if params != nil {
+		raw, err := bson.Marshal(params)
+		if err != nil {
+			return Job{}, err
+		}
+
+		job.Params = raw
+	}
+
+	err := pool.store.Create(ctx, job)
+	if err != nil {
+		return Job{}, err
+	}
+
+	select {
+	case pool.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Get retrieves the job of the given tenant with the given id
+func (pool *Pool) Get(ctx context.Context, id primitive.ObjectID, tenant string) (Job, error) {
+	return pool.store.Get(ctx, id, tenant)
+}
+
+// Run starts the workers of the pool and marks the jobs whose lease expired as failed, at the lease duration interval
+func (pool *Pool) Run() {
+	for i := 0; i < pool.workers; i++ {
+		go pool.work()
+	}
+
+	ticker := time.NewTicker(pool.leaseDuration)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := pool.store.FailInterrupted(context.Background(), time.Now().UTC())
+		if err != nil {
+			pool.logger.Error(err, map[string]string{"operation": "fail_interrupted_jobs"})
+			continue
+		}
+
+		if count > 0 {
+			pool.logger.Info("Interrupted jobs marked as failed", map[string]string{"count": fmt.Sprint(count)})
+		}
+	}
+}
+
+// work runs the queued jobs one at a time. It waits for the poll interval, or until a job is enqueued,
+// when no job is queued.
+func (pool *Pool) work() {
+	for {
+		ran, err := pool.runNext(context.Background())
+		if err != nil {
+			pool.logger.Error(err, map[string]string{"operation": "claim_job"})
+		}
+
+		if !ran {
+			select {
+			case <-pool.wake:
+			case <-time.After(pool.pollInterval):
+			}
+		}
+	}
+}
+
+// runNext claims the oldest queued job and runs it. It returns false when no job is queued.
+func (pool *Pool) runNext(ctx context.Context) (bool, error) {
+	now := time.Now().UTC()
+
+	job, err := pool.store.Claim(ctx, pool.types(), pool.owner, now, now.Add(pool.leaseDuration))
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	pool.run(*job)
+
+	return true, nil
+}
+
+// run runs the given claimed job and records its outcome
+func (pool *Pool) run(job Job) {
+	pool.metrics.RunningGauge.Inc()
+	defer pool.metrics.RunningGauge.Dec()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx = data.ContextWithActor(data.ContextWithTenant(ctx, job.TenantID), job.CreatedBy)
+
+	ctx, span := pool.tracer.Start(
+		ctx,
+		"job "+job.Type,
+		trace.WithAttributes(attribute.String("job_id", job.ID.Hex()), attribute.String("type", job.Type)),
+	)
+	defer span.End()
+
+	reporter := &Reporter{progress: job.Progress}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pool.heartbeat(ctx, cancel, job, reporter, done)
+	}()
+
+	result, err := pool.execute(ctx, job, reporter)
+
+	close(done)
+	wg.Wait()
+
+	job.Progress = reporter.Progress()
+	job.Status = StatusSucceeded
+	job.Result = result
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		job.Status = StatusFailed
+		job.Error = err.Error()
+
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			job.Errors = validationErr.Errors
+		}
+
+		pool.logger.Error(err, map[string]string{"operation": "run_job", "job_id": job.ID.Hex(), "type": job.Type})
+	}
+
+	finishCtx, finishCancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer finishCancel()
+
+	err = pool.store.Finish(finishCtx, job, time.Now().UTC())
+	if err != nil {
+		pool.logger.Error(err, map[string]string{"operation": "finish_job", "job_id": job.ID.Hex(), "type": job.Type})
+		return
+	}
+
+	pool.metrics.FinishedCounter.WithLabelValues(job.Type, job.Status).Inc()
+
+	if job.StartedAt != nil {
+		pool.metrics.DurationHistogram.WithLabelValues(job.Type).Observe(time.Since(*job.StartedAt).Seconds())
+	}
+}
+
+// execute runs the handler of the given job, converting its panics into errors
+func (pool *Pool) execute(ctx context.Context, job Job, reporter *Reporter) (result bson.M, err error) {
+	handler := pool.handler(job.Type)
+	if handler == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, job.Type)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result = nil
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+
+	return handler(ctx, job, reporter)
+}
+
+// heartbeat saves the progress of the given job and renews its lease at the poll interval until done is closed.
+// The job is canceled when its lease is lost.
+func (pool *Pool) heartbeat(ctx context.Context, cancel context.CancelFunc, job Job, reporter *Reporter, done <-chan struct{}) {
+	ticker := time.NewTicker(pool.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := pool.store.Renew(ctx, job.ID, pool.owner, reporter.Progress(), time.Now().UTC().Add(pool.leaseDuration))
+			if errors.Is(err, ErrLeaseLost) {
+				pool.logger.Info("Lease of the job lost", map[string]string{"job_id": job.ID.Hex(), "type": job.Type})
+				cancel()
+				return
+			}
+
+			if err != nil {
+				pool.logger.Error(err, map[string]string{"operation": "renew_job", "job_id": job.ID.Hex()})
+			}
+		}
+	}
+}
+
+// handler returns the handler of the given job type, or nil if none was registered
+func (pool *Pool) handler(jobType string) Handler {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.handlers[jobType]
+}
+
+// types returns the job types having a registered handler
+func (pool *Pool) types() []string {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	types := make([]string, 0, len(pool.handlers))
+	for jobType := range pool.handlers {
+		types = append(types, jobType)
+	}
+
+	sort.Strings(types)
+
+	return types
+}
@@ -0,0 +1,314 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testMetrics are shared by the pools of the tests since prometheus metrics can only be registered once
+var testMetrics = NewMetrics("jobs_test")
+
+// fakeStore keeps the jobs in memory
+type fakeStore struct {
+	mu   sync.Mutex
+	jobs []*Job
+}
+
+// Create appends the given job as queued
+func (store *fakeStore) Create(ctx context.Context, job Job) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	job.Status = StatusQueued
+	store.jobs = append(store.jobs, &job)
+
+	return nil
+}
+
+// Get retrieves the job of the given tenant with the given id
+func (store *fakeStore) Get(ctx context.Context, id primitive.ObjectID, tenant string) (Job, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, job := range store.jobs {
+		if job.ID == id && job.TenantID == tenant {
+			return *job, nil
+		}
+	}
+
+	return Job{}, database.ErrRecordNotFound
+}
+
+// Claim marks the oldest queued job of the listed types as run by the given owner
+func (store *fakeStore) Claim(ctx context.Context, types []string, owner string, now time.Time, leaseUntil time.Time) (*Job, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, job := range store.jobs {
+		for _, jobType := range types {
+			if job.Status == StatusQueued && job.Type == jobType {
+				job.Status = StatusRunning
+				job.Owner = owner
+				job.StartedAt = &now
+				job.LeaseUntil = &leaseUntil
+
+				claimed := *job
+
+				return &claimed, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Renew saves the progress of the job with the given id
+func (store *fakeStore) Renew(ctx context.Context, id primitive.ObjectID, owner string, progress Progress, leaseUntil time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, job := range store.jobs {
+		if job.ID == id && job.Owner == owner && job.Status == StatusRunning {
+			job.Progress = progress
+			job.LeaseUntil = &leaseUntil
+
+			return nil
+		}
+	}
+
+	return ErrLeaseLost
+}
+
+// Finish records the outcome of the given job
+func (store *fakeStore) Finish(ctx context.Context, finished Job, now time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, job := range store.jobs {
+		if job.ID == finished.ID && job.Owner == finished.Owner && job.Status == StatusRunning {
+			finished.FinishedAt = &now
+			*job = finished
+
+			return nil
+		}
+	}
+
+	return ErrLeaseLost
+}
+
+// FailInterrupted marks the running jobs whose lease expired as failed
+func (store *fakeStore) FailInterrupted(ctx context.Context, now time.Time) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	count := int64(0)
+	for _, job := range store.jobs {
+		if job.Status == StatusRunning && job.LeaseUntil.Before(now) {
+			job.Status = StatusFailed
+			job.Error = interruptedError
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// newTestPool returns a pool of a single worker running the jobs of the given store
+func newTestPool(store jobStore) *Pool {
+	cfg := settings.Jobs{Workers: 1, PollInterval: 10, LeaseDuration: 1, Retention: 60}
+
+	return NewPool(store, cfg, "Catalog", logger.New(io.Discard, logger.LevelInfo), testMetrics)
+}
+
+func TestPoolRun(t *testing.T) {
+	tests := []struct {
+		testName       string
+		handler        Handler
+		wantedStatus   string
+		wantedError    string
+		wantedErrors   int
+		wantedProgress Progress
+	}{
+		{
+			"Succeeded job",
+			func(ctx context.Context, job Job, reporter *Reporter) (bson.M, error) {
+				reporter.SetTotal(3)
+				reporter.Succeed(2)
+				reporter.Fail(1)
+
+				tenant, _ := data.TenantFromContext(ctx)
+				actor, _ := data.ActorFromContext(ctx)
+
+				return bson.M{"tenant": tenant, "actor": actor}, nil
+			},
+			StatusSucceeded,
+			"",
+			0,
+			Progress{Total: 3, Processed: 3, Succeeded: 2, Failed: 1},
+		},
+		{
+			"Failed job",
+			func(ctx context.Context, job Job, reporter *Reporter) (bson.M, error) {
+				reporter.SetTotal(3)
+				reporter.Succeed(1)
+
+				return nil, errors.New("index unavailable")
+			},
+			StatusFailed,
+			"index unavailable",
+			0,
+			Progress{Total: 3, Processed: 1, Succeeded: 1},
+		},
+		{
+			"Invalid job",
+			func(ctx context.Context, job Job, reporter *Reporter) (bson.M, error) {
+				return nil, &ValidationError{Errors: map[string]string{"percent": "must not lower prices below 0"}}
+			},
+			StatusFailed,
+			"validation failed",
+			1,
+			Progress{},
+		},
+		{
+			"Panicking job",
+			func(ctx context.Context, job Job, reporter *Reporter) (bson.M, error) {
+				panic("nil item")
+			},
+			StatusFailed,
+			"job panicked: nil item",
+			0,
+			Progress{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			store := &fakeStore{}
+			pool := newTestPool(store)
+			pool.Register("test", tt.handler)
+
+			ctx := data.ContextWithActor(data.ContextWithTenant(context.Background(), "eu-1"), 4)
+
+			enqueued, err := pool.Enqueue(ctx, "test", bson.M{"percent": 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ran, err := pool.runNext(context.Background())
+			if err != nil || !ran {
+				t.Fatalf("want job to be run; got %v, %v", ran, err)
+			}
+
+			job, err := pool.Get(context.Background(), enqueued.ID, "eu-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if job.Status != tt.wantedStatus {
+				t.Errorf("want status %q; got %q", tt.wantedStatus, job.Status)
+			}
+
+			if job.Error != tt.wantedError {
+				t.Errorf("want error %q; got %q", tt.wantedError, job.Error)
+			}
+
+			if len(job.Errors) != tt.wantedErrors {
+				t.Errorf("want %d errors; got %d", tt.wantedErrors, len(job.Errors))
+			}
+
+			if job.Progress != tt.wantedProgress {
+				t.Errorf("want progress %+v; got %+v", tt.wantedProgress, job.Progress)
+			}
+
+			if job.Status == StatusSucceeded && (job.Result["tenant"] != "eu-1" || job.Result["actor"] != int64(4)) {
+				t.Errorf("want job run on behalf of the tenant and the actor; got %v", job.Result)
+			}
+		})
+	}
+}
+
+func TestPoolEnqueue(t *testing.T) {
+	store := &fakeStore{}
+	pool := newTestPool(store)
+	pool.Register("test", func(ctx context.Context, job Job, reporter *Reporter) (bson.M, error) { return nil, nil })
+
+	_, err := pool.Enqueue(context.Background(), "unknown", nil)
+	if !errors.Is(err, ErrUnknownType) {
+		t.Errorf("want %v; got %v", ErrUnknownType, err)
+	}
+
+	job, err := pool.Enqueue(context.Background(), "test", struct {
+		Percent float64 `bson:"percent"`
+	}{Percent: -5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var params struct {
+		Percent float64 `bson:"percent"`
+	}
+
+	err = job.DecodeParams(&params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if params.Percent != -5 {
+		t.Errorf("want percent %v; got %v", -5, params.Percent)
+	}
+
+	ran, err := pool.runNext(context.Background())
+	if err != nil || !ran {
+		t.Fatalf("want job to be run; got %v, %v", ran, err)
+	}
+
+	// Nothing is claimed once every queued job of a registered type ran
+	ran, err = pool.runNext(context.Background())
+	if err != nil || ran {
+		t.Errorf("want no job to be run; got %v, %v", ran, err)
+	}
+}
+
+func TestPoolLeaseLost(t *testing.T) {
+	store := &fakeStore{}
+	pool := newTestPool(store)
+
+	// The job runs until it is canceled, which happens once it is marked as failed
+	pool.Register("test", func(ctx context.Context, job Job, reporter *Reporter) (bson.M, error) {
+		store.FailInterrupted(context.Background(), time.Now().UTC().Add(time.Hour))
+
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	})
+
+	job, err := pool.Enqueue(context.Background(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pool.runNext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = pool.Get(context.Background(), job.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Status != StatusFailed || job.Error != interruptedError {
+		t.Errorf("want job failed with %q; got %q with %q", interruptedError, job.Status, job.Error)
+	}
+}
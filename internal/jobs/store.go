@@ -0,0 +1,180 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// interruptedError is the error of the jobs whose worker stopped renewing their lease
+const interruptedError = "interrupted: the job stopped before it finished"
+
+// Store is a struct that manages the jobs collection. The finished jobs are removed after the given retention.
+type Store struct {
+	collection *mongo.Collection
+	retention  time.Duration
+}
+
+// NewStore creates a new Store keeping the finished jobs for the given retention
+func NewStore(client *mongo.Client, databaseName string, retention time.Duration) *Store {
+	return &Store{
+		collection: client.Database(databaseName).Collection(constants.JobsCollection),
+		retention:  retention,
+	}
+}
+
+// Create inserts the given job as queued
+func (store *Store) Create(ctx context.Context, job Job) error {
+	job.Status = StatusQueued
+
+	_, err := store.collection.InsertOne(ctx, job)
+
+	return err
+}
+
+// Get retrieves the job of the given tenant with the given id.
+// It returns database.ErrRecordNotFound if the job doesn't exist or belongs to another tenant.
+func (store *Store) Get(ctx context.Context, id primitive.ObjectID, tenant string) (Job, error) {
+	var job Job
+
+	err := store.collection.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenant}).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Job{}, database.ErrRecordNotFound
+		}
+
+		return Job{}, err
+	}
+
+	return job, nil
+}
+
+// Claim marks the oldest queued job whose type is listed as run by the given owner until the given time and
+// returns it. It returns nil when no job is queued.
+func (store *Store) Claim(ctx context.Context, types []string, owner string, now time.Time, leaseUntil time.Time) (*Job, error) {
+	var job Job
+
+	err := store.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"status": StatusQueued, "type": bson.M{"$in": types}},
+		bson.M{"$set": bson.M{"status": StatusRunning, "owner": owner, "started_at": now, "lease_until": leaseUntil}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetReturnDocument(options.After),
+	).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Renew saves the progress of the job with the given id and extends its lease until the given time.
+// It returns ErrLeaseLost if the job is no longer run by the given owner.
+func (store *Store) Renew(ctx context.Context, id primitive.ObjectID, owner string, progress Progress, leaseUntil time.Time) error {
+	result, err := store.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "owner": owner, "status": StatusRunning},
+		bson.M{"$set": bson.M{"progress": progress, "lease_until": leaseUntil}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Finish records the outcome of the job with the given id run by the given owner, which is kept for the retention
+// of the store. The outcome of a job whose lease was lost is dropped and ErrLeaseLost is returned.
+func (store *Store) Finish(ctx context.Context, job Job, now time.Time) error {
+	set := bson.M{
+		"status":      job.Status,
+		"progress":    job.Progress,
+		"finished_at": now,
+		"expires_at":  now.Add(store.retention),
+	}
+
+	if job.Result != nil {
+		set["result"] = job.Result
+	}
+
+	if job.Error != "" {
+		set["error"] = job.Error
+	}
+
+	if job.Errors != nil {
+		set["errors"] = job.Errors
+	}
+
+	result, err := store.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": job.ID, "owner": job.Owner, "status": StatusRunning},
+		bson.M{"$set": set, "$unset": bson.M{"lease_until": ""}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// FailInterrupted marks the running jobs whose lease expired before the given time as failed, and returns their
+// number. They are not run again since their handlers may not be idempotent (i.e. a price adjustment).
+func (store *Store) FailInterrupted(ctx context.Context, now time.Time) (int64, error) {
+	result, err := store.collection.UpdateMany(
+		ctx,
+		bson.M{"status": StatusRunning, "lease_until": bson.M{"$lt": now}},
+		bson.M{
+			"$set":   bson.M{"status": StatusFailed, "error": interruptedError, "finished_at": now, "expires_at": now.Add(store.retention)},
+			"$unset": bson.M{"lease_until": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// CreateJobsCollection creates the jobs collection along with the index removing the expired jobs and the
+// indexes of the claims
+func CreateJobsCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// Create collection
+	err := db.CreateCollection(context.Background(), constants.JobsCollection)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lease_until", Value: 1}}},
+	}
+
+	_, err = db.Collection(constants.JobsCollection).Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
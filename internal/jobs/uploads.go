@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"errors"
+	"io"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UploadStore is a struct that keeps the files uploaded for the jobs (i.e. the items of an import) in a GridFS
+// bucket, so that the job can be run by the workers of any instance. The files are streamed in chunks and never
+// held in memory.
+type UploadStore struct {
+	bucket *gridfs.Bucket
+}
+
+// NewUploadStore creates a new UploadStore for the given database
+func NewUploadStore(client *mongo.Client, databaseName string) (*UploadStore, error) {
+	bucket, err := gridfs.NewBucket(client.Database(databaseName), options.GridFSBucket().SetName(constants.JobUploadsBucket))
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadStore{bucket: bucket}, nil
+}
+
+// Save stores the content of the given reader under the given name, along with the type of the job it is uploaded
+// for, and returns the id of the upload
+func (store *UploadStore) Save(name string, jobType string, content io.Reader) (primitive.ObjectID, error) {
+	return store.bucket.UploadFromStream(name, content, options.GridFSUpload().SetMetadata(bson.M{"job_type": jobType}))
+}
+
+// Open returns a reader of the upload with the given id, which must be closed
+func (store *UploadStore) Open(id primitive.ObjectID) (io.ReadCloser, error) {
+	stream, err := store.bucket.OpenDownloadStream(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+// Delete removes the upload with the given id. It does nothing if the upload doesn't exist.
+func (store *UploadStore) Delete(id primitive.ObjectID) error {
+	err := store.bucket.Delete(id)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil
+	}
+
+	return err
+}
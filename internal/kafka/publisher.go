@@ -0,0 +1,135 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// recordData is the key or the value of a produced record
+type recordData struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// recordHeader is a header of a produced record. Its value is encoded in base64.
+type recordHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// produceRequest is the body of the requests producing a record with the REST Proxy API v3
+type produceRequest struct {
+	Key       recordData     `json:"key"`
+	Value     recordData     `json:"value"`
+	Headers   []recordHeader `json:"headers"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Publisher is the Kafka implementation of messaging.Publisher. It produces the messages through
+// a Kafka REST Proxy to one topic per aggregate, keyed by the ID of the aggregate so that the
+// events of an aggregate are kept in order.
+type Publisher struct {
+	client      *http.Client
+	url         string
+	topicPrefix string
+}
+
+// NewPublisher creates a new Kafka publisher from the given configuration
+func NewPublisher(cfg settings.Kafka) *Publisher {
+	return &Publisher{
+		client:      &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Millisecond},
+		url:         fmt.Sprintf("%s/v3/clusters/%s", strings.TrimSuffix(cfg.URL, "/"), url.PathEscape(cfg.ClusterID)),
+		topicPrefix: cfg.TopicPrefix,
+	}
+}
+
+// System returns the name of the message broker
+func (publisher *Publisher) System() string {
+	return "kafka"
+}
+
+// Destination returns the topic of the aggregate of the given route
+func (publisher *Publisher) Destination(route messaging.Route) string {
+	return publisher.topicPrefix + route.Aggregate
+}
+
+// Send produces the given message to the topic of the given route. The properties of the
+// message are sent as headers along with the headers of the message.
+func (publisher *Publisher) Send(ctx context.Context, route messaging.Route, msg messaging.Message) error {
+	headers := map[string]string{
+		"content-type": msg.ContentType,
+		"message-id":   msg.ID,
+		"type":         msg.Type,
+	}
+
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	record := produceRequest{
+		Key:       recordData{Type: "STRING", Data: msg.Key},
+		Value:     recordData{Type: "JSON", Data: json.RawMessage(msg.Body)},
+		Headers:   []recordHeader{},
+		Timestamp: msg.Timestamp,
+	}
+
+	for name, value := range headers {
+		record.Headers = append(record.Headers, recordHeader{Name: name, Value: base64.StdEncoding.EncodeToString([]byte(value))})
+	}
+
+	sort.Slice(record.Headers, func(i, j int) bool { return record.Headers[i].Name < record.Headers[j].Name })
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	topic := publisher.Destination(route)
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/topics/%s/records", publisher.url, url.PathEscape(topic)),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := publisher.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var response struct {
+			Message string `json:"message"`
+		}
+
+		err = json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&response)
+		if err != nil || response.Message == "" {
+			return fmt.Errorf("failed to produce record to topic %s: status %d", topic, res.StatusCode)
+		}
+
+		return fmt.Errorf("failed to produce record to topic %s: status %d: %s", topic, res.StatusCode, response.Message)
+	}
+
+	return nil
+}
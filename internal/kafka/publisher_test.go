@@ -0,0 +1,90 @@
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+func TestSend(t *testing.T) {
+	var path string
+	var record struct {
+		Key     recordData      `json:"key"`
+		Value   json.RawMessage `json:"value"`
+		Headers []recordHeader  `json:"headers"`
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if record.Key.Data == "unknown" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code": 404, "message": "This server does not host this topic-partition."}`))
+			return
+		}
+
+		w.Write([]byte(`{"error_code": 200, "partition_id": 0, "offset": 1}`))
+	}))
+	defer ts.Close()
+
+	publisher := NewPublisher(settings.Kafka{URL: ts.URL, ClusterID: "cluster-1", TopicPrefix: "Play.Catalog.", Timeout: 500})
+
+	err := publisher.Send(context.Background(), messaging.ItemExpiredRoute, messaging.Message{
+		ID:          "potion-1",
+		Key:         "potion",
+		Type:        "item.expired.v1",
+		ContentType: "application/json",
+		Timestamp:   time.Now().UTC(),
+		Headers:     map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		Body:        []byte(`{"name":"Potion"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Events are published to the topic of their aggregate
+	if path != "/v3/clusters/cluster-1/topics/Play.Catalog.item/records" {
+		t.Errorf("want path %q; got %q", "/v3/clusters/cluster-1/topics/Play.Catalog.item/records", path)
+	}
+
+	if record.Key.Data != "potion" {
+		t.Errorf("want key %q; got %v", "potion", record.Key.Data)
+	}
+
+	wantedHeaders := map[string]string{
+		"content-type": "application/json",
+		"message-id":   "potion-1",
+		"type":         "item.expired.v1",
+		"traceparent":  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+
+	for _, header := range record.Headers {
+		value, _ := base64.StdEncoding.DecodeString(header.Value)
+		if string(value) != wantedHeaders[header.Name] {
+			t.Errorf("want header %s %q; got %q", header.Name, wantedHeaders[header.Name], value)
+		}
+
+		delete(wantedHeaders, header.Name)
+	}
+
+	if len(wantedHeaders) != 0 {
+		t.Errorf("want headers %v to be sent", wantedHeaders)
+	}
+
+	err = publisher.Send(context.Background(), messaging.ItemExpiredRoute, messaging.Message{Key: "unknown", Body: []byte(`{}`)})
+	if err == nil {
+		t.Error("want error; got nil")
+	}
+}
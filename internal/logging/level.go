@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the minimum severity of the log entries written by a LevelFilter
+type Level int32
+
+const (
+	LevelDebug Level = iota // Every entry, including the debug ones
+	LevelInfo               // Info, warning and error entries
+	LevelError              // Error entries only
+)
+
+// Levels are the names of the levels, in increasing order of severity
+var Levels = []string{"debug", "info", "error"}
+
+// String returns the name of the level
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return ""
+	}
+
+	return Levels[l]
+}
+
+// ParseLevel returns the level with the given name
+func ParseLevel(name string) (Level, error) {
+	for i, level := range Levels {
+		if level == name {
+			return Level(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid log level %q", name)
+}
+
+// droppedPrefixes are the prefixes of the entries which are dropped at each level
+var droppedPrefixes = map[Level][][]byte{
+	LevelInfo:  {[]byte(`{"level":"DEBUG"`)},
+	LevelError: {[]byte(`{"level":"DEBUG"`), []byte(`{"level":"INFO"`), []byte(`{"level":"WARNING"`)},
+}
+
+// LevelFilter is an io.Writer which drops the entries of the common logger below a level which can be
+// changed at runtime. The common logger is created with the info level and writes to the filter.
+// It also writes the debug entries, which the common logger does not support, while the level is debug.
+type LevelFilter struct {
+	output io.Writer
+	level  atomic.Int32
+	mutex  sync.Mutex
+}
+
+// NewLevelFilter creates a new LevelFilter writing the entries at or above the given level to the given output
+func NewLevelFilter(output io.Writer, level Level) *LevelFilter {
+	filter := &LevelFilter{output: output}
+	filter.SetLevel(level)
+
+	return filter
+}
+
+// Level returns the current level
+func (f *LevelFilter) Level() Level {
+	return Level(f.level.Load())
+}
+
+// SetLevel changes the level from the next entry on
+func (f *LevelFilter) SetLevel(level Level) {
+	f.level.Store(int32(level))
+}
+
+// Write writes the given entry unless it is below the current level
+func (f *LevelFilter) Write(entry []byte) (int, error) {
+	if f.dropped(entry) {
+		return len(entry), nil
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.output.Write(entry)
+}
+
+// dropped checks if the given entry is below the current level
+func (f *LevelFilter) dropped(entry []byte) bool {
+	for _, prefix := range droppedPrefixes[f.Level()] {
+		if bytes.HasPrefix(entry, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Debug writes a debug entry, in the format of the common logger, if the current level is debug
+func (f *LevelFilter) Debug(message string, properties map[string]string) {
+	if f.Level() != LevelDebug {
+		return
+	}
+
+	entry, err := json.Marshal(struct {
+		Level      string            `json:"level"`
+		Time       string            `json:"time"`
+		Message    string            `json:"message"`
+		Properties map[string]string `json:"properties,omitempty"`
+	}{
+		Level:      "DEBUG",
+		Time:       time.Now().UTC().Format(time.RFC3339),
+		Message:    message,
+		Properties: properties,
+	})
+	if err != nil {
+		return
+	}
+
+	f.Write(append(entry, '\n'))
+}
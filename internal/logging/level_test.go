@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Common/logger"
+)
+
+func TestLevelFilter(t *testing.T) {
+	tests := []struct {
+		level        Level
+		wantedLevels []string
+	}{
+		{LevelDebug, []string{"DEBUG", "INFO", "WARNING", "ERROR"}},
+		{LevelInfo, []string{"INFO", "WARNING", "ERROR"}},
+		{LevelError, []string{"ERROR"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var output bytes.Buffer
+
+			filter := NewLevelFilter(&output, LevelInfo)
+			filter.SetLevel(tt.level)
+
+			commonLogger := logger.New(filter, logger.LevelInfo)
+
+			filter.Debug("debug entry", nil)
+			commonLogger.Info("info entry", nil)
+			commonLogger.Warning("warning entry", nil)
+			commonLogger.Error(bytes.ErrTooLarge, nil)
+
+			lines := bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n"))
+			if len(lines) != len(tt.wantedLevels) {
+				t.Fatalf("want %d entries; got %d", len(tt.wantedLevels), len(lines))
+			}
+
+			for i, level := range tt.wantedLevels {
+				if !bytes.HasPrefix(lines[i], []byte(`{"level":"`+level+`"`)) {
+					t.Errorf("want entry %d to have level %s; got %s", i, level, lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range Levels {
+		level, err := ParseLevel(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if level.String() != name {
+			t.Errorf("want %q; got %q", name, level.String())
+		}
+	}
+
+	_, err := ParseLevel("verbose")
+	if err == nil {
+		t.Error("want an error for an unknown level")
+	}
+}
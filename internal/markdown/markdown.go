@@ -0,0 +1,24 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// renderer renders CommonMark. The HTML written in the Markdown is kept so that the sanitization policy decides
+// which tags are served.
+var renderer = goldmark.New(goldmark.WithRendererOptions(html.WithUnsafe()))
+
+// Render renders the given CommonMark Markdown into HTML.
+// The HTML written in the Markdown is kept as it is so the rendered HTML must be sanitized before being served.
+func Render(source string) string {
+	var buf bytes.Buffer
+
+	// Rendering only fails when the output can't be written, which never happens with a buffer
+	_ = renderer.Convert([]byte(source), &buf)
+
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,36 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"Paragraphs", "Restores health.\nTastes bad.\n\nSold by the alchemist.", "<p>Restores health.\nTastes bad.</p>\n<p>Sold by the alchemist.</p>"},
+		{"Line break", "Restores health.  \nTastes bad.", "<p>Restores health.<br>\nTastes bad.</p>"},
+		{"Headings", "# Potion\n### Effects ###", "<h1>Potion</h1>\n<h3>Effects</h3>"},
+		{"Emphasis", "A **rare** and *powerful* __potion__ of _the_ healer", "<p>A <strong>rare</strong> and <em>powerful</em> <strong>potion</strong> of <em>the</em> healer</p>"},
+		{"Underscores inside words", "snake_case_name", "<p>snake_case_name</p>"},
+		{"Code span", "Use `<potion> **now**`", "<p>Use <code>&lt;potion&gt; **now**</code></p>"},
+		{"Link", "Sold by [the *alchemist*](https://example.com/alchemist_shop)", `<p>Sold by <a href="https://example.com/alchemist_shop">the <em>alchemist</em></a></p>`},
+		{"Bullet list", "- Restores health\n- Cures\n  poison\n\nDone", "<ul>\n<li>Restores health</li>\n<li>Cures\npoison</li>\n</ul>\n<p>Done</p>"},
+		{"Ordered list", "1. Open\n2. Drink", "<ol>\n<li>Open</li>\n<li>Drink</li>\n</ol>"},
+		{"Blockquote", "> Best potion\n> ever\n\nReview", "<blockquote>\n<p>Best potion\never</p>\n</blockquote>\n<p>Review</p>"},
+		{"Escaped blockquote marker", "&gt; Best potion", "<p>&gt; Best potion</p>"},
+		{"Entity in code span", "Type `&lt;` for <", "<p>Type <code>&amp;lt;</code> for &lt;</p>"},
+		{"Code block", "```\nif hp < 10 {\n\tdrink()\n}\n```", "<pre><code>if hp &lt; 10 {\n\tdrink()\n}\n</code></pre>"},
+		{"HTML", "A <b>rare</b> potion<script>alert(1)</script>", "<p>A <b>rare</b> potion<script>alert(1)</script></p>"},
+		{"Unclosed code block", "```\n# Potion", "<pre><code># Potion</code></pre>"},
+		{"Empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.source); got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// CloudEventsContentType is the content type of the messages holding a CloudEvent in structured mode
+const CloudEventsContentType = "application/cloudevents+json"
+
+// CloudEventsSpecVersion is the version of the CloudEvents specification of the envelopes
+const CloudEventsSpecVersion = "1.0"
+
+// CloudEvent is a struct that defines the CloudEvents 1.0 envelope of an event in structured mode.
+// The trace context of the producer is carried by the distributed tracing extension.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	TraceParent     string          `json:"traceparent,omitempty"`
+	TraceState      string          `json:"tracestate,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// wrapCloudEvent wraps the JSON body of the given message in a CloudEvent published by the given source.
+// The id, type and time of the event are the ones of the message.
+func wrapCloudEvent(ctx context.Context, source string, msg Message) (Message, error) {
+	traceContext := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, traceContext)
+
+	body, err := json.Marshal(CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              msg.ID,
+		Source:          source,
+		Type:            msg.Type,
+		Time:            msg.Timestamp,
+		DataContentType: msg.ContentType,
+		TraceParent:     traceContext.Get("traceparent"),
+		TraceState:      traceContext.Get("tracestate"),
+		Data:            msg.Body,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg.ContentType = CloudEventsContentType
+	msg.Body = body
+
+	return msg, nil
+}
+
+// UnwrapCloudEvent replaces a consumed CloudEvent by its data. The type of the event becomes the type
+// of the message and the trace context of the event is copied to the headers of the message when they
+// do not hold one, so that it can be extracted like the one of the other messages. Messages which are
+// not CloudEvents are returned untouched.
+func UnwrapCloudEvent(msg Message) (Message, error) {
+	if !strings.HasPrefix(msg.ContentType, CloudEventsContentType) {
+		return msg, nil
+	}
+
+	var event CloudEvent
+
+	err := json.Unmarshal(msg.Body, &event)
+	if err != nil {
+		return msg, err
+	}
+
+	if event.SpecVersion != CloudEventsSpecVersion {
+		return msg, errors.New("unsupported CloudEvents version " + event.SpecVersion)
+	}
+
+	headers := make(map[string]string, len(msg.Headers))
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	if _, ok := headers["traceparent"]; !ok && event.TraceParent != "" {
+		headers["traceparent"] = event.TraceParent
+		headers["tracestate"] = event.TraceState
+	}
+
+	msg.Headers = headers
+	msg.ID = event.ID
+	msg.Type = event.Type
+	msg.Timestamp = event.Time
+	msg.ContentType = event.DataContentType
+	msg.Body = event.Data
+
+	return msg, nil
+}
@@ -0,0 +1,138 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWrapCloudEvent(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	msg, err := wrapCloudEvent(ctx, "/catalog", Message{
+		ID:          "potion-1",
+		Type:        "item.created.v2",
+		ContentType: "application/json",
+		Timestamp:   time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
+		Body:        []byte(`{"name":"Potion"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.ContentType != CloudEventsContentType {
+		t.Errorf("want content type %q; got %q", CloudEventsContentType, msg.ContentType)
+	}
+
+	var event map[string]any
+
+	if err := json.Unmarshal(msg.Body, &event); err != nil {
+		t.Fatal(err)
+	}
+
+	wantedAttributes := map[string]string{
+		"specversion":     "1.0",
+		"id":              "potion-1",
+		"source":          "/catalog",
+		"type":            "item.created.v2",
+		"time":            "2022-10-01T12:00:00Z",
+		"datacontenttype": "application/json",
+		"traceparent":     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+
+	for attribute, value := range wantedAttributes {
+		if event[attribute] != value {
+			t.Errorf("want %s %q; got %v", attribute, value, event[attribute])
+		}
+	}
+
+	if data, _ := json.Marshal(event["data"]); string(data) != `{"name":"Potion"}` {
+		t.Errorf("want data %q; got %q", `{"name":"Potion"}`, data)
+	}
+}
+
+func TestUnwrapCloudEvent(t *testing.T) {
+	event := `{"specversion":"1.0","id":"potion-1","source":"/catalog","type":"item.created.v2",` +
+		`"time":"2022-10-01T12:00:00Z","datacontenttype":"application/json",` +
+		`"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01","data":{"name":"Potion"}}`
+
+	tests := []struct {
+		testName     string
+		msg          Message
+		wantedType   string
+		wantedBody   string
+		wantedParent string
+		wantsError   bool
+	}{
+		{
+			"CloudEvent",
+			Message{ContentType: CloudEventsContentType, Body: []byte(event)},
+			"item.created.v2",
+			`{"name":"Potion"}`,
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			false,
+		},
+		{
+			"Trace context of the headers is kept",
+			Message{
+				ContentType: CloudEventsContentType,
+				Headers:     map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+				Body:        []byte(event),
+			},
+			"item.created.v2",
+			`{"name":"Potion"}`,
+			"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			false,
+		},
+		{
+			"Plain message",
+			Message{ContentType: "application/json", Type: "user.updated.v1", Body: []byte(`{"id":"1"}`)},
+			"user.updated.v1",
+			`{"id":"1"}`,
+			"",
+			false,
+		},
+		{
+			"Unsupported specification version",
+			Message{ContentType: CloudEventsContentType, Body: []byte(`{"specversion":"0.3","data":{}}`)},
+			"",
+			"",
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			msg, err := UnwrapCloudEvent(tt.msg)
+			if (err != nil) != tt.wantsError {
+				t.Fatalf("want error %t; got %v", tt.wantsError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if msg.Type != tt.wantedType {
+				t.Errorf("want type %q; got %q", tt.wantedType, msg.Type)
+			}
+
+			if string(msg.Body) != tt.wantedBody {
+				t.Errorf("want body %q; got %q", tt.wantedBody, msg.Body)
+			}
+
+			if parent := msg.Headers["traceparent"]; parent != tt.wantedParent {
+				t.Errorf("want traceparent %q; got %q", tt.wantedParent, parent)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// Names of the events exchanged with the other microservices
+const (
+	ItemCreated          = "item.created"
+	ItemUpdated          = "item.updated"
+	ItemDeleted          = "item.deleted"
+	ItemExpired          = "item.expired"
+	SnapshotCompleted    = "snapshot.completed"
+	ErasureCompleted     = "erasure.completed"
+	UserUpdated          = "user.updated"
+	UserDeleted          = "user.deleted"
+	UserErasureRequested = "user.erasure.requested"
+	PurchaseCompleted    = "purchase.completed"
+)
+
+// schemaRX matches the schema of an event (i.e. "item.created.v1")
+var schemaRX = regexp.MustCompile(`^([a-z]+(?:\.[a-z]+)*)\.v([1-9][0-9]*)$`)
+
+// Schema identifies the contract of the payload of an event
+type Schema struct {
+	Name    string
+	Version int
+}
+
+// String returns the schema in its "<name>.v<version>" form
+func (s Schema) String() string {
+	return fmt.Sprintf("%s.v%d", s.Name, s.Version)
+}
+
+// ParseSchema parses a schema in its "<name>.v<version>" form
+func ParseSchema(value string) (Schema, error) {
+	matches := schemaRX.FindStringSubmatch(value)
+	if matches == nil {
+		return Schema{}, fmt.Errorf("invalid event schema %q", value)
+	}
+
+	version, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return Schema{}, fmt.Errorf("invalid event schema %q", value)
+	}
+
+	return Schema{Name: matches[1], Version: version}, nil
+}
+
+// contract is a struct that defines the versions of a published event. Each version converts the source
+// of the event into its payload.
+type contract[T any] struct {
+	name     string
+	versions map[int]func(source T) any
+}
+
+// schemas returns the schemas of the versions of the event, oldest first
+func (c contract[T]) schemas() []Schema {
+	schemas := []Schema{}
+
+	for version := range c.versions {
+		schemas = append(schemas, Schema{Name: c.name, Version: version})
+	}
+
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Version < schemas[j].Version })
+
+	return schemas
+}
+
+// encodedEvent is an event serialized according to one of the versions of its contract
+type encodedEvent struct {
+	schema Schema
+	body   []byte
+}
+
+// encode serializes the given source into every version of the event enabled by the serializer.
+// The schema of the version is added to the payload.
+func (c contract[T]) encode(serializer *Serializer, source T) ([]encodedEvent, error) {
+	events := []encodedEvent{}
+
+	for _, schema := range serializer.enabled(c.name, c.schemas()) {
+		payload, err := json.Marshal(c.versions[schema.Version](source))
+		if err != nil {
+			return nil, err
+		}
+
+		// Add the schema to the payload
+		fields := map[string]json.RawMessage{}
+
+		err = json.Unmarshal(payload, &fields)
+		if err != nil {
+			return nil, err
+		}
+
+		fields["schema"] = json.RawMessage(strconv.Quote(schema.String()))
+
+		body, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, encodedEvent{schema: schema, body: body})
+	}
+
+	return events, nil
+}
+
+// publishedSchemas returns the schemas of the events published by the service
+func publishedSchemas() []Schema {
+	schemas := []Schema{}
+	schemas = append(schemas, itemCreatedContract.schemas()...)
+	schemas = append(schemas, itemUpdatedContract.schemas()...)
+	schemas = append(schemas, itemDeletedContract.schemas()...)
+	schemas = append(schemas, itemExpiredContract.schemas()...)
+	schemas = append(schemas, snapshotCompletedContract.schemas()...)
+	schemas = append(schemas, erasureCompletedContract.schemas()...)
+
+	return schemas
+}
+
+// Serializer is a struct that selects the versions in which the events are published.
+// Several versions of an event can be published during the transition to a new version so that
+// its consumers can be upgraded independently. Events without configured versions are published
+// in their latest version.
+type Serializer struct {
+	versions map[string][]Schema
+	// Source of the CloudEvents wrapping the events. Events are not wrapped when it is empty.
+	cloudEventsSource string
+}
+
+// NewSerializer returns a new Serializer publishing the events of the given service in the configured
+// versions (i.e. "item.created.v1"). It returns an error if one of the versions does not exist.
+func NewSerializer(cfg settings.Events, serviceName string) (*Serializer, error) {
+	known := make(map[Schema]bool)
+	for _, schema := range publishedSchemas() {
+		known[schema] = true
+	}
+
+	serializer := &Serializer{versions: make(map[string][]Schema)}
+
+	if cfg.CloudEvents {
+		serializer.cloudEventsSource = "/" + serviceName
+	}
+
+	for _, version := range cfg.Versions {
+		schema, err := ParseSchema(version)
+		if err != nil {
+			return nil, err
+		}
+
+		if !known[schema] {
+			return nil, fmt.Errorf("unknown event schema %q", version)
+		}
+
+		serializer.versions[schema.Name] = append(serializer.versions[schema.Name], schema)
+	}
+
+	return serializer, nil
+}
+
+// enabled returns the versions of the given event which are published among its available versions.
+// The available versions must be sorted from the oldest to the latest.
+func (serializer *Serializer) enabled(name string, available []Schema) []Schema {
+	if versions, ok := serializer.versions[name]; ok {
+		return versions
+	}
+
+	return available[len(available)-1:]
+}
+
+// consumedSchema returns the schema of a consumed event. The schema is read from the payload and
+// then from the type of the message. Events of producers which do not version their events yet
+// are considered to be of the first version.
+func consumedSchema(name string, body []byte, messageType string) (Schema, error) {
+	var payload struct {
+		Schema string `json:"schema"`
+	}
+
+	err := json.Unmarshal(body, &payload)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	switch {
+	case payload.Schema != "":
+		return ParseSchema(payload.Schema)
+	case schemaRX.MatchString(messageType):
+		return ParseSchema(messageType)
+	default:
+		return Schema{Name: name, Version: 1}, nil
+	}
+}
+
+// DecodeEvent decodes the payload of a consumed event into target if its version is supported.
+// Unknown fields are ignored so that producers can add fields without a new version. It returns
+// false if the event must be skipped, i.e. when the producer also publishes a version which is
+// not supported yet during a transition.
+func DecodeEvent(name string, supported []int, body []byte, messageType string, target any) (bool, error) {
+	schema, err := consumedSchema(name, body, messageType)
+	if err != nil {
+		return false, err
+	}
+
+	if schema.Name != name {
+		return false, fmt.Errorf("unexpected event schema %q", schema)
+	}
+
+	isSupported := false
+	for _, version := range supported {
+		isSupported = isSupported || version == schema.Version
+	}
+
+	if !isSupported {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(body, target)
+}
@@ -0,0 +1,203 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		testName   string
+		value      string
+		wantsError bool
+	}{
+		{"Valid schema", "item.created.v2", false},
+		{"Missing version", "item.created", true},
+		{"Version zero", "item.created.v0", true},
+		{"Upper case name", "Item.Created.v1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			schema, err := ParseSchema(tt.value)
+			if (err != nil) != tt.wantsError {
+				t.Fatalf("want error %t; got %v", tt.wantsError, err)
+			}
+
+			if err == nil && schema.String() != tt.value {
+				t.Errorf("want %q; got %q", tt.value, schema)
+			}
+		})
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	item := data.Item{
+		ID:       primitive.NewObjectID(),
+		Name:     "Potion",
+		Price:    5,
+		Tags:     []string{"healing", "consumable"},
+		AutoTags: []data.AutoTag{{Tag: "consumable", Rule: "potion-consumable"}},
+		Version:  1,
+	}
+
+	if _, err := NewSerializer(settings.Events{Versions: []string{"item.created.v9"}}, "catalog"); err == nil {
+		t.Error("want error for unknown schema; got nil")
+	}
+
+	tests := []struct {
+		testName       string
+		versions       []string
+		wantedSchemas  []string
+		wantedContains string
+	}{
+		{"Latest version by default", []string{}, []string{"item.created.v2"}, `"rule":"potion-consumable"`},
+		{"Transition to a new version", []string{"item.created.v1", "item.created.v2"}, []string{"item.created.v1", "item.created.v2"}, `"auto_tags"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			serializer, err := NewSerializer(settings.Events{Versions: tt.versions}, "catalog")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := itemCreatedContract.encode(serializer, item)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(events) != len(tt.wantedSchemas) {
+				t.Fatalf("want %d events; got %d", len(tt.wantedSchemas), len(events))
+			}
+
+			bodies := ""
+
+			for i, event := range events {
+				if event.schema.String() != tt.wantedSchemas[i] {
+					t.Errorf("want %q; got %q", tt.wantedSchemas[i], event.schema)
+				}
+
+				var payload struct {
+					Schema string `json:"schema"`
+				}
+
+				if err := json.Unmarshal(event.body, &payload); err != nil || payload.Schema != tt.wantedSchemas[i] {
+					t.Errorf("want payload schema %q; got %q", tt.wantedSchemas[i], event.body)
+				}
+
+				bodies += string(event.body)
+			}
+
+			if !strings.Contains(bodies, tt.wantedContains) {
+				t.Errorf("want bodies %q to contain %q", bodies, tt.wantedContains)
+			}
+		})
+	}
+}
+
+func TestPublishEventVersions(t *testing.T) {
+	serializer, err := NewSerializer(settings.Events{Versions: []string{"item.created.v1", "item.created.v2"}}, "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := &fakePublisher{}
+	item := data.Item{ID: primitive.NewObjectID(), Name: "Potion", Version: 1}
+	eventID := fmt.Sprintf("%s-1", item.ID.Hex())
+
+	err = publishEvent(context.Background(), newEventPublisher(publisher, serializer, "catalog"), ItemCreatedRoute, itemCreatedContract, item, Message{ID: eventID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(publisher.sent) != 2 {
+		t.Fatalf("want %d messages; got %d", 2, len(publisher.sent))
+	}
+
+	// Every version has its own message id, the id of the event is kept in a header
+	for i, schema := range []string{"item.created.v1", "item.created.v2"} {
+		msg := publisher.sent[i].Message
+
+		if want := eventID + "." + schema; msg.ID != want || msg.Type != schema {
+			t.Errorf("want message %q of type %q; got %q of type %q", want, schema, msg.ID, msg.Type)
+		}
+
+		if msg.Headers[EventIDHeader] != eventID {
+			t.Errorf("want event id %q; got %q", eventID, msg.Headers[EventIDHeader])
+		}
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	tests := []struct {
+		testName      string
+		body          string
+		messageType   string
+		wantedDecoded bool
+		wantsError    bool
+	}{
+		{"Unversioned producer", `{"item_id": "potion", "quantity": 1}`, "", true, false},
+		{"Unknown fields are ignored", `{"schema": "purchase.completed.v1", "item_id": "potion", "quantity": 1, "price": 5}`, "", true, false},
+		{"Version from the message type", `{"item_id": "potion", "quantity": 1}`, "purchase.completed.v1", true, false},
+		{"Unsupported version is skipped", `{"schema": "purchase.completed.v2", "item": {"id": "potion"}}`, "", false, false},
+		{"Other event", `{"schema": "user.updated.v1"}`, "", false, true},
+		{"Malformed payload", `{"item_id": `, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var event struct {
+				ItemID   string `json:"item_id"`
+				Quantity int64  `json:"quantity"`
+			}
+
+			decoded, err := DecodeEvent(PurchaseCompleted, []int{1}, []byte(tt.body), tt.messageType, &event)
+			if (err != nil) != tt.wantsError {
+				t.Fatalf("want error %t; got %v", tt.wantsError, err)
+			}
+
+			if decoded != tt.wantedDecoded {
+				t.Fatalf("want decoded %t; got %t", tt.wantedDecoded, decoded)
+			}
+
+			if decoded && (event.ItemID != "potion" || event.Quantity != 1) {
+				t.Errorf("want purchase of %d %q; got %+v", 1, "potion", event)
+			}
+		})
+	}
+}
+
+func TestItemEventID(t *testing.T) {
+	item := data.Item{ID: primitive.NewObjectID(), Name: "Potion", Version: 2}
+
+	tests := []struct {
+		name     string
+		changeID string
+		want     string
+	}{
+		{name: "Change", changeID: "8263F1", want: item.ID.Hex() + "-8263F1"},
+		{name: "Other change of the same version", changeID: "8263F2", want: item.ID.Hex() + "-8263F2"},
+		{name: "No change", changeID: "", want: item.ID.Hex() + "-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.changeID != "" {
+				ctx = ContextWithChangeID(ctx, tt.changeID)
+			}
+
+			if got := itemEventID(ctx, item); got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
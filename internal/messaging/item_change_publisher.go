@@ -0,0 +1,114 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ItemUpdatedV1 is the first version of the event published whenever an item is changed
+type ItemUpdatedV1 struct {
+	ID          string      `json:"id"`
+	ExternalID  string      `json:"external_id,omitempty"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       float64     `json:"price"`
+	Tags        []ItemTagV2 `json:"tags"`
+	Version     int32       `json:"version"`
+}
+
+// itemUpdatedContract defines the versions of the item updated event
+var itemUpdatedContract = contract[data.Item]{
+	name: ItemUpdated,
+	versions: map[int]func(item data.Item) any{
+		1: func(item data.Item) any {
+			return ItemUpdatedV1{
+				ID:          item.ID.Hex(),
+				ExternalID:  item.ExternalID,
+				Name:        item.Name,
+				Description: item.Description,
+				Price:       item.Price,
+				Tags:        itemTags(item),
+				Version:     item.Version,
+			}
+		},
+	},
+}
+
+// ItemDeletedV1 is the first version of the event published whenever an item is deleted
+type ItemDeletedV1 struct {
+	ID string `json:"id"`
+}
+
+// itemDeletedContract defines the versions of the item deleted event
+var itemDeletedContract = contract[primitive.ObjectID]{
+	name: ItemDeleted,
+	versions: map[int]func(id primitive.ObjectID) any{
+		1: func(id primitive.ObjectID) any { return ItemDeletedV1{ID: id.Hex()} },
+	},
+}
+
+// changeIDContextKey is the key used for getting and setting the id of the database change whose event is published
+type changeIDContextKey struct{}
+
+// ContextWithChangeID returns a copy of the given context holding the id of the database change whose event is
+// published (i.e. the resume token of a change stream event), which identifies the event instead of the version
+// of the item since the items written without the repository keep their version
+func ContextWithChangeID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, changeIDContextKey{}, id)
+}
+
+// itemEventID returns the message id of the event of the given item: the id of the change held by the given
+// context, if any, or the version of the item, so that consumers can deduplicate the events of the same change
+func itemEventID(ctx context.Context, item data.Item) string {
+	if changeID, ok := ctx.Value(changeIDContextKey{}).(string); ok && changeID != "" {
+		return fmt.Sprintf("%s-%s", item.ID.Hex(), changeID)
+	}
+
+	return fmt.Sprintf("%s-%d", item.ID.Hex(), item.Version)
+}
+
+// ItemChangePublisher is the publisher of the events of the changes made to the items, whichever
+// their origin (i.e. the API, a migration or a script run against the database)
+type ItemChangePublisher struct {
+	*eventPublisher
+}
+
+// NewItemChangePublisher returns a new ItemChangePublisher sending the events to the given publisher
+func NewItemChangePublisher(publisher Publisher, serializer *Serializer, serviceName string) *ItemChangePublisher {
+	return &ItemChangePublisher{
+		eventPublisher: newEventPublisher(publisher, serializer, serviceName),
+	}
+}
+
+// PublishCreated publishes the item created event of the given item, created at the given time
+func (publisher *ItemChangePublisher) PublishCreated(ctx context.Context, item data.Item, at time.Time) error {
+	return publishEvent(ctx, publisher.eventPublisher, ItemCreatedRoute, itemCreatedContract, item, Message{
+		ID:        itemEventID(ctx, item),
+		Key:       item.ID.Hex(),
+		Timestamp: at,
+	}, attribute.String("item_id", item.ID.Hex()))
+}
+
+// PublishUpdated publishes the item updated event of the given item, changed at the given time
+func (publisher *ItemChangePublisher) PublishUpdated(ctx context.Context, item data.Item, at time.Time) error {
+	return publishEvent(ctx, publisher.eventPublisher, ItemUpdatedRoute, itemUpdatedContract, item, Message{
+		ID:        itemEventID(ctx, item),
+		Key:       item.ID.Hex(),
+		Timestamp: at,
+	}, attribute.String("item_id", item.ID.Hex()))
+}
+
+// PublishDeleted publishes the item deleted event of the item with the given id, deleted at the given time
+func (publisher *ItemChangePublisher) PublishDeleted(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return publishEvent(ctx, publisher.eventPublisher, ItemDeletedRoute, itemDeletedContract, id, Message{
+		// An item is only deleted once
+		ID:        fmt.Sprintf("%s-deleted", id.Hex()),
+		Key:       id.Hex(),
+		Timestamp: at,
+	}, attribute.String("item_id", id.Hex()))
+}
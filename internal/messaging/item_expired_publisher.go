@@ -0,0 +1,53 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ItemExpiredV1 is the first version of the event published whenever an item expires
+type ItemExpiredV1 struct {
+	ID         string    `json:"id"`
+	ExternalID string    `json:"external_id,omitempty"`
+	Name       string    `json:"name"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// itemExpiredContract defines the versions of the item expired event
+var itemExpiredContract = contract[data.Item]{
+	name: ItemExpired,
+	versions: map[int]func(item data.Item) any{
+		1: func(item data.Item) any {
+			return ItemExpiredV1{ID: item.ID.Hex(), ExternalID: item.ExternalID, Name: item.Name, ExpiresAt: *item.ExpiresAt}
+		},
+	},
+}
+
+// ItemExpiredPublisher is the publisher for item expired event
+type ItemExpiredPublisher struct {
+	*eventPublisher
+}
+
+// NewItemExpiredPublisher returns a new ItemExpiredPublisher sending the events to the given publisher
+func NewItemExpiredPublisher(publisher Publisher, serializer *Serializer, serviceName string) *ItemExpiredPublisher {
+	return &ItemExpiredPublisher{
+		eventPublisher: newEventPublisher(publisher, serializer, serviceName),
+	}
+}
+
+// Publish publishes the event of the given expired item along with the trace context of the caller
+func (publisher *ItemExpiredPublisher) Publish(ctx context.Context, item data.Item) error {
+	if item.ExpiresAt == nil {
+		return fmt.Errorf("item %s has no expiration date", item.ID.Hex())
+	}
+
+	return publishEvent(ctx, publisher.eventPublisher, ItemExpiredRoute, itemExpiredContract, item, Message{
+		// Consumers can deduplicate the events of the same expiration
+		ID:  fmt.Sprintf("%s-%d", item.ID.Hex(), item.ExpiresAt.Unix()),
+		Key: item.ID.Hex(),
+	}, attribute.String("item_id", item.ID.Hex()))
+}
@@ -0,0 +1,133 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ItemCreatedV1 is the first version of the event published for every item of a catalog snapshot
+type ItemCreatedV1 struct {
+	ID          string         `json:"id"`
+	ExternalID  string         `json:"external_id,omitempty"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Price       float64        `json:"price"`
+	Tags        []string       `json:"tags"`
+	AutoTags    []data.AutoTag `json:"auto_tags"`
+	Version     int32          `json:"version"`
+}
+
+// ItemTagV2 is a tag of an item along with the auto-tagging rule which added it, if any
+type ItemTagV2 struct {
+	Name string `json:"name"`
+	Rule string `json:"rule,omitempty"`
+}
+
+// ItemCreatedV2 is the second version of the item created event. The tags added by the
+// auto-tagging rules are listed along with the other tags.
+type ItemCreatedV2 struct {
+	ID          string      `json:"id"`
+	ExternalID  string      `json:"external_id,omitempty"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       float64     `json:"price"`
+	Tags        []ItemTagV2 `json:"tags"`
+	Version     int32       `json:"version"`
+}
+
+// itemCreatedContract defines the versions of the item created event
+var itemCreatedContract = contract[data.Item]{
+	name: ItemCreated,
+	versions: map[int]func(item data.Item) any{
+		1: func(item data.Item) any {
+			return ItemCreatedV1{
+				ID:          item.ID.Hex(),
+				ExternalID:  item.ExternalID,
+				Name:        item.Name,
+				Description: item.Description,
+				Price:       item.Price,
+				Tags:        item.Tags,
+				AutoTags:    item.AutoTags,
+				Version:     item.Version,
+			}
+		},
+		2: func(item data.Item) any {
+			return ItemCreatedV2{
+				ID:          item.ID.Hex(),
+				ExternalID:  item.ExternalID,
+				Name:        item.Name,
+				Description: item.Description,
+				Price:       item.Price,
+				Tags:        itemTags(item),
+				Version:     item.Version,
+			}
+		},
+	},
+}
+
+// itemTags returns the tags of the given item along with the auto-tagging rule which added them
+func itemTags(item data.Item) []ItemTagV2 {
+	rules := make(map[string]string, len(item.AutoTags))
+	for _, autoTag := range item.AutoTags {
+		rules[autoTag.Tag] = autoTag.Rule
+	}
+
+	tags := make([]ItemTagV2, 0, len(item.Tags))
+	for _, tag := range item.Tags {
+		tags = append(tags, ItemTagV2{Name: tag, Rule: rules[tag]})
+	}
+
+	return tags
+}
+
+// SnapshotCompletedV1 is the first version of the event published once every item of a catalog snapshot was published
+type SnapshotCompletedV1 struct {
+	SnapshotID string `json:"snapshot_id"`
+	Items      int    `json:"items"`
+}
+
+// snapshotCompletedContract defines the versions of the snapshot completed event
+var snapshotCompletedContract = contract[SnapshotCompletedV1]{
+	name: SnapshotCompleted,
+	versions: map[int]func(event SnapshotCompletedV1) any{
+		1: func(event SnapshotCompletedV1) any { return event },
+	},
+}
+
+// ItemSnapshotPublisher is the publisher of the catalog snapshots used to bootstrap new consumers.
+// The messages of a snapshot share the same "snapshot_id" header. Every item is published as an
+// item created event and the last message is a snapshot completed event.
+type ItemSnapshotPublisher struct {
+	*eventPublisher
+}
+
+// NewItemSnapshotPublisher returns a new ItemSnapshotPublisher sending the events to the given publisher
+func NewItemSnapshotPublisher(publisher Publisher, serializer *Serializer, serviceName string) *ItemSnapshotPublisher {
+	return &ItemSnapshotPublisher{
+		eventPublisher: newEventPublisher(publisher, serializer, serviceName),
+	}
+}
+
+// PublishItem publishes the item created event of the given item as part of the given snapshot
+func (publisher *ItemSnapshotPublisher) PublishItem(ctx context.Context, snapshotID string, item data.Item) error {
+	return publishEvent(ctx, publisher.eventPublisher, ItemSnapshotRoute, itemCreatedContract, item, Message{
+		// Consumers can deduplicate the events of the same version of an item
+		ID:      fmt.Sprintf("%s-%d", item.ID.Hex(), item.Version),
+		Key:     item.ID.Hex(),
+		Headers: map[string]string{"snapshot_id": snapshotID},
+	}, attribute.String("snapshot_id", snapshotID), attribute.String("item_id", item.ID.Hex()))
+}
+
+// PublishCompleted publishes the snapshot completed event of the given snapshot
+func (publisher *ItemSnapshotPublisher) PublishCompleted(ctx context.Context, snapshotID string, items int) error {
+	event := SnapshotCompletedV1{SnapshotID: snapshotID, Items: items}
+
+	return publishEvent(ctx, publisher.eventPublisher, SnapshotCompletedRoute, snapshotCompletedContract, event, Message{
+		ID:      snapshotID,
+		Key:     snapshotID,
+		Headers: map[string]string{"snapshot_id": snapshotID},
+	}, attribute.String("snapshot_id", snapshotID))
+}
@@ -0,0 +1,260 @@
+// Package messaging defines the events published and consumed by the Catalog microservice
+// independently of the message broker they are exchanged through.
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Route is a struct that defines where an event is published. RabbitMQ publishes the event to the
+// exchange of the route while Kafka publishes it to the topic of the aggregate of the route.
+type Route struct {
+	Exchange  string
+	Aggregate string
+}
+
+// Routes of the published events
+var (
+	ItemCreatedRoute       = Route{Exchange: "Play.Catalog:item-created", Aggregate: "item"}
+	ItemUpdatedRoute       = Route{Exchange: "Play.Catalog:item-updated", Aggregate: "item"}
+	ItemDeletedRoute       = Route{Exchange: "Play.Catalog:item-deleted", Aggregate: "item"}
+	ItemExpiredRoute       = Route{Exchange: "Play.Catalog:item-expired", Aggregate: "item"}
+	ItemSnapshotRoute      = Route{Exchange: "Play.Catalog:item-snapshot", Aggregate: "item"}
+	SnapshotCompletedRoute = Route{Exchange: "Play.Catalog:item-snapshot", Aggregate: "snapshot"}
+	ErasureCompletedRoute  = Route{Exchange: "Play.Catalog:erasure-completed", Aggregate: "erasure"}
+)
+
+// Routes returns the routes of the events published by the service
+func Routes() []Route {
+	return []Route{
+		ItemCreatedRoute,
+		ItemUpdatedRoute,
+		ItemDeletedRoute,
+		ItemExpiredRoute,
+		ItemSnapshotRoute,
+		SnapshotCompletedRoute,
+		ErasureCompletedRoute,
+	}
+}
+
+// Message is a struct that defines a serialized event along with its metadata
+type Message struct {
+	ID          string            // Consumers can deduplicate the messages having the same ID
+	Key         string            // ID of the aggregate of the event, the events of an aggregate are kept in order
+	Type        string            // Schema of the event (i.e. "item.created.v1")
+	ContentType string            // Content type of the body
+	Timestamp   time.Time         // Time at which the event occurred
+	Headers     map[string]string // Headers of the message, including the trace context of the producer
+	Body        []byte
+}
+
+// Publisher is implemented by the message brokers the events are published to
+type Publisher interface {
+	// System returns the name of the message broker (i.e. "rabbitmq")
+	System() string
+	// Destination returns the name of the exchange or topic of the given route
+	Destination(route Route) string
+	// Send sends the given message to the destination of the given route
+	Send(ctx context.Context, route Route, msg Message) error
+}
+
+// Envelope is a struct that defines a message along with its route
+type Envelope struct {
+	Route   Route
+	Message Message
+}
+
+// BatchPublisher is implemented by the publishers able to send several messages at once.
+// SendBatch waits for every message to be confirmed by the broker and returns the error of each
+// message of the batch, which is nil when the message was confirmed.
+type BatchPublisher interface {
+	Publisher
+	SendBatch(ctx context.Context, batch []Envelope) []error
+}
+
+// Subscription is a struct that defines the events consumed by the service. RabbitMQ binds the queue
+// of the subscription to the exchange of its route while NATS JetStream creates a durable consumer
+// of the subject of the route. The name of the subscription is unique within the service.
+// The events of a subscription are shared between the instances of the service, unless it is a broadcast
+// subscription: every instance then receives the events published once it started, through an exclusive
+// queue deleted along with its connection or an ephemeral consumer, and they are neither retried nor dead lettered.
+type Subscription struct {
+	Route     Route
+	Name      string
+	Broadcast bool
+}
+
+// Subscriptions to the events of the other microservices
+var (
+	UserUpdatedSubscription = Subscription{
+		Route: Route{Exchange: "Play.Identity:user-updated", Aggregate: "user"},
+		Name:  "user-updated",
+	}
+	UserDeletedSubscription = Subscription{
+		Route: Route{Exchange: "Play.Identity:user-deleted", Aggregate: "user"},
+		Name:  "user-deleted",
+	}
+	UserErasureRequestedSubscription = Subscription{
+		Route: Route{Exchange: "Play.Identity:user-erasure-requested", Aggregate: "user"},
+		Name:  "user-erasure-requested",
+	}
+	UserCacheUpdatedSubscription = Subscription{
+		Route:     UserUpdatedSubscription.Route,
+		Name:      "user-cache-updated",
+		Broadcast: true,
+	}
+	UserCacheDeletedSubscription = Subscription{
+		Route:     UserDeletedSubscription.Route,
+		Name:      "user-cache-deleted",
+		Broadcast: true,
+	}
+	UserCacheErasedSubscription = Subscription{
+		Route:     UserErasureRequestedSubscription.Route,
+		Name:      "user-cache-erased",
+		Broadcast: true,
+	}
+	PurchaseCompletedSubscription = Subscription{
+		Route: Route{Exchange: "Play.Trading:purchase-completed", Aggregate: "purchase"},
+		Name:  "purchase-completed",
+	}
+)
+
+// Handler processes a consumed message. The span of the message is provided
+// so that the handler can record the attributes of the event.
+type Handler func(ctx context.Context, span trace.Span, msg Message) error
+
+// Consumer is implemented by the consumers of the message brokers the events are consumed from.
+// Messages which cannot be processed are discarded while the messages whose handler panicked
+// are redelivered a limited number of times.
+type Consumer interface {
+	// StartConsumer starts up the consumer and keeps it listening for messages
+	StartConsumer() error
+}
+
+// EventIDHeader is the header holding the id of the published event, shared by the messages of its versions
+const EventIDHeader = "x-event-id"
+
+// eventPublisher is the base of our publishers. It serializes the events according to their contract
+// and sends them to a message broker along with the trace context of the caller.
+type eventPublisher struct {
+	publisher  Publisher
+	serializer *Serializer
+	tracer     trace.Tracer
+}
+
+// newEventPublisher returns a new eventPublisher sending the events to the given publisher
+func newEventPublisher(publisher Publisher, serializer *Serializer, serviceName string) *eventPublisher {
+	return &eventPublisher{
+		publisher:  publisher,
+		serializer: serializer,
+		tracer:     otel.Tracer(serviceName),
+	}
+}
+
+// publishEvent publishes the given source in every version of the event enabled by the serializer of the
+// publisher. Each version is published in its own message whose type is the schema of the version and whose id
+// is the id of the event suffixed by the schema (i.e. "63f1...-2.item.created.v1"), so that the brokers and the
+// consumers deduplicating the messages by id keep every version. The id of the event is sent in the EventIDHeader.
+func publishEvent[T any](
+	ctx context.Context,
+	publisher *eventPublisher,
+	route Route,
+	c contract[T],
+	source T,
+	msg Message,
+	attributes ...attribute.KeyValue,
+) error {
+	events, err := c.encode(publisher.serializer, source)
+	if err != nil {
+		return err
+	}
+
+	eventID := msg.ID
+
+	for _, event := range events {
+		headers := make(map[string]string, len(msg.Headers)+1)
+		for key, value := range msg.Headers {
+			headers[key] = value
+		}
+
+		headers[EventIDHeader] = eventID
+
+		versioned := msg
+		versioned.ID = fmt.Sprintf("%s.%s", eventID, event.schema)
+		versioned.Type = event.schema.String()
+		versioned.Headers = headers
+		versioned.Body = event.body
+
+		err = publisher.publish(ctx, route, versioned, append(attributes, attribute.String("event.schema", versioned.Type))...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publish sends the given message within a producer span having the given attributes
+func (publisher *eventPublisher) publish(ctx context.Context, route Route, msg Message, attributes ...attribute.KeyValue) error {
+	destination := publisher.publisher.Destination(route)
+
+	// Create trace for the message
+	ctx, span := publisher.tracer.Start(
+		ctx,
+		fmt.Sprintf("%s send", destination),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String(publisher.publisher.System()),
+			semconv.MessagingDestinationKey.String(destination),
+		),
+		trace.WithAttributes(attributes...),
+	)
+	defer span.End()
+
+	err := publisher.send(ctx, route, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
+
+// send sends the given message along with the trace context of the caller.
+// The message is wrapped in a CloudEvent when the serializer of the publisher enables them.
+func (publisher *eventPublisher) send(ctx context.Context, route Route, msg Message) error {
+	// Propagate the trace context to the consumers
+	headers := make(map[string]string, len(msg.Headers))
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+
+	msg.Headers = headers
+	msg.ContentType = "application/json"
+
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now().UTC()
+	}
+
+	if publisher.serializer.cloudEventsSource != "" {
+		var err error
+
+		msg, err = wrapCloudEvent(ctx, publisher.serializer.cloudEventsSource, msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return publisher.publisher.Send(ctx, route, msg)
+}
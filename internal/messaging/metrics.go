@@ -0,0 +1,63 @@
+package messaging
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ConsumerMetrics is a struct that holds some prometheus metrics
+// regarding the messages processed by our consumers
+type ConsumerMetrics struct {
+	ConsumedMessagesCounter    *prometheus.CounterVec
+	FailedMessagesCounter      *prometheus.CounterVec
+	RedeliveredMessagesCounter *prometheus.CounterVec
+	PanicsCounter              *prometheus.CounterVec
+	DeadLetteredCounter        *prometheus.CounterVec
+	ProcessingTimeHistogram    *prometheus.HistogramVec
+}
+
+// NewConsumerMetrics creates counters and histograms used to keep
+// track of consumer metrics in our application
+func NewConsumerMetrics(appName string) *ConsumerMetrics {
+	consumedMessagesCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_consumed_messages_total", appName),
+		Help: "The total number of messages consumed",
+	}, []string{"queue"})
+
+	failedMessagesCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_failed_messages_total", appName),
+		Help: "The total number of messages which failed to be processed",
+	}, []string{"queue"})
+
+	redeliveredMessagesCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_redelivered_messages_total", appName),
+		Help: "The total number of messages which were redelivered by the broker",
+	}, []string{"queue"})
+
+	panicsCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_message_handler_panics_total", appName),
+		Help: "The total number of panics recovered while processing messages",
+	}, []string{"queue"})
+
+	deadLetteredCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_dead_lettered_messages_total", appName),
+		Help: "The total number of messages sent to the dead letter queue",
+	}, []string{"queue"})
+
+	processingTimeHistogram := promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    fmt.Sprintf("%s_message_processing_time_seconds", appName),
+		Help:    "Processing time of consumed messages in seconds",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"queue"})
+
+	return &ConsumerMetrics{
+		ConsumedMessagesCounter:    consumedMessagesCounter,
+		FailedMessagesCounter:      failedMessagesCounter,
+		RedeliveredMessagesCounter: redeliveredMessagesCounter,
+		PanicsCounter:              panicsCounter,
+		DeadLetteredCounter:        deadLetteredCounter,
+		ProcessingTimeHistogram:    processingTimeHistogram,
+	}
+}
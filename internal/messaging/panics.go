@@ -0,0 +1,29 @@
+package messaging
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error returned when a message handler panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error returns the panic value as a string
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("message handler panicked: %v", e.Value)
+}
+
+// RecoverPanic runs the given message handler and converts a panic raised by it into a PanicError
+// holding the stack trace of the goroutine at the time of the panic
+func RecoverPanic(handler func() error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &PanicError{Value: value, Stack: debug.Stack()}
+		}
+	}()
+
+	return handler()
+}
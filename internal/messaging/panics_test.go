@@ -0,0 +1,40 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	handlerErr := errors.New("handler failed")
+
+	tests := []struct {
+		testName    string
+		handler     func() error
+		wantedPanic bool
+		wantedErr   error
+	}{
+		{"Handler succeeded", func() error { return nil }, false, nil},
+		{"Handler failed", func() error { return handlerErr }, false, handlerErr},
+		{"Handler panicked", func() error { panic("nil map") }, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			err := RecoverPanic(tt.handler)
+
+			var handlerPanic *PanicError
+			if errors.As(err, &handlerPanic) != tt.wantedPanic {
+				t.Errorf("want panic %t; got %v", tt.wantedPanic, err)
+			}
+
+			if !tt.wantedPanic && !errors.Is(err, tt.wantedErr) {
+				t.Errorf("want %v; got %v", tt.wantedErr, err)
+			}
+
+			if tt.wantedPanic && len(handlerPanic.Stack) == 0 {
+				t.Error("want panic stack trace to be recorded")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// purchaseCompletedEvent is the event sent by the Trading microservice whenever a purchase is completed
+type purchaseCompletedEvent struct {
+	ItemID   string `json:"item_id"`
+	Quantity int64  `json:"quantity"`
+}
+
+// PurchaseRecorder is implemented by the counters of the purchases of the items
+type PurchaseRecorder interface {
+	RecordPurchase(ctx context.Context, key string, id primitive.ObjectID, quantity int64) error
+}
+
+// PurchaseCompletedHandler is the handler of the purchase completed events. It counts the purchases of the items.
+type PurchaseCompletedHandler struct {
+	recorder PurchaseRecorder
+}
+
+// NewPurchaseCompletedHandler returns a new PurchaseCompletedHandler
+func NewPurchaseCompletedHandler(recorder PurchaseRecorder) *PurchaseCompletedHandler {
+	return &PurchaseCompletedHandler{recorder: recorder}
+}
+
+// Handle decodes the purchase completed event contained in a message and counts the purchase.
+// The purchase is written before the message is acknowledged and its message id is used as the key of the
+// purchase so that a redelivered message is not counted twice. Versions of the event which are not supported
+// yet are skipped.
+func (handler *PurchaseCompletedHandler) Handle(ctx context.Context, span trace.Span, msg Message) error {
+	var event purchaseCompletedEvent
+
+	decoded, err := DecodeEvent(PurchaseCompleted, []int{1}, msg.Body, msg.Type, &event)
+	if err != nil || !decoded {
+		return err
+	}
+
+	span.SetAttributes(attribute.String("item_id", event.ItemID), attribute.Int64("quantity", event.Quantity))
+
+	id, err := primitive.ObjectIDFromHex(event.ItemID)
+	if err != nil {
+		return fmt.Errorf("invalid item id %q", event.ItemID)
+	}
+
+	if event.Quantity < 1 {
+		return fmt.Errorf("invalid quantity %d", event.Quantity)
+	}
+
+	// Messages without id are counted again when they are redelivered
+	key := msg.ID
+	if key == "" {
+		key = primitive.NewObjectID().Hex()
+	}
+
+	return handler.recorder.RecordPurchase(ctx, "purchase:"+key, id, event.Quantity)
+}
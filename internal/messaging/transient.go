@@ -0,0 +1,30 @@
+package messaging
+
+import (
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+)
+
+// TransientError is the error returned when a message handler failed with a transient error
+// (i.e. MongoDB was unavailable), so that the message is retried later instead of being discarded
+type TransientError struct {
+	Err error
+}
+
+// Error returns the message of the transient error
+func (e *TransientError) Error() string {
+	return "transient error: " + e.Err.Error()
+}
+
+// Unwrap returns the transient error
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// Transient wraps the given error into a TransientError when it is transient and returns it as is otherwise
+func Transient(err error) error {
+	if err == nil || !data.IsTransient(err) {
+		return err
+	}
+
+	return &TransientError{Err: err}
+}
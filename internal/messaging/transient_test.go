@@ -0,0 +1,35 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+)
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		testName        string
+		err             error
+		wantedTransient bool
+	}{
+		{"No error", nil, false},
+		{"Permanent error", errors.New("invalid user"), false},
+		{"Circuit breaker open", data.ErrCircuitOpen, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			err := Transient(tt.err)
+
+			var transientErr *TransientError
+			if errors.As(err, &transientErr) != tt.wantedTransient {
+				t.Errorf("want transient %t; got %v", tt.wantedTransient, err)
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("want error %v; got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package messaging
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// UserHolder is implemented by the caches of the users, whose changed users are held until their change is stored
+type UserHolder interface {
+	Hold(id int64, version int32)
+}
+
+// UserCacheHandler is the handler of the user updated and deleted events received by every instance of the service.
+// The events are stored by a single instance, so each instance invalidates the changed users in its own cache and
+// does not cache them again until their change is stored.
+type UserCacheHandler struct {
+	cache UserHolder
+}
+
+// NewUserCacheHandler returns a new UserCacheHandler holding the changed users in the given cache
+func NewUserCacheHandler(cache UserHolder) *UserCacheHandler {
+	return &UserCacheHandler{cache: cache}
+}
+
+// HandleUpdated decodes the user updated event contained in a message and holds the user until its version is stored.
+// Versions of the event which are not supported yet are skipped.
+func (handler *UserCacheHandler) HandleUpdated(ctx context.Context, span trace.Span, msg Message) error {
+	var event userUpdatedEvent
+
+	decoded, err := DecodeEvent(UserUpdated, []int{1}, msg.Body, msg.Type, &event)
+	if err != nil || !decoded {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int64("user_id", event.ID), attribute.Int("user_version", int(event.Version)))
+
+	handler.cache.Hold(event.ID, event.Version)
+
+	return nil
+}
+
+// HandleDeleted decodes the user deleted event contained in a message and holds the user until the cache expires,
+// by which time the user is deleted. Versions of the event which are not supported yet are skipped.
+func (handler *UserCacheHandler) HandleDeleted(ctx context.Context, span trace.Span, msg Message) error {
+	return handler.holdDeleted(span, UserDeleted, msg)
+}
+
+// HandleErasureRequested decodes the user erasure requested event contained in a message and holds the user until
+// the cache expires, by which time the user is erased. Versions of the event which are not supported yet are skipped.
+func (handler *UserCacheHandler) HandleErasureRequested(ctx context.Context, span trace.Span, msg Message) error {
+	return handler.holdDeleted(span, UserErasureRequested, msg)
+}
+
+// holdDeleted decodes the event with the given name, which removes a user, and holds the user at any version
+func (handler *UserCacheHandler) holdDeleted(span trace.Span, name string, msg Message) error {
+	var event userDeletedEvent
+
+	decoded, err := DecodeEvent(name, []int{1}, msg.Body, msg.Type, &event)
+	if err != nil || !decoded {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int64("user_id", event.ID))
+
+	handler.cache.Hold(event.ID, 0)
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeHolder records the held users
+type fakeHolder struct {
+	held []string
+}
+
+// Hold records the held user along with its version
+func (holder *fakeHolder) Hold(id int64, version int32) {
+	holder.held = append(holder.held, fmt.Sprintf("%d@%d", id, version))
+}
+
+func TestUserCacheHandler(t *testing.T) {
+	tests := []struct {
+		testName   string
+		handle     func(handler *UserCacheHandler) Handler
+		body       string
+		wantedHeld string
+	}{
+		{"Updated", func(handler *UserCacheHandler) Handler { return handler.HandleUpdated }, `{"schema": "user.updated.v1", "id": 7, "version": 3}`, "[7@3]"},
+		{"Deleted", func(handler *UserCacheHandler) Handler { return handler.HandleDeleted }, `{"schema": "user.deleted.v1", "id": 7}`, "[7@0]"},
+		{"Erasure requested", func(handler *UserCacheHandler) Handler { return handler.HandleErasureRequested }, `{"schema": "user.erasure.requested.v1", "id": 7, "request_id": "d7c0"}`, "[7@0]"},
+		{"Unsupported version", func(handler *UserCacheHandler) Handler { return handler.HandleUpdated }, `{"schema": "user.updated.v9", "id": 7, "version": 3}`, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			holder := &fakeHolder{}
+
+			err := tt.handle(NewUserCacheHandler(holder))(context.Background(), trace.SpanFromContext(context.Background()), Message{Body: []byte(tt.body)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if held := fmt.Sprint(holder.held); held != tt.wantedHeld {
+				t.Errorf("want %s; got %s", tt.wantedHeld, held)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PlayEconomy37/Play.Common/database"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// userDeletedEvent is the event sent by the Identity microservice whenever an user is deleted
+type userDeletedEvent struct {
+	ID int64 `json:"id"`
+}
+
+// UserDeletedHandler is the handler of the user deleted events. It removes the deleted users from the catalog
+// so that their requests are no longer authenticated.
+type UserDeletedHandler struct {
+	store       UserStore
+	invalidator UserInvalidator
+}
+
+// NewUserDeletedHandler returns a new UserDeletedHandler invalidating the deleted users with the given invalidator
+func NewUserDeletedHandler(store UserStore, invalidator UserInvalidator) *UserDeletedHandler {
+	return &UserDeletedHandler{store: store, invalidator: invalidator}
+}
+
+// Handle decodes the user deleted event contained in a message and deletes the user.
+// Versions of the event which are not supported yet are skipped.
+func (handler *UserDeletedHandler) Handle(ctx context.Context, span trace.Span, msg Message) error {
+	var event userDeletedEvent
+
+	decoded, err := DecodeEvent(UserDeleted, []int{1}, msg.Body, msg.Type, &event)
+	if err != nil || !decoded {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int64("user_id", event.ID))
+
+	// The user is invalidated even if it was already deleted, i.e. by a redelivery
+	handler.invalidator.Invalidate(event.ID)
+
+	err = handler.store.Delete(ctx, event.ID)
+	if err != nil && !errors.Is(err, database.ErrRecordNotFound) {
+		return Transient(err)
+	}
+
+	return nil
+}
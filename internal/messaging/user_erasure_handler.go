@@ -0,0 +1,108 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// userErasureRequestedEvent is the event sent by the Identity microservice whenever a user asks for their
+// personal data to be erased
+type userErasureRequestedEvent struct {
+	ID        int64  `json:"id"`
+	RequestID string `json:"request_id"`
+}
+
+// ErasureCompletedV1 is the first version of the event published once the personal data of a user was erased
+type ErasureCompletedV1 struct {
+	UserID      int64        `json:"user_id"`
+	RequestID   string       `json:"request_id,omitempty"`
+	Erased      data.Erasure `json:"erased"`
+	CompletedAt time.Time    `json:"completed_at"`
+}
+
+// erasureCompletedContract defines the versions of the erasure completed event
+var erasureCompletedContract = contract[ErasureCompletedV1]{
+	name: ErasureCompleted,
+	versions: map[int]func(event ErasureCompletedV1) any{
+		1: func(event ErasureCompletedV1) any { return event },
+	},
+}
+
+// UserEraser is implemented by the stores erasing the personal data of the users
+type UserEraser interface {
+	Erase(ctx context.Context, userID int64) (data.Erasure, error)
+}
+
+// UserErasureHandler is the handler of the user erasure requested events. It erases the personal data the
+// catalog holds about the user, removes the user from the cache and confirms the erasure with an erasure
+// completed event.
+type UserErasureHandler struct {
+	*eventPublisher
+	eraser      UserEraser
+	invalidator UserInvalidator
+}
+
+// NewUserErasureHandler returns a new UserErasureHandler sending the erasure completed events to the given publisher
+func NewUserErasureHandler(
+	eraser UserEraser,
+	invalidator UserInvalidator,
+	publisher Publisher,
+	serializer *Serializer,
+	serviceName string,
+) *UserErasureHandler {
+	return &UserErasureHandler{
+		eventPublisher: newEventPublisher(publisher, serializer, serviceName),
+		eraser:         eraser,
+		invalidator:    invalidator,
+	}
+}
+
+// Handle decodes the user erasure requested event contained in a message, erases the user and publishes the
+// erasure completed event. A redelivered request is erased and confirmed again, with the same message id.
+// Versions of the event which are not supported yet are skipped.
+func (handler *UserErasureHandler) Handle(ctx context.Context, span trace.Span, msg Message) error {
+	var event userErasureRequestedEvent
+
+	decoded, err := DecodeEvent(UserErasureRequested, []int{1}, msg.Body, msg.Type, &event)
+	if err != nil || !decoded {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int64("user_id", event.ID), attribute.String("request_id", event.RequestID))
+
+	erasure, err := handler.eraser.Erase(ctx, event.ID)
+	if err != nil {
+		return Transient(err)
+	}
+
+	// The user is invalidated once deleted so that the cache is not filled again in the meantime
+	handler.invalidator.Invalidate(event.ID)
+
+	// Consumers can deduplicate the confirmations of the same request
+	messageID := event.RequestID
+	if messageID == "" {
+		messageID = fmt.Sprintf("erasure-%d", event.ID)
+	}
+
+	completed := ErasureCompletedV1{
+		UserID:      event.ID,
+		RequestID:   event.RequestID,
+		Erased:      erasure,
+		CompletedAt: time.Now().UTC(),
+	}
+
+	err = publishEvent(ctx, handler.eventPublisher, ErasureCompletedRoute, erasureCompletedContract, completed, Message{
+		ID:  messageID,
+		Key: fmt.Sprint(event.ID),
+	}, attribute.Int64("user_id", event.ID))
+	if err != nil {
+		return Transient(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeEraser returns the given erasure or error for every user
+type fakeEraser struct {
+	erasure data.Erasure
+	err     error
+	erased  []int64
+}
+
+// Erase records the erased user
+func (eraser *fakeEraser) Erase(ctx context.Context, userID int64) (data.Erasure, error) {
+	eraser.erased = append(eraser.erased, userID)
+
+	return eraser.erasure, eraser.err
+}
+
+// fakeInvalidator records the invalidated users
+type fakeInvalidator struct {
+	invalidated []int64
+}
+
+// Invalidate records the invalidated user
+func (invalidator *fakeInvalidator) Invalidate(id int64) {
+	invalidator.invalidated = append(invalidator.invalidated, id)
+}
+
+// fakePublisher records the sent messages
+type fakePublisher struct {
+	sent []Envelope
+}
+
+// System returns the name of the fake broker
+func (publisher *fakePublisher) System() string {
+	return "fake"
+}
+
+// Destination returns the exchange of the given route
+func (publisher *fakePublisher) Destination(route Route) string {
+	return route.Exchange
+}
+
+// Send records the given message
+func (publisher *fakePublisher) Send(ctx context.Context, route Route, msg Message) error {
+	publisher.sent = append(publisher.sent, Envelope{Route: route, Message: msg})
+
+	return nil
+}
+
+func TestUserErasureHandler(t *testing.T) {
+	serializer, err := NewSerializer(settings.Events{}, "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Erasure confirmed", func(t *testing.T) {
+		eraser := &fakeEraser{erasure: data.Erasure{Users: 1, Reviews: 2, Favorites: 3, Actors: 4}}
+		invalidator := &fakeInvalidator{}
+		publisher := &fakePublisher{}
+		handler := NewUserErasureHandler(eraser, invalidator, publisher, serializer, "catalog")
+
+		err := handler.Handle(context.Background(), trace.SpanFromContext(context.Background()), Message{
+			Body: []byte(`{"schema": "user.erasure.requested.v1", "id": 7, "request_id": "d7c0"}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(eraser.erased) != 1 || eraser.erased[0] != 7 || len(invalidator.invalidated) != 1 || invalidator.invalidated[0] != 7 {
+			t.Errorf("want user 7 erased and invalidated; got %v and %v", eraser.erased, invalidator.invalidated)
+		}
+
+		if len(publisher.sent) != 1 {
+			t.Fatalf("want %d message; got %d", 1, len(publisher.sent))
+		}
+
+		sent := publisher.sent[0]
+		if sent.Route != ErasureCompletedRoute || sent.Message.Headers[EventIDHeader] != "d7c0" || sent.Message.Key != "7" || sent.Message.Type != "erasure.completed.v1" {
+			t.Errorf("want erasure completed message of request %q; got %+v", "d7c0", sent)
+		}
+
+		var event ErasureCompletedV1
+
+		if err := json.Unmarshal(sent.Message.Body, &event); err != nil {
+			t.Fatal(err)
+		}
+
+		if event.UserID != 7 || event.RequestID != "d7c0" || event.Erased != eraser.erasure || event.CompletedAt.IsZero() {
+			t.Errorf("want the erasure of user 7; got %+v", event)
+		}
+	})
+
+	t.Run("Transient failure", func(t *testing.T) {
+		eraser := &fakeEraser{err: data.ErrCircuitOpen}
+		invalidator := &fakeInvalidator{}
+		publisher := &fakePublisher{}
+		handler := NewUserErasureHandler(eraser, invalidator, publisher, serializer, "catalog")
+
+		err := handler.Handle(context.Background(), trace.SpanFromContext(context.Background()), Message{
+			Body: []byte(`{"id": 7}`),
+		})
+
+		var transientErr *TransientError
+		if !errors.As(err, &transientErr) {
+			t.Errorf("want transient error; got %v", err)
+		}
+
+		if len(publisher.sent) != 0 {
+			t.Errorf("want no confirmation; got %d messages", len(publisher.sent))
+		}
+	})
+}
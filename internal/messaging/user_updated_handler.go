@@ -0,0 +1,74 @@
+package messaging
+
+import (
+	"context"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/events"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// userUpdatedEvent is the event sent by the Identity microservice whenever an user is created or updated.
+// It extends the common event with the display name projected by the catalog.
+type userUpdatedEvent struct {
+	events.UserUpdatedEvent
+	Name string `json:"name"`
+}
+
+// UserStore is implemented by the stores applying the updates of the users
+type UserStore interface {
+	Apply(ctx context.Context, update data.UserUpdate) (bool, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// UserInvalidator is implemented by the caches of the users, whose changed users are invalidated
+type UserInvalidator interface {
+	Invalidate(id int64)
+}
+
+// UserUpdatedHandler is the handler of the user updated events. It keeps the users of the
+// catalog in sync with the ones of the Identity microservice. The events which are older than the
+// stored user, i.e. redeliveries arriving after a newer event, are discarded.
+type UserUpdatedHandler struct {
+	store       UserStore
+	invalidator UserInvalidator
+}
+
+// NewUserUpdatedHandler returns a new UserUpdatedHandler invalidating the updated users with the given invalidator
+func NewUserUpdatedHandler(store UserStore, invalidator UserInvalidator) *UserUpdatedHandler {
+	return &UserUpdatedHandler{store: store, invalidator: invalidator}
+}
+
+// Handle decodes the user updated event contained in a message and processes it.
+// Versions of the event which are not supported yet are skipped.
+func (handler *UserUpdatedHandler) Handle(ctx context.Context, span trace.Span, msg Message) error {
+	var event userUpdatedEvent
+
+	decoded, err := DecodeEvent(UserUpdated, []int{1}, msg.Body, msg.Type, &event)
+	if err != nil || !decoded {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int64("user_id", event.ID), attribute.Int("user_version", int(event.Version)))
+
+	applied, err := handler.store.Apply(ctx, data.UserUpdate{
+		ID:          event.ID,
+		Name:        event.Name,
+		Email:       event.Email,
+		Permissions: event.Permissions,
+		Activated:   event.Activated,
+		Version:     event.Version,
+	})
+	if err != nil {
+		return Transient(err)
+	}
+
+	span.SetAttributes(attribute.Bool("stale", !applied))
+
+	if applied {
+		handler.invalidator.Invalidate(event.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,420 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ContentType is the media type of the MessagePack documents
+const ContentType = "application/msgpack"
+
+// maxDepth is the maximum nesting of the converted documents, which protects the recursive conversions
+const maxDepth = 100
+
+// ErrMalformed is returned when a MessagePack document cannot be converted into JSON
+var ErrMalformed = errors.New("malformed MessagePack")
+
+// FromJSON converts a JSON document into MessagePack. The order of the object keys is preserved and
+// the integer numbers are encoded as integers, the other numbers as 64-bit floats.
+func FromJSON(document []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+
+	var buf bytes.Buffer
+
+	err := encodeJSONValue(&buf, decoder, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure that the document only contains a single JSON value
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("document must only contain a single JSON value")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeJSONValue encodes the next JSON value of the decoder into MessagePack
+func encodeJSONValue(buf *bytes.Buffer, decoder *json.Decoder, depth int) error {
+	if depth > maxDepth {
+		return errors.New("document is nested too deeply")
+	}
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token := token.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if token {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		encodeNumber(buf, token)
+	case string:
+		encodeString(buf, token)
+	case json.Delim:
+		// The values are encoded first since the headers of the arrays and maps hold their length
+		var values bytes.Buffer
+		var length int
+
+		for decoder.More() {
+			if token == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+
+				encodeString(&values, key.(string))
+			}
+
+			err := encodeJSONValue(&values, decoder, depth+1)
+			if err != nil {
+				return err
+			}
+
+			length++
+		}
+
+		// Consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+
+		if token == '{' {
+			encodeHeader(buf, length, 0x80, 0xde, 0xdf)
+		} else {
+			encodeHeader(buf, length, 0x90, 0xdc, 0xdd)
+		}
+
+		buf.Write(values.Bytes())
+	}
+
+	return nil
+}
+
+// encodeNumber encodes a JSON number with the smallest MessagePack representation
+func encodeNumber(buf *bytes.Buffer, number json.Number) {
+	value, err := number.Int64()
+	if err != nil {
+		// Numbers with a fraction, an exponent or out of the int64 range are floats
+		float, _ := number.Float64()
+
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(float))
+
+		return
+	}
+
+	switch {
+	case value >= 0 && value <= math.MaxInt8:
+		buf.WriteByte(byte(value))
+	case value < 0 && value >= -32:
+		buf.WriteByte(byte(int8(value)))
+	case value >= math.MinInt8 && value <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(value)))
+	case value >= math.MinInt16 && value <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(value))
+	case value >= math.MinInt32 && value <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(value))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, value)
+	}
+}
+
+// encodeString encodes a string as a MessagePack str
+func encodeString(buf *bytes.Buffer, value string) {
+	switch length := len(value); {
+	case length <= 31:
+		buf.WriteByte(0xa0 | byte(length))
+	case length <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		binary.Write(buf, binary.BigEndian, uint16(length))
+	default:
+		buf.WriteByte(0xdb)
+		binary.Write(buf, binary.BigEndian, uint32(length))
+	}
+
+	buf.WriteString(value)
+}
+
+// encodeHeader encodes the header of an array or a map with the given fix, 16-bit and 32-bit formats
+func encodeHeader(buf *bytes.Buffer, length int, fix byte, format16 byte, format32 byte) {
+	switch {
+	case length <= 15:
+		buf.WriteByte(fix | byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(format16)
+		binary.Write(buf, binary.BigEndian, uint16(length))
+	default:
+		buf.WriteByte(format32)
+		binary.Write(buf, binary.BigEndian, uint32(length))
+	}
+}
+
+// ToJSON converts a MessagePack document into JSON. Map keys must be strings, binary values are
+// converted into base64 strings like encoding/json does and extension types are not supported.
+func ToJSON(document []byte) ([]byte, error) {
+	reader := bytes.NewReader(document)
+
+	var buf bytes.Buffer
+
+	err := decodeValue(&buf, reader, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader.Len() != 0 {
+		return nil, fmt.Errorf("%w: document must only contain a single value", ErrMalformed)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeValue converts the next MessagePack value of the reader into JSON
+func decodeValue(buf *bytes.Buffer, reader *bytes.Reader, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%w: document is nested too deeply", ErrMalformed)
+	}
+
+	format, err := reader.ReadByte()
+	if err != nil {
+		return fmt.Errorf("%w: unexpected end of document", ErrMalformed)
+	}
+
+	switch {
+	case format <= 0x7f:
+		buf.WriteString(strconv.Itoa(int(format)))
+		return nil
+	case format >= 0xe0:
+		buf.WriteString(strconv.Itoa(int(int8(format))))
+		return nil
+	case format >= 0x80 && format <= 0x8f:
+		return decodeMap(buf, reader, int(format&0x0f), depth)
+	case format >= 0x90 && format <= 0x9f:
+		return decodeArray(buf, reader, int(format&0x0f), depth)
+	case format >= 0xa0 && format <= 0xbf:
+		return decodeString(buf, reader, int(format&0x1f))
+	}
+
+	switch format {
+	case 0xc0:
+		buf.WriteString("null")
+	case 0xc2:
+		buf.WriteString("false")
+	case 0xc3:
+		buf.WriteString("true")
+	case 0xc4, 0xc5, 0xc6:
+		length, err := readLength(reader, format-0xc4)
+		if err != nil {
+			return err
+		}
+
+		value, err := readBytes(reader, length)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte('"')
+		buf.WriteString(base64.StdEncoding.EncodeToString(value))
+		buf.WriteByte('"')
+	case 0xca:
+		var value float32
+		if err := binary.Read(reader, binary.BigEndian, &value); err != nil {
+			return fmt.Errorf("%w: unexpected end of document", ErrMalformed)
+		}
+
+		return writeFloat(buf, float64(value), 32)
+	case 0xcb:
+		var value float64
+		if err := binary.Read(reader, binary.BigEndian, &value); err != nil {
+			return fmt.Errorf("%w: unexpected end of document", ErrMalformed)
+		}
+
+		return writeFloat(buf, value, 64)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		value, err := readUint(reader, 1<<(format-0xcc))
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(strconv.FormatUint(value, 10))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (format - 0xd0)
+
+		value, err := readUint(reader, size)
+		if err != nil {
+			return err
+		}
+
+		// Sign extend the value from its size
+		shift := 64 - 8*size
+		buf.WriteString(strconv.FormatInt(int64(value<<shift)>>shift, 10))
+	case 0xd9, 0xda, 0xdb:
+		length, err := readLength(reader, format-0xd9)
+		if err != nil {
+			return err
+		}
+
+		return decodeString(buf, reader, length)
+	case 0xdc, 0xdd:
+		length, err := readLength(reader, format-0xdc+1)
+		if err != nil {
+			return err
+		}
+
+		return decodeArray(buf, reader, length, depth)
+	case 0xde, 0xdf:
+		length, err := readLength(reader, format-0xde+1)
+		if err != nil {
+			return err
+		}
+
+		return decodeMap(buf, reader, length, depth)
+	default:
+		return fmt.Errorf("%w: unsupported format 0x%x", ErrMalformed, format)
+	}
+
+	return nil
+}
+
+// decodeArray converts a MessagePack array of the given length into a JSON array
+func decodeArray(buf *bytes.Buffer, reader *bytes.Reader, length int, depth int) error {
+	buf.WriteByte('[')
+
+	for i := 0; i < length; i++ {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+
+		err := decodeValue(buf, reader, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte(']')
+
+	return nil
+}
+
+// decodeMap converts a MessagePack map of the given length into a JSON object
+func decodeMap(buf *bytes.Buffer, reader *bytes.Reader, length int, depth int) error {
+	buf.WriteByte('{')
+
+	for i := 0; i < length; i++ {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+
+		// JSON objects only have string keys
+		format, err := reader.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: unexpected end of document", ErrMalformed)
+		}
+
+		if !(format >= 0xa0 && format <= 0xbf) && format != 0xd9 && format != 0xda && format != 0xdb {
+			return fmt.Errorf("%w: map keys must be strings", ErrMalformed)
+		}
+
+		reader.UnreadByte()
+
+		err = decodeValue(buf, reader, depth+1)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte(':')
+
+		err = decodeValue(buf, reader, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return nil
+}
+
+// decodeString converts a MessagePack str of the given length into a JSON string
+func decodeString(buf *bytes.Buffer, reader *bytes.Reader, length int) error {
+	value, err := readBytes(reader, length)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(string(value))
+	if err != nil {
+		return err
+	}
+
+	buf.Write(encoded)
+
+	return nil
+}
+
+// writeFloat writes a float into JSON. JSON has no representation of NaN and of the infinities.
+func writeFloat(buf *bytes.Buffer, value float64, bitSize int) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%w: unsupported float value %v", ErrMalformed, value)
+	}
+
+	buf.WriteString(strconv.FormatFloat(value, 'g', -1, bitSize))
+
+	return nil
+}
+
+// readLength reads the length of a value stored on 1, 2 or 4 bytes for the size indexes 0, 1 and 2
+func readLength(reader *bytes.Reader, sizeIndex byte) (int, error) {
+	length, err := readUint(reader, 1<<sizeIndex)
+
+	return int(length), err
+}
+
+// readUint reads a big-endian unsigned integer of the given size in bytes
+func readUint(reader *bytes.Reader, size int) (uint64, error) {
+	value, err := readBytes(reader, size)
+	if err != nil {
+		return 0, err
+	}
+
+	var result uint64
+	for _, b := range value {
+		result = result<<8 | uint64(b)
+	}
+
+	return result, nil
+}
+
+// readBytes reads the given number of bytes, making sure that the document holds them before allocating them
+func readBytes(reader *bytes.Reader, length int) ([]byte, error) {
+	if length < 0 || length > reader.Len() {
+		return nil, fmt.Errorf("%w: unexpected end of document", ErrMalformed)
+	}
+
+	value := make([]byte, length)
+	reader.Read(value)
+
+	return value, nil
+}
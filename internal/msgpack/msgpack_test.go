@@ -0,0 +1,101 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     []byte
+	}{
+		{"Null", `null`, []byte{0xc0}},
+		{"Booleans", `[true, false]`, []byte{0x92, 0xc3, 0xc2}},
+		{"Positive fixint", `5`, []byte{0x05}},
+		{"Negative fixint", `-3`, []byte{0xfd}},
+		{"Int8", `-100`, []byte{0xd0, 0x9c}},
+		{"Int16", `1000`, []byte{0xd1, 0x03, 0xe8}},
+		{"Int32", `100000`, []byte{0xd2, 0x00, 0x01, 0x86, 0xa0}},
+		{"Float", `0.5`, []byte{0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0}},
+		{"Fixstr", `"Potion"`, append([]byte{0xa6}, "Potion"...)},
+		{"Str8", `"` + strings.Repeat("a", 32) + `"`, append([]byte{0xd9, 0x20}, strings.Repeat("a", 32)...)},
+		{"Keys in order", `{"name": "Ether", "price": 3}`, append(append([]byte{0x82, 0xa4}, "name"...), append(append([]byte{0xa5}, "Ether"...), append(append([]byte{0xa5}, "price"...), 0x03)...)...)},
+		{"Array16", `[` + strings.TrimSuffix(strings.Repeat("1,", 16), ",") + `]`, append([]byte{0xdc, 0x00, 0x10}, bytes.Repeat([]byte{0x01}, 16)...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromJSON([]byte(tt.document))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("want % x; got % x", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	document := `{"item":{"id":"63407e2c8bcd4a43ec1c4ff4","name":"Potion \"Deluxe\"","price":5.25,"tags":["healing","consumable"],"version":-70000,"expires_at":null,"featured":false,"rating":{"average":4.5,"count":300},"stock":7,"delta":-2}}`
+
+	encoded, err := FromJSON([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := ToJSON(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(decoded) != document {
+		t.Errorf("want %s; got %s", document, decoded)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		document  []byte
+		want      string
+		wantedErr bool
+	}{
+		{"Unsigned integers", []byte{0x93, 0xcc, 0xff, 0xcd, 0x01, 0x00, 0xcf, 0, 0, 0, 0, 0, 0, 0, 0x01}, `[255,256,1]`, false},
+		{"Signed integers", []byte{0x92, 0xd1, 0xff, 0x38, 0xd3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}, `[-200,-2]`, false},
+		{"Float32", []byte{0xca, 0x3f, 0xc0, 0, 0}, `1.5`, false},
+		{"Binary", []byte{0xc4, 0x03, 'a', 'b', 'c'}, `"YWJj"`, false},
+		{"Non string key", []byte{0x81, 0x01, 0x02}, ``, true},
+		{"Truncated string", []byte{0xa5, 'a', 'b'}, ``, true},
+		{"Truncated map", []byte{0x82, 0xa1, 'a', 0x01}, ``, true},
+		{"Extension type", []byte{0xd4, 0x01, 0x00}, ``, true},
+		{"Trailing data", []byte{0xc0, 0xc0}, ``, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSON(tt.document)
+
+			if tt.wantedErr {
+				if !errors.Is(err, ErrMalformed) {
+					t.Errorf("want error %v; got %v", ErrMalformed, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("want %s; got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// replayBatchSize is the number of replayed entries inserted at once into the outbox
+const replayBatchSize = 500
+
+// HistoryEntry is a struct that defines a published event kept in the history
+type HistoryEntry struct {
+	Entry       `bson:",inline"`
+	PublishedAt time.Time `bson:"published_at"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+// ReplayFilter is a struct that selects the events of the history which are replayed.
+// The empty fields do not filter the events.
+type ReplayFilter struct {
+	Keys  []string  // Ids of the aggregates of the events (i.e. the items)
+	Types []string  // Names (i.e. "item.updated") or schemas (i.e. "item.updated.v2") of the events
+	From  time.Time // Events which happened at or after the given time
+	To    time.Time // Events which happened before the given time
+}
+
+// query converts the filter into a MongoDB query on the history. The names of the events match all their versions.
+func (filter ReplayFilter) query() bson.M {
+	query := bson.M{}
+
+	if len(filter.Keys) != 0 {
+		query["key"] = bson.M{"$in": filter.Keys}
+	}
+
+	if len(filter.Types) != 0 {
+		types := make(bson.A, 0, len(filter.Types))
+
+		for _, name := range filter.Types {
+			if _, err := messaging.ParseSchema(name); err == nil {
+				types = append(types, bson.M{"type": name})
+			} else {
+				types = append(types, bson.M{"type": bson.M{"$regex": fmt.Sprintf(`^%s\.v[0-9]+$`, regexp.QuoteMeta(name))}})
+			}
+		}
+
+		query["$or"] = types
+	}
+
+	timestamp := bson.M{}
+
+	if !filter.From.IsZero() {
+		timestamp["$gte"] = filter.From
+	}
+
+	if !filter.To.IsZero() {
+		timestamp["$lt"] = filter.To
+	}
+
+	if len(timestamp) != 0 {
+		query["timestamp"] = timestamp
+	}
+
+	return query
+}
+
+// archive copies the entries with the given ids, except the replayed ones, into the history.
+// The entries which were already archived, i.e. published twice, are only kept once.
+func (store *Store) archive(ctx context.Context, ids []primitive.ObjectID, now time.Time) error {
+	cursor, err := store.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "replay": bson.M{"$ne": true}})
+	if err != nil {
+		return err
+	}
+
+	entries := []Entry{}
+
+	err = cursor.All(ctx, &entries)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	documents := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		entry.Claim = primitive.NilObjectID
+		documents = append(documents, HistoryEntry{Entry: entry, PublishedAt: now, ExpiresAt: now.Add(store.historyRetention)})
+	}
+
+	_, err = store.history.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	return nil
+}
+
+// CountHistory returns the number of events of the history selected by the given filter
+func (store *Store) CountHistory(ctx context.Context, filter ReplayFilter) (int64, error) {
+	return store.history.CountDocuments(ctx, filter.query())
+}
+
+// Replay stores the events of the history selected by the given filter in the outbox again, in the order in which
+// they happened, so that the relay publishes them with their original message id. It returns the number of replayed events.
+func (store *Store) Replay(ctx context.Context, filter ReplayFilter) (int, error) {
+	cursor, err := store.history.Find(
+		ctx,
+		filter.query(),
+		options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	defer cursor.Close(ctx)
+
+	replayed := 0
+	batch := make([]any, 0, replayBatchSize)
+
+	insert := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		_, err := store.collection.InsertMany(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		replayed += len(batch)
+		batch = batch[:0]
+
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var historyEntry HistoryEntry
+
+		err = cursor.Decode(&historyEntry)
+		if err != nil {
+			return replayed, err
+		}
+
+		entry := newEntry(historyEntry.Envelope(), time.Now().UTC())
+		entry.Replay = true
+
+		batch = append(batch, entry)
+
+		if len(batch) == replayBatchSize {
+			err = insert()
+			if err != nil {
+				return replayed, err
+			}
+		}
+	}
+
+	if cursor.Err() != nil {
+		return replayed, cursor.Err()
+	}
+
+	return replayed, insert()
+}
+
+// CreateOutboxHistoryCollection creates the outbox history collection along with the index removing the expired
+// events and the indexes of the replay filters
+func CreateOutboxHistoryCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// Create collection
+	err := db.CreateCollection(context.Background(), constants.OutboxHistoryCollection)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "timestamp", Value: 1}}},
+	}
+
+	_, err = db.Collection(constants.OutboxHistoryCollection).Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package outbox
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReplayFilterQuery(t *testing.T) {
+	from := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		testName string
+		filter   ReplayFilter
+		want     bson.M
+	}{
+		{"No filter", ReplayFilter{}, bson.M{}},
+		{"Items", ReplayFilter{Keys: []string{"63407e2c8bcd4a43ec1c4ff4"}}, bson.M{"key": bson.M{"$in": []string{"63407e2c8bcd4a43ec1c4ff4"}}}},
+		{
+			"Event names and schemas",
+			ReplayFilter{Types: []string{"item.updated", "item.created.v2"}},
+			bson.M{"$or": bson.A{bson.M{"type": bson.M{"$regex": `^item\.updated\.v[0-9]+$`}}, bson.M{"type": "item.created.v2"}}},
+		},
+		{"Time range", ReplayFilter{From: from, To: to}, bson.M{"timestamp": bson.M{"$gte": from, "$lt": to}}},
+		{"Open time range", ReplayFilter{From: from}, bson.M{"timestamp": bson.M{"$gte": from}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			got := tt.filter.query()
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %v; got %v", tt.want, got)
+			}
+		})
+	}
+}
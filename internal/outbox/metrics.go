@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics is a struct that holds some prometheus metrics regarding the relay of the outbox
+type Metrics struct {
+	PendingGauge            prometheus.Gauge
+	PublishedCounter        prometheus.Counter
+	FailuresCounter         prometheus.Counter
+	DeadLetteredCounter     prometheus.Counter
+	PublishLatencyHistogram prometheus.Histogram
+}
+
+// NewMetrics creates the gauges, counters and histograms used to keep track of the relay
+func NewMetrics(appName string) *Metrics {
+	pendingGauge := promauto.NewGauge(prometheus.GaugeOpts{
+		Name: fmt.Sprintf("%s_outbox_pending_messages", appName),
+		Help: "The number of messages of the outbox waiting to be published",
+	})
+
+	publishedCounter := promauto.NewCounter(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_outbox_published_messages_total", appName),
+		Help: "The total number of messages of the outbox published to the message broker",
+	})
+
+	failuresCounter := promauto.NewCounter(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_outbox_publish_failures_total", appName),
+		Help: "The total number of messages of the outbox which failed to be published",
+	})
+
+	deadLetteredCounter := promauto.NewCounter(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_outbox_dead_lettered_messages_total", appName),
+		Help: "The total number of messages of the outbox moved to the dead letters after exhausting their publish attempts",
+	})
+
+	publishLatencyHistogram := promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    fmt.Sprintf("%s_outbox_publish_latency_seconds", appName),
+		Help:    "Time elapsed between the storage of a message in the outbox and its confirmation by the message broker",
+		Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900},
+	})
+
+	return &Metrics{
+		PendingGauge:            pendingGauge,
+		PublishedCounter:        publishedCounter,
+		FailuresCounter:         failuresCounter,
+		DeadLetteredCounter:     deadLetteredCounter,
+		PublishLatencyHistogram: publishLatencyHistogram,
+	}
+}
@@ -0,0 +1,34 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+)
+
+// Outbox is an implementation of messaging.Publisher which stores the messages in the outbox
+// collection instead of sending them. The relay then publishes them with the wrapped publisher.
+type Outbox struct {
+	store     *Store
+	publisher messaging.Publisher
+}
+
+// NewOutbox returns a new Outbox storing the messages sent to the given publisher
+func NewOutbox(store *Store, publisher messaging.Publisher) *Outbox {
+	return &Outbox{store: store, publisher: publisher}
+}
+
+// System returns the name of the message broker the messages are relayed to
+func (outbox *Outbox) System() string {
+	return outbox.publisher.System()
+}
+
+// Destination returns the destination of the given route on the message broker the messages are relayed to
+func (outbox *Outbox) Destination(route messaging.Route) string {
+	return outbox.publisher.Destination(route)
+}
+
+// Send stores the given message in the outbox
+func (outbox *Outbox) Send(ctx context.Context, route messaging.Route, msg messaging.Message) error {
+	return outbox.store.Enqueue(ctx, messaging.Envelope{Route: route, Message: msg})
+}
@@ -0,0 +1,323 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrDrainInProgress is returned when the outbox is drained while another drain is in progress
+var ErrDrainInProgress = errors.New("outbox is already being drained")
+
+// entryStore is implemented by the stores of the outbox entries
+type entryStore interface {
+	Claim(ctx context.Context, limit int, now time.Time, lockedUntil time.Time) ([]Entry, error)
+	Delete(ctx context.Context, ids []primitive.ObjectID) error
+	Fail(ctx context.Context, id primitive.ObjectID, publishErr error, retryAt time.Time) error
+	DeadLetter(ctx context.Context, id primitive.ObjectID, publishErr error) error
+	CountDeadLetters(ctx context.Context) (int64, error)
+	RequeueDeadLetters(ctx context.Context) (int, error)
+	Stats(ctx context.Context) (int64, *time.Time, error)
+}
+
+// Status is a struct that defines the state of the relay
+type Status struct {
+	Paused   bool       `json:"paused"`
+	Draining bool       `json:"draining"`
+	Pending  int64      `json:"pending"`
+	Oldest   *time.Time `json:"oldest,omitempty"`
+	// Events which exhausted their publish attempts
+	DeadLettered int64 `json:"dead_lettered"`
+}
+
+// Relay is a struct that publishes the entries of the outbox to the message broker in batches.
+// Batches are confirmed by the broker when the publisher supports it. The relay can be paused
+// while the broker is under maintenance and drained to publish every pending entry at once.
+// The entries which failed to be published maxAttempts times are dead lettered.
+type Relay struct {
+	store         entryStore
+	publisher     messaging.Publisher
+	batchSize     int
+	lockDuration  time.Duration
+	retryInterval time.Duration
+	maxAttempts   int
+	metrics       *Metrics
+	logger        *logger.Logger
+	tracer        trace.Tracer
+
+	mu       sync.Mutex
+	paused   bool
+	draining bool
+
+	// Batches are relayed one at a time
+	relayMu sync.Mutex
+}
+
+// NewRelay returns a new Relay publishing the entries of the given store with the given publisher
+func NewRelay(
+	store entryStore,
+	publisher messaging.Publisher,
+	cfg settings.Outbox,
+	serviceName string,
+	logger *logger.Logger,
+	metrics *Metrics,
+) *Relay {
+	return &Relay{
+		store:         store,
+		publisher:     publisher,
+		batchSize:     cfg.BatchSize,
+		lockDuration:  time.Duration(cfg.LockDuration) * time.Second,
+		retryInterval: time.Duration(cfg.RetryInterval) * time.Second,
+		maxAttempts:   cfg.MaxAttempts,
+		metrics:       metrics,
+		logger:        logger,
+		tracer:        otel.Tracer(serviceName),
+	}
+}
+
+// Run relays the pending entries at the given interval. Ticks are skipped while the relay is paused or drained.
+func (relay *Relay) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		relay.mu.Lock()
+		skip := relay.paused || relay.draining
+		relay.mu.Unlock()
+
+		if !skip {
+			published, err := relay.relayPending(context.Background())
+			if err != nil {
+				relay.logger.Error(err, map[string]string{"operation": "relay_outbox", "published": fmt.Sprint(published)})
+			}
+		}
+
+		relay.refreshPending(context.Background())
+	}
+}
+
+// Pause stops the relay of the entries until Resume is called. Entries keep being stored in the outbox.
+func (relay *Relay) Pause() {
+	relay.mu.Lock()
+	defer relay.mu.Unlock()
+
+	relay.paused = true
+}
+
+// Resume resumes the relay of the entries
+func (relay *Relay) Resume() {
+	relay.mu.Lock()
+	defer relay.mu.Unlock()
+
+	relay.paused = false
+}
+
+// Drain publishes every pending entry, even when the relay is paused, and returns the number
+// of published entries. It stops at the first batch which could not be entirely published.
+func (relay *Relay) Drain(ctx context.Context) (int, error) {
+	relay.mu.Lock()
+	if relay.draining {
+		relay.mu.Unlock()
+		return 0, ErrDrainInProgress
+	}
+
+	relay.draining = true
+	relay.mu.Unlock()
+
+	defer func() {
+		relay.mu.Lock()
+		relay.draining = false
+		relay.mu.Unlock()
+
+		relay.refreshPending(context.Background())
+	}()
+
+	return relay.relayPending(ctx)
+}
+
+// Status returns the state of the relay along with the pending entries of the outbox
+func (relay *Relay) Status(ctx context.Context) (Status, error) {
+	relay.mu.Lock()
+	status := Status{Paused: relay.paused, Draining: relay.draining}
+	relay.mu.Unlock()
+
+	pending, oldest, err := relay.store.Stats(ctx)
+	if err != nil {
+		return status, err
+	}
+
+	status.Pending = pending
+	status.Oldest = oldest
+
+	status.DeadLettered, err = relay.store.CountDeadLetters(ctx)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+// Requeue stores the dead lettered entries in the outbox again so that they are published with new attempts,
+// i.e. once the queues their routes were missing are declared, and returns the number of requeued entries
+func (relay *Relay) Requeue(ctx context.Context) (int, error) {
+	requeued, err := relay.store.RequeueDeadLetters(ctx)
+
+	relay.refreshPending(context.Background())
+
+	return requeued, err
+}
+
+// refreshPending updates the number of pending entries of the metrics
+func (relay *Relay) refreshPending(ctx context.Context) {
+	pending, _, err := relay.store.Stats(ctx)
+	if err != nil {
+		relay.logger.Error(err, map[string]string{"operation": "count_outbox"})
+		return
+	}
+
+	relay.metrics.PendingGauge.Set(float64(pending))
+}
+
+// relayPending relays batches of entries until the outbox is empty or a batch fails
+func (relay *Relay) relayPending(ctx context.Context) (int, error) {
+	relay.relayMu.Lock()
+	defer relay.relayMu.Unlock()
+
+	published := 0
+
+	for {
+		count, failed, err := relay.relayBatch(ctx)
+		published += count
+
+		if err != nil || count == 0 || failed {
+			return published, err
+		}
+	}
+}
+
+// relayBatch claims a batch of entries and publishes them. Published entries are removed from the outbox
+// while the other ones are retried later. It returns the number of published entries and whether some
+// entries failed to be published.
+func (relay *Relay) relayBatch(ctx context.Context) (int, bool, error) {
+	now := time.Now().UTC()
+
+	entries, err := relay.store.Claim(ctx, relay.batchSize, now, now.Add(relay.lockDuration))
+	if err != nil || len(entries) == 0 {
+		return 0, false, err
+	}
+
+	ctx, span := relay.tracer.Start(
+		ctx,
+		"outbox relay",
+		trace.WithAttributes(
+			attribute.Int("outbox.batch_size", len(entries)),
+			attribute.String("messaging.system", relay.publisher.System()),
+		),
+	)
+	defer span.End()
+
+	errs := relay.send(ctx, entries)
+
+	confirmed := []primitive.ObjectID{}
+	failures, deadLettered := 0, 0
+
+	for i, entry := range entries {
+		if errs[i] == nil {
+			confirmed = append(confirmed, entry.ID)
+			relay.metrics.PublishLatencyHistogram.Observe(time.Since(entry.CreatedAt).Seconds())
+			continue
+		}
+
+		failures++
+
+		if relay.exhausted(entry) {
+			err = relay.store.DeadLetter(ctx, entry.ID, errs[i])
+			if err != nil {
+				relay.logger.Error(err, map[string]string{"operation": "dead_letter_outbox_entry", "message_id": entry.MessageID})
+				continue
+			}
+
+			deadLettered++
+			relay.logger.Error(errs[i], map[string]string{
+				"operation":  "dead_letter_outbox_entry",
+				"message_id": entry.MessageID,
+				"attempts":   fmt.Sprint(entry.Attempts + 1),
+			})
+
+			continue
+		}
+
+		err = relay.store.Fail(ctx, entry.ID, errs[i], time.Now().UTC().Add(relay.retryInterval))
+		if err != nil {
+			relay.logger.Error(err, map[string]string{"operation": "release_outbox_entry", "message_id": entry.MessageID})
+		}
+	}
+
+	relay.metrics.PublishedCounter.Add(float64(len(confirmed)))
+	relay.metrics.FailuresCounter.Add(float64(failures))
+	relay.metrics.DeadLetteredCounter.Add(float64(deadLettered))
+
+	span.SetAttributes(attribute.Int("outbox.published", len(confirmed)), attribute.Int("outbox.failures", failures))
+
+	if failures != 0 {
+		firstErr := firstError(errs)
+
+		span.RecordError(firstErr)
+		span.SetStatus(codes.Error, firstErr.Error())
+		relay.logger.Error(firstErr, map[string]string{"operation": "publish_outbox", "failures": fmt.Sprint(failures)})
+	}
+
+	// Entries which could not be deleted are published again once their lock expires
+	err = relay.store.Delete(ctx, confirmed)
+	if err != nil {
+		return 0, failures != 0, err
+	}
+
+	return len(confirmed), failures != 0, nil
+}
+
+// exhausted checks if the given entry, which just failed to be published, failed its last allowed attempt
+func (relay *Relay) exhausted(entry Entry) bool {
+	return relay.maxAttempts > 0 && entry.Attempts+1 >= relay.maxAttempts
+}
+
+// send publishes the given entries in a single batch when the publisher supports it and one by one otherwise
+func (relay *Relay) send(ctx context.Context, entries []Entry) []error {
+	batch := make([]messaging.Envelope, 0, len(entries))
+	for _, entry := range entries {
+		batch = append(batch, entry.Envelope())
+	}
+
+	if publisher, ok := relay.publisher.(messaging.BatchPublisher); ok {
+		return publisher.SendBatch(ctx, batch)
+	}
+
+	errs := make([]error, len(batch))
+	for i, envelope := range batch {
+		errs[i] = relay.publisher.Send(ctx, envelope.Route, envelope.Message)
+	}
+
+	return errs
+}
+
+// firstError returns the first error which is not nil
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
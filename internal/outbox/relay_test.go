@@ -0,0 +1,353 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testMetrics are shared by the relays of the tests since prometheus metrics can only be registered once
+var testMetrics = NewMetrics("outbox_test")
+
+// fakeStore keeps the entries of the outbox in memory
+type fakeStore struct {
+	mu          sync.Mutex
+	entries     map[primitive.ObjectID]*Entry
+	deadLetters []Entry
+}
+
+// newFakeStore returns a fakeStore holding the given number of entries
+func newFakeStore(count int) *fakeStore {
+	store := &fakeStore{entries: make(map[primitive.ObjectID]*Entry)}
+
+	for i := 0; i < count; i++ {
+		entry := newEntry(messaging.Envelope{
+			Route:   messaging.ItemExpiredRoute,
+			Message: messaging.Message{ID: primitive.NewObjectID().Hex(), Type: "item.expired.v1"},
+		}, time.Now().UTC().Add(-time.Minute))
+
+		store.entries[entry.ID] = &entry
+	}
+
+	return store
+}
+
+// Claim locks up to limit pending entries, oldest first
+func (store *fakeStore) Claim(ctx context.Context, limit int, now time.Time, lockedUntil time.Time) ([]Entry, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entries := []Entry{}
+	for _, entry := range store.entries {
+		if !entry.LockedUntil.After(now) {
+			entries = append(entries, *entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID.Hex() < entries[j].ID.Hex() })
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	for _, entry := range entries {
+		store.entries[entry.ID].LockedUntil = lockedUntil
+	}
+
+	return entries, nil
+}
+
+// Delete removes the entries with the given ids
+func (store *fakeStore) Delete(ctx context.Context, ids []primitive.ObjectID) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, id := range ids {
+		delete(store.entries, id)
+	}
+
+	return nil
+}
+
+// Fail releases the entry with the given id at the given time
+func (store *fakeStore) Fail(ctx context.Context, id primitive.ObjectID, publishErr error, retryAt time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.entries[id].Attempts++
+	store.entries[id].LastError = publishErr.Error()
+	store.entries[id].LockedUntil = retryAt
+
+	return nil
+}
+
+// DeadLetter moves the entry with the given id to the dead letters
+func (store *fakeStore) DeadLetter(ctx context.Context, id primitive.ObjectID, publishErr error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry := *store.entries[id]
+	entry.Attempts++
+	entry.LastError = publishErr.Error()
+
+	store.deadLetters = append(store.deadLetters, entry)
+	delete(store.entries, id)
+
+	return nil
+}
+
+// CountDeadLetters returns the number of dead lettered entries
+func (store *fakeStore) CountDeadLetters(ctx context.Context) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return int64(len(store.deadLetters)), nil
+}
+
+// RequeueDeadLetters moves the dead lettered entries back to the outbox without their attempts
+func (store *fakeStore) RequeueDeadLetters(ctx context.Context) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	requeued := len(store.deadLetters)
+	for _, deadLetter := range store.deadLetters {
+		entry := newEntry(deadLetter.Envelope(), time.Now().UTC())
+		store.entries[entry.ID] = &entry
+	}
+
+	store.deadLetters = nil
+
+	return requeued, nil
+}
+
+// Stats returns the number of entries
+func (store *fakeStore) Stats(ctx context.Context) (int64, *time.Time, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return int64(len(store.entries)), nil, nil
+}
+
+// fakePublisher records the published messages and fails the messages whose ID is listed
+type fakePublisher struct {
+	failures map[string]bool
+	sent     []messaging.Message
+	batches  int
+}
+
+func (publisher *fakePublisher) System() string { return "fake" }
+
+func (publisher *fakePublisher) Destination(route messaging.Route) string { return route.Exchange }
+
+func (publisher *fakePublisher) Send(ctx context.Context, route messaging.Route, msg messaging.Message) error {
+	if publisher.failures[msg.ID] {
+		return errors.New("not confirmed")
+	}
+
+	publisher.sent = append(publisher.sent, msg)
+
+	return nil
+}
+
+// fakeBatchPublisher records the batches sent to the fake publisher
+type fakeBatchPublisher struct {
+	*fakePublisher
+}
+
+func (publisher fakeBatchPublisher) SendBatch(ctx context.Context, batch []messaging.Envelope) []error {
+	publisher.batches++
+
+	errs := make([]error, len(batch))
+	for i, envelope := range batch {
+		errs[i] = publisher.Send(ctx, envelope.Route, envelope.Message)
+	}
+
+	return errs
+}
+
+// newTestRelay returns a relay publishing the entries of the given store in batches of 2 entries
+// and dead lettering them after 3 attempts
+func newTestRelay(store entryStore, publisher messaging.Publisher) *Relay {
+	cfg := settings.Outbox{Interval: 1, BatchSize: 2, LockDuration: 30, RetryInterval: 10, MaxAttempts: 3}
+
+	return NewRelay(store, publisher, cfg, "Catalog", logger.New(io.Discard, logger.LevelInfo), testMetrics)
+}
+
+func TestRelayDrain(t *testing.T) {
+	tests := []struct {
+		testName        string
+		batch           bool
+		entries         int
+		failures        int
+		wantedPublished int
+		wantedBatches   int
+		wantedRemainder int
+	}{
+		{"Sequential publisher", false, 5, 0, 5, 0, 0},
+		{"Batch publisher", true, 5, 0, 5, 3, 0},
+		{"Empty outbox", true, 0, 0, 0, 0, 0},
+		{"Unconfirmed message", true, 5, 1, 1, 1, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			store := newFakeStore(tt.entries)
+			publisher := &fakePublisher{failures: make(map[string]bool)}
+
+			// Fail the second oldest entry, the relay stops after the first batch
+			if tt.failures != 0 {
+				ids := make([]string, 0, len(store.entries))
+				for id := range store.entries {
+					ids = append(ids, id.Hex())
+				}
+
+				sort.Strings(ids)
+
+				id, _ := primitive.ObjectIDFromHex(ids[1])
+				publisher.failures[store.entries[id].MessageID] = true
+			}
+
+			var relay *Relay
+			if tt.batch {
+				relay = newTestRelay(store, fakeBatchPublisher{publisher})
+			} else {
+				relay = newTestRelay(store, publisher)
+			}
+
+			published, err := relay.Drain(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if published != tt.wantedPublished {
+				t.Errorf("want %d published messages; got %d", tt.wantedPublished, published)
+			}
+
+			if publisher.batches != tt.wantedBatches {
+				t.Errorf("want %d batches; got %d", tt.wantedBatches, publisher.batches)
+			}
+
+			if len(store.entries) != tt.wantedRemainder {
+				t.Errorf("want %d pending entries; got %d", tt.wantedRemainder, len(store.entries))
+			}
+
+			failed := 0
+			for _, entry := range store.entries {
+				if entry.Attempts != 0 {
+					failed++
+				}
+			}
+
+			if failed != tt.failures {
+				t.Errorf("want %d failed entries; got %d", tt.failures, failed)
+			}
+		})
+	}
+}
+
+func TestRelayPause(t *testing.T) {
+	store := newFakeStore(3)
+	relay := newTestRelay(store, &fakePublisher{})
+
+	relay.Pause()
+
+	status, err := relay.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !status.Paused || status.Pending != 3 {
+		t.Errorf("want paused relay with %d pending entries; got %+v", 3, status)
+	}
+
+	// Paused relays can still be drained
+	published, err := relay.Drain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if published != 3 {
+		t.Errorf("want %d published messages; got %d", 3, published)
+	}
+
+	relay.Resume()
+
+	status, err = relay.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Paused || status.Pending != 0 {
+		t.Errorf("want resumed relay without pending entries; got %+v", status)
+	}
+}
+
+func TestRelayDeadLetter(t *testing.T) {
+	store := newFakeStore(1)
+	publisher := &fakePublisher{failures: make(map[string]bool)}
+	relay := newTestRelay(store, fakeBatchPublisher{publisher})
+
+	var entry *Entry
+	for _, pending := range store.entries {
+		entry = pending
+	}
+
+	// The message is not routed until its queue is declared
+	publisher.failures[entry.MessageID] = true
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		_, err := relay.Drain(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Failed entries are released at once so that the next drain retries them
+		store.mu.Lock()
+		for _, pending := range store.entries {
+			pending.LockedUntil = time.Now().UTC()
+		}
+		store.mu.Unlock()
+	}
+
+	status, err := relay.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Pending != 0 || status.DeadLettered != 1 {
+		t.Fatalf("want %d pending and %d dead lettered entries; got %+v", 0, 1, status)
+	}
+
+	if store.deadLetters[0].Attempts != 3 || store.deadLetters[0].LastError != "not confirmed" {
+		t.Errorf("want %d attempts failing with %q; got %d failing with %q", 3, "not confirmed", store.deadLetters[0].Attempts, store.deadLetters[0].LastError)
+	}
+
+	delete(publisher.failures, entry.MessageID)
+
+	requeued, err := relay.Requeue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requeued != 1 {
+		t.Errorf("want %d requeued entries; got %d", 1, requeued)
+	}
+
+	published, err := relay.Drain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if published != 1 || publisher.sent[0].ID != entry.MessageID {
+		t.Errorf("want message %q to be published; got %d published messages", entry.MessageID, published)
+	}
+}
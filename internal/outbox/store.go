@@ -0,0 +1,342 @@
+// Package outbox stores the published events in MongoDB and relays them to the message broker,
+// so that the events are not lost while the broker is unavailable.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Entry is a struct that defines an event waiting to be relayed to the message broker
+type Entry struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Exchange    string             `bson:"exchange"`
+	Aggregate   string             `bson:"aggregate"`
+	MessageID   string             `bson:"message_id"`
+	Key         string             `bson:"key"`
+	Type        string             `bson:"type"`
+	ContentType string             `bson:"content_type"`
+	Timestamp   time.Time          `bson:"timestamp"`
+	Headers     map[string]string  `bson:"headers"`
+	Body        []byte             `bson:"body"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	Attempts    int                `bson:"attempts"`
+	LastError   string             `bson:"last_error,omitempty"`
+	LockedUntil time.Time          `bson:"locked_until"`
+	Claim       primitive.ObjectID `bson:"claim,omitempty"`
+	Replay      bool               `bson:"replay,omitempty"` // Replayed from the history, which does not keep it again
+}
+
+// newEntry converts a message into an outbox entry
+func newEntry(envelope messaging.Envelope, now time.Time) Entry {
+	return Entry{
+		ID:          primitive.NewObjectID(),
+		Exchange:    envelope.Route.Exchange,
+		Aggregate:   envelope.Route.Aggregate,
+		MessageID:   envelope.Message.ID,
+		Key:         envelope.Message.Key,
+		Type:        envelope.Message.Type,
+		ContentType: envelope.Message.ContentType,
+		Timestamp:   envelope.Message.Timestamp,
+		Headers:     envelope.Message.Headers,
+		Body:        envelope.Message.Body,
+		CreatedAt:   now,
+		LockedUntil: now,
+	}
+}
+
+// Envelope converts the entry back into the message it holds
+func (entry Entry) Envelope() messaging.Envelope {
+	return messaging.Envelope{
+		Route: messaging.Route{Exchange: entry.Exchange, Aggregate: entry.Aggregate},
+		Message: messaging.Message{
+			ID:          entry.MessageID,
+			Key:         entry.Key,
+			Type:        entry.Type,
+			ContentType: entry.ContentType,
+			Timestamp:   entry.Timestamp,
+			Headers:     entry.Headers,
+			Body:        entry.Body,
+		},
+	}
+}
+
+// DeadLetterEntry is a struct that defines an event which exhausted its publish attempts
+type DeadLetterEntry struct {
+	Entry          `bson:",inline"`
+	DeadLetteredAt time.Time `bson:"dead_lettered_at"`
+}
+
+// Store is a struct that manages the entries of the outbox collection. The published entries are kept in the
+// history for the given retention so that they can be replayed, and the entries which exhausted their publish
+// attempts are moved to the dead letters until they are requeued.
+type Store struct {
+	collection       *mongo.Collection
+	history          *mongo.Collection
+	deadLetters      *mongo.Collection
+	historyRetention time.Duration
+}
+
+// NewStore creates a new Store keeping the published entries for the given retention, or deleting them when it is 0
+func NewStore(client *mongo.Client, databaseName string, historyRetention time.Duration) *Store {
+	db := client.Database(databaseName)
+
+	return &Store{
+		collection:       db.Collection(constants.OutboxCollection),
+		history:          db.Collection(constants.OutboxHistoryCollection),
+		deadLetters:      db.Collection(constants.OutboxDeadLettersCollection),
+		historyRetention: historyRetention,
+	}
+}
+
+// Enqueue stores the given message so that it is relayed to the message broker
+func (store *Store) Enqueue(ctx context.Context, envelope messaging.Envelope) error {
+	_, err := store.collection.InsertOne(ctx, newEntry(envelope, time.Now().UTC()))
+
+	return err
+}
+
+// Claim locks up to limit pending entries, oldest first, until the given time and returns them.
+// Entries locked by another relay are skipped until their lock expires.
+func (store *Store) Claim(ctx context.Context, limit int, now time.Time, lockedUntil time.Time) ([]Entry, error) {
+	pending := bson.M{"locked_until": bson.M{"$lte": now}}
+
+	cursor, err := store.collection.Find(
+		ctx,
+		pending,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)).SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+
+	err = cursor.All(ctx, &candidates)
+	if err != nil || len(candidates) == 0 {
+		return []Entry{}, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(candidates))
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.ID)
+	}
+
+	// Only the entries which were not claimed in the meantime get the claim of this relay
+	claim := primitive.NewObjectID()
+
+	_, err = store.collection.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "locked_until": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"locked_until": lockedUntil, "claim": claim}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err = store.collection.Find(ctx, bson.M{"claim": claim}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+
+	err = cursor.All(ctx, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Delete removes the entries with the given ids once they were published, after moving them to the history
+func (store *Store) Delete(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if store.historyRetention > 0 {
+		err := store.archive(ctx, ids, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := store.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+
+	return err
+}
+
+// Fail records that the entry with the given id could not be published and releases it
+// so that it is retried at the given time
+func (store *Store) Fail(ctx context.Context, id primitive.ObjectID, publishErr error, retryAt time.Time) error {
+	_, err := store.collection.UpdateByID(ctx, id, bson.M{
+		"$inc":   bson.M{"attempts": 1},
+		"$set":   bson.M{"last_error": publishErr.Error(), "locked_until": retryAt},
+		"$unset": bson.M{"claim": ""},
+	})
+
+	return err
+}
+
+// DeadLetter moves the entry with the given id, which exhausted its publish attempts, to the dead letters along
+// with the error of its last attempt. An entry which was already moved, i.e. whose removal from the outbox failed,
+// is only kept once.
+func (store *Store) DeadLetter(ctx context.Context, id primitive.ObjectID, publishErr error) error {
+	var entry Entry
+
+	err := store.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&entry)
+	if err != nil {
+		return err
+	}
+
+	entry.Attempts++
+	entry.LastError = publishErr.Error()
+	entry.Claim = primitive.NilObjectID
+
+	_, err = store.deadLetters.InsertOne(ctx, DeadLetterEntry{Entry: entry, DeadLetteredAt: time.Now().UTC()})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	_, err = store.collection.DeleteOne(ctx, bson.M{"_id": id})
+
+	return err
+}
+
+// CountDeadLetters returns the number of entries which exhausted their publish attempts
+func (store *Store) CountDeadLetters(ctx context.Context) (int64, error) {
+	return store.deadLetters.CountDocuments(ctx, bson.M{})
+}
+
+// RequeueDeadLetters stores the dead lettered entries in the outbox again, oldest first and without their previous
+// attempts, so that the relay publishes them with their original message id. It returns the number of requeued entries.
+func (store *Store) RequeueDeadLetters(ctx context.Context) (int, error) {
+	cursor, err := store.deadLetters.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+
+	defer cursor.Close(ctx)
+
+	requeued := 0
+	batch := make([]any, 0, replayBatchSize)
+	ids := make([]primitive.ObjectID, 0, replayBatchSize)
+
+	// The entries are only removed from the dead letters once they are back in the outbox
+	requeue := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		_, err := store.collection.InsertMany(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		_, err = store.deadLetters.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return err
+		}
+
+		requeued += len(batch)
+		batch = batch[:0]
+		ids = ids[:0]
+
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var deadLetter DeadLetterEntry
+
+		err = cursor.Decode(&deadLetter)
+		if err != nil {
+			return requeued, err
+		}
+
+		entry := newEntry(deadLetter.Envelope(), time.Now().UTC())
+		entry.Replay = deadLetter.Replay
+
+		batch = append(batch, entry)
+		ids = append(ids, deadLetter.ID)
+
+		if len(batch) == replayBatchSize {
+			err = requeue()
+			if err != nil {
+				return requeued, err
+			}
+		}
+	}
+
+	if cursor.Err() != nil {
+		return requeued, cursor.Err()
+	}
+
+	return requeued, requeue()
+}
+
+// Stats returns the number of entries of the outbox along with the creation time of the
+// oldest one, which is nil when the outbox is empty
+func (store *Store) Stats(ctx context.Context) (int64, *time.Time, error) {
+	count, err := store.collection.CountDocuments(ctx, bson.M{})
+	if err != nil || count == 0 {
+		return count, nil, err
+	}
+
+	var oldest Entry
+
+	err = store.collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "_id", Value: 1}})).Decode(&oldest)
+	if err != nil {
+		return count, nil, err
+	}
+
+	return count, &oldest.CreatedAt, nil
+}
+
+// Compact releases the disk space left by the published entries of the outbox and by the expired entries of the
+// history. Both collections have a high turnover, whose free space is otherwise only reused by the new entries.
+// The compaction applies to the MongoDB node the service is connected to.
+func (store *Store) Compact(ctx context.Context) error {
+	for _, collection := range []*mongo.Collection{store.collection, store.history} {
+		err := collection.Database().RunCommand(ctx, bson.D{{Key: "compact", Value: collection.Name()}}).Err()
+		if err != nil {
+			return fmt.Errorf("failed to compact %s: %w", collection.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// CreateOutboxCollection creates the outbox collection along with the index of the pending entries
+func CreateOutboxCollection(client *mongo.Client, databaseName string) error {
+	db := client.Database(databaseName)
+
+	// Create collection
+	err := db.CreateCollection(context.Background(), constants.OutboxCollection)
+	if err != nil {
+		// Returns error if collection already exists so we ignore it
+		return nil
+	}
+
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "locked_until", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "claim", Value: 1}}},
+	}
+
+	_, err = db.Collection(constants.OutboxCollection).Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package popularity
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store is implemented by the stores of the popularity counts of the items. The counts of a batch
+// which were already added are skipped so that a batch can be retried.
+type Store interface {
+	Increment(ctx context.Context, batch string, day time.Time, counts map[primitive.ObjectID]data.PopularityCounts) error
+}
+
+// batch is a struct that holds the counts of a day written at once to the store
+type batch struct {
+	id     string
+	day    time.Time
+	counts map[primitive.ObjectID]data.PopularityCounts
+}
+
+// Counter is a struct that counts the views of the items in memory so that recording them does
+// not slow down the requests. The counts are periodically flushed to the store in batches.
+// The purchases are written to the store right away since their events are acknowledged afterwards.
+type Counter struct {
+	store  Store
+	logger *logger.Logger
+
+	mu      sync.Mutex
+	pending map[time.Time]map[primitive.ObjectID]data.PopularityCounts // Counts per day
+	failed  []batch                                                    // Batches to retry as they are
+}
+
+// NewCounter returns a new Counter flushing its counts to the given store
+func NewCounter(store Store, logger *logger.Logger) *Counter {
+	return &Counter{
+		store:   store,
+		logger:  logger,
+		pending: make(map[time.Time]map[primitive.ObjectID]data.PopularityCounts),
+	}
+}
+
+// RecordView counts a view of the given item
+func (counter *Counter) RecordView(id primitive.ObjectID) {
+	counter.add(data.Day(time.Now()), id, data.PopularityCounts{Views: 1})
+}
+
+// RecordPurchase writes the purchase of the given quantity of the given item to the store. The purchase is
+// identified by the given key (i.e. the id of its message) so that it is only counted once when it is redelivered.
+func (counter *Counter) RecordPurchase(ctx context.Context, key string, id primitive.ObjectID, quantity int64) error {
+	counts := map[primitive.ObjectID]data.PopularityCounts{id: {Purchases: quantity}}
+
+	return counter.store.Increment(ctx, key, data.Day(time.Now()), counts)
+}
+
+// add adds the given counts to the pending counts of an item for the given day
+func (counter *Counter) add(day time.Time, id primitive.ObjectID, counts data.PopularityCounts) {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	if counter.pending[day] == nil {
+		counter.pending[day] = make(map[primitive.ObjectID]data.PopularityCounts)
+	}
+
+	counter.pending[day][id] = counter.pending[day][id].Add(counts)
+}
+
+// Flush writes the pending counts to the store, one batch per day. Batches which could not be written are
+// retried as they are by the next flush, so that the counts of a batch which was partially written before
+// failing are not counted twice.
+func (counter *Counter) Flush(ctx context.Context) error {
+	counter.mu.Lock()
+	batches := counter.failed
+	for day, counts := range counter.pending {
+		batches = append(batches, batch{id: primitive.NewObjectID().Hex(), day: day, counts: counts})
+	}
+
+	counter.failed = nil
+	counter.pending = make(map[time.Time]map[primitive.ObjectID]data.PopularityCounts)
+	counter.mu.Unlock()
+
+	var flushErr error
+
+	for _, b := range batches {
+		err := counter.store.Increment(ctx, b.id, b.day, b.counts)
+		if err != nil {
+			flushErr = err
+
+			counter.mu.Lock()
+			counter.failed = append(counter.failed, b)
+			counter.mu.Unlock()
+		}
+	}
+
+	return flushErr
+}
+
+// Run flushes the counts at the given interval until the given context is done
+func (counter *Counter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := counter.Flush(ctx)
+		if err != nil {
+			counter.logger.Error(err, map[string]string{"operation": "flush_popularity", "pending_batches": fmt.Sprint(counter.pendingBatches())})
+		}
+	}
+}
+
+// pendingBatches returns the number of batches which are waiting to be written
+func (counter *Counter) pendingBatches() int {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	return len(counter.pending) + len(counter.failed)
+}
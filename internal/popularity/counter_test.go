@@ -0,0 +1,112 @@
+package popularity
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeStore records the incremented counts once per batch and fails while err is set.
+// When partial is set, the counts are recorded before failing.
+type fakeStore struct {
+	counts  map[primitive.ObjectID]data.PopularityCounts
+	batches map[string]bool
+	calls   int
+	err     error
+	partial bool
+}
+
+// Increment records the given counts unless their batch was already recorded
+func (store *fakeStore) Increment(ctx context.Context, batch string, day time.Time, counts map[primitive.ObjectID]data.PopularityCounts) error {
+	store.calls++
+
+	if store.err != nil && !store.partial {
+		return store.err
+	}
+
+	if !store.batches[batch] {
+		store.batches[batch] = true
+
+		for id, count := range counts {
+			store.counts[id] = store.counts[id].Add(count)
+		}
+	}
+
+	return store.err
+}
+
+func TestCounterFlush(t *testing.T) {
+	store := &fakeStore{counts: make(map[primitive.ObjectID]data.PopularityCounts), batches: make(map[string]bool)}
+	counter := NewCounter(store, logger.New(io.Discard, logger.LevelInfo))
+
+	potion := primitive.NewObjectID()
+	ether := primitive.NewObjectID()
+
+	counter.RecordView(potion)
+	counter.RecordView(potion)
+	counter.RecordView(ether)
+
+	// Counts are kept while the store is unavailable
+	store.err = errors.New("store unavailable")
+
+	if err := counter.Flush(context.Background()); err == nil {
+		t.Fatal("want error; got nil")
+	}
+
+	// Batches written partially before failing are retried without counting them twice
+	store.partial = true
+
+	if err := counter.Flush(context.Background()); err == nil {
+		t.Fatal("want error; got nil")
+	}
+
+	store.err = nil
+	store.partial = false
+
+	// Purchases are written right away and counted once per key
+	for i := 0; i < 2; i++ {
+		if err := counter.RecordPurchase(context.Background(), "purchase:1", potion, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[primitive.ObjectID]data.PopularityCounts{
+		potion: {Views: 2, Purchases: 2},
+		ether:  {Views: 1},
+	}
+
+	for id, want := range expected {
+		if got := store.counts[id]; got != want {
+			t.Errorf("want %+v; got %+v", want, got)
+		}
+	}
+
+	// Nothing is left to flush
+	calls := store.calls
+
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.calls != calls {
+		t.Errorf("want %d calls; got %d", calls, store.calls)
+	}
+}
+
+func TestPopularityCountsScore(t *testing.T) {
+	counts := data.PopularityCounts{Views: 3, Purchases: 2}
+
+	if counts.Score() != 23 {
+		t.Errorf("want %d; got %d", 23, counts.Score())
+	}
+}
@@ -0,0 +1,191 @@
+package protobuf
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ContentType is the media type of the protobuf messages
+const ContentType = "application/x-protobuf"
+
+// ErrUnsupported is returned when a JSON response has no protobuf message (i.e. errors or stats)
+var ErrUnsupported = errors.New("response has no protobuf message")
+
+// FromJSON converts the JSON envelope of an item ({"item": ...}) into an Item message and the envelope
+// of a list of items ({"items": [...], "metadata": ...}) into an ItemList message, as defined in
+// proto/catalog/v1/items.proto. The other envelopes return ErrUnsupported.
+func FromJSON(body []byte) ([]byte, error) {
+	var envelope struct {
+		Item     *data.Item        `json:"item"`
+		Items    *[]data.Item      `json:"items"`
+		Metadata *filters.Metadata `json:"metadata"`
+	}
+
+	err := json.Unmarshal(body, &envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case envelope.Item != nil:
+		return appendItem(nil, *envelope.Item), nil
+	case envelope.Items != nil:
+		return appendItemList(nil, *envelope.Items, envelope.Metadata), nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// appendItemList appends an ItemList message
+func appendItemList(b []byte, items []data.Item, metadata *filters.Metadata) []byte {
+	for _, item := range items {
+		b = appendMessage(b, 1, appendItem(nil, item))
+	}
+
+	if metadata != nil {
+		var m []byte
+		m = appendInt(m, 1, int64(metadata.CurrentPage))
+		m = appendInt(m, 2, int64(metadata.PageSize))
+		m = appendInt(m, 3, int64(metadata.FirstPage))
+		m = appendInt(m, 4, int64(metadata.LastPage))
+		m = appendInt(m, 5, int64(metadata.TotalRecords))
+
+		b = appendMessage(b, 2, m)
+	}
+
+	return b
+}
+
+// appendItem appends an Item message
+func appendItem(b []byte, item data.Item) []byte {
+	if !item.ID.IsZero() {
+		b = appendString(b, 1, item.ID.Hex())
+	}
+
+	b = appendString(b, 2, item.ExternalID)
+	b = appendString(b, 3, item.Name)
+	b = appendString(b, 4, item.Description)
+	b = appendString(b, 5, item.DescriptionHTML)
+	b = appendDouble(b, 6, item.Price)
+
+	for _, tag := range item.Tags {
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		b = protowire.AppendString(b, tag)
+	}
+
+	for _, autoTag := range item.AutoTags {
+		var m []byte
+		m = appendString(m, 1, autoTag.Tag)
+		m = appendString(m, 2, autoTag.Rule)
+
+		b = appendMessage(b, 8, m)
+	}
+
+	for _, image := range item.Images {
+		var m []byte
+		m = appendString(m, 1, image.Key)
+		m = appendString(m, 2, image.URL)
+		m = appendString(m, 3, image.ContentType)
+		m = appendInt(m, 4, image.Size)
+		m = appendTimestamp(m, 5, &image.UploadedAt)
+
+		b = appendMessage(b, 9, m)
+	}
+
+	b = appendBool(b, 10, item.FlaggedForReview)
+	b = appendBool(b, 11, item.Featured)
+	b = appendInt(b, 12, int64(item.FeaturedPriority))
+
+	if item.Rating != nil {
+		var m []byte
+		m = appendDouble(m, 1, item.Rating.Average)
+		m = appendInt(m, 2, int64(item.Rating.Count))
+
+		b = appendMessage(b, 13, m)
+	}
+
+	// The favorited field has an explicit presence so that false is told apart from unknown
+	if item.Favorited != nil {
+		b = protowire.AppendTag(b, 14, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(*item.Favorited))
+	}
+
+	b = appendInt(b, 15, int64(item.Version))
+	b = appendTimestamp(b, 16, item.ExpiresAt)
+	b = appendInt(b, 17, item.CreatedBy)
+	b = appendInt(b, 18, item.UpdatedBy)
+
+	return b
+}
+
+// appendTimestamp appends a google.protobuf.Timestamp message. Nil and zero times are left out.
+func appendTimestamp(b []byte, number protowire.Number, t *time.Time) []byte {
+	if t == nil || t.IsZero() {
+		return b
+	}
+
+	var m []byte
+	m = appendInt(m, 1, t.Unix())
+	m = appendInt(m, 2, int64(t.Nanosecond()))
+
+	return appendMessage(b, number, m)
+}
+
+// appendMessage appends an embedded message
+func appendMessage(b []byte, number protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+
+	return protowire.AppendBytes(b, message)
+}
+
+// The scalar fields are left out when they hold their default value, as proto3 does
+
+// appendString appends a string field
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+
+	return protowire.AppendString(b, value)
+}
+
+// appendInt appends an int32 or int64 field
+func appendInt(b []byte, number protowire.Number, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+
+	return protowire.AppendVarint(b, uint64(value))
+}
+
+// appendDouble appends a double field
+func appendDouble(b []byte, number protowire.Number, value float64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
+
+// appendBool appends a bool field
+func appendBool(b []byte, number protowire.Number, value bool) []byte {
+	if !value {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+
+	return protowire.AppendVarint(b, protowire.EncodeBool(value))
+}
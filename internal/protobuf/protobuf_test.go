@@ -0,0 +1,129 @@
+package protobuf
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fields decodes the fields of a message into their raw values by field number.
+// Varints are returned as uint64, fixed64 values as float64 and bytes as []byte.
+func fields(t *testing.T, message []byte) map[protowire.Number][]any {
+	decoded := map[protowire.Number][]any{}
+
+	for len(message) > 0 {
+		number, wireType, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		message = message[n:]
+
+		var value any
+
+		switch wireType {
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(message)
+		case protowire.Fixed64Type:
+			var bits uint64
+			bits, n = protowire.ConsumeFixed64(message)
+			value = math.Float64frombits(bits)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(message)
+		default:
+			t.Fatalf("unexpected wire type %d", wireType)
+		}
+
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		message = message[n:]
+
+		decoded[number] = append(decoded[number], value)
+	}
+
+	return decoded
+}
+
+func TestFromJSONItem(t *testing.T) {
+	body := `{"item": {"id": "63407e2c8bcd4a43ec1c4ff4", "name": "Potion", "description": "Restores health", "price": 5.5, "tags": ["healing", "consumable"], "featured": false, "rating": {"average": 4.5, "count": 2}, "favorited": false, "version": 3, "expires_at": "2030-01-01T00:00:00Z"}}`
+
+	message, err := FromJSON([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := fields(t, message)
+
+	if id := string(item[1][0].([]byte)); id != "63407e2c8bcd4a43ec1c4ff4" {
+		t.Errorf("want id %q; got %q", "63407e2c8bcd4a43ec1c4ff4", id)
+	}
+
+	if name := string(item[3][0].([]byte)); name != "Potion" {
+		t.Errorf("want name %q; got %q", "Potion", name)
+	}
+
+	if price := item[6][0].(float64); price != 5.5 {
+		t.Errorf("want price %v; got %v", 5.5, price)
+	}
+
+	if len(item[7]) != 2 || string(item[7][1].([]byte)) != "consumable" {
+		t.Errorf("want tags %v; got %q", []string{"healing", "consumable"}, item[7])
+	}
+
+	// Default values are left out, except the favorited flag which has an explicit presence
+	if _, ok := item[11]; ok {
+		t.Errorf("want featured left out; got %v", item[11])
+	}
+
+	if len(item[14]) != 1 || item[14][0].(uint64) != 0 {
+		t.Errorf("want favorited set to false; got %v", item[14])
+	}
+
+	if version := item[15][0].(uint64); version != 3 {
+		t.Errorf("want version %d; got %d", 3, version)
+	}
+
+	rating := fields(t, item[13][0].([]byte))
+	if average, count := rating[1][0].(float64), rating[2][0].(uint64); average != 4.5 || count != 2 {
+		t.Errorf("want rating %v (%d); got %v (%d)", 4.5, 2, average, count)
+	}
+
+	expiresAt := fields(t, item[16][0].([]byte))
+	if seconds := expiresAt[1][0].(uint64); seconds != 1893456000 {
+		t.Errorf("want expires_at %d; got %d", 1893456000, seconds)
+	}
+}
+
+func TestFromJSONItemList(t *testing.T) {
+	body := `{"items": [{"name": "Potion"}, {"name": "Ether"}], "metadata": {"current_page": 1, "page_size": 20, "first_page": 1, "last_page": 1, "total_records": 2}}`
+
+	message, err := FromJSON([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := fields(t, message)
+
+	if len(list[1]) != 2 {
+		t.Fatalf("want %d items; got %d", 2, len(list[1]))
+	}
+
+	if name := string(fields(t, list[1][1].([]byte))[3][0].([]byte)); name != "Ether" {
+		t.Errorf("want name %q; got %q", "Ether", name)
+	}
+
+	metadata := fields(t, list[2][0].([]byte))
+	if pageSize, total := metadata[2][0].(uint64), metadata[5][0].(uint64); pageSize != 20 || total != 2 {
+		t.Errorf("want page size %d and %d records; got %d and %d", 20, 2, pageSize, total)
+	}
+}
+
+func TestFromJSONUnsupported(t *testing.T) {
+	_, err := FromJSON([]byte(`{"error": "the requested resource could not be found"}`))
+
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("want error %v; got %v", ErrUnsupported, err)
+	}
+}
@@ -0,0 +1,167 @@
+package rabbitmq
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrConnectionClosed is returned when a channel is requested from a connection which was closed by the service
+var ErrConnectionClosed = errors.New("rabbitmq connection closed")
+
+// Connection is a connection to RabbitMQ which is re-established with an exponential backoff when the broker
+// closes it (i.e. when it restarts). The consumers and the publisher create their channels on the current
+// connection, so that they re-create them once the connection is re-established.
+type Connection struct {
+	dial   func() (*amqp.Connection, error)
+	cfg    settings.RabbitMQ
+	logger *logger.Logger
+
+	mu     sync.RWMutex
+	conn   *amqp.Connection
+	closed bool // Closed by the service, which stops the recovery
+}
+
+// NewConnection dials RabbitMQ with the given function and keeps the connection open until it is closed
+func NewConnection(dial func() (*amqp.Connection, error), cfg settings.RabbitMQ, logger *logger.Logger) (*Connection, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &Connection{
+		dial:   dial,
+		cfg:    cfg,
+		logger: logger,
+		conn:   conn,
+	}
+
+	go connection.recover(conn)
+
+	return connection, nil
+}
+
+// Channel opens a new channel on the current connection. It fails while the connection is being re-established.
+func (connection *Connection) Channel() (*amqp.Channel, error) {
+	connection.mu.RLock()
+	defer connection.mu.RUnlock()
+
+	if connection.closed {
+		return nil, ErrConnectionClosed
+	}
+
+	return connection.conn.Channel()
+}
+
+// Properties returns the server properties of the current connection (i.e. the version of the broker)
+func (connection *Connection) Properties() amqp.Table {
+	connection.mu.RLock()
+	defer connection.mu.RUnlock()
+
+	return connection.conn.Properties
+}
+
+// IsClosed returns whether the connection was closed by the service
+func (connection *Connection) IsClosed() bool {
+	connection.mu.RLock()
+	defer connection.mu.RUnlock()
+
+	return connection.closed
+}
+
+// Close closes the connection, which is no longer re-established
+func (connection *Connection) Close() error {
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+
+	connection.closed = true
+
+	return connection.conn.Close()
+}
+
+// recover waits for the given connection to be closed by the broker and re-establishes it, until the
+// connection is closed by the service
+func (connection *Connection) recover(conn *amqp.Connection) {
+	for {
+		closeErr := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+		if closeErr == nil || connection.IsClosed() {
+			return
+		}
+
+		connection.logger.Warning("RabbitMQ connection lost", map[string]string{"error": closeErr.Error()})
+
+		backoff := newBackoff(connection.cfg)
+
+		for {
+			time.Sleep(backoff.next())
+
+			if connection.IsClosed() {
+				return
+			}
+
+			var err error
+
+			conn, err = connection.dial()
+			if err == nil {
+				break
+			}
+
+			connection.logger.Warning("Failed to reconnect to RabbitMQ", map[string]string{"error": err.Error()})
+		}
+
+		connection.mu.Lock()
+
+		if connection.closed {
+			connection.mu.Unlock()
+			conn.Close()
+			return
+		}
+
+		connection.conn = conn
+		connection.mu.Unlock()
+
+		connection.logger.Info("Reconnected to RabbitMQ", nil)
+	}
+}
+
+// backoff computes the exponential and jittered delays between the attempts to recover from a failure
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	ceiling time.Duration
+}
+
+// newBackoff returns a backoff starting at the initial backoff of the settings
+func newBackoff(cfg settings.RabbitMQ) *backoff {
+	initial := time.Duration(cfg.InitialBackoff) * time.Millisecond
+
+	return &backoff{
+		initial: initial,
+		max:     time.Duration(cfg.MaxBackoff) * time.Millisecond,
+		ceiling: initial,
+	}
+}
+
+// next returns the delay before the next attempt, between half of the current ceiling and the ceiling
+// so that the instances of the service do not reconnect all at once, and doubles the ceiling
+func (b *backoff) next() time.Duration {
+	ceiling := b.ceiling
+
+	b.ceiling *= 2
+	if b.ceiling > b.max {
+		b.ceiling = b.max
+	}
+
+	return ceiling/2 + time.Duration(rand.Int63n(int64(ceiling/2)+1))
+}
+
+// reset restarts the backoff from the initial backoff after a successful attempt
+func (b *backoff) reset() {
+	b.ceiling = b.initial
+}
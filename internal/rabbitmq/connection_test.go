@@ -0,0 +1,31 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+func TestBackoff(t *testing.T) {
+	backoff := newBackoff(settings.RabbitMQ{InitialBackoff: 100, MaxBackoff: 500})
+
+	// The delays are between half of the ceiling and the ceiling, which is doubled up to the max backoff
+	ceilings := []time.Duration{100, 200, 400, 500, 500}
+
+	for attempt, ceiling := range ceilings {
+		ceiling *= time.Millisecond
+
+		delay := backoff.next()
+
+		if delay < ceiling/2 || delay > ceiling {
+			t.Errorf("attempt %d: want delay between %v and %v; got %v", attempt+1, ceiling/2, ceiling, delay)
+		}
+	}
+
+	backoff.reset()
+
+	if delay := backoff.next(); delay > 100*time.Millisecond {
+		t.Errorf("want delay lower than %v after reset; got %v", 100*time.Millisecond, delay)
+	}
+}
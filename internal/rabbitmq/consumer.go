@@ -0,0 +1,485 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Consumer is the RabbitMQ implementation of messaging.Consumer. It binds the queue of a subscription
+// to the exchange of its route and processes the delivered messages with a handler while taking care
+// of the tracing, the metrics, the retries and the dead lettering of the messages.
+type Consumer struct {
+	conn           *Connection
+	exchangeName   string
+	routingKey     string
+	consumerTag    string
+	queueName      string
+	deadLetterName string
+	maxRetries     int
+	retryDelays    []time.Duration // Delays of the successive retries, empty to retry immediately
+	broadcast      bool            // The queue is exclusive to the instance, without retries nor dead lettering
+	handle         messaging.Handler
+	logger         *logger.Logger
+	tracer         trace.Tracer
+	metrics        *messaging.ConsumerMetrics
+	processing     int64 // Delivered messages which are not acknowledged yet
+}
+
+// Backlog is a struct that holds the messages of the queue of a consumer which are not processed yet
+type Backlog struct {
+	Queue      string `json:"queue"`
+	Ready      int    `json:"ready"`      // Waiting in the queue
+	Processing int64  `json:"processing"` // Delivered to the consumer
+}
+
+// NewConsumer returns a new Consumer processing the messages of the given subscription
+// delivered to the "<service>-<subscription>" queue. The messages waiting for their n-th
+// retry are held by the "<queue>.retry.<delay>ms" queue of its delay until the delay expires.
+// The queue of a broadcast subscription is named after the instance, "<service>-<subscription>.<hostname>-<pid>",
+// and is deleted along with the connection.
+func NewConsumer(
+	conn *Connection,
+	subscription messaging.Subscription,
+	handle messaging.Handler,
+	serviceName string,
+	logger *logger.Logger,
+	metrics *messaging.ConsumerMetrics,
+	cfg settings.Consumers,
+) *Consumer {
+	queueName := fmt.Sprintf("%s-%s", serviceName, subscription.Name)
+
+	if subscription.Broadcast {
+		hostname, _ := os.Hostname()
+		queueName = fmt.Sprintf("%s.%s-%d", queueName, hostname, os.Getpid())
+		cfg.MaxRetries = 0
+	}
+
+	return &Consumer{
+		conn:           conn,
+		exchangeName:   subscription.Route.Exchange,
+		routingKey:     "",
+		consumerTag:    "",
+		queueName:      queueName,
+		deadLetterName: fmt.Sprintf("%s.dead-letter", queueName),
+		maxRetries:     cfg.MaxRetries,
+		retryDelays:    retryDelays(cfg),
+		broadcast:      subscription.Broadcast,
+		handle:         handle,
+		logger:         logger,
+		tracer:         otel.Tracer(serviceName),
+		metrics:        metrics,
+	}
+}
+
+// Topology returns the exchanges and queues declared by the consumer
+func (consumer *Consumer) Topology() ([]string, []string) {
+	if consumer.broadcast {
+		return []string{consumer.exchangeName}, []string{consumer.queueName}
+	}
+
+	queues := []string{consumer.queueName, consumer.deadLetterName}
+	seen := map[string]bool{}
+
+	// The retries capped at the maximum delay share their retry queue
+	for _, delay := range consumer.retryDelays {
+		if name := consumer.retryQueueName(delay); !seen[name] {
+			seen[name] = true
+			queues = append(queues, name)
+		}
+	}
+
+	return []string{consumer.exchangeName, consumer.deadLetterName}, queues
+}
+
+// retryQueueName returns the name of the queue holding the messages waiting for the given delay.
+// The delay is part of the name so that changing the delays declares new queues instead of
+// conflicting with the message TTL of the existing ones.
+func (consumer *Consumer) retryQueueName(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%dms", consumer.queueName, delay.Milliseconds())
+}
+
+// Backlog returns the messages of the queue of the consumer which are not processed yet.
+// The ready messages are read from the broker on a dedicated channel.
+func (consumer *Consumer) Backlog() (Backlog, error) {
+	backlog := Backlog{Queue: consumer.queueName, Processing: atomic.LoadInt64(&consumer.processing)}
+
+	channel, err := consumer.conn.Channel()
+	if err != nil {
+		return backlog, err
+	}
+
+	defer channel.Close()
+
+	durable, exclusive := queueFlags(consumer.conn.cfg.Queues, true)
+
+	queue, err := channel.QueueDeclarePassive(
+		consumer.queueName,
+		durable,
+		false, // delete when unused?
+		exclusive,
+		false, // no wait?
+		queueArguments(consumer.conn.cfg.Queues, consumer.deadLetterName),
+	)
+	if err != nil {
+		return backlog, err
+	}
+
+	backlog.Ready = queue.Messages
+
+	return backlog, nil
+}
+
+// CreateChannel declares an exchange and a queue using consumer fields and binds the two together
+func (consumer *Consumer) CreateChannel() (*amqp.Channel, error) {
+	channel, err := consumer.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	// Declare exchange
+	err = channel.ExchangeDeclare(
+		consumer.exchangeName,
+		"fanout", // Exchange type
+		true,     // durable?
+		false,    // auto-delete?
+		false,    // internal exchange
+		false,    // no wait?
+		nil,      // arguments
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if consumer.broadcast {
+		return consumer.bindBroadcastQueue(channel)
+	}
+
+	// Declare dead letter exchange and queue receiving the messages rejected by the consumer
+	err = declareDeadLetterQueue(channel, consumer.deadLetterName, consumer.conn.cfg.DeadLetterQueues)
+	if err != nil {
+		return nil, err
+	}
+
+	// Declare the queues holding the messages until their retry
+	for _, delay := range consumer.retryDelays {
+		err = declareRetryQueue(channel, consumer.retryQueueName(delay), delay, consumer.queueName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The retried and dead lettered messages are only acknowledged once the broker confirmed their copy
+	err = channel.Confirm(false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Declare queue, exclusive unless it is a quorum queue
+	queue, err := declareQueue(channel, consumer.queueName, consumer.conn.cfg.Queues, true, consumer.deadLetterName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bind exchange to the queue
+	err = channel.QueueBind(
+		queue.Name,
+		consumer.routingKey,
+		consumer.exchangeName,
+		false, // no wait?
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return channel, nil
+}
+
+// bindBroadcastQueue declares the queue of a broadcast subscription on the given channel and binds it to the exchange.
+// The queue is exclusive to the connection of the instance and deleted along with it.
+func (consumer *Consumer) bindBroadcastQueue(channel *amqp.Channel) (*amqp.Channel, error) {
+	queue, err := channel.QueueDeclare(
+		consumer.queueName,
+		false, // durable?
+		true,  // delete when unused?
+		true,  // exclusive?
+		false, // no wait?
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = channel.QueueBind(
+		queue.Name,
+		consumer.routingKey,
+		consumer.exchangeName,
+		false, // no wait?
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return channel, nil
+}
+
+// StartConsumer starts up consumer and keeps it listening for messages. When the channel is closed (i.e. when the
+// broker restarts), the channel is re-created and the queue re-declared with an exponential backoff, until the
+// connection is closed by the service.
+func (consumer *Consumer) StartConsumer() error {
+	backoff := newBackoff(consumer.conn.cfg)
+
+	for {
+		err := consumer.consume(backoff.reset)
+
+		if consumer.conn.IsClosed() {
+			return nil
+		}
+
+		properties := map[string]string{"queue": consumer.queueName}
+		if err != nil {
+			properties["error"] = err.Error()
+		}
+
+		consumer.logger.Warning("Consumer channel closed, consuming again", properties)
+
+		time.Sleep(backoff.next())
+	}
+}
+
+// consume declares the queue of the consumer on a new channel and processes its messages until the channel
+// is closed. The given function is called once the messages are being consumed.
+func (consumer *Consumer) consume(consuming func()) error {
+	// Declare exchange, create channel and queue, and bind the two
+	channel, err := consumer.CreateChannel()
+	if err != nil {
+		return err
+	}
+
+	defer channel.Close()
+
+	// Receive messages
+	messages, err := channel.Consume(
+		consumer.queueName,
+		consumer.consumerTag,
+		false, // auto-ack?
+		false, // exclusive?
+		false, // no local?
+		false, // no wait?
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	consuming()
+
+	// The deliveries are closed along with the channel
+	for msg := range messages {
+		atomic.AddInt64(&consumer.processing, 1)
+
+		go func(msg amqp.Delivery) {
+			defer atomic.AddInt64(&consumer.processing, -1)
+
+			consumer.handleMessage(channel, msg)
+		}(msg)
+	}
+
+	return nil
+}
+
+// handleMessage decodes a delivered message, unwrapping its CloudEvent if any, and processes it while
+// recording metrics and a trace linked to the producer's trace context.
+// A panic raised while processing the message is recovered so that the consumer keeps running.
+// The message is then retried before being dead lettered, as well as when the handler failed with a transient error.
+func (consumer *Consumer) handleMessage(channel *amqp.Channel, msg amqp.Delivery) {
+	start := time.Now()
+
+	consumer.metrics.ConsumedMessagesCounter.WithLabelValues(consumer.queueName).Inc()
+
+	if msg.Redelivered {
+		consumer.metrics.RedeliveredMessagesCounter.WithLabelValues(consumer.queueName).Inc()
+	}
+
+	defer func() {
+		consumer.metrics.ProcessingTimeHistogram.WithLabelValues(consumer.queueName).Observe(time.Since(start).Seconds())
+	}()
+
+	// The handler receives the data of the CloudEvents while the original message is kept for the retries
+	event, unwrapErr := messaging.UnwrapCloudEvent(newMessage(msg))
+
+	// Extract trace context from message headers
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(event.Headers))
+
+	// Create trace for the message
+	ctx, span := consumer.tracer.Start(
+		ctx,
+		fmt.Sprintf("%s process", consumer.queueName),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("rabbitmq"),
+			semconv.MessagingDestinationKey.String(consumer.exchangeName),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingMessageIDKey.String(msg.MessageId),
+			attribute.Bool("messaging.rabbitmq.redelivered", msg.Redelivered),
+		),
+	)
+	defer span.End()
+
+	err := unwrapErr
+	if err == nil {
+		err = messaging.RecoverPanic(func() error {
+			return consumer.handle(ctx, span, event)
+		})
+	}
+
+	if err == nil {
+		consumer.ack(msg)
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	consumer.metrics.FailedMessagesCounter.WithLabelValues(consumer.queueName).Inc()
+
+	properties := map[string]string{
+		"queue":       consumer.queueName,
+		"message_id":  msg.MessageId,
+		"retry_count": fmt.Sprint(retryCount(msg)),
+	}
+
+	var handlerPanic *messaging.PanicError
+	var transientErr *messaging.TransientError
+
+	switch {
+	case errors.As(err, &handlerPanic):
+		consumer.metrics.PanicsCounter.WithLabelValues(consumer.queueName).Inc()
+		properties["panic_stack"] = string(handlerPanic.Stack)
+	case errors.As(err, &transientErr):
+		// The handler is likely to succeed once MongoDB is available again
+	default:
+		// Messages which cannot be processed are not retried
+		consumer.logger.Error(err, properties)
+		consumer.ack(msg)
+		return
+	}
+
+	consumer.logger.Error(err, properties)
+
+	// The messages of a broadcast subscription are neither retried nor dead lettered
+	if consumer.broadcast {
+		consumer.ack(msg)
+		return
+	}
+
+	consumer.retryOrDeadLetter(ctx, channel, msg, err)
+}
+
+// newMessage converts a delivered message into a messaging.Message. Only the headers holding
+// a string are kept, which includes the trace context of the producer. Some AMQP clients send
+// the strings of the headers as byte arrays, so those are kept as well.
+func newMessage(msg amqp.Delivery) messaging.Message {
+	headers := make(map[string]string, len(msg.Headers))
+
+	for key, value := range msg.Headers {
+		switch value := value.(type) {
+		case string:
+			headers[key] = value
+		case []byte:
+			headers[key] = string(value)
+		}
+	}
+
+	return messaging.Message{
+		ID:          msg.MessageId,
+		Type:        msg.Type,
+		ContentType: msg.ContentType,
+		Timestamp:   msg.Timestamp,
+		Headers:     headers,
+		Body:        msg.Body,
+	}
+}
+
+// retryOrDeadLetter publishes the message with an incremented retry count to the retry queue of the delay of the
+// retry, which routes it back to the queue once its delay expires, or directly to the queue without retry delays.
+// The message is acknowledged once the broker confirmed its copy, so that it is not lost if the copy is not stored.
+// Once the maximum number of retries is reached, the message is published to the dead letter exchange.
+// The message keeps the given cause of its failure, displayed by the parking lot.
+func (consumer *Consumer) retryOrDeadLetter(ctx context.Context, channel *amqp.Channel, msg amqp.Delivery, cause error) {
+	retries := retryCount(msg)
+
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	headers[failureReasonHeader] = cause.Error()
+
+	if retries < consumer.maxRetries {
+		headers[retryCountHeader] = int32(retries + 1)
+
+		// Messages are published to the queues through the default exchange
+		routingKey := consumer.queueName
+		if len(consumer.retryDelays) != 0 {
+			routingKey = consumer.retryQueueName(consumer.retryDelays[retries])
+		}
+
+		err := republish(ctx, channel, "", routingKey, msg, headers)
+		if err == nil {
+			consumer.ack(msg)
+			return
+		}
+
+		consumer.logger.Error(err, map[string]string{"queue": consumer.queueName, "message_id": msg.MessageId})
+	}
+
+	// The message is rejected when it cannot be published, so that the broker dead letters it without its failure reason
+	err := republish(ctx, channel, consumer.deadLetterExchange(), "", msg, headers)
+	if err == nil {
+		consumer.ack(msg)
+	} else {
+		consumer.logger.Error(err, map[string]string{"queue": consumer.queueName, "message_id": msg.MessageId})
+
+		err = msg.Nack(false, false)
+		if err != nil {
+			consumer.logger.Error(err, map[string]string{"queue": consumer.queueName, "message_id": msg.MessageId})
+			return
+		}
+	}
+
+	consumer.metrics.DeadLetteredCounter.WithLabelValues(consumer.queueName).Inc()
+}
+
+// deadLetterExchange returns the exchange the messages of the queue are dead lettered to
+func (consumer *Consumer) deadLetterExchange() string {
+	if consumer.conn.cfg.Queues.DeadLetterExchange != "" {
+		return consumer.conn.cfg.Queues.DeadLetterExchange
+	}
+
+	return consumer.deadLetterName
+}
+
+// ack acknowledges a processed message
+func (consumer *Consumer) ack(msg amqp.Delivery) {
+	err := msg.Ack(false)
+	if err != nil {
+		consumer.logger.Error(err, map[string]string{"queue": consumer.queueName, "message_id": msg.MessageId})
+	}
+}
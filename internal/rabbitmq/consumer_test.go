@@ -0,0 +1,47 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestNewMessage(t *testing.T) {
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		testName string
+		headers  amqp.Table
+	}{
+		{"String header", amqp.Table{"traceparent": traceParent}},
+		{"Byte array header", amqp.Table{"traceparent": []byte(traceParent)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			msg := newMessage(amqp.Delivery{
+				Headers:     tt.headers,
+				MessageId:   "user-1",
+				Type:        "user.updated.v1",
+				ContentType: "application/json",
+				Body:        []byte(`{"id": 1}`),
+			})
+
+			if msg.ID != "user-1" || msg.Type != "user.updated.v1" || msg.ContentType != "application/json" {
+				t.Errorf("want message %q of type %q; got %+v", "user-1", "user.updated.v1", msg)
+			}
+
+			// The consumer span is a child of the producer span
+			ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier(msg.Headers))
+			parent := trace.SpanContextFromContext(ctx)
+
+			if parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || parent.SpanID().String() != "00f067aa0ba902b7" {
+				t.Errorf("want trace context %q to be kept; got %v", traceParent, msg.Headers)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package rabbitmq
+
+import (
+	"context"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// retryCountHeader is the message header holding the number of times a message has been retried
+const retryCountHeader = "x-retry-count"
+
+// failureReasonHeader is the message header holding the error of the last attempt to process a message
+const failureReasonHeader = "x-failure-reason"
+
+// retryCount returns the number of times the given message has been retried
+func retryCount(msg amqp.Delivery) int {
+	switch count := msg.Headers[retryCountHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	default:
+		return 0
+	}
+}
+
+// failureReason returns the reason why the given message was dead lettered. The messages dead lettered by the
+// broker (i.e. expired or exceeding the maximum length of their queue) hold the reason in their x-death header.
+func failureReason(msg amqp.Delivery) string {
+	if reason, ok := msg.Headers[failureReasonHeader].(string); ok {
+		return reason
+	}
+
+	deaths, ok := msg.Headers["x-death"].([]interface{})
+	if !ok || len(deaths) == 0 {
+		return ""
+	}
+
+	// The most recent death comes first
+	death, ok := deaths[0].(amqp.Table)
+	if !ok {
+		return ""
+	}
+
+	reason, _ := death["reason"].(string)
+
+	return reason
+}
+
+// republishTimeout is the time given to the broker to confirm a republished message
+const republishTimeout = 10 * time.Second
+
+// republish publishes a persistent copy of the given message with the given headers to the given exchange
+// on the given confirm channel and waits for the broker to confirm it
+func republish(ctx context.Context, channel *amqp.Channel, exchange string, routingKey string, msg amqp.Delivery, headers amqp.Table) error {
+	ctx, cancel := context.WithTimeout(ctx, republishTimeout)
+	defer cancel()
+
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: msg.CorrelationId,
+		MessageId:     msg.MessageId,
+		Type:          msg.Type,
+		Timestamp:     msg.Timestamp,
+		Body:          msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return waitConfirmation(ctx, confirmation)
+}
+
+// retryDelays returns the delays of the retries of the given settings, starting at the initial retry delay
+// and doubled after each retry up to the maximum retry delay. No delay is returned without initial retry delay.
+func retryDelays(cfg settings.Consumers) []time.Duration {
+	if cfg.InitialRetryDelay == 0 {
+		return nil
+	}
+
+	delays := make([]time.Duration, cfg.MaxRetries)
+	delay := time.Duration(cfg.InitialRetryDelay) * time.Millisecond
+	maxDelay := time.Duration(cfg.MaxRetryDelay) * time.Millisecond
+
+	for i := range delays {
+		delays[i] = delay
+
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+
+	return delays
+}
+
+// declareRetryQueue declares a durable queue with the given name holding the messages for the given delay,
+// after which they are dead lettered to the given queue through the default exchange. The retry queues have
+// no consumer and all their messages have the same delay, so they expire in order. The name of a queue
+// holds its delay, since the message TTL of an existing queue cannot be changed by declaring it again.
+func declareRetryQueue(channel *amqp.Channel, name string, delay time.Duration, queueName string) error {
+	_, err := channel.QueueDeclare(
+		name,
+		true,  // durable?
+		false, // delete when unused?
+		false, // exclusive channel?
+		false, // no wait?
+		amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queueName,
+		},
+	)
+
+	return err
+}
+
+// declareDeadLetterQueue declares a durable exchange and queue with the given name and options and binds the two together
+func declareDeadLetterQueue(channel *amqp.Channel, name string, options settings.Queue) error {
+	// Declare exchange
+	err := channel.ExchangeDeclare(
+		name,
+		"fanout", // Exchange type
+		true,     // durable?
+		false,    // auto-delete?
+		false,    // internal exchange
+		false,    // no wait?
+		nil,      // arguments
+	)
+	if err != nil {
+		return err
+	}
+
+	// Declare queue
+	queue, err := declareQueue(channel, name, options, false, "")
+	if err != nil {
+		return err
+	}
+
+	// Bind exchange to the queue
+	return channel.QueueBind(
+		queue.Name,
+		"",
+		name,
+		false, // no wait?
+		nil,
+	)
+}
@@ -0,0 +1,79 @@
+package rabbitmq
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestRetryCount(t *testing.T) {
+	tests := []struct {
+		testName    string
+		headers     amqp.Table
+		wantedCount int
+	}{
+		{"No headers", nil, 0},
+		{"No retry header", amqp.Table{"traceparent": "00-abc"}, 0},
+		{"Retry header as int32", amqp.Table{retryCountHeader: int32(2)}, 2},
+		{"Retry header as int64", amqp.Table{retryCountHeader: int64(3)}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			count := retryCount(amqp.Delivery{Headers: tt.headers})
+
+			if count != tt.wantedCount {
+				t.Errorf("want %d; got %d", tt.wantedCount, count)
+			}
+		})
+	}
+}
+
+func TestRetryDelays(t *testing.T) {
+	tests := []struct {
+		testName     string
+		cfg          settings.Consumers
+		wantedDelays []time.Duration
+	}{
+		{"Immediate retries", settings.Consumers{MaxRetries: 3, MaxRetryDelay: 1_000}, nil},
+		{"Doubled delays", settings.Consumers{MaxRetries: 3, InitialRetryDelay: 1_000, MaxRetryDelay: 60_000}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{"Capped delays", settings.Consumers{MaxRetries: 4, InitialRetryDelay: 1_000, MaxRetryDelay: 3_000}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			delays := retryDelays(tt.cfg)
+
+			if !reflect.DeepEqual(delays, tt.wantedDelays) {
+				t.Errorf("want %v; got %v", tt.wantedDelays, delays)
+			}
+		})
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	tests := []struct {
+		testName     string
+		headers      amqp.Table
+		wantedReason string
+	}{
+		{"No headers", nil, ""},
+		{"Failure reason header", amqp.Table{failureReasonHeader: "message handler panicked: nil map"}, "message handler panicked: nil map"},
+		{"Dead lettered by the broker", amqp.Table{"x-death": []interface{}{amqp.Table{"reason": "expired"}, amqp.Table{"reason": "rejected"}}}, "expired"},
+		{"Failure reason header and dead lettered by the broker", amqp.Table{failureReasonHeader: "transient error: timeout", "x-death": []interface{}{amqp.Table{"reason": "maxlen"}}}, "transient error: timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			reason := failureReason(amqp.Delivery{Headers: tt.headers})
+
+			if reason != tt.wantedReason {
+				t.Errorf("want %q; got %q", tt.wantedReason, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrUnknownQueue is returned when a queue is not one of the dead letter queues of the consumers
+var ErrUnknownQueue = errors.New("unknown dead letter queue")
+
+// ParkingLot gives access to the dead letter queues of the consumers, in which the messages that could not be
+// processed are parked until they are requeued to the queue of their consumer or purged
+type ParkingLot struct {
+	conn   *Connection
+	names  []string
+	queues map[string]string // Queues of the consumers by dead letter queue
+}
+
+// DeadLetterQueue is a struct that holds the number of messages parked in a dead letter queue
+type DeadLetterQueue struct {
+	Name     string `json:"name"`
+	Queue    string `json:"queue"` // Queue of the consumer the messages are requeued to
+	Messages int    `json:"messages"`
+}
+
+// DeadLetter is a struct that holds a message parked in a dead letter queue.
+// The JSON bodies are returned as is and the other ones encoded in base64.
+type DeadLetter struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	ContentType string    `json:"content_type"`
+	Timestamp   time.Time `json:"timestamp"`
+	Retries     int       `json:"retries"`
+	Reason      string    `json:"reason"`
+	Body        any       `json:"body"`
+}
+
+// NewParkingLot returns a new ParkingLot for the dead letter queues of the given consumers
+func NewParkingLot(conn *Connection, consumers []*Consumer) *ParkingLot {
+	lot := &ParkingLot{conn: conn, queues: make(map[string]string, len(consumers))}
+
+	for _, consumer := range consumers {
+		lot.names = append(lot.names, consumer.deadLetterName)
+		lot.queues[consumer.deadLetterName] = consumer.queueName
+	}
+
+	return lot
+}
+
+// Queues returns the dead letter queues along with their number of messages
+func (lot *ParkingLot) Queues() ([]DeadLetterQueue, error) {
+	channel, err := lot.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	defer channel.Close()
+
+	queues := make([]DeadLetterQueue, 0, len(lot.names))
+
+	for _, name := range lot.names {
+		queue, err := inspectQueue(channel, name)
+		if err != nil {
+			return nil, err
+		}
+
+		queues = append(queues, DeadLetterQueue{Name: name, Queue: lot.queues[name], Messages: queue.Messages})
+	}
+
+	return queues, nil
+}
+
+// Peek returns up to limit messages of the given dead letter queue, oldest first, without removing them.
+// The messages are received and left unacknowledged, so that the broker requeues them once the channel is closed.
+func (lot *ParkingLot) Peek(name string, limit int) ([]DeadLetter, error) {
+	if _, ok := lot.queues[name]; !ok {
+		return nil, ErrUnknownQueue
+	}
+
+	channel, err := lot.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	defer channel.Close()
+
+	letters := []DeadLetter{}
+
+	for len(letters) < limit {
+		msg, ok, err := channel.Get(name, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			break
+		}
+
+		letters = append(letters, newDeadLetter(msg))
+	}
+
+	return letters, nil
+}
+
+// Requeue publishes the messages of the given dead letter queue with the given ids, or all of them when no id is
+// given, back to the queue of their consumer and returns the number of requeued messages. The retry count and the
+// failure reason of the messages are reset. A message is removed from the dead letter queue once the broker
+// confirmed its publication, so it is not lost if the queue of the consumer does not exist.
+func (lot *ParkingLot) Requeue(ctx context.Context, name string, ids []string) (int, error) {
+	queueName, ok := lot.queues[name]
+	if !ok {
+		return 0, ErrUnknownQueue
+	}
+
+	return lot.process(name, ids, func(channel *amqp.Channel, returns <-chan amqp.Return, msg amqp.Delivery) error {
+		headers := amqp.Table{}
+		for key, value := range msg.Headers {
+			headers[key] = value
+		}
+
+		delete(headers, retryCountHeader)
+		delete(headers, failureReasonHeader)
+		delete(headers, "x-death")
+
+		confirmation, err := channel.PublishWithDeferredConfirmWithContext(ctx, "", queueName, true, false, amqp.Publishing{
+			Headers:       headers,
+			ContentType:   msg.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			CorrelationId: msg.CorrelationId,
+			MessageId:     msg.MessageId,
+			Type:          msg.Type,
+			Timestamp:     msg.Timestamp,
+			Body:          msg.Body,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = waitConfirmation(ctx, confirmation)
+		if err != nil {
+			return err
+		}
+
+		// The broker returns an unroutable message before confirming it
+		if len(drainReturns(returns)) != 0 {
+			return ErrUnroutable
+		}
+
+		return nil
+	})
+}
+
+// Purge removes the messages of the given dead letter queue with the given ids, or all of them when no id is given,
+// and returns the number of removed messages
+func (lot *ParkingLot) Purge(name string, ids []string) (int, error) {
+	if _, ok := lot.queues[name]; !ok {
+		return 0, ErrUnknownQueue
+	}
+
+	if len(ids) != 0 {
+		return lot.process(name, ids, func(*amqp.Channel, <-chan amqp.Return, amqp.Delivery) error {
+			return nil
+		})
+	}
+
+	channel, err := lot.conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+
+	defer channel.Close()
+
+	return channel.QueuePurge(name, false)
+}
+
+// process receives the messages of the given dead letter queue on a channel in confirm mode and acknowledges those
+// with the given ids, or all of them when no id is given, once the given action succeeds for them. The other messages
+// are requeued when the channel is closed. Only the messages which were in the queue beforehand are received.
+func (lot *ParkingLot) process(
+	name string,
+	ids []string,
+	action func(channel *amqp.Channel, returns <-chan amqp.Return, msg amqp.Delivery) error,
+) (int, error) {
+	channel, err := lot.conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+
+	defer channel.Close()
+
+	err = channel.Confirm(false)
+	if err != nil {
+		return 0, err
+	}
+
+	// A single message is published at once
+	returns := channel.NotifyReturn(make(chan amqp.Return, 1))
+
+	queue, err := inspectQueue(channel, name)
+	if err != nil {
+		return 0, err
+	}
+
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+
+	processed := 0
+
+	for i := 0; i < queue.Messages; i++ {
+		msg, ok, err := channel.Get(name, false)
+		if err != nil {
+			return processed, err
+		}
+
+		if !ok {
+			break
+		}
+
+		if len(selected) != 0 && !selected[msg.MessageId] {
+			continue
+		}
+
+		err = action(channel, returns, msg)
+		if err != nil {
+			return processed, err
+		}
+
+		err = msg.Ack(false)
+		if err != nil {
+			return processed, err
+		}
+
+		processed++
+	}
+
+	return processed, nil
+}
+
+// inspectQueue returns the state of the given dead letter queue, which is declared by the consumers
+func inspectQueue(channel *amqp.Channel, name string) (amqp.Queue, error) {
+	return channel.QueueDeclarePassive(
+		name,
+		true,  // durable?
+		false, // delete when unused?
+		false, // exclusive channel?
+		false, // no wait?
+		nil,   // arguments
+	)
+}
+
+// newDeadLetter converts a message received from a dead letter queue into a DeadLetter
+func newDeadLetter(msg amqp.Delivery) DeadLetter {
+	var body any = msg.Body
+	if json.Valid(msg.Body) {
+		body = json.RawMessage(msg.Body)
+	}
+
+	return DeadLetter{
+		ID:          msg.MessageId,
+		Type:        msg.Type,
+		ContentType: msg.ContentType,
+		Timestamp:   msg.Timestamp,
+		Retries:     retryCount(msg),
+		Reason:      failureReason(msg),
+		Body:        body,
+	}
+}
@@ -0,0 +1,42 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestNewDeadLetter(t *testing.T) {
+	tests := []struct {
+		testName   string
+		body       []byte
+		wantedBody string
+	}{
+		{"JSON body", []byte(`{"id":1}`), `{"id":1}`},
+		{"Binary body", []byte{0x81, 0xa2, 'i', 'd', 0x01}, `"gaJpZAE="`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			letter := newDeadLetter(amqp.Delivery{
+				MessageId: "user-1",
+				Headers:   amqp.Table{retryCountHeader: int32(3), failureReasonHeader: "transient error: timeout"},
+				Body:      tt.body,
+			})
+
+			if letter.ID != "user-1" || letter.Retries != 3 || letter.Reason != "transient error: timeout" {
+				t.Errorf("want message %q retried 3 times; got %+v", "user-1", letter)
+			}
+
+			body, err := json.Marshal(letter.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != tt.wantedBody {
+				t.Errorf("want body %s; got %s", tt.wantedBody, body)
+			}
+		})
+	}
+}
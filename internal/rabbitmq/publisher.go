@@ -0,0 +1,252 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Publisher is the RabbitMQ implementation of messaging.BatchPublisher. It publishes persistent messages
+// to the fanout exchanges of the routes of the events. Messages are mandatory and published on a channel in confirm mode.
+type Publisher struct {
+	conn *Connection
+
+	// A channel must not be used concurrently to publish messages
+	mu             sync.Mutex
+	confirmChannel *amqp.Channel
+	returns        <-chan amqp.Return
+}
+
+// maxReturns is the number of returned messages buffered by the confirm channel, which is at least the
+// maximum size of the batches of the outbox
+const maxReturns = 1_000
+
+var (
+	// ErrRejected is returned when the broker rejects a message (i.e. a queue reached its maximum length)
+	ErrRejected = errors.New("message was rejected by the broker")
+
+	// ErrUnroutable is returned when the broker returns a mandatory message which was not routed to any queue
+	ErrUnroutable = errors.New("message is unroutable")
+)
+
+// NewPublisher returns a new Publisher
+func NewPublisher(conn *Connection) *Publisher {
+	return &Publisher{conn: conn}
+}
+
+// System returns the name of the message broker
+func (publisher *Publisher) System() string {
+	return "rabbitmq"
+}
+
+// Destination returns the exchange of the given route
+func (publisher *Publisher) Destination(route messaging.Route) string {
+	return route.Exchange
+}
+
+// Topology returns the exchanges and queues declared by the publisher
+func (publisher *Publisher) Topology() ([]string, []string) {
+	exchanges := []string{}
+	declared := make(map[string]bool)
+
+	for _, route := range messaging.Routes() {
+		if !declared[route.Exchange] {
+			exchanges = append(exchanges, route.Exchange)
+			declared[route.Exchange] = true
+		}
+	}
+
+	return exchanges, []string{}
+}
+
+// CreateChannel declares the exchanges of the publisher on a new channel
+func (publisher *Publisher) CreateChannel() (*amqp.Channel, error) {
+	channel, err := publisher.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	exchanges, _ := publisher.Topology()
+
+	for _, exchange := range exchanges {
+		// Declare exchange
+		err = channel.ExchangeDeclare(
+			exchange,
+			"fanout", // Exchange type
+			true,     // durable?
+			false,    // auto-delete?
+			false,    // internal exchange
+			false,    // no wait?
+			nil,      // arguments
+		)
+		if err != nil {
+			channel.Close()
+			return nil, err
+		}
+	}
+
+	return channel, nil
+}
+
+// Send publishes the given message to the exchange of the given route and waits for the broker to confirm it
+func (publisher *Publisher) Send(ctx context.Context, route messaging.Route, msg messaging.Message) error {
+	return publisher.SendBatch(ctx, []messaging.Envelope{{Route: route, Message: msg}})[0]
+}
+
+// SendBatch publishes the given messages to the exchanges of their routes and waits for the broker
+// to confirm them. The messages are mandatory: those which are not routed to any queue (i.e. no queue
+// is bound to the exchange) are returned by the broker and fail with ErrUnroutable, so that the outbox
+// retries them instead of dropping them. The confirm channel is created on first use or after it was closed.
+func (publisher *Publisher) SendBatch(ctx context.Context, batch []messaging.Envelope) []error {
+	errs := make([]error, 0, len(batch))
+
+	// At most maxReturns messages are published at once so that the returns never fill their buffer
+	for start := 0; start < len(batch); start += maxReturns {
+		end := start + maxReturns
+		if end > len(batch) {
+			end = len(batch)
+		}
+
+		errs = append(errs, publisher.sendConfirmed(ctx, batch[start:end])...)
+	}
+
+	return errs
+}
+
+// sendConfirmed publishes the given messages on the confirm channel and waits for their confirmations.
+// The lock is held until then so that the returned messages are attributed to the batch which sent them.
+func (publisher *Publisher) sendConfirmed(ctx context.Context, batch []messaging.Envelope) []error {
+	errs := make([]error, len(batch))
+	confirmations := make([]*amqp.DeferredConfirmation, len(batch))
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if publisher.confirmChannel == nil || publisher.confirmChannel.IsClosed() {
+		err := publisher.createConfirmChannel()
+		if err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+
+			return errs
+		}
+	}
+
+	for i, envelope := range batch {
+		confirmations[i], errs[i] = publisher.confirmChannel.PublishWithDeferredConfirmWithContext(
+			ctx,
+			envelope.Route.Exchange,
+			"",
+			true,  // mandatory?
+			false, // immediate?
+			newPublishing(envelope.Message),
+		)
+	}
+
+	// Confirmations are awaited once every message was sent
+	for i, confirmation := range confirmations {
+		if errs[i] == nil {
+			errs[i] = waitConfirmation(ctx, confirmation)
+		}
+	}
+
+	// The broker returns an unroutable message before confirming it
+	markReturned(batch, errs, drainReturns(publisher.returns))
+
+	return errs
+}
+
+// createConfirmChannel creates a channel in confirm mode which notifies the returned messages
+func (publisher *Publisher) createConfirmChannel() error {
+	channel, err := publisher.CreateChannel()
+	if err != nil {
+		return err
+	}
+
+	err = channel.Confirm(false)
+	if err != nil {
+		channel.Close()
+		return err
+	}
+
+	publisher.confirmChannel = channel
+	publisher.returns = channel.NotifyReturn(make(chan amqp.Return, maxReturns))
+
+	return nil
+}
+
+// waitConfirmation waits for the broker to confirm a message until the given context is done
+func waitConfirmation(ctx context.Context, confirmation *amqp.DeferredConfirmation) error {
+	acked := make(chan bool, 1)
+
+	go func() {
+		acked <- confirmation.Wait()
+	}()
+
+	select {
+	case ack := <-acked:
+		if !ack {
+			return ErrRejected
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drainReturns returns the messages received on the given channel without waiting for further ones
+func drainReturns(returns <-chan amqp.Return) []amqp.Return {
+	returned := []amqp.Return{}
+
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return returned
+			}
+
+			returned = append(returned, ret)
+		default:
+			return returned
+		}
+	}
+}
+
+// markReturned fails the messages of the batch which were returned by the broker and confirmed otherwise
+func markReturned(batch []messaging.Envelope, errs []error, returned []amqp.Return) {
+	replies := make(map[string]string, len(returned))
+	for _, ret := range returned {
+		replies[ret.MessageId] = ret.ReplyText
+	}
+
+	for i, envelope := range batch {
+		if reply, ok := replies[envelope.Message.ID]; ok && errs[i] == nil {
+			errs[i] = fmt.Errorf("%w: %s", ErrUnroutable, reply)
+		}
+	}
+}
+
+// newPublishing converts a message into a persistent AMQP message
+func newPublishing(msg messaging.Message) amqp.Publishing {
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.ID,
+		Type:         msg.Type,
+		Timestamp:    msg.Timestamp,
+		Body:         msg.Body,
+	}
+}
@@ -0,0 +1,38 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/messaging"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestMarkReturned(t *testing.T) {
+	batch := []messaging.Envelope{
+		{Message: messaging.Message{ID: "routed"}},
+		{Message: messaging.Message{ID: "returned"}},
+		{Message: messaging.Message{ID: "rejected"}},
+	}
+	errs := []error{nil, nil, ErrRejected}
+
+	returns := make(chan amqp.Return, 2)
+	returns <- amqp.Return{MessageId: "returned", ReplyText: "NO_ROUTE"}
+	returns <- amqp.Return{MessageId: "rejected", ReplyText: "NO_ROUTE"}
+
+	markReturned(batch, errs, drainReturns(returns))
+
+	if errs[0] != nil {
+		t.Errorf("want nil error for routed message; got %v", errs[0])
+	}
+
+	if !errors.Is(errs[1], ErrUnroutable) {
+		t.Errorf("want error %v for returned message; got %v", ErrUnroutable, errs[1])
+	}
+
+	// The first failure of a message is kept
+	if !errors.Is(errs[2], ErrRejected) {
+		t.Errorf("want error %v for rejected message; got %v", ErrRejected, errs[2])
+	}
+}
@@ -0,0 +1,60 @@
+package rabbitmq
+
+import (
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// declareQueue declares a queue with the given name and options. Exclusive classic queues are not durable
+// and are deleted along with the connection, while quorum queues are always durable and shared.
+// The messages dead lettered by the queue are routed to the given exchange unless the options override it.
+func declareQueue(channel *amqp.Channel, name string, options settings.Queue, exclusive bool, deadLetterExchange string) (amqp.Queue, error) {
+	durable, exclusive := queueFlags(options, exclusive)
+
+	return channel.QueueDeclare(
+		name,
+		durable,
+		false, // delete when unused?
+		exclusive,
+		false, // no wait?
+		queueArguments(options, deadLetterExchange),
+	)
+}
+
+// queueFlags returns whether a queue with the given options is durable and exclusive
+func queueFlags(options settings.Queue, exclusive bool) (bool, bool) {
+	if options.Type == "quorum" || !exclusive {
+		return true, false
+	}
+
+	return false, true
+}
+
+// queueArguments returns the arguments declaring a queue with the given options. The arguments left to their
+// defaults are omitted so that the queues declared before they were configurable keep the same arguments.
+func queueArguments(options settings.Queue, deadLetterExchange string) amqp.Table {
+	args := amqp.Table{}
+
+	if options.Type == "quorum" {
+		args["x-queue-type"] = "quorum"
+	}
+
+	if options.MessageTTL > 0 {
+		args["x-message-ttl"] = int64(options.MessageTTL)
+	}
+
+	if options.MaxLength > 0 {
+		args["x-max-length"] = int64(options.MaxLength)
+	}
+
+	if options.DeadLetterExchange != "" {
+		deadLetterExchange = options.DeadLetterExchange
+	}
+
+	if deadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = deadLetterExchange
+	}
+
+	return args
+}
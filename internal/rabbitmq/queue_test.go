@@ -0,0 +1,62 @@
+package rabbitmq
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestQueueArguments(t *testing.T) {
+	tests := []struct {
+		name               string
+		options            settings.Queue
+		deadLetterExchange string
+		want               amqp.Table
+	}{
+		{"Defaults", settings.Queue{Type: "classic"}, "", amqp.Table{}},
+		{"Default dead letter exchange", settings.Queue{Type: "classic"}, "catalog.dead-letter", amqp.Table{"x-dead-letter-exchange": "catalog.dead-letter"}},
+		{
+			"Quorum queue with limits",
+			settings.Queue{Type: "quorum", MessageTTL: 60_000, MaxLength: 1_000, DeadLetterExchange: "parking-lot"},
+			"catalog.dead-letter",
+			amqp.Table{"x-queue-type": "quorum", "x-message-ttl": int64(60_000), "x-max-length": int64(1_000), "x-dead-letter-exchange": "parking-lot"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := queueArguments(tt.options, tt.deadLetterExchange)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %v; got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQueueFlags(t *testing.T) {
+	tests := []struct {
+		name            string
+		queueType       string
+		exclusive       bool
+		wantedDurable   bool
+		wantedExclusive bool
+	}{
+		{"Exclusive classic queue", "classic", true, false, true},
+		{"Shared classic queue", "classic", false, true, false},
+		{"Quorum queue", "quorum", true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			durable, exclusive := queueFlags(settings.Queue{Type: tt.queueType}, tt.exclusive)
+
+			if durable != tt.wantedDurable || exclusive != tt.wantedExclusive {
+				t.Errorf("want durable %t and exclusive %t; got %t and %t", tt.wantedDurable, tt.wantedExclusive, durable, exclusive)
+			}
+		})
+	}
+}
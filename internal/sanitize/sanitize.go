@@ -0,0 +1,58 @@
+package sanitize
+
+import (
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// DroppedTags are the tags whose content is removed along with them since it is not meant to be displayed
+var DroppedTags = []string{"script", "style", "iframe", "object", "embed", "noscript", "template", "svg", "math"}
+
+// Policy is a struct that sanitizes the item descriptions and the HTML rendered from them, which are displayed in web views.
+// The allowed tags and attributes are kept and every other tag is removed along with its attributes
+// while its text is kept, except for the dropped tags whose content is removed as well.
+// Comments are removed and the text is escaped so that the result is always safe HTML.
+type Policy struct {
+	policy *bluemonday.Policy // Nil with the "none" policy
+}
+
+// NewPolicy returns the sanitization policy of the given configuration
+func NewPolicy(cfg settings.Sanitization) *Policy {
+	var policy *bluemonday.Policy
+
+	switch cfg.Policy {
+	case "none":
+		return &Policy{}
+	case "basic":
+		policy = bluemonday.NewPolicy()
+		policy.AllowElements(cfg.AllowedTags...)
+
+		if len(cfg.AllowedAttributes) != 0 {
+			policy.AllowAttrs(cfg.AllowedAttributes...).OnElements(cfg.AllowedTags...)
+		}
+
+		// The URLs must be relative or have an allowed scheme
+		policy.AllowURLSchemes(cfg.AllowedSchemes...)
+		policy.AllowRelativeURLs(true)
+		policy.RequireParseableURLs(true)
+	default:
+		// The strict policy does not keep any tag
+		policy = bluemonday.StrictPolicy()
+	}
+
+	policy.SkipElementsContent(DroppedTags...)
+
+	return &Policy{policy: policy}
+}
+
+// Sanitize returns the given HTML with the tags and attributes which are not allowed removed.
+// The text is returned as it is with the "none" policy.
+func (policy *Policy) Sanitize(s string) string {
+	if policy.policy == nil {
+		return s
+	}
+
+	return strings.TrimSpace(policy.policy.Sanitize(s))
+}
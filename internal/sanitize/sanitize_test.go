@@ -0,0 +1,58 @@
+package sanitize
+
+import (
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// newTestPolicy returns a policy of the given mode with the default configuration
+func newTestPolicy(mode string) *Policy {
+	return NewPolicy(settings.Sanitization{
+		Policy:            mode,
+		AllowedTags:       []string{"p", "b", "a", "br"},
+		AllowedAttributes: []string{"href", "title"},
+		AllowedSchemes:    []string{"http", "https", "mailto"},
+	})
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		input  string
+		want   string
+	}{
+		{"Plain text", "basic", "A healing potion", "A healing potion"},
+		{"Allowed tags", "basic", "<p>A <b>healing</b> potion<br></p>", "<p>A <b>healing</b> potion<br></p>"},
+		{"Unknown tags", "basic", "<div><span>A potion</span></div>", "A potion"},
+		{"Script", "basic", "A potion<script>alert(1)</script>", "A potion"},
+		{"Nested dropped tags", "basic", "<svg><math>x</math>y</svg>A potion", "A potion"},
+		{"Event handler", "basic", `<p onclick="alert(1)" title="Potion">A potion</p>`, `<p title="Potion">A potion</p>`},
+		{"Allowed link", "basic", `<a href="https://example.com/potion">A potion</a>`, `<a href="https://example.com/potion">A potion</a>`},
+		{"Relative link", "basic", `<a href="/items/potion">A potion</a>`, `<a href="/items/potion">A potion</a>`},
+		{"Script link", "basic", `<a href="javascript:alert(1)">A potion</a>`, "A potion"},
+		{"Obfuscated script link", "basic", `<a href=" JaVa&#09;Script:alert(1)">A potion</a>`, "A potion"},
+		{"Comment", "basic", "A <!-- hidden -->potion", "A potion"},
+		{"Escaped text", "basic", "1 &lt; 2 &amp; 3 > 2", "1 &lt; 2 &amp; 3 &gt; 2"},
+		{"Escaped attribute", "basic", `<p title="&quot;><script>">A potion</p>`, `<p title="&#34;&gt;&lt;script&gt;">A potion</p>`},
+		{"Strict", "strict", "<p>A <b>healing</b> potion<script>alert(1)</script></p>", "A healing potion"},
+		{"None", "none", "<p onclick=\"alert(1)\">A potion</p>", "<p onclick=\"alert(1)\">A potion</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newTestPolicy(tt.policy)
+
+			got := policy.Sanitize(tt.input)
+			if got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+
+			// Sanitizing a sanitized description does not change it
+			if again := policy.Sanitize(got); again != got {
+				t.Errorf("want %q; got %q after sanitizing twice", got, again)
+			}
+		})
+	}
+}
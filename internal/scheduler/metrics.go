@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics is a struct that holds some prometheus metrics regarding the scheduled tasks, labelled by task
+type Metrics struct {
+	RunsCounter       *prometheus.CounterVec
+	DurationHistogram *prometheus.HistogramVec
+}
+
+// NewMetrics creates the counters and histograms used to keep track of the scheduled tasks
+func NewMetrics(appName string) *Metrics {
+	runsCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_scheduled_task_runs_total", appName),
+		Help: "The total number of runs of the scheduled tasks by the instance, by task and status",
+	}, []string{"task", "status"})
+
+	durationHistogram := promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    fmt.Sprintf("%s_scheduled_task_duration_seconds", appName),
+		Help:    "Duration of the runs of the scheduled tasks, by task",
+		Buckets: []float64{0.01, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+	}, []string{"task"})
+
+	return &Metrics{
+		RunsCounter:       runsCounter,
+		DurationHistogram: durationHistogram,
+	}
+}
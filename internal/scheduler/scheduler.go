@@ -0,0 +1,196 @@
+// Package scheduler runs the recurring maintenance tasks of the catalog (i.e. the check for expired items)
+// within the service rather than from an external cron. The tasks are locked in MongoDB so that each run of
+// a task happens on a single instance, whichever the number of instances.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// releaseTimeout is the maximum duration of the write of the outcome of a run
+const releaseTimeout = 10 * time.Second
+
+// Task is a struct that defines a recurring maintenance task
+type Task struct {
+	Name     string
+	Interval time.Duration // Time between the starts of two runs, the task is disabled when it is zero
+	Local    bool          // Run by every instance without lock, i.e. for the in-memory state of the instances
+	Run      func(ctx context.Context) error
+}
+
+// lockStore is implemented by the stores of the locks of the tasks
+type lockStore interface {
+	Acquire(ctx context.Context, name string, owner string, now time.Time, lockedUntil time.Time) (bool, error)
+	Release(ctx context.Context, name string, owner string, ranAt time.Time, nextRunAt time.Time, runErr error) error
+}
+
+// Scheduler is a struct that runs the registered tasks at their interval. A task is run by the instance which
+// acquires its lock once the previous run is due; the runs are canceled after the lock duration so that they
+// never overlap with the run of another instance.
+type Scheduler struct {
+	store         lockStore
+	owner         string
+	checkInterval time.Duration
+	lockDuration  time.Duration
+	logger        *logger.Logger
+	metrics       *Metrics
+	tracer        trace.Tracer
+	now           func() time.Time
+
+	mu       sync.Mutex
+	tasks    []Task
+	running  map[string]bool
+	nextRuns map[string]time.Time // Next runs of the local tasks
+}
+
+// New returns a new Scheduler locking the tasks with the given store
+func New(store lockStore, cfg settings.Scheduler, serviceName string, logger *logger.Logger, metrics *Metrics) *Scheduler {
+	return &Scheduler{
+		store:         store,
+		owner:         primitive.NewObjectID().Hex(),
+		checkInterval: time.Duration(cfg.CheckInterval) * time.Second,
+		lockDuration:  time.Duration(cfg.LockDuration) * time.Second,
+		logger:        logger,
+		metrics:       metrics,
+		tracer:        otel.Tracer(serviceName),
+		now:           time.Now,
+		running:       make(map[string]bool),
+		nextRuns:      make(map[string]time.Time),
+	}
+}
+
+// Register adds the given task to the scheduled tasks. Disabled tasks are ignored.
+func (scheduler *Scheduler) Register(task Task) {
+	if task.Interval <= 0 {
+		return
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	scheduler.tasks = append(scheduler.tasks, task)
+}
+
+// Run starts the due tasks at the check interval
+func (scheduler *Scheduler) Run() {
+	ticker := time.NewTicker(scheduler.checkInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		scheduler.tick(context.Background())
+	}
+}
+
+// tick starts the tasks which are not running on the instance, except the local tasks which are not due yet.
+// Whether the other tasks are due is decided by their lock.
+func (scheduler *Scheduler) tick(ctx context.Context) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	now := scheduler.now()
+
+	for _, task := range scheduler.tasks {
+		if scheduler.running[task.Name] || (task.Local && now.Before(scheduler.nextRuns[task.Name])) {
+			continue
+		}
+
+		scheduler.running[task.Name] = true
+
+		go func(task Task) {
+			_, err := scheduler.runTask(ctx, task)
+			if err != nil {
+				scheduler.logger.Error(err, map[string]string{"operation": "lock_task", "task": task.Name})
+			}
+
+			scheduler.mu.Lock()
+			defer scheduler.mu.Unlock()
+
+			delete(scheduler.running, task.Name)
+		}(task)
+	}
+}
+
+// runTask runs the given task unless it is locked by another run and returns whether it ran. The failures of
+// the task are logged rather than returned.
+func (scheduler *Scheduler) runTask(ctx context.Context, task Task) (bool, error) {
+	startedAt := scheduler.now().UTC()
+
+	if task.Local {
+		scheduler.execute(ctx, task)
+
+		scheduler.mu.Lock()
+		defer scheduler.mu.Unlock()
+
+		scheduler.nextRuns[task.Name] = startedAt.Add(task.Interval)
+
+		return true, nil
+	}
+
+	acquired, err := scheduler.store.Acquire(ctx, task.Name, scheduler.owner, startedAt, startedAt.Add(scheduler.lockDuration))
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	runErr := scheduler.execute(ctx, task)
+
+	releaseCtx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	err = scheduler.store.Release(releaseCtx, task.Name, scheduler.owner, startedAt, startedAt.Add(task.Interval), runErr)
+	if errors.Is(err, ErrLockLost) {
+		scheduler.logger.Info("Lock of the task lost", map[string]string{"task": task.Name})
+		return true, nil
+	}
+
+	return true, err
+}
+
+// execute runs the given task within the lock duration, records its outcome and returns its error
+func (scheduler *Scheduler) execute(ctx context.Context, task Task) error {
+	ctx, cancel := context.WithTimeout(ctx, scheduler.lockDuration)
+	defer cancel()
+
+	ctx, span := scheduler.tracer.Start(ctx, "task "+task.Name, trace.WithAttributes(attribute.String("task", task.Name)))
+	defer span.End()
+
+	start := time.Now()
+	err := scheduler.call(ctx, task)
+	scheduler.metrics.DurationHistogram.WithLabelValues(task.Name).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		scheduler.metrics.RunsCounter.WithLabelValues(task.Name, "failed").Inc()
+		scheduler.logger.Error(err, map[string]string{"operation": "run_task", "task": task.Name})
+
+		return err
+	}
+
+	scheduler.metrics.RunsCounter.WithLabelValues(task.Name, "succeeded").Inc()
+
+	return nil
+}
+
+// call runs the given task, converting its panics into errors
+func (scheduler *Scheduler) call(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("task panicked: %v", recovered)
+		}
+	}()
+
+	return task.Run(ctx)
+}
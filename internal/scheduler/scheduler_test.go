@@ -0,0 +1,200 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+)
+
+// testMetrics are shared by the schedulers of the tests since prometheus metrics can only be registered once
+var testMetrics = NewMetrics("scheduler_test")
+
+// fakeStore keeps the locks of the tasks in memory
+type fakeStore struct {
+	mu    sync.Mutex
+	locks map[string]*lock
+}
+
+// Acquire locks the given task if its lock expired
+func (store *fakeStore) Acquire(ctx context.Context, name string, owner string, now time.Time, lockedUntil time.Time) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if l, ok := store.locks[name]; ok && l.LockedUntil.After(now) {
+		return false, nil
+	}
+
+	store.locks[name] = &lock{Name: name, Owner: owner, LockedUntil: lockedUntil}
+
+	return true, nil
+}
+
+// Release keeps the given task locked until its next run
+func (store *fakeStore) Release(ctx context.Context, name string, owner string, ranAt time.Time, nextRunAt time.Time, runErr error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	l, ok := store.locks[name]
+	if !ok || l.Owner != owner {
+		return ErrLockLost
+	}
+
+	l.LockedUntil = nextRunAt
+	l.LastRunAt = ranAt
+	l.LastError = ""
+
+	if runErr != nil {
+		l.LastError = runErr.Error()
+	}
+
+	return nil
+}
+
+// newTestScheduler returns a scheduler whose clock is controlled by the returned function
+func newTestScheduler(store *fakeStore) (*Scheduler, func(time.Duration)) {
+	now := time.Now()
+
+	scheduler := New(store, settings.Scheduler{CheckInterval: 10, LockDuration: 60}, "Catalog", logger.New(io.Discard, logger.LevelInfo), testMetrics)
+	scheduler.now = func() time.Time { return now }
+
+	return scheduler, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSchedulerRunTask(t *testing.T) {
+	store := &fakeStore{locks: make(map[string]*lock)}
+	first, advance := newTestScheduler(store)
+	second, _ := newTestScheduler(store)
+	second.now = first.now
+
+	runs := 0
+	task := Task{Name: "test.count", Interval: time.Minute, Run: func(ctx context.Context) error {
+		runs++
+		return nil
+	}}
+
+	ran, err := first.runTask(context.Background(), task)
+	if err != nil || !ran {
+		t.Fatalf("want task to run; got %t, %v", ran, err)
+	}
+
+	// The task is locked until its next run, whichever the instance
+	for _, scheduler := range []*Scheduler{first, second} {
+		ran, err = scheduler.runTask(context.Background(), task)
+		if err != nil || ran {
+			t.Errorf("want task not to run before its interval; got %t, %v", ran, err)
+		}
+	}
+
+	advance(time.Minute)
+
+	ran, err = second.runTask(context.Background(), task)
+	if err != nil || !ran {
+		t.Errorf("want task to run once due; got %t, %v", ran, err)
+	}
+
+	if runs != 2 {
+		t.Errorf("want %d; got %d", 2, runs)
+	}
+}
+
+func TestSchedulerFailedTask(t *testing.T) {
+	store := &fakeStore{locks: make(map[string]*lock)}
+	scheduler, _ := newTestScheduler(store)
+
+	tests := []struct {
+		testName    string
+		run         func(ctx context.Context) error
+		wantedError string
+	}{
+		{"Error", func(ctx context.Context) error { return errors.New("compaction failed") }, "compaction failed"},
+		{"Panic", func(ctx context.Context) error { panic("boom") }, "task panicked: boom"},
+		{"Lock duration exceeded", func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }, "context deadline exceeded"},
+	}
+
+	scheduler.lockDuration = 10 * time.Millisecond
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			name := "test." + tt.testName
+
+			ran, err := scheduler.runTask(context.Background(), Task{Name: name, Interval: time.Minute, Run: tt.run})
+			if err != nil || !ran {
+				t.Fatalf("want task to run; got %t, %v", ran, err)
+			}
+
+			if got := store.locks[name].LastError; got != tt.wantedError {
+				t.Errorf("want last error %q; got %q", tt.wantedError, got)
+			}
+		})
+	}
+}
+
+func TestSchedulerLocalTask(t *testing.T) {
+	store := &fakeStore{locks: make(map[string]*lock)}
+	scheduler, advance := newTestScheduler(store)
+
+	var mu sync.Mutex
+	runs := 0
+	done := make(chan struct{}, 1)
+
+	scheduler.Register(Task{Name: "test.local", Interval: time.Minute, Local: true, Run: func(ctx context.Context) error {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+
+		done <- struct{}{}
+		return nil
+	}})
+
+	// Disabled tasks are not scheduled
+	scheduler.Register(Task{Name: "test.disabled", Run: func(ctx context.Context) error {
+		t.Error("want disabled task not to run; got run")
+		return nil
+	}})
+
+	// waitIdle waits for the started runs to finish
+	waitIdle := func() {
+		for {
+			scheduler.mu.Lock()
+			running := len(scheduler.running)
+			scheduler.mu.Unlock()
+
+			if running == 0 {
+				return
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	scheduler.tick(context.Background())
+	<-done
+	waitIdle()
+
+	// The local task is not due yet
+	scheduler.tick(context.Background())
+	waitIdle()
+
+	advance(time.Minute)
+	scheduler.tick(context.Background())
+	<-done
+	waitIdle()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if runs != 2 {
+		t.Errorf("want %d; got %d", 2, runs)
+	}
+
+	// Local tasks are not locked
+	if len(store.locks) != 0 {
+		t.Errorf("want %d locks; got %d", 0, len(store.locks))
+	}
+}
@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/constants"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrLockLost is returned when the lock of a task was taken over by another instance while the task ran
+var ErrLockLost = errors.New("lock of the task lost")
+
+// lock is a struct that defines the lock of a task along with its last run
+type lock struct {
+	Name        string    `bson:"_id"`
+	Owner       string    `bson:"owner"`
+	LockedUntil time.Time `bson:"locked_until"`
+	LastRunAt   time.Time `bson:"last_run_at,omitempty"`
+	LastError   string    `bson:"last_error,omitempty"`
+}
+
+// Store is a struct that manages the locks of the tasks. The lock of a task is held by the instance running it
+// and, once the task ran, until its next run, so that a single instance runs each scheduled run.
+type Store struct {
+	collection *mongo.Collection
+}
+
+// NewStore creates a new Store
+func NewStore(client *mongo.Client, databaseName string) *Store {
+	return &Store{collection: client.Database(databaseName).Collection(constants.SchedulerLocksCollection)}
+}
+
+// Acquire locks the given task for the given owner until the given time.
+// It returns false when the task is locked, i.e. it is running or not due yet.
+func (store *Store) Acquire(ctx context.Context, name string, owner string, now time.Time, lockedUntil time.Time) (bool, error) {
+	// The upsert fails with a duplicate key error when the existing lock of the task did not expire yet
+	_, err := store.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": name, "locked_until": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"owner": owner, "locked_until": lockedUntil}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Release records the outcome of the run of the given task started at the given time and keeps the task
+// locked until its next run. It returns ErrLockLost when the lock is no longer held by the given owner.
+func (store *Store) Release(ctx context.Context, name string, owner string, ranAt time.Time, nextRunAt time.Time, runErr error) error {
+	update := bson.M{"$set": bson.M{"locked_until": nextRunAt, "last_run_at": ranAt}}
+
+	if runErr != nil {
+		update["$set"].(bson.M)["last_error"] = runErr.Error()
+	} else {
+		update["$unset"] = bson.M{"last_error": ""}
+	}
+
+	result, err := store.collection.UpdateOne(ctx, bson.M{"_id": name, "owner": owner}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrLockLost
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// requestTimeout is the maximum amount of time given to a request sent to Elasticsearch
+const requestTimeout = 3 * time.Second
+
+// indexProperties are the fields of the documents of the items index
+var indexProperties = map[string]any{
+	"id":                 map[string]any{"type": "keyword"},
+	"tenant_id":          map[string]any{"type": "keyword"},
+	"external_id":        map[string]any{"type": "keyword"},
+	"name":               map[string]any{"type": "text", "fields": map[string]any{"keyword": map[string]any{"type": "keyword"}}},
+	"description":        map[string]any{"type": "text"},
+	"price":              map[string]any{"type": "double"},
+	"tags":               map[string]any{"type": "keyword"},
+	"images":             map[string]any{"type": "object", "enabled": false}, // Stored but not searched
+	"flagged_for_review": map[string]any{"type": "boolean"},
+	"featured":           map[string]any{"type": "boolean"},
+	"featured_priority":  map[string]any{"type": "integer"},
+	"rating":             map[string]any{"properties": map[string]any{"average": map[string]any{"type": "float"}, "count": map[string]any{"type": "integer"}}},
+	"version":            map[string]any{"type": "integer"},
+	"expires_at":         map[string]any{"type": "date"},
+	"created_by":         map[string]any{"type": "long"},
+	"updated_by":         map[string]any{"type": "long"},
+	"created_at":         map[string]any{"type": "date"},
+	"updated_at":         map[string]any{"type": "date"},
+	"synced_at":          map[string]any{"type": "date"},
+}
+
+// indexMapping is the mapping of the items index
+var indexMapping = map[string]any{
+	"mappings": map[string]any{
+		"dynamic":    "strict",
+		"properties": indexProperties,
+	},
+}
+
+// itemDocument is a struct that defines an item as it is indexed in Elasticsearch
+type itemDocument struct {
+	ID               string           `json:"id"`
+	TenantID         string           `json:"tenant_id,omitempty"`
+	ExternalID       string           `json:"external_id,omitempty"`
+	Name             string           `json:"name"`
+	Description      string           `json:"description"`
+	Price            float64          `json:"price"`
+	Tags             []string         `json:"tags"`
+	Images           []data.ItemImage `json:"images,omitempty"`
+	FlaggedForReview bool             `json:"flagged_for_review,omitempty"`
+	Featured         bool             `json:"featured,omitempty"`
+	FeaturedPriority int32            `json:"featured_priority,omitempty"`
+	Rating           *data.ItemRating `json:"rating,omitempty"`
+	Version          int32            `json:"version"`
+	ExpiresAt        *time.Time       `json:"expires_at,omitempty"`
+	CreatedBy        int64            `json:"created_by,omitempty"`
+	UpdatedBy        int64            `json:"updated_by,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+	SyncedAt         *time.Time       `json:"synced_at,omitempty"` // Set by the change stream indexing, see StreamIndexer
+}
+
+// newItemDocument converts an item into its Elasticsearch document
+func newItemDocument(item data.Item) itemDocument {
+	return itemDocument{
+		ID:               item.ID.Hex(),
+		TenantID:         item.TenantID,
+		ExternalID:       item.ExternalID,
+		Name:             item.Name,
+		Description:      item.Description,
+		Price:            item.Price,
+		Tags:             item.Tags,
+		Images:           item.Images,
+		FlaggedForReview: item.FlaggedForReview,
+		Featured:         item.Featured,
+		FeaturedPriority: item.FeaturedPriority,
+		Rating:           item.Rating,
+		Version:          item.Version,
+		ExpiresAt:        item.ExpiresAt,
+		CreatedBy:        item.CreatedBy,
+		UpdatedBy:        item.UpdatedBy,
+		CreatedAt:        item.CreatedAt,
+		UpdatedAt:        item.UpdatedAt,
+	}
+}
+
+// item converts an Elasticsearch document back into an item
+func (doc itemDocument) item() (data.Item, error) {
+	id, err := primitive.ObjectIDFromHex(doc.ID)
+	if err != nil {
+		return data.Item{}, err
+	}
+
+	return data.Item{
+		ID:               id,
+		TenantID:         doc.TenantID,
+		ExternalID:       doc.ExternalID,
+		Name:             doc.Name,
+		Description:      doc.Description,
+		Price:            doc.Price,
+		Tags:             doc.Tags,
+		Images:           doc.Images,
+		FlaggedForReview: doc.FlaggedForReview,
+		Featured:         doc.Featured,
+		FeaturedPriority: doc.FeaturedPriority,
+		Rating:           doc.Rating,
+		Version:          doc.Version,
+		ExpiresAt:        doc.ExpiresAt,
+		CreatedBy:        doc.CreatedBy,
+		UpdatedBy:        doc.UpdatedBy,
+		CreatedAt:        doc.CreatedAt,
+		UpdatedAt:        doc.UpdatedAt,
+	}, nil
+}
+
+// Elasticsearch is a struct that mirrors the catalog items into an Elasticsearch
+// (or OpenSearch) index and searches them
+type Elasticsearch struct {
+	client   *http.Client
+	url      string
+	index    string
+	username string
+	password string
+}
+
+// NewElasticsearch creates a new Elasticsearch search index from the given configuration
+func NewElasticsearch(cfg settings.Elasticsearch) *Elasticsearch {
+	return &Elasticsearch{
+		client:   &http.Client{Timeout: requestTimeout},
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		index:    cfg.Index,
+		username: cfg.Username,
+		password: cfg.Password,
+	}
+}
+
+// EnsureIndex creates the items index with its mapping if it does not exist yet.
+// The fields added since an existing index was created are added to its mapping.
+// It returns true if the index was created.
+func (es *Elasticsearch) EnsureIndex(ctx context.Context) (bool, error) {
+	statusCode, err := es.do(ctx, http.MethodHead, es.index, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if statusCode == http.StatusOK {
+		statusCode, err = es.do(ctx, http.MethodPut, fmt.Sprintf("%s/_mapping", es.index), map[string]any{"properties": indexProperties}, nil)
+		if err != nil {
+			return false, err
+		}
+
+		if statusCode != http.StatusOK {
+			return false, fmt.Errorf("failed to update the mapping of elasticsearch index %q: status %d", es.index, statusCode)
+		}
+
+		return false, nil
+	}
+
+	statusCode, err = es.do(ctx, http.MethodPut, es.index, indexMapping, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if statusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to create elasticsearch index %q: status %d", es.index, statusCode)
+	}
+
+	return true, nil
+}
+
+// IndexItem creates or replaces the document of the given item. The version of the item is the external version
+// of the document, so that a synchronization finishing after a newer one can't overwrite the newer document:
+// the document is kept when the index already holds a newer version of the item.
+func (es *Elasticsearch) IndexItem(ctx context.Context, item data.Item) error {
+	query := url.Values{"version": []string{strconv.Itoa(int(item.Version))}, "version_type": []string{"external_gte"}}
+
+	statusCode, err := es.do(ctx, http.MethodPut, fmt.Sprintf("%s/_doc/%s?%s", es.index, item.ID.Hex(), query.Encode()), newItemDocument(item), nil)
+	if err != nil {
+		return err
+	}
+
+	if statusCode == http.StatusConflict {
+		return nil
+	}
+
+	if statusCode != http.StatusOK && statusCode != http.StatusCreated {
+		return fmt.Errorf("failed to index item %s: status %d", item.ID.Hex(), statusCode)
+	}
+
+	return nil
+}
+
+// ReplaceItem creates or replaces the document of the given item, whichever the version of the indexed document,
+// recording when it was synchronized. The changes of the items are indexed in order, so the last write wins even
+// when an older version of the item was restored.
+func (es *Elasticsearch) ReplaceItem(ctx context.Context, item data.Item, syncedAt time.Time) error {
+	doc := newItemDocument(item)
+	doc.SyncedAt = &syncedAt
+
+	statusCode, err := es.do(ctx, http.MethodPut, fmt.Sprintf("%s/_doc/%s", es.index, item.ID.Hex()), doc, nil)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusOK && statusCode != http.StatusCreated {
+		return fmt.Errorf("failed to index item %s: status %d", item.ID.Hex(), statusCode)
+	}
+
+	return nil
+}
+
+// DeleteSyncedBefore deletes the documents which were not synchronized since the given time, including the
+// documents which were never synchronized by ReplaceItem. It returns the number of deleted documents.
+func (es *Elasticsearch) DeleteSyncedBefore(ctx context.Context, at time.Time) (int, error) {
+	var response struct {
+		Deleted int `json:"deleted"`
+	}
+
+	query := map[string]any{
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must_not": map[string]any{"range": map[string]any{"synced_at": map[string]any{"gte": at}}},
+			},
+		},
+	}
+
+	statusCode, err := es.do(ctx, http.MethodPost, fmt.Sprintf("%s/_delete_by_query?conflicts=proceed", es.index), query, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	if statusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to delete the outdated items: status %d", statusCode)
+	}
+
+	return response.Deleted, nil
+}
+
+// DeleteItem deletes the document of the item with the given id
+func (es *Elasticsearch) DeleteItem(ctx context.Context, id primitive.ObjectID) error {
+	statusCode, err := es.do(ctx, http.MethodDelete, fmt.Sprintf("%s/_doc/%s", es.index, id.Hex()), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	// An item which was never indexed does not need to be deleted
+	if statusCode != http.StatusOK && statusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete item %s: status %d", id.Hex(), statusCode)
+	}
+
+	return nil
+}
+
+// SearchItems returns the page of items matching the given query along with the total number of matching items
+func (es *Elasticsearch) SearchItems(ctx context.Context, query Query) ([]data.Item, int, error) {
+	var response struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source itemDocument `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	statusCode, err := es.do(ctx, http.MethodPost, fmt.Sprintf("%s/_search", es.index), query.body(), &response)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to search items: status %d", statusCode)
+	}
+
+	items := []data.Item{}
+
+	for _, hit := range response.Hits.Hits {
+		item, err := hit.Source.item()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, response.Hits.Total.Value, nil
+}
+
+// do sends a request to Elasticsearch with the given JSON body and decodes the JSON response into target
+// if one is provided. It returns the status code of the response.
+func (es *Elasticsearch) do(ctx context.Context, method string, path string, body any, target any) (int, error) {
+	var requestBody io.Reader
+
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+
+		requestBody = bytes.NewReader(js)
+	}
+
+	// Only the path is escaped, the query was encoded by the caller
+	path, rawQuery, _ := strings.Cut(path, "?")
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s", es.url, (&url.URL{Path: path, RawQuery: rawQuery}).RequestURI()), requestBody)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if es.username != "" {
+		req.SetBasicAuth(es.username, es.password)
+	}
+
+	res, err := es.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	if target != nil && res.StatusCode == http.StatusOK {
+		err = json.NewDecoder(res.Body).Decode(target)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return res.StatusCode, nil
+}
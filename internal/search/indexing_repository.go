@@ -0,0 +1,196 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/database"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// syncTimeout is the maximum amount of time given to an asynchronous index synchronization
+const syncTimeout = 5 * time.Second
+
+// backfillPageSize is the number of items indexed at once when backfilling the index
+const backfillPageSize = 100
+
+// IndexingRepository is an items repository which mirrors every successful write into
+// the Elasticsearch index. MongoDB stays the source of truth, failures to update the
+// index are only logged. It is used without change stream, see StreamIndexer otherwise.
+type IndexingRepository struct {
+	data.Repository[primitive.ObjectID, data.Item]
+	index  *Elasticsearch
+	logger *logger.Logger
+}
+
+// NewIndexingRepository creates a new items repository keeping the given index in sync.
+// It is also the data.ItemsIndexer of the stores writing the items without the repository.
+func NewIndexingRepository(
+	repository data.Repository[primitive.ObjectID, data.Item],
+	index *Elasticsearch,
+	logger *logger.Logger,
+) *IndexingRepository {
+	return &IndexingRepository{
+		Repository: repository,
+		index:      index,
+		logger:     logger,
+	}
+}
+
+// Create inserts a new item and indexes it
+func (repo IndexingRepository) Create(ctx context.Context, item data.Item) (*primitive.ObjectID, error) {
+	id, err := repo.Repository.Create(ctx, item)
+	if err != nil {
+		return id, err
+	}
+
+	repo.sync(ctx, "create", *id)
+
+	return id, nil
+}
+
+// Update updates a specific item and re-indexes it
+func (repo IndexingRepository) Update(ctx context.Context, item data.Item) error {
+	err := repo.Repository.Update(ctx, item)
+	if err != nil {
+		return err
+	}
+
+	repo.sync(ctx, "update", item.ID)
+
+	return nil
+}
+
+// Delete deletes a specific item and removes it from the index
+func (repo IndexingRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	err := repo.Repository.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	repo.sync(ctx, "delete", id)
+
+	return nil
+}
+
+// Restore writes the given items and re-indexes them
+func (repo IndexingRepository) Restore(ctx context.Context, documents []data.Document[data.Item]) error {
+	err := repo.Repository.Restore(ctx, documents)
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		repo.sync(ctx, "restore", document.Entity.ID)
+	}
+
+	return nil
+}
+
+// Reindex re-indexes the items with the given ids in the background, once the write of the given context is
+// committed. The items which no longer exist are removed from the index.
+func (repo IndexingRepository) Reindex(ctx context.Context, ids ...primitive.ObjectID) {
+	for _, id := range ids {
+		repo.sync(ctx, "reindex", id)
+	}
+}
+
+// sync updates the document of the item with the given id in the background once the write of the given
+// context is committed, so that the writes of an aborted transaction are not indexed. The stored item is
+// re-read so that the index holds the dates and version set by the store.
+func (repo IndexingRepository) sync(ctx context.Context, operation string, id primitive.ObjectID) {
+	data.AfterCommit(ctx, func() {
+		go repo.syncNow(operation, id)
+	})
+}
+
+// syncNow updates the document of the item with the given id
+func (repo IndexingRepository) syncNow(operation string, id primitive.ObjectID) {
+	// Recover any panic so that a synchronization can never crash the service
+	defer func() {
+		if err := recover(); err != nil {
+			repo.logger.Error(fmt.Errorf("%s", err), nil)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	var err error
+
+	if operation == "delete" {
+		err = repo.index.DeleteItem(ctx, id)
+	} else {
+		var item data.Item
+
+		item, err = repo.Repository.GetByID(ctx, id)
+
+		switch {
+		case err == nil:
+			err = repo.index.IndexItem(ctx, item)
+		case operation == "reindex" && errors.Is(err, database.ErrRecordNotFound):
+			err = repo.index.DeleteItem(ctx, id)
+		}
+	}
+
+	if err != nil {
+		repo.logger.Error(err, map[string]string{
+			"operation": operation,
+			"item_id":   id.Hex(),
+			"store":     "elasticsearch",
+		})
+	}
+}
+
+// Backfill indexes every item of the given repository, page by page. The given progress function, when not nil,
+// is called after every page with the number of indexed items and the total number of items.
+func Backfill(ctx context.Context, repository data.Repository[primitive.ObjectID, data.Item], index *Elasticsearch, progress func(indexed int, total int)) (int, error) {
+	return backfill(ctx, repository, index.IndexItem, progress)
+}
+
+// backfill calls put with every item of the given repository, page by page
+func backfill(
+	ctx context.Context,
+	repository data.Repository[primitive.ObjectID, data.Item],
+	put func(ctx context.Context, item data.Item) error,
+	progress func(indexed int, total int),
+) (int, error) {
+	indexed := 0
+
+	for page := 1; ; page++ {
+		items, metadata, err := repository.GetAll(ctx, primitive.M{}, filters.Filters{
+			Page:         page,
+			PageSize:     backfillPageSize,
+			Sort:         "_id",
+			SortSafelist: []string{"_id"},
+		})
+		if err != nil {
+			return indexed, err
+		}
+
+		for _, item := range items {
+			err = put(ctx, item)
+			if err != nil {
+				return indexed, err
+			}
+
+			indexed++
+		}
+
+		if progress != nil {
+			progress(indexed, metadata.TotalRecords)
+		}
+
+		if len(items) < backfillPageSize {
+			return indexed, nil
+		}
+	}
+}
+
+// Ensure the indexing repository can be used in place of any items repository
+var _ data.Repository[primitive.ObjectID, data.Item] = IndexingRepository{}
@@ -0,0 +1,165 @@
+package search
+
+import (
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Common/filters"
+)
+
+// maxResultWindow is the default maximum number of hits returned by an Elasticsearch search
+const maxResultWindow = 10_000
+
+// Query is a struct that holds the parameters of an items search.
+// Zero values are ignored.
+type Query struct {
+	Tenant        string // Only the items of the tenant are searched
+	Text          string
+	MinPrice      float64
+	MaxPrice      float64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	Filters       filters.Filters
+}
+
+// sortFields maps the sort columns of the items to the fields of the index
+var sortFields = map[string]string{
+	"_id":   "id",
+	"name":  "name.keyword",
+	"price": "price",
+}
+
+// body converts the query into the body of an Elasticsearch search request
+func (query Query) body() map[string]any {
+	bool := map[string]any{
+		"must": []any{
+			map[string]any{
+				"multi_match": map[string]any{
+					"query":     query.Text,
+					"fields":    []string{"name^3", "description"},
+					"fuzziness": "AUTO",
+				},
+			},
+		},
+		// Expired items are never listed
+		"must_not": []any{
+			map[string]any{"range": map[string]any{"expires_at": map[string]any{"lte": "now"}}},
+		},
+	}
+
+	rangeFilters := []any{}
+
+	if query.Tenant != "" {
+		rangeFilters = append(rangeFilters, map[string]any{"term": map[string]any{"tenant_id": query.Tenant}})
+	}
+
+	if priceRange := numberRange(query.MinPrice, query.MaxPrice); priceRange != nil {
+		rangeFilters = append(rangeFilters, map[string]any{"range": map[string]any{"price": priceRange}})
+	}
+
+	if createdRange := dateRange(query.CreatedAfter, query.CreatedBefore); createdRange != nil {
+		rangeFilters = append(rangeFilters, map[string]any{"range": map[string]any{"created_at": createdRange}})
+	}
+
+	if updatedRange := dateRange(query.UpdatedAfter, query.UpdatedBefore); updatedRange != nil {
+		rangeFilters = append(rangeFilters, map[string]any{"range": map[string]any{"updated_at": updatedRange}})
+	}
+
+	if len(rangeFilters) != 0 {
+		bool["filter"] = rangeFilters
+	}
+
+	// A page size of 0 lists every item, as it does with MongoDB, up to the largest page Elasticsearch returns
+	size := query.Filters.Limit()
+	if size == 0 {
+		size = maxResultWindow
+	}
+
+	body := map[string]any{
+		"query":            map[string]any{"bool": bool},
+		"sort":             query.sort(),
+		"track_total_hits": true,
+		"size":             size,
+	}
+
+	if query.Filters.Offset() > 0 {
+		body["from"] = query.Filters.Offset()
+	}
+
+	return body
+}
+
+// sort converts the sort parameter of the query into an Elasticsearch sort.
+// The sort parameter must have been validated against the sort safelist beforehand.
+func (query Query) sort() []any {
+	sort := []any{}
+	sortsByID := false
+
+	for _, field := range strings.Split(query.Filters.Sort, ",") {
+		if field == data.RelevanceSort {
+			sort = append(sort, map[string]any{"_score": "desc"})
+			continue
+		}
+
+		column := strings.TrimPrefix(field, "-")
+
+		order := "asc"
+		if strings.HasPrefix(field, "-") {
+			order = "desc"
+		}
+
+		sort = append(sort, map[string]any{sortFields[column]: order})
+
+		if column == "_id" {
+			sortsByID = true
+		}
+	}
+
+	// Include a secondary sort on the id to ensure a consistent ordering
+	if !sortsByID {
+		sort = append(sort, map[string]any{"id": "asc"})
+	}
+
+	return sort
+}
+
+// numberRange returns the range matching the numbers between the given bounds (inclusive)
+func numberRange(min float64, max float64) map[string]any {
+	if min == 0 && max == 0 {
+		return nil
+	}
+
+	numberRange := map[string]any{}
+
+	if min != 0 {
+		numberRange["gte"] = min
+	}
+
+	if max != 0 {
+		numberRange["lte"] = max
+	}
+
+	return numberRange
+}
+
+// dateRange returns the range matching the dates strictly between the given bounds
+func dateRange(after time.Time, before time.Time) map[string]any {
+	if after.IsZero() && before.IsZero() {
+		return nil
+	}
+
+	dateRange := map[string]any{}
+
+	if !after.IsZero() {
+		dateRange["gt"] = after.Format(time.RFC3339Nano)
+	}
+
+	if !before.IsZero() {
+		dateRange["lt"] = before.Format(time.RFC3339Nano)
+	}
+
+	return dateRange
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Common/filters"
+)
+
+func TestQueryBody(t *testing.T) {
+	createdAfter := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		testName       string
+		query          Query
+		expectedFilter any
+		expectedSort   []any
+		expectedFrom   any
+		expectedSize   int
+	}{
+		{
+			"Search sorted by relevance",
+			Query{Text: "potoin", Filters: filters.Filters{Page: 1, PageSize: 20, Sort: "relevance"}},
+			nil,
+			[]any{map[string]any{"_score": "desc"}, map[string]any{"id": "asc"}},
+			nil,
+			20,
+		},
+		{
+			"Filtered search sorted by name",
+			Query{
+				Text:         "potoin",
+				MinPrice:     5,
+				CreatedAfter: createdAfter,
+				Filters:      filters.Filters{Page: 3, PageSize: 10, Sort: "-name,_id"},
+			},
+			[]any{
+				map[string]any{"range": map[string]any{"price": map[string]any{"gte": 5.0}}},
+				map[string]any{"range": map[string]any{"created_at": map[string]any{"gt": "2022-01-01T00:00:00Z"}}},
+			},
+			[]any{map[string]any{"name.keyword": "desc"}, map[string]any{"id": "asc"}},
+			20,
+			10,
+		},
+		{
+			"Search of a tenant",
+			Query{Tenant: "eu-1", Text: "potoin", Filters: filters.Filters{Page: 1, PageSize: 20, Sort: "relevance"}},
+			[]any{map[string]any{"term": map[string]any{"tenant_id": "eu-1"}}},
+			[]any{map[string]any{"_score": "desc"}, map[string]any{"id": "asc"}},
+			nil,
+			20,
+		},
+		{
+			"Search without page size",
+			Query{Text: "potoin", Filters: filters.Filters{Page: 1, PageSize: 0, Sort: "relevance"}},
+			nil,
+			[]any{map[string]any{"_score": "desc"}, map[string]any{"id": "asc"}},
+			nil,
+			maxResultWindow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			body := tt.query.body()
+			boolQuery := body["query"].(map[string]any)["bool"].(map[string]any)
+
+			if !reflect.DeepEqual(boolQuery["filter"], tt.expectedFilter) {
+				t.Errorf("want %v; got %v", tt.expectedFilter, boolQuery["filter"])
+			}
+
+			if boolQuery["must_not"] == nil {
+				t.Errorf("want expired items to be excluded")
+			}
+
+			if !reflect.DeepEqual(body["sort"], tt.expectedSort) {
+				t.Errorf("want %v; got %v", tt.expectedSort, body["sort"])
+			}
+
+			if !reflect.DeepEqual(body["from"], tt.expectedFrom) {
+				t.Errorf("want %v; got %v", tt.expectedFrom, body["from"])
+			}
+
+			if body["size"] != tt.expectedSize {
+				t.Errorf("want size %d; got %v", tt.expectedSize, body["size"])
+			}
+		})
+	}
+}
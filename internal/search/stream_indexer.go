@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StreamIndexer is a struct that updates the Elasticsearch index from the change stream of the items. Unlike the
+// IndexingRepository, a change whose indexing failed is retried until it succeeds, and the index also follows
+// the writes made without the service (i.e. a migration or a script).
+type StreamIndexer struct {
+	index      *Elasticsearch
+	repository data.Repository[primitive.ObjectID, data.Item]
+	now        func() time.Time
+}
+
+// NewStreamIndexer creates a new StreamIndexer rebuilding the given index from the items of the given repository
+func NewStreamIndexer(index *Elasticsearch, repository data.Repository[primitive.ObjectID, data.Item]) *StreamIndexer {
+	return &StreamIndexer{
+		index:      index,
+		repository: repository,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// PublishCreated indexes the created item
+func (indexer *StreamIndexer) PublishCreated(ctx context.Context, item data.Item, at time.Time) error {
+	return indexer.index.ReplaceItem(ctx, item, indexer.now())
+}
+
+// PublishUpdated re-indexes the updated item
+func (indexer *StreamIndexer) PublishUpdated(ctx context.Context, item data.Item, at time.Time) error {
+	return indexer.index.ReplaceItem(ctx, item, indexer.now())
+}
+
+// PublishDeleted removes the deleted item from the index
+func (indexer *StreamIndexer) PublishDeleted(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return indexer.index.DeleteItem(ctx, id)
+}
+
+// Stale creates the index if it does not exist, in which case it has to be resynchronized
+func (indexer *StreamIndexer) Stale(ctx context.Context) (bool, error) {
+	return indexer.index.EnsureIndex(ctx)
+}
+
+// Resync rebuilds the index from every item, removing the documents of the items deleted while the changes
+// were not watched
+func (indexer *StreamIndexer) Resync(ctx context.Context) error {
+	_, err := indexer.index.EnsureIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	at := indexer.now()
+
+	_, err = indexer.Backfill(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = indexer.index.DeleteSyncedBefore(ctx, at)
+
+	return err
+}
+
+// Backfill re-indexes every item, page by page, like the Backfill function. An item changed while it is
+// re-indexed may keep the document read by the backfill until its next change.
+func (indexer *StreamIndexer) Backfill(ctx context.Context, progress func(indexed int, total int)) (int, error) {
+	return backfill(ctx, indexer.repository, func(ctx context.Context, item data.Item) error {
+		return indexer.index.ReplaceItem(ctx, item, indexer.now())
+	}, progress)
+}
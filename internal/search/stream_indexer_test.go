@@ -0,0 +1,122 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/filters"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeRepository returns its items as a single page
+type fakeRepository struct {
+	data.Repository[primitive.ObjectID, data.Item]
+	items []data.Item
+}
+
+// GetAll returns the items of the first page
+func (repo fakeRepository) GetAll(ctx context.Context, filter primitive.M, findOpts filters.Filters) ([]data.Item, filters.Metadata, error) {
+	if findOpts.Page > 1 {
+		return []data.Item{}, filters.Metadata{TotalRecords: len(repo.items)}, nil
+	}
+
+	return repo.items, filters.Metadata{TotalRecords: len(repo.items)}, nil
+}
+
+// fakeElasticsearch records the requests sent to the index
+type fakeElasticsearch struct {
+	mu       sync.Mutex
+	requests []string
+	synced   map[string]time.Time
+	deleted  []byte
+}
+
+// ServeHTTP records the request and acknowledges it
+func (es *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.requests = append(es.requests, r.Method+" "+r.URL.RequestURI())
+
+	switch {
+	case strings.Contains(r.URL.Path, "/_doc/"):
+		var doc itemDocument
+
+		_ = json.NewDecoder(r.Body).Decode(&doc)
+		if doc.SyncedAt != nil {
+			es.synced[doc.ID] = *doc.SyncedAt
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasSuffix(r.URL.Path, "/_delete_by_query"):
+		var body map[string]any
+
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		es.deleted, _ = json.Marshal(body)
+
+		_, _ = w.Write([]byte(`{"deleted": 1}`))
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestStreamIndexerResync(t *testing.T) {
+	es := &fakeElasticsearch{synced: map[string]time.Time{}}
+
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	items := []data.Item{
+		{ID: primitive.NewObjectID(), Name: "Potion", Price: 5, Version: 3},
+		{ID: primitive.NewObjectID(), Name: "Ether", Price: 10, Version: 1},
+	}
+
+	at := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	indexer := NewStreamIndexer(NewElasticsearch(settings.Elasticsearch{URL: server.URL, Index: "items"}), fakeRepository{items: items})
+	indexer.now = func() time.Time { return at }
+
+	err := indexer.Resync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"HEAD /items",
+		"PUT /items/_mapping",
+		"PUT /items/_doc/" + items[0].ID.Hex(),
+		"PUT /items/_doc/" + items[1].ID.Hex(),
+		"POST /items/_delete_by_query?conflicts=proceed",
+	}
+
+	if len(es.requests) != len(want) {
+		t.Fatalf("want %d requests; got %d: %v", len(want), len(es.requests), es.requests)
+	}
+
+	// The documents are replaced without external version, so that a restored older version is indexed
+	for i := range want {
+		if es.requests[i] != want[i] {
+			t.Errorf("want %q; got %q", want[i], es.requests[i])
+		}
+	}
+
+	for _, item := range items {
+		if synced := es.synced[item.ID.Hex()]; !synced.Equal(at) {
+			t.Errorf("want item %s synced at %v; got %v", item.ID.Hex(), at, synced)
+		}
+	}
+
+	// The documents which were not replaced by the resync are deleted
+	wantQuery := `{"query":{"bool":{"must_not":{"range":{"synced_at":{"gte":"2022-03-01T12:00:00Z"}}}}}}`
+	if string(es.deleted) != wantQuery {
+		t.Errorf("want %s; got %s", wantQuery, es.deleted)
+	}
+}
@@ -0,0 +1,1220 @@
+package settings
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"github.com/knadh/koanf"
+	"github.com/knadh/koanf/parsers/json"
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
+)
+
+// Logging is a struct that holds the logging configuration. The level can be changed at runtime by the admins.
+type Logging struct {
+	Level string `koanf:"Level"` // "debug", "info" or "error"
+}
+
+// Database is a struct that holds the configuration of the MongoDB connection pool and timeouts.
+// It complements the DB section of the common configuration, which holds the connection string.
+type Database struct {
+	MaxPoolSize            int `koanf:"MaxPoolSize"`            // Maximum number of connections, 0 to use DB.MaxOpenConns
+	MinPoolSize            int `koanf:"MinPoolSize"`            // Connections kept open while idle
+	ConnectTimeout         int `koanf:"ConnectTimeout"`         // Milliseconds given to the establishment of a connection
+	SocketTimeout          int `koanf:"SocketTimeout"`          // Milliseconds given to a read or a write on a connection, 0 for no timeout
+	ServerSelectionTimeout int `koanf:"ServerSelectionTimeout"` // Milliseconds given to the selection of a server for an operation
+}
+
+// ReadPreferences is a struct that holds the read preferences of the item read endpoints (i.e. "secondaryPreferred"
+// to spread the read load across the replica set members). The other endpoints and the writes always use the primary.
+type ReadPreferences struct {
+	ListItems    string `koanf:"ListItems"`    // GET /v1/items
+	GetItem      string `koanf:"GetItem"`      // GET /v1/items/{id}
+	MaxStaleness int    `koanf:"MaxStaleness"` // Seconds a secondary may lag behind the primary, 0 for no maximum
+}
+
+// ReadPreferenceModes are the supported read preferences
+var ReadPreferenceModes = []string{"primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest"}
+
+// Transactions is a struct that holds the configuration of the MongoDB transactions wrapping the composite writes
+// (i.e. the bulk operations). Transactions require a replica set or a sharded cluster.
+type Transactions struct {
+	Enabled bool `koanf:"Enabled"`
+}
+
+// EventSourcing is a struct that holds the configuration of the event-sourced persistence of the items.
+// When enabled, every change to an item is appended to its events, from which its state can be derived at any
+// time, and the items collection is the projection of their current state. It requires the transactions.
+type EventSourcing struct {
+	Enabled          bool `koanf:"Enabled"`
+	SnapshotInterval int  `koanf:"SnapshotInterval"` // Number of events of an item between two of its snapshots
+}
+
+// SlowQueries is a struct that holds the configuration of the slow MongoDB operations logging.
+// The operations taking longer than the threshold are logged along with their filter and counted.
+type SlowQueries struct {
+	Threshold int `koanf:"Threshold"` // Milliseconds, 0 to disable the slow query logging
+}
+
+// CircuitBreaker is a struct that holds the configuration of the circuit breaker of the MongoDB operations.
+// The circuit opens when the rate of the failed or of the slow operations among the last ones reaches its
+// threshold. The operations then fail fast until a trial operation succeeds once the open duration is over.
+type CircuitBreaker struct {
+	Enabled       bool    `koanf:"Enabled"`
+	Window        int     `koanf:"Window"`        // Number of the last operations the rates are computed on
+	MinOperations int     `koanf:"MinOperations"` // Operations needed in the window before the circuit can open
+	FailureRate   float64 `koanf:"FailureRate"`   // Rate of the operations failing because MongoDB is unreachable (0 to 1)
+	SlowDuration  int     `koanf:"SlowDuration"`  // Milliseconds after which an operation is slow
+	SlowRate      float64 `koanf:"SlowRate"`      // Rate of the slow operations (0 to 1)
+	OpenDuration  int     `koanf:"OpenDuration"`  // Seconds during which the operations are rejected once the circuit is open
+}
+
+// Retries is a struct that holds the configuration of the retries of the idempotent MongoDB operations
+// (the reads and the restores) failing with a transient error, such as a network error or a primary election.
+// Every operation adds BudgetRatio tokens to a budget of at most Budget tokens and every retry takes one,
+// so that the retries cannot multiply the load of a struggling cluster.
+type Retries struct {
+	Enabled        bool    `koanf:"Enabled"`
+	MaxAttempts    int     `koanf:"MaxAttempts"`    // Attempts of an operation, including the first one
+	InitialBackoff int     `koanf:"InitialBackoff"` // Milliseconds, doubled after each attempt and jittered
+	MaxBackoff     int     `koanf:"MaxBackoff"`     // Milliseconds
+	Budget         int     `koanf:"Budget"`         // Maximum number of tokens of the retry budget
+	BudgetRatio    float64 `koanf:"BudgetRatio"`    // Tokens added by each operation
+}
+
+// Tracing is a struct that holds the Opentelemetry tracing configuration
+type Tracing struct {
+	Exporter           string  `koanf:"Exporter"` // "otlp", "jaeger" or "none"
+	Endpoint           string  `koanf:"Endpoint"`
+	Protocol           string  `koanf:"Protocol"` // "grpc" or "http" (only used by the otlp exporter)
+	Insecure           bool    `koanf:"Insecure"`
+	SampleRatio        float64 `koanf:"SampleRatio"`
+	ResourceAttributes string  `koanf:"ResourceAttributes"` // Comma separated key=value pairs
+	MongoStatements    bool    `koanf:"MongoStatements"`    // Record the MongoDB commands, including their values, on the spans
+}
+
+// Migration is a struct that holds the storage backend migration configuration.
+// In "dual-write" mode every write of the items and saved filters repositories goes to both the source
+// and the target store while reads are served by the source store and optionally compared against the
+// target store in the background. The reads cannot be cut over to the target store since the stores
+// writing the items without the repository (i.e. the ratings) only write to the source store.
+type Migration struct {
+	Mode           string `koanf:"Mode"`    // "off" or "dual-write"
+	Primary        string `koanf:"Primary"` // "source", the only supported primary store
+	TargetDsn      string `koanf:"TargetDsn"`
+	TargetDatabase string `koanf:"TargetDatabase"`
+	ShadowReads    bool   `koanf:"ShadowReads"`
+}
+
+// TaggingRule is a struct that defines a rule automatically tagging the items matching it
+// (i.e. price gt 500 -> "premium")
+type TaggingRule struct {
+	Name     string `koanf:"Name"`
+	Field    string `koanf:"Field"`    // "name", "description" or "price"
+	Operator string `koanf:"Operator"` // "eq", "contains", "gt", "gte", "lt" or "lte"
+	Value    string `koanf:"Value"`
+	Tag      string `koanf:"Tag"`
+}
+
+// Tagging is a struct that holds the auto-tagging configuration
+type Tagging struct {
+	Rules []TaggingRule `koanf:"Rules"`
+}
+
+// BodyLimits is a struct that holds the maximum request body size in bytes accepted by each group of routes
+type BodyLimits struct {
+	Default      int64 `koanf:"Default"`
+	Items        int64 `koanf:"Items"`
+	SavedFilters int64 `koanf:"SavedFilters"`
+	BulkImport   int64 `koanf:"BulkImport"`
+}
+
+// TLS is a struct that holds the configuration of the HTTPS listener, for the deployments without a terminating
+// ingress in front of the service. The certificate is read from CertFile and KeyFile or obtained from an ACME
+// certificate authority (i.e. Let's Encrypt) for the AutocertHosts with the TLS-ALPN-01 challenge.
+type TLS struct {
+	Enabled          bool     `koanf:"Enabled"`
+	CertFile         string   `koanf:"CertFile"`
+	KeyFile          string   `koanf:"KeyFile"`
+	AutocertHosts    []string `koanf:"AutocertHosts"`
+	AutocertCacheDir string   `koanf:"AutocertCacheDir"` // Directory keeping the obtained certificates across restarts
+	AutocertEmail    string   `koanf:"AutocertEmail"`    // Contact of the ACME account, optional
+	MinVersion       string   `koanf:"MinVersion"`       // "1.2" or "1.3"
+}
+
+// InternalListener is a struct that holds the configuration of the listener of the internal endpoints, which
+// requires the callers to present a client certificate signed by ClientCAFile (mutual TLS). The certificates
+// of the trusted Play services are further restricted to AllowedClients, matched against their common name
+// and their DNS names. Any certificate signed by ClientCAFile is trusted when AllowedClients is empty.
+type InternalListener struct {
+	Enabled        bool     `koanf:"Enabled"`
+	Address        string   `koanf:"Address"`
+	CertFile       string   `koanf:"CertFile"`
+	KeyFile        string   `koanf:"KeyFile"`
+	ClientCAFile   string   `koanf:"ClientCAFile"`
+	AllowedClients []string `koanf:"AllowedClients"`
+}
+
+// AdminListener is a struct that holds the configuration of the listener of the operational endpoints
+// (/admin, /debug/pprof and /metrics). When it is enabled, the main listener no longer serves them, so
+// that they are only reachable on a port which is not exposed publicly.
+type AdminListener struct {
+	Enabled bool   `koanf:"Enabled"`
+	Address string `koanf:"Address"`
+}
+
+// Server is a struct that holds the configuration of the HTTP listeners
+type Server struct {
+	HTTP2    bool             `koanf:"HTTP2"` // Negotiated with ALPN over TLS, accepted in cleartext (h2c) otherwise
+	TLS      TLS              `koanf:"TLS"`
+	Internal InternalListener `koanf:"Internal"`
+	Admin    AdminListener    `koanf:"Admin"`
+}
+
+// Compression is a struct that holds the response compression configuration
+type Compression struct {
+	Enabled bool `koanf:"Enabled"`
+	MinSize int  `koanf:"MinSize"` // Minimum response size in bytes before compressing
+	Level   int  `koanf:"Level"`   // Gzip compression level (-1 for the default level, 1 to 9 otherwise)
+}
+
+// CORS is a struct that holds the Cross-Origin Resource Sharing configuration.
+// An empty list of allowed origins disables CORS and "*" allows every origin.
+type CORS struct {
+	AllowedOrigins   []string `koanf:"AllowedOrigins"`
+	AllowedMethods   []string `koanf:"AllowedMethods"`
+	AllowedHeaders   []string `koanf:"AllowedHeaders"`
+	ExposedHeaders   []string `koanf:"ExposedHeaders"`
+	AllowCredentials bool     `koanf:"AllowCredentials"`
+	MaxAge           int      `koanf:"MaxAge"` // Seconds during which a preflight response can be cached
+}
+
+// Consumers is a struct that holds the RabbitMQ consumers configuration. The messages whose handler panicked or
+// failed with a transient error are retried after a delay doubled after each retry.
+type Consumers struct {
+	MaxRetries        int `koanf:"MaxRetries"`        // Number of times a message is retried before being dead lettered
+	InitialRetryDelay int `koanf:"InitialRetryDelay"` // Milliseconds before the first retry, 0 to retry immediately
+	MaxRetryDelay     int `koanf:"MaxRetryDelay"`     // Milliseconds
+}
+
+// Elasticsearch is a struct that holds the configuration of the Elasticsearch (or OpenSearch)
+// cluster mirroring the catalog items
+type Elasticsearch struct {
+	URL      string `koanf:"URL"`
+	Index    string `koanf:"Index"`
+	Username string `koanf:"Username"` // Leave empty to disable basic authentication
+	Password string `koanf:"Password"`
+}
+
+// Search is a struct that holds the configuration of the backend used by the items name search.
+// The "atlas" and "elasticsearch" backends fall back to the classic "text" index when they
+// are not available.
+type Search struct {
+	Backend       string        `koanf:"Backend"`  // "text", "atlas" or "elasticsearch"
+	Index         string        `koanf:"Index"`    // Name of the Atlas Search index
+	MaxEdits      int           `koanf:"MaxEdits"` // Number of typos tolerated per word by the fuzzy matching (0 to 2)
+	Elasticsearch Elasticsearch `koanf:"Elasticsearch"`
+
+	FailureBackoff int `koanf:"FailureBackoff"` // Seconds during which the searches skip a backend which failed, 0 to try it on every search
+}
+
+// HTMLNameRegex is a regular expression used for checking the format of the tag and attribute names (i.e. "blockquote")
+var HTMLNameRegex = regexp.MustCompile("^[a-z][a-z0-9-]*$")
+
+// Sanitization is a struct that holds the policy sanitizing the item descriptions before they are stored, as well as
+// the HTML rendered from them. The "basic" policy keeps the allowed tags and attributes, the "strict" policy removes
+// every tag and the "none" policy keeps them verbatim. Scripts and similar tags are always removed along with their content.
+type Sanitization struct {
+	Policy            string   `koanf:"Policy"`            // "basic", "strict" or "none"
+	AllowedTags       []string `koanf:"AllowedTags"`       // Tags kept by the basic policy (i.e. "p")
+	AllowedAttributes []string `koanf:"AllowedAttributes"` // Attributes kept on the allowed tags (i.e. "href")
+	AllowedSchemes    []string `koanf:"AllowedSchemes"`    // Schemes of the URLs allowed in the href and src attributes
+}
+
+// BannedWordRegex is a regular expression used for checking that the banned words are single words (i.e. "scam")
+var BannedWordRegex = regexp.MustCompile(`^[\p{L}\p{N}]+$`)
+
+// Moderation is a struct that holds the words the names and descriptions of the items, along with the comments
+// of the reviews, must not contain. The "reject" action fails the validation of the items and reviews containing
+// them while the "review" action stores the items flagged for review and the reviews pending.
+type Moderation struct {
+	BannedWords []string `koanf:"BannedWords"` // Matched as whole words regardless of the case
+	Action      string   `koanf:"Action"`      // "reject" or "review"
+}
+
+// Pricing is a struct that holds the rules the prices of the items must follow.
+// They are enforced by the API and by the validation schema of the items collection.
+type Pricing struct {
+	MinPrice    float64 `koanf:"MinPrice"`
+	MaxPrice    float64 `koanf:"MaxPrice"`
+	MaxDecimals int     `koanf:"MaxDecimals"` // Maximum number of decimal places of a price
+}
+
+// Constraints is a struct that holds the uniqueness constraints of the items.
+// The unique indexes of the items collection are reconciled with them on startup.
+type Constraints struct {
+	UniqueFields []string `koanf:"UniqueFields"` // "name" and/or "description"
+}
+
+// Expiration is a struct that holds the configuration of the expiring items.
+// Expired items are hidden from the listings and an ItemExpired event is published for each of them.
+type Expiration struct {
+	CheckInterval int  `koanf:"CheckInterval"` // Seconds between two checks for expired items
+	Purge         bool `koanf:"Purge"`         // Delete the expired items with a TTL index
+	PurgeDelay    int  `koanf:"PurgeDelay"`    // Seconds during which an expired item is kept before being deleted
+}
+
+// PopularityRetentionDays is the number of days during which the daily popularity counts of the items are kept
+const PopularityRetentionDays = 30
+
+// Popularity is a struct that holds the configuration of the popularity tracking of the items.
+// Views and purchases are counted in memory and flushed to the database in batches.
+type Popularity struct {
+	FlushInterval  int  `koanf:"FlushInterval"`  // Seconds between two flushes of the counters
+	TrackPurchases bool `koanf:"TrackPurchases"` // Count the purchases of the Trading events
+	TrendingDays   int  `koanf:"TrendingDays"`   // Default number of days covered by the trending items
+}
+
+// Inventory is a struct that holds the configuration of the client of the Inventory microservice,
+// which provides the stock of the items
+type Inventory struct {
+	URL              string `koanf:"URL"`              // Leave empty to disable the stock expansion
+	Timeout          int    `koanf:"Timeout"`          // Milliseconds given to a request
+	CacheTTL         int    `koanf:"CacheTTL"`         // Seconds during which the stock of an item is cached
+	FailureThreshold int    `koanf:"FailureThreshold"` // Consecutive failures opening the circuit breaker
+	OpenDuration     int    `koanf:"OpenDuration"`     // Seconds during which requests are rejected once the circuit is open
+}
+
+// Identity is a struct that holds the configuration of the client of the Identity microservice,
+// from which the users are resynchronized
+type Identity struct {
+	URL      string `koanf:"URL"`      // Leave empty to disable the resynchronization of the users
+	Token    string `koanf:"Token"`    // Bearer token authenticating the catalog, if required
+	Timeout  int    `koanf:"Timeout"`  // Milliseconds given to a request
+	PageSize int    `koanf:"PageSize"` // Users retrieved per request
+}
+
+// S3 is a struct that holds the configuration of an Amazon S3 bucket or of an S3 compatible storage
+type S3 struct {
+	Endpoint        string `koanf:"Endpoint"` // URL of an S3 compatible storage using path-style URLs (i.e. "http://localhost:9000" for MinIO), AWS if empty
+	Region          string `koanf:"Region"`
+	Bucket          string `koanf:"Bucket"`
+	AccessKeyID     string `koanf:"AccessKeyID"`
+	SecretAccessKey string `koanf:"SecretAccessKey"`
+}
+
+// AzureBlob is a struct that holds the configuration of an Azure Blob Storage container
+type AzureBlob struct {
+	Endpoint   string `koanf:"Endpoint"` // URL of the account (i.e. "http://127.0.0.1:10000/devstoreaccount1" for Azurite), Azure if empty
+	Account    string `koanf:"Account"`
+	AccountKey string `koanf:"AccountKey"` // Base64 encoded key of the account
+	Container  string `koanf:"Container"`
+}
+
+// Thumbnails is a struct that holds the configuration of the thumbnails generated from the images of the items.
+// The sizes are the maximum width and height of the thumbnails, which keep the aspect ratio of the images.
+type Thumbnails struct {
+	Workers    int `koanf:"Workers"`   // Number of images processed at once
+	QueueSize  int `koanf:"QueueSize"` // Images waiting for a worker, the further images are processed on the next start
+	SmallSize  int `koanf:"SmallSize"`
+	MediumSize int `koanf:"MediumSize"`
+}
+
+// ImageContentTypes are the content types of the images which can be uploaded, the ones whose thumbnails can be generated
+var ImageContentTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// Images is a struct that holds the configuration of the storage of the images of the items.
+// Clients upload the images directly to the object storage with presigned URLs.
+type Images struct {
+	Backend          string     `koanf:"Backend"`          // "s3" or "azure", leave empty to disable the images
+	PublicURL        string     `koanf:"PublicURL"`        // Base URL of the CDN serving the images, the storage URLs are returned if empty
+	UploadExpiration int        `koanf:"UploadExpiration"` // Seconds during which an upload URL can be used
+	MaxSize          int64      `koanf:"MaxSize"`          // Maximum size of an image in bytes
+	ContentTypes     []string   `koanf:"ContentTypes"`     // Accepted content types of the images, among ImageContentTypes
+	MaxImages        int        `koanf:"MaxImages"`        // Maximum number of images of an item
+	Thumbnails       Thumbnails `koanf:"Thumbnails"`
+	S3               S3         `koanf:"S3"`
+	Azure            AzureBlob  `koanf:"Azure"`
+}
+
+// Events is a struct that holds the configuration of the published events
+type Events struct {
+	// Versions in which the events are published (i.e. "item.created.v1"). Several versions of an event can be
+	// published during a transition. Events which are not listed are published in their latest version.
+	Versions []string `koanf:"Versions"`
+	// Whether the published events are wrapped in a CloudEvents 1.0 envelope
+	CloudEvents bool `koanf:"CloudEvents"`
+}
+
+// Kafka is a struct that holds the configuration of the Kafka REST Proxy the events are published to.
+// Events are published to one topic per aggregate (i.e. "Play.Catalog.item") keyed by the ID of the aggregate.
+type Kafka struct {
+	URL         string `koanf:"URL"`         // URL of the REST Proxy (API v3)
+	ClusterID   string `koanf:"ClusterID"`   // ID of the Kafka cluster
+	TopicPrefix string `koanf:"TopicPrefix"` // Prefix of the name of the topics
+	Timeout     int    `koanf:"Timeout"`     // Milliseconds given to a request
+}
+
+// NATS is a struct that holds the configuration of the NATS JetStream broker. The events are published
+// to the subjects of their routes (i.e. "Play.Catalog.item-expired") which are captured by the stream
+// of the service, and consumed with durable pull consumers.
+type NATS struct {
+	URL        string `koanf:"URL"`
+	Stream     string `koanf:"Stream"`     // Name of the stream of the published events
+	MaxDeliver int    `koanf:"MaxDeliver"` // Maximum number of deliveries of a message
+	Backoff    []int  `koanf:"Backoff"`    // Seconds before each redelivery of a message which failed to be processed
+}
+
+// RabbitMQ is a struct that holds the configuration of the RabbitMQ broker, whose address is part of the
+// common configuration. The connection is re-established when the broker closes it (i.e. when it restarts),
+// along with the channels of the consumers and of the publisher.
+type RabbitMQ struct {
+	InitialBackoff   int   `koanf:"InitialBackoff"`   // Milliseconds before reconnecting, doubled after each failed attempt and jittered
+	MaxBackoff       int   `koanf:"MaxBackoff"`       // Milliseconds
+	Queues           Queue `koanf:"Queues"`           // Queues of the consumers
+	DeadLetterQueues Queue `koanf:"DeadLetterQueues"` // Queues the messages rejected by the consumers are published to
+}
+
+// Queue is a struct that holds the options of the RabbitMQ queues declared by the service.
+// A queue declared with other options must be deleted before the service declares it again.
+type Queue struct {
+	Type               string `koanf:"Type"`               // "classic" or "quorum", which is durable and replicated
+	MessageTTL         int    `koanf:"MessageTTL"`         // Milliseconds before a message is dead lettered, 0 to keep it
+	MaxLength          int    `koanf:"MaxLength"`          // Maximum number of messages, the oldest ones being dead lettered, 0 for no limit
+	DeadLetterExchange string `koanf:"DeadLetterExchange"` // Exchange receiving the dead lettered messages instead of the default one
+}
+
+// MessageBroker is a struct that holds the configuration of the broker the events are exchanged through.
+// With Kafka, the consumed events are still received from RabbitMQ.
+type MessageBroker struct {
+	Type     string   `koanf:"Type"` // "rabbitmq", "kafka" or "nats"
+	RabbitMQ RabbitMQ `koanf:"RabbitMQ"`
+	Kafka    Kafka    `koanf:"Kafka"`
+	NATS     NATS     `koanf:"NATS"`
+}
+
+// Outbox is a struct that holds the configuration of the relay of the outbox. The events stored in the
+// outbox are published in batches to the message broker.
+type Outbox struct {
+	Interval      int `koanf:"Interval"`      // Seconds between two checks for pending events
+	BatchSize     int `koanf:"BatchSize"`     // Maximum number of events published at once
+	LockDuration  int `koanf:"LockDuration"`  // Seconds during which the events claimed by a relay are hidden from the others
+	RetryInterval int `koanf:"RetryInterval"` // Seconds before an event which failed to be published is retried
+	MaxAttempts   int `koanf:"MaxAttempts"`   // Attempts after which an event is dead lettered, 0 to retry it forever
+
+	HistoryRetention int `koanf:"HistoryRetention"` // Seconds the published events are kept to be replayed, 0 to delete them
+	MaxReplayEvents  int `koanf:"MaxReplayEvents"`  // Maximum number of events replayed at once
+}
+
+// ChangeStream is a struct that holds the configuration of the change stream watching the items collection.
+// The changes are converted into item created, updated and deleted events stored in the outbox. A single
+// instance of the service watches the collection at a time, as long as it renews its lease. Change streams
+// require a replica set or a sharded cluster.
+type ChangeStream struct {
+	Enabled       bool `koanf:"Enabled"`
+	LeaseDuration int  `koanf:"LeaseDuration"` // Seconds after which another instance takes over the watch if the lease is not renewed
+	RetryInterval int  `koanf:"RetryInterval"` // Seconds before the watch is resumed after a failure or before trying to acquire the lease again
+}
+
+// ReadModel is a struct that holds the configuration of the read model of the listings. When enabled, the listings
+// of the items are served from a denormalized collection updated from a change stream of the items, which uses the
+// lease duration and the retry interval of the change stream settings.
+type ReadModel struct {
+	Enabled bool `koanf:"Enabled"`
+}
+
+// TenantRegex is a regular expression used for checking the format of the tenants (i.e. "eu-shard-1")
+var TenantRegex = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$")
+
+// Tenancy is a struct that holds the configuration of the tenants sharing the catalog.
+// Every item belongs to the tenant of the access token it was created with.
+type Tenancy struct {
+	Claim         string `koanf:"Claim"`         // Claim of the access tokens holding the tenant
+	Header        string `koanf:"Header"`        // Header of the requests targeting a tenant, rejected when it is not the tenant of the token
+	DefaultTenant string `koanf:"DefaultTenant"` // Tenant of the access tokens without tenant claim and of the existing items
+}
+
+// Authorization is a struct that holds the configuration of the authorization of the changes to the items.
+// In "owner" mode, catalog:write users can only update and delete the items they created while catalog:admin
+// users can change every item. In "permission" mode, the catalog:write permission is enough.
+type Authorization struct {
+	Mode string `koanf:"Mode"` // "permission" or "owner"
+}
+
+// UserCache is a struct that holds the configuration of the in-memory cache of the users authenticating the
+// requests. The cached users are invalidated by the user updated and deleted events received by the instance.
+type UserCache struct {
+	TTL        int `koanf:"TTL"`        // Seconds during which a user is cached, 0 to disable the cache
+	MaxEntries int `koanf:"MaxEntries"` // Maximum number of cached users
+}
+
+// PublicCatalog is a struct that holds the configuration of the public catalog. When enabled, the items
+// can be listed and retrieved without authentication, i.e. by a marketing site, with their public fields only.
+// The anonymous requests are rate limited per client.
+type PublicCatalog struct {
+	Enabled        bool     `koanf:"Enabled"`
+	Tenant         string   `koanf:"Tenant"`         // Tenant whose items are public, the default tenant if empty
+	Fields         []string `koanf:"Fields"`         // Item fields returned to the anonymous requests
+	RateLimit      float64  `koanf:"RateLimit"`      // Requests per second allowed per client
+	Burst          int      `koanf:"Burst"`          // Requests a client can send at once
+	ClientIPHeader string   `koanf:"ClientIPHeader"` // Header holding the client address set by the proxy (i.e. "X-Forwarded-For"), the remote address is used if empty
+}
+
+// Jobs is a struct that holds the configuration of the workers running the long operations (i.e. the reindex of the
+// search index) in the background. The jobs are stored in MongoDB so that their status can be retrieved from every
+// instance, and a job whose worker stops renewing its lease (i.e. the instance was stopped) is marked as failed.
+type Jobs struct {
+	Workers       int `koanf:"Workers"`       // Number of jobs run at once by the instance
+	PollInterval  int `koanf:"PollInterval"`  // Milliseconds between two checks for queued jobs and two progress updates
+	LeaseDuration int `koanf:"LeaseDuration"` // Seconds after which a running job whose lease is not renewed is marked as failed
+	Retention     int `koanf:"Retention"`     // Seconds the finished jobs are kept
+}
+
+// Scheduler is a struct that holds the configuration of the recurring maintenance tasks run by the service.
+// Each run of a task happens on the single instance which locks it, except the tasks of the in-memory state
+// of the instances (i.e. the caches) which run on every instance. A task is disabled when its interval is 0.
+type Scheduler struct {
+	CheckInterval int `koanf:"CheckInterval"` // Seconds between two checks for due tasks
+	LockDuration  int `koanf:"LockDuration"`  // Seconds after which a run is canceled and its lock released, i.e. when its instance stopped
+
+	CacheEvictionInterval    int `koanf:"CacheEvictionInterval"`    // Seconds between two evictions of the expired entries of the caches
+	OutboxCompactionInterval int `koanf:"OutboxCompactionInterval"` // Seconds between two compactions of the outbox collections, disabled by default
+}
+
+// Retention is a struct that holds the retention windows of the history of the items, after which it is purged
+// by the "retention.purge" scheduled task. A retention of 0 keeps the history forever.
+type Retention struct {
+	PurgeInterval int `koanf:"PurgeInterval"` // Seconds between two purges, 0 to disable them
+	Revisions     int `koanf:"Revisions"`     // Seconds the revisions of the items are kept to be rolled back to
+}
+
+// Administration is a struct that holds the configuration of the catalog:admin operations
+type Administration struct {
+	MaxBulkItems  int  `koanf:"MaxBulkItems"`  // Maximum number of items changed by a bulk delete or a price adjustment
+	MaxImportRows int  `koanf:"MaxImportRows"` // Maximum number of items of a bulk upsert run as a background job
+	Maintenance   bool `koanf:"Maintenance"`   // Enable the maintenance mode on startup, where the items can only be changed by the bulk operations
+	// Seconds between two reads of the maintenance mode shared by the instances
+	MaintenancePollInterval int `koanf:"MaintenancePollInterval"`
+}
+
+// Settings is a struct that holds the configuration specific to the Catalog microservice.
+// It complements the common configuration which is shared between all microservices.
+type Settings struct {
+	Logging         Logging         `koanf:"Logging"`
+	Database        Database        `koanf:"Database"`
+	ReadPreferences ReadPreferences `koanf:"ReadPreferences"`
+	Transactions    Transactions    `koanf:"Transactions"`
+	EventSourcing   EventSourcing   `koanf:"EventSourcing"`
+	SlowQueries     SlowQueries     `koanf:"SlowQueries"`
+	CircuitBreaker  CircuitBreaker  `koanf:"CircuitBreaker"`
+	Retries         Retries         `koanf:"Retries"`
+	Tracing         Tracing         `koanf:"Tracing"`
+	Migration       Migration       `koanf:"Migration"`
+	Tagging         Tagging         `koanf:"Tagging"`
+	Server          Server          `koanf:"Server"`
+	BodyLimits      BodyLimits      `koanf:"BodyLimits"`
+	Compression     Compression     `koanf:"Compression"`
+	CORS            CORS            `koanf:"CORS"`
+	Consumers       Consumers       `koanf:"Consumers"`
+	Search          Search          `koanf:"Search"`
+	Pricing         Pricing         `koanf:"Pricing"`
+	Sanitization    Sanitization    `koanf:"Sanitization"`
+	Moderation      Moderation      `koanf:"Moderation"`
+	Constraints     Constraints     `koanf:"Constraints"`
+	Expiration      Expiration      `koanf:"Expiration"`
+	Popularity      Popularity      `koanf:"Popularity"`
+	Inventory       Inventory       `koanf:"Inventory"`
+	Identity        Identity        `koanf:"Identity"`
+	Images          Images          `koanf:"Images"`
+	Events          Events          `koanf:"Events"`
+
+	MessageBroker MessageBroker `koanf:"MessageBroker"`
+	Outbox        Outbox        `koanf:"Outbox"`
+	ChangeStream  ChangeStream  `koanf:"ChangeStream"`
+	ReadModel     ReadModel     `koanf:"ReadModel"`
+	Tenancy       Tenancy       `koanf:"Tenancy"`
+	Authorization Authorization `koanf:"Authorization"`
+	UserCache     UserCache     `koanf:"UserCache"`
+	PublicCatalog PublicCatalog `koanf:"PublicCatalog"`
+	Jobs          Jobs          `koanf:"Jobs"`
+	Scheduler     Scheduler     `koanf:"Scheduler"`
+	Retention     Retention     `koanf:"Retention"`
+
+	Administration Administration `koanf:"Administration"`
+}
+
+// LoadSettings reads the Catalog settings from a given file and from environment variables
+// (i.e. Tracing__Endpoint=...).
+func LoadSettings(filePath string) (*Settings, error) {
+	settings := Settings{
+		Logging: Logging{
+			Level: "info",
+		},
+		Database: Database{
+			ConnectTimeout:         10_000,
+			ServerSelectionTimeout: 10_000,
+		},
+		ReadPreferences: ReadPreferences{
+			ListItems: "primary",
+			GetItem:   "primary",
+		},
+		EventSourcing: EventSourcing{
+			SnapshotInterval: 100,
+		},
+		SlowQueries: SlowQueries{
+			Threshold: 100,
+		},
+		CircuitBreaker: CircuitBreaker{
+			Enabled:       true,
+			Window:        100,
+			MinOperations: 20,
+			FailureRate:   0.5,
+			SlowDuration:  2_000,
+			SlowRate:      0.8,
+			OpenDuration:  10,
+		},
+		Retries: Retries{
+			Enabled:        true,
+			MaxAttempts:    3,
+			InitialBackoff: 50,
+			MaxBackoff:     1_000,
+			Budget:         20,
+			BudgetRatio:    0.1,
+		},
+		Tracing: Tracing{
+			Exporter:    "jaeger",
+			Endpoint:    "http://localhost:14268/api/traces",
+			Protocol:    "grpc",
+			SampleRatio: 1,
+		},
+		Migration: Migration{
+			Mode:    "off",
+			Primary: "source",
+		},
+		BodyLimits: BodyLimits{
+			Default:      1_048_576,
+			Items:        8_192,
+			SavedFilters: 8_192,
+			BulkImport:   52_428_800,
+		},
+		Server: Server{
+			HTTP2: true,
+			TLS: TLS{
+				AutocertCacheDir: "certs",
+				MinVersion:       "1.2",
+			},
+			Internal: InternalListener{
+				Address: ":4443",
+			},
+			Admin: AdminListener{
+				Address: ":4445",
+			},
+		},
+		Compression: Compression{
+			Enabled: true,
+			MinSize: 1_024,
+			Level:   -1,
+		},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Tenant-ID"},
+			ExposedHeaders: []string{"Location"},
+			MaxAge:         600,
+		},
+		Consumers: Consumers{
+			MaxRetries:        3,
+			InitialRetryDelay: 1_000,
+			MaxRetryDelay:     60_000,
+		},
+		Search: Search{
+			Backend:  "text",
+			Index:    "default",
+			MaxEdits: 1,
+			Elasticsearch: Elasticsearch{
+				URL:   "http://localhost:9200",
+				Index: "catalog-items",
+			},
+
+			FailureBackoff: 30,
+		},
+		Pricing: Pricing{
+			MinPrice:    0.1,
+			MaxPrice:    1000,
+			MaxDecimals: 2,
+		},
+		Sanitization: Sanitization{
+			Policy:            "basic",
+			AllowedTags:       []string{"p", "br", "h1", "h2", "h3", "h4", "h5", "h6", "b", "strong", "i", "em", "u", "ul", "ol", "li", "blockquote", "code", "pre", "a"},
+			AllowedAttributes: []string{"href", "title"},
+			AllowedSchemes:    []string{"http", "https", "mailto"},
+		},
+		Moderation: Moderation{
+			BannedWords: []string{},
+			Action:      "reject",
+		},
+		Constraints: Constraints{
+			UniqueFields: []string{"name"},
+		},
+		Expiration: Expiration{
+			CheckInterval: 60,
+			PurgeDelay:    3_600,
+		},
+		Popularity: Popularity{
+			FlushInterval: 10,
+			TrendingDays:  7,
+		},
+		Inventory: Inventory{
+			Timeout:          500,
+			CacheTTL:         5,
+			FailureThreshold: 5,
+			OpenDuration:     30,
+		},
+		Identity: Identity{
+			Timeout:  5_000,
+			PageSize: 100,
+		},
+		Images: Images{
+			UploadExpiration: 900,
+			MaxSize:          5 << 20,
+			ContentTypes:     []string{"image/jpeg", "image/png", "image/gif"},
+			MaxImages:        10,
+			Thumbnails: Thumbnails{
+				Workers:    2,
+				QueueSize:  100,
+				SmallSize:  128,
+				MediumSize: 512,
+			},
+			S3: S3{
+				Region: "us-east-1",
+			},
+		},
+		Events: Events{
+			Versions:    []string{"item.created.v1", "item.created.v2"},
+			CloudEvents: true,
+		},
+		MessageBroker: MessageBroker{
+			Type: "rabbitmq",
+			RabbitMQ: RabbitMQ{
+				InitialBackoff: 500,
+				MaxBackoff:     30_000,
+				Queues: Queue{
+					Type: "classic",
+				},
+				DeadLetterQueues: Queue{
+					Type: "classic",
+				},
+			},
+			Kafka: Kafka{
+				URL:         "http://localhost:8082",
+				TopicPrefix: "Play.Catalog.",
+				Timeout:     3_000,
+			},
+			NATS: NATS{
+				URL:        "nats://localhost:4222",
+				Stream:     "PLAY_CATALOG",
+				MaxDeliver: 4,
+				Backoff:    []int{1, 5, 30},
+			},
+		},
+		Outbox: Outbox{
+			Interval:      1,
+			BatchSize:     100,
+			LockDuration:  30,
+			RetryInterval: 10,
+			MaxAttempts:   360,
+
+			HistoryRetention: 604_800,
+			MaxReplayEvents:  10_000,
+		},
+		ChangeStream: ChangeStream{
+			LeaseDuration: 30,
+			RetryInterval: 5,
+		},
+		Tenancy: Tenancy{
+			Claim:         "tenant",
+			Header:        "X-Tenant-ID",
+			DefaultTenant: "default",
+		},
+		Authorization: Authorization{
+			Mode: "permission",
+		},
+		UserCache: UserCache{
+			TTL:        30,
+			MaxEntries: 10_000,
+		},
+		PublicCatalog: PublicCatalog{
+			Fields:    []string{"id", "name", "description", "price", "tags", "images"},
+			RateLimit: 2,
+			Burst:     10,
+		},
+		Jobs: Jobs{
+			Workers:       2,
+			PollInterval:  1_000,
+			LeaseDuration: 60,
+			Retention:     604_800,
+		},
+		Scheduler: Scheduler{
+			CheckInterval:            10,
+			LockDuration:             600,
+			CacheEvictionInterval:    300,
+			OutboxCompactionInterval: 0,
+		},
+		Retention: Retention{
+			PurgeInterval: 3_600,
+		},
+		Administration: Administration{
+			MaxBulkItems:            500,
+			MaxImportRows:           100_000,
+			MaintenancePollInterval: 5,
+		},
+	}
+
+	configReader := koanf.New(".")
+
+	// Load JSON config
+	if err := configReader.Load(file.Provider(filePath), json.Parser()); err != nil {
+		return nil, err
+	}
+
+	// Load environment variables and merge into the loaded config
+	configReader.Load(
+		env.Provider(
+			"",
+			"__",
+			nil,
+		),
+		nil,
+	)
+
+	err := configReader.Unmarshal("", &settings)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.CORS.AllowCredentials && validator.In("*", settings.CORS.AllowedOrigins...) {
+		return nil, errors.New("CORS credentials cannot be allowed for every origin")
+	}
+
+	if !validator.In(settings.Search.Backend, "text", "atlas", "elasticsearch") {
+		return nil, fmt.Errorf("invalid search backend %q", settings.Search.Backend)
+	}
+
+	if !validator.Between(settings.Search.MaxEdits, 0, 2) {
+		return nil, fmt.Errorf("invalid search max edits %d", settings.Search.MaxEdits)
+	}
+
+	if settings.Search.FailureBackoff < 0 {
+		return nil, fmt.Errorf("invalid search failure backoff %d", settings.Search.FailureBackoff)
+	}
+
+	if settings.Pricing.MinPrice <= 0 || settings.Pricing.MaxPrice < settings.Pricing.MinPrice {
+		return nil, fmt.Errorf("invalid price range %g-%g", settings.Pricing.MinPrice, settings.Pricing.MaxPrice)
+	}
+
+	if !validator.Between(settings.Pricing.MaxDecimals, 0, 6) {
+		return nil, fmt.Errorf("invalid price max decimals %d", settings.Pricing.MaxDecimals)
+	}
+
+	if !validator.In(settings.Sanitization.Policy, "basic", "strict", "none") {
+		return nil, fmt.Errorf("invalid sanitization policy %q", settings.Sanitization.Policy)
+	}
+
+	// Tag and attribute names are compared in lower case
+	for _, names := range [][]string{settings.Sanitization.AllowedTags, settings.Sanitization.AllowedAttributes} {
+		for _, name := range names {
+			if !validator.Matches(name, HTMLNameRegex) {
+				return nil, fmt.Errorf("invalid sanitization tag or attribute %q", name)
+			}
+		}
+	}
+
+	if !validator.In(settings.Moderation.Action, "reject", "review") {
+		return nil, fmt.Errorf("invalid moderation action %q", settings.Moderation.Action)
+	}
+
+	for _, word := range settings.Moderation.BannedWords {
+		if !validator.Matches(word, BannedWordRegex) {
+			return nil, fmt.Errorf("invalid banned word %q", word)
+		}
+	}
+
+	if !validator.AllIn(settings.Constraints.UniqueFields, "name", "description") {
+		return nil, fmt.Errorf("invalid unique fields %v", settings.Constraints.UniqueFields)
+	}
+
+	if settings.Expiration.CheckInterval < 1 {
+		return nil, fmt.Errorf("invalid expiration check interval %d", settings.Expiration.CheckInterval)
+	}
+
+	// The events of the expired items must be published before they are deleted
+	if settings.Expiration.Purge && settings.Expiration.PurgeDelay < settings.Expiration.CheckInterval {
+		return nil, fmt.Errorf("expiration purge delay %d must not be lower than the check interval", settings.Expiration.PurgeDelay)
+	}
+
+	if settings.Popularity.FlushInterval < 1 {
+		return nil, fmt.Errorf("invalid popularity flush interval %d", settings.Popularity.FlushInterval)
+	}
+
+	// Daily counts are only kept for a limited period
+	if !validator.Between(settings.Popularity.TrendingDays, 1, PopularityRetentionDays) {
+		return nil, fmt.Errorf("invalid popularity trending days %d", settings.Popularity.TrendingDays)
+	}
+
+	if settings.Inventory.Timeout < 1 || settings.Inventory.CacheTTL < 0 {
+		return nil, fmt.Errorf("invalid inventory timeout %d or cache TTL %d", settings.Inventory.Timeout, settings.Inventory.CacheTTL)
+	}
+
+	if settings.Inventory.FailureThreshold < 1 || settings.Inventory.OpenDuration < 1 {
+		return nil, fmt.Errorf(
+			"invalid inventory failure threshold %d or open duration %d",
+			settings.Inventory.FailureThreshold,
+			settings.Inventory.OpenDuration,
+		)
+	}
+
+	if settings.Identity.Timeout < 1 || !validator.Between(settings.Identity.PageSize, 1, 1_000) {
+		return nil, fmt.Errorf("invalid identity timeout %d or page size %d", settings.Identity.Timeout, settings.Identity.PageSize)
+	}
+
+	images := settings.Images
+
+	switch images.Backend {
+	case "":
+	case "s3":
+		if images.S3.Region == "" || images.S3.Bucket == "" || images.S3.AccessKeyID == "" || images.S3.SecretAccessKey == "" {
+			return nil, errors.New("invalid images S3 configuration, the region, bucket and credentials are required")
+		}
+	case "azure":
+		_, keyErr := base64.StdEncoding.DecodeString(images.Azure.AccountKey)
+		if images.Azure.Account == "" || images.Azure.Container == "" || images.Azure.AccountKey == "" || keyErr != nil {
+			return nil, errors.New("invalid images Azure configuration, the account, base64 account key and container are required")
+		}
+	default:
+		return nil, fmt.Errorf("invalid images backend %q", images.Backend)
+	}
+
+	// Presigned S3 URLs are valid for 7 days at most
+	if images.UploadExpiration < 1 || images.UploadExpiration > 7*24*60*60 {
+		return nil, fmt.Errorf("invalid images upload expiration %d", images.UploadExpiration)
+	}
+
+	if images.MaxSize < 1 || images.MaxImages < 1 {
+		return nil, fmt.Errorf("invalid images max size %d or max images %d", images.MaxSize, images.MaxImages)
+	}
+
+	if len(images.ContentTypes) == 0 || !validator.NoDuplicates(images.ContentTypes) {
+		return nil, fmt.Errorf("invalid images content types %v", images.ContentTypes)
+	}
+
+	for _, contentType := range images.ContentTypes {
+		if !validator.In(contentType, ImageContentTypes...) {
+			return nil, fmt.Errorf("unsupported images content type %q, must be one of %v", contentType, ImageContentTypes)
+		}
+	}
+
+	if images.Thumbnails.Workers < 1 || images.Thumbnails.QueueSize < 1 {
+		return nil, fmt.Errorf("invalid thumbnails workers %d or queue size %d", images.Thumbnails.Workers, images.Thumbnails.QueueSize)
+	}
+
+	if images.Thumbnails.SmallSize < 1 || images.Thumbnails.MediumSize <= images.Thumbnails.SmallSize {
+		return nil, fmt.Errorf(
+			"invalid thumbnails small size %d or medium size %d",
+			images.Thumbnails.SmallSize,
+			images.Thumbnails.MediumSize,
+		)
+	}
+
+	if !validator.In(settings.MessageBroker.Type, "rabbitmq", "kafka", "nats") {
+		return nil, fmt.Errorf("invalid message broker %q", settings.MessageBroker.Type)
+	}
+
+	if settings.MessageBroker.RabbitMQ.InitialBackoff < 1 || settings.MessageBroker.RabbitMQ.MaxBackoff < settings.MessageBroker.RabbitMQ.InitialBackoff {
+		return nil, fmt.Errorf(
+			"invalid rabbitmq initial backoff %d or max backoff %d",
+			settings.MessageBroker.RabbitMQ.InitialBackoff,
+			settings.MessageBroker.RabbitMQ.MaxBackoff,
+		)
+	}
+
+	if settings.Consumers.MaxRetries < 0 || settings.Consumers.InitialRetryDelay < 0 || settings.Consumers.MaxRetryDelay < settings.Consumers.InitialRetryDelay {
+		return nil, fmt.Errorf(
+			"invalid consumers max retries %d, initial retry delay %d or max retry delay %d",
+			settings.Consumers.MaxRetries,
+			settings.Consumers.InitialRetryDelay,
+			settings.Consumers.MaxRetryDelay,
+		)
+	}
+
+	queues := []struct {
+		name  string
+		queue Queue
+	}{
+		{"queues", settings.MessageBroker.RabbitMQ.Queues},
+		{"dead letter queues", settings.MessageBroker.RabbitMQ.DeadLetterQueues},
+	}
+
+	for _, q := range queues {
+		if !validator.In(q.queue.Type, "classic", "quorum") || q.queue.MessageTTL < 0 || q.queue.MaxLength < 0 {
+			return nil, fmt.Errorf(
+				"invalid rabbitmq %s type %q, message TTL %d or max length %d",
+				q.name,
+				q.queue.Type,
+				q.queue.MessageTTL,
+				q.queue.MaxLength,
+			)
+		}
+	}
+
+	if settings.MessageBroker.Type == "kafka" && (settings.MessageBroker.Kafka.ClusterID == "" || settings.MessageBroker.Kafka.Timeout < 1) {
+		return nil, fmt.Errorf(
+			"invalid kafka cluster ID %q or timeout %d",
+			settings.MessageBroker.Kafka.ClusterID,
+			settings.MessageBroker.Kafka.Timeout,
+		)
+	}
+
+	// JetStream redelivers a message at most once per backoff duration
+	if settings.MessageBroker.Type == "nats" && (settings.MessageBroker.NATS.Stream == "" ||
+		len(settings.MessageBroker.NATS.Backoff) == 0 ||
+		settings.MessageBroker.NATS.MaxDeliver <= len(settings.MessageBroker.NATS.Backoff)) {
+		return nil, fmt.Errorf(
+			"invalid nats stream %q, max deliver %d or backoff %v",
+			settings.MessageBroker.NATS.Stream,
+			settings.MessageBroker.NATS.MaxDeliver,
+			settings.MessageBroker.NATS.Backoff,
+		)
+	}
+
+	if settings.Outbox.Interval < 1 || !validator.Between(settings.Outbox.BatchSize, 1, 1_000) {
+		return nil, fmt.Errorf("invalid outbox interval %d or batch size %d", settings.Outbox.Interval, settings.Outbox.BatchSize)
+	}
+
+	// Claimed events must be published before they can be claimed by another relay
+	if settings.Outbox.LockDuration < 1 || settings.Outbox.RetryInterval < 1 {
+		return nil, fmt.Errorf(
+			"invalid outbox lock duration %d or retry interval %d",
+			settings.Outbox.LockDuration,
+			settings.Outbox.RetryInterval,
+		)
+	}
+
+	if settings.Outbox.MaxAttempts < 0 {
+		return nil, fmt.Errorf("invalid outbox max attempts %d", settings.Outbox.MaxAttempts)
+	}
+
+	if settings.Outbox.HistoryRetention < 0 || settings.Outbox.MaxReplayEvents < 1 {
+		return nil, fmt.Errorf(
+			"invalid outbox history retention %d or max replay events %d",
+			settings.Outbox.HistoryRetention,
+			settings.Outbox.MaxReplayEvents,
+		)
+	}
+
+	// The lease must be renewed, which happens at least every second, before it expires
+	if settings.ChangeStream.LeaseDuration < 2 || settings.ChangeStream.RetryInterval < 1 {
+		return nil, fmt.Errorf(
+			"invalid change stream lease duration %d or retry interval %d",
+			settings.ChangeStream.LeaseDuration,
+			settings.ChangeStream.RetryInterval,
+		)
+	}
+
+	// The lease is renewed along with the progress of the job, so it must last longer than the poll interval
+	if !validator.Between(settings.Jobs.Workers, 1, 32) || settings.Jobs.PollInterval < 1 ||
+		settings.Jobs.LeaseDuration*1_000 <= settings.Jobs.PollInterval || settings.Jobs.Retention < 1 {
+		return nil, fmt.Errorf(
+			"invalid jobs workers %d, poll interval %d, lease duration %d or retention %d",
+			settings.Jobs.Workers,
+			settings.Jobs.PollInterval,
+			settings.Jobs.LeaseDuration,
+			settings.Jobs.Retention,
+		)
+	}
+
+	// The runs are canceled once their lock expired, so the lock must last at least until the next check
+	if settings.Scheduler.CheckInterval < 1 || settings.Scheduler.LockDuration < settings.Scheduler.CheckInterval ||
+		settings.Scheduler.CacheEvictionInterval < 0 || settings.Scheduler.OutboxCompactionInterval < 0 {
+		return nil, fmt.Errorf(
+			"invalid scheduler check interval %d, lock duration %d, cache eviction interval %d or outbox compaction interval %d",
+			settings.Scheduler.CheckInterval,
+			settings.Scheduler.LockDuration,
+			settings.Scheduler.CacheEvictionInterval,
+			settings.Scheduler.OutboxCompactionInterval,
+		)
+	}
+
+	if settings.Retention.PurgeInterval < 0 || settings.Retention.Revisions < 0 {
+		return nil, fmt.Errorf("invalid retention purge interval %d or revisions retention %d", settings.Retention.PurgeInterval, settings.Retention.Revisions)
+	}
+
+	if settings.Tenancy.Claim == "" || settings.Tenancy.Header == "" || !validator.Matches(settings.Tenancy.DefaultTenant, TenantRegex) {
+		return nil, fmt.Errorf(
+			"invalid tenancy claim %q, header %q or default tenant %q",
+			settings.Tenancy.Claim,
+			settings.Tenancy.Header,
+			settings.Tenancy.DefaultTenant,
+		)
+	}
+
+	if !validator.In(settings.Logging.Level, "debug", "info", "error") {
+		return nil, fmt.Errorf("invalid log level %q", settings.Logging.Level)
+	}
+
+	if settings.Database.MaxPoolSize < 0 || settings.Database.MinPoolSize < 0 ||
+		(settings.Database.MaxPoolSize != 0 && settings.Database.MinPoolSize > settings.Database.MaxPoolSize) {
+		return nil, fmt.Errorf(
+			"invalid database pool size %d-%d",
+			settings.Database.MinPoolSize,
+			settings.Database.MaxPoolSize,
+		)
+	}
+
+	if settings.Database.ConnectTimeout < 1 || settings.Database.SocketTimeout < 0 || settings.Database.ServerSelectionTimeout < 1 {
+		return nil, fmt.Errorf(
+			"invalid database connect timeout %d, socket timeout %d or server selection timeout %d",
+			settings.Database.ConnectTimeout,
+			settings.Database.SocketTimeout,
+			settings.Database.ServerSelectionTimeout,
+		)
+	}
+
+	// The ratings, popularity counts, thumbnails, expiration flags and erasures are only written to the source store
+	if settings.Migration.Primary != "source" {
+		return nil, fmt.Errorf("invalid migration primary %q, the reads cannot be cut over to the target store", settings.Migration.Primary)
+	}
+
+	// The sessions of the transactions can't be used by the client of the other store
+	if settings.Transactions.Enabled && settings.Migration.Mode == "dual-write" {
+		return nil, errors.New("transactions cannot be enabled in dual-write mode")
+	}
+
+	// The events of an item are appended along with the write of its projection
+	if settings.EventSourcing.Enabled && !settings.Transactions.Enabled {
+		return nil, errors.New("event sourcing requires the transactions to be enabled")
+	}
+
+	if settings.EventSourcing.SnapshotInterval < 1 {
+		return nil, fmt.Errorf("invalid event sourcing snapshot interval %d", settings.EventSourcing.SnapshotInterval)
+	}
+
+	// MongoDB requires a maximum staleness of at least 90 seconds
+	if !validator.In(settings.ReadPreferences.ListItems, ReadPreferenceModes...) ||
+		!validator.In(settings.ReadPreferences.GetItem, ReadPreferenceModes...) ||
+		(settings.ReadPreferences.MaxStaleness != 0 && settings.ReadPreferences.MaxStaleness < 90) {
+		return nil, fmt.Errorf(
+			"invalid read preferences %q and %q or max staleness %d",
+			settings.ReadPreferences.ListItems,
+			settings.ReadPreferences.GetItem,
+			settings.ReadPreferences.MaxStaleness,
+		)
+	}
+
+	if settings.SlowQueries.Threshold < 0 {
+		return nil, fmt.Errorf("invalid slow query threshold %d", settings.SlowQueries.Threshold)
+	}
+
+	if settings.CircuitBreaker.Enabled && (!validator.Between(settings.CircuitBreaker.MinOperations, 1, settings.CircuitBreaker.Window) ||
+		settings.CircuitBreaker.SlowDuration < 1 ||
+		settings.CircuitBreaker.OpenDuration < 1) {
+		return nil, fmt.Errorf(
+			"invalid circuit breaker window %d, min operations %d, slow duration %d or open duration %d",
+			settings.CircuitBreaker.Window,
+			settings.CircuitBreaker.MinOperations,
+			settings.CircuitBreaker.SlowDuration,
+			settings.CircuitBreaker.OpenDuration,
+		)
+	}
+
+	if settings.CircuitBreaker.Enabled && (settings.CircuitBreaker.FailureRate <= 0 || settings.CircuitBreaker.FailureRate > 1 ||
+		settings.CircuitBreaker.SlowRate <= 0 || settings.CircuitBreaker.SlowRate > 1) {
+		return nil, fmt.Errorf(
+			"invalid circuit breaker failure rate %g or slow rate %g",
+			settings.CircuitBreaker.FailureRate,
+			settings.CircuitBreaker.SlowRate,
+		)
+	}
+
+	if settings.Retries.Enabled && (settings.Retries.MaxAttempts < 1 ||
+		settings.Retries.InitialBackoff < 1 ||
+		settings.Retries.MaxBackoff < settings.Retries.InitialBackoff ||
+		settings.Retries.Budget < 1 ||
+		settings.Retries.BudgetRatio <= 0) {
+		return nil, fmt.Errorf(
+			"invalid retries max attempts %d, backoff %d-%d, budget %d or budget ratio %g",
+			settings.Retries.MaxAttempts,
+			settings.Retries.InitialBackoff,
+			settings.Retries.MaxBackoff,
+			settings.Retries.Budget,
+			settings.Retries.BudgetRatio,
+		)
+	}
+
+	if !validator.In(settings.Authorization.Mode, "permission", "owner") {
+		return nil, fmt.Errorf("invalid authorization mode %q", settings.Authorization.Mode)
+	}
+
+	if settings.UserCache.TTL < 0 || settings.UserCache.MaxEntries < 1 {
+		return nil, fmt.Errorf("invalid user cache TTL %d or max entries %d", settings.UserCache.TTL, settings.UserCache.MaxEntries)
+	}
+
+	if settings.PublicCatalog.Tenant != "" && !validator.Matches(settings.PublicCatalog.Tenant, TenantRegex) {
+		return nil, fmt.Errorf("invalid public catalog tenant %q", settings.PublicCatalog.Tenant)
+	}
+
+	// The names of the fields are checked against the item fields on startup
+	if len(settings.PublicCatalog.Fields) == 0 || !validator.NoDuplicates(settings.PublicCatalog.Fields) {
+		return nil, fmt.Errorf("invalid public catalog fields %v", settings.PublicCatalog.Fields)
+	}
+
+	if settings.PublicCatalog.RateLimit <= 0 || settings.PublicCatalog.Burst < 1 {
+		return nil, fmt.Errorf(
+			"invalid public catalog rate limit %g or burst %d",
+			settings.PublicCatalog.RateLimit,
+			settings.PublicCatalog.Burst,
+		)
+	}
+
+	if !validator.Between(settings.Administration.MaxBulkItems, 1, 10_000) {
+		return nil, fmt.Errorf("invalid administration max bulk items %d", settings.Administration.MaxBulkItems)
+	}
+
+	if settings.Administration.MaxImportRows < settings.Administration.MaxBulkItems {
+		return nil, fmt.Errorf("invalid administration max import rows %d", settings.Administration.MaxImportRows)
+	}
+
+	if settings.Administration.MaintenancePollInterval < 1 {
+		return nil, fmt.Errorf("invalid administration maintenance poll interval %d", settings.Administration.MaintenancePollInterval)
+	}
+
+	if settings.Compression.Level < gzip.HuffmanOnly || settings.Compression.Level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid compression level %d", settings.Compression.Level)
+	}
+
+	if tls := settings.Server.TLS; tls.Enabled {
+		certFiles := tls.CertFile != "" && tls.KeyFile != ""
+
+		if certFiles == (len(tls.AutocertHosts) != 0) || (!certFiles && (tls.CertFile != "" || tls.KeyFile != "")) {
+			return nil, errors.New("invalid TLS configuration, either the certificate and key files or the autocert hosts are required")
+		}
+
+		if !validator.In(tls.MinVersion, "1.2", "1.3") {
+			return nil, fmt.Errorf("invalid TLS min version %q", tls.MinVersion)
+		}
+	}
+
+	if internal := settings.Server.Internal; internal.Enabled {
+		if internal.Address == "" || internal.CertFile == "" || internal.KeyFile == "" || internal.ClientCAFile == "" {
+			return nil, errors.New("invalid internal listener configuration, the address, the certificate, key and client CA files are required")
+		}
+	}
+
+	if admin := settings.Server.Admin; admin.Enabled && admin.Address == "" {
+		return nil, errors.New("invalid admin listener configuration, the address is required")
+	}
+
+	return &settings, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// sasVersion is the version of the Azure Storage service used to sign the shared access signatures
+const sasVersion = "2020-12-06"
+
+// azure is a struct that signs the URLs of the blobs of an Azure Blob Storage container with service shared access signatures
+type azure struct {
+	endpoint   string
+	account    string
+	accountKey []byte
+	container  string
+}
+
+// newAzure returns the signer of the configured container
+func newAzure(cfg settings.AzureBlob) (*azure, error) {
+	accountKey, err := base64.StdEncoding.DecodeString(cfg.AccountKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure account key: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.Account)
+	}
+
+	return &azure{
+		endpoint:   endpoint,
+		account:    cfg.Account,
+		accountKey: accountKey,
+		container:  cfg.Container,
+	}, nil
+}
+
+// presignUpload returns the URL of a Put Blob request, allowed to create and write the blob
+func (signer *azure) presignUpload(key string, contentType string, now time.Time, expiration time.Duration) (string, map[string]string) {
+	headers := map[string]string{
+		"x-ms-blob-type":          "BlockBlob",
+		"x-ms-blob-content-type":  contentType,
+		"x-ms-blob-cache-control": cacheControl,
+	}
+
+	return signer.presign(key, "cw", now.Add(expiration)), headers
+}
+
+// presignRead returns the URL of a Get Blob or Get Blob Properties request, allowed to read the blob
+func (signer *azure) presignRead(method string, key string, now time.Time, expiration time.Duration) string {
+	return signer.presign(key, "r", now.Add(expiration))
+}
+
+// presignDelete returns the URL of a Delete Blob request, allowed to delete the blob
+func (signer *azure) presignDelete(key string, now time.Time, expiration time.Duration) string {
+	return signer.presign(key, "d", now.Add(expiration))
+}
+
+// objectURL returns the URL of the blob with the given key
+func (signer *azure) objectURL(key string) string {
+	return fmt.Sprintf("%s/%s/%s", signer.endpoint, signer.container, uriEncode(key, false))
+}
+
+// presign returns the URL of the blob with the given key along with a service shared access signature
+// granting the given permissions until the given time
+func (signer *azure) presign(key string, permissions string, expiresAt time.Time) string {
+	expiry := expiresAt.UTC().Format("2006-01-02T15:04:05Z")
+
+	// The unused fields of the signature are left empty
+	stringToSign := strings.Join([]string{
+		permissions,
+		"", // Start time
+		expiry,
+		fmt.Sprintf("/blob/%s/%s/%s", signer.account, signer.container, key),
+		"", // Stored access policy
+		"", // IP range
+		"", // Protocol
+		sasVersion,
+		"b", // Blob resource
+		"",  // Snapshot time
+		"",  // Encryption scope
+		"",  // Cache-Control override
+		"",  // Content-Disposition override
+		"",  // Content-Encoding override
+		"",  // Content-Language override
+		"",  // Content-Type override
+	}, "\n")
+
+	query := url.Values{
+		"sv":  {sasVersion},
+		"sr":  {"b"},
+		"sp":  {permissions},
+		"se":  {expiry},
+		"sig": {base64.StdEncoding.EncodeToString(hmacSHA256(signer.accountKey, stringToSign))},
+	}
+
+	return signer.objectURL(key) + "?" + query.Encode()
+}
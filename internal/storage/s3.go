@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// s3 is a struct that signs the URLs of the objects of an Amazon S3 bucket with the AWS Signature Version 4
+type s3 struct {
+	scheme          string
+	host            string
+	pathPrefix      string // Bucket of the path-style URLs
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+}
+
+// newS3 returns the signer of the configured bucket. Custom endpoints use path-style URLs.
+func newS3(cfg settings.S3) *s3 {
+	signer := &s3{
+		scheme:          "https",
+		host:            fmt.Sprintf("%s.s3.%s.amazonaws.com", cfg.Bucket, cfg.Region),
+		region:          cfg.Region,
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+	}
+
+	if cfg.Endpoint != "" {
+		scheme, host, _ := strings.Cut(strings.TrimSuffix(cfg.Endpoint, "/"), "://")
+		signer.scheme = scheme
+		signer.host = host
+		signer.pathPrefix = "/" + cfg.Bucket
+	}
+
+	return signer
+}
+
+// presignUpload returns the URL of a PUT request whose content type and caching policy are signed
+func (signer *s3) presignUpload(key string, contentType string, now time.Time, expiration time.Duration) (string, map[string]string) {
+	headers := map[string]string{
+		"Cache-Control": cacheControl,
+		"Content-Type":  contentType,
+	}
+
+	return signer.presign(http.MethodPut, key, headers, now, expiration), headers
+}
+
+// presignRead returns the URL of a GET or HEAD request
+func (signer *s3) presignRead(method string, key string, now time.Time, expiration time.Duration) string {
+	return signer.presign(method, key, nil, now, expiration)
+}
+
+// presignDelete returns the URL of a DELETE request
+func (signer *s3) presignDelete(key string, now time.Time, expiration time.Duration) string {
+	return signer.presign(http.MethodDelete, key, nil, now, expiration)
+}
+
+// objectURL returns the URL of the object with the given key
+func (signer *s3) objectURL(key string) string {
+	return fmt.Sprintf("%s://%s%s/%s", signer.scheme, signer.host, signer.pathPrefix, uriEncode(key, false))
+}
+
+// presign returns the URL authorizing the given request with the query string parameters of the AWS Signature Version 4.
+// The payload is not signed since it is sent by the client.
+func (signer *s3) presign(method string, key string, headers map[string]string, now time.Time, expiration time.Duration) string {
+	date := now.Format("20060102")
+	timestamp := now.Format("20060102T150405Z")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, signer.region)
+
+	// Signed headers must be sent by the client with the same values
+	canonicalHeaders := map[string]string{"host": signer.host}
+	for name, value := range headers {
+		canonicalHeaders[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+
+	names := make([]string, 0, len(canonicalHeaders))
+	for name := range canonicalHeaders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var headersBuilder strings.Builder
+	for _, name := range names {
+		headersBuilder.WriteString(name + ":" + canonicalHeaders[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	query := canonicalQuery(map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    signer.accessKeyID + "/" + scope,
+		"X-Amz-Date":          timestamp,
+		"X-Amz-Expires":       strconv.Itoa(int(expiration.Seconds())),
+		"X-Amz-SignedHeaders": signedHeaders,
+	})
+
+	path := signer.pathPrefix + "/" + uriEncode(key, false)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		query,
+		headersBuilder.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", timestamp, scope, hex.EncodeToString(hashedRequest[:])}, "\n")
+
+	// The signing key is derived from the secret key for the date, region and service of the request
+	signingKey := hmacSHA256([]byte("AWS4"+signer.secretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, signer.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", signer.scheme, signer.host, path, query, signature)
+}
+
+// canonicalQuery returns the given query string parameters encoded and sorted by name
+func canonicalQuery(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(params[name], true))
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the given data with the given key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// requestTimeout is the maximum amount of time given to a request sent to the object storage
+const requestTimeout = 5 * time.Second
+
+// readExpiration is the time during which the URLs used by the service to read and write objects are valid
+const readExpiration = time.Minute
+
+// cacheControl is the caching policy of the uploaded objects. Every upload has its own key
+// so that the objects never change and can be cached forever by the CDN and the browsers.
+const cacheControl = "public, max-age=31536000, immutable"
+
+// ErrObjectNotFound is returned when an object does not exist in the object storage
+var ErrObjectNotFound = errors.New("object not found")
+
+// backend is implemented by the object storages which sign the URLs of their objects
+type backend interface {
+	// presignUpload returns the URL uploading an object with the given key until the given time,
+	// along with the headers the upload must send
+	presignUpload(key string, contentType string, now time.Time, expiration time.Duration) (string, map[string]string)
+	// presignRead returns the URL reading the object with the given key, or its properties with the HEAD method,
+	// until the given time
+	presignRead(method string, key string, now time.Time, expiration time.Duration) string
+	// presignDelete returns the URL deleting the object with the given key until the given time
+	presignDelete(key string, now time.Time, expiration time.Duration) string
+	// objectURL returns the unsigned URL of the object with the given key
+	objectURL(key string) string
+}
+
+// Upload is a struct that defines the request a client must send to upload an object
+type Upload struct {
+	Key       string            `json:"key"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Object is a struct that defines the properties of an uploaded object
+type Object struct {
+	Size        int64
+	ContentType string
+}
+
+// Storage is a struct that issues presigned URLs so that clients upload objects directly to
+// an Amazon S3 bucket or an Azure Blob Storage container, and reads the properties of the uploaded objects
+type Storage struct {
+	backend          backend
+	client           *http.Client
+	publicURL        string
+	uploadExpiration time.Duration
+	now              func() time.Time
+}
+
+// New creates a new Storage for the configured backend
+func New(cfg settings.Images) (*Storage, error) {
+	var b backend
+
+	switch cfg.Backend {
+	case "s3":
+		b = newS3(cfg.S3)
+	case "azure":
+		azure, err := newAzure(cfg.Azure)
+		if err != nil {
+			return nil, err
+		}
+
+		b = azure
+	default:
+		return nil, fmt.Errorf("unknown images backend %q", cfg.Backend)
+	}
+
+	return &Storage{
+		backend:          b,
+		client:           &http.Client{Timeout: requestTimeout},
+		publicURL:        strings.TrimSuffix(cfg.PublicURL, "/"),
+		uploadExpiration: time.Duration(cfg.UploadExpiration) * time.Second,
+		now:              time.Now,
+	}, nil
+}
+
+// PresignUpload returns the request uploading an object of the given content type with the given key
+func (storage *Storage) PresignUpload(key string, contentType string) Upload {
+	now := storage.now().UTC()
+	url, headers := storage.backend.presignUpload(key, contentType, now, storage.uploadExpiration)
+
+	return Upload{
+		Key:       key,
+		Method:    http.MethodPut,
+		URL:       url,
+		Headers:   headers,
+		ExpiresAt: now.Add(storage.uploadExpiration),
+	}
+}
+
+// Stat returns the properties of the object with the given key.
+// ErrObjectNotFound is returned when the object was not uploaded.
+func (storage *Storage) Stat(ctx context.Context, key string) (Object, error) {
+	url := storage.backend.presignRead(http.MethodHead, key, storage.now().UTC(), readExpiration)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return Object{}, err
+	}
+
+	res, err := storage.client.Do(req)
+	if err != nil {
+		return Object{}, err
+	}
+
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return Object{}, ErrObjectNotFound
+	case res.StatusCode != http.StatusOK:
+		return Object{}, fmt.Errorf("object storage responded with status %d", res.StatusCode)
+	}
+
+	size, err := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return Object{}, fmt.Errorf("invalid object size: %w", err)
+	}
+
+	return Object{Size: size, ContentType: res.Header.Get("Content-Type")}, nil
+}
+
+// Get returns the content of the object with the given key, which must be closed by the caller.
+// ErrObjectNotFound is returned when the object does not exist.
+func (storage *Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	url := storage.backend.presignRead(http.MethodGet, key, storage.now().UTC(), readExpiration)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := storage.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrObjectNotFound
+	case res.StatusCode != http.StatusOK:
+		res.Body.Close()
+		return nil, fmt.Errorf("object storage responded with status %d", res.StatusCode)
+	}
+
+	return res.Body, nil
+}
+
+// Put uploads the given content as the object with the given key, with the same headers as the uploads of the clients
+func (storage *Storage) Put(ctx context.Context, key string, contentType string, content []byte) error {
+	url, headers := storage.backend.presignUpload(key, contentType, storage.now().UTC(), readExpiration)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := storage.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
+		return fmt.Errorf("object storage responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// Delete removes the object with the given key. Deleting an object which does not exist succeeds.
+func (storage *Storage) Delete(ctx context.Context, key string) error {
+	url := storage.backend.presignDelete(key, storage.now().UTC(), readExpiration)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := storage.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("object storage responded with status %d", res.StatusCode)
+	}
+}
+
+// URL returns the URL serving the object with the given key, which is the URL of the CDN when configured
+func (storage *Storage) URL(key string) string {
+	if storage.publicURL == "" {
+		return storage.backend.objectURL(key)
+	}
+
+	return storage.publicURL + "/" + uriEncode(key, false)
+}
+
+// uriEncode encodes the given string as required by the signatures, with every character
+// but the unreserved characters of RFC 3986 escaped. Slashes are kept unless requested otherwise.
+func uriEncode(s string, encodeSlash bool) string {
+	var builder strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			builder.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			builder.WriteByte(c)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,157 @@
+package tagging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+// rule is a compiled auto-tagging rule
+type rule struct {
+	settings.TaggingRule
+	number float64 // Parsed value of numeric rules
+}
+
+// Engine is a struct that applies auto-tagging rules to items
+type Engine struct {
+	rules []rule
+}
+
+// NewEngine compiles the given auto-tagging rules into a new Engine.
+// It returns an error if one of the rules is invalid.
+func NewEngine(rules []settings.TaggingRule) (*Engine, error) {
+	engine := &Engine{}
+
+	for _, r := range rules {
+		if r.Name == "" || r.Tag == "" {
+			return nil, fmt.Errorf("tagging rule %q must have a name and a tag", r.Name)
+		}
+
+		compiled := rule{TaggingRule: r}
+
+		switch r.Field {
+		case "name", "description":
+			if r.Operator != "eq" && r.Operator != "contains" {
+				return nil, fmt.Errorf("tagging rule %q has unsupported operator %q for field %q", r.Name, r.Operator, r.Field)
+			}
+
+			compiled.Value = strings.ToLower(r.Value)
+		case "price":
+			number, err := strconv.ParseFloat(r.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("tagging rule %q must have a numeric value", r.Name)
+			}
+
+			switch r.Operator {
+			case "eq", "gt", "gte", "lt", "lte":
+				compiled.number = number
+			default:
+				return nil, fmt.Errorf("tagging rule %q has unsupported operator %q for field %q", r.Name, r.Operator, r.Field)
+			}
+		default:
+			return nil, fmt.Errorf("tagging rule %q has unsupported field %q", r.Name, r.Field)
+		}
+
+		engine.rules = append(engine.rules, compiled)
+	}
+
+	return engine, nil
+}
+
+// Apply evaluates the rules against the given item and returns the item with its auto tags
+// recomputed. Tags previously added by a rule which no longer matches are removed while
+// manually added tags are kept untouched. A rule whose tag was added manually does not record
+// an auto tag, so that the tag is kept once the rule no longer matches. Auto tags are only added
+// while the item has less than data.MaxTags tags, so that the tags validated before the rules are
+// applied stay valid. The tags supplied by a request are manual tags: their handlers reset the
+// auto tags of the item along with its tags (see SetManualTags).
+func (e *Engine) Apply(item data.Item) data.Item {
+	// Separate manual tags from the tags previously added by rules
+	previousAutoTags := make(map[string]bool)
+	for _, autoTag := range item.AutoTags {
+		previousAutoTags[autoTag.Tag] = true
+	}
+
+	tags := []string{}
+	manual := make(map[string]bool)
+
+	for _, tag := range item.Tags {
+		if previousAutoTags[tag] || manual[tag] {
+			continue
+		}
+
+		tags = append(tags, tag)
+		manual[tag] = true
+	}
+
+	// Evaluate rules
+	autoTags := []data.AutoTag{}
+	seen := make(map[string]bool)
+
+	for _, r := range e.rules {
+		if manual[r.Tag] || !r.matches(item) || (!seen[r.Tag] && len(tags) >= data.MaxTags) {
+			continue
+		}
+
+		autoTags = append(autoTags, data.AutoTag{Tag: r.Tag, Rule: r.Name})
+
+		if !seen[r.Tag] {
+			tags = append(tags, r.Tag)
+			seen[r.Tag] = true
+		}
+	}
+
+	item.Tags = tags
+	item.AutoTags = autoTags
+
+	return item
+}
+
+// SetManualTags returns the given item with the given tags supplied by a user, which replace its tags.
+// Its auto tags are reset so that none of the supplied tags is removed once its rule no longer matches,
+// the rules adding their tags again when the engine is applied.
+func SetManualTags(item data.Item, tags []string) data.Item {
+	item.Tags = tags
+	item.AutoTags = nil
+
+	return item
+}
+
+// matches returns true if the item satisfies the rule
+func (r rule) matches(item data.Item) bool {
+	switch r.Field {
+	case "name":
+		return r.matchesText(item.Name)
+	case "description":
+		return r.matchesText(item.Description)
+	case "price":
+		switch r.Operator {
+		case "eq":
+			return item.Price == r.number
+		case "gt":
+			return item.Price > r.number
+		case "gte":
+			return item.Price >= r.number
+		case "lt":
+			return item.Price < r.number
+		case "lte":
+			return item.Price <= r.number
+		}
+	}
+
+	return false
+}
+
+// matchesText compares a text field with the rule value ignoring case
+func (r rule) matchesText(value string) bool {
+	value = strings.ToLower(value)
+
+	if r.Operator == "eq" {
+		return value == r.Value
+	}
+
+	return strings.Contains(value, r.Value)
+}
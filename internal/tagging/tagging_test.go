@@ -0,0 +1,116 @@
+package tagging
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+)
+
+var rules = []settings.TaggingRule{
+	{Name: "premium-price", Field: "price", Operator: "gt", Value: "500", Tag: "premium"},
+	{Name: "potion-consumable", Field: "name", Operator: "contains", Value: "potion", Tag: "consumable"},
+}
+
+func TestNewEngine(t *testing.T) {
+	tests := []struct {
+		testName   string
+		rule       settings.TaggingRule
+		wantsError bool
+	}{
+		{"Valid text rule", settings.TaggingRule{Name: "rule", Field: "name", Operator: "contains", Value: "potion", Tag: "tag"}, false},
+		{"Valid price rule", settings.TaggingRule{Name: "rule", Field: "price", Operator: "lte", Value: "5", Tag: "tag"}, false},
+		{"Missing tag", settings.TaggingRule{Name: "rule", Field: "name", Operator: "contains", Value: "potion"}, true},
+		{"Unsupported field", settings.TaggingRule{Name: "rule", Field: "version", Operator: "eq", Value: "1", Tag: "tag"}, true},
+		{"Unsupported text operator", settings.TaggingRule{Name: "rule", Field: "name", Operator: "gt", Value: "potion", Tag: "tag"}, true},
+		{"Non numeric price value", settings.TaggingRule{Name: "rule", Field: "price", Operator: "gt", Value: "cheap", Tag: "tag"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			_, err := NewEngine([]settings.TaggingRule{tt.rule})
+
+			if (err != nil) != tt.wantsError {
+				t.Errorf("want error %t; got %v", tt.wantsError, err)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	engine, err := NewEngine(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// repriced returns the given item with the given price, i.e. once updated
+	repriced := func(item data.Item, price float64) data.Item {
+		item.Price = price
+		return item
+	}
+
+	tests := []struct {
+		testName         string
+		item             data.Item
+		expectedTags     []string
+		expectedAutoTags []data.AutoTag
+	}{
+		{
+			"No matching rule",
+			data.Item{Name: "Ether", Price: 3},
+			[]string{},
+			[]data.AutoTag{},
+		},
+		{
+			"Matching rules",
+			data.Item{Name: "Mega Potion", Price: 600, Tags: []string{"healing"}},
+			[]string{"healing", "premium", "consumable"},
+			[]data.AutoTag{{Tag: "premium", Rule: "premium-price"}, {Tag: "consumable", Rule: "potion-consumable"}},
+		},
+		{
+			"Stale auto tag is removed",
+			data.Item{Name: "Mega Potion", Price: 10, Tags: []string{"healing", "premium", "consumable"}, AutoTags: []data.AutoTag{{Tag: "premium", Rule: "premium-price"}, {Tag: "consumable", Rule: "potion-consumable"}}},
+			[]string{"healing", "consumable"},
+			[]data.AutoTag{{Tag: "consumable", Rule: "potion-consumable"}},
+		},
+		{
+			"Manual tag matching an auto tag is not duplicated",
+			data.Item{Name: "Potion", Price: 5, Tags: []string{"consumable"}},
+			[]string{"consumable"},
+			[]data.AutoTag{},
+		},
+		{
+			"Manual tag equal to a rule tag survives the rule ceasing to match",
+			repriced(engine.Apply(data.Item{Name: "Mega Potion", Price: 600, Tags: []string{"premium"}}), 10),
+			[]string{"premium", "consumable"},
+			[]data.AutoTag{{Tag: "consumable", Rule: "potion-consumable"}},
+		},
+		{
+			"Supplied tag equal to a stale auto tag is kept",
+			SetManualTags(data.Item{Name: "Mega Potion", Price: 10, Tags: []string{"premium"}, AutoTags: []data.AutoTag{{Tag: "premium", Rule: "premium-price"}}}, []string{"premium"}),
+			[]string{"premium", "consumable"},
+			[]data.AutoTag{{Tag: "consumable", Rule: "potion-consumable"}},
+		},
+		{
+			"Auto tags capped by the tags limit",
+			data.Item{Name: "Mega Potion", Price: 600, Tags: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19"}},
+			[]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19", "premium"},
+			[]data.AutoTag{{Tag: "premium", Rule: "premium-price"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			item := engine.Apply(tt.item)
+
+			if !reflect.DeepEqual(item.Tags, tt.expectedTags) {
+				t.Errorf("want %v; got %v", tt.expectedTags, item.Tags)
+			}
+
+			if !reflect.DeepEqual(item.AutoTags, tt.expectedAutoTags) {
+				t.Errorf("want %v; got %v", tt.expectedAutoTags, item.AutoTags)
+			}
+		})
+	}
+}
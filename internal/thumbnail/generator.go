@@ -0,0 +1,238 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sync"
+	"time"
+
+	// Register the GIF decoder
+	_ "image/gif"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"github.com/PlayEconomy37/Play.Common/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Names of the sizes of the thumbnails
+const (
+	Small  = "small"
+	Medium = "medium"
+)
+
+// generateTimeout is the maximum amount of time given to generate the thumbnails of an image
+const generateTimeout = 30 * time.Second
+
+// maxPixels is the maximum number of pixels of the images whose thumbnails are generated,
+// which bounds the memory used to decode an image
+const maxPixels = 25_000_000
+
+// jpegQuality is the quality of the JPEG thumbnails
+const jpegQuality = 85
+
+// pendingImagesLimit is the maximum number of items whose pending images are queued on startup
+const pendingImagesLimit = 1000
+
+// SupportedContentTypes are the content types of the images whose thumbnails can be generated, which are the only
+// ones accepted by the uploads. The thumbnails of the images uploaded before are not generated.
+var SupportedContentTypes = settings.ImageContentTypes
+
+// objectStorage is implemented by the object storages holding the images
+type objectStorage interface {
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Put(ctx context.Context, key string, contentType string, content []byte) error
+	URL(key string) string
+}
+
+// thumbnailsStore is implemented by the stores recording the thumbnails of the images
+type thumbnailsStore interface {
+	SetThumbnails(ctx context.Context, itemID primitive.ObjectID, key string, thumbnails []data.Thumbnail) (bool, error)
+	PendingImages(ctx context.Context, contentTypes []string, limit int64) ([]data.PendingImage, error)
+}
+
+// size is the name and the maximum width and height of a thumbnail
+type size struct {
+	name    string
+	maxSize int
+}
+
+// Generator is a struct that generates the thumbnails of the images of the items with a pool of workers.
+// The images are queued in memory, those which are lost when the service stops are queued again on the next start.
+type Generator struct {
+	storage objectStorage
+	store   thumbnailsStore
+	sizes   []size
+	workers int
+	maxSize int64
+	jobs    chan data.PendingImage
+	logger  *logger.Logger
+	metrics *Metrics
+}
+
+// NewGenerator returns a new Generator storing the thumbnails alongside the images
+func NewGenerator(storage objectStorage, store thumbnailsStore, cfg settings.Images, logger *logger.Logger, metrics *Metrics) *Generator {
+	return &Generator{
+		storage: storage,
+		store:   store,
+		sizes:   []size{{Small, cfg.Thumbnails.SmallSize}, {Medium, cfg.Thumbnails.MediumSize}},
+		workers: cfg.Thumbnails.Workers,
+		maxSize: cfg.MaxSize,
+		jobs:    make(chan data.PendingImage, cfg.Thumbnails.QueueSize),
+		logger:  logger,
+		metrics: metrics,
+	}
+}
+
+// Run queues the images whose thumbnails are missing and generates the thumbnails of the queued images
+func (generator *Generator) Run() {
+	var wg sync.WaitGroup
+
+	for i := 0; i < generator.workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range generator.jobs {
+				generator.process(job)
+			}
+		}()
+	}
+
+	err := generator.enqueuePending(context.Background())
+	if err != nil {
+		generator.logger.Error(err, map[string]string{"operation": "enqueue_pending_thumbnails"})
+	}
+
+	wg.Wait()
+}
+
+// Enqueue queues the given image of the item with the given id without waiting.
+// The image is dropped when the queue is full, its thumbnails are then generated on the next start.
+func (generator *Generator) Enqueue(itemID primitive.ObjectID, itemImage data.ItemImage) {
+	select {
+	case generator.jobs <- data.PendingImage{ItemID: itemID, Image: itemImage}:
+	default:
+		generator.metrics.ImagesCounter.WithLabelValues("dropped").Inc()
+		generator.logger.Warning("Thumbnails queue is full", map[string]string{"item": itemID.Hex(), "key": itemImage.Key})
+	}
+}
+
+// enqueuePending queues the supported images whose thumbnails were not generated
+func (generator *Generator) enqueuePending(ctx context.Context) error {
+	images, err := generator.store.PendingImages(ctx, SupportedContentTypes, pendingImagesLimit)
+	if err != nil {
+		return err
+	}
+
+	for _, pending := range images {
+		generator.Enqueue(pending.ItemID, pending.Image)
+	}
+
+	return nil
+}
+
+// process generates the thumbnails of the given image unless it is not supported
+func (generator *Generator) process(job data.PendingImage) {
+	if !validator.In(job.Image.ContentType, SupportedContentTypes...) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), generateTimeout)
+	defer cancel()
+
+	err := generator.generate(ctx, job)
+	if err != nil {
+		generator.metrics.ImagesCounter.WithLabelValues("failed").Inc()
+		generator.logger.Error(err, map[string]string{
+			"operation": "generate_thumbnails",
+			"item":      job.ItemID.Hex(),
+			"key":       job.Image.Key,
+		})
+
+		return
+	}
+
+	generator.metrics.ImagesCounter.WithLabelValues("generated").Inc()
+}
+
+// generate downloads the given image, uploads its thumbnails and records them on the image.
+// The thumbnails of the PNG and GIF images are PNG images so that they keep their transparency.
+func (generator *Generator) generate(ctx context.Context, job data.PendingImage) error {
+	body, err := generator.storage.Get(ctx, job.Image.Key)
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(body, generator.maxSize+1))
+	if err != nil {
+		return err
+	}
+
+	if int64(len(content)) > generator.maxSize {
+		return fmt.Errorf("image is larger than %d bytes", generator.maxSize)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	if config.Width*config.Height > maxPixels {
+		return fmt.Errorf("image of %dx%d pixels is larger than %d pixels", config.Width, config.Height, maxPixels)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	thumbnails := make([]data.Thumbnail, 0, len(generator.sizes))
+
+	for _, size := range generator.sizes {
+		thumbnail := Resize(img, size.maxSize)
+
+		var buf bytes.Buffer
+		contentType := "image/png"
+
+		if format == "jpeg" {
+			contentType = "image/jpeg"
+			err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(&buf, thumbnail)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s-%s", job.Image.Key, size.name)
+
+		err = generator.storage.Put(ctx, key, contentType, buf.Bytes())
+		if err != nil {
+			return err
+		}
+
+		thumbnails = append(thumbnails, data.Thumbnail{
+			Size:   size.name,
+			Key:    key,
+			URL:    generator.storage.URL(key),
+			Width:  thumbnail.Bounds().Dx(),
+			Height: thumbnail.Bounds().Dy(),
+		})
+	}
+
+	// The item or the image may have been deleted meanwhile, leaving the thumbnails to the lifecycle rules of the storage
+	_, err = generator.store.SetThumbnails(ctx, job.ItemID, job.Image.Key, thumbnails)
+
+	return err
+}
@@ -0,0 +1,127 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/data"
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"github.com/PlayEconomy37/Play.Common/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testMetrics are shared by the generators of the tests since prometheus metrics can only be registered once
+var testMetrics = NewMetrics("thumbnail_test")
+
+// fakeStorage keeps the objects in memory
+type fakeStorage struct {
+	objects      map[string][]byte
+	contentTypes map[string]string
+}
+
+// Get returns the content of the object with the given key
+func (storage *fakeStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(storage.objects[key])), nil
+}
+
+// Put stores the given object
+func (storage *fakeStorage) Put(ctx context.Context, key string, contentType string, content []byte) error {
+	storage.objects[key] = content
+	storage.contentTypes[key] = contentType
+	return nil
+}
+
+// URL returns the URL of the object with the given key
+func (storage *fakeStorage) URL(key string) string {
+	return "https://cdn.example.com/" + key
+}
+
+// fakeStore records the thumbnails of a single image
+type fakeStore struct {
+	thumbnails []data.Thumbnail
+}
+
+// SetThumbnails records the given thumbnails
+func (store *fakeStore) SetThumbnails(ctx context.Context, itemID primitive.ObjectID, key string, thumbnails []data.Thumbnail) (bool, error) {
+	store.thumbnails = thumbnails
+	return true, nil
+}
+
+// PendingImages returns no images
+func (store *fakeStore) PendingImages(ctx context.Context, contentTypes []string, limit int64) ([]data.PendingImage, error) {
+	return nil, nil
+}
+
+func TestGenerate(t *testing.T) {
+	var original bytes.Buffer
+
+	err := png.Encode(&original, image.NewRGBA(image.Rect(0, 0, 1024, 768)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &fakeStorage{
+		objects:      map[string][]byte{"items/default/1/2": original.Bytes()},
+		contentTypes: map[string]string{},
+	}
+	store := &fakeStore{}
+
+	cfg := settings.Images{MaxSize: 1 << 20, Thumbnails: settings.Thumbnails{Workers: 1, QueueSize: 1, SmallSize: 128, MediumSize: 512}}
+	generator := NewGenerator(storage, store, cfg, logger.New(io.Discard, logger.LevelInfo), testMetrics)
+
+	err = generator.generate(context.Background(), data.PendingImage{
+		ItemID: primitive.NewObjectID(),
+		Image:  data.ItemImage{Key: "items/default/1/2", ContentType: "image/png"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wanted := []data.Thumbnail{
+		{Size: Small, Key: "items/default/1/2-small", URL: "https://cdn.example.com/items/default/1/2-small", Width: 128, Height: 96},
+		{Size: Medium, Key: "items/default/1/2-medium", URL: "https://cdn.example.com/items/default/1/2-medium", Width: 512, Height: 384},
+	}
+
+	if len(store.thumbnails) != len(wanted) {
+		t.Fatalf("want %d; got %d", len(wanted), len(store.thumbnails))
+	}
+
+	for i := range wanted {
+		if store.thumbnails[i] != wanted[i] {
+			t.Errorf("want %v; got %v", wanted[i], store.thumbnails[i])
+		}
+
+		if storage.contentTypes[wanted[i].Key] != "image/png" {
+			t.Errorf("want %q; got %q", "image/png", storage.contentTypes[wanted[i].Key])
+		}
+	}
+
+	// Images larger than the maximum size are not decoded
+	generator.maxSize = 10
+
+	err = generator.generate(context.Background(), data.PendingImage{
+		ItemID: primitive.NewObjectID(),
+		Image:  data.ItemImage{Key: "items/default/1/2", ContentType: "image/png"},
+	})
+	if err == nil {
+		t.Error("want error; got nil")
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	cfg := settings.Images{Thumbnails: settings.Thumbnails{Workers: 1, QueueSize: 1, SmallSize: 128, MediumSize: 512}}
+	generator := NewGenerator(&fakeStorage{}, &fakeStore{}, cfg, logger.New(io.Discard, logger.LevelInfo), testMetrics)
+
+	generator.Enqueue(primitive.NewObjectID(), data.ItemImage{Key: "first"})
+
+	// The queue is full so the image is dropped instead of blocking the caller
+	generator.Enqueue(primitive.NewObjectID(), data.ItemImage{Key: "second"})
+
+	if len(generator.jobs) != 1 || (<-generator.jobs).Image.Key != "first" {
+		t.Error("want first image queued only")
+	}
+}
@@ -0,0 +1,25 @@
+package thumbnail
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics is a struct that holds some prometheus metrics regarding the generation of the thumbnails
+type Metrics struct {
+	ImagesCounter *prometheus.CounterVec
+}
+
+// NewMetrics creates the counters used to keep track of the generation of the thumbnails
+func NewMetrics(appName string) *Metrics {
+	imagesCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_thumbnail_images_total", appName),
+		Help: "The total number of images whose thumbnails were generated, failed or dropped because the queue was full",
+	}, []string{"result"})
+
+	return &Metrics{
+		ImagesCounter: imagesCounter,
+	}
+}
@@ -0,0 +1,63 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+)
+
+// Resize returns the given image downscaled to fit within a square of the given size, keeping its aspect ratio.
+// Images which already fit are returned as they are. Each pixel of the thumbnail is the average of the pixels
+// of the image it covers, which keeps the details of the image without aliasing.
+func Resize(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width <= maxSize && height <= maxSize {
+		return src
+	}
+
+	thumbnailWidth, thumbnailHeight := maxSize, height*maxSize/width
+	if height > width {
+		thumbnailWidth, thumbnailHeight = width*maxSize/height, maxSize
+	}
+
+	// Very wide or tall images keep at least a pixel
+	if thumbnailWidth < 1 {
+		thumbnailWidth = 1
+	}
+
+	if thumbnailHeight < 1 {
+		thumbnailHeight = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, thumbnailWidth, thumbnailHeight))
+
+	for y := 0; y < thumbnailHeight; y++ {
+		y0 := bounds.Min.Y + y*height/thumbnailHeight
+		y1 := bounds.Min.Y + (y+1)*height/thumbnailHeight
+
+		for x := 0; x < thumbnailWidth; x++ {
+			x0 := bounds.Min.X + x*width/thumbnailWidth
+			x1 := bounds.Min.X + (x+1)*width/thumbnailWidth
+
+			var r, g, b, a, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// The colors are alpha-premultiplied so that transparent pixels do not darken the average
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	return dst
+}
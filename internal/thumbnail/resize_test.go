@@ -0,0 +1,55 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		testName     string
+		width        int
+		height       int
+		maxSize      int
+		wantedWidth  int
+		wantedHeight int
+	}{
+		{"Landscape", 1024, 512, 128, 128, 64},
+		{"Portrait", 300, 900, 90, 30, 90},
+		{"Square", 600, 600, 128, 128, 128},
+		{"Already small", 100, 50, 128, 100, 50},
+		{"Very wide", 10000, 2, 128, 128, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			got := Resize(image.NewRGBA(image.Rect(0, 0, tt.width, tt.height)), tt.maxSize).Bounds()
+
+			if got.Dx() != tt.wantedWidth || got.Dy() != tt.wantedHeight {
+				t.Errorf("want %dx%d; got %dx%d", tt.wantedWidth, tt.wantedHeight, got.Dx(), got.Dy())
+			}
+		})
+	}
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	// Black and white columns average to grey
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if x%2 == 0 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	got := color.RGBAModel.Convert(Resize(src, 2).At(0, 0)).(color.RGBA)
+
+	if got.R != 127 || got.G != 127 || got.B != 127 || got.A != 255 {
+		t.Errorf("want grey; got %v", got)
+	}
+}
@@ -0,0 +1,17 @@
+package tracing
+
+import (
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewMongoMonitor creates a MongoDB command monitor starting a child span of the current span for every command
+// (i.e. "items.find"), with the collection and the operation as attributes. The commands themselves, which contain
+// the documents and the filter values, are only recorded as the "db.statement" attribute when statements is enabled.
+func NewMongoMonitor(tracerProvider trace.TracerProvider, statements bool) *event.CommandMonitor {
+	return otelmongo.NewMonitor(
+		otelmongo.WithTracerProvider(tracerProvider),
+		otelmongo.WithCommandAttributeDisabled(!statements),
+	)
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewMongoMonitor(t *testing.T) {
+	command, err := bson.Marshal(bson.D{{Key: "find", Value: "items"}, {Key: "filter", Value: bson.M{"name": "Potion"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName        string
+		statements      bool
+		wantedStatement bool
+	}{
+		{"Statements disabled", false, false},
+		{"Statements enabled", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			monitor := NewMongoMonitor(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), tt.statements)
+
+			monitor.Started(context.Background(), &event.CommandStartedEvent{
+				Command:      command,
+				DatabaseName: "Catalog",
+				CommandName:  "find",
+				RequestID:    1,
+				ConnectionID: "localhost:27017[-1]",
+			})
+			monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+				CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, ConnectionID: "localhost:27017[-1]"},
+			})
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("want %d span; got %d", 1, len(spans))
+			}
+
+			if spans[0].Name() != "items.find" {
+				t.Errorf("want span %q; got %q", "items.find", spans[0].Name())
+			}
+
+			attributes := attribute.NewSet(spans[0].Attributes()...)
+
+			if collection, _ := attributes.Value("db.mongodb.collection"); collection.AsString() != "items" {
+				t.Errorf("want collection %q; got %q", "items", collection.AsString())
+			}
+
+			if _, statement := attributes.Value("db.statement"); statement != tt.wantedStatement {
+				t.Errorf("want statement %t; got %t", tt.wantedStatement, statement)
+			}
+		})
+	}
+}
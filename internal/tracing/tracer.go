@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PlayEconomy37/Play.Catalog/internal/settings"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// SetupTracer sets up Opentelemetry with the exporter, sampler and resource attributes
+// defined in the given tracing configuration
+func SetupTracer(cfg settings.Tracing, serviceName string) (*sdktrace.TracerProvider, error) {
+	// Resource attributes describing this service
+	attributes := []attribute.KeyValue{semconv.ServiceNameKey.String(serviceName)}
+
+	for _, pair := range strings.Split(cfg.ResourceAttributes, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || key == "" {
+			continue
+		}
+
+		attributes = append(attributes, attribute.String(key, value))
+	}
+
+	// Sample a ratio of the root spans and follow the sampling decision of the parent span otherwise
+	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attributes...)),
+	}
+
+	// Setup exporter
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tracerProvider, nil
+}
+
+// newExporter creates the span exporter defined in the given tracing configuration.
+// It returns a nil exporter when tracing export is disabled.
+func newExporter(cfg settings.Tracing) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "otlp":
+		return newOTLPExporter(cfg)
+	case "jaeger":
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.Endpoint)))
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", cfg.Exporter)
+	}
+}
+
+// newOTLPExporter creates an OTLP exporter using either the gRPC or the HTTP protocol
+func newOTLPExporter(cfg settings.Tracing) (*otlptrace.Exporter, error) {
+	switch cfg.Protocol {
+	case "grpc":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		return otlptrace.New(context.Background(), otlptracegrpc.NewClient(opts...))
+	case "http":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		return otlptrace.New(context.Background(), otlptracehttp.NewClient(opts...))
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol %q", cfg.Protocol)
+	}
+}
@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/PlayEconomy37/Play.Common/validator"
+)
+
+// Validator extends the validator of the common package with the stable reason of every error
+// (i.e. "out_of_range"), given along with its message so that the error codes returned to the clients
+// don't depend on the wording of the messages
+type Validator struct {
+	validator.Validator
+	Reasons map[string]string
+}
+
+// New creates a new Validator instance without errors
+func New() *Validator {
+	return &Validator{Validator: *validator.New(), Reasons: make(map[string]string)}
+}
+
+// AddError adds an error message along with its reason (so long as no entry already exists for the given key)
+func (v *Validator) AddError(key, reason, message string) {
+	if _, exists := v.Errors[key]; !exists {
+		v.Errors[key] = message
+		v.Reasons[key] = reason
+	}
+}
+
+// Check adds an error message along with its reason only if a validation check is not 'ok'
+func (v *Validator) Check(ok bool, key, reason, message string) {
+	if !ok {
+		v.AddError(key, reason, message)
+	}
+}
+
+// Track calls fn with the validator of the common package, whose helpers don't know about reasons,
+// and sets the given reason on the errors added by fn
+func (v *Validator) Track(reason string, fn func(common *validator.Validator)) {
+	fn(&v.Validator)
+
+	for key := range v.Errors {
+		if _, exists := v.Reasons[key]; !exists {
+			v.Reasons[key] = reason
+		}
+	}
+}
+
+// Codes returns the stable code of every error (i.e. "price.out_of_range"). The errors without reason,
+// added directly to the common validator, have the "<key>.invalid" code.
+func (v *Validator) Codes() map[string]string {
+	codes := make(map[string]string, len(v.Errors))
+
+	for key := range v.Errors {
+		reason := v.Reasons[key]
+		if reason == "" {
+			reason = "invalid"
+		}
+
+		codes[key] = fmt.Sprintf("%s.%s", key, reason)
+	}
+
+	return codes
+}
@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PlayEconomy37/Play.Common/validator"
+)
+
+func TestCodes(t *testing.T) {
+	v := New()
+
+	v.Check(false, "price", "out_of_range", "must be greater or equal to 0.1 and lower or equal to 1000")
+	v.Check(true, "name", "required", "must be provided")
+	v.AddError("tags", "duplicate", "must not contain duplicate values")
+	v.AddError("tags", "too_many", "must not contain more than 20 tags")
+	v.Track("invalid_format", func(common *validator.Validator) {
+		common.AddError("page", "must be an integer value")
+	})
+	v.Validator.AddError("sort", "invalid sort value")
+
+	expectedErrors := map[string]string{
+		"price": "must be greater or equal to 0.1 and lower or equal to 1000",
+		"tags":  "must not contain duplicate values",
+		"page":  "must be an integer value",
+		"sort":  "invalid sort value",
+	}
+
+	if !reflect.DeepEqual(v.Errors, expectedErrors) {
+		t.Errorf("want errors %v; got %v", expectedErrors, v.Errors)
+	}
+
+	expectedCodes := map[string]string{
+		"price": "price.out_of_range",
+		"tags":  "tags.duplicate",
+		"page":  "page.invalid_format",
+		"sort":  "sort.invalid",
+	}
+
+	if codes := v.Codes(); !reflect.DeepEqual(codes, expectedCodes) {
+		t.Errorf("want codes %v; got %v", expectedCodes, codes)
+	}
+}
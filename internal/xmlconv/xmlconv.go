@@ -0,0 +1,156 @@
+package xmlconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ContentType is the media type of the XML documents
+const ContentType = "application/xml"
+
+// maxDepth is the maximum nesting of the converted documents, which protects the recursive conversion
+const maxDepth = 100
+
+// nameRegex matches the object keys which are valid XML element names
+var nameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// FromJSON converts a JSON document into an XML document whose root element is named after the given name.
+// Object keys become elements (or "entry" elements with a "key" attribute when they are not valid element
+// names), each value of an array becomes an element named after the singular of the array name (i.e. "item"
+// for "items" and "value" when there is none) and null values are empty elements with a nil="true" attribute.
+func FromJSON(document []byte, root string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buf)
+
+	err := encodeValue(encoder, decoder, xml.StartElement{Name: xml.Name{Local: root}}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure that the document only contains a single JSON value
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("document must only contain a single JSON value")
+	}
+
+	err = encoder.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeValue encodes the next JSON value of the decoder as the given element
+func encodeValue(encoder *xml.Encoder, decoder *json.Decoder, start xml.StartElement, depth int) error {
+	if depth > maxDepth {
+		return errors.New("document is nested too deeply")
+	}
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token := token.(type) {
+	case nil:
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+		return encodeElement(encoder, start, "")
+	case bool:
+		if token {
+			return encodeElement(encoder, start, "true")
+		}
+
+		return encodeElement(encoder, start, "false")
+	case json.Number:
+		return encodeElement(encoder, start, token.String())
+	case string:
+		return encodeElement(encoder, start, token)
+	}
+
+	delim := token.(json.Delim)
+
+	err = encoder.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		child := xml.StartElement{Name: xml.Name{Local: singular(start)}}
+
+		if delim == '{' {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+
+			child = element(key.(string))
+		}
+
+		err := encodeValue(encoder, decoder, child, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing delimiter
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// encodeElement encodes an element holding the given text
+func encodeElement(encoder *xml.Encoder, start xml.StartElement, text string) error {
+	err := encoder.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	if text != "" {
+		err = encoder.EncodeToken(xml.CharData(text))
+		if err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// element returns the element of the given object key
+func element(key string) xml.StartElement {
+	if !nameRegex.MatchString(key) || strings.HasPrefix(strings.ToLower(key), "xml") {
+		return xml.StartElement{
+			Name: xml.Name{Local: "entry"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+		}
+	}
+
+	return xml.StartElement{Name: xml.Name{Local: key}}
+}
+
+// singular returns the name of the elements of the values of the array encoded as the given element
+func singular(start xml.StartElement) string {
+	name := start.Name.Local
+
+	switch {
+	case name == "entry" && len(start.Attr) != 0:
+		return "value"
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return strings.TrimSuffix(name, "s")
+	default:
+		return "value"
+	}
+}
@@ -0,0 +1,44 @@
+package xmlconv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{"Object", `{"item": {"name": "Potion", "price": 5.5, "featured": false}}`, `<response><item><name>Potion</name><price>5.5</price><featured>false</featured></item></response>`},
+		{"Arrays", `{"items": [{"tags": ["healing"]}], "categories": [1], "data": [true]}`, `<response><items><item><tags><tag>healing</tag></tags></item></items><categories><category>1</category></categories><data><value>true</value></data></response>`},
+		{"Null", `{"expires_at": null}`, `<response><expires_at nil="true"></expires_at></response>`},
+		{"Escaped text", `{"description": "<b>Rare</b> & strong"}`, `<response><description>&lt;b&gt;Rare&lt;/b&gt; &amp; strong</description></response>`},
+		{"Invalid element names", `{"stock": {"63407e2c8bcd4a43ec1c4ff4": [3]}}`, `<response><stock><entry key="63407e2c8bcd4a43ec1c4ff4"><value>3</value></entry></stock></response>`},
+		{"Pagination metadata", `{"items": [], "metadata": {"current_page": 1, "total_records": 0}}`, `<response><items></items><metadata><current_page>1</current_page><total_records>0</total_records></metadata></response>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromJSON([]byte(tt.document), "response")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body := strings.TrimPrefix(string(got), `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+
+			if body != tt.want {
+				t.Errorf("want %s; got %s", tt.want, body)
+			}
+		})
+	}
+}
+
+func TestFromJSONMalformed(t *testing.T) {
+	for _, document := range []string{`{"name": `, `{} {}`, strings.Repeat("[", 200) + strings.Repeat("]", 200)} {
+		if _, err := FromJSON([]byte(document), "response"); err == nil {
+			t.Errorf("want error for %q; got nil", document)
+		}
+	}
+}